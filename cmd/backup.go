@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/archive"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup [file]",
+	Short: "Create an archive with the config, journal, database and attachments",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := utils.OpenDB()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		path := fmt.Sprintf("paisa-%s.tar.gz", time.Now().Format("2006-01-02-15-04-05"))
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		file, err := os.Create(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+
+		manifest, err := archive.Create(db, file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Created %s with %d journal files and %d attachments", path, len(manifest.Journal), len(manifest.Attachments))
+	},
+}
+
+var restoreForce bool
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore file",
+	Short: "Restore an archive created by the backup command",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := utils.OpenDB()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+
+		manifest, err := archive.Restore(db, file, archive.RestoreOptions{Force: restoreForce, DB: true})
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Infof("Restored %s created by paisa %s", args[0], manifest.Version)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().BoolVarP(&restoreForce, "force", "f", false, "restore an archive created by a newer version")
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ananthakumaran/paisa/internal/doctor"
+	"github.com/samber/lo"
+	"github.com/spf13/cobra"
+)
+
+var doctorOffline bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the config, ledger binary, database and price providers",
+	Run: func(cmd *cobra.Command, args []string) {
+		path := ResolveConfigFile()
+		if path != "" {
+			if absolute, err := filepath.Abs(path); err == nil {
+				path = absolute
+			}
+		}
+
+		checks := doctor.Run(path, doctor.Options{Offline: doctorOffline})
+		for _, check := range checks {
+			fmt.Printf("%s %s: %s\n", doctorSymbols[check.Status], check.Name, check.Message)
+			if check.Fix != "" {
+				fmt.Printf("    fix: %s\n", check.Fix)
+			}
+		}
+
+		if lo.ContainsBy(checks, func(c doctor.Check) bool { return c.Status == doctor.FAIL }) {
+			os.Exit(1)
+		}
+	},
+}
+
+var doctorSymbols = map[doctor.Status]string{
+	doctor.OK:   "[ok]  ",
+	doctor.WARN: "[warn]",
+	doctor.FAIL: "[fail]",
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "skip the sample fetch of the price providers")
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/encryption"
+	"github.com/bmatcuk/doublestar/v4"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var encryptCmd = &cobra.Command{
+	Use:   "encrypt",
+	Short: "Encrypt the journal files and the database",
+	Run: func(cmd *cobra.Command, args []string) {
+		key := readKey()
+		journalPath := config.GetJournalPath()
+		dir := filepath.Dir(journalPath)
+		ext := filepath.Ext(journalPath)
+		paths, err := config.GlobFiles(dir, ext)
+		if err != nil {
+			log.Fatal(err)
+		}
+		backups, err := doublestar.FilepathGlob(dir + "/**/*" + ext + ".backup.*")
+		if err != nil {
+			log.Fatal(err)
+		}
+		paths = append(paths, backups...)
+		if _, err := os.Stat(config.GetDBPath()); err == nil {
+			paths = append(paths, config.GetDBPath())
+		}
+
+		for _, path := range paths {
+			if strings.HasSuffix(path, encryption.EXTENSION) {
+				continue
+			}
+			err := encryption.EncryptFile(key, path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Info("Encrypted ", path)
+		}
+	},
+}
+
+var decryptCmd = &cobra.Command{
+	Use:   "decrypt",
+	Short: "Decrypt the journal files and the database",
+	Run: func(cmd *cobra.Command, args []string) {
+		key := readKey()
+		paths, err := doublestar.FilepathGlob(filepath.Dir(config.GetJournalPath()) + "/**/*" + encryption.EXTENSION)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := os.Stat(config.GetDBPath() + encryption.EXTENSION); err == nil {
+			paths = append(paths, config.GetDBPath()+encryption.EXTENSION)
+		}
+
+		for _, path := range paths {
+			err := encryption.DecryptFile(key, path)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Info("Decrypted ", path)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(encryptCmd)
+	rootCmd.AddCommand(decryptCmd)
+}
+
+// readKey reads the passphrase from the key file, PAISA_PASSPHRASE
+// environment variable or the terminal, in that order.
+func readKey() *encryption.Key {
+	var passphrase string
+	if keyFile := config.GetConfig().Encryption.KeyFile; keyFile != "" {
+		content, err := os.ReadFile(config.ResolvePath(keyFile))
+		if err != nil {
+			log.Fatal(err)
+		}
+		passphrase = strings.TrimSpace(string(content))
+	} else if env := os.Getenv("PAISA_PASSPHRASE"); env != "" {
+		passphrase = env
+	} else {
+		fmt.Fprint(os.Stderr, "Passphrase: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			log.Fatal(err)
+		}
+		passphrase = strings.TrimSpace(line)
+	}
+
+	key, err := encryption.NewKey(passphrase)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return key
+}
+
+// UnlockVault decrypts the journal and the database and points paisa
+// at the decrypted copy. The changes are encrypted back on exit.
+func UnlockVault() {
+	journalPath := config.GetJournalPath()
+	vault, err := encryption.Open(journalPath, config.GetDBPath(), readKey())
+	if err != nil {
+		log.Fatal(err)
+	}
+	config.OverridePaths(vault.JournalPath(journalPath), vault.DBPath())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		LockVault()
+		os.Exit(0)
+	}()
+}
+
+func LockVault() {
+	if vault := encryption.Current(); vault != nil {
+		err := vault.Close()
+		if err != nil {
+			log.Error("Failed to encrypt the journal: ", err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ananthakumaran/paisa/internal/server"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var formatDryRun bool
+
+var formatCmd = &cobra.Command{
+	Use:   "format [file]",
+	Short: "Format the journal files",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := utils.OpenDB()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		request := server.FormatRequest{DryRun: formatDryRun}
+		if len(args) > 0 {
+			request.Name = args[0]
+		}
+
+		result := server.FormatFiles(db, request)
+		if message, ok := result["message"]; ok {
+			log.Fatal(message)
+		}
+
+		for _, change := range result["changes"].([]server.FormatChange) {
+			if formatDryRun {
+				fmt.Print(change.Diff)
+			} else {
+				log.Info("Formatted ", change.FileName)
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(formatCmd)
+	formatCmd.Flags().BoolVarP(&formatDryRun, "dry-run", "n", false, "print the diff instead of writing the files")
+}
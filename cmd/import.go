@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ananthakumaran/paisa/internal/csvimport"
+	"github.com/ananthakumaran/paisa/internal/server"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var importRules string
+var importBroker string
+var importFile string
+var importDryRun bool
+
+var importCmd = &cobra.Command{
+	Use:   "import file",
+	Short: "Import the transactions of a bank statement or a broker trade file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if importRules == "" && importBroker == "" {
+			log.Fatal("Either --rules or --broker is required")
+		}
+
+		db, err := utils.OpenDB()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		statement, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		request := server.StatementImportRequest{Content: string(statement), Broker: importBroker, File: importFile, DryRun: importDryRun}
+		if importRules != "" {
+			request.Rules, err = csvimport.LoadRules(importRules)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		result := server.ImportStatement(db, request)
+		content, found := result["content"]
+		switch {
+		case importDryRun && found:
+			fmt.Println(content)
+		case result["saved"] == true:
+			log.Infof("Imported %d transactions", result["imported"])
+		case !found && result["imported"] == 0:
+			log.Info(result["message"])
+		default:
+			if found {
+				fmt.Println(content)
+			}
+			log.Fatal(result["message"])
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+	importCmd.Flags().StringVarP(&importRules, "rules", "r", "", "rules file describing the columns of the bank statement")
+	importCmd.Flags().StringVarP(&importBroker, "broker", "b", "", "import the trade file of the configured broker, zerodha or ibkr")
+	importCmd.Flags().StringVarP(&importFile, "file", "f", "", "journal file to append to (default is the main journal file)")
+	importCmd.Flags().BoolVarP(&importDryRun, "dry-run", "n", false, "print the transactions instead of appending them")
+}
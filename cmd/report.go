@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/server"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var reportFormat string
+
+var reportAliases = map[string]string{
+	"cashflow": "cash_flow",
+}
+
+var reportCmd = &cobra.Command{
+	Use:       "report name",
+	Short:     "Print a report without starting the server",
+	Long:      "Print a report without starting the server. Available reports: " + strings.Join(server.ExportReportNames(), ", "),
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: server.ExportReportNames(),
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := utils.OpenDB()
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		name := strings.ReplaceAll(args[0], "-", "_")
+		if alias, ok := reportAliases[name]; ok {
+			name = alias
+		}
+
+		err = server.WriteReport(os.Stdout, db, name, reportFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVarP(&reportFormat, "format", "f", "table", "output format: table, csv or json")
+}
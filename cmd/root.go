@@ -12,6 +12,7 @@ import (
 	"github.com/adrg/xdg"
 	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/generator"
+	"github.com/ananthakumaran/paisa/internal/remote"
 	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
@@ -25,6 +26,9 @@ var now string
 var rootCmd = &cobra.Command{
 	Use:   "paisa",
 	Short: "Personal finance manager",
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		LockVault()
+	},
 }
 
 func Execute() {
@@ -47,12 +51,24 @@ func Initialize() {
 	}
 	currentCommand, _, _ := rootCmd.Find(os.Args[1:])
 
-	if !lo.Contains([]string{"serve", "update"}, currentCommand.Name()) {
+	if !lo.Contains([]string{"serve", "update", "format", "report", "import", "backup", "restore", "encrypt", "decrypt"}, currentCommand.Name()) {
 		return
 	}
 
 	InitConfig()
 
+	if lo.Contains([]string{"encrypt", "decrypt"}, currentCommand.Name()) {
+		return
+	}
+
+	if config.GetConfig().Encryption.Enabled {
+		UnlockVault()
+	}
+
+	_, err := remote.Open(config.GetJournalPath())
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func InitLogger(desktop bool, hook log.Hook) {
@@ -116,22 +132,32 @@ func InitConfig() {
 		}
 	}
 
-	xdgDocumentDir := filepath.Join(xdg.UserDirs.Documents, "paisa")
-	xdgDocumentPath := filepath.Join(xdgDocumentDir, "paisa.yaml")
-	if envConfigFile := os.Getenv("PAISA_CONFIG"); envConfigFile != "" {
-		config.LoadConfigFile(envConfigFile)
-	} else if configFile != "" {
-		config.LoadConfigFile(configFile)
-	} else if utils.FileExists("paisa.yaml") {
-		config.LoadConfigFile("paisa.yaml")
-	} else if utils.FileExists(xdgDocumentPath) {
-		config.LoadConfigFile(xdgDocumentPath)
+	if path := ResolveConfigFile(); path != "" {
+		config.LoadConfigFile(path)
 	} else {
+		xdgDocumentDir := filepath.Join(xdg.UserDirs.Documents, "paisa")
 		err := os.MkdirAll(xdgDocumentDir, 0755)
 		if err != nil {
 			log.Fatal(err)
 		}
 		generator.MinimalConfig(xdgDocumentDir)
-		config.LoadConfigFile(xdgDocumentPath)
+		config.LoadConfigFile(filepath.Join(xdgDocumentDir, "paisa.yaml"))
+	}
+}
+
+// ResolveConfigFile returns the path of the config file picked in the
+// order of PAISA_CONFIG, --config, ./paisa.yaml and the documents
+// directory, or empty if none is found.
+func ResolveConfigFile() string {
+	xdgDocumentPath := filepath.Join(xdg.UserDirs.Documents, "paisa", "paisa.yaml")
+	if envConfigFile := os.Getenv("PAISA_CONFIG"); envConfigFile != "" {
+		return envConfigFile
+	} else if configFile != "" {
+		return configFile
+	} else if utils.FileExists("paisa.yaml") {
+		return "paisa.yaml"
+	} else if utils.FileExists(xdgDocumentPath) {
+		return xdgDocumentPath
 	}
+	return ""
 }
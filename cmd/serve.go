@@ -3,6 +3,7 @@ package cmd
 import (
 	"os"
 
+	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/model"
 	"github.com/ananthakumaran/paisa/internal/server"
 	"github.com/ananthakumaran/paisa/internal/utils"
@@ -11,6 +12,8 @@ import (
 )
 
 var port int
+var readonly bool
+var watch bool
 
 var serveCmd = &cobra.Command{
 	Use:   "serve",
@@ -26,6 +29,13 @@ var serveCmd = &cobra.Command{
 		if err != nil {
 			log.Fatal(err)
 		}
+		if readonly {
+			config.ForceReadonly()
+		}
+		if watch {
+			config.ForceWatchJournal()
+		}
+
 		server.Listen(db, port)
 	},
 }
@@ -33,4 +43,6 @@ var serveCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(serveCmd)
 	serveCmd.Flags().IntVarP(&port, "port", "p", 7500, "port to listen on")
+	serveCmd.Flags().BoolVar(&readonly, "readonly", false, "disable all the endpoints that modify the journal, config or prices")
+	serveCmd.Flags().BoolVarP(&watch, "watch", "w", false, "sync the journal whenever a journal file changes")
 }
@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"fmt"
+
+	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/spf13/cobra"
 )
 
@@ -9,7 +11,7 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version information",
 	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Version:", "0.6.6")
+		fmt.Println("Version:", config.VERSION)
 	},
 }
 
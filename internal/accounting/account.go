@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/utils"
 	"golang.org/x/exp/slices"
 	"gorm.io/gorm"
 )
@@ -13,14 +14,15 @@ type accountCache struct {
 	accounts []string
 }
 
-var acache accountCache
+var acaches utils.NamespacedCache[accountCache]
 
-func loadAccountCache(db *gorm.DB) {
+func loadAccountCache(db *gorm.DB, acache *accountCache) {
 	db.Model(&posting.Posting{}).Distinct().Pluck("Account", &acache.accounts)
 }
 
 func AllAccounts(db *gorm.DB) []string {
-	acache.Do(func() { loadAccountCache(db) })
+	acache := acaches.Get(db)
+	acache.Do(func() { loadAccountCache(db, acache) })
 	return acache.accounts
 }
 
@@ -29,5 +31,5 @@ func IsLeafAccount(db *gorm.DB, account string) bool {
 }
 
 func ClearCache() {
-	acache = accountCache{}
+	acaches.Clear()
 }
@@ -0,0 +1,134 @@
+package accrual
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+const PAYEE = "Accrued interest"
+
+type Rate struct {
+	From time.Time       `json:"from"`
+	Rate decimal.Decimal `json:"rate"`
+}
+
+// Generate returns the interest accrued on the configured accounts
+// since the interest was last credited. Interest of a month is
+// computed on the balance at the end of the month and posted on the
+// last day of the month. Accrued interest doesn't compound, same as
+// EPF and PPF, where the interest is credited once a year.
+func Generate(postings []*posting.Posting, accruals []config.InterestAccrual, until time.Time) []*posting.Posting {
+	interestTransactions := make(map[string]bool)
+	for _, p := range postings {
+		if utils.IsInterestAccount(p.Account) {
+			interestTransactions[p.TransactionID] = true
+		}
+	}
+
+	generated := []*posting.Posting{}
+	for _, accrual := range accruals {
+		rates, err := ParseRates(accrual.Rates)
+		if err != nil {
+			log.Warn("Invalid interest rate for ", accrual.Account, ": ", err)
+			continue
+		}
+
+		ps := lo.Filter(postings, func(p *posting.Posting, _ int) bool {
+			return p.Account == accrual.Account && p.Commodity == config.DefaultCurrency()
+		})
+		if len(ps) == 0 || len(rates) == 0 {
+			continue
+		}
+
+		start := utils.BeginningOfMonth(ps[0].Date)
+		for _, p := range ps {
+			if interestTransactions[p.TransactionID] {
+				start = utils.BeginningOfMonth(p.Date).AddDate(0, 1, 0)
+			}
+		}
+
+		incomeAccount := accrual.IncomeAccount
+		if incomeAccount == "" {
+			parts := strings.Split(accrual.Account, ":")
+			incomeAccount = config.InterestAccounts()[0] + ":" + parts[len(parts)-1]
+		}
+
+		for month := start; !utils.EndOfMonth(month).After(until); month = month.AddDate(0, 1, 0) {
+			end := utils.EndOfMonth(month)
+			r, found := RateOn(rates, month)
+			if !found {
+				continue
+			}
+
+			balance := decimal.Zero
+			for _, p := range ps {
+				if !p.Date.After(end) {
+					balance = balance.Add(p.Amount)
+				}
+			}
+
+			interest := balance.Mul(r).Div(decimal.NewFromInt(1200)).Round(2)
+			if !interest.IsPositive() {
+				continue
+			}
+
+			date := month.AddDate(0, 1, -1)
+			transactionID := "accrual:" + accrual.Account + ":" + month.Format("2006-01")
+			generated = append(generated,
+				accruedPosting(transactionID, date, accrual.Account, interest),
+				accruedPosting(transactionID, date, incomeAccount, interest.Neg()))
+		}
+	}
+
+	return generated
+}
+
+func accruedPosting(transactionID string, date time.Time, account string, amount decimal.Decimal) *posting.Posting {
+	return &posting.Posting{
+		TransactionID: transactionID,
+		Date:          date,
+		Payee:         PAYEE,
+		Account:       account,
+		Commodity:     config.DefaultCurrency(),
+		Quantity:      amount,
+		Amount:        amount,
+	}
+}
+
+func ParseRates(configured []config.InterestRate) ([]Rate, error) {
+	rates := []Rate{}
+	for _, r := range configured {
+		from, err := time.ParseInLocation("2006-01-02", r.From, config.TimeZone())
+		if err != nil {
+			return nil, err
+		}
+		rates = append(rates, Rate{From: from, Rate: decimal.NewFromFloat(r.Rate)})
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].From.Before(rates[j].From) })
+	return rates, nil
+}
+
+// RateOn returns the rate applicable on the date, the rates are
+// expected to be sorted by the date.
+func RateOn(rates []Rate, date time.Time) (decimal.Decimal, bool) {
+	var found *Rate
+	for i := range rates {
+		if !rates[i].From.After(date) {
+			found = &rates[i]
+		}
+	}
+
+	if found == nil {
+		return decimal.Zero, false
+	}
+	return found.Rate, true
+}
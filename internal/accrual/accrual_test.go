@@ -0,0 +1,54 @@
+package accrual
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(value string) time.Time {
+	d, _ := time.ParseInLocation("2006-01-02", value, config.TimeZone())
+	return d
+}
+
+func deposit(transactionID string, on string, account string, amount int64) *posting.Posting {
+	return &posting.Posting{
+		TransactionID: transactionID,
+		Date:          date(on),
+		Account:       account,
+		Commodity:     "INR",
+		Amount:        decimal.NewFromInt(amount),
+		Quantity:      decimal.NewFromInt(amount),
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	postings := []*posting.Posting{
+		deposit("1", "2023-01-10", "Assets:Debt:EPF", 120000),
+		deposit("2", "2023-03-31", "Assets:Debt:EPF", 5000),
+		deposit("2", "2023-03-31", "Income:Interest:EPF", -5000),
+		deposit("3", "2023-04-10", "Assets:Debt:EPF", 115000),
+	}
+	accruals := []config.InterestAccrual{{
+		Account: "Assets:Debt:EPF",
+		Rates:   []config.InterestRate{{From: "2022-04-01", Rate: 8}, {From: "2023-05-01", Rate: 12}},
+	}}
+
+	generated := Generate(postings, accruals, date("2023-06-15"))
+	assert.Len(t, generated, 4)
+
+	assert.Equal(t, "2023-04-30", generated[0].Date.Format("2006-01-02"))
+	assert.Equal(t, "Assets:Debt:EPF", generated[0].Account)
+	assert.Equal(t, "1600", generated[0].Amount.String())
+	assert.Equal(t, "Income:Interest:EPF", generated[1].Account)
+	assert.Equal(t, "-1600", generated[1].Amount.String())
+
+	assert.Equal(t, "2023-05-31", generated[2].Date.Format("2006-01-02"))
+	assert.Equal(t, "2400", generated[2].Amount.String())
+}
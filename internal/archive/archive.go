@@ -0,0 +1,308 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/attachment"
+	"github.com/ananthakumaran/paisa/internal/backup"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// FORMAT_VERSION is bumped whenever the layout of the archive changes
+// in a way older versions can't restore.
+const FORMAT_VERSION = 1
+
+const (
+	manifestEntry    = "manifest.json"
+	configEntry      = "config.yaml"
+	dbEntry          = "paisa.db"
+	journalPrefix    = "journal/"
+	attachmentPrefix = "attachments/"
+)
+
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	Version       string    `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Journal       []string  `json:"journal"`
+	Attachments   []string  `json:"attachments"`
+	DB            bool      `json:"db"`
+}
+
+type RestoreOptions struct {
+	// restore an archive created by a newer version of paisa
+	Force bool
+	// overwrite the database file. The db is closed before that and
+	// can't be used afterwards, so this is meant for the cli.
+	DB bool
+}
+
+// Create writes a gzipped tar archive with the config, the journal
+// files, the attachments and a snapshot of the database.
+func Create(db *gorm.DB, w io.Writer) (Manifest, error) {
+	manifest := Manifest{FormatVersion: FORMAT_VERSION, Version: config.VERSION, CreatedAt: time.Now(), Journal: []string{}, Attachments: []string{}}
+
+	journalNames, err := journal.Files(db)
+	if err != nil {
+		return manifest, err
+	}
+
+	storage, err := attachment.GetStorage()
+	if err != nil {
+		return manifest, err
+	}
+	attachmentNames, err := storage.List()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return manifest, err
+	}
+
+	snapshot, err := snapshotDB(db)
+	if err != nil {
+		return manifest, err
+	}
+	manifest.DB = true
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	files := []func() (string, []byte, error){
+		func() (string, []byte, error) {
+			content, err := os.ReadFile(config.GetConfigPath())
+			return configEntry, content, err
+		},
+		func() (string, []byte, error) { return dbEntry, snapshot, nil },
+	}
+	for _, name := range journalNames {
+		name := name
+		manifest.Journal = append(manifest.Journal, filepath.ToSlash(name))
+		files = append(files, func() (string, []byte, error) {
+			content, err := journal.Read(db, name)
+			return journalPrefix + filepath.ToSlash(name), []byte(content), err
+		})
+	}
+	for _, name := range attachmentNames {
+		name := name
+		manifest.Attachments = append(manifest.Attachments, name)
+		files = append(files, func() (string, []byte, error) {
+			content, err := storage.Get(name)
+			return attachmentPrefix + name, content, err
+		})
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	err = writeEntry(tarWriter, manifestEntry, content)
+	if err != nil {
+		return manifest, err
+	}
+
+	for _, file := range files {
+		name, content, err := file()
+		if err != nil {
+			return manifest, err
+		}
+
+		err = writeEntry(tarWriter, name, content)
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return manifest, err
+	}
+	return manifest, gzipWriter.Close()
+}
+
+// Restore writes back the files in the archive. The current config and
+// journal files are backed up first. Archives created by a newer
+// version are rejected unless forced, the config might have options
+// this version doesn't understand.
+func Restore(db *gorm.DB, r io.Reader, options RestoreOptions) (Manifest, error) {
+	var manifest Manifest
+	entries, err := readEntries(r)
+	if err != nil {
+		return manifest, err
+	}
+
+	content, ok := entries[manifestEntry]
+	if !ok {
+		return manifest, fmt.Errorf("Invalid archive, %s is missing", manifestEntry)
+	}
+	err = json.Unmarshal(content, &manifest)
+	if err != nil {
+		return manifest, err
+	}
+
+	if manifest.FormatVersion > FORMAT_VERSION {
+		return manifest, fmt.Errorf("Archive format %d is not supported, please upgrade paisa", manifest.FormatVersion)
+	}
+	if compareVersion(manifest.Version, config.VERSION) > 0 && !options.Force {
+		return manifest, fmt.Errorf("Archive was created by paisa %s, which is newer than %s", manifest.Version, config.VERSION)
+	}
+
+	configPath := config.GetConfigPath()
+	if content, ok := entries[configEntry]; ok {
+		_, err = backup.Create("restore archive", configPath)
+		if err != nil {
+			return manifest, fmt.Errorf("Failed to create backup: %w", err)
+		}
+
+		err = config.LoadConfig(content, "")
+		if err != nil {
+			return manifest, err
+		}
+
+		// an existing file keeps its mode, the config and the db hold
+		// credentials, so they are private to the user otherwise
+		err = os.WriteFile(configPath, content, 0600)
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	files := make(map[string]string)
+	for _, name := range manifest.Journal {
+		content, ok := entries[journalPrefix+name]
+		if !ok {
+			return manifest, fmt.Errorf("Invalid archive, journal file %s is missing", name)
+		}
+		files[filepath.FromSlash(name)] = string(content)
+	}
+	if len(files) > 0 {
+		err = journal.WriteAll(db, "restore archive", files)
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	if len(manifest.Attachments) > 0 {
+		storage, err := attachment.GetStorage()
+		if err != nil {
+			return manifest, err
+		}
+
+		for _, name := range manifest.Attachments {
+			if err := attachment.ValidateName(name); err != nil {
+				return manifest, err
+			}
+
+			err = storage.Put(name, entries[attachmentPrefix+name])
+			if err != nil {
+				return manifest, err
+			}
+		}
+	}
+
+	if options.DB && manifest.DB {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return manifest, err
+		}
+		err = sqlDB.Close()
+		if err != nil {
+			return manifest, err
+		}
+
+		err = os.WriteFile(config.GetDBPath(), entries[dbEntry], 0600)
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	log.Info("Restored archive created at ", manifest.CreatedAt)
+	return manifest, nil
+}
+
+// snapshotDB copies the database into a temporary file, which is
+// consistent even when there are writes in progress.
+func snapshotDB(db *gorm.DB) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "paisa-archive-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, dbEntry)
+	err = db.Exec("VACUUM INTO ?", path).Error
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+func writeEntry(w *tar.Writer, name string, content []byte) error {
+	err := w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), ModTime: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+func readEntries(r io.Reader) (map[string][]byte, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	entries := make(map[string][]byte)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("Invalid archive entry %s", header.Name)
+		}
+
+		content, err := io.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		entries[name] = content
+	}
+	return entries, nil
+}
+
+func compareVersion(a string, b string) int {
+	as := strings.Split(a, ".")
+	bs := strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+package attachment
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/utils"
+)
+
+var ErrNotFound = errors.New("Attachment not found")
+
+// Storage is implemented by the backends that can hold attachments.
+// Names are flat, they never contain a path separator.
+type Storage interface {
+	Put(name string, content []byte) error
+	Get(name string) ([]byte, error)
+	Delete(name string) error
+	List() ([]string, error)
+}
+
+func GetStorage() (Storage, error) {
+	c := config.GetConfig().Attachments
+	switch c.Storage {
+	case "", "local":
+		return LocalStorage{Dir: config.GetAttachmentsDir()}, nil
+	case "s3":
+		return NewS3Storage(c.S3)
+	case "webdav":
+		return NewWebDAVStorage(c.WebDAV)
+	}
+
+	return nil, fmt.Errorf("Unknown attachment storage %s", c.Storage)
+}
+
+var unsafeCharsRegex = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Name returns the name under which the file is stored. The content
+// hash is used as a prefix so uploading the same file twice doesn't
+// create a duplicate and two receipts named the same don't collide.
+func Name(fileName string, content []byte) string {
+	base := unsafeCharsRegex.ReplaceAllString(filepath.Base(fileName), "-")
+	base = strings.Trim(base, "-.")
+	if base == "" {
+		base = "attachment"
+	}
+	return utils.Sha256(string(content))[:12] + "-" + base
+}
+
+func ValidateName(name string) error {
+	if name == "" || unsafeCharsRegex.MatchString(name) || strings.HasPrefix(name, ".") {
+		return fmt.Errorf("Invalid attachment name %s", name)
+	}
+	return nil
+}
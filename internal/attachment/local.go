@@ -0,0 +1,48 @@
+package attachment
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type LocalStorage struct {
+	Dir string
+}
+
+func (s LocalStorage) Put(name string, content []byte) error {
+	return os.WriteFile(filepath.Join(s.Dir, name), content, 0640)
+}
+
+func (s LocalStorage) Get(name string) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return content, err
+}
+
+func (s LocalStorage) Delete(name string) error {
+	err := os.Remove(filepath.Join(s.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s LocalStorage) List() ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
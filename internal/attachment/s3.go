@@ -0,0 +1,215 @@
+package attachment
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+)
+
+// S3Storage talks to any S3 compatible object storage using path style
+// urls and AWS signature version 4.
+type S3Storage struct {
+	config config.S3Storage
+	client *http.Client
+}
+
+func NewS3Storage(c config.S3Storage) (S3Storage, error) {
+	if c.Endpoint == "" || c.Bucket == "" || c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return S3Storage{}, fmt.Errorf("S3 storage requires endpoint, bucket, access_key_id and secret_access_key")
+	}
+
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+
+	return S3Storage{config: c, client: &http.Client{Timeout: 60 * time.Second}}, nil
+}
+
+func (s S3Storage) Put(name string, content []byte) error {
+	_, err := s.do(http.MethodPut, s.config.Prefix+name, nil, content)
+	return err
+}
+
+func (s S3Storage) Get(name string) ([]byte, error) {
+	return s.do(http.MethodGet, s.config.Prefix+name, nil, nil)
+}
+
+func (s S3Storage) Delete(name string) error {
+	if _, err := s.Get(name); err != nil {
+		return err
+	}
+
+	_, err := s.do(http.MethodDelete, s.config.Prefix+name, nil, nil)
+	return err
+}
+
+func (s S3Storage) List() ([]string, error) {
+	type listResult struct {
+		Contents []struct {
+			Key string `xml:"Key"`
+		} `xml:"Contents"`
+		IsTruncated           bool   `xml:"IsTruncated"`
+		NextContinuationToken string `xml:"NextContinuationToken"`
+	}
+
+	names := []string{}
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.config.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+
+		body, err := s.do(http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listResult
+		err = xml.Unmarshal(body, &result)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Contents {
+			name := strings.TrimPrefix(c.Key, s.config.Prefix)
+			if name != "" && !strings.Contains(name, "/") {
+				names = append(names, name)
+			}
+		}
+
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s S3Storage) do(method string, key string, query url.Values, content []byte) ([]byte, error) {
+	endpoint, err := url.Parse(strings.TrimRight(s.config.Endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	path := endpoint.Path + "/" + s.config.Bucket
+	if key != "" {
+		path += "/" + key
+	}
+
+	escapedPath := escapePath(path)
+	rawQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+	u := endpoint.Scheme + "://" + endpoint.Host + escapedPath
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	s.sign(req, escapedPath, rawQuery, content, time.Now().UTC())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("S3 request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return body, nil
+}
+
+func (s S3Storage) sign(req *http.Request, escapedPath string, rawQuery string, content []byte, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(content)
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		escapedPath,
+		canonicalQuery(rawQuery),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	parts := strings.Split(rawQuery, "&")
+	sort.Strings(parts)
+	return strings.Join(parts, "&")
+}
+
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sha256Hex(content []byte) string {
+	h := sha256.Sum256(content)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,118 @@
+package attachment
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+)
+
+type WebDAVStorage struct {
+	config config.WebDAVStorage
+	client *http.Client
+}
+
+func NewWebDAVStorage(c config.WebDAVStorage) (WebDAVStorage, error) {
+	if c.URL == "" {
+		return WebDAVStorage{}, fmt.Errorf("WebDAV storage requires url")
+	}
+
+	if !strings.HasSuffix(c.URL, "/") {
+		c.URL += "/"
+	}
+
+	return WebDAVStorage{config: c, client: &http.Client{Timeout: 60 * time.Second}}, nil
+}
+
+func (s WebDAVStorage) Put(name string, content []byte) error {
+	_, err := s.do(http.MethodPut, name, content, nil)
+	return err
+}
+
+func (s WebDAVStorage) Get(name string) ([]byte, error) {
+	return s.do(http.MethodGet, name, nil, nil)
+}
+
+func (s WebDAVStorage) Delete(name string) error {
+	_, err := s.do(http.MethodDelete, name, nil, nil)
+	return err
+}
+
+func (s WebDAVStorage) List() ([]string, error) {
+	type multistatus struct {
+		Responses []struct {
+			Href string `xml:"href"`
+		} `xml:"response"`
+	}
+
+	body, err := s.do("PROPFIND", "", []byte(`<?xml version="1.0"?><propfind xmlns="DAV:"><prop><resourcetype/></prop></propfind>`), map[string]string{"Depth": "1", "Content-Type": "application/xml"})
+	if err != nil {
+		return nil, err
+	}
+
+	var result multistatus
+	err = xml.Unmarshal(body, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, r := range result.Responses {
+		if strings.HasSuffix(r.Href, "/") {
+			continue
+		}
+
+		name, err := url.PathUnescape(path.Base(r.Href))
+		if err != nil {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s WebDAVStorage) do(method string, name string, content []byte, headers map[string]string) ([]byte, error) {
+	req, err := http.NewRequest(method, s.config.URL+url.PathEscape(name), bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.config.Username != "" {
+		req.SetBasicAuth(s.config.Username, s.config.Password)
+	}
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("WebDAV request failed with status %d", resp.StatusCode)
+	}
+
+	return body, nil
+}
@@ -0,0 +1,262 @@
+package backup
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/encryption"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// Only the most recent backups are kept, older ones are removed when a
+// new backup is created.
+const MAX_BACKUPS = 100
+
+const manifestName = "manifest.json"
+
+type File struct {
+	Path string `json:"path"`
+	Name string `json:"name"`
+}
+
+type Backup struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Operation string    `json:"operation"`
+	Files     []File    `json:"files"`
+}
+
+var mu sync.Mutex
+
+// Create copies the given files into a new directory inside the
+// backups directory. Files that don't exist yet are skipped.
+func Create(operation string, paths ...string) (Backup, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return create(operation, paths)
+}
+
+func create(operation string, paths []string) (Backup, error) {
+	now := time.Now()
+	backupsDir := config.GetBackupsDir()
+
+	id := now.Format("2006-01-02-15-04-05.000")
+	for i := 1; exists(filepath.Join(backupsDir, id)); i++ {
+		id = fmt.Sprintf("%s-%d", now.Format("2006-01-02-15-04-05.000"), i)
+	}
+
+	b := Backup{ID: id, CreatedAt: now, Operation: operation, Files: []File{}}
+	dir := filepath.Join(backupsDir, id)
+	err := os.MkdirAll(dir, 0750)
+	if err != nil {
+		return b, err
+	}
+
+	for i, path := range paths {
+		path, err := filepath.Abs(path)
+		if err != nil {
+			return b, err
+		}
+
+		content, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return b, err
+		}
+
+		content, err = encryption.Seal(content)
+		if err != nil {
+			return b, err
+		}
+
+		name := fmt.Sprintf("%d-%s", i, filepath.Base(path))
+		err = os.WriteFile(filepath.Join(dir, name), content, 0640)
+		if err != nil {
+			return b, err
+		}
+		b.Files = append(b.Files, File{Path: path, Name: name})
+	}
+
+	manifest, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return b, err
+	}
+
+	err = os.WriteFile(filepath.Join(dir, manifestName), manifest, 0640)
+	if err != nil {
+		return b, err
+	}
+
+	prune(backupsDir)
+	return b, nil
+}
+
+func List() ([]Backup, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return list(config.GetBackupsDir())
+}
+
+func list(backupsDir string) ([]Backup, error) {
+	entries, err := os.ReadDir(backupsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []Backup{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		b, err := read(backupsDir, entry.Name())
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+		backups = append(backups, b)
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	return backups, nil
+}
+
+// Restore writes back the files of the backup to their original
+// location. The current content of those files is backed up first, so
+// a restore can be undone as well.
+func Restore(id string) (Backup, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	backupsDir := config.GetBackupsDir()
+	if err := validateID(id); err != nil {
+		return Backup{}, err
+	}
+
+	b, err := read(backupsDir, id)
+	if err != nil {
+		return b, err
+	}
+
+	paths := make([]string, len(b.Files))
+	for i, file := range b.Files {
+		paths[i] = file.Path
+	}
+
+	_, err = create("restore "+id, paths)
+	if err != nil {
+		return b, fmt.Errorf("Failed to backup current files: %w", err)
+	}
+
+	for _, file := range b.Files {
+		content, err := readFile(backupsDir, id, file)
+		if err != nil {
+			return b, err
+		}
+
+		err = os.MkdirAll(filepath.Dir(file.Path), 0700)
+		if err != nil {
+			return b, err
+		}
+
+		var perm os.FileMode = 0644
+		if stat, err := os.Stat(file.Path); err == nil {
+			perm = stat.Mode().Perm()
+		}
+
+		err = utils.WriteFileAtomic(file.Path, content, perm)
+		if err != nil {
+			return b, err
+		}
+	}
+
+	log.Info("Restored backup ", id)
+	return b, nil
+}
+
+// Get returns the backup along with the content of its files, in the
+// same order as the files.
+func Get(id string) (Backup, [][]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := validateID(id); err != nil {
+		return Backup{}, nil, err
+	}
+
+	backupsDir := config.GetBackupsDir()
+	b, err := read(backupsDir, id)
+	if err != nil {
+		return b, nil, err
+	}
+
+	contents := make([][]byte, len(b.Files))
+	for i, file := range b.Files {
+		contents[i], err = readFile(backupsDir, id, file)
+		if err != nil {
+			return b, nil, err
+		}
+	}
+	return b, contents, nil
+}
+
+func readFile(backupsDir string, id string, file File) ([]byte, error) {
+	content, err := os.ReadFile(filepath.Join(backupsDir, id, file.Name))
+	if err != nil {
+		return nil, err
+	}
+	return encryption.Unseal(content)
+}
+
+func validateID(id string) error {
+	if id == "" || strings.ContainsAny(id, `/\`) || id == ".." {
+		return fmt.Errorf("Invalid backup %s", id)
+	}
+	return nil
+}
+
+func read(backupsDir string, id string) (Backup, error) {
+	var b Backup
+	content, err := os.ReadFile(filepath.Join(backupsDir, id, manifestName))
+	if err != nil {
+		return b, err
+	}
+
+	err = json.Unmarshal(content, &b)
+	return b, err
+}
+
+func prune(backupsDir string) {
+	backups, err := list(backupsDir)
+	if err != nil {
+		log.Warn(err)
+		return
+	}
+
+	if len(backups) <= MAX_BACKUPS {
+		return
+	}
+
+	for _, b := range backups[MAX_BACKUPS:] {
+		err := os.RemoveAll(filepath.Join(backupsDir, b.ID))
+		if err != nil {
+			log.Warn(err)
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,181 @@
+// Package bankfeed pulls the transactions and the balances of the
+// linked bank accounts from the bank account data providers and turns
+// the transactions into drafts in the import review queue.
+package bankfeed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/bankconnection"
+	"github.com/ananthakumaran/paisa/internal/model/draft"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const UNKNOWN_ACCOUNT = "Assets:Unknown"
+
+// Transaction is a booked transaction as reported by the provider.
+// AccountID is the external id of the account it belongs to. Amount is
+// positive when the money comes into the account.
+type Transaction struct {
+	ID          string
+	AccountID   string
+	Date        time.Time
+	Payee       string
+	Description string
+	Amount      decimal.Decimal
+	Currency    string
+}
+
+type Balance struct {
+	Amount decimal.Decimal
+	Date   time.Time
+}
+
+type Provider interface {
+	Name() string
+	// Fetch returns the transactions of the accounts of the connection
+	// booked on or after the date and the current balances keyed by the
+	// external id of the account, if the provider reports them. The
+	// transactions fetched before a failure are returned along with the
+	// error. The changes to the connection, like the cursor of the
+	// incremental sync, are saved once the transactions are imported.
+	Fetch(connection *bankconnection.Connection, accounts []bankconnection.Account, since time.Time) ([]Transaction, map[string]Balance, error)
+}
+
+// Sync pulls the linked connections of the providers. A failing
+// connection doesn't stop the others. Returns the number of drafts
+// created.
+func Sync(db *gorm.DB, providers []Provider) (int, error) {
+	created := 0
+	var errs []error
+
+	for _, provider := range providers {
+		for _, connection := range bankconnection.ByStatus(db, provider.Name(), bankconnection.Linked) {
+			count, err := SyncConnection(db, provider, connection)
+			created += count
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	log.Infof("Imported %d drafts from the bank feeds", created)
+	return created, errors.Join(errs...)
+}
+
+// SyncConnection creates a draft for each transaction of the
+// connection that's not seen before and records the balances.
+func SyncConnection(db *gorm.DB, provider Provider, connection bankconnection.Connection) (int, error) {
+	now := utils.Now()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, -config.GetConfig().BankFeeds.LookbackDays)
+
+	accounts := bankconnection.Accounts(db, connection.ID)
+	transactions, balances, err := provider.Fetch(&connection, accounts, since)
+
+	byID := lo.KeyBy(accounts, func(a bankconnection.Account) string { return a.ExternalID })
+	created := 0
+	for _, t := range transactions {
+		account, found := byID[t.AccountID]
+		if !found || t.ID == "" || t.Date.Before(since) || draft.Exists(db, provider.Name(), t.ID) {
+			continue
+		}
+		d := buildDraft(db, provider.Name(), account, t)
+		draft.Create(db, &d)
+		created++
+	}
+
+	for _, account := range accounts {
+		if balance, found := balances[account.ExternalID]; found {
+			account.Balance = balance.Amount
+			account.BalanceDate = balance.Date
+		}
+		if err == nil {
+			account.SyncedAt = time.Now()
+		}
+		bankconnection.SaveAccount(db, &account)
+	}
+	bankconnection.Save(db, &connection)
+
+	if err != nil {
+		return created, fmt.Errorf("%s: %w", connection.Institution, err)
+	}
+	return created, nil
+}
+
+func buildDraft(db *gorm.DB, source string, account bankconnection.Account, t Transaction) draft.Draft {
+	if t.Currency == "" {
+		t.Currency = account.Currency
+	}
+	return BuildDraft(db, source, LedgerAccount(source, account), config.GetConfig().ImportRules, t)
+}
+
+// BuildDraft books the transaction against the ledger account, the
+// other side is picked by the import rules.
+func BuildDraft(db *gorm.DB, source string, ledgerAccount string, rules []config.ImportRule, t Transaction) draft.Draft {
+	debit := t.Amount.IsNegative()
+	counterAccount, payee := CategorizeWith(db, rules, t.Payee, t.Description, debit)
+	if payee == "" {
+		payee = t.Description
+	}
+
+	currency := t.Currency
+	if currency == "" {
+		currency = config.DefaultCurrency()
+	}
+
+	postings := []journal.Posting{
+		{Account: counterAccount, Amount: journal.FormatAmount(t.Amount.Neg(), currency)},
+		{Account: ledgerAccount},
+	}
+	if !debit {
+		postings = []journal.Posting{
+			{Account: ledgerAccount, Amount: journal.FormatAmount(t.Amount, currency)},
+			{Account: counterAccount},
+		}
+	}
+
+	return draft.Draft{
+		Source:     source,
+		ExternalID: t.ID,
+		Date:       t.Date,
+		Payee:      payee,
+		Account:    ledgerAccount,
+		Amount:     t.Amount,
+		Content:    journal.Transaction{Date: t.Date, Payee: payee, Postings: postings}.Format(),
+		Raw:        strings.TrimSpace(t.Payee + " " + t.Description),
+		Status:     draft.Pending,
+	}
+}
+
+// LedgerAccount maps the provider account to the journal account
+// configured for it. The match is compared with the number (IBAN or
+// the last digits), the name and the id of the account.
+func LedgerAccount(provider string, account bankconnection.Account) string {
+	for _, a := range config.GetConfig().BankFeeds.Accounts {
+		if a.Provider != "" && a.Provider != provider {
+			continue
+		}
+
+		match := strings.ReplaceAll(a.Match, " ", "")
+		if match == "" {
+			continue
+		}
+
+		number := strings.ReplaceAll(account.Number, " ", "")
+		if (number != "" && strings.HasSuffix(strings.ToUpper(number), strings.ToUpper(match))) ||
+			strings.EqualFold(account.Name, a.Match) ||
+			account.ExternalID == a.Match {
+			return a.Account
+		}
+	}
+	return UNKNOWN_ACCOUNT
+}
@@ -0,0 +1,357 @@
+// Package gocardless links the bank accounts through the GoCardless
+// Bank Account Data API (formerly Nordigen), which covers most of the
+// banks in the EU and the UK.
+package gocardless
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/bankfeed"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/bankconnection"
+	"github.com/gofrs/uuid"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	NAME     = "gocardless"
+	BASE_URL = "https://bankaccountdata.gocardless.com/api/v2"
+)
+
+// balance types in the order of preference, the booked balances match
+// the journal better than the available ones
+var balanceTypes = []string{"closingBooked", "interimBooked", "expected", "interimAvailable", "openingBooked"}
+
+var client = &http.Client{Timeout: 60 * time.Second}
+
+type Provider struct {
+	config config.GoCardless
+	// access token, valid for a day, the provider is created afresh
+	// for every operation
+	access string
+}
+
+func New() *Provider {
+	return &Provider{config: config.GetConfig().BankFeeds.GoCardless}
+}
+
+func (p *Provider) Name() string {
+	return NAME
+}
+
+func (p *Provider) Configured() bool {
+	return p.config.SecretID != "" && p.config.SecretKey != ""
+}
+
+type Institution struct {
+	ID                   string   `json:"id"`
+	Name                 string   `json:"name"`
+	BIC                  string   `json:"bic"`
+	Logo                 string   `json:"logo"`
+	Countries            []string `json:"countries"`
+	TransactionTotalDays string   `json:"transaction_total_days"`
+}
+
+type requisition struct {
+	ID       string   `json:"id"`
+	Link     string   `json:"link"`
+	Status   string   `json:"status"`
+	Accounts []string `json:"accounts"`
+}
+
+type amount struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+type balance struct {
+	BalanceAmount amount `json:"balanceAmount"`
+	BalanceType   string `json:"balanceType"`
+	ReferenceDate string `json:"referenceDate"`
+}
+
+type transaction struct {
+	TransactionID                          string   `json:"transactionId"`
+	InternalTransactionID                  string   `json:"internalTransactionId"`
+	BookingDate                            string   `json:"bookingDate"`
+	ValueDate                              string   `json:"valueDate"`
+	TransactionAmount                      amount   `json:"transactionAmount"`
+	CreditorName                           string   `json:"creditorName"`
+	DebtorName                             string   `json:"debtorName"`
+	RemittanceInformationUnstructured      string   `json:"remittanceInformationUnstructured"`
+	RemittanceInformationUnstructuredArray []string `json:"remittanceInformationUnstructuredArray"`
+}
+
+// Institutions lists the banks available in the country, given as the
+// ISO 3166 two letter code.
+func (p *Provider) Institutions(country string) ([]Institution, error) {
+	var institutions []Institution
+	err := p.request(http.MethodGet, "/institutions/?country="+url.QueryEscape(strings.ToLower(country)), nil, &institutions)
+	return institutions, err
+}
+
+// Link starts the consent flow with the institution. The user has to
+// open the returned link to authorize the access at the bank, after
+// which the bank redirects to the redirect url with the reference of
+// the connection in the ref parameter.
+func (p *Provider) Link(db *gorm.DB, institutionID string, redirect string) (string, error) {
+	reference := uuid.Must(uuid.NewV4()).String()
+	var r requisition
+	err := p.request(http.MethodPost, "/requisitions/", map[string]any{
+		"institution_id": institutionID,
+		"redirect":       redirect,
+		"reference":      reference,
+	}, &r)
+	if err != nil {
+		return "", err
+	}
+
+	bankconnection.Create(db, &bankconnection.Connection{
+		Provider:    NAME,
+		Reference:   reference,
+		ExternalID:  r.ID,
+		Institution: institutionID,
+		Status:      bankconnection.Pending,
+	})
+	return r.Link, nil
+}
+
+// Complete is called once the user is back from the bank, it records
+// the accounts the user gave access to.
+func (p *Provider) Complete(db *gorm.DB, reference string) (bankconnection.Connection, error) {
+	connection, found := bankconnection.ByReference(db, NAME, reference)
+	if !found {
+		return connection, fmt.Errorf("Unknown reference %s", reference)
+	}
+
+	var r requisition
+	err := p.request(http.MethodGet, "/requisitions/"+url.PathEscape(connection.ExternalID)+"/", nil, &r)
+	if err != nil {
+		return connection, err
+	}
+
+	switch r.Status {
+	case "LN":
+		connection.Status = bankconnection.Linked
+	case "EX", "RJ", "SU":
+		connection.Status = bankconnection.Expired
+	}
+	bankconnection.Save(db, &connection)
+
+	if connection.Status != bankconnection.Linked {
+		return connection, fmt.Errorf("Connection with %s is not linked, status: %s", connection.Institution, r.Status)
+	}
+
+	for _, id := range r.Accounts {
+		var details struct {
+			Account struct {
+				IBAN     string `json:"iban"`
+				BBAN     string `json:"bban"`
+				Name     string `json:"name"`
+				Product  string `json:"product"`
+				Currency string `json:"currency"`
+			} `json:"account"`
+		}
+		err := p.request(http.MethodGet, "/accounts/"+url.PathEscape(id)+"/details/", nil, &details)
+		if err != nil {
+			return connection, err
+		}
+
+		a := details.Account
+		bankconnection.UpsertAccount(db, &bankconnection.Account{
+			ConnectionID: connection.ID,
+			ExternalID:   id,
+			Name:         lo.Ternary(a.Name != "", a.Name, a.Product),
+			Number:       lo.Ternary(a.IBAN != "", a.IBAN, a.BBAN),
+			Currency:     a.Currency,
+		})
+	}
+
+	return connection, nil
+}
+
+// Fetch reads the accounts one by one, the banks limit the number of
+// requests per account.
+func (p *Provider) Fetch(connection *bankconnection.Connection, accounts []bankconnection.Account, since time.Time) ([]bankfeed.Transaction, map[string]bankfeed.Balance, error) {
+	transactions := []bankfeed.Transaction{}
+	balances := make(map[string]bankfeed.Balance)
+	var errs []error
+
+	for _, account := range accounts {
+		ts, err := p.fetchTransactions(account, since)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", account.Name, err))
+			continue
+		}
+		transactions = append(transactions, ts...)
+
+		balance, err := p.fetchBalance(account)
+		if err != nil {
+			// the balances are rate limited separately, the transactions
+			// are still good
+			log.Warnf("Failed to fetch the balance of %s: %v", account.Name, err)
+			continue
+		}
+		if balance != nil {
+			balances[account.ExternalID] = *balance
+		}
+	}
+
+	return transactions, balances, errors.Join(errs...)
+}
+
+func (p *Provider) fetchTransactions(account bankconnection.Account, since time.Time) ([]bankfeed.Transaction, error) {
+	var response struct {
+		Transactions struct {
+			Booked []transaction `json:"booked"`
+		} `json:"transactions"`
+	}
+	err := p.request(http.MethodGet, "/accounts/"+url.PathEscape(account.ExternalID)+"/transactions/?date_from="+since.Format("2006-01-02"), nil, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := []bankfeed.Transaction{}
+	for _, t := range response.Transactions.Booked {
+		date, err := time.ParseInLocation("2006-01-02", lo.Ternary(t.BookingDate != "", t.BookingDate, t.ValueDate), config.TimeZone())
+		if err != nil {
+			continue
+		}
+
+		value := t.TransactionAmount.Amount
+		description := t.RemittanceInformationUnstructured
+		if description == "" {
+			description = strings.Join(t.RemittanceInformationUnstructuredArray, " ")
+		}
+
+		transactions = append(transactions, bankfeed.Transaction{
+			ID:          transactionID(account, t),
+			AccountID:   account.ExternalID,
+			Date:        date,
+			Payee:       lo.Ternary(value.IsNegative(), t.CreditorName, t.DebtorName),
+			Description: description,
+			Amount:      value,
+			Currency:    t.TransactionAmount.Currency,
+		})
+	}
+	return transactions, nil
+}
+
+func (p *Provider) fetchBalance(account bankconnection.Account) (*bankfeed.Balance, error) {
+	var balances struct {
+		Balances []balance `json:"balances"`
+	}
+	err := p.request(http.MethodGet, "/accounts/"+url.PathEscape(account.ExternalID)+"/balances/", nil, &balances)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, balanceType := range balanceTypes {
+		b, found := lo.Find(balances.Balances, func(b balance) bool { return b.BalanceType == balanceType })
+		if !found {
+			continue
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", b.ReferenceDate, config.TimeZone())
+		if err != nil {
+			date = time.Now()
+		}
+		return &bankfeed.Balance{Amount: b.BalanceAmount.Amount, Date: date}, nil
+	}
+	return nil, nil
+}
+
+// transactionID is the id given by the bank, some banks only provide
+// the internal id of the provider and a few neither.
+func transactionID(account bankconnection.Account, t transaction) string {
+	id := t.TransactionID
+	if id == "" {
+		id = t.InternalTransactionID
+	}
+	if id == "" {
+		sum := sha256.Sum256([]byte(strings.Join([]string{t.BookingDate, t.TransactionAmount.Amount.String(), t.CreditorName, t.DebtorName, t.RemittanceInformationUnstructured}, "|")))
+		id = hex.EncodeToString(sum[:])
+	}
+	return account.ExternalID + ":" + id
+}
+
+func (p *Provider) token() (string, error) {
+	if p.access != "" {
+		return p.access, nil
+	}
+
+	var token struct {
+		Access string `json:"access"`
+	}
+	err := p.do(http.MethodPost, "/token/new/", "", map[string]string{"secret_id": p.config.SecretID, "secret_key": p.config.SecretKey}, &token)
+	if err != nil {
+		return "", err
+	}
+	p.access = token.Access
+	return p.access, nil
+}
+
+func (p *Provider) request(method string, path string, body any, out any) error {
+	if !p.Configured() {
+		return fmt.Errorf("GoCardless is not configured")
+	}
+
+	token, err := p.token()
+	if err != nil {
+		return err
+	}
+	return p.do(method, path, token, body, out)
+}
+
+func (p *Provider) do(method string, path string, token string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequest(method, BASE_URL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Summary string `json:"summary"`
+			Detail  string `json:"detail"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		return fmt.Errorf("GoCardless request failed: status %d %s %s", resp.StatusCode, failure.Summary, failure.Detail)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
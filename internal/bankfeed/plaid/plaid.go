@@ -0,0 +1,300 @@
+// Package plaid links the bank accounts through Plaid, which covers
+// the banks in the US and Canada.
+package plaid
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/bankfeed"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/bankconnection"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const NAME = "plaid"
+
+// page size of the transactions sync, the maximum allowed
+const SYNC_BATCH_SIZE = 500
+
+var client = &http.Client{Timeout: 60 * time.Second}
+
+type Provider struct {
+	config config.Plaid
+}
+
+func New() *Provider {
+	return &Provider{config: config.GetConfig().BankFeeds.Plaid}
+}
+
+func (p *Provider) Name() string {
+	return NAME
+}
+
+func (p *Provider) Configured() bool {
+	return p.config.ClientID != "" && p.config.Secret != ""
+}
+
+func (p *Provider) baseURL() string {
+	return fmt.Sprintf("https://%s.plaid.com", p.config.Environment)
+}
+
+// Error is the error returned by the Plaid API.
+type Error struct {
+	Type    string `json:"error_type"`
+	Code    string `json:"error_code"`
+	Message string `json:"error_message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("Plaid request failed: %s %s", e.Code, e.Message)
+}
+
+// the codes that need the user to go through the link again
+var relinkCodes = []string{"ITEM_LOGIN_REQUIRED", "PENDING_EXPIRATION", "USER_PERMISSION_REVOKED", "ITEM_NOT_FOUND"}
+
+type account struct {
+	AccountID    string `json:"account_id"`
+	Name         string `json:"name"`
+	OfficialName string `json:"official_name"`
+	Mask         string `json:"mask"`
+	Balances     struct {
+		Current                decimal.NullDecimal `json:"current"`
+		ISOCurrencyCode        string              `json:"iso_currency_code"`
+		UnofficialCurrencyCode string              `json:"unofficial_currency_code"`
+	} `json:"balances"`
+}
+
+type transaction struct {
+	TransactionID          string          `json:"transaction_id"`
+	AccountID              string          `json:"account_id"`
+	Amount                 decimal.Decimal `json:"amount"`
+	ISOCurrencyCode        string          `json:"iso_currency_code"`
+	UnofficialCurrencyCode string          `json:"unofficial_currency_code"`
+	Date                   string          `json:"date"`
+	Name                   string          `json:"name"`
+	MerchantName           string          `json:"merchant_name"`
+	Pending                bool            `json:"pending"`
+}
+
+// LinkToken creates the token to open Plaid Link with on the browser.
+// The webhook is notified whenever new transactions are available.
+func (p *Provider) LinkToken(webhook string) (string, error) {
+	body := map[string]any{
+		"client_name":   "Paisa",
+		"user":          map[string]string{"client_user_id": "paisa"},
+		"products":      []string{"transactions"},
+		"country_codes": []string{"US", "CA"},
+		"language":      "en",
+		"transactions":  map[string]int{"days_requested": lo.Max([]int{config.GetConfig().BankFeeds.LookbackDays, 30})},
+	}
+	if webhook != "" {
+		body["webhook"] = webhook
+	}
+
+	var response struct {
+		LinkToken string `json:"link_token"`
+	}
+	err := p.request("/link/token/create", body, &response)
+	return response.LinkToken, err
+}
+
+// Exchange trades the public token handed out by Plaid Link at the
+// end of the flow for the access token, which is stored sealed along
+// with the accounts of the item.
+func (p *Provider) Exchange(db *gorm.DB, publicToken string, institution string) (bankconnection.Connection, error) {
+	var exchange struct {
+		AccessToken string `json:"access_token"`
+		ItemID      string `json:"item_id"`
+	}
+	err := p.request("/item/public_token/exchange", map[string]string{"public_token": publicToken}, &exchange)
+	if err != nil {
+		return bankconnection.Connection{}, err
+	}
+
+	connection, found := bankconnection.ByReference(db, NAME, exchange.ItemID)
+	if !found {
+		connection = bankconnection.Connection{Provider: NAME, Reference: exchange.ItemID, ExternalID: exchange.ItemID}
+	}
+	connection.Status = bankconnection.Linked
+	if institution != "" {
+		connection.Institution = institution
+	}
+	err = connection.SetSecret(exchange.AccessToken)
+	if err != nil {
+		return connection, err
+	}
+	bankconnection.Save(db, &connection)
+
+	accounts, err := p.accounts(exchange.AccessToken)
+	if err != nil {
+		return connection, err
+	}
+	for _, a := range accounts {
+		bankconnection.UpsertAccount(db, &bankconnection.Account{
+			ConnectionID: connection.ID,
+			ExternalID:   a.AccountID,
+			Name:         lo.Ternary(a.OfficialName != "", a.OfficialName, a.Name),
+			Number:       a.Mask,
+			Currency:     currency(a.Balances.ISOCurrencyCode, a.Balances.UnofficialCurrencyCode),
+		})
+	}
+
+	return connection, nil
+}
+
+// Fetch pulls the changes since the cursor of the last sync, the
+// first sync starts with the history requested while linking. Only
+// the added transactions are of interest, the pending ones are
+// skipped as they are added again once posted.
+func (p *Provider) Fetch(connection *bankconnection.Connection, accounts []bankconnection.Account, since time.Time) ([]bankfeed.Transaction, map[string]bankfeed.Balance, error) {
+	token, err := connection.OpenSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	added, cursor, err := p.sync(token, connection.Cursor)
+	if err != nil {
+		p.expireOn(connection, err)
+		return nil, nil, err
+	}
+
+	transactions := []bankfeed.Transaction{}
+	for _, t := range added {
+		if t.Pending {
+			continue
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", t.Date, config.TimeZone())
+		if err != nil {
+			continue
+		}
+
+		transactions = append(transactions, bankfeed.Transaction{
+			ID:          t.TransactionID,
+			AccountID:   t.AccountID,
+			Date:        date,
+			Payee:       lo.Ternary(t.MerchantName != "", t.MerchantName, t.Name),
+			Description: t.Name,
+			// plaid reports the money going out of the account as positive
+			Amount:   t.Amount.Neg(),
+			Currency: currency(t.ISOCurrencyCode, t.UnofficialCurrencyCode),
+		})
+	}
+	connection.Cursor = cursor
+
+	balances := make(map[string]bankfeed.Balance)
+	latest, err := p.accounts(token)
+	if err != nil {
+		p.expireOn(connection, err)
+		return transactions, balances, err
+	}
+	for _, a := range latest {
+		if a.Balances.Current.Valid {
+			balances[a.AccountID] = bankfeed.Balance{Amount: a.Balances.Current.Decimal, Date: time.Now()}
+		}
+	}
+
+	return transactions, balances, nil
+}
+
+// sync pages through the transactions sync. The pagination has to be
+// restarted from the original cursor if the data changes in between.
+func (p *Provider) sync(token string, cursor string) ([]transaction, string, error) {
+	for attempt := 1; ; attempt++ {
+		added := []transaction{}
+		next := cursor
+		for {
+			var response struct {
+				Added      []transaction `json:"added"`
+				NextCursor string        `json:"next_cursor"`
+				HasMore    bool          `json:"has_more"`
+			}
+			body := map[string]any{"access_token": token, "count": SYNC_BATCH_SIZE}
+			if next != "" {
+				body["cursor"] = next
+			}
+			err := p.request("/transactions/sync", body, &response)
+			if err != nil {
+				if e, ok := err.(*Error); ok && e.Code == "TRANSACTIONS_SYNC_MUTATION_DURING_PAGINATION" && attempt < 3 {
+					break
+				}
+				return nil, cursor, err
+			}
+
+			added = append(added, response.Added...)
+			next = response.NextCursor
+			if !response.HasMore {
+				return added, next, nil
+			}
+		}
+	}
+}
+
+func (p *Provider) accounts(token string) ([]account, error) {
+	var response struct {
+		Accounts []account `json:"accounts"`
+	}
+	err := p.request("/accounts/get", map[string]string{"access_token": token}, &response)
+	return response.Accounts, err
+}
+
+// expireOn marks the connection expired when the item needs the user to
+// link it again.
+func (p *Provider) expireOn(connection *bankconnection.Connection, err error) {
+	if e, ok := err.(*Error); ok && lo.Contains(relinkCodes, e.Code) {
+		connection.Status = bankconnection.Expired
+	}
+}
+
+func currency(iso string, unofficial string) string {
+	return lo.Ternary(iso != "", iso, unofficial)
+}
+
+func (p *Provider) request(path string, body any, out any) error {
+	if !p.Configured() {
+		return fmt.Errorf("Plaid is not configured")
+	}
+
+	payload := map[string]any{"client_id": p.config.ClientID, "secret": p.config.Secret}
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(content, &payload)
+	if err != nil {
+		return err
+	}
+	content, err = json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.baseURL()+path, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		failure := &Error{}
+		err = json.NewDecoder(resp.Body).Decode(failure)
+		if err != nil {
+			return fmt.Errorf("Plaid request failed: status %d", resp.StatusCode)
+		}
+		return failure
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
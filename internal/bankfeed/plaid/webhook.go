@@ -0,0 +1,165 @@
+package plaid
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const VERIFICATION_HEADER = "Plaid-Verification"
+
+// webhooks older than this are rejected to limit replays
+const maxWebhookAge = 5 * time.Minute
+
+type Webhook struct {
+	Type   string `json:"webhook_type"`
+	Code   string `json:"webhook_code"`
+	ItemID string `json:"item_id"`
+	Error  *Error `json:"error"`
+}
+
+// NeedsSync reports whether new transactions are available for the
+// item.
+func (w Webhook) NeedsSync() bool {
+	return w.Type == "TRANSACTIONS" && w.Code == "SYNC_UPDATES_AVAILABLE"
+}
+
+// NeedsRelink reports whether the user has to link the item again.
+func (w Webhook) NeedsRelink() bool {
+	if w.Type != "ITEM" {
+		return false
+	}
+	switch w.Code {
+	case "PENDING_EXPIRATION", "USER_PERMISSION_REVOKED":
+		return true
+	case "ERROR":
+		return w.Error != nil && w.Error.Code == "ITEM_LOGIN_REQUIRED"
+	}
+	return false
+}
+
+var (
+	keys   = make(map[string]*ecdsa.PublicKey)
+	keysMu sync.Mutex
+)
+
+// VerifyWebhook checks the signature Plaid sends along with the
+// webhook, a JWT signed with ES256 that carries the hash of the body,
+// and returns the parsed webhook.
+func (p *Provider) VerifyWebhook(token string, body []byte) (Webhook, error) {
+	var webhook Webhook
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return webhook, errors.New("Missing or malformed webhook verification token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	err := decodeSegment(parts[0], &header)
+	if err != nil {
+		return webhook, err
+	}
+	if header.Alg != "ES256" {
+		return webhook, fmt.Errorf("Unexpected webhook signature algorithm %s", header.Alg)
+	}
+
+	key, err := p.verificationKey(header.Kid)
+	if err != nil {
+		return webhook, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		return webhook, errors.New("Malformed webhook signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		return webhook, errors.New("Invalid webhook signature")
+	}
+
+	var claims struct {
+		IssuedAt          int64  `json:"iat"`
+		RequestBodySHA256 string `json:"request_body_sha256"`
+	}
+	err = decodeSegment(parts[1], &claims)
+	if err != nil {
+		return webhook, err
+	}
+	if time.Since(time.Unix(claims.IssuedAt, 0)) > maxWebhookAge {
+		return webhook, errors.New("Webhook is too old")
+	}
+
+	sum := sha256.Sum256(body)
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(claims.RequestBodySHA256)) != 1 {
+		return webhook, errors.New("Webhook body doesn't match the signature")
+	}
+
+	err = json.Unmarshal(body, &webhook)
+	return webhook, err
+}
+
+// verificationKey fetches the public key the webhook was signed with,
+// the keys are cached until they are rotated.
+func (p *Provider) verificationKey(id string) (*ecdsa.PublicKey, error) {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+
+	if key, ok := keys[id]; ok {
+		return key, nil
+	}
+
+	var response struct {
+		Key struct {
+			Crv       string `json:"crv"`
+			X         string `json:"x"`
+			Y         string `json:"y"`
+			ExpiredAt *int64 `json:"expired_at"`
+		} `json:"key"`
+	}
+	err := p.request("/webhook_verification_key/get", map[string]string{"key_id": id}, &response)
+	if err != nil {
+		return nil, err
+	}
+	if response.Key.Crv != "P-256" {
+		return nil, fmt.Errorf("Unexpected webhook verification key curve %s", response.Key.Crv)
+	}
+	if response.Key.ExpiredAt != nil {
+		return nil, errors.New("Webhook verification key has expired")
+	}
+
+	x, err := base64.RawURLEncoding.DecodeString(response.Key.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := base64.RawURLEncoding.DecodeString(response.Key.Y)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+	keys[id] = key
+	return key, nil
+}
+
+func decodeSegment(segment string, out any) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, out)
+}
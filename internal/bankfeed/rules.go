@@ -0,0 +1,49 @@
+package bankfeed
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"gorm.io/gorm"
+)
+
+// Categorize picks the account to book the other side of an imported
+// transaction against, and the payee to record it with. The first
+// import rule matching the payee or the description wins. Without a
+// matching rule, the account the payee was last booked against is
+// used, falling back to the unknown expense or income account.
+func Categorize(db *gorm.DB, payee string, description string, debit bool) (string, string) {
+	return CategorizeWith(db, config.GetConfig().ImportRules, payee, description, debit)
+}
+
+// CategorizeWith is Categorize with the given import rules instead of
+// the configured ones.
+func CategorizeWith(db *gorm.DB, rules []config.ImportRule, payee string, description string, debit bool) (string, string) {
+	text := strings.TrimSpace(payee + " " + description)
+	for _, rule := range rules {
+		regex, err := regexp.Compile("(?i)" + rule.Match)
+		if err != nil || !regex.MatchString(text) {
+			continue
+		}
+
+		if rule.Payee != "" {
+			payee = rule.Payee
+		}
+		return rule.Account, payee
+	}
+
+	prefix, fallback := "Expenses:%", "Expenses:Unknown"
+	if !debit {
+		prefix, fallback = "Income:%", "Income:Unknown"
+	}
+
+	if payee != "" {
+		p := query.Init(db).IncludeExcluded().Payee(payee).Like(prefix).Desc().First()
+		if p != nil {
+			return p.Account, payee
+		}
+	}
+	return fallback, payee
+}
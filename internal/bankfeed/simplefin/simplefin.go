@@ -0,0 +1,231 @@
+// Package simplefin links the bank accounts through a SimpleFIN
+// bridge. Unlike the other providers, it needs no credentials in the
+// configuration, the user pastes the setup token from the bridge.
+package simplefin
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/bankfeed"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/bankconnection"
+	"github.com/gofrs/uuid"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const NAME = "simplefin"
+
+var client = &http.Client{Timeout: 60 * time.Second}
+
+// ErrAccessRevoked is returned when the bridge no longer accepts the
+// access url, the setup has to be done again.
+var ErrAccessRevoked = errors.New("SimpleFIN access was revoked")
+
+type Provider struct{}
+
+func New() *Provider {
+	return &Provider{}
+}
+
+func (p *Provider) Name() string {
+	return NAME
+}
+
+type account struct {
+	Org struct {
+		Name   string `json:"name"`
+		Domain string `json:"domain"`
+	} `json:"org"`
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	Currency     string          `json:"currency"`
+	Balance      decimal.Decimal `json:"balance"`
+	BalanceDate  int64           `json:"balance-date"`
+	Transactions []transaction   `json:"transactions"`
+}
+
+type transaction struct {
+	ID          string          `json:"id"`
+	Posted      int64           `json:"posted"`
+	Amount      decimal.Decimal `json:"amount"`
+	Description string          `json:"description"`
+	Payee       string          `json:"payee"`
+	Pending     bool            `json:"pending"`
+}
+
+// Link claims the setup token, which can be used only once, and
+// stores the access url it's exchanged for along with the accounts.
+func (p *Provider) Link(db *gorm.DB, setupToken string) (bankconnection.Connection, error) {
+	claim, err := base64.StdEncoding.DecodeString(strings.TrimSpace(setupToken))
+	if err != nil {
+		return bankconnection.Connection{}, fmt.Errorf("Invalid setup token: %w", err)
+	}
+	claimURL, err := url.Parse(string(claim))
+	if err != nil || claimURL.Scheme != "https" {
+		return bankconnection.Connection{}, errors.New("Invalid setup token, expected a https claim url")
+	}
+
+	resp, err := client.Post(claimURL.String(), "text/plain", nil)
+	if err != nil {
+		return bankconnection.Connection{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return bankconnection.Connection{}, fmt.Errorf("Failed to claim the setup token, status %d, it can be claimed only once", resp.StatusCode)
+	}
+	access, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return bankconnection.Connection{}, err
+	}
+
+	accessURL := strings.TrimSpace(string(access))
+	accounts, problems, err := p.accounts(accessURL, url.Values{"balances-only": {"1"}})
+	if err != nil {
+		return bankconnection.Connection{}, err
+	}
+
+	connection := bankconnection.Connection{
+		Provider:    NAME,
+		Reference:   uuid.Must(uuid.NewV4()).String(),
+		ExternalID:  claimURL.Host,
+		Institution: strings.Join(lo.Uniq(lo.Map(accounts, func(a account, _ int) string { return a.Org.Name })), ", "),
+		Status:      bankconnection.Linked,
+	}
+	err = connection.SetSecret(accessURL)
+	if err != nil {
+		return connection, err
+	}
+	bankconnection.Create(db, &connection)
+
+	for _, a := range accounts {
+		bankconnection.UpsertAccount(db, &bankconnection.Account{
+			ConnectionID: connection.ID,
+			ExternalID:   a.ID,
+			Name:         a.Name,
+			Currency:     currency(a.Currency),
+			Balance:      a.Balance,
+			BalanceDate:  time.Unix(a.BalanceDate, 0),
+		})
+	}
+
+	for _, problem := range problems {
+		log.Warnf("SimpleFIN: %s", problem)
+	}
+	return connection, nil
+}
+
+// Fetch reads all the accounts of the connection in one request. The
+// bridge reports the problems with the individual banks along with the
+// data of the others.
+func (p *Provider) Fetch(connection *bankconnection.Connection, accounts []bankconnection.Account, since time.Time) ([]bankfeed.Transaction, map[string]bankfeed.Balance, error) {
+	accessURL, err := connection.OpenSecret()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	latest, problems, err := p.accounts(accessURL, url.Values{"start-date": {strconv.FormatInt(since.Unix(), 10)}})
+	if err != nil {
+		if errors.Is(err, ErrAccessRevoked) {
+			connection.Status = bankconnection.Expired
+		}
+		return nil, nil, err
+	}
+
+	transactions := []bankfeed.Transaction{}
+	balances := make(map[string]bankfeed.Balance)
+	for _, a := range latest {
+		if !lo.ContainsBy(accounts, func(known bankconnection.Account) bool { return known.ExternalID == a.ID }) {
+			log.Warnf("SimpleFIN account %s of %s was added after the setup, set it up again to import it", a.Name, a.Org.Name)
+			continue
+		}
+
+		balances[a.ID] = bankfeed.Balance{Amount: a.Balance, Date: time.Unix(a.BalanceDate, 0)}
+
+		for _, t := range a.Transactions {
+			if t.Pending || t.Posted == 0 {
+				continue
+			}
+
+			transactions = append(transactions, bankfeed.Transaction{
+				ID:          a.ID + ":" + t.ID,
+				AccountID:   a.ID,
+				Date:        time.Unix(t.Posted, 0).In(config.TimeZone()),
+				Payee:       lo.Ternary(t.Payee != "", t.Payee, t.Description),
+				Description: t.Description,
+				Amount:      t.Amount,
+				Currency:    currency(a.Currency),
+			})
+		}
+	}
+
+	return transactions, balances, joinProblems(problems)
+}
+
+// accounts returns the accounts along with the problems reported by
+// the bridge, the error is set only if the request failed as a whole.
+func (p *Provider) accounts(accessURL string, params url.Values) ([]account, []string, error) {
+	u, err := url.Parse(accessURL)
+	if err != nil || u.User == nil {
+		return nil, nil, errors.New("Invalid SimpleFIN access url")
+	}
+	username := u.User.Username()
+	password, _ := u.User.Password()
+	u.User = nil
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/accounts"
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.SetBasicAuth(username, password)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden {
+		return nil, nil, ErrAccessRevoked
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("SimpleFIN request failed: status %d", resp.StatusCode)
+	}
+
+	var response struct {
+		Errors   []string  `json:"errors"`
+		Accounts []account `json:"accounts"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response.Accounts, response.Errors, nil
+}
+
+func joinProblems(problems []string) error {
+	return errors.Join(lo.Map(problems, func(problem string, _ int) error { return errors.New(problem) })...)
+}
+
+// currency is either an ISO 4217 code or the url of a custom currency,
+// the latter is left to the default currency.
+func currency(code string) string {
+	if len(code) == 3 {
+		return strings.ToUpper(code)
+	}
+	return ""
+}
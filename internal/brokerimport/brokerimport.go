@@ -0,0 +1,285 @@
+// Package brokerimport turns the trade files of the brokers into
+// drafts in the import review queue. The sales are booked against the
+// lots held in the journal in the FIFO order, along with the capital
+// gains.
+package brokerimport
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/draft"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	ZERODHA = "zerodha"
+	IBKR    = "ibkr"
+)
+
+var symbolRegex = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// Trade is an order executed at the broker, the executions of the same
+// order are merged. Charges are the brokerage and the taxes paid on
+// the trade.
+type Trade struct {
+	ID       string
+	Date     time.Time
+	Symbol   string
+	Buy      bool
+	Quantity decimal.Decimal
+	Price    decimal.Decimal
+	Currency string
+	Charges  decimal.Decimal
+}
+
+// Dividend is the gross amount declared, Tax is the amount withheld.
+type Dividend struct {
+	ID       string
+	Date     time.Time
+	Symbol   string
+	Amount   decimal.Decimal
+	Tax      decimal.Decimal
+	Currency string
+}
+
+// Charge is a fee not tied to a trade, like the charges for a period
+// reported in the P&L statement.
+type Charge struct {
+	ID          string
+	Date        time.Time
+	Description string
+	Amount      decimal.Decimal
+	Currency    string
+}
+
+type Statement struct {
+	Trades    []Trade
+	Dividends []Dividend
+	Charges   []Charge
+}
+
+// Parse reads the file downloaded from the broker, the format is
+// detected from the content.
+func Parse(broker string, content string) (Statement, error) {
+	content = strings.TrimPrefix(content, "\uFEFF")
+	switch broker {
+	case ZERODHA:
+		if strings.Contains(strings.SplitN(content, "\n", 2)[0], "trade_id") {
+			return parseZerodhaTradebook(content)
+		}
+		return parseZerodhaPnL(content)
+	case IBKR:
+		return parseFlexStatement([]byte(content))
+	}
+	return Statement{}, fmt.Errorf("Unknown broker %s", broker)
+}
+
+// Drafts builds a draft for each trade, dividend and charge in the
+// statement that's not seen before. The drafts of an earlier statement
+// should be accepted before importing the next one, the lots are
+// looked up in the journal.
+func Drafts(db *gorm.DB, broker config.BrokerImport, statement Statement) []draft.Draft {
+	source := broker.Broker
+	drafts := []draft.Draft{}
+
+	trades := mergeTrades(statement.Trades)
+	sort.SliceStable(trades, func(i, j int) bool {
+		// buys first on the same day, so the intraday sales find the lots
+		if trades[i].Date.Equal(trades[j].Date) {
+			return trades[i].Buy && !trades[j].Buy
+		}
+		return trades[i].Date.Before(trades[j].Date)
+	})
+
+	holdings := make(map[string][]lot)
+	for _, t := range trades {
+		account := assetAccount(broker, t.Symbol)
+		if _, ok := holdings[account]; !ok {
+			holdings[account] = openLots(db, account)
+		}
+
+		if draft.Exists(db, source, t.ID) {
+			continue
+		}
+
+		var postings []journal.Posting
+		if t.Buy {
+			postings, holdings[account] = buyPostings(broker, account, t, holdings[account])
+		} else {
+			postings, holdings[account] = sellPostings(broker, account, t, holdings[account])
+		}
+
+		payee := fmt.Sprintf("%s %s %s", lo.Ternary(t.Buy, "Buy", "Sell"), t.Quantity.String(), t.Symbol)
+		drafts = append(drafts, newDraft(source, t.ID, t.Date, payee, account, t.Quantity.Mul(t.Price), postings))
+	}
+
+	for _, d := range statement.Dividends {
+		if draft.Exists(db, source, d.ID) {
+			continue
+		}
+
+		postings := []journal.Posting{
+			{Account: broker.CashAccount, Amount: journal.FormatAmount(d.Amount.Sub(d.Tax), currency(d.Currency))},
+		}
+		if !d.Tax.IsZero() {
+			postings = append(postings, journal.Posting{Account: lo.Ternary(broker.TaxAccount != "", broker.TaxAccount, config.TaxAccounts()[0]), Amount: journal.FormatAmount(d.Tax, currency(d.Currency))})
+		}
+		postings = append(postings, journal.Posting{Account: dividendAccount(broker, d.Symbol)})
+		drafts = append(drafts, newDraft(source, d.ID, d.Date, "Dividend "+d.Symbol, broker.CashAccount, d.Amount, postings))
+	}
+
+	for _, c := range statement.Charges {
+		if draft.Exists(db, source, c.ID) {
+			continue
+		}
+
+		postings := []journal.Posting{
+			{Account: chargesAccount(broker), Amount: journal.FormatAmount(c.Amount, currency(c.Currency))},
+			{Account: broker.CashAccount},
+		}
+		drafts = append(drafts, newDraft(source, c.ID, c.Date, c.Description, broker.CashAccount, c.Amount.Neg(), postings))
+	}
+
+	return drafts
+}
+
+type lot struct {
+	date     time.Time
+	quantity decimal.Decimal
+	price    decimal.Decimal
+}
+
+func openLots(db *gorm.DB, account string) []lot {
+	postings := query.Init(db).IncludeExcluded().Where("account = ?", account).All()
+	return lo.Map(accounting.FIFO(postings), func(p posting.Posting, _ int) lot {
+		return lot{date: p.Date, quantity: p.Quantity, price: p.Price()}
+	})
+}
+
+func buyPostings(broker config.BrokerImport, account string, t Trade, lots []lot) ([]journal.Posting, []lot) {
+	postings := []journal.Posting{
+		{Account: account, Amount: fmt.Sprintf("%s @ %s", journal.FormatAmount(t.Quantity, t.Symbol), journal.FormatAmount(t.Price, currency(t.Currency)))},
+	}
+	if !t.Charges.IsZero() {
+		postings = append(postings, journal.Posting{Account: chargesAccount(broker), Amount: journal.FormatAmount(t.Charges, currency(t.Currency))})
+	}
+	postings = append(postings, journal.Posting{Account: broker.CashAccount})
+
+	return postings, append(lots, lot{date: t.Date, quantity: t.Quantity, price: t.Price})
+}
+
+// sellPostings books the sale against the oldest lots first. The
+// quantity not found in the journal is sold at the sale price, with a
+// note to fill in the cost.
+func sellPostings(broker config.BrokerImport, account string, t Trade, lots []lot) ([]journal.Posting, []lot) {
+	var lotPostings []journal.Posting
+	gain := decimal.Zero
+	remaining := t.Quantity
+	for remaining.IsPositive() && len(lots) > 0 {
+		first := lots[0]
+		quantity := decimal.Min(first.quantity, remaining)
+		gain = gain.Add(t.Price.Sub(first.price).Mul(quantity))
+		lotPostings = append(lotPostings, journal.Posting{
+			Account: account,
+			Amount: fmt.Sprintf("%s {%s} [%s] @ %s",
+				journal.FormatAmount(quantity.Neg(), t.Symbol),
+				journal.FormatAmount(first.price, currency(t.Currency)),
+				first.date.Format("2006/01/02"),
+				journal.FormatAmount(t.Price, currency(t.Currency))),
+		})
+
+		remaining = remaining.Sub(quantity)
+		if quantity.Equal(first.quantity) {
+			lots = lots[1:]
+		} else {
+			first.quantity = first.quantity.Sub(quantity)
+			lots[0] = first
+		}
+	}
+
+	if remaining.IsPositive() {
+		lotPostings = append(lotPostings, journal.Posting{
+			Account: account,
+			Amount:  fmt.Sprintf("%s @ %s", journal.FormatAmount(remaining.Neg(), t.Symbol), journal.FormatAmount(t.Price, currency(t.Currency))),
+			Note:    "lot not found in the journal, fill in the cost",
+		})
+		log.Warnf("Sold %s %s more than held in %s", remaining.String(), t.Symbol, account)
+	}
+
+	postings := []journal.Posting{{Account: broker.CashAccount}}
+	if !t.Charges.IsZero() {
+		postings = append(postings, journal.Posting{Account: chargesAccount(broker), Amount: journal.FormatAmount(t.Charges, currency(t.Currency))})
+	}
+	if !gain.IsZero() {
+		postings = append(postings, journal.Posting{Account: config.CapitalGainsAccount() + ":" + strings.TrimPrefix(account, "Assets:"), Amount: journal.FormatAmount(gain.Neg().Round(4), currency(t.Currency))})
+	}
+	return append(postings, lotPostings...), lots
+}
+
+// mergeTrades combines the executions of the same order, the price is
+// the average weighted by the quantity.
+func mergeTrades(trades []Trade) []Trade {
+	merged := []Trade{}
+	index := make(map[string]int)
+	for _, t := range trades {
+		i, ok := index[t.ID]
+		if !ok {
+			index[t.ID] = len(merged)
+			merged = append(merged, t)
+			continue
+		}
+
+		m := merged[i]
+		quantity := m.Quantity.Add(t.Quantity)
+		m.Price = m.Price.Mul(m.Quantity).Add(t.Price.Mul(t.Quantity)).Div(quantity).Round(4)
+		m.Quantity = quantity
+		m.Charges = m.Charges.Add(t.Charges)
+		merged[i] = m
+	}
+	return merged
+}
+
+func newDraft(source string, id string, date time.Time, payee string, account string, amount decimal.Decimal, postings []journal.Posting) draft.Draft {
+	return draft.Draft{
+		Source:     source,
+		ExternalID: id,
+		Date:       date,
+		Payee:      payee,
+		Account:    account,
+		Amount:     amount,
+		Content:    journal.Transaction{Date: date, Payee: payee, Postings: postings}.Format(),
+		Status:     draft.Pending,
+	}
+}
+
+func assetAccount(broker config.BrokerImport, symbol string) string {
+	return broker.AssetAccount + ":" + sanitize(symbol)
+}
+
+func dividendAccount(broker config.BrokerImport, symbol string) string {
+	return lo.Ternary(broker.DividendAccount != "", broker.DividendAccount, "Income:Dividend") + ":" + sanitize(symbol)
+}
+
+func chargesAccount(broker config.BrokerImport) string {
+	return lo.Ternary(broker.ChargesAccount != "", broker.ChargesAccount, "Expenses:Charges")
+}
+
+func sanitize(symbol string) string {
+	return strings.Trim(symbolRegex.ReplaceAllString(symbol, "_"), "_")
+}
+
+func currency(code string) string {
+	return lo.Ternary(code != "", code, config.DefaultCurrency())
+}
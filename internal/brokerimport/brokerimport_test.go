@@ -0,0 +1,88 @@
+package brokerimport
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseZerodha(t *testing.T) {
+	statement, err := Parse(ZERODHA, `symbol,isin,trade_date,exchange,segment,series,trade_type,auction,quantity,price,trade_id,order_id,order_execution_time
+INFY,INE009A01021,2024-01-05,NSE,EQ,EQ,buy,false,6.000000,1500.00,1001,2001,2024-01-05T09:20:01
+INFY,INE009A01021,2024-01-05,NSE,EQ,EQ,buy,false,4.000000,1510.00,1002,2001,2024-01-05T09:20:02
+INFY,INE009A01021,2024-03-05,NSE,EQ,EQ,sell,false,10.000000,1600.00,1003,2002,2024-03-05T10:00:00
+`)
+	assert.NoError(t, err)
+	trades := mergeTrades(statement.Trades)
+	assert.Len(t, trades, 2)
+	assert.Equal(t, "2001", trades[0].ID)
+	assert.True(t, trades[0].Buy)
+	assert.Equal(t, "10", trades[0].Quantity.String())
+	assert.Equal(t, "1504", trades[0].Price.String())
+	assert.False(t, trades[1].Buy)
+
+	statement, err = Parse(ZERODHA, `Client ID,AB1234
+P&L Statement for Equity from 2023-04-01 to 2024-03-31
+
+Charges,Amount
+Brokerage - Z,12.50
+Exchange Transaction Charges - Z,3.25
+Securities Transaction Tax - Z,"1,024.00"
+
+Symbol,ISIN,Ex-Date,Quantity,Dividend Per Share,Net Dividend Amount
+INFY,INE009A01021,2023-06-01,10,17.5,175
+`)
+	assert.NoError(t, err)
+	assert.Len(t, statement.Charges, 1)
+	assert.Equal(t, "1039.75", statement.Charges[0].Amount.String())
+	assert.Equal(t, "2024-03-31", statement.Charges[0].Date.Format("2006-01-02"))
+	assert.Len(t, statement.Dividends, 1)
+	assert.Equal(t, "175", statement.Dividends[0].Amount.String())
+}
+
+func TestParseFlexStatement(t *testing.T) {
+	statement, err := Parse(IBKR, `<FlexQueryResponse queryName="paisa" type="AF">
+<FlexStatements count="1">
+<FlexStatement accountId="U1234567" fromDate="20240101" toDate="20241231">
+<Trades>
+<Trade assetCategory="STK" symbol="AAPL" currency="USD" tradeDate="20240105" quantity="10" tradePrice="185.5" buySell="BUY" ibCommission="-1" tradeID="11" ibOrderID="21"/>
+<Trade assetCategory="CASH" symbol="EUR.USD" currency="USD" tradeDate="20240105" quantity="100" tradePrice="1.1" buySell="BUY" ibCommission="-2" tradeID="12" ibOrderID="22"/>
+</Trades>
+<CashTransactions>
+<CashTransaction type="Dividends" symbol="AAPL" currency="USD" dateTime="2024-02-15" amount="2.4" transactionID="31" description="AAPL CASH DIVIDEND"/>
+<CashTransaction type="Withholding Tax" symbol="AAPL" currency="USD" dateTime="2024-02-15" amount="-0.36" transactionID="32" description="AAPL US TAX"/>
+</CashTransactions>
+</FlexStatement>
+</FlexStatements>
+</FlexQueryResponse>`)
+	assert.NoError(t, err)
+	assert.Len(t, statement.Trades, 1)
+	assert.Equal(t, "21", statement.Trades[0].ID)
+	assert.Equal(t, "1", statement.Trades[0].Charges.String())
+	assert.Len(t, statement.Dividends, 1)
+	assert.Equal(t, "0.36", statement.Dividends[0].Tax.String())
+	assert.Len(t, statement.Charges, 0)
+}
+
+func TestSellPostings(t *testing.T) {
+	broker := config.BrokerImport{Broker: ZERODHA, AssetAccount: "Assets:Equity:Zerodha", CashAccount: "Assets:Checking:Zerodha"}
+	date := func(value string) time.Time {
+		d, _ := time.Parse("2006-01-02", value)
+		return d
+	}
+	lots := []lot{
+		{date: date("2024-01-05"), quantity: decimal.NewFromInt(4), price: decimal.NewFromInt(1500)},
+		{date: date("2024-02-05"), quantity: decimal.NewFromInt(10), price: decimal.NewFromInt(1550)},
+	}
+
+	postings, remaining := sellPostings(broker, "Assets:Equity:Zerodha:INFY", Trade{Symbol: "INFY", Quantity: decimal.NewFromInt(6), Price: decimal.NewFromInt(1600), Currency: "INR"}, lots)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "8", remaining[0].quantity.String())
+	assert.Equal(t, "Income:CapitalGains:Equity:Zerodha:INFY", postings[1].Account)
+	assert.Equal(t, "-500 INR", postings[1].Amount)
+	assert.Equal(t, "-4 INFY {1500 INR} [2024/01/05] @ 1600 INR", postings[2].Amount)
+	assert.Equal(t, "-2 INFY {1550 INR} [2024/02/05] @ 1600 INR", postings[3].Amount)
+}
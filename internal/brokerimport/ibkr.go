@@ -0,0 +1,238 @@
+package brokerimport
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+const FLEX_URL = "https://ndcdyn.interactivebrokers.com/AccountManagement/FlexWebService/SendRequest"
+
+// the statement is generated in the background, the download is
+// retried a few times while it's in progress
+const (
+	flexAttempts = 10
+	flexWait     = 5 * time.Second
+	// statement generation in progress
+	flexInProgress = "1019"
+)
+
+var flexClient = &http.Client{Timeout: 60 * time.Second}
+
+type flexTrade struct {
+	TradeID       string `xml:"tradeID,attr"`
+	OrderID       string `xml:"ibOrderID,attr"`
+	AssetCategory string `xml:"assetCategory,attr"`
+	Symbol        string `xml:"symbol,attr"`
+	Currency      string `xml:"currency,attr"`
+	TradeDate     string `xml:"tradeDate,attr"`
+	Quantity      string `xml:"quantity,attr"`
+	TradePrice    string `xml:"tradePrice,attr"`
+	BuySell       string `xml:"buySell,attr"`
+	Commission    string `xml:"ibCommission,attr"`
+}
+
+type flexCashTransaction struct {
+	TransactionID string `xml:"transactionID,attr"`
+	Type          string `xml:"type,attr"`
+	Symbol        string `xml:"symbol,attr"`
+	Currency      string `xml:"currency,attr"`
+	DateTime      string `xml:"dateTime,attr"`
+	Amount        string `xml:"amount,attr"`
+	Description   string `xml:"description,attr"`
+}
+
+type flexQueryResponse struct {
+	Statements []struct {
+		Trades           []flexTrade           `xml:"Trades>Trade"`
+		CashTransactions []flexCashTransaction `xml:"CashTransactions>CashTransaction"`
+	} `xml:"FlexStatements>FlexStatement"`
+}
+
+type flexStatementResponse struct {
+	Status        string `xml:"Status"`
+	ReferenceCode string `xml:"ReferenceCode"`
+	URL           string `xml:"Url"`
+	ErrorCode     string `xml:"ErrorCode"`
+	ErrorMessage  string `xml:"ErrorMessage"`
+}
+
+// parseFlexStatement reads the XML output of an Activity Flex Query
+// with the Trades and the Cash Transactions sections. The withholding
+// tax is matched with the dividend of the same symbol and date. The
+// currency conversions are left out.
+func parseFlexStatement(content []byte) (Statement, error) {
+	var response flexQueryResponse
+	err := xml.Unmarshal(content, &response)
+	if err != nil {
+		return Statement{}, err
+	}
+	if len(response.Statements) == 0 {
+		return Statement{}, errors.New("No FlexStatement found, expected the XML output of a Flex Query")
+	}
+
+	statement := Statement{}
+	for _, s := range response.Statements {
+		for _, t := range s.Trades {
+			if t.AssetCategory == "CASH" {
+				continue
+			}
+
+			date, err := flexDate(t.TradeDate)
+			if err != nil {
+				return statement, fmt.Errorf("Trade %s: %w", t.TradeID, err)
+			}
+			quantity, err := decimal.NewFromString(t.Quantity)
+			if err != nil {
+				return statement, fmt.Errorf("Trade %s: invalid quantity: %w", t.TradeID, err)
+			}
+			price, err := decimal.NewFromString(t.TradePrice)
+			if err != nil {
+				return statement, fmt.Errorf("Trade %s: invalid price: %w", t.TradeID, err)
+			}
+			commission, _ := decimal.NewFromString(t.Commission)
+
+			id := t.OrderID
+			if id == "" {
+				id = t.TradeID
+			}
+
+			statement.Trades = append(statement.Trades, Trade{
+				ID:       id,
+				Date:     date,
+				Symbol:   t.Symbol,
+				Buy:      strings.HasPrefix(t.BuySell, "BUY"),
+				Quantity: quantity.Abs(),
+				Price:    price,
+				Currency: t.Currency,
+				Charges:  commission.Neg(),
+			})
+		}
+
+		dividends := make(map[string]int)
+		var taxes []flexCashTransaction
+		for _, c := range s.CashTransactions {
+			date, err := flexDate(c.DateTime)
+			if err != nil {
+				return statement, fmt.Errorf("Cash transaction %s: %w", c.TransactionID, err)
+			}
+			amount, err := decimal.NewFromString(c.Amount)
+			if err != nil {
+				return statement, fmt.Errorf("Cash transaction %s: invalid amount: %w", c.TransactionID, err)
+			}
+
+			switch c.Type {
+			case "Dividends", "Payment In Lieu Of Dividends":
+				dividends[c.Symbol+date.Format("20060102")] = len(statement.Dividends)
+				statement.Dividends = append(statement.Dividends, Dividend{
+					ID:       c.TransactionID,
+					Date:     date,
+					Symbol:   c.Symbol,
+					Amount:   amount,
+					Currency: c.Currency,
+				})
+			case "Withholding Tax":
+				taxes = append(taxes, c)
+			case "Other Fees", "Commission Adjustments", "Broker Interest Paid":
+				statement.Charges = append(statement.Charges, Charge{
+					ID:          c.TransactionID,
+					Date:        date,
+					Description: c.Description,
+					Amount:      amount.Neg(),
+					Currency:    c.Currency,
+				})
+			}
+		}
+
+		for _, c := range taxes {
+			date, _ := flexDate(c.DateTime)
+			amount, _ := decimal.NewFromString(c.Amount)
+			i, ok := dividends[c.Symbol+date.Format("20060102")]
+			if !ok {
+				statement.Charges = append(statement.Charges, Charge{ID: c.TransactionID, Date: date, Description: c.Description, Amount: amount.Neg(), Currency: c.Currency})
+				continue
+			}
+			statement.Dividends[i].Tax = statement.Dividends[i].Tax.Add(amount.Neg())
+		}
+	}
+
+	return statement, nil
+}
+
+// flexDate handles both the default yyyyMMdd format and the
+// yyyy-MM-dd format, optionally followed by the time.
+func flexDate(value string) (time.Time, error) {
+	value = strings.ReplaceAll(value, "-", "")
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %s", value)
+	}
+	return time.ParseInLocation("20060102", value[:8], config.TimeZone())
+}
+
+// FetchFlexStatement runs the Flex Query through the Flex Web Service.
+func FetchFlexStatement(token string, queryID string) (Statement, error) {
+	var request flexStatementResponse
+	err := flexGet(FLEX_URL+"?"+url.Values{"t": {token}, "q": {queryID}, "v": {"3"}}.Encode(), &request)
+	if err != nil {
+		return Statement{}, err
+	}
+	if request.Status != "Success" {
+		return Statement{}, fmt.Errorf("Flex query failed: %s %s", request.ErrorCode, request.ErrorMessage)
+	}
+
+	download := request.URL + "?" + url.Values{"t": {token}, "q": {request.ReferenceCode}, "v": {"3"}}.Encode()
+	for attempt := 1; attempt <= flexAttempts; attempt++ {
+		content, err := flexDownload(download)
+		if err != nil {
+			return Statement{}, err
+		}
+
+		var status flexStatementResponse
+		if xml.Unmarshal(content, &status) == nil && status.Status != "" {
+			if status.ErrorCode == flexInProgress {
+				time.Sleep(flexWait)
+				continue
+			}
+			return Statement{}, fmt.Errorf("Flex query failed: %s %s", status.ErrorCode, status.ErrorMessage)
+		}
+
+		return parseFlexStatement(content)
+	}
+	return Statement{}, errors.New("Flex statement is not ready yet, try again later")
+}
+
+func flexGet(address string, out any) error {
+	content, err := flexDownload(address)
+	if err != nil {
+		return err
+	}
+	return xml.Unmarshal(content, out)
+}
+
+func flexDownload(address string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	// the flex web service rejects the requests without a user agent
+	req.Header.Set("User-Agent", "paisa")
+
+	resp, err := flexClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Flex request failed: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,201 @@
+package brokerimport
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	zerodhaPeriodRegex = regexp.MustCompile(`(\d{4}-\d{2}-\d{2}) to (\d{4}-\d{2}-\d{2})`)
+	zerodhaChargeRegex = regexp.MustCompile(`(?i)^(brokerage|exchange transaction charges|clearing charges|central gst|state gst|integrated gst|securities transaction tax|sebi turnover fees|stamp duty|ipft|dp charges)`)
+)
+
+func readCSV(content string) ([][]string, error) {
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	return reader.ReadAll()
+}
+
+// parseZerodhaTradebook reads the tradebook downloaded from Console,
+// the executions of an order are merged using the order id.
+func parseZerodhaTradebook(content string) (Statement, error) {
+	records, err := readCSV(content)
+	if err != nil {
+		return Statement{}, err
+	}
+	if len(records) == 0 {
+		return Statement{}, errors.New("Empty tradebook")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"symbol", "trade_date", "trade_type", "quantity", "price", "trade_id"} {
+		if _, ok := columns[name]; !ok {
+			return Statement{}, fmt.Errorf("Column %s not found in the tradebook", name)
+		}
+	}
+
+	cell := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	statement := Statement{}
+	for n, record := range records[1:] {
+		if cell(record, "symbol") == "" {
+			continue
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", cell(record, "trade_date"), config.TimeZone())
+		if err != nil {
+			return statement, fmt.Errorf("Line %d: invalid trade_date: %w", n+2, err)
+		}
+		quantity, err := decimal.NewFromString(cell(record, "quantity"))
+		if err != nil {
+			return statement, fmt.Errorf("Line %d: invalid quantity: %w", n+2, err)
+		}
+		price, err := decimal.NewFromString(cell(record, "price"))
+		if err != nil {
+			return statement, fmt.Errorf("Line %d: invalid price: %w", n+2, err)
+		}
+
+		id := cell(record, "order_id")
+		if id == "" {
+			id = cell(record, "trade_id")
+		}
+
+		statement.Trades = append(statement.Trades, Trade{
+			ID:       id,
+			Date:     date,
+			Symbol:   cell(record, "symbol"),
+			Buy:      strings.EqualFold(cell(record, "trade_type"), "buy"),
+			Quantity: quantity,
+			Price:    price,
+			Currency: "INR",
+		})
+	}
+
+	return statement, nil
+}
+
+// parseZerodhaPnL reads the charges and the dividends out of the P&L
+// statement. The charges are booked as a single transaction at the end
+// of the period of the statement.
+func parseZerodhaPnL(content string) (Statement, error) {
+	records, err := readCSV(content)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	var start, end string
+	for _, record := range records {
+		for _, c := range record {
+			if match := zerodhaPeriodRegex.FindStringSubmatch(c); match != nil {
+				start, end = match[1], match[2]
+				break
+			}
+		}
+		if start != "" {
+			break
+		}
+	}
+	if start == "" {
+		return Statement{}, errors.New("Period of the P&L statement not found, expected a line like P&L Statement for Equity from 2023-04-01 to 2024-03-31")
+	}
+	endDate, err := time.ParseInLocation("2006-01-02", end, config.TimeZone())
+	if err != nil {
+		return Statement{}, err
+	}
+
+	statement := Statement{}
+	charges := decimal.Zero
+	var dividendColumns map[string]int
+	for _, record := range records {
+		record = lo.Map(record, func(c string, _ int) string { return strings.TrimSpace(c) })
+		if len(record) == 0 || lo.EveryBy(record, func(c string) bool { return c == "" }) {
+			dividendColumns = nil
+			continue
+		}
+
+		if lo.Contains(record, "Net Dividend Amount") {
+			dividendColumns = make(map[string]int)
+			for i, name := range record {
+				dividendColumns[name] = i
+			}
+			continue
+		}
+
+		if dividendColumns != nil {
+			dividend, ok := zerodhaDividend(record, dividendColumns)
+			if ok {
+				statement.Dividends = append(statement.Dividends, dividend)
+			}
+			continue
+		}
+
+		if zerodhaChargeRegex.MatchString(record[0]) {
+			for _, c := range record[1:] {
+				amount, err := decimal.NewFromString(strings.ReplaceAll(c, ",", ""))
+				if err == nil {
+					charges = charges.Add(amount)
+					break
+				}
+			}
+		}
+	}
+
+	if !charges.IsZero() {
+		statement.Charges = append(statement.Charges, Charge{
+			ID:          fmt.Sprintf("charges:%s:%s", start, end),
+			Date:        endDate,
+			Description: fmt.Sprintf("Charges from %s to %s", start, end),
+			Amount:      charges,
+			Currency:    "INR",
+		})
+	}
+
+	return statement, nil
+}
+
+func zerodhaDividend(record []string, columns map[string]int) (Dividend, bool) {
+	cell := func(name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	symbol := cell("Symbol")
+	date, err := time.ParseInLocation("2006-01-02", cell("Ex-Date"), config.TimeZone())
+	if symbol == "" || err != nil {
+		return Dividend{}, false
+	}
+	amount, err := decimal.NewFromString(strings.ReplaceAll(cell("Net Dividend Amount"), ",", ""))
+	if err != nil {
+		return Dividend{}, false
+	}
+
+	return Dividend{
+		ID:       fmt.Sprintf("dividend:%s:%s", symbol, cell("Ex-Date")),
+		Date:     date,
+		Symbol:   symbol,
+		Amount:   amount,
+		Currency: "INR",
+	}, true
+}
@@ -7,8 +7,12 @@ import (
 	"github.com/ananthakumaran/paisa/internal/service"
 )
 
+// Clear drops the in memory caches, it should be called whenever the
+// postings, the prices or the config change.
 func Clear() {
+	clearResponses()
 	service.ClearInterestCache()
+	service.ClearClassificationCache()
 	service.ClearPriceCache()
 	accounting.ClearCache()
 	prediction.ClearCache()
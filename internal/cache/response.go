@@ -0,0 +1,54 @@
+package cache
+
+import "sync"
+
+// MAX_RESPONSES bounds the memory used by the response cache, the
+// whole cache is dropped when it's full.
+const MAX_RESPONSES = 512
+
+type Response struct {
+	ContentType string
+	Body        []byte
+}
+
+var responses = struct {
+	sync.RWMutex
+	generation uint64
+	entries    map[string]Response
+}{}
+
+// Generation changes whenever the cache is cleared. A response
+// computed during an older generation might be stale and is not
+// stored.
+func Generation() uint64 {
+	responses.RLock()
+	defer responses.RUnlock()
+	return responses.generation
+}
+
+func GetResponse(key string) (Response, bool) {
+	responses.RLock()
+	defer responses.RUnlock()
+	response, ok := responses.entries[key]
+	return response, ok
+}
+
+func PutResponse(key string, generation uint64, response Response) {
+	responses.Lock()
+	defer responses.Unlock()
+	if generation != responses.generation {
+		return
+	}
+
+	if responses.entries == nil || len(responses.entries) >= MAX_RESPONSES {
+		responses.entries = make(map[string]Response)
+	}
+	responses.entries[key] = response
+}
+
+func clearResponses() {
+	responses.Lock()
+	defer responses.Unlock()
+	responses.generation++
+	responses.entries = nil
+}
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "embed"
@@ -14,11 +18,14 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"dario.cat/mergo"
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/santhosh-tekuri/jsonschema/v5"
 
 	"gopkg.in/yaml.v3"
 )
 
+const VERSION = "0.6.6"
+
 type TaxCategoryType string
 
 const (
@@ -36,6 +43,7 @@ const (
 	NPS        CommodityType = "nps"
 	Stock      CommodityType = "stock"
 	Metal      CommodityType = "metal"
+	RealEstate CommodityType = "real_estate"
 	Unknown    CommodityType = "unknown"
 )
 
@@ -60,8 +68,29 @@ type Commodity struct {
 	Name        string          `json:"name" yaml:"name"`
 	Type        CommodityType   `json:"type" yaml:"type"`
 	Price       Price           `json:"price" yaml:"price"`
+	Fallbacks   []Price         `json:"fallbacks" yaml:"fallbacks"`
 	Harvest     int             `json:"harvest" yaml:"harvest"`
 	TaxCategory TaxCategoryType `json:"tax_category" yaml:"tax_category"`
+	Purity      float64         `json:"purity" yaml:"purity"`
+	Deduction   float64         `json:"deduction" yaml:"deduction"`
+	Currency    string          `json:"currency" yaml:"currency"`
+}
+
+type CorporateActionType string
+
+const (
+	Split  CorporateActionType = "split"
+	Bonus  CorporateActionType = "bonus"
+	Merger CorporateActionType = "merger"
+	Rename CorporateActionType = "rename"
+)
+
+type CorporateAction struct {
+	Type      CorporateActionType `json:"type" yaml:"type"`
+	Commodity string              `json:"commodity" yaml:"commodity"`
+	Date      string              `json:"date" yaml:"date"`
+	Ratio     string              `json:"ratio" yaml:"ratio"`
+	To        string              `json:"to" yaml:"to"`
 }
 
 type Account struct {
@@ -69,9 +98,22 @@ type Account struct {
 	Icon string `json:"icon" yaml:"icon"`
 }
 
+// AccountRoles maps the accounts that are treated specially to the
+// names used in the journal. An account matches a role if it's the
+// same as or a sub account of one of the listed accounts.
+type AccountRoles struct {
+	Checking []string `json:"checking" yaml:"checking"`
+	Tax      []string `json:"tax" yaml:"tax"`
+	Interest []string `json:"interest" yaml:"interest"`
+	// parent of the capital gains accounts, the gains of Assets:Equity:AAPL
+	// are booked under Income:CapitalGains:Equity:AAPL
+	CapitalGains string `json:"capital_gains" yaml:"capital_gains"`
+}
+
 type UserAccount struct {
 	Username string `json:"username" yaml:"username"`
 	Password string `json:"password" yaml:"password"`
+	Readonly bool   `json:"readonly" yaml:"readonly"`
 }
 
 type Goals struct {
@@ -105,8 +147,102 @@ type ScheduleAL struct {
 	Accounts []string `json:"accounts" yaml:"accounts"`
 }
 
+type ScheduleFAEntity struct {
+	Name     string   `json:"name" yaml:"name"`
+	Country  string   `json:"country" yaml:"country"`
+	Currency string   `json:"currency" yaml:"currency"`
+	Accounts []string `json:"accounts" yaml:"accounts"`
+}
+
+type ExchangeRate struct {
+	Currency string  `json:"currency" yaml:"currency"`
+	Date     string  `json:"date" yaml:"date"`
+	Rate     float64 `json:"rate" yaml:"rate"`
+}
+
+type ScheduleFA struct {
+	Entities []ScheduleFAEntity `json:"entities" yaml:"entities"`
+	Rates    []ExchangeRate     `json:"rates" yaml:"rates"`
+}
+
+type CapitalGainsProfile struct {
+	Name              string   `json:"name" yaml:"name"`
+	Accounts          []string `json:"accounts" yaml:"accounts"`
+	LotMatching       string   `json:"lot_matching" yaml:"lot_matching"`
+	Year              string   `json:"year" yaml:"year"`
+	HoldingPeriodDays int      `json:"holding_period_days" yaml:"holding_period_days"`
+	LongTermExempt    bool     `json:"long_term_exempt" yaml:"long_term_exempt"`
+	Currency          string   `json:"currency" yaml:"currency"`
+	ExchangeRates     string   `json:"exchange_rates" yaml:"exchange_rates"`
+}
+
+type IncomeTax struct {
+	Regime             string   `json:"regime" yaml:"regime"`
+	Section80CAccounts []string `json:"section_80c_accounts" yaml:"section_80c_accounts"`
+	ELSSAccounts       []string `json:"elss_accounts" yaml:"elss_accounts"`
+	HRAExemption       float64  `json:"hra_exemption" yaml:"hra_exemption"`
+	OtherDeductions    float64  `json:"other_deductions" yaml:"other_deductions"`
+}
+
+type GSTRate struct {
+	Account string  `json:"account" yaml:"account"`
+	Rate    float64 `json:"rate" yaml:"rate"`
+}
+
+type GST struct {
+	Tag    string    `json:"tag" yaml:"tag"`
+	Period string    `json:"period" yaml:"period"`
+	Rates  []GSTRate `json:"rates" yaml:"rates"`
+}
+
+type SinkingFund struct {
+	Account string  `json:"account" yaml:"account"`
+	Target  float64 `json:"target" yaml:"target"`
+	Date    string  `json:"date" yaml:"date"`
+}
+
+type BudgetPeriod struct {
+	Account string `json:"account" yaml:"account"`
+	Period  string `json:"period" yaml:"period"`
+}
+
 type Budget struct {
-	Rollover BoolType `json:"rollover" yaml:"rollover"`
+	Rollover     BoolType       `json:"rollover" yaml:"rollover"`
+	SinkingFunds []SinkingFund  `json:"sinking_funds" yaml:"sinking_funds"`
+	Periods      []BudgetPeriod `json:"periods" yaml:"periods"`
+}
+
+type S3Storage struct {
+	Endpoint        string `json:"endpoint" yaml:"endpoint"`
+	Region          string `json:"region" yaml:"region"`
+	Bucket          string `json:"bucket" yaml:"bucket"`
+	Prefix          string `json:"prefix" yaml:"prefix"`
+	AccessKeyID     string `json:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" yaml:"secret_access_key"`
+}
+
+type WebDAVStorage struct {
+	URL      string `json:"url" yaml:"url"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+type Attachments struct {
+	Storage   string        `json:"storage" yaml:"storage"`
+	Directory string        `json:"directory" yaml:"directory"`
+	S3        S3Storage     `json:"s3" yaml:"s3"`
+	WebDAV    WebDAVStorage `json:"webdav" yaml:"webdav"`
+}
+
+type JournalStorage struct {
+	Storage string        `json:"storage" yaml:"storage"`
+	S3      S3Storage     `json:"s3" yaml:"s3"`
+	WebDAV  WebDAVStorage `json:"webdav" yaml:"webdav"`
+}
+
+type Encryption struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	KeyFile string `json:"key_file" yaml:"key_file"`
 }
 
 type AllocationTarget struct {
@@ -115,6 +251,285 @@ type AllocationTarget struct {
 	Accounts []string `json:"accounts" yaml:"accounts"`
 }
 
+type Rebalance struct {
+	MinimumTradeAmount float64 `json:"minimum_trade_amount" yaml:"minimum_trade_amount"`
+	AvoidSelling       bool    `json:"avoid_selling" yaml:"avoid_selling"`
+}
+
+type NumberFormat struct {
+	DecimalSeparator string `json:"decimal_separator" yaml:"decimal_separator"`
+	GroupSeparator   string `json:"group_separator" yaml:"group_separator"`
+	Grouping         string `json:"grouping" yaml:"grouping"`
+}
+
+type SavingsRate struct {
+	Taxes        string `json:"taxes" yaml:"taxes"`
+	EMIAsExpense bool   `json:"emi_as_expense" yaml:"emi_as_expense"`
+}
+
+type InterestRate struct {
+	From string  `json:"from" yaml:"from"`
+	Rate float64 `json:"rate" yaml:"rate"`
+}
+
+type InterestAccrual struct {
+	Account       string         `json:"account" yaml:"account"`
+	IncomeAccount string         `json:"income_account" yaml:"income_account"`
+	Rates         []InterestRate `json:"rates" yaml:"rates"`
+}
+
+type Project struct {
+	Name     string   `json:"name" yaml:"name"`
+	Tag      string   `json:"tag" yaml:"tag"`
+	Accounts []string `json:"accounts" yaml:"accounts"`
+	Budget   float64  `json:"budget" yaml:"budget"`
+	Start    string   `json:"start" yaml:"start"`
+	End      string   `json:"end" yaml:"end"`
+}
+
+type Loan struct {
+	Account string         `json:"account" yaml:"account"`
+	Rates   []InterestRate `json:"rates" yaml:"rates"`
+}
+
+type Vesting struct {
+	CliffMonths     int `json:"cliff_months" yaml:"cliff_months"`
+	PeriodMonths    int `json:"period_months" yaml:"period_months"`
+	FrequencyMonths int `json:"frequency_months" yaml:"frequency_months"`
+}
+
+type Grant struct {
+	Name          string  `json:"name" yaml:"name"`
+	Commodity     string  `json:"commodity" yaml:"commodity"`
+	Account       string  `json:"account" yaml:"account"`
+	IncomeAccount string  `json:"income_account" yaml:"income_account"`
+	GrantDate     string  `json:"grant_date" yaml:"grant_date"`
+	Units         float64 `json:"units" yaml:"units"`
+	Vesting       Vesting `json:"vesting" yaml:"vesting"`
+	Withholding   float64 `json:"withholding" yaml:"withholding"`
+}
+
+type FixedAsset struct {
+	Name           string  `json:"name" yaml:"name"`
+	Account        string  `json:"account" yaml:"account"`
+	ExpenseAccount string  `json:"expense_account" yaml:"expense_account"`
+	PurchaseDate   string  `json:"purchase_date" yaml:"purchase_date"`
+	Cost           float64 `json:"cost" yaml:"cost"`
+	Salvage        float64 `json:"salvage" yaml:"salvage"`
+	Method         string  `json:"method" yaml:"method"`
+	Life           int     `json:"life" yaml:"life"`
+	Rate           float64 `json:"rate" yaml:"rate"`
+	Post           bool    `json:"post" yaml:"post"`
+}
+
+// CustomReport aggregates the postings matching the filters by up to
+// two dimensions, the first one is the x axis of the chart and the
+// second one splits it into series.
+type CustomReport struct {
+	Name        string   `json:"name" yaml:"name"`
+	Description string   `json:"description" yaml:"description"`
+	Accounts    []string `json:"accounts" yaml:"accounts"`
+	Payee       string   `json:"payee" yaml:"payee"`
+	Tags        []string `json:"tags" yaml:"tags"`
+	From        string   `json:"from" yaml:"from"`
+	Until       string   `json:"until" yaml:"until"`
+	LastNMonths int      `json:"last_n_months" yaml:"last_n_months"`
+	// month, quarter, year, financial_year, account, payee, commodity
+	// or tag:{name}
+	GroupBy      []string `json:"group_by" yaml:"group_by"`
+	AccountDepth int      `json:"account_depth" yaml:"account_depth"`
+	Aggregation  string   `json:"aggregation" yaml:"aggregation"`
+	Negate       bool     `json:"negate" yaml:"negate"`
+	Chart        string   `json:"chart" yaml:"chart"`
+}
+
+type Profile struct {
+	Name        string `json:"name" yaml:"name"`
+	JournalPath string `json:"journal_path" yaml:"journal_path"`
+	DBPath      string `json:"db_path" yaml:"db_path"`
+}
+
+type FederationToken struct {
+	Name    string `json:"name" yaml:"name"`
+	Token   string `json:"token" yaml:"token"`
+	Profile string `json:"profile" yaml:"profile"`
+}
+
+type FederationRemote struct {
+	Name  string `json:"name" yaml:"name"`
+	URL   string `json:"url" yaml:"url"`
+	Token string `json:"token" yaml:"token"`
+}
+
+type CalendarToken struct {
+	Name  string `json:"name" yaml:"name"`
+	Token string `json:"token" yaml:"token"`
+}
+
+type Calendar struct {
+	Tokens []CalendarToken `json:"tokens" yaml:"tokens"`
+}
+
+type OIDC struct {
+	Issuer       string   `json:"issuer" yaml:"issuer"`
+	ClientID     string   `json:"client_id" yaml:"client_id"`
+	ClientSecret string   `json:"client_secret" yaml:"client_secret"`
+	RedirectURL  string   `json:"redirect_url" yaml:"redirect_url"`
+	AllowedUsers []string `json:"allowed_users" yaml:"allowed_users"`
+	Readonly     bool     `json:"readonly" yaml:"readonly"`
+}
+
+type Federation struct {
+	Tokens  []FederationToken  `json:"tokens" yaml:"tokens"`
+	Remotes []FederationRemote `json:"remotes" yaml:"remotes"`
+}
+
+type Webhook struct {
+	Name    string            `json:"name" yaml:"name"`
+	URL     string            `json:"url" yaml:"url"`
+	Events  []string          `json:"events" yaml:"events"`
+	Secret  string            `json:"secret" yaml:"secret"`
+	Headers map[string]string `json:"headers" yaml:"headers"`
+}
+
+type LowBalanceAlert struct {
+	Account   string  `json:"account" yaml:"account"`
+	Threshold float64 `json:"threshold" yaml:"threshold"`
+}
+
+type EmailTransport struct {
+	Host     string   `json:"host" yaml:"host"`
+	Port     int      `json:"port" yaml:"port"`
+	Username string   `json:"username" yaml:"username"`
+	Password string   `json:"password" yaml:"password"`
+	From     string   `json:"from" yaml:"from"`
+	To       []string `json:"to" yaml:"to"`
+}
+
+type TelegramTransport struct {
+	BotToken string `json:"bot_token" yaml:"bot_token"`
+	ChatID   string `json:"chat_id" yaml:"chat_id"`
+}
+
+type Notifications struct {
+	Schedule         string            `json:"schedule" yaml:"schedule"`
+	BudgetSummary    bool              `json:"budget_summary" yaml:"budget_summary"`
+	MonthlySummary   bool              `json:"monthly_summary" yaml:"monthly_summary"`
+	BillReminderDays int               `json:"bill_reminder_days" yaml:"bill_reminder_days"`
+	AnomalyDays      int               `json:"anomaly_days" yaml:"anomaly_days"`
+	LowBalance       []LowBalanceAlert `json:"low_balance" yaml:"low_balance"`
+	Email            EmailTransport    `json:"email" yaml:"email"`
+	Telegram         TelegramTransport `json:"telegram" yaml:"telegram"`
+}
+
+type EmailImportAccount struct {
+	Bank    string `json:"bank" yaml:"bank"`
+	Number  string `json:"number" yaml:"number"`
+	Account string `json:"account" yaml:"account"`
+}
+
+type EmailImport struct {
+	Host         string               `json:"host" yaml:"host"`
+	Port         int                  `json:"port" yaml:"port"`
+	Username     string               `json:"username" yaml:"username"`
+	Password     string               `json:"password" yaml:"password"`
+	Folder       string               `json:"folder" yaml:"folder"`
+	Schedule     string               `json:"schedule" yaml:"schedule"`
+	LookbackDays int                  `json:"lookback_days" yaml:"lookback_days"`
+	Accounts     []EmailImportAccount `json:"accounts" yaml:"accounts"`
+}
+
+type ImportRule struct {
+	Match   string `json:"match" yaml:"match"`
+	Account string `json:"account" yaml:"account"`
+	Payee   string `json:"payee" yaml:"payee"`
+}
+
+type GoCardless struct {
+	SecretID    string `json:"secret_id" yaml:"secret_id"`
+	SecretKey   string `json:"secret_key" yaml:"secret_key"`
+	RedirectURL string `json:"redirect_url" yaml:"redirect_url"`
+}
+
+type Plaid struct {
+	ClientID    string `json:"client_id" yaml:"client_id"`
+	Secret      string `json:"secret" yaml:"secret"`
+	Environment string `json:"environment" yaml:"environment"`
+	WebhookURL  string `json:"webhook_url" yaml:"webhook_url"`
+}
+
+type BankFeedAccount struct {
+	Provider string `json:"provider" yaml:"provider"`
+	Match    string `json:"match" yaml:"match"`
+	Account  string `json:"account" yaml:"account"`
+}
+
+type BankFeeds struct {
+	Schedule     string            `json:"schedule" yaml:"schedule"`
+	LookbackDays int               `json:"lookback_days" yaml:"lookback_days"`
+	GoCardless   GoCardless        `json:"gocardless" yaml:"gocardless"`
+	Plaid        Plaid             `json:"plaid" yaml:"plaid"`
+	Accounts     []BankFeedAccount `json:"accounts" yaml:"accounts"`
+}
+
+type BrokerImport struct {
+	Broker          string `json:"broker" yaml:"broker"`
+	AssetAccount    string `json:"asset_account" yaml:"asset_account"`
+	CashAccount     string `json:"cash_account" yaml:"cash_account"`
+	ChargesAccount  string `json:"charges_account" yaml:"charges_account"`
+	DividendAccount string `json:"dividend_account" yaml:"dividend_account"`
+	TaxAccount      string `json:"tax_account" yaml:"tax_account"`
+	FlexToken       string `json:"flex_token" yaml:"flex_token"`
+	FlexQueryID     string `json:"flex_query_id" yaml:"flex_query_id"`
+}
+
+type CryptoExchange struct {
+	Exchange        string   `json:"exchange" yaml:"exchange"`
+	APIKey          string   `json:"api_key" yaml:"api_key"`
+	APISecret       string   `json:"api_secret" yaml:"api_secret"`
+	Account         string   `json:"account" yaml:"account"`
+	FeesAccount     string   `json:"fees_account" yaml:"fees_account"`
+	TransferAccount string   `json:"transfer_account" yaml:"transfer_account"`
+	IncomeAccount   string   `json:"income_account" yaml:"income_account"`
+	Symbols         []string `json:"symbols" yaml:"symbols"`
+}
+
+type CryptoWallet struct {
+	Chain             string `json:"chain" yaml:"chain"`
+	Address           string `json:"address" yaml:"address"`
+	Account           string `json:"account" yaml:"account"`
+	AdjustmentAccount string `json:"adjustment_account" yaml:"adjustment_account"`
+}
+
+type Crypto struct {
+	Schedule     string           `json:"schedule" yaml:"schedule"`
+	LookbackDays int              `json:"lookback_days" yaml:"lookback_days"`
+	EthereumRPC  string           `json:"ethereum_rpc" yaml:"ethereum_rpc"`
+	Exchanges    []CryptoExchange `json:"exchanges" yaml:"exchanges"`
+	Wallets      []CryptoWallet   `json:"wallets" yaml:"wallets"`
+}
+
+type TransactionTemplatePosting struct {
+	Account string `json:"account" yaml:"account"`
+	Amount  string `json:"amount" yaml:"amount"`
+}
+
+type TransactionTemplate struct {
+	Name     string                       `json:"name" yaml:"name"`
+	Payee    string                       `json:"payee" yaml:"payee"`
+	Note     string                       `json:"note" yaml:"note"`
+	File     string                       `json:"file" yaml:"file"`
+	Postings []TransactionTemplatePosting `json:"postings" yaml:"postings"`
+}
+
+type ScheduledTransaction struct {
+	Name     string `json:"name" yaml:"name"`
+	Schedule string `json:"schedule" yaml:"schedule"`
+	Content  string `json:"content" yaml:"content"`
+	File     string `json:"file" yaml:"file"`
+	Review   bool   `json:"review" yaml:"review"`
+}
+
 type CreditCard struct {
 	Account         string `json:"account" yaml:"account"`
 	CreditLimit     int    `json:"credit_limit" yaml:"credit_limit"`
@@ -125,66 +540,191 @@ type CreditCard struct {
 	ExpirationDate  string `json:"expiration_date" yaml:"expiration_date"`
 }
 
+type ExpenseSharing struct {
+	Tag               string `json:"tag" yaml:"tag"`
+	ReceivableAccount string `json:"receivable_account" yaml:"receivable_account"`
+}
+
+type Invoices struct {
+	ReceivableAccount string `json:"receivable_account" yaml:"receivable_account"`
+	IncomeAccount     string `json:"income_account" yaml:"income_account"`
+	DueDays           int    `json:"due_days" yaml:"due_days"`
+}
+
 type Config struct {
 	JournalPath                string       `json:"journal_path" yaml:"journal_path"`
 	DBPath                     string       `json:"db_path" yaml:"db_path"`
 	SheetsDirectory            string       `json:"sheets_directory" yaml:"sheets_directory"`
+	BackupsDirectory           string       `json:"backups_directory" yaml:"backups_directory"`
+	GitCommit                  bool         `json:"git_commit" yaml:"git_commit"`
 	Readonly                   bool         `json:"readonly" yaml:"readonly"`
 	LedgerCli                  string       `json:"ledger_cli" yaml:"ledger_cli"`
 	DefaultCurrency            string       `json:"default_currency" yaml:"default_currency"`
 	DisplayPrecision           int          `json:"display_precision" yaml:"display_precision"`
 	AmountAlignmentColumn      int          `json:"amount_alignment_column" yaml:"amount_alignment_column"`
 	Locale                     string       `json:"locale" yaml:"locale"`
+	NumberFormat               NumberFormat `json:"number_format" yaml:"number_format"`
+	DateFormat                 string       `json:"date_format" yaml:"date_format"`
 	TimeZone                   string       `json:"time_zone" yaml:"time_zone"`
 	FinancialYearStartingMonth time.Month   `json:"financial_year_starting_month" yaml:"financial_year_starting_month"`
 	WeekStartingDay            time.Weekday `json:"week_starting_day" yaml:"week_starting_day"`
 	Strict                     BoolType     `json:"strict" yaml:"strict"`
+	IncludeFuturePostings      BoolType     `json:"include_future_postings" yaml:"include_future_postings"`
+	ExcludedAccounts           []string     `json:"excluded_accounts" yaml:"excluded_accounts"`
+	SyncSchedule               string       `json:"sync_schedule" yaml:"sync_schedule"`
+	WatchJournal               bool         `json:"watch_journal" yaml:"watch_journal"`
+	PriceFetchConcurrency      int          `json:"price_fetch_concurrency" yaml:"price_fetch_concurrency"`
+	PriceStaleDays             int          `json:"price_stale_days" yaml:"price_stale_days"`
 
 	Budget Budget `json:"budget" yaml:"budget"`
 
+	Attachments Attachments `json:"attachments" yaml:"attachments"`
+
+	JournalStorage JournalStorage `json:"journal_storage" yaml:"journal_storage"`
+
+	Encryption Encryption `json:"encryption" yaml:"encryption"`
+
 	ScheduleALs []ScheduleAL `json:"schedule_al" yaml:"schedule_al"`
 
+	ScheduleFA ScheduleFA `json:"schedule_fa" yaml:"schedule_fa"`
+
+	IncomeTax IncomeTax `json:"income_tax" yaml:"income_tax"`
+
+	GST GST `json:"gst" yaml:"gst"`
+
+	CapitalGainsProfiles []CapitalGainsProfile `json:"capital_gains_profiles" yaml:"capital_gains_profiles"`
+
 	AllocationTargets []AllocationTarget `json:"allocation_targets" yaml:"allocation_targets"`
 
+	Rebalance Rebalance `json:"rebalance" yaml:"rebalance"`
+
+	SavingsRate SavingsRate `json:"savings_rate" yaml:"savings_rate"`
+
+	InterestAccruals []InterestAccrual `json:"interest_accruals" yaml:"interest_accruals"`
+
+	Loans []Loan `json:"loans" yaml:"loans"`
+
+	Projects []Project `json:"projects" yaml:"projects"`
+
+	Grants []Grant `json:"grants" yaml:"grants"`
+
+	FixedAssets []FixedAsset `json:"fixed_assets" yaml:"fixed_assets"`
+
+	CustomReports []CustomReport `json:"custom_reports" yaml:"custom_reports"`
+
 	Commodities []Commodity `json:"commodities" yaml:"commodities"`
 
+	CorporateActions []CorporateAction `json:"corporate_actions" yaml:"corporate_actions"`
+
 	DisplayBuiltinTemplates bool             `json:"display_builtin_templates" yaml:"display_builtin_templates"`
 	ImportTemplates         []ImportTemplate `json:"import_templates" yaml:"import_templates"`
 
 	Accounts []Account `json:"accounts" yaml:"accounts"`
 
+	AccountRoles AccountRoles `json:"account_roles" yaml:"account_roles"`
+
 	Goals Goals `json:"goals" yaml:"goals"`
 
 	UserAccounts []UserAccount `json:"user_accounts" yaml:"user_accounts"`
 
+	OIDC OIDC `json:"oidc" yaml:"oidc"`
+
+	Federation Federation `json:"federation" yaml:"federation"`
+
+	Calendar Calendar `json:"calendar" yaml:"calendar"`
+
+	Profiles []Profile `json:"profiles" yaml:"profiles"`
+
+	Webhooks []Webhook `json:"webhooks" yaml:"webhooks"`
+
+	Notifications Notifications `json:"notifications" yaml:"notifications"`
+
+	ScheduledTransactions []ScheduledTransaction `json:"scheduled_transactions" yaml:"scheduled_transactions"`
+
+	EmailImport EmailImport `json:"email_import" yaml:"email_import"`
+
+	BankFeeds BankFeeds `json:"bank_feeds" yaml:"bank_feeds"`
+
+	BrokerImports []BrokerImport `json:"broker_imports" yaml:"broker_imports"`
+
+	Crypto Crypto `json:"crypto" yaml:"crypto"`
+
+	ImportRules []ImportRule `json:"import_rules" yaml:"import_rules"`
+
+	TransactionTemplates []TransactionTemplate `json:"transaction_templates" yaml:"transaction_templates"`
+
 	CreditCards []CreditCard `json:"credit_cards" yaml:"credit_cards"`
+
+	ExpenseSharing ExpenseSharing `json:"expense_sharing" yaml:"expense_sharing"`
+
+	Invoices Invoices `json:"invoices" yaml:"invoices"`
 }
 
 var config Config
 var configPath string
+
+// mu guards the config, it's swapped as a whole when the config is
+// reloaded
+var mu sync.RWMutex
 var location *time.Location
 
 var defaultConfig = Config{
 	Readonly:                   false,
+	GitCommit:                  false,
 	LedgerCli:                  "ledger",
 	DefaultCurrency:            "INR",
 	DisplayPrecision:           0,
 	AmountAlignmentColumn:      52,
 	Locale:                     "en-IN",
 	TimeZone:                   "",
-	Budget:                     Budget{Rollover: Yes},
+	Budget:                     Budget{Rollover: Yes, SinkingFunds: []SinkingFund{}, Periods: []BudgetPeriod{}},
+	Attachments:                Attachments{Storage: "local"},
+	JournalStorage:             JournalStorage{Storage: "local"},
+	Encryption:                 Encryption{Enabled: false},
 	FinancialYearStartingMonth: 4,
 	Strict:                     No,
+	IncludeFuturePostings:      No,
+	ExcludedAccounts:           []string{},
+	PriceFetchConcurrency:      4,
+	PriceStaleDays:             7,
 	WeekStartingDay:            0,
 	ScheduleALs:                []ScheduleAL{},
+	ScheduleFA:                 ScheduleFA{Entities: []ScheduleFAEntity{}, Rates: []ExchangeRate{}},
+	IncomeTax:                  IncomeTax{Section80CAccounts: []string{}, ELSSAccounts: []string{}},
+	GST:                        GST{Tag: "gst", Period: "monthly", Rates: []GSTRate{}},
+	CapitalGainsProfiles:       []CapitalGainsProfile{},
 	AllocationTargets:          []AllocationTarget{},
+	SavingsRate:                SavingsRate{Taxes: "deduct"},
+	InterestAccruals:           []InterestAccrual{},
+	Loans:                      []Loan{},
+	Projects:                   []Project{},
+	Grants:                     []Grant{},
+	FixedAssets:                []FixedAsset{},
+	CustomReports:              []CustomReport{},
 	Commodities:                []Commodity{},
+	CorporateActions:           []CorporateAction{},
 	DisplayBuiltinTemplates:    false,
 	ImportTemplates:            []ImportTemplate{},
 	Accounts:                   []Account{},
+	AccountRoles:               AccountRoles{Checking: []string{"Assets:Checking"}, Tax: []string{"Expenses:Tax"}, Interest: []string{"Income:Interest"}, CapitalGains: "Income:CapitalGains"},
 	Goals:                      Goals{Retirement: []RetirementGoal{}, Savings: []SavingsGoal{}},
 	UserAccounts:               []UserAccount{},
+	OIDC:                       OIDC{AllowedUsers: []string{}},
+	Federation:                 Federation{Tokens: []FederationToken{}, Remotes: []FederationRemote{}},
+	Calendar:                   Calendar{Tokens: []CalendarToken{}},
+	Profiles:                   []Profile{},
+	Webhooks:                   []Webhook{},
+	ScheduledTransactions:      []ScheduledTransaction{},
+	EmailImport:                EmailImport{Port: 993, Folder: "INBOX", LookbackDays: 7, Accounts: []EmailImportAccount{}},
+	BankFeeds:                  BankFeeds{LookbackDays: 30, Plaid: Plaid{Environment: "production"}, Accounts: []BankFeedAccount{}},
+	ImportRules:                []ImportRule{},
+	BrokerImports:              []BrokerImport{},
+	Crypto:                     Crypto{LookbackDays: 30, EthereumRPC: "https://cloudflare-eth.com", Exchanges: []CryptoExchange{}, Wallets: []CryptoWallet{}},
+	TransactionTemplates:       []TransactionTemplate{},
+	Notifications:              Notifications{BillReminderDays: 3, LowBalance: []LowBalanceAlert{}, Email: EmailTransport{Port: 587, To: []string{}}},
 	CreditCards:                []CreditCard{},
+	ExpenseSharing:             ExpenseSharing{Tag: "shared", ReceivableAccount: "Assets:Receivable"},
+	Invoices:                   Invoices{ReceivableAccount: "Assets:Receivable", IncomeAccount: "Income:Business", DueDays: 30},
 }
 
 var itemsUniquePropertiesMeta = jsonschema.MustCompileString("itemsUniqueProperties.json", `{
@@ -265,12 +805,12 @@ func SaveConfig(content []byte) error {
 		return err
 	}
 
-	yamlContent, err := yaml.Marshal(config)
+	yamlContent, err := yaml.Marshal(GetConfig())
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(configPath, yamlContent, 0644)
+	err = os.WriteFile(GetConfigPath(), yamlContent, 0644)
 	if err != nil {
 		return err
 	}
@@ -310,55 +850,178 @@ func LoadConfig(content []byte, cp string) error {
 		return errors.New(fmt.Sprintf("Invalid configuration\n%#v", err))
 	}
 
-	config = Config{}
-	err = yaml.Unmarshal(content, &config)
+	loaded := Config{}
+	err = yaml.Unmarshal(content, &loaded)
 	if err != nil {
 		return err
 	}
 
-	err = mergo.Merge(&config, defaultConfig, mergo.WithOverrideEmptySlice)
+	err = mergo.Merge(&loaded, defaultConfig, mergo.WithOverrideEmptySlice)
 
 	if err != nil {
 		return err
 	}
 
-	if cp != "" && configPath == "" {
-		configPath = cp
+	if forceReadonly {
+		loaded.Readonly = true
+	}
+
+	if forceWatchJournal {
+		loaded.WatchJournal = true
 	}
 
-	if config.TimeZone == "" {
-		location = time.Local
-	} else {
-		location, err = time.LoadLocation(config.TimeZone)
+	if errs := check(loaded); len(errs) > 0 {
+		return errors.New(errs[0].Message)
+	}
+
+	loadedLocation := time.Local
+	if loaded.TimeZone != "" {
+		loadedLocation, err = time.LoadLocation(loaded.TimeZone)
 		if err != nil {
-			location = time.Local
-			return errors.New(fmt.Sprintf("Invalid time zone: %s\n%#v", config.TimeZone, err))
+			return errors.New(fmt.Sprintf("Invalid time zone: %s\n%#v", loaded.TimeZone, err))
 		}
 	}
 
+	// the config is swapped only after it's validated, a bad config
+	// leaves the current one in place
+	mu.Lock()
+	config = loaded
+	location = loadedLocation
+	if cp != "" && configPath == "" {
+		configPath = cp
+	}
+	if initial == nil {
+		initial = &loaded
+	}
+	mu.Unlock()
+
+	revision.Add(1)
+	return nil
+}
+
+// Reload reads the config file again, the current config is kept if
+// the file is invalid.
+func Reload() error {
+	content, err := os.ReadFile(GetConfigPath())
+	if err != nil {
+		return err
+	}
+
+	err = LoadConfig(content, "")
+	if err != nil {
+		return err
+	}
+
+	log.Info("Reloaded config file: ", GetConfigPath())
 	return nil
 }
 
+// RESTART_REQUIRED are the settings read only when the server starts,
+// the rest take effect as soon as the config is reloaded.
+var RESTART_REQUIRED = []string{"journal_path", "db_path", "journal_storage", "encryption"}
+
+// the config the process started with
+var initial *Config
+
+// RestartRequired returns the settings that changed since the server
+// started but need a restart to take effect.
+func RestartRequired() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	changed := []string{}
+	if initial == nil {
+		return changed
+	}
+
+	before := reflect.ValueOf(*initial)
+	after := reflect.ValueOf(config)
+	for i := 0; i < before.NumField(); i++ {
+		name := strings.Split(before.Type().Field(i).Tag.Get("json"), ",")[0]
+		if slices.Contains(RESTART_REQUIRED, name) && !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+var revision atomic.Uint64
+
+// Revision changes every time the config is loaded, so the values
+// derived from the config know when to recompute.
+func Revision() uint64 {
+	return revision.Load()
+}
+
+var forceReadonly bool
+
+// ForceReadonly keeps the readonly mode on irrespective of the value in
+// the configuration file, used by paisa serve --readonly
+func ForceReadonly() {
+	mu.Lock()
+	defer mu.Unlock()
+	forceReadonly = true
+	config.Readonly = true
+}
+
+var forceWatchJournal bool
+
+// ForceWatchJournal syncs the journal on every change irrespective of
+// the value in the configuration file, used by paisa serve --watch
+func ForceWatchJournal() {
+	mu.Lock()
+	defer mu.Unlock()
+	forceWatchJournal = true
+	config.WatchJournal = true
+}
+
 func GetConfig() Config {
+	mu.RLock()
+	defer mu.RUnlock()
 	return config
 }
 
+var journalPathOverride, dbPathOverride string
+
+// OverridePaths points the journal and the database at a different
+// location without modifying the configuration. It's used to work on
+// the decrypted copy of an encrypted journal.
+func OverridePaths(journalPath string, dbPath string) {
+	journalPathOverride = journalPath
+	dbPathOverride = dbPath
+}
+
 func GetJournalPath() string {
-	if !filepath.IsAbs(config.JournalPath) {
-		return filepath.Join(GetConfigDir(), config.JournalPath)
+	if journalPathOverride != "" {
+		return journalPathOverride
+	}
+
+	if !filepath.IsAbs(GetConfig().JournalPath) {
+		return filepath.Join(GetConfigDir(), GetConfig().JournalPath)
 	}
 
-	return config.JournalPath
+	return GetConfig().JournalPath
+}
+
+// LedgerCliFor returns the ledger client used to read the journal.
+// Journals with a beancount extension are read with beancount
+// irrespective of ledger_cli.
+func LedgerCliFor(journalPath string) string {
+	switch strings.ToLower(filepath.Ext(journalPath)) {
+	case ".beancount", ".bean":
+		return "beancount"
+	}
+	return GetConfig().LedgerCli
 }
 
 func GetSheetDir() string {
-	if config.SheetsDirectory == "" {
+	if GetConfig().SheetsDirectory == "" {
 		return filepath.Dir(GetJournalPath())
 	}
 
-	dir := config.SheetsDirectory
-	if !filepath.IsAbs(config.SheetsDirectory) {
-		dir = filepath.Join(GetConfigDir(), config.SheetsDirectory)
+	dir := GetConfig().SheetsDirectory
+	if !filepath.IsAbs(GetConfig().SheetsDirectory) {
+		dir = filepath.Join(GetConfigDir(), GetConfig().SheetsDirectory)
 	}
 
 	err := os.MkdirAll(dir, 0750)
@@ -369,19 +1032,108 @@ func GetSheetDir() string {
 	return dir
 }
 
+func GetBackupsDir() string {
+	dir := backupsDir()
+	err := os.MkdirAll(dir, 0750)
+	if err != nil {
+		log.Fatal("Failed to create backups directory", err)
+	}
+
+	return dir
+}
+
+func backupsDir() string {
+	dir := GetConfig().BackupsDirectory
+	if dir == "" {
+		dir = "backups"
+	}
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(GetConfigDir(), dir)
+	}
+	return dir
+}
+
+// IsBackup reports whether the path is inside the backups directory.
+func IsBackup(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	dir, err := filepath.Abs(backupsDir())
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// GlobFiles returns the files with the given extension inside the
+// directory and its sub directories. The backups keep the extension of
+// the original files, so the backups directory is skipped, even if it's
+// inside the directory.
+func GlobFiles(dir string, ext string) ([]string, error) {
+	paths, err := doublestar.FilepathGlob(dir + "/**/*" + ext)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, path := range paths {
+		if !IsBackup(path) {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+func GetAttachmentsDir() string {
+	dir := GetConfig().Attachments.Directory
+	if dir == "" {
+		dir = "attachments"
+	}
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(GetConfigDir(), dir)
+	}
+
+	err := os.MkdirAll(dir, 0750)
+	if err != nil {
+		log.Fatal("Failed to create attachments directory", err)
+	}
+
+	return dir
+}
+
 func GetDBPath() string {
-	if !filepath.IsAbs(config.DBPath) {
-		return filepath.Join(GetConfigDir(), config.DBPath)
+	if dbPathOverride != "" {
+		return dbPathOverride
+	}
+
+	if !filepath.IsAbs(GetConfig().DBPath) {
+		return filepath.Join(GetConfigDir(), GetConfig().DBPath)
 	}
 
-	return config.DBPath
+	return GetConfig().DBPath
+}
+
+// ResolvePath resolves the path relative to the config directory
+func ResolvePath(path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.Join(GetConfigDir(), path)
+	}
+
+	return path
 }
 
 func GetConfigDir() string {
-	return filepath.Dir(configPath)
+	return filepath.Dir(GetConfigPath())
 }
 
 func GetConfigPath() string {
+	mu.RLock()
+	defer mu.RUnlock()
 	return configPath
 }
 
@@ -421,10 +1173,52 @@ func EnsureLogFilePath() (string, error) {
 }
 
 func DefaultCurrency() string {
+	mu.RLock()
+	defer mu.RUnlock()
 	return config.DefaultCurrency
 }
 
+// CheckingAccounts returns the accounts that hold the cash, the first
+// one funds the budget and the what-if projections.
+func CheckingAccounts() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(config.AccountRoles.Checking) == 0 {
+		return defaultConfig.AccountRoles.Checking
+	}
+	return config.AccountRoles.Checking
+}
+
+func TaxAccounts() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(config.AccountRoles.Tax) == 0 {
+		return defaultConfig.AccountRoles.Tax
+	}
+	return config.AccountRoles.Tax
+}
+
+func InterestAccounts() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(config.AccountRoles.Interest) == 0 {
+		return defaultConfig.AccountRoles.Interest
+	}
+	return config.AccountRoles.Interest
+}
+
+func CapitalGainsAccount() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	if config.AccountRoles.CapitalGains == "" {
+		return defaultConfig.AccountRoles.CapitalGains
+	}
+	return config.AccountRoles.CapitalGains
+}
+
 func TimeZone() *time.Location {
+	mu.RLock()
+	defer mu.RUnlock()
 	if location != nil {
 		return location
 	}
@@ -0,0 +1,259 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// REDACTED replaces the credentials in the config sent to the
+// browser. A credential which is still REDACTED when the config is
+// saved keeps its current value.
+const REDACTED = "********"
+
+var secretKeysRegex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// secrets are the paths of the credentials in the config. The items of
+// a list are matched by the properties in brackets, so the credentials
+// are restored to the right item even if the list is reordered.
+var secrets = []string{
+	"user_accounts[username].password",
+	"attachments.s3.secret_access_key",
+	"attachments.webdav.password",
+	"journal_storage.s3.secret_access_key",
+	"journal_storage.webdav.password",
+	"oidc.client_secret",
+	"federation.tokens[name].token",
+	"federation.remotes[name].token",
+	"calendar.tokens[name].token",
+	"webhooks[name].secret",
+	"notifications.email.password",
+	"notifications.telegram.bot_token",
+	"email_import.password",
+	"bank_feeds.gocardless.secret_key",
+	"bank_feeds.plaid.secret",
+	"broker_imports[broker].flex_token",
+	"crypto.exchanges[exchange,account].api_key",
+	"crypto.exchanges[exchange,account].api_secret",
+}
+
+// Redact returns a copy of the config with the credentials replaced by
+// REDACTED. Empty credentials are left as is.
+func Redact(c Config) Config {
+	v := reflect.ValueOf(&c).Elem()
+	for _, path := range secrets {
+		redact(v, strings.Split(path, "."))
+	}
+	return c
+}
+
+func redact(v reflect.Value, path []string) {
+	name, _ := parseSecretSegment(path[0])
+	field := fieldByTag(v, name)
+	if !field.IsValid() {
+		return
+	}
+
+	if len(path) == 1 {
+		if field.String() != "" {
+			field.SetString(REDACTED)
+		}
+		return
+	}
+
+	if field.Kind() == reflect.Slice {
+		// the items are shared with the current config
+		items := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+		reflect.Copy(items, field)
+		field.Set(items)
+		for i := 0; i < items.Len(); i++ {
+			redact(items.Index(i), path[1:])
+		}
+		return
+	}
+
+	redact(field, path[1:])
+}
+
+// RestoreSecrets replaces the REDACTED credentials in the config
+// content with the ones in the current config. A REDACTED credential
+// without a current value, like the token of a renamed item, has to
+// be entered again.
+func RestoreSecrets(content []byte) ([]byte, error) {
+	if !bytes.Contains(content, []byte(REDACTED)) {
+		return content, nil
+	}
+
+	var root yaml.Node
+	err := yaml.Unmarshal(content, &root)
+	if err != nil || len(root.Content) == 0 {
+		// the validation reports the invalid yaml
+		return content, nil
+	}
+
+	current := reflect.ValueOf(GetConfig())
+	for _, path := range secrets {
+		err := restore(root.Content[0], current, strings.Split(path, "."), path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return yaml.Marshal(&root)
+}
+
+func restore(node *yaml.Node, current reflect.Value, path []string, secret string) error {
+	name, keys := parseSecretSegment(path[0])
+	value := mappingValue(node, name)
+	if value == nil {
+		return nil
+	}
+
+	var field reflect.Value
+	if current.IsValid() {
+		field = fieldByTag(current, name)
+	}
+
+	if len(path) == 1 {
+		if value.Kind != yaml.ScalarNode || value.Value != REDACTED {
+			return nil
+		}
+		if !field.IsValid() || field.String() == "" {
+			return fmt.Errorf("%s is not set, enter it again", secretKeysRegex.ReplaceAllString(secret, ""))
+		}
+		value.Value = field.String()
+		return nil
+	}
+
+	if value.Kind == yaml.SequenceNode {
+		for _, item := range value.Content {
+			var match reflect.Value
+			for i := 0; field.IsValid() && i < field.Len(); i++ {
+				if sameItem(item, field.Index(i), keys) {
+					match = field.Index(i)
+					break
+				}
+			}
+
+			err := restore(item, match, path[1:], secret)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return restore(value, field, path[1:], secret)
+}
+
+// RedactContent replaces the credentials in the config content with
+// REDACTED, like Redact. The content which can't be parsed is replaced
+// as a whole, as it might still hold a credential.
+func RedactContent(content []byte) []byte {
+	var root yaml.Node
+	err := yaml.Unmarshal(content, &root)
+	if err != nil {
+		return []byte(fmt.Sprintf("<invalid config, %d bytes>", len(content)))
+	}
+	if len(root.Content) == 0 {
+		return content
+	}
+
+	for _, path := range secrets {
+		redactNode(root.Content[0], strings.Split(path, "."))
+	}
+
+	redacted, err := yaml.Marshal(&root)
+	if err != nil {
+		return []byte(fmt.Sprintf("<invalid config, %d bytes>", len(content)))
+	}
+	return redacted
+}
+
+func redactNode(node *yaml.Node, path []string) {
+	name, _ := parseSecretSegment(path[0])
+	value := mappingValue(node, name)
+	if value == nil {
+		return
+	}
+
+	if len(path) == 1 {
+		if value.Kind == yaml.ScalarNode && value.Value != "" {
+			value.Tag = "!!str"
+			value.Value = REDACTED
+		}
+		return
+	}
+
+	if value.Kind == yaml.SequenceNode {
+		for _, item := range value.Content {
+			redactNode(item, path[1:])
+		}
+		return
+	}
+
+	redactNode(value, path[1:])
+}
+
+// parseSecretSegment splits a path segment like tokens[name] into the
+// property and the keys of the items.
+func parseSecretSegment(segment string) (string, []string) {
+	name, keys, found := strings.Cut(segment, "[")
+	if !found {
+		return name, nil
+	}
+	return name, strings.Split(strings.TrimSuffix(keys, "]"), ",")
+}
+
+func fieldByTag(v reflect.Value, name string) reflect.Value {
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		tag, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("yaml"), ",")
+		if tag == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func sameItem(node *yaml.Node, item reflect.Value, keys []string) bool {
+	for _, key := range keys {
+		value := mappingValue(node, key)
+		field := fieldByTag(item, key)
+		if !field.IsValid() {
+			return false
+		}
+
+		if value == nil {
+			if field.String() != "" {
+				return false
+			}
+			continue
+		}
+
+		if value.Value != field.String() {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const secretsConfig = `
+journal_path: main.ledger
+db_path: paisa.db
+oidc:
+  issuer: https://accounts.example.com
+  client_id: paisa
+  client_secret: oidc-secret
+webhooks:
+  - name: slack
+    url: https://hooks.example.com/slack
+    secret: slack-secret
+  - name: ntfy
+    url: https://ntfy.example.com
+    secret: ntfy-secret
+crypto:
+  exchanges:
+    - exchange: kraken
+      api_key: kraken-key
+      api_secret: kraken-secret
+      account: Assets:Crypto:Kraken
+`
+
+func TestRedact(t *testing.T) {
+	require.NoError(t, LoadConfig([]byte(secretsConfig), ""))
+
+	redacted := Redact(GetConfig())
+	assert.Equal(t, REDACTED, redacted.OIDC.ClientSecret)
+	assert.Equal(t, REDACTED, redacted.Webhooks[0].Secret)
+	assert.Equal(t, REDACTED, redacted.Crypto.Exchanges[0].APIKey)
+	assert.Equal(t, REDACTED, redacted.Crypto.Exchanges[0].APISecret)
+	assert.Equal(t, "", redacted.Notifications.Telegram.BotToken)
+	assert.Equal(t, "https://hooks.example.com/slack", redacted.Webhooks[0].URL)
+
+	// the current config is left as is
+	assert.Equal(t, "oidc-secret", GetConfig().OIDC.ClientSecret)
+	assert.Equal(t, "slack-secret", GetConfig().Webhooks[0].Secret)
+}
+
+func TestRestoreSecrets(t *testing.T) {
+	require.NoError(t, LoadConfig([]byte(secretsConfig), ""))
+
+	content, err := yaml.Marshal(Redact(GetConfig()))
+	require.NoError(t, err)
+	restored, err := RestoreSecrets(content)
+	require.NoError(t, err)
+
+	loaded := Config{}
+	require.NoError(t, yaml.Unmarshal(restored, &loaded))
+	assert.Equal(t, "oidc-secret", loaded.OIDC.ClientSecret)
+	assert.Equal(t, "slack-secret", loaded.Webhooks[0].Secret)
+	assert.Equal(t, "kraken-key", loaded.Crypto.Exchanges[0].APIKey)
+
+	reordered := `
+oidc:
+  client_secret: new-secret
+webhooks:
+  - name: ntfy
+    secret: "********"
+  - name: slack
+    secret: "********"
+`
+	restored, err = RestoreSecrets([]byte(reordered))
+	require.NoError(t, err)
+	loaded = Config{}
+	require.NoError(t, yaml.Unmarshal(restored, &loaded))
+	assert.Equal(t, "new-secret", loaded.OIDC.ClientSecret)
+	assert.Equal(t, "ntfy-secret", loaded.Webhooks[0].Secret)
+	assert.Equal(t, "slack-secret", loaded.Webhooks[1].Secret)
+
+	_, err = RestoreSecrets([]byte("webhooks:\n  - name: renamed\n    secret: \"********\"\n"))
+	assert.EqualError(t, err, "webhooks.secret is not set, enter it again")
+}
+
+func TestRedactContent(t *testing.T) {
+	redacted := RedactContent([]byte(secretsConfig))
+	for _, secret := range []string{"oidc-secret", "slack-secret", "ntfy-secret", "kraken-key", "kraken-secret"} {
+		assert.NotContains(t, string(redacted), secret)
+	}
+
+	loaded := Config{}
+	require.NoError(t, yaml.Unmarshal(redacted, &loaded))
+	assert.Equal(t, REDACTED, loaded.OIDC.ClientSecret)
+	assert.Equal(t, REDACTED, loaded.Webhooks[1].Secret)
+	assert.Equal(t, REDACTED, loaded.Crypto.Exchanges[0].APISecret)
+	assert.Equal(t, "https://ntfy.example.com", loaded.Webhooks[1].URL)
+
+	redacted = RedactContent([]byte(`{"webhooks": [{"name": "slack", "secret": "slack-secret"}], "notifications": {"telegram": {"bot_token": 12345}}}`))
+	assert.NotContains(t, string(redacted), "slack-secret")
+	assert.NotContains(t, string(redacted), "12345")
+	require.NoError(t, yaml.Unmarshal(redacted, &loaded))
+	assert.Equal(t, REDACTED, loaded.Notifications.Telegram.BotToken)
+
+	assert.Equal(t, "<invalid config, 12 bytes>", string(RedactContent([]byte("password: [a"))))
+}
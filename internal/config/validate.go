@@ -0,0 +1,340 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/scheduler"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError points to the value in the config that failed the
+// validation. Path is a JSON pointer like /commodities/0/name, Line
+// and Column are 0 when the value is not present in the file, for
+// example when the error is about a default value.
+type ValidationError struct {
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+var yamlLineRegex = regexp.MustCompile(`line (\d+)`)
+
+// Validate checks the config against the schema and the rules that
+// can't be expressed in the schema, like valid account patterns and
+// schedules, without loading it. All the errors are returned instead
+// of the first one.
+func Validate(content []byte) []ValidationError {
+	var root yaml.Node
+	err := yaml.Unmarshal(content, &root)
+	if err != nil {
+		e := ValidationError{Path: "", Message: err.Error()}
+		if match := yamlLineRegex.FindStringSubmatch(err.Error()); match != nil {
+			e.Line, _ = strconv.Atoi(match[1])
+		}
+		return []ValidationError{e}
+	}
+
+	var configJson interface{}
+	err = yaml.Unmarshal(content, &configJson)
+	if err != nil {
+		return []ValidationError{{Message: err.Error()}}
+	}
+
+	errs := []ValidationError{}
+	var schemaError *jsonschema.ValidationError
+	err = schema.Validate(configJson)
+	if errors.As(err, &schemaError) {
+		errs = append(errs, schemaErrors(schemaError)...)
+	} else if err != nil {
+		errs = append(errs, ValidationError{Message: err.Error()})
+	}
+
+	loaded := Config{}
+	err = yaml.Unmarshal(content, &loaded)
+	if err != nil {
+		// the type errors are already reported by the schema
+		if len(errs) == 0 {
+			errs = append(errs, ValidationError{Message: err.Error()})
+		}
+		return locate(&root, errs)
+	}
+
+	errs = append(errs, check(loaded)...)
+	errs = append(errs, checkAppreciationCodes(loaded.Commodities)...)
+	errs = append(errs, checkAccountPatterns(&root, "")...)
+	return locate(&root, errs)
+}
+
+// schemaErrors flattens the nested schema errors to the leaf errors,
+// which describe the actual problem. The long list of allowed values,
+// like the time zones, is left out of the message.
+func schemaErrors(e *jsonschema.ValidationError) []ValidationError {
+	if len(e.Causes) == 0 {
+		message := e.Message
+		if strings.HasPrefix(message, "value must be one of") && len(message) > 200 {
+			message = "value is not one of the allowed values"
+		}
+		return []ValidationError{{Path: e.InstanceLocation, Message: message}}
+	}
+
+	errs := []ValidationError{}
+	for _, cause := range e.Causes {
+		errs = append(errs, schemaErrors(cause)...)
+	}
+	return errs
+}
+
+// check validates the rules that are not covered by the schema, a
+// config failing these is not loaded.
+func check(c Config) []ValidationError {
+	errs := []ValidationError{}
+	add := func(path string, format string, args ...any) {
+		errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	schedules := []struct {
+		path     string
+		name     string
+		schedule string
+	}{
+		{"/sync_schedule", "sync schedule", c.SyncSchedule},
+		{"/notifications/schedule", "notifications schedule", c.Notifications.Schedule},
+		{"/email_import/schedule", "email import schedule", c.EmailImport.Schedule},
+		{"/bank_feeds/schedule", "bank feeds schedule", c.BankFeeds.Schedule},
+		{"/crypto/schedule", "crypto schedule", c.Crypto.Schedule},
+	}
+	for _, s := range schedules {
+		if s.schedule == "" {
+			continue
+		}
+		if _, err := scheduler.Parse(s.schedule); err != nil {
+			add(s.path, "Invalid %s: %s", s.name, err)
+		}
+	}
+
+	for i, st := range c.ScheduledTransactions {
+		if _, err := scheduler.Parse("0 0 " + st.Schedule); err != nil {
+			add(fmt.Sprintf("/scheduled_transactions/%d/schedule", i), "Invalid schedule for scheduled transaction %s: %s", st.Name, err)
+		}
+	}
+
+	for i, action := range c.CorporateActions {
+		if action.Ratio == "" && action.Type != Rename {
+			add(fmt.Sprintf("/corporate_actions/%d", i), "Missing ratio for the %s of %s", action.Type, action.Commodity)
+		}
+		if action.To == "" && (action.Type == Merger || action.Type == Rename) {
+			add(fmt.Sprintf("/corporate_actions/%d", i), "Missing to for the %s of %s", action.Type, action.Commodity)
+		}
+	}
+
+	for i, rule := range c.ImportRules {
+		if _, err := regexp.Compile(rule.Match); err != nil {
+			add(fmt.Sprintf("/import_rules/%d/match", i), "Invalid match for import rule %s: %s", rule.Account, err)
+		}
+	}
+
+	return errs
+}
+
+// checkAppreciationCodes validates the codes of the appreciation
+// provider, which is either an annual rate or index:{commodity}. The
+// index may have only the prices from the journal, so it's not
+// required to be in the config.
+func checkAppreciationCodes(commodities []Commodity) []ValidationError {
+	errs := []ValidationError{}
+	for i, commodity := range commodities {
+		prices := append([]Price{commodity.Price}, commodity.Fallbacks...)
+		for j, price := range prices {
+			if price.Provider != "appreciation" {
+				continue
+			}
+
+			path := fmt.Sprintf("/commodities/%d/price/code", i)
+			if j > 0 {
+				path = fmt.Sprintf("/commodities/%d/fallbacks/%d/code", i, j-1)
+			}
+
+			if index, ok := strings.CutPrefix(price.Code, "index:"); ok {
+				if index == commodity.Name {
+					errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("Commodity %s can't follow its own price", commodity.Name)})
+				}
+				continue
+			}
+
+			if _, err := strconv.ParseFloat(price.Code, 64); err != nil {
+				errs = append(errs, ValidationError{Path: path, Message: fmt.Sprintf("Invalid appreciation code %s for %s, use a rate like 6.5 or index:{commodity}", price.Code, commodity.Name)})
+			}
+		}
+	}
+	return errs
+}
+
+// checkAccountPatterns validates the account patterns anywhere in the
+// config, the lists named accounts or ending with _accounts and the
+// savings and expenses of the retirement goals. An invalid pattern
+// would otherwise fail only when the page using it is opened.
+func checkAccountPatterns(node *yaml.Node, path string) []ValidationError {
+	errs := []ValidationError{}
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			errs = append(errs, checkAccountPatterns(child, path)...)
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			errs = append(errs, checkAccountPatterns(child, fmt.Sprintf("%s/%d", path, i))...)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+			childPath := path + "/" + escapePointer(key)
+			if value.Kind == yaml.SequenceNode && isAccountPatternList(key) {
+				errs = append(errs, checkAccountPatternList(value, childPath)...)
+				continue
+			}
+			errs = append(errs, checkAccountPatterns(value, childPath)...)
+		}
+	}
+	return errs
+}
+
+func isAccountPatternList(key string) bool {
+	return key == "accounts" || strings.HasSuffix(key, "_accounts") || key == "savings" || key == "expenses"
+}
+
+func checkAccountPatternList(node *yaml.Node, path string) []ValidationError {
+	errs := []ValidationError{}
+	negated, regular := 0, 0
+	for i, child := range node.Content {
+		if child.Kind != yaml.ScalarNode {
+			errs = append(errs, checkAccountPatterns(child, fmt.Sprintf("%s/%d", path, i))...)
+			continue
+		}
+
+		if message := checkAccountPattern(child.Value); message != "" {
+			errs = append(errs, ValidationError{Path: fmt.Sprintf("%s/%d", path, i), Message: message})
+		}
+		if strings.HasPrefix(child.Value, "!") {
+			negated++
+		} else {
+			regular++
+		}
+	}
+
+	if negated > 0 && regular > 0 {
+		errs = append(errs, ValidationError{Path: path, Message: "Negated account patterns can't be mixed with the others"})
+	}
+	return errs
+}
+
+func checkAccountPattern(pattern string) string {
+	account := strings.TrimPrefix(pattern, "!")
+	if strings.TrimSpace(account) == "" {
+		return "Account pattern can't be empty"
+	}
+	if _, err := filepath.Match(account, ""); err != nil {
+		return fmt.Sprintf("Invalid account pattern %s: %s", pattern, err)
+	}
+	if strings.HasPrefix(account, ":") || strings.HasSuffix(account, ":") || strings.Contains(account, "::") {
+		return fmt.Sprintf("Invalid account pattern %s: empty account name between :", pattern)
+	}
+	return ""
+}
+
+// locate fills in the line and the column of the errors. The
+// duplicate items are reported on the array by the schema, they are
+// pointed to the repeated item instead.
+func locate(root *yaml.Node, errs []ValidationError) []ValidationError {
+	for i, e := range errs {
+		if rest, ok := strings.CutPrefix(e.Message, "duplicate "); ok {
+			property, value, _ := strings.Cut(rest, " ")
+			if _, items := find(root, e.Path); items != nil {
+				if index := duplicateItem(items, property, value); index >= 0 {
+					errs[i].Path = fmt.Sprintf("%s/%d", e.Path, index)
+				}
+			}
+		}
+
+		if errs[i].Path == "" {
+			continue
+		}
+		if position, _ := find(root, errs[i].Path); position != nil {
+			errs[i].Line = position.Line
+			errs[i].Column = position.Column
+		}
+	}
+	return errs
+}
+
+// find returns the node to point to for the JSON pointer, which is the
+// key for the values of a mapping, along with the value itself. If the
+// pointer goes past the file, the deepest node found is returned and
+// the value is nil.
+func find(root *yaml.Node, pointer string) (*yaml.Node, *yaml.Node) {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	position := node
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		if node == nil {
+			break
+		}
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == token {
+					position = node.Content[i]
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			if index, err := strconv.Atoi(token); err == nil && index < len(node.Content) {
+				position = node.Content[index]
+				next = position
+			}
+		}
+		node = next
+	}
+	return position, node
+}
+
+// duplicateItem returns the index of the second item of the sequence
+// with the value for the property.
+func duplicateItem(node *yaml.Node, property string, value string) int {
+	if node.Kind != yaml.SequenceNode {
+		return -1
+	}
+
+	seen := false
+	for i, item := range node.Content {
+		for j := 0; item.Kind == yaml.MappingNode && j+1 < len(item.Content); j += 2 {
+			if item.Content[j].Value == property && item.Content[j+1].Value == value {
+				if seen {
+					return i
+				}
+				seen = true
+			}
+		}
+	}
+	return -1
+}
+
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -0,0 +1,118 @@
+package corporateaction
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/model/price"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+type action struct {
+	commodity string
+	date      time.Time
+	factor    decimal.Decimal
+	to        string
+}
+
+// parse turns the configured actions into the factor the units get
+// multiplied by, ordered by the date so that a split of the renamed
+// commodity applies to the units held before the rename as well.
+func parse(actions []config.CorporateAction) []action {
+	parsed := []action{}
+	for _, a := range actions {
+		date, err := time.ParseInLocation("2006-01-02", a.Date, config.TimeZone())
+		if err != nil {
+			log.Warnf("Invalid date %s for the %s of %s", a.Date, a.Type, a.Commodity)
+			continue
+		}
+
+		factor := decimal.NewFromInt(1)
+		if a.Type != config.Rename {
+			parts := strings.Split(a.Ratio, ":")
+			left, errLeft := decimal.NewFromString(parts[0])
+			right, errRight := decimal.NewFromString(parts[len(parts)-1])
+			if len(parts) != 2 || errLeft != nil || errRight != nil || left.IsZero() || right.IsZero() {
+				log.Warnf("Invalid ratio %s for the %s of %s", a.Ratio, a.Type, a.Commodity)
+				continue
+			}
+
+			if a.Type == config.Bonus {
+				factor = left.Add(right).Div(right)
+			} else {
+				factor = left.Div(right)
+			}
+		}
+
+		to := ""
+		if a.Type == config.Merger || a.Type == config.Rename {
+			to = a.To
+		}
+
+		parsed = append(parsed, action{commodity: a.Commodity, date: date, factor: factor, to: to})
+	}
+
+	sort.SliceStable(parsed, func(i, j int) bool { return parsed[i].date.Before(parsed[j].date) })
+	return parsed
+}
+
+// AdjustPostings restates the postings before each action in the
+// units after it, the amount and thus the cost stay the same. The
+// merged and the renamed commodities are moved to the new commodity.
+func AdjustPostings(postings []*posting.Posting, actions []config.CorporateAction) {
+	for _, a := range parse(actions) {
+		for _, p := range postings {
+			if p.Commodity != a.commodity || !p.Date.Before(a.date) {
+				continue
+			}
+
+			p.Quantity = p.Quantity.Mul(a.factor)
+			if a.to != "" {
+				p.Commodity = a.to
+			}
+		}
+	}
+}
+
+// AdjustPrices restates the prices before each action as the price of
+// the units after it.
+func AdjustPrices(prices []price.Price, actions []config.CorporateAction) []price.Price {
+	for _, a := range parse(actions) {
+		prices = lo.Map(prices, func(p price.Price, _ int) price.Price {
+			if p.CommodityName != a.commodity || !p.Date.Before(a.date) {
+				return p
+			}
+
+			p.Value = p.Value.Div(a.factor)
+			if a.to != "" {
+				p.CommodityName = a.to
+			}
+			return p
+		})
+	}
+	return prices
+}
+
+// AdjustFetchedPrices restates the price history of the commodity
+// fetched from a provider which doesn't adjust for the splits and the
+// bonuses itself. The history stays with the commodity it's fetched
+// for, even if it's renamed later.
+func AdjustFetchedPrices(commodity string, prices []*price.Price, actions []config.CorporateAction) []*price.Price {
+	for _, a := range parse(actions) {
+		if a.commodity != commodity {
+			continue
+		}
+
+		for _, p := range prices {
+			if p.Date.Before(a.date) {
+				p.Value = p.Value.Div(a.factor)
+			}
+		}
+	}
+	return prices
+}
@@ -0,0 +1,60 @@
+package corporateaction
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/model/price"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(value string) time.Time {
+	d, _ := time.ParseInLocation("2006-01-02", value, config.TimeZone())
+	return d
+}
+
+func buy(on string, commodity string, quantity int64, amount int64) *posting.Posting {
+	return &posting.Posting{
+		Date:      date(on),
+		Account:   "Assets:Equity:" + commodity,
+		Commodity: commodity,
+		Quantity:  decimal.NewFromInt(quantity),
+		Amount:    decimal.NewFromInt(amount),
+	}
+}
+
+func TestAdjustPostings(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	actions := []config.CorporateAction{
+		{Type: config.Split, Commodity: "NEWCO", Date: "2023-06-01", Ratio: "2:1"},
+		{Type: config.Rename, Commodity: "OLDCO", Date: "2022-01-01", To: "NEWCO"},
+		{Type: config.Bonus, Commodity: "BANK", Date: "2022-01-01", Ratio: "1:2"},
+	}
+
+	postings := []*posting.Posting{
+		buy("2021-05-01", "OLDCO", 10, 1000),
+		buy("2022-05-01", "NEWCO", 10, 1500),
+		buy("2023-07-01", "NEWCO", 10, 900),
+		buy("2021-05-01", "BANK", 10, 1000),
+	}
+	AdjustPostings(postings, actions)
+
+	assert.Equal(t, "NEWCO", postings[0].Commodity)
+	assert.Equal(t, "20", postings[0].Quantity.String())
+	assert.Equal(t, "1000", postings[0].Amount.String())
+	assert.Equal(t, "20", postings[1].Quantity.String())
+	assert.Equal(t, "10", postings[2].Quantity.String())
+	assert.Equal(t, "15", postings[3].Quantity.String())
+
+	prices := AdjustPrices([]price.Price{
+		{Date: date("2021-12-31"), CommodityName: "OLDCO", Value: decimal.NewFromInt(120)},
+		{Date: date("2023-06-01"), CommodityName: "NEWCO", Value: decimal.NewFromInt(90)},
+	}, actions)
+	assert.Equal(t, "NEWCO", prices[0].CommodityName)
+	assert.Equal(t, "60", prices[0].Value.String())
+	assert.Equal(t, "90", prices[1].Value.String())
+}
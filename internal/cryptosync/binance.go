@@ -0,0 +1,196 @@
+package cryptosync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+)
+
+const BINANCE_URL = "https://api.binance.com"
+
+// the trades can only be listed per market, the markets of the held
+// assets against these are looked at along with the configured ones
+var binanceQuotes = []string{"USDT", "USDC", "FDUSD", "BTC", "ETH", "BNB", "EUR"}
+
+var client = &http.Client{Timeout: 60 * time.Second}
+
+type binance struct {
+	config config.CryptoExchange
+}
+
+type binanceError struct {
+	Code    int    `json:"code"`
+	Message string `json:"msg"`
+}
+
+func (e *binanceError) Error() string {
+	return fmt.Sprintf("Binance request failed: %d %s", e.Code, e.Message)
+}
+
+// invalid symbol, the market doesn't exist
+const binanceInvalidSymbol = -1121
+
+func (b *binance) Fetch(since time.Time) (Activity, error) {
+	activity := Activity{Balances: make(map[string]decimal.Decimal)}
+
+	var account struct {
+		Balances []struct {
+			Asset  string          `json:"asset"`
+			Free   decimal.Decimal `json:"free"`
+			Locked decimal.Decimal `json:"locked"`
+		} `json:"balances"`
+	}
+	err := b.request("/api/v3/account", url.Values{}, &account)
+	if err != nil {
+		return activity, err
+	}
+	for _, balance := range account.Balances {
+		total := balance.Free.Add(balance.Locked)
+		if !total.IsZero() {
+			activity.Balances[balance.Asset] = total
+		}
+	}
+
+	start := strconv.FormatInt(since.UnixMilli(), 10)
+	for _, market := range b.markets(lo.Keys(activity.Balances)) {
+		var trades []struct {
+			ID              int64           `json:"id"`
+			Qty             decimal.Decimal `json:"qty"`
+			QuoteQty        decimal.Decimal `json:"quoteQty"`
+			Commission      decimal.Decimal `json:"commission"`
+			CommissionAsset string          `json:"commissionAsset"`
+			Time            int64           `json:"time"`
+			IsBuyer         bool            `json:"isBuyer"`
+		}
+		err := b.request("/api/v3/myTrades", url.Values{"symbol": {market[0] + market[1]}, "startTime": {start}, "limit": {"1000"}}, &trades)
+		if err != nil {
+			if e, ok := err.(*binanceError); ok && e.Code == binanceInvalidSymbol {
+				continue
+			}
+			return activity, err
+		}
+
+		for _, t := range trades {
+			trade := Trade{
+				ID:        fmt.Sprintf("trade:%s%s:%d", market[0], market[1], t.ID),
+				Date:      time.UnixMilli(t.Time).In(config.TimeZone()),
+				Fee:       t.CommissionAsset,
+				FeeAmount: t.Commission,
+			}
+			if t.IsBuyer {
+				trade.Bought, trade.BoughtAmount, trade.Sold, trade.SoldAmount = market[0], t.Qty, market[1], t.QuoteQty
+			} else {
+				trade.Bought, trade.BoughtAmount, trade.Sold, trade.SoldAmount = market[1], t.QuoteQty, market[0], t.Qty
+			}
+			activity.Trades = append(activity.Trades, trade)
+		}
+	}
+
+	var deposits []struct {
+		ID         string          `json:"id"`
+		Amount     decimal.Decimal `json:"amount"`
+		Coin       string          `json:"coin"`
+		Status     int             `json:"status"`
+		InsertTime int64           `json:"insertTime"`
+	}
+	err = b.request("/sapi/v1/capital/deposit/hisrec", url.Values{"startTime": {start}}, &deposits)
+	if err != nil {
+		return activity, err
+	}
+	for _, d := range deposits {
+		// success
+		if d.Status != 1 {
+			continue
+		}
+		activity.Transfers = append(activity.Transfers, Transfer{ID: "deposit:" + d.ID, Date: time.UnixMilli(d.InsertTime).In(config.TimeZone()), Kind: Deposit, Asset: d.Coin, Amount: d.Amount})
+	}
+
+	var withdrawals []struct {
+		ID             string          `json:"id"`
+		Amount         decimal.Decimal `json:"amount"`
+		TransactionFee decimal.Decimal `json:"transactionFee"`
+		Coin           string          `json:"coin"`
+		Status         int             `json:"status"`
+		ApplyTime      string          `json:"applyTime"`
+	}
+	err = b.request("/sapi/v1/capital/withdraw/history", url.Values{"startTime": {start}}, &withdrawals)
+	if err != nil {
+		return activity, err
+	}
+	for _, w := range withdrawals {
+		// completed
+		if w.Status != 6 {
+			continue
+		}
+		date, err := time.ParseInLocation("2006-01-02 15:04:05", w.ApplyTime, time.UTC)
+		if err != nil {
+			continue
+		}
+		activity.Transfers = append(activity.Transfers, Transfer{ID: "withdrawal:" + w.ID, Date: date.In(config.TimeZone()), Kind: Withdrawal, Asset: w.Coin, Amount: w.Amount, Fee: w.TransactionFee})
+	}
+
+	return activity, nil
+}
+
+// markets returns the base and the quote asset of the markets to list
+// the trades of, the configured symbols are written as BASE/QUOTE.
+func (b *binance) markets(assets []string) [][2]string {
+	markets := [][2]string{}
+	for _, symbol := range b.config.Symbols {
+		base, quote, found := strings.Cut(strings.ToUpper(symbol), "/")
+		if found {
+			markets = append(markets, [2]string{base, quote})
+		}
+	}
+
+	for _, asset := range assets {
+		for _, quote := range binanceQuotes {
+			if asset != quote {
+				markets = append(markets, [2]string{asset, quote})
+			}
+		}
+	}
+	return lo.Uniq(markets)
+}
+
+func (b *binance) request(path string, params url.Values, out any) error {
+	params.Set("timestamp", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	params.Set("recvWindow", "10000")
+	query := params.Encode()
+	mac := hmac.New(sha256.New, []byte(b.config.APISecret))
+	mac.Write([]byte(query))
+	query += "&signature=" + hex.EncodeToString(mac.Sum(nil))
+
+	req, err := http.NewRequest(http.MethodGet, BINANCE_URL+path+"?"+query, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-MBX-APIKEY", b.config.APIKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		failure := &binanceError{}
+		err = json.NewDecoder(resp.Body).Decode(failure)
+		if err != nil {
+			return fmt.Errorf("Binance request failed: status %d", resp.StatusCode)
+		}
+		return failure
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,95 @@
+package cryptosync
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+const BLOCKSTREAM_URL = "https://blockstream.info/api"
+
+// addressBalance returns the confirmed balance of the address in BTC
+// or ETH. Bitcoin is looked up on Blockstream, ethereum on the
+// configured JSON-RPC node.
+func addressBalance(chain string, address string, rpc string) (decimal.Decimal, error) {
+	switch chain {
+	case BITCOIN:
+		return bitcoinBalance(address)
+	case ETHEREUM:
+		return ethereumBalance(address, rpc)
+	}
+	return decimal.Zero, fmt.Errorf("Unknown chain %s", chain)
+}
+
+func bitcoinBalance(address string) (decimal.Decimal, error) {
+	resp, err := client.Get(BLOCKSTREAM_URL + "/address/" + url.PathEscape(address))
+	if err != nil {
+		return decimal.Zero, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return decimal.Zero, fmt.Errorf("Blockstream request failed: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		ChainStats struct {
+			Funded int64 `json:"funded_txo_sum"`
+			Spent  int64 `json:"spent_txo_sum"`
+		} `json:"chain_stats"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	// satoshi
+	return decimal.New(result.ChainStats.Funded-result.ChainStats.Spent, -8), nil
+}
+
+func ethereumBalance(address string, rpc string) (decimal.Decimal, error) {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_getBalance",
+		"params":  []string{address, "latest"},
+	})
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	resp, err := client.Post(rpc, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return decimal.Zero, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return decimal.Zero, fmt.Errorf("Ethereum RPC request failed: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Result string `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if result.Error != nil {
+		return decimal.Zero, fmt.Errorf("Ethereum RPC request failed: %s", result.Error.Message)
+	}
+
+	wei, ok := new(big.Int).SetString(strings.TrimPrefix(result.Result, "0x"), 16)
+	if !ok {
+		return decimal.Zero, fmt.Errorf("Invalid balance %s", result.Result)
+	}
+	return decimal.NewFromBigInt(wei, -18), nil
+}
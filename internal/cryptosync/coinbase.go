@@ -0,0 +1,219 @@
+package cryptosync
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	COINBASE_HOST = "api.coinbase.com"
+	// validity of the token signed for a request
+	coinbaseTokenTTL = 2 * time.Minute
+)
+
+var coinbaseRewardTypes = []string{"staking_reward", "interest", "inflation_reward", "earn_payout", "learning_reward"}
+
+// coinbase reads the wallets of the Coinbase app with a CDP API key,
+// the key name goes in api_key and the EC private key in api_secret.
+type coinbase struct {
+	config config.CryptoExchange
+}
+
+type coinbaseMoney struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+type coinbaseAccount struct {
+	ID       string `json:"id"`
+	Currency struct {
+		Code string `json:"code"`
+		Type string `json:"type"`
+	} `json:"currency"`
+	Balance coinbaseMoney `json:"balance"`
+}
+
+type coinbaseTransaction struct {
+	ID           string        `json:"id"`
+	Type         string        `json:"type"`
+	Status       string        `json:"status"`
+	Amount       coinbaseMoney `json:"amount"`
+	NativeAmount coinbaseMoney `json:"native_amount"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+type coinbaseError struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
+type coinbasePage[T any] struct {
+	Pagination struct {
+		NextURI string `json:"next_uri"`
+	} `json:"pagination"`
+	Data []T `json:"data"`
+}
+
+// Fetch maps the transactions of the crypto wallets. The buys, the
+// sells and the conversions are booked against the native currency of
+// the user, so a conversion shows up as a sale and a purchase. The
+// fiat wallets only contribute the deposits and the withdrawals.
+func (c *coinbase) Fetch(since time.Time) (Activity, error) {
+	activity := Activity{Balances: make(map[string]decimal.Decimal)}
+
+	accounts, err := coinbaseList[coinbaseAccount](c, "/v2/accounts?limit=100", func(coinbaseAccount) bool { return true })
+	if err != nil {
+		return activity, err
+	}
+
+	for _, account := range accounts {
+		if !account.Balance.Amount.IsZero() {
+			activity.Balances[account.Currency.Code] = account.Balance.Amount
+		}
+
+		transactions, err := coinbaseList[coinbaseTransaction](c, "/v2/accounts/"+account.ID+"/transactions?limit=100", func(t coinbaseTransaction) bool {
+			return !t.CreatedAt.Before(since)
+		})
+		if err != nil {
+			return activity, err
+		}
+
+		fiat := account.Currency.Type == "fiat"
+		for _, t := range transactions {
+			if t.Status != "completed" || t.CreatedAt.Before(since) {
+				continue
+			}
+
+			date := t.CreatedAt.In(config.TimeZone())
+			amount := t.Amount.Amount
+			native := t.NativeAmount.Amount.Abs()
+			switch {
+			case !fiat && lo.Contains([]string{"buy", "sell", "trade", "advanced_trade_fill"}, t.Type):
+				trade := Trade{ID: t.ID, Date: date}
+				if amount.IsPositive() {
+					trade.Bought, trade.BoughtAmount, trade.Sold, trade.SoldAmount = t.Amount.Currency, amount, t.NativeAmount.Currency, native
+				} else {
+					trade.Bought, trade.BoughtAmount, trade.Sold, trade.SoldAmount = t.NativeAmount.Currency, native, t.Amount.Currency, amount.Abs()
+				}
+				activity.Trades = append(activity.Trades, trade)
+			case t.Type == "send" || t.Type == "fiat_deposit" || t.Type == "fiat_withdrawal":
+				activity.Transfers = append(activity.Transfers, Transfer{ID: t.ID, Date: date, Kind: lo.Ternary(amount.IsPositive(), Deposit, Withdrawal), Asset: t.Amount.Currency, Amount: amount.Abs()})
+			case lo.Contains(coinbaseRewardTypes, t.Type) && amount.IsPositive():
+				activity.Transfers = append(activity.Transfers, Transfer{ID: t.ID, Date: date, Kind: Reward, Asset: t.Amount.Currency, Amount: amount})
+			}
+		}
+	}
+
+	return activity, nil
+}
+
+// coinbaseList follows the pages, newest first, as long as the last
+// item of the page is wanted.
+func coinbaseList[T any](c *coinbase, path string, wanted func(T) bool) ([]T, error) {
+	items := []T{}
+	for path != "" {
+		var page coinbasePage[T]
+		err := c.request(path, &page)
+		if err != nil {
+			return items, err
+		}
+		items = append(items, page.Data...)
+
+		if len(page.Data) == 0 || !wanted(page.Data[len(page.Data)-1]) {
+			break
+		}
+		path = page.Pagination.NextURI
+	}
+	return items, nil
+}
+
+func (c *coinbase) request(path string, out any) error {
+	token, err := c.token("GET " + COINBASE_HOST + strings.SplitN(path, "?", 2)[0])
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://"+COINBASE_HOST+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Errors []coinbaseError `json:"errors"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		messages := lo.Map(failure.Errors, func(e coinbaseError, _ int) string { return e.Message })
+		return fmt.Errorf("Coinbase request failed: status %d %s", resp.StatusCode, strings.Join(messages, ", "))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// token signs a JWT for the request with the EC private key of the
+// API key, as required by the Coinbase Developer Platform.
+func (c *coinbase) token(uri string) (string, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(c.config.APISecret, `\n`, "\n")))
+	if block == nil {
+		return "", errors.New("Invalid Coinbase API secret, expected the EC private key in the PEM format")
+	}
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, 16)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT", "kid": c.config.APIKey, "nonce": hex.EncodeToString(nonce)})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"sub": c.config.APIKey,
+		"iss": "cdp",
+		"nbf": now.Unix(),
+		"exp": now.Add(coinbaseTokenTTL).Unix(),
+		"uri": uri,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
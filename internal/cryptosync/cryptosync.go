@@ -0,0 +1,287 @@
+// Package cryptosync pulls the trades and the transfers from the
+// crypto exchanges with read only API keys, and the balances of the
+// on-chain addresses. The trades and the transfers become drafts in
+// the import review queue, the balances are compared with the journal.
+package cryptosync
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/draft"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	BINANCE  = "binance"
+	COINBASE = "coinbase"
+	KRAKEN   = "kraken"
+
+	BITCOIN  = "bitcoin"
+	ETHEREUM = "ethereum"
+)
+
+var assetRegex = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// Trade exchanges one asset for another, the fee is charged in the
+// fee asset on top.
+type Trade struct {
+	ID           string
+	Date         time.Time
+	Sold         string
+	SoldAmount   decimal.Decimal
+	Bought       string
+	BoughtAmount decimal.Decimal
+	Fee          string
+	FeeAmount    decimal.Decimal
+}
+
+// TransferKind tells apart the money moved in or out of the exchange
+// from the rewards like staking and interest.
+type TransferKind string
+
+const (
+	Deposit    TransferKind = "deposit"
+	Withdrawal TransferKind = "withdrawal"
+	Reward     TransferKind = "reward"
+)
+
+// Transfer moves the asset in or out of the exchange. Amount is
+// always positive, the fee is charged in the same asset.
+type Transfer struct {
+	ID     string
+	Date   time.Time
+	Kind   TransferKind
+	Asset  string
+	Amount decimal.Decimal
+	Fee    decimal.Decimal
+}
+
+type Activity struct {
+	Trades    []Trade
+	Transfers []Transfer
+	// balances by the asset
+	Balances map[string]decimal.Decimal
+}
+
+type Exchange interface {
+	// Fetch returns the activity since the date along with the current
+	// balances.
+	Fetch(since time.Time) (Activity, error)
+}
+
+// Holding compares the balance reported by the exchange or the chain
+// with the balance in the journal.
+type Holding struct {
+	Source   string          `json:"source"`
+	Account  string          `json:"account"`
+	Asset    string          `json:"asset"`
+	Reported decimal.Decimal `json:"reported"`
+	Journal  decimal.Decimal `json:"journal"`
+	SyncedAt time.Time       `json:"synced_at"`
+}
+
+var (
+	holdings   []Holding
+	holdingsMu sync.Mutex
+)
+
+// Holdings returns the balances as of the last sync.
+func Holdings() []Holding {
+	holdingsMu.Lock()
+	defer holdingsMu.Unlock()
+	return append([]Holding{}, holdings...)
+}
+
+func NewExchange(c config.CryptoExchange) (Exchange, error) {
+	switch c.Exchange {
+	case BINANCE:
+		return &binance{config: c}, nil
+	case COINBASE:
+		return &coinbase{config: c}, nil
+	case KRAKEN:
+		return &kraken{config: c}, nil
+	}
+	return nil, fmt.Errorf("Unknown exchange %s", c.Exchange)
+}
+
+// Sync pulls all the configured exchanges and wallets. A failing
+// source doesn't stop the others. Returns the number of drafts
+// created.
+func Sync(db *gorm.DB) (int, error) {
+	c := config.GetConfig().Crypto
+	since := utils.Now().AddDate(0, 0, -c.LookbackDays)
+	created := 0
+	var errs []error
+	var latest []Holding
+
+	for _, e := range c.Exchanges {
+		exchange, err := NewExchange(e)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		activity, err := exchange.Fetch(since)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", e.Exchange, err))
+			continue
+		}
+
+		for _, d := range exchangeDrafts(db, e, activity) {
+			draft.Create(db, &d)
+			created++
+		}
+
+		for asset, balance := range activity.Balances {
+			account := assetAccount(e.Account, asset)
+			latest = append(latest, Holding{Source: e.Exchange, Account: account, Asset: asset, Reported: balance, Journal: journalBalance(db, account, asset), SyncedAt: time.Now()})
+		}
+	}
+
+	for _, w := range c.Wallets {
+		asset := lo.Ternary(w.Chain == BITCOIN, "BTC", "ETH")
+		balance, err := addressBalance(w.Chain, w.Address, c.EthereumRPC)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", w.Chain, w.Address, err))
+			continue
+		}
+
+		holding := Holding{Source: w.Chain, Account: w.Account, Asset: asset, Reported: balance, Journal: journalBalance(db, w.Account, asset), SyncedAt: time.Now()}
+		latest = append(latest, holding)
+
+		d, ok := adjustmentDraft(db, w, holding)
+		if ok {
+			draft.Create(db, &d)
+			created++
+		}
+	}
+
+	holdingsMu.Lock()
+	holdings = latest
+	holdingsMu.Unlock()
+
+	log.Infof("Imported %d drafts from the crypto exchanges and wallets", created)
+	return created, errors.Join(errs...)
+}
+
+func exchangeDrafts(db *gorm.DB, e config.CryptoExchange, activity Activity) []draft.Draft {
+	drafts := []draft.Draft{}
+	feesAccount := lo.Ternary(e.FeesAccount != "", e.FeesAccount, "Expenses:Charges")
+
+	for _, t := range activity.Trades {
+		if draft.Exists(db, e.Exchange, t.ID) || t.BoughtAmount.IsZero() {
+			continue
+		}
+
+		price := t.SoldAmount.Div(t.BoughtAmount).Round(8)
+		postings := []journal.Posting{
+			{Account: assetAccount(e.Account, t.Bought), Amount: fmt.Sprintf("%s @ %s", journal.FormatAmount(t.BoughtAmount, t.Bought), journal.FormatAmount(price, t.Sold))},
+			{Account: assetAccount(e.Account, t.Sold), Amount: journal.FormatAmount(t.SoldAmount.Neg(), t.Sold)},
+		}
+		if t.FeeAmount.IsPositive() {
+			postings = append(postings,
+				journal.Posting{Account: feesAccount, Amount: journal.FormatAmount(t.FeeAmount, t.Fee)},
+				journal.Posting{Account: assetAccount(e.Account, t.Fee), Amount: journal.FormatAmount(t.FeeAmount.Neg(), t.Fee)})
+		}
+
+		payee := fmt.Sprintf("Buy %s %s with %s", t.BoughtAmount.String(), t.Bought, t.Sold)
+		drafts = append(drafts, newDraft(e.Exchange, t.ID, t.Date, payee, assetAccount(e.Account, t.Bought), t.BoughtAmount, postings))
+	}
+
+	for _, t := range activity.Transfers {
+		if draft.Exists(db, e.Exchange, t.ID) || t.Amount.IsZero() {
+			continue
+		}
+
+		account := assetAccount(e.Account, t.Asset)
+		var postings []journal.Posting
+		var payee string
+		switch t.Kind {
+		case Deposit:
+			payee = "Deposit " + t.Asset
+			postings = []journal.Posting{
+				{Account: account, Amount: journal.FormatAmount(t.Amount.Sub(t.Fee), t.Asset)},
+				{Account: lo.Ternary(e.TransferAccount != "", e.TransferAccount, "Assets:Unknown"), Amount: journal.FormatAmount(t.Amount.Neg(), t.Asset)},
+			}
+		case Withdrawal:
+			payee = "Withdrawal " + t.Asset
+			postings = []journal.Posting{
+				{Account: lo.Ternary(e.TransferAccount != "", e.TransferAccount, "Assets:Unknown"), Amount: journal.FormatAmount(t.Amount, t.Asset)},
+				{Account: account, Amount: journal.FormatAmount(t.Amount.Add(t.Fee).Neg(), t.Asset)},
+			}
+		case Reward:
+			payee = "Reward " + t.Asset
+			postings = []journal.Posting{
+				{Account: account, Amount: journal.FormatAmount(t.Amount.Sub(t.Fee), t.Asset)},
+				{Account: lo.Ternary(e.IncomeAccount != "", e.IncomeAccount, "Income:Crypto") + ":" + sanitize(t.Asset), Amount: journal.FormatAmount(t.Amount.Neg(), t.Asset)},
+			}
+		}
+		if t.Fee.IsPositive() {
+			postings = append(postings, journal.Posting{Account: feesAccount, Amount: journal.FormatAmount(t.Fee, t.Asset)})
+		}
+
+		drafts = append(drafts, newDraft(e.Exchange, t.ID, t.Date, payee, account, t.Amount, postings))
+	}
+
+	return drafts
+}
+
+// adjustmentDraft books the difference between the balance of the
+// address and the journal. The same balance is adjusted only once,
+// even if the draft is rejected.
+func adjustmentDraft(db *gorm.DB, w config.CryptoWallet, h Holding) (draft.Draft, bool) {
+	difference := h.Reported.Sub(h.Journal)
+	if difference.IsZero() {
+		return draft.Draft{}, false
+	}
+
+	id := fmt.Sprintf("%s:%s", w.Address, h.Reported.String())
+	if draft.Exists(db, w.Chain, id) {
+		return draft.Draft{}, false
+	}
+
+	postings := []journal.Posting{
+		{Account: w.Account, Amount: journal.FormatAmount(difference, h.Asset)},
+		{Account: lo.Ternary(w.AdjustmentAccount != "", w.AdjustmentAccount, "Assets:Unknown"), Amount: journal.FormatAmount(difference.Neg(), h.Asset)},
+	}
+	return newDraft(w.Chain, id, utils.Now(), fmt.Sprintf("%s balance adjustment", h.Asset), w.Account, difference, postings), true
+}
+
+func journalBalance(db *gorm.DB, account string, asset string) decimal.Decimal {
+	postings := query.Init(db).IncludeExcluded().Where("account = ? AND commodity = ?", account, asset).UntilToday().All()
+	return utils.SumBy(postings, func(p posting.Posting) decimal.Decimal { return p.Quantity })
+}
+
+func newDraft(source string, id string, date time.Time, payee string, account string, amount decimal.Decimal, postings []journal.Posting) draft.Draft {
+	return draft.Draft{
+		Source:     source,
+		ExternalID: id,
+		Date:       date,
+		Payee:      payee,
+		Account:    account,
+		Amount:     amount,
+		Content:    journal.Transaction{Date: date, Payee: payee, Postings: postings}.Format(),
+		Status:     draft.Pending,
+	}
+}
+
+func assetAccount(parent string, asset string) string {
+	return parent + ":" + sanitize(asset)
+}
+
+func sanitize(asset string) string {
+	return strings.Trim(assetRegex.ReplaceAllString(asset, "_"), "_")
+}
@@ -0,0 +1,34 @@
+package cryptosync
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKrakenActivity(t *testing.T) {
+	assert.Equal(t, "BTC", krakenAsset("XXBT"))
+	assert.Equal(t, "ETH", krakenAsset("ETH.F"))
+	assert.Equal(t, "SOL", krakenAsset("SOL.S"))
+
+	trades, transfers := krakenActivity([]krakenEntry{
+		{ID: "L2", RefID: "T1", Time: 1700000000, Type: "trade", Asset: "XXBT", Amount: decimal.RequireFromString("0.01")},
+		{ID: "L1", RefID: "T1", Time: 1700000000, Type: "trade", Asset: "ZEUR", Amount: decimal.RequireFromString("-350"), Fee: decimal.RequireFromString("0.91")},
+		{ID: "L3", RefID: "D1", Time: 1690000000, Type: "deposit", Asset: "ZEUR", Amount: decimal.RequireFromString("500")},
+		{ID: "L4", RefID: "S1", Time: 1710000000, Type: "staking", Asset: "DOT.S", Amount: decimal.RequireFromString("0.2")},
+		{ID: "L5", RefID: "X1", Time: 1710000000, Type: "transfer", Subtype: "spottostaking", Asset: "DOT", Amount: decimal.RequireFromString("-10")},
+	})
+
+	assert.Len(t, trades, 1)
+	assert.Equal(t, "T1", trades[0].ID)
+	assert.Equal(t, "BTC", trades[0].Bought)
+	assert.Equal(t, "EUR", trades[0].Sold)
+	assert.Equal(t, "350", trades[0].SoldAmount.String())
+	assert.Equal(t, "EUR", trades[0].Fee)
+
+	assert.Len(t, transfers, 2)
+	assert.Equal(t, Deposit, transfers[0].Kind)
+	assert.Equal(t, Reward, transfers[1].Kind)
+	assert.Equal(t, "DOT", transfers[1].Asset)
+}
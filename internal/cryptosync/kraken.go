@@ -0,0 +1,216 @@
+package cryptosync
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+const KRAKEN_URL = "https://api.kraken.com"
+
+// the assets listed before Kraken dropped the X and Z prefixes
+var krakenAssets = map[string]string{
+	"XXBT": "BTC",
+	"XBT":  "BTC",
+	"XXDG": "DOGE",
+	"XDG":  "DOGE",
+	"XETH": "ETH",
+	"XETC": "ETC",
+	"XLTC": "LTC",
+	"XXRP": "XRP",
+	"XXLM": "XLM",
+	"XXMR": "XMR",
+	"XZEC": "ZEC",
+	"XREP": "REP",
+	"XMLN": "MLN",
+	"ZUSD": "USD",
+	"ZEUR": "EUR",
+	"ZGBP": "GBP",
+	"ZCAD": "CAD",
+	"ZJPY": "JPY",
+	"ZAUD": "AUD",
+	"ZCHF": "CHF",
+}
+
+type kraken struct {
+	config config.CryptoExchange
+}
+
+type krakenEntry struct {
+	ID      string          `json:"-"`
+	RefID   string          `json:"refid"`
+	Time    float64         `json:"time"`
+	Type    string          `json:"type"`
+	Subtype string          `json:"subtype"`
+	Asset   string          `json:"asset"`
+	Amount  decimal.Decimal `json:"amount"`
+	Fee     decimal.Decimal `json:"fee"`
+}
+
+// Fetch reads the ledger, which has an entry for each leg of a trade
+// and for each deposit, withdrawal and staking reward. The moves
+// between the spot and the staking wallets are left out as the
+// balances of both are merged.
+func (k *kraken) Fetch(since time.Time) (Activity, error) {
+	activity := Activity{Balances: make(map[string]decimal.Decimal)}
+
+	var balances map[string]decimal.Decimal
+	err := k.request("/0/private/Balance", url.Values{}, &balances)
+	if err != nil {
+		return activity, err
+	}
+	for asset, balance := range balances {
+		if !balance.IsZero() {
+			asset = krakenAsset(asset)
+			activity.Balances[asset] = activity.Balances[asset].Add(balance)
+		}
+	}
+
+	entries, err := k.ledger(since)
+	if err != nil {
+		return activity, err
+	}
+	activity.Trades, activity.Transfers = krakenActivity(entries)
+	return activity, nil
+}
+
+func (k *kraken) ledger(since time.Time) ([]krakenEntry, error) {
+	entries := []krakenEntry{}
+	for {
+		var page struct {
+			Ledger map[string]krakenEntry `json:"ledger"`
+			Count  int                    `json:"count"`
+		}
+		err := k.request("/0/private/Ledgers", url.Values{"start": {strconv.FormatInt(since.Unix(), 10)}, "ofs": {strconv.Itoa(len(entries))}}, &page)
+		if err != nil {
+			return entries, err
+		}
+
+		for id, entry := range page.Ledger {
+			entry.ID = id
+			entries = append(entries, entry)
+		}
+		if len(page.Ledger) == 0 || len(entries) >= page.Count {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// krakenActivity pairs the legs of the trades by the reference id,
+// the fee is taken from whichever leg it was charged on.
+func krakenActivity(entries []krakenEntry) ([]Trade, []Transfer) {
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time < entries[j].Time })
+
+	trades := []Trade{}
+	transfers := []Transfer{}
+	legs := make(map[string][]krakenEntry)
+	order := []string{}
+	for _, e := range entries {
+		date := time.Unix(int64(e.Time), 0).In(config.TimeZone())
+		asset := krakenAsset(e.Asset)
+		switch {
+		case e.Type == "trade" || e.Type == "spend" || e.Type == "receive":
+			if _, ok := legs[e.RefID]; !ok {
+				order = append(order, e.RefID)
+			}
+			legs[e.RefID] = append(legs[e.RefID], e)
+		case e.Type == "deposit" && e.Amount.IsPositive():
+			transfers = append(transfers, Transfer{ID: e.ID, Date: date, Kind: Deposit, Asset: asset, Amount: e.Amount, Fee: e.Fee})
+		case e.Type == "withdrawal" && e.Amount.IsNegative():
+			transfers = append(transfers, Transfer{ID: e.ID, Date: date, Kind: Withdrawal, Asset: asset, Amount: e.Amount.Abs(), Fee: e.Fee})
+		case (e.Type == "staking" || (e.Type == "earn" && e.Subtype == "reward")) && e.Amount.IsPositive():
+			transfers = append(transfers, Transfer{ID: e.ID, Date: date, Kind: Reward, Asset: asset, Amount: e.Amount, Fee: e.Fee})
+		}
+	}
+
+	for _, refID := range order {
+		trade := Trade{ID: refID}
+		for _, leg := range legs[refID] {
+			asset := krakenAsset(leg.Asset)
+			trade.Date = time.Unix(int64(leg.Time), 0).In(config.TimeZone())
+			if leg.Amount.IsNegative() {
+				trade.Sold, trade.SoldAmount = asset, leg.Amount.Abs()
+			} else {
+				trade.Bought, trade.BoughtAmount = asset, leg.Amount
+			}
+			if leg.Fee.IsPositive() && trade.FeeAmount.IsZero() {
+				trade.Fee, trade.FeeAmount = asset, leg.Fee
+			}
+		}
+
+		if trade.Sold != "" && trade.Bought != "" {
+			trades = append(trades, trade)
+		}
+	}
+
+	return trades, transfers
+}
+
+// krakenAsset drops the legacy prefixes and the suffixes of the
+// staking variants, ETH.S and ETH.F are both ETH.
+func krakenAsset(asset string) string {
+	asset, _, _ = strings.Cut(asset, ".")
+	if normalized, ok := krakenAssets[asset]; ok {
+		return normalized
+	}
+	return asset
+}
+
+func (k *kraken) request(path string, params url.Values, out any) error {
+	secret, err := base64.StdEncoding.DecodeString(k.config.APISecret)
+	if err != nil {
+		return fmt.Errorf("Invalid Kraken API secret: %w", err)
+	}
+
+	nonce := strconv.FormatInt(time.Now().UnixMicro(), 10)
+	params.Set("nonce", nonce)
+	body := params.Encode()
+
+	digest := sha256.Sum256([]byte(nonce + body))
+	mac := hmac.New(sha512.New, secret)
+	mac.Write(append([]byte(path), digest[:]...))
+
+	req, err := http.NewRequest(http.MethodPost, KRAKEN_URL+path, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("API-Key", k.config.APIKey)
+	req.Header.Set("API-Sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("Kraken request failed: status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Error  []string        `json:"error"`
+		Result json.RawMessage `json:"result"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&result)
+	if err != nil {
+		return err
+	}
+	if len(result.Error) > 0 {
+		return fmt.Errorf("Kraken request failed: %s", strings.Join(result.Error, ", "))
+	}
+	return json.Unmarshal(result.Result, out)
+}
@@ -0,0 +1,186 @@
+// Package csvimport reads the bank statements exported as CSV. The
+// columns and the account are described by a rules file, and the
+// transactions go through the same categorization as the bank feeds.
+package csvimport
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/bankfeed"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/locale"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+const SOURCE = "csv"
+
+// Columns are the names of the columns in the header. Either amount,
+// which is negative for the money going out, or debit and credit
+// should be given.
+type Columns struct {
+	Date        string `json:"date" yaml:"date"`
+	Payee       string `json:"payee" yaml:"payee"`
+	Description string `json:"description" yaml:"description"`
+	Amount      string `json:"amount" yaml:"amount"`
+	Debit       string `json:"debit" yaml:"debit"`
+	Credit      string `json:"credit" yaml:"credit"`
+}
+
+type Rules struct {
+	// account of the statement, example: Assets:Checking:HDFC
+	Account  string `json:"account" yaml:"account"`
+	Currency string `json:"currency" yaml:"currency"`
+	// same tokens as the date_format in the configuration
+	DateFormat       string `json:"date_format" yaml:"date_format"`
+	DecimalSeparator string `json:"decimal_separator" yaml:"decimal_separator"`
+	Delimiter        string `json:"delimiter" yaml:"delimiter"`
+	// number of lines before the header
+	Skip    int                 `json:"skip" yaml:"skip"`
+	Columns Columns             `json:"columns" yaml:"columns"`
+	Rules   []config.ImportRule `json:"rules" yaml:"rules"`
+}
+
+var nonNumericRegex = regexp.MustCompile(`[^0-9.\-]`)
+
+func LoadRules(path string) (Rules, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	var rules Rules
+	err = yaml.Unmarshal(content, &rules)
+	if err != nil {
+		return Rules{}, err
+	}
+	return rules, nil
+}
+
+// Parse reads the transactions of the statement. Identical rows are
+// told apart by the number of times they occur, so the id stays the
+// same when the overlapping statements are imported again.
+func Parse(content string, rules Rules) ([]bankfeed.Transaction, error) {
+	if rules.Account == "" {
+		return nil, errors.New("Account is required in the rules")
+	}
+	if rules.Columns.Date == "" || rules.Columns.Payee == "" {
+		return nil, errors.New("Date and payee columns are required in the rules")
+	}
+	if rules.Columns.Amount == "" && rules.Columns.Debit == "" && rules.Columns.Credit == "" {
+		return nil, errors.New("Either amount or debit and credit columns are required in the rules")
+	}
+
+	reader := csv.NewReader(strings.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	if rules.Delimiter != "" {
+		reader.Comma = []rune(rules.Delimiter)[0]
+	}
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) <= rules.Skip {
+		return nil, errors.New("Header not found in the statement")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[rules.Skip] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{rules.Columns.Date, rules.Columns.Payee, rules.Columns.Description, rules.Columns.Amount, rules.Columns.Debit, rules.Columns.Credit} {
+		if _, ok := columns[strings.ToLower(name)]; name != "" && !ok {
+			return nil, fmt.Errorf("Column %s not found in the statement", name)
+		}
+	}
+
+	cell := func(record []string, name string) string {
+		i, ok := columns[strings.ToLower(name)]
+		if name == "" || !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	dateFormat := rules.DateFormat
+	if dateFormat == "" {
+		dateFormat = locale.DEFAULT_DATE_FORMAT
+	}
+
+	transactions := []bankfeed.Transaction{}
+	seen := make(map[string]int)
+	for n, record := range records[rules.Skip+1:] {
+		line := n + rules.Skip + 2
+		if cell(record, rules.Columns.Date) == "" {
+			continue
+		}
+
+		date, err := time.ParseInLocation(locale.DateLayout(dateFormat), cell(record, rules.Columns.Date), config.TimeZone())
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: invalid date: %w", line, err)
+		}
+
+		var amount decimal.Decimal
+		if rules.Columns.Amount != "" {
+			amount, err = parseAmount(cell(record, rules.Columns.Amount), rules.DecimalSeparator)
+		} else {
+			var debit, credit decimal.Decimal
+			debit, err = parseAmount(cell(record, rules.Columns.Debit), rules.DecimalSeparator)
+			if err == nil {
+				credit, err = parseAmount(cell(record, rules.Columns.Credit), rules.DecimalSeparator)
+			}
+			amount = credit.Sub(debit.Abs())
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: invalid amount: %w", line, err)
+		}
+		if amount.IsZero() {
+			continue
+		}
+
+		t := bankfeed.Transaction{
+			Date:        date,
+			Payee:       cell(record, rules.Columns.Payee),
+			Description: cell(record, rules.Columns.Description),
+			Amount:      amount,
+			Currency:    rules.Currency,
+		}
+		key := strings.Join([]string{rules.Account, date.Format("2006-01-02"), t.Payee, t.Description, amount.String()}, "|")
+		seen[key]++
+		hash := sha1.Sum([]byte(fmt.Sprintf("%s|%d", key, seen[key])))
+		t.ID = hex.EncodeToString(hash[:])
+		transactions = append(transactions, t)
+	}
+
+	return transactions, nil
+}
+
+func parseAmount(value string, decimalSeparator string) (decimal.Decimal, error) {
+	if decimalSeparator == "," {
+		value = strings.ReplaceAll(strings.ReplaceAll(value, ".", ""), ",", ".")
+	}
+	negative := strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")")
+	value = nonNumericRegex.ReplaceAllString(value, "")
+	if value == "" {
+		return decimal.Zero, nil
+	}
+
+	amount, err := decimal.NewFromString(value)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
@@ -0,0 +1,51 @@
+package csvimport
+
+import (
+	"testing"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	statement := `HDFC Bank statement
+Date,Narration,Withdrawal,Deposit
+05/03/2024,UBER TRIP,"1,250.50",
+06/03/2024,SALARY ACME,,"90,000.00"
+06/03/2024,UBER TRIP,"1,250.50",
+06/03/2024,UBER TRIP,"1,250.50",
+,Closing balance,,
+`
+	rules := Rules{
+		Account:    "Assets:Checking:HDFC",
+		DateFormat: "DD/MM/YYYY",
+		Skip:       1,
+		Columns:    Columns{Date: "Date", Payee: "Narration", Debit: "Withdrawal", Credit: "Deposit"},
+	}
+
+	transactions, err := Parse(statement, rules)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 4)
+	assert.Equal(t, "2024-03-05", transactions[0].Date.Format("2006-01-02"))
+	assert.Equal(t, "UBER TRIP", transactions[0].Payee)
+	assert.Equal(t, "-1250.5", transactions[0].Amount.String())
+	assert.Equal(t, "90000", transactions[1].Amount.String())
+	assert.NotEqual(t, transactions[2].ID, transactions[3].ID)
+
+	again, _ := Parse(statement, rules)
+	assert.Equal(t, transactions[3].ID, again[3].ID)
+
+	transactions, err = Parse("Date;Payee;Amount\n2024-03-05;Bakery;-12,50\n", Rules{
+		Account:          "Assets:Checking:N26",
+		Delimiter:        ";",
+		DecimalSeparator: ",",
+		Columns:          Columns{Date: "Date", Payee: "Payee", Amount: "Amount"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "-12.5", transactions[0].Amount.String())
+
+	_, err = Parse(statement, Rules{Account: "Assets:Checking", Columns: Columns{Date: "Txn Date", Payee: "Narration", Amount: "Amount"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package depreciation
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+const PAYEE = "Depreciation"
+
+const (
+	STRAIGHT_LINE = "straight_line"
+	WDV           = "wdv"
+)
+
+const DEFAULT_EXPENSE_ACCOUNT = "Expenses:Depreciation"
+
+type Point struct {
+	Date         time.Time       `json:"date"`
+	Depreciation decimal.Decimal `json:"depreciation"`
+	Accumulated  decimal.Decimal `json:"accumulated"`
+	BookValue    decimal.Decimal `json:"book_value"`
+}
+
+// Schedule returns the book value of the asset at the end of each
+// month, starting with the month of purchase, till the end of the
+// life of the asset or the until date, whichever is earlier. With
+// straight line, the cost less the salvage value is spread evenly over
+// the life. With written down value (wdv), the annual rate is applied
+// monthly on the book value. The book value never goes below the
+// salvage value.
+func Schedule(asset config.FixedAsset, until time.Time) ([]Point, error) {
+	purchaseDate, err := time.ParseInLocation("2006-01-02", asset.PurchaseDate, config.TimeZone())
+	if err != nil {
+		return nil, err
+	}
+
+	cost := decimal.NewFromFloat(asset.Cost)
+	salvage := decimal.NewFromFloat(asset.Salvage)
+	months := asset.Life * 12
+	rate := decimal.NewFromFloat(asset.Rate)
+
+	switch Method(asset) {
+	case STRAIGHT_LINE:
+		if months <= 0 {
+			return nil, fmt.Errorf("life is required for the straight line method")
+		}
+	case WDV:
+		if !rate.IsPositive() {
+			return nil, fmt.Errorf("rate is required for the written down value method")
+		}
+	default:
+		return nil, fmt.Errorf("unknown method %s", asset.Method)
+	}
+
+	monthly := cost.Sub(salvage).Div(decimal.NewFromInt(int64(max(months, 1)))).Round(2)
+	points := []Point{}
+	bookValue := cost
+	accumulated := decimal.Zero
+	for i := 0; months <= 0 || i < months; i++ {
+		date := utils.EndOfMonth(utils.BeginningOfMonth(purchaseDate).AddDate(0, i, 0))
+		if date.After(until) || !bookValue.GreaterThan(salvage) {
+			break
+		}
+
+		var amount decimal.Decimal
+		if Method(asset) == STRAIGHT_LINE {
+			amount = monthly
+			if i == months-1 {
+				amount = bookValue.Sub(salvage)
+			}
+		} else {
+			amount = bookValue.Mul(rate).Div(decimal.NewFromInt(1200)).Round(2)
+		}
+		amount = decimal.Min(amount, bookValue.Sub(salvage))
+		if !amount.IsPositive() {
+			break
+		}
+
+		bookValue = bookValue.Sub(amount)
+		accumulated = accumulated.Add(amount)
+		points = append(points, Point{Date: date, Depreciation: amount, Accumulated: accumulated, BookValue: bookValue})
+	}
+
+	return points, nil
+}
+
+func Method(asset config.FixedAsset) string {
+	if asset.Method == "" {
+		return STRAIGHT_LINE
+	}
+	return asset.Method
+}
+
+func ExpenseAccount(asset config.FixedAsset) string {
+	if asset.ExpenseAccount != "" {
+		return asset.ExpenseAccount
+	}
+	parts := strings.Split(asset.Account, ":")
+	return DEFAULT_EXPENSE_ACCOUNT + ":" + parts[len(parts)-1]
+}
+
+// Generate returns the monthly depreciation postings of the assets
+// that opt in with post, so that the balance of the asset account is
+// the book value.
+func Generate(assets []config.FixedAsset, until time.Time) []*posting.Posting {
+	generated := []*posting.Posting{}
+	for _, asset := range assets {
+		if !asset.Post {
+			continue
+		}
+
+		points, err := Schedule(asset, until)
+		if err != nil {
+			log.Warn("Invalid fixed asset ", asset.Name, ": ", err)
+			continue
+		}
+
+		for _, point := range points {
+			transactionID := "depreciation:" + asset.Account + ":" + point.Date.Format("2006-01")
+			generated = append(generated,
+				depreciationPosting(transactionID, asset, point.Date, ExpenseAccount(asset), point.Depreciation),
+				depreciationPosting(transactionID, asset, point.Date, asset.Account, point.Depreciation.Neg()))
+		}
+	}
+
+	return generated
+}
+
+func depreciationPosting(transactionID string, asset config.FixedAsset, date time.Time, account string, amount decimal.Decimal) *posting.Posting {
+	return &posting.Posting{
+		TransactionID: transactionID,
+		Date:          utils.BeginningOfDay(date),
+		Payee:         PAYEE + " of " + asset.Name,
+		Account:       account,
+		Commodity:     config.DefaultCurrency(),
+		Quantity:      amount,
+		Amount:        amount,
+	}
+}
@@ -0,0 +1,60 @@
+package depreciation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func date(value string) time.Time {
+	d, _ := time.ParseInLocation("2006-01-02", value, config.TimeZone())
+	return d
+}
+
+func TestSchedule(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	laptop := config.FixedAsset{Name: "Laptop", Account: "Assets:Equipment:Laptop", PurchaseDate: "2023-01-10", Cost: 1000, Salvage: 100, Life: 3}
+	points, err := Schedule(laptop, date("2030-01-01"))
+	assert.NoError(t, err)
+	assert.Len(t, points, 36)
+	assert.Equal(t, "2023-01-31", points[0].Date.Format("2006-01-02"))
+	assert.Equal(t, "25", points[0].Depreciation.String())
+	assert.Equal(t, "975", points[0].BookValue.String())
+	assert.Equal(t, "100", points[35].BookValue.String())
+	assert.Equal(t, "900", points[35].Accumulated.String())
+
+	points, err = Schedule(laptop, date("2023-03-15"))
+	assert.NoError(t, err)
+	assert.Len(t, points, 2)
+
+	vehicle := config.FixedAsset{Name: "Car", Account: "Assets:Vehicle:Car", PurchaseDate: "2023-01-01", Cost: 12000, Method: WDV, Rate: 12}
+	points, err = Schedule(vehicle, date("2023-03-01"))
+	assert.NoError(t, err)
+	assert.Len(t, points, 2)
+	assert.Equal(t, "120", points[0].Depreciation.String())
+	assert.Equal(t, "118.8", points[1].Depreciation.String())
+	assert.Equal(t, "11761.2", points[1].BookValue.String())
+
+	_, err = Schedule(config.FixedAsset{PurchaseDate: "2023-01-01", Cost: 100, Method: WDV}, date("2023-03-01"))
+	assert.Error(t, err)
+}
+
+func TestGenerate(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	assets := []config.FixedAsset{
+		{Name: "Laptop", Account: "Assets:Equipment:Laptop", PurchaseDate: "2023-01-10", Cost: 1200, Life: 1, Post: true},
+		{Name: "Phone", Account: "Assets:Equipment:Phone", PurchaseDate: "2023-01-10", Cost: 600, Life: 1},
+	}
+
+	generated := Generate(assets, date("2023-03-01"))
+	assert.Len(t, generated, 4)
+	assert.Equal(t, "2023-01-31", generated[0].Date.Format("2006-01-02"))
+	assert.Equal(t, "Expenses:Depreciation:Laptop", generated[0].Account)
+	assert.Equal(t, "100", generated[0].Amount.String())
+	assert.Equal(t, "Assets:Equipment:Laptop", generated[1].Account)
+	assert.Equal(t, "-100", generated[1].Amount.String())
+}
@@ -0,0 +1,232 @@
+// Package doctor checks the setup of Paisa, the configuration, the
+// ledger binary, the database and the price providers, and suggests
+// a fix for each problem found. Unlike the journal diagnosis shown in
+// the UI, it works even when the server can't start.
+package doctor
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/binary"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/ledger"
+	"github.com/ananthakumaran/paisa/internal/model"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/model/price"
+	"github.com/ananthakumaran/paisa/internal/scraper"
+	"github.com/ananthakumaran/paisa/internal/scraper/appreciation"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+type Status string
+
+const (
+	OK   Status = "ok"
+	WARN Status = "warning"
+	FAIL Status = "error"
+)
+
+type Check struct {
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	Fix     string `json:"fix"`
+}
+
+type Options struct {
+	// skip the sample fetch of the price providers
+	Offline bool
+}
+
+// Run checks the setup with the configuration at configPath. The
+// checks that depend on a working configuration are skipped if it
+// fails to load.
+func Run(configPath string, options Options) []Check {
+	checks := []Check{checkConfig(configPath)}
+	if checks[0].Status == FAIL {
+		return checks
+	}
+
+	checks = append(checks, checkJournal()...)
+	checks = append(checks, checkDatabase()...)
+	if !options.Offline {
+		checks = append(checks, checkPriceProviders()...)
+	}
+	return checks
+}
+
+func checkConfig(configPath string) Check {
+	check := Check{Name: "Config"}
+	if configPath == "" {
+		check.Status = FAIL
+		check.Message = "Config file not found"
+		check.Fix = "Run paisa init to create a sample config or pass the path with --config"
+		return check
+	}
+
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		check.Status = FAIL
+		check.Message = err.Error()
+		check.Fix = fmt.Sprintf("Make sure %s is readable", configPath)
+		return check
+	}
+
+	err = config.LoadConfig(content, configPath)
+	if err != nil {
+		check.Status = FAIL
+		check.Message = err.Error()
+		check.Fix = fmt.Sprintf("Fix the errors above in %s, see https://paisa.fyi/reference/config", configPath)
+		return check
+	}
+
+	check.Status = OK
+	check.Message = configPath
+	return check
+}
+
+func checkJournal() []Check {
+	journalPath := config.GetJournalPath()
+	cli := config.LedgerCliFor(journalPath)
+	if !utils.FileExists(journalPath) {
+		return []Check{{Name: "Journal", Status: FAIL, Message: fmt.Sprintf("%s not found", journalPath), Fix: "Set journal_path in the config to an existing file"}}
+	}
+
+	binaryCheck := checkBinary(cli)
+	checks := []Check{binaryCheck}
+	if binaryCheck.Status == FAIL {
+		return checks
+	}
+
+	if config.GetConfig().Encryption.Enabled {
+		return append(checks, Check{Name: "Journal", Status: WARN, Message: "Skipped the validation of the encrypted journal", Fix: "Run paisa update to validate the journal"})
+	}
+
+	errors, _, err := ledger.CliFor(journalPath).ValidateFile(journalPath)
+	if err != nil {
+		message := err.Error()
+		if len(errors) > 0 {
+			message = fmt.Sprintf("%d errors, the first one at line %d: %s", len(errors), errors[0].LineFrom, strings.TrimSpace(errors[0].Message))
+		}
+		return append(checks, Check{Name: "Journal", Status: FAIL, Message: message, Fix: fmt.Sprintf("Fix the errors in %s, the editor in the UI highlights them", journalPath)})
+	}
+	return append(checks, Check{Name: "Journal", Status: OK, Message: journalPath})
+}
+
+func checkBinary(cli string) Check {
+	check := Check{Name: "Ledger CLI"}
+
+	var path string
+	var err error
+	switch cli {
+	case "hledger":
+		path, err = binary.LookPath("hledger")
+		check.Fix = "Install hledger from https://hledger.org/install.html and make sure it's in the PATH"
+	case "beancount":
+		path, err = binary.LookPath("bean-check")
+		check.Fix = "Install beancount with pip install beancount and make sure bean-check is in the PATH"
+	default:
+		path, err = binary.LedgerBinaryPath()
+		check.Fix = "Install ledger from https://ledger-cli.org/download.html or set ledger_cli to hledger"
+	}
+	if err != nil {
+		check.Status = FAIL
+		check.Message = fmt.Sprintf("%s not found: %s", cli, err.Error())
+		return check
+	}
+
+	var output, error bytes.Buffer
+	err = utils.Exec(path, &output, &error, "--version")
+	if err != nil {
+		check.Status = FAIL
+		check.Message = fmt.Sprintf("Failed to run %s: %s", path, strings.TrimSpace(err.Error()+" "+error.String()))
+		return check
+	}
+
+	version := strings.TrimSpace(strings.Split(utils.Dos2Unix(output.String()+error.String()), "\n")[0])
+	check.Status = OK
+	check.Fix = ""
+	check.Message = fmt.Sprintf("%s (%s)", version, path)
+	return check
+}
+
+func checkDatabase() []Check {
+	dbPath := config.GetDBPath()
+	if !utils.FileExists(dbPath) {
+		return []Check{{Name: "Database", Status: WARN, Message: fmt.Sprintf("%s not found", dbPath), Fix: "Run paisa update to create the database"}}
+	}
+
+	db, err := utils.OpenDB()
+	if err != nil {
+		return []Check{{Name: "Database", Status: FAIL, Message: err.Error(), Fix: fmt.Sprintf("Make sure %s is a readable sqlite database or remove it and run paisa update", dbPath)}}
+	}
+
+	missing := missingColumns(db)
+	if len(missing) > 0 {
+		return []Check{{Name: "Database", Status: WARN, Message: "Schema is outdated, missing " + strings.Join(missing, ", "), Fix: "Run paisa update to migrate the database"}}
+	}
+
+	var count int64
+	db.Model(&posting.Posting{}).Count(&count)
+	if count == 0 {
+		return []Check{{Name: "Database", Status: WARN, Message: "No postings found", Fix: "Run paisa update to sync the journal"}}
+	}
+	return []Check{{Name: "Database", Status: OK, Message: fmt.Sprintf("%s, %d postings", dbPath, count)}}
+}
+
+func missingColumns(db *gorm.DB) []string {
+	missing := []string{}
+	migrator := db.Migrator()
+	for _, m := range model.Models() {
+		statement := &gorm.Statement{DB: db}
+		if err := statement.Parse(m); err != nil {
+			continue
+		}
+
+		table := statement.Schema.Table
+		if !migrator.HasTable(m) {
+			missing = append(missing, table)
+			continue
+		}
+		for _, column := range statement.Schema.DBNames {
+			if !migrator.HasColumn(m, column) {
+				missing = append(missing, table+"."+column)
+			}
+		}
+	}
+	return missing
+}
+
+func checkPriceProviders() []Check {
+	codes := lo.Map(scraper.GetAllProviders(), func(p price.PriceProvider, _ int) string { return p.Code() })
+	checks := []Check{}
+	for _, commodity := range config.GetConfig().Commodities {
+		code := commodity.Price.Provider
+		if code == "" || code == appreciation.CODE {
+			continue
+		}
+
+		name := "Price of " + commodity.Name
+		if !lo.Contains(codes, code) {
+			checks = append(checks, Check{Name: name, Status: FAIL, Message: fmt.Sprintf("Unknown price provider %s", code), Fix: "Pick one of " + strings.Join(codes, ", ")})
+			continue
+		}
+
+		prices, err := scraper.GetProviderByCode(code).GetPrices(commodity.Price.Code, commodity.Name)
+		switch {
+		case err != nil:
+			checks = append(checks, Check{Name: name, Status: FAIL, Message: err.Error(), Fix: fmt.Sprintf("Check the code %s and the network, or pick another provider for %s", commodity.Price.Code, commodity.Name)})
+		case len(prices) == 0:
+			checks = append(checks, Check{Name: name, Status: WARN, Message: fmt.Sprintf("%s returned no prices", code), Fix: fmt.Sprintf("Check the code %s of %s", commodity.Price.Code, commodity.Name)})
+		default:
+			latest := lo.MaxBy(prices, func(a *price.Price, b *price.Price) bool { return a.Date.After(b.Date) })
+			checks = append(checks, Check{Name: name, Status: OK, Message: fmt.Sprintf("%d prices, latest on %s", len(prices), latest.Date.Format("2006-01-02"))})
+		}
+	}
+	return checks
+}
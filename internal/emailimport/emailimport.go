@@ -0,0 +1,240 @@
+// Package emailimport reads the transaction alert emails sent by the
+// banks from an IMAP folder and turns them into drafts in the import
+// review queue.
+package emailimport
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/bankfeed"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/draft"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	SOURCE           = "email"
+	FETCH_BATCH_SIZE = 50
+	UNKNOWN_ACCOUNT  = "Assets:Unknown"
+)
+
+var (
+	htmlTagRegex   = regexp.MustCompile(`(?s)<(script|style).*?</(script|style)>|<[^>]+>`)
+	dataURIRegex   = regexp.MustCompile(`data:[^\s"']+`)
+	errUnsupported = errors.New("unsupported content")
+)
+
+// Import searches the configured folder for the alerts received in
+// the lookback window and creates a draft for each alert that's not
+// seen before. Returns the number of drafts created.
+func Import(db *gorm.DB) (int, error) {
+	c := config.GetConfig().EmailImport
+	if c.Host == "" {
+		return 0, errors.New("Email import is not configured")
+	}
+
+	client, err := dialIMAP(c.Host, c.Port)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	if err := client.Login(c.Username, c.Password); err != nil {
+		return 0, err
+	}
+	if err := client.Select(c.Folder); err != nil {
+		return 0, err
+	}
+
+	// the search ignores the time, the whole day is included
+	since := utils.Now().AddDate(0, 0, -c.LookbackDays)
+	uids, err := client.Search(since, senders())
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, batch := range lo.Chunk(uids, FETCH_BATCH_SIZE) {
+		messages, err := client.Fetch(batch)
+		if err != nil {
+			return created, err
+		}
+
+		for _, uid := range batch {
+			raw, ok := messages[uid]
+			if !ok {
+				continue
+			}
+
+			d, ok := buildDraft(db, raw)
+			if !ok {
+				continue
+			}
+			draft.Create(db, &d)
+			created++
+		}
+	}
+
+	log.Infof("Imported %d drafts from %d emails", created, len(uids))
+	return created, nil
+}
+
+func buildDraft(db *gorm.DB, raw []byte) (draft.Draft, bool) {
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		log.Warn(err)
+		return draft.Draft{}, false
+	}
+
+	id := strings.Trim(message.Header.Get("Message-Id"), "<> ")
+	if id == "" {
+		sum := sha256.Sum256(raw)
+		id = hex.EncodeToString(sum[:])
+	}
+	if draft.Exists(db, SOURCE, id) {
+		return draft.Draft{}, false
+	}
+
+	text, err := messageText(message.Header.Get("Content-Type"), message.Header.Get("Content-Transfer-Encoding"), message.Body)
+	if err != nil {
+		log.Warnf("Failed to read the email %s: %v", id, err)
+		return draft.Draft{}, false
+	}
+
+	alert, ok := parseAlert(message.Header.Get("From"), text)
+	if !ok {
+		return draft.Draft{}, false
+	}
+
+	account := accountFor(alert)
+	counterAccount, payee := bankfeed.Categorize(db, alert.Payee, "", alert.Debit)
+	postings := []journal.Posting{
+		{Account: counterAccount, Amount: journal.FormatAmount(alert.Amount, config.DefaultCurrency())},
+		{Account: account},
+	}
+	if !alert.Debit {
+		postings = []journal.Posting{
+			{Account: account, Amount: journal.FormatAmount(alert.Amount, config.DefaultCurrency())},
+			{Account: counterAccount},
+		}
+	}
+
+	if payee == "" {
+		payee = strings.ToUpper(alert.Bank)
+	}
+
+	amount := alert.Amount
+	if alert.Debit {
+		amount = amount.Neg()
+	}
+
+	return draft.Draft{
+		Source:     SOURCE,
+		ExternalID: id,
+		Date:       alert.Date,
+		Payee:      payee,
+		Account:    account,
+		Amount:     amount,
+		Content:    journal.Transaction{Date: alert.Date, Payee: payee, Postings: postings}.Format(),
+		Raw:        strings.Join(strings.Fields(text), " "),
+		Status:     draft.Pending,
+	}, true
+}
+
+// accountFor maps the bank and the number in the alert to the account
+// configured for it. A mapping without the number applies to all the
+// accounts and the cards of the bank.
+func accountFor(alert Alert) string {
+	accounts := config.GetConfig().EmailImport.Accounts
+	for _, a := range accounts {
+		if strings.EqualFold(a.Bank, alert.Bank) && a.Number != "" && strings.HasSuffix(a.Number, alert.Number) {
+			return a.Account
+		}
+	}
+	for _, a := range accounts {
+		if strings.EqualFold(a.Bank, alert.Bank) && a.Number == "" {
+			return a.Account
+		}
+	}
+	return UNKNOWN_ACCOUNT
+}
+
+// messageText returns the text of the email, the plain text part if
+// there is one, otherwise the html part with the tags stripped.
+func messageText(contentType string, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		var htmlText string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+
+			text, err := messageText(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if errors.Is(err, errUnsupported) {
+				continue
+			}
+			if err != nil {
+				return "", err
+			}
+
+			partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+			if partType == "text/html" {
+				htmlText = text
+				continue
+			}
+			return text, nil
+		}
+
+		if htmlText == "" {
+			return "", errUnsupported
+		}
+		return htmlText, nil
+	}
+
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", errUnsupported
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	if mediaType == "text/html" {
+		return html.UnescapeString(htmlTagRegex.ReplaceAllString(dataURIRegex.ReplaceAllString(string(content), ""), " ")), nil
+	}
+	return string(content), nil
+}
@@ -0,0 +1,193 @@
+package emailimport
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	literalRegex  = regexp.MustCompile(`\{(\d+)\}$`)
+	fetchUIDRegex = regexp.MustCompile(`\bUID (\d+)\b`)
+)
+
+// imapClient speaks just enough IMAP4rev1 (RFC 3501) to search a
+// folder and download the matching messages, always over TLS.
+type imapClient struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// imapResponse is an untagged response line, with the literals it
+// carried cut out of the line.
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+func dialIMAP(host string, port int) (*imapClient, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, strconv.Itoa(port)), &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &imapClient{conn: conn, r: bufio.NewReader(conn)}
+	greeting, err := c.readLine()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("Unexpected greeting from the IMAP server: %s", greeting)
+	}
+	return c, nil
+}
+
+func (c *imapClient) Close() error {
+	_, _ = c.command("LOGOUT")
+	return c.conn.Close()
+}
+
+func (c *imapClient) Login(username, password string) error {
+	_, err := c.command("LOGIN %s %s", quote(username), quote(password))
+	return err
+}
+
+func (c *imapClient) Select(folder string) error {
+	_, err := c.command("SELECT %s", quote(folder))
+	return err
+}
+
+// Search returns the UIDs of the messages received on or after the
+// date from any of the senders.
+func (c *imapClient) Search(since time.Time, senders []string) ([]uint32, error) {
+	criteria := "SINCE " + since.Format("2-Jan-2006")
+	if len(senders) > 0 {
+		from := "FROM " + quote(senders[len(senders)-1])
+		for i := len(senders) - 2; i >= 0; i-- {
+			from = fmt.Sprintf("OR FROM %s %s", quote(senders[i]), from)
+		}
+		criteria += " " + from
+	}
+
+	responses, err := c.command("UID SEARCH %s", criteria)
+	if err != nil {
+		return nil, err
+	}
+
+	uids := []uint32{}
+	for _, r := range responses {
+		if !strings.HasPrefix(r.line, "SEARCH") {
+			continue
+		}
+		for _, field := range strings.Fields(strings.TrimPrefix(r.line, "SEARCH")) {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// Fetch downloads the raw messages without marking them as seen.
+func (c *imapClient) Fetch(uids []uint32) (map[uint32][]byte, error) {
+	messages := make(map[uint32][]byte)
+	if len(uids) == 0 {
+		return messages, nil
+	}
+
+	set := make([]string, len(uids))
+	for i, uid := range uids {
+		set[i] = strconv.FormatUint(uint64(uid), 10)
+	}
+
+	responses, err := c.command("UID FETCH %s (UID BODY.PEEK[])", strings.Join(set, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range responses {
+		match := fetchUIDRegex.FindStringSubmatch(r.line)
+		if !strings.Contains(r.line, "FETCH") || match == nil || len(r.literals) == 0 {
+			continue
+		}
+		uid, _ := strconv.ParseUint(match[1], 10, 32)
+		messages[uint32(uid)] = r.literals[0]
+	}
+	return messages, nil
+}
+
+// command sends the command and reads the untagged responses till the
+// tagged completion, which is turned into an error unless it's OK.
+func (c *imapClient) command(format string, args ...any) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(2 * time.Minute))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+
+	responses := []imapResponse{}
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if strings.HasPrefix(line, tag+" ") {
+			status := strings.TrimPrefix(line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return nil, errors.New("IMAP: " + status)
+			}
+			return responses, nil
+		}
+
+		if !strings.HasPrefix(line, "* ") {
+			continue
+		}
+
+		response := imapResponse{line: strings.TrimPrefix(line, "* ")}
+		for {
+			match := literalRegex.FindStringSubmatch(line)
+			if match == nil {
+				break
+			}
+			size, _ := strconv.Atoi(match[1])
+			literal := make([]byte, size)
+			if _, err := io.ReadFull(c.r, literal); err != nil {
+				return nil, err
+			}
+			response.literals = append(response.literals, literal)
+
+			line, err = c.readLine()
+			if err != nil {
+				return nil, err
+			}
+			response.line += line
+		}
+		responses = append(responses, response)
+	}
+}
+
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
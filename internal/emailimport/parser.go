@@ -0,0 +1,143 @@
+package emailimport
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+// Alert is a transaction reported by the bank in an alert email.
+type Alert struct {
+	Bank string
+	// last digits of the account or the card number
+	Number string
+	Date   time.Time
+	Amount decimal.Decimal
+	Payee  string
+	Debit  bool
+}
+
+type alertPattern struct {
+	regex       *regexp.Regexp
+	debit       bool
+	dateLayouts []string
+}
+
+type bankParser struct {
+	bank string
+	// part of the sender address, used to search the folder
+	sender   string
+	patterns []alertPattern
+}
+
+const (
+	amountPattern = `(?:Rs\.?|INR) ?(?P<amount>[\d,]+(?:\.\d+)?)`
+	numberPattern = `(?:[xX*]+)?(?P<number>\d{3,4})`
+)
+
+func pattern(debit bool, expression string, dateLayouts ...string) alertPattern {
+	expression = strings.NewReplacer("AMOUNT", amountPattern, "NUMBER", numberPattern).Replace(expression)
+	return alertPattern{regex: regexp.MustCompile("(?i)" + expression), debit: debit, dateLayouts: dateLayouts}
+}
+
+// The alert formats are not documented by the banks and change once
+// in a while. The patterns cover the card spends and the account
+// debits and credits as they read at the time of writing.
+var bankParsers = []bankParser{
+	{
+		bank:   "hdfc",
+		sender: "hdfcbank",
+		patterns: []alertPattern{
+			pattern(true, `HDFC Bank Credit Card ending NUMBER for AMOUNT at (?P<payee>.+?) on (?P<date>\d{2}-\d{2}-\d{4})`, "02-01-2006"),
+			pattern(true, `AMOUNT (?:has been |is )?debited from (?:your )?(?:HDFC Bank )?(?:account|a/c) NUMBER to (?:VPA )?(?P<payee>.+?) on (?P<date>\d{2}-\d{2}-\d{2,4})`, "02-01-06", "02-01-2006"),
+			pattern(false, `AMOUNT (?:is successfully |has been )?credited to (?:your )?(?:HDFC Bank )?(?:account|a/c) NUMBER by (?:VPA )?(?P<payee>.+?) on (?P<date>\d{2}-\d{2}-\d{2,4})`, "02-01-06", "02-01-2006"),
+		},
+	},
+	{
+		bank:   "icici",
+		sender: "icicibank",
+		patterns: []alertPattern{
+			pattern(true, `ICICI Bank Credit Card NUMBER has been used for a transaction of AMOUNT on (?P<date>[A-Za-z]{3} \d{1,2}, \d{4}) at [\d:]+\. Info: (?P<payee>.+?)\.`, "Jan 2, 2006"),
+			pattern(true, `ICICI Bank (?:Account|A/c) NUMBER (?:has been |is )?debited (?:with|for) AMOUNT on (?P<date>\d{2}-[A-Za-z]{3}-\d{2,4})[.;,]? (?:Info:? )?(?P<payee>.+?)[.;]`, "02-Jan-06", "02-Jan-2006"),
+			pattern(false, `ICICI Bank (?:Account|A/c) NUMBER (?:has been |is )?credited with AMOUNT on (?P<date>\d{2}-[A-Za-z]{3}-\d{2,4})[.;,]? (?:Info:? |by )?(?P<payee>.+?)[.;]`, "02-Jan-06", "02-Jan-2006"),
+		},
+	},
+	{
+		bank:   "kotak",
+		sender: "kotak",
+		patterns: []alertPattern{
+			pattern(true, `Transaction of AMOUNT on (?:your )?Kotak (?:Bank )?Credit Card NUMBER at (?P<payee>.+?) on (?P<date>\d{2}/\d{2}/\d{4})`, "02/01/2006"),
+			pattern(true, `AMOUNT (?:is |has been )?debited from (?:your )?Kotak Bank (?:a/c|account) NUMBER on (?P<date>\d{2}-\d{2}-\d{2,4}) (?:towards|to) (?P<payee>.+?)[.;]`, "02-01-06", "02-01-2006"),
+			pattern(false, `AMOUNT (?:is |has been )?credited to (?:your )?Kotak Bank (?:a/c|account) NUMBER on (?P<date>\d{2}-\d{2}-\d{2,4}) (?:from|by) (?P<payee>.+?)[.;]`, "02-01-06", "02-01-2006"),
+		},
+	},
+}
+
+func senders() []string {
+	senders := []string{}
+	for _, p := range bankParsers {
+		senders = append(senders, p.sender)
+	}
+	return senders
+}
+
+// parseAlert picks the parser of the bank from the sender address and
+// tries the patterns of the bank one by one on the text of the email.
+func parseAlert(from string, text string) (Alert, bool) {
+	from = strings.ToLower(from)
+	text = strings.Join(strings.Fields(text), " ")
+
+	for _, parser := range bankParsers {
+		if !strings.Contains(from, parser.sender) {
+			continue
+		}
+
+		for _, p := range parser.patterns {
+			match := p.regex.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+
+			groups := make(map[string]string)
+			for i, name := range p.regex.SubexpNames() {
+				if name != "" {
+					groups[name] = match[i]
+				}
+			}
+
+			amount, err := decimal.NewFromString(strings.ReplaceAll(groups["amount"], ",", ""))
+			if err != nil {
+				continue
+			}
+
+			date, ok := parseDate(groups["date"], p.dateLayouts)
+			if !ok {
+				continue
+			}
+
+			return Alert{
+				Bank:   parser.bank,
+				Number: groups["number"],
+				Date:   date,
+				Amount: amount,
+				Payee:  strings.TrimRight(strings.TrimSpace(groups["payee"]), ".,;"),
+				Debit:  p.debit,
+			}, true
+		}
+	}
+
+	return Alert{}, false
+}
+
+func parseDate(value string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		date, err := time.ParseInLocation(layout, value, config.TimeZone())
+		if err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,39 @@
+package emailimport
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAlert(t *testing.T) {
+	alert, ok := parseAlert("HDFC Bank InstaAlerts <alerts@hdfcbank.net>", `Dear Card Member,
+		Thank you for using your HDFC Bank Credit Card ending 4321 for Rs 1,249.50 at AMAZON PAY INDIA on 05-01-2024 18:42:11.`)
+	assert.True(t, ok)
+	assert.Equal(t, "hdfc", alert.Bank)
+	assert.Equal(t, "4321", alert.Number)
+	assert.Equal(t, "2024-01-05", alert.Date.Format("2006-01-02"))
+	assert.Equal(t, "1249.5", alert.Amount.String())
+	assert.Equal(t, "AMAZON PAY INDIA", alert.Payee)
+	assert.True(t, alert.Debit)
+
+	alert, ok = parseAlert("credit_cards@icicibank.com", "Your ICICI Bank Credit Card XX9876 has been used for a transaction of INR 560.00 on Feb 14, 2024 at 10:05:41. Info: SWIGGY. The Available Credit Limit on your card is INR 1,00,000.00.")
+	assert.True(t, ok)
+	assert.Equal(t, "icici", alert.Bank)
+	assert.Equal(t, "9876", alert.Number)
+	assert.Equal(t, "2024-02-14", alert.Date.Format("2006-01-02"))
+	assert.Equal(t, "SWIGGY", alert.Payee)
+
+	alert, ok = parseAlert("BankAlerts@kotak.com", "Rs.2500.00 is credited to your Kotak Bank a/c XX5555 on 03-03-24 from JOHN DOE. Current balance is Rs.10000.")
+	assert.True(t, ok)
+	assert.Equal(t, "5555", alert.Number)
+	assert.Equal(t, "2024-03-03", alert.Date.Format("2006-01-02"))
+	assert.Equal(t, "JOHN DOE", alert.Payee)
+	assert.False(t, alert.Debit)
+
+	_, ok = parseAlert("newsletter@hdfcbank.net", "Check out the new offers on your card.")
+	assert.False(t, ok)
+
+	_, ok = parseAlert("someone@example.com", "Thank you for using your HDFC Bank Credit Card ending 4321 for Rs 100 at SHOP on 05-01-2024")
+	assert.False(t, ok)
+}
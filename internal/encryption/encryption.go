@@ -0,0 +1,155 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// EXTENSION is appended to the name of the encrypted files.
+const EXTENSION = ".enc"
+
+var magic = []byte("PAISA-ENC-1\n")
+
+const saltSize = 16
+
+var ErrWrongPassphrase = errors.New("Failed to decrypt, the passphrase is wrong or the file is corrupted")
+
+// Key derives the AES-256 keys from the passphrase. Deriving a key is
+// deliberately slow, so the keys are cached by salt. All the files
+// encrypted with the same Key share the salt, but not the nonce.
+type Key struct {
+	passphrase []byte
+	salt       []byte
+	keys       map[string][]byte
+	mu         sync.Mutex
+}
+
+func NewKey(passphrase string) (*Key, error) {
+	if passphrase == "" {
+		return nil, errors.New("Passphrase can't be empty")
+	}
+
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return &Key{passphrase: []byte(passphrase), salt: salt, keys: make(map[string][]byte)}, nil
+}
+
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// Encrypt returns magic | salt | nonce | AES-GCM ciphertext.
+func (k *Key) Encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := k.cipher(k.salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header := append(append(append([]byte{}, magic...), k.salt...), nonce...)
+	return gcm.Seal(header, nonce, plaintext, magic), nil
+}
+
+func (k *Key) Decrypt(data []byte) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return nil, errors.New("File is not encrypted")
+	}
+
+	data = data[len(magic):]
+	if len(data) < saltSize {
+		return nil, ErrWrongPassphrase
+	}
+	salt, data := data[:saltSize], data[saltSize:]
+
+	gcm, err := k.cipher(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, data, magic)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return plaintext, nil
+}
+
+func (k *Key) cipher(salt []byte) (cipher.AEAD, error) {
+	k.mu.Lock()
+	key, ok := k.keys[string(salt)]
+	if !ok {
+		var err error
+		key, err = scrypt.Key(k.passphrase, salt, 1<<15, 8, 1, 32)
+		if err != nil {
+			k.mu.Unlock()
+			return nil, err
+		}
+		k.keys[string(salt)] = key
+	}
+	k.mu.Unlock()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptFile replaces the file with its encrypted copy, which has the
+// encryption extension.
+func EncryptFile(key *Key, path string) error {
+	plaintext, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := key.Encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(path+EXTENSION, data, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// DecryptFile is the inverse of EncryptFile, the path should have the
+// encryption extension.
+func DecryptFile(key *Key, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := key.Decrypt(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	err = os.WriteFile(strings.TrimSuffix(path, EXTENSION), plaintext, 0600)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
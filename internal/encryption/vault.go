@@ -0,0 +1,297 @@
+package encryption
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const FLUSH_INTERVAL = 30 * time.Second
+
+const dbDir = ".db"
+
+// Vault keeps the decrypted copy of the journal directory and the
+// database in a private temporary directory, in memory where the
+// system provides /dev/shm. The changes made to the copy are encrypted
+// back to the original location on Flush, which runs periodically
+// and after every journal write.
+type Vault struct {
+	key       *Key
+	sourceDir string
+	workDir   string
+	sourceDB  string
+	workDB    string
+	sealed    map[string]time.Time
+	mu        sync.Mutex
+	done      chan struct{}
+}
+
+var current *Vault
+
+// Current returns the open vault, nil if the encryption is not
+// enabled.
+func Current() *Vault {
+	return current
+}
+
+// Open decrypts the files of the journal directory with the encryption
+// extension and the database into a new vault.
+func Open(journalPath string, dbPath string, key *Key) (*Vault, error) {
+	if _, err := os.Stat(journalPath + EXTENSION); err != nil {
+		return nil, fmt.Errorf("%s not found, run paisa encrypt to encrypt the journal: %w", journalPath+EXTENSION, err)
+	}
+
+	// the location is stable across restarts, so the paths recorded in
+	// the backups stay valid
+	base := os.TempDir()
+	if info, err := os.Stat("/dev/shm"); err == nil && info.IsDir() {
+		base = "/dev/shm"
+	}
+	hash := sha256.Sum256([]byte(filepath.Dir(journalPath)))
+	workDir := filepath.Join(base, "paisa-vault-"+hex.EncodeToString(hash[:])[:12])
+	err := os.RemoveAll(workDir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.Mkdir(workDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vault{
+		key:       key,
+		sourceDir: filepath.Dir(journalPath),
+		workDir:   workDir,
+		sourceDB:  dbPath,
+		workDB:    filepath.Join(workDir, dbDir, filepath.Base(dbPath)),
+		sealed:    make(map[string]time.Time),
+		done:      make(chan struct{}),
+	}
+
+	err = v.open()
+	if err != nil {
+		os.RemoveAll(workDir)
+		return nil, err
+	}
+
+	current = v
+	go v.flushPeriodically()
+	log.Info("Decrypted the journal into ", workDir)
+	return v, nil
+}
+
+func (v *Vault) JournalPath(journalPath string) string {
+	rel, _ := filepath.Rel(v.sourceDir, journalPath)
+	return filepath.Join(v.workDir, rel)
+}
+
+func (v *Vault) DBPath() string {
+	return v.workDB
+}
+
+// SourceDir is the directory with the encrypted journal files.
+func (v *Vault) SourceDir() string {
+	return v.sourceDir
+}
+
+func (v *Vault) open() error {
+	err := os.MkdirAll(filepath.Dir(v.workDB), 0700)
+	if err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(v.sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, EXTENSION) || path == v.sourceDB+EXTENSION {
+			return nil
+		}
+
+		rel, err := filepath.Rel(v.sourceDir, strings.TrimSuffix(path, EXTENSION))
+		if err != nil {
+			return err
+		}
+		return v.decrypt(path, filepath.Join(v.workDir, rel))
+	})
+	if err != nil {
+		return err
+	}
+
+	err = v.decrypt(v.sourceDB+EXTENSION, v.workDB)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (v *Vault) decrypt(source string, target string) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := v.key.Decrypt(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", source, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(target), 0700)
+	if err != nil {
+		return err
+	}
+
+	err = os.WriteFile(target, plaintext, 0600)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(target)
+	if err != nil {
+		return err
+	}
+	v.sealed[target] = info.ModTime()
+	return nil
+}
+
+// Flush encrypts the files modified since the last flush back to the
+// original location and removes the ones deleted since then.
+func (v *Vault) Flush() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(v.workDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target, ok := v.target(path)
+		if d.IsDir() || !ok {
+			return nil
+		}
+		seen[path] = true
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if sealed, ok := v.sealed[path]; ok && sealed.Equal(info.ModTime()) {
+			return nil
+		}
+
+		plaintext, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		data, err := v.key.Encrypt(plaintext)
+		if err != nil {
+			return err
+		}
+
+		err = os.MkdirAll(filepath.Dir(target), 0700)
+		if err != nil {
+			return err
+		}
+		tmp := target + ".tmp"
+		err = os.WriteFile(tmp, data, 0600)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(tmp, target)
+		if err != nil {
+			return err
+		}
+
+		v.sealed[path] = info.ModTime()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for path := range v.sealed {
+		if seen[path] {
+			continue
+		}
+
+		target, _ := v.target(path)
+		err := os.Remove(target)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		delete(v.sealed, path)
+	}
+	return nil
+}
+
+// target returns the location of the encrypted copy of the file. The
+// sqlite journal files next to the db and the temporary files created
+// while validating the journal are never encrypted.
+func (v *Vault) target(path string) (string, bool) {
+	if path == v.workDB {
+		return v.sourceDB + EXTENSION, true
+	}
+	if strings.HasPrefix(path, filepath.Dir(v.workDB)+string(filepath.Separator)) || strings.HasPrefix(filepath.Base(path), "paisa-tmp-") {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(v.workDir, path)
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(v.sourceDir, rel) + EXTENSION, true
+}
+
+// Close flushes the pending changes and removes the decrypted files.
+func (v *Vault) Close() error {
+	close(v.done)
+	err := v.Flush()
+	if err != nil {
+		return err
+	}
+
+	current = nil
+	return os.RemoveAll(v.workDir)
+}
+
+func (v *Vault) flushPeriodically() {
+	ticker := time.NewTicker(FLUSH_INTERVAL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-v.done:
+			return
+		case <-ticker.C:
+			if err := v.Flush(); err != nil {
+				log.Error("Failed to encrypt the journal: ", err)
+			}
+		}
+	}
+}
+
+// Seal encrypts the content with the key of the open vault, the
+// content is returned as is if the encryption is not enabled.
+func Seal(content []byte) ([]byte, error) {
+	if current == nil {
+		return content, nil
+	}
+	return current.key.Encrypt(content)
+}
+
+// Unseal is the inverse of Seal. Content that was written before the
+// encryption was enabled is returned as is.
+func Unseal(content []byte) ([]byte, error) {
+	if current == nil || !IsEncrypted(content) {
+		return content, nil
+	}
+	return current.key.Decrypt(content)
+}
@@ -0,0 +1,113 @@
+// Package event is the internal event bus. Subsystems that react to
+// changes in the ledger (hooks, notifications etc) subscribe here
+// instead of being called directly from the server handlers.
+//
+// Events
+//
+//   - ledger.loaded: the journal was parsed and the postings were
+//     saved to the database. Data: namespace.
+//   - price.updated: commodity prices were fetched. Data: namespace.
+//   - price.update_failed: the price of a commodity couldn't be
+//     fetched. Data: namespace, commodity, provider, error.
+//   - journal.changed: one or more journal files were modified on
+//     the disk. Data: files (relative to the journal directory).
+//   - month.closed: a calendar month ended. Data: month (2006-01).
+//   - threshold.crossed: a monitored value moved past its limit.
+//     Data: kind, account, value, limit.
+//   - goal.reached: a goal reached its target. Data: type, name,
+//     current, target.
+//
+// Handlers are invoked asynchronously, a slow or panicking handler
+// doesn't affect the publisher or the other handlers. Each handler gets
+// its own copy of the data map, the values in it are shared and should
+// not be modified.
+package event
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Name string
+
+const (
+	LedgerLoaded     Name = "ledger.loaded"
+	PriceUpdated     Name = "price.updated"
+	PriceFailed      Name = "price.update_failed"
+	JournalChanged   Name = "journal.changed"
+	MonthClosed      Name = "month.closed"
+	ThresholdCrossed Name = "threshold.crossed"
+	GoalReached      Name = "goal.reached"
+)
+
+// All can be used to subscribe to every event.
+const All Name = "*"
+
+type Event struct {
+	Name Name           `json:"name"`
+	Time time.Time      `json:"time"`
+	Data map[string]any `json:"data"`
+}
+
+type Handler func(Event)
+
+type subscription struct {
+	id      int
+	name    Name
+	handler Handler
+}
+
+var (
+	mu            sync.RWMutex
+	nextID        int
+	subscriptions []subscription
+)
+
+// Subscribe registers the handler for the event and returns a function
+// to remove it.
+func Subscribe(name Name, handler Handler) func() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	nextID++
+	id := nextID
+	subscriptions = append(subscriptions, subscription{id: id, name: name, handler: handler})
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for i, s := range subscriptions {
+			if s.id == id {
+				subscriptions = append(subscriptions[:i], subscriptions[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func Publish(name Name, data map[string]any) {
+	if data == nil {
+		data = map[string]any{}
+	}
+	now := time.Now()
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, s := range subscriptions {
+		if s.name == name || s.name == All {
+			go dispatch(s.handler, Event{Name: name, Time: now, Data: maps.Clone(data)})
+		}
+	}
+}
+
+func dispatch(handler Handler, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Event handler for %s failed: %v", e.Name, r)
+		}
+	}()
+	handler(e)
+}
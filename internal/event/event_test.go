@@ -0,0 +1,32 @@
+package event
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishCopiesData(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	var mu sync.Mutex
+	seen := []any{}
+	handler := func(e Event) {
+		defer wg.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, e.Data["month"])
+		e.Data["month"] = "changed"
+	}
+	defer Subscribe(MonthClosed, handler)()
+	defer Subscribe(MonthClosed, handler)()
+
+	data := map[string]any{"month": "2023-01"}
+	Publish(MonthClosed, data)
+	wg.Wait()
+
+	assert.Equal(t, []any{"2023-01", "2023-01"}, seen)
+	assert.Equal(t, "2023-01", data["month"])
+}
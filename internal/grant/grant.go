@@ -0,0 +1,95 @@
+package grant
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/shopspring/decimal"
+)
+
+const PAYEE = "Vest"
+
+const DEFAULT_INCOME_ACCOUNT = "Income:Salary:RSU"
+
+type Vest struct {
+	Date  time.Time       `json:"date"`
+	Units decimal.Decimal `json:"units"`
+}
+
+// Vests returns the vesting schedule of the grant. The units are split
+// evenly over the periods, the periods before the cliff vest together
+// at the cliff. The last vest takes the rounding difference.
+func Vests(g config.Grant) ([]Vest, error) {
+	grantDate, err := time.ParseInLocation("2006-01-02", g.GrantDate, config.TimeZone())
+	if err != nil {
+		return nil, err
+	}
+
+	vesting := g.Vesting
+	if vesting.FrequencyMonths <= 0 || vesting.PeriodMonths <= 0 {
+		return []Vest{}, nil
+	}
+
+	periods := vesting.PeriodMonths / vesting.FrequencyMonths
+	if periods == 0 {
+		periods = 1
+	}
+	total := decimal.NewFromFloat(g.Units)
+	perPeriod := total.Div(decimal.NewFromInt(int64(periods))).Floor()
+
+	vests := []Vest{}
+	pending := decimal.Zero
+	vested := decimal.Zero
+	for i := 1; i <= periods; i++ {
+		months := i * vesting.FrequencyMonths
+		pending = pending.Add(perPeriod)
+		if i == periods {
+			pending = pending.Add(total.Sub(vested).Sub(pending))
+		}
+
+		if months < vesting.CliffMonths {
+			continue
+		}
+
+		vests = append(vests, Vest{Date: grantDate.AddDate(0, months, 0), Units: pending})
+		vested = vested.Add(pending)
+		pending = decimal.Zero
+	}
+
+	return vests, nil
+}
+
+func IncomeAccount(g config.Grant) string {
+	if g.IncomeAccount != "" {
+		return g.IncomeAccount
+	}
+	return DEFAULT_INCOME_ACCOUNT
+}
+
+// ForecastPostings returns the forecast postings of the vests after
+// now, valued at the latest price of the commodity. The vests till
+// now are expected to be recorded in the journal.
+func ForecastPostings(grants []config.Grant, now time.Time, latestPrice func(commodity string) decimal.Decimal) []*posting.Posting {
+	postings := []*posting.Posting{}
+	for _, g := range grants {
+		vests, err := Vests(g)
+		if err != nil {
+			continue
+		}
+
+		unitPrice := latestPrice(g.Commodity)
+		for _, v := range vests {
+			if !v.Date.After(now) {
+				continue
+			}
+
+			amount := v.Units.Mul(unitPrice)
+			transactionID := "grant:" + g.Name + ":" + v.Date.Format("2006-01-02")
+			postings = append(postings,
+				&posting.Posting{TransactionID: transactionID, Date: v.Date, Payee: PAYEE + " " + g.Name, Account: g.Account, Commodity: g.Commodity, Quantity: v.Units, Amount: amount, Forecast: true},
+				&posting.Posting{TransactionID: transactionID, Date: v.Date, Payee: PAYEE + " " + g.Name, Account: IncomeAccount(g), Commodity: config.DefaultCurrency(), Quantity: amount.Neg(), Amount: amount.Neg(), Forecast: true})
+		}
+	}
+	return postings
+}
@@ -0,0 +1,190 @@
+package journal
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+var dateRegex = regexp.MustCompile(`^(\d{4})[/.-](\d{1,2})[/.-](\d{1,2})`)
+
+var postingRegex = regexp.MustCompile(`^[ \t]+((?:[*!]\s+)?[^;\s](?:[^;\s]| [^;\s])*)[ \t]+([^;]*?)([+-]?[.,0-9]+)(.*)$`)
+
+var accountOnlyRegex = regexp.MustCompile(`^[ \t]+((?:[*!]\s+)?[^;\s](?:[^;\s]| [^;\s])*)\s*$`)
+
+var prefixCommodityRegex = regexp.MustCompile(`^([A-Za-z_]+)\s+$`)
+
+var bareSuffixRegex = regexp.MustCompile(`^\s*(?:[@=;].*)?$`)
+
+// FormatFile reformats the journal text to the canonical style. The
+// postings are indented by 4 spaces, commodity names written before
+// the amount are moved after it and the amounts are aligned to the
+// amount alignment column. Transactions are sorted by date, but only
+// within a run of transactions, they are never moved across a
+// directive or a detached comment. Beancount files are not sorted as
+// the directives there are dated as well.
+func FormatFile(content string) string {
+	lines := strings.Split(content, "\n")
+	inTransaction := false
+	for i, line := range lines {
+		if dateRegex.MatchString(line) || strings.HasPrefix(line, "~") || strings.HasPrefix(line, "=") {
+			inTransaction = true
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" || !isIndented(line) {
+			inTransaction = false
+		}
+
+		if inTransaction {
+			lines[i] = formatLine(line)
+		}
+	}
+
+	if config.LedgerCliFor(config.GetJournalPath()) != "beancount" {
+		lines = sortTransactions(lines)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Diff returns the unified diff between the two versions of the file,
+// empty if they are the same.
+func Diff(name string, before string, after string) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: name,
+		ToFile:   name,
+		Context:  3,
+	})
+	return diff
+}
+
+func formatLine(line string) string {
+	alignment := config.GetConfig().AmountAlignmentColumn
+
+	if match := postingRegex.FindStringSubmatch(line); match != nil {
+		account, prefix, amount, suffix := match[1], match[2], match[3], match[4]
+		if commodity := prefixCommodityRegex.FindStringSubmatch(prefix); commodity != nil && bareSuffixRegex.MatchString(suffix) {
+			prefix = ""
+			suffix = " " + commodity[1]
+			if rest := strings.TrimSpace(match[4]); rest != "" {
+				suffix += " " + rest
+			}
+		}
+
+		if len(account)+len(prefix)+len(amount) <= alignment-6 {
+			return strings.Repeat(" ", 4) + account + strings.Repeat(" ", alignment-4-len(account)-len(prefix)-len(amount)) + prefix + amount + suffix
+		}
+		return strings.Repeat(" ", 4) + strings.TrimLeft(line, " \t")
+	}
+
+	if match := accountOnlyRegex.FindStringSubmatch(line); match != nil {
+		return strings.Repeat(" ", 4) + match[1]
+	}
+
+	return strings.Repeat(" ", 4) + strings.TrimLeft(line, " \t")
+}
+
+type block struct {
+	lines       []string
+	transaction bool
+	blank       bool
+	date        [3]int
+}
+
+func sortTransactions(lines []string) []string {
+	blocks := []block{}
+	comments := []string{}
+	flushComments := func() {
+		for _, c := range comments {
+			blocks = append(blocks, block{lines: []string{c}})
+		}
+		comments = []string{}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case dateRegex.MatchString(line):
+			b := block{lines: append(comments, line), transaction: true, date: parseDate(line)}
+			comments = []string{}
+			for i+1 < len(lines) && isIndented(lines[i+1]) && strings.TrimSpace(lines[i+1]) != "" {
+				i++
+				b.lines = append(b.lines, lines[i])
+			}
+			blocks = append(blocks, b)
+		case strings.TrimSpace(line) == "":
+			flushComments()
+			blocks = append(blocks, block{lines: []string{line}, blank: true})
+		case isComment(line):
+			comments = append(comments, line)
+		default:
+			flushComments()
+			blocks = append(blocks, block{lines: []string{line}})
+		}
+	}
+	flushComments()
+
+	for start := 0; start < len(blocks); {
+		end := start
+		for end < len(blocks) && (blocks[end].transaction || blocks[end].blank) {
+			end++
+		}
+		if end == start {
+			start++
+			continue
+		}
+
+		positions := []int{}
+		transactions := []block{}
+		for i := start; i < end; i++ {
+			if blocks[i].transaction {
+				positions = append(positions, i)
+				transactions = append(transactions, blocks[i])
+			}
+		}
+		sort.SliceStable(transactions, func(i, j int) bool { return compareDate(transactions[i].date, transactions[j].date) < 0 })
+		for i, p := range positions {
+			blocks[p] = transactions[i]
+		}
+		start = end
+	}
+
+	result := []string{}
+	for _, b := range blocks {
+		result = append(result, b.lines...)
+	}
+	return result
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+func isComment(line string) bool {
+	return line != "" && strings.ContainsRune(";#%|*", rune(line[0]))
+}
+
+func parseDate(line string) [3]int {
+	match := dateRegex.FindStringSubmatch(line)
+	var date [3]int
+	for i := 0; i < 3; i++ {
+		date[i], _ = strconv.Atoi(match[i+1])
+	}
+	return date
+}
+
+func compareDate(a [3]int, b [3]int) int {
+	for i := 0; i < 3; i++ {
+		if a[i] != b[i] {
+			return a[i] - b[i]
+		}
+	}
+	return 0
+}
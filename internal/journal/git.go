@@ -0,0 +1,70 @@
+package journal
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/encryption"
+	"github.com/ananthakumaran/paisa/internal/remote"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Written is called after the journal files are modified. The changes
+// are encrypted right away if the journal is encrypted, uploaded if it
+// is stored remotely and committed to the git repository of the
+// journal directory, if enabled. The files are already written by
+// then, so the failures are only logged.
+func Written(db *gorm.DB, operation string, names ...string) {
+	if _, ok := db.Get(JOURNAL_PATH_KEY); ok || len(names) == 0 {
+		return
+	}
+
+	dir := Dir(db)
+	if vault := encryption.Current(); vault != nil {
+		if err := vault.Flush(); err != nil {
+			log.Error("Failed to encrypt the journal: ", err)
+			return
+		}
+
+		dir = vault.SourceDir()
+		encrypted := make([]string, len(names))
+		for i, name := range names {
+			encrypted[i] = name + encryption.EXTENSION
+		}
+		names = encrypted
+	}
+
+	if r := remote.Current(); r != nil {
+		if _, err := r.Sync(names...); err != nil {
+			log.Error("Failed to upload the journal: ", err)
+		}
+	}
+
+	if config.GetConfig().GitCommit {
+		commit(dir, operation, names)
+	}
+}
+
+func commit(dir string, operation string, names []string) {
+	git := func(args ...string) error {
+		output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput()
+		if err != nil {
+			log.Warn("git ", strings.Join(args, " "), ": ", strings.TrimSpace(string(output)))
+		}
+		return err
+	}
+
+	err := git(append([]string{"add", "--"}, names...)...)
+	if err != nil {
+		return
+	}
+
+	// nothing to commit if the content didn't change
+	if exec.Command("git", append([]string{"-C", dir, "diff", "--cached", "--quiet", "--"}, names...)...).Run() == nil {
+		return
+	}
+
+	_ = git(append([]string{"commit", "-m", "paisa: " + operation, "--"}, names...)...)
+}
@@ -0,0 +1,228 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/backup"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const JOURNAL_PATH_KEY = "paisa:journal_path"
+
+// WithJournalPath points the db session at a different main journal
+// file. Everything that reads or writes the journal through the
+// session will use it instead of the configured one.
+func WithJournalPath(db *gorm.DB, path string) *gorm.DB {
+	return db.Set(JOURNAL_PATH_KEY, path).Session(&gorm.Session{})
+}
+
+func JournalPath(db *gorm.DB) string {
+	if path, ok := db.Get(JOURNAL_PATH_KEY); ok {
+		return path.(string)
+	}
+	return config.GetJournalPath()
+}
+
+func Dir(db *gorm.DB) string {
+	return filepath.Dir(JournalPath(db))
+}
+
+func Path(db *gorm.DB, name string) (string, error) {
+	dir := Dir(db)
+	path := filepath.Join(dir, name)
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("File %s is outside the journal directory", name)
+	}
+	return path, nil
+}
+
+// Files returns the names of all the journal files under the journal
+// directory, relative to it.
+func Files(db *gorm.DB) ([]string, error) {
+	path := JournalPath(db)
+	dir := filepath.Dir(path)
+	paths, err := config.GlobFiles(dir, filepath.Ext(path))
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, p := range paths {
+		name, err := filepath.Rel(dir, p)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func Read(db *gorm.DB, name string) (string, error) {
+	path, err := Path(db, name)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// Backup copies the files to the managed backups directory. Working
+// copies of the journal, like the one used by a sandbox, are not
+// backed up.
+func Backup(db *gorm.DB, operation string, names ...string) error {
+	if _, ok := db.Get(JOURNAL_PATH_KEY); ok {
+		return nil
+	}
+
+	paths := make([]string, len(names))
+	for i, name := range names {
+		path, err := Path(db, name)
+		if err != nil {
+			return err
+		}
+		paths[i] = path
+	}
+
+	_, err := backup.Create(operation, paths...)
+	return err
+}
+
+// Write replaces the content of the file after taking a backup of the
+// existing content in the backups directory. The file is created if it
+// doesn't exist yet.
+func Write(db *gorm.DB, operation string, name string, content string) error {
+	err := Backup(db, operation, name)
+	if err != nil {
+		return fmt.Errorf("Failed to create backup: %w", err)
+	}
+
+	err = write(db, name, content)
+	if err != nil {
+		return err
+	}
+
+	Written(db, operation, name)
+	return nil
+}
+
+func write(db *gorm.DB, name string, content string) error {
+	path, err := Path(db, name)
+	if err != nil {
+		return err
+	}
+
+	stat, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return err
+		}
+		return utils.WriteFileAtomic(path, []byte(content), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteFileAtomic(path, []byte(content), stat.Mode().Perm())
+}
+
+// ReplaceLines replaces the lines between begin and end (1 based,
+// inclusive) with the replacement text. An empty replacement removes
+// the lines altogether.
+func ReplaceLines(content string, begin uint64, end uint64, replacement string) (string, error) {
+	lines := strings.Split(content, "\n")
+	if begin < 1 || end < begin || end > uint64(len(lines)) {
+		return "", errors.New("Invalid line range")
+	}
+
+	var result []string
+	result = append(result, lines[:begin-1]...)
+	if replacement != "" {
+		result = append(result, strings.Split(replacement, "\n")...)
+	} else if begin > 1 && end < uint64(len(lines)) && strings.TrimSpace(lines[begin-2]) == "" && strings.TrimSpace(lines[end]) == "" {
+		// avoid leaving behind consecutive blank lines
+		end++
+	}
+	result = append(result, lines[end:]...)
+	return strings.Join(result, "\n"), nil
+}
+
+func Lines(content string, begin uint64, end uint64) (string, error) {
+	lines := strings.Split(content, "\n")
+	if begin < 1 || end < begin || end > uint64(len(lines)) {
+		return "", errors.New("Invalid line range")
+	}
+	return strings.Join(lines[begin-1:end], "\n"), nil
+}
+
+// WriteAll writes all the files, restoring the original content of the
+// files already written in case any of the writes fail. Files created
+// by the call are removed.
+func WriteAll(db *gorm.DB, operation string, files map[string]string) error {
+	err := Backup(db, operation, lo.Keys(files)...)
+	if err != nil {
+		return fmt.Errorf("Failed to create backup: %w", err)
+	}
+
+	type original struct {
+		content string
+		perm    os.FileMode
+	}
+	originals := make(map[string]*original)
+	for name := range files {
+		path, err := Path(db, name)
+		if err != nil {
+			return err
+		}
+		stat, err := os.Stat(path)
+		if errors.Is(err, os.ErrNotExist) {
+			originals[name] = nil
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		originals[name] = &original{content: string(content), perm: stat.Mode().Perm()}
+	}
+
+	written := []string{}
+	for name, content := range files {
+		err := write(db, name, content)
+		if err != nil {
+			for _, w := range written {
+				path, _ := Path(db, w)
+				var restoreErr error
+				if originals[w] == nil {
+					restoreErr = os.Remove(path)
+				} else {
+					restoreErr = utils.WriteFileAtomic(path, []byte(originals[w].content), originals[w].perm)
+				}
+				if restoreErr != nil {
+					log.Error(restoreErr)
+				}
+			}
+			return err
+		}
+		written = append(written, name)
+	}
+
+	Written(db, operation, written...)
+	return nil
+}
@@ -0,0 +1,54 @@
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAll(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	dir := t.TempDir()
+	db, err := utils.OpenDBAt(filepath.Join(dir, "paisa.db"))
+	require.NoError(t, err)
+	db = WithJournalPath(db, filepath.Join(dir, "main.ledger"))
+
+	main := filepath.Join(dir, "main.ledger")
+	require.NoError(t, os.WriteFile(main, []byte("old"), 0600))
+
+	err = WriteAll(db, "test", map[string]string{"main.ledger": "new", "2023/jan.ledger": "created"})
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+
+	stat, err := os.Stat(main)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+
+	// the backups are kept in the backups directory only
+	copies, err := filepath.Glob(main + ".backup.*")
+	require.NoError(t, err)
+	assert.Empty(t, copies)
+
+	content, err = os.ReadFile(filepath.Join(dir, "2023", "jan.ledger"))
+	require.NoError(t, err)
+	assert.Equal(t, "created", string(content))
+
+	files, err := Files(db)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"main.ledger", "2023/jan.ledger"}, files)
+
+	err = WriteAll(db, "test", map[string]string{"main.ledger": "newer", "../outside.ledger": "x"})
+	assert.Error(t, err)
+	content, err = os.ReadFile(main)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+}
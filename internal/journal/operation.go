@@ -0,0 +1,332 @@
+package journal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+var statusRegex = regexp.MustCompile(`^(\d{4}[/.-]\d{1,2}[/.-]\d{1,2}(?:=\S+)?)(?:\s+[*!])?(\s.*)?$`)
+
+var quotedRegex = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+var headerRegex = regexp.MustCompile(`^(\d{4}[/.-]\d{1,2}[/.-]\d{1,2}(?:=\S+)?(?:\s+[*!])?(?:\s+\([^)]*\))?)(\s+)([^;]*?)(\s*(?:;.*)?)$`)
+
+// RenameAccount renames the account used in the posting lines of the
+// transaction text, including the virtual postings. Sub accounts are
+// renamed as well.
+func RenameAccount(text string, oldAccount string, newAccount string) (string, bool) {
+	regex := regexp.MustCompile(`^((?:\t|\s{2})\s*(?:[*!]\s+)?[\[(]?)(` + regexp.QuoteMeta(oldAccount) + `)((?::[^\s;\])]+(?:\s[^\s;\])]+)*)?)([\])]?(?:(?:\t|\s{2}).*|\s*))$`)
+	updated := false
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if regex.MatchString(line) {
+			lines[i] = regex.ReplaceAllString(line, "${1}"+strings.ReplaceAll(newAccount, "$", "$$")+"${3}${4}")
+			updated = true
+		}
+	}
+	return strings.Join(lines, "\n"), updated
+}
+
+// SplitPosting replaces the posting line of the account with the given
+// postings. The account should appear exactly once in the
+// transaction. The note of the original posting is kept on the new
+// postings without a note.
+func SplitPosting(text string, account string, postings []Posting) (string, bool) {
+	regex := regexp.MustCompile(`^(?:\t|\s{2})\s*(?:[*!]\s+)?` + regexp.QuoteMeta(account) + `(?:(?:\t|\s{2})[^;]*)?\s*(?:;\s*(.*))?$`)
+	lines := strings.Split(text, "\n")
+	matched := -1
+	for i, line := range lines {
+		if regex.MatchString(line) {
+			if matched != -1 {
+				return text, false
+			}
+			matched = i
+		}
+	}
+	if matched == -1 {
+		return text, false
+	}
+
+	note := strings.TrimSpace(regex.FindStringSubmatch(lines[matched])[1])
+	replacement := []string{}
+	for _, p := range postings {
+		if p.Note == "" {
+			p.Note = note
+		}
+		replacement = append(replacement, formatPosting(p))
+	}
+
+	lines = append(lines[:matched], append(replacement, lines[matched+1:]...)...)
+	return strings.Join(lines, "\n"), true
+}
+
+// ChangePayee replaces the payee in the transaction header line. On
+// beancount only the payee string is replaced, the narration and the
+// tags are kept. A lone string is the narration, which is shown as the
+// payee, so it's replaced instead.
+func ChangePayee(text string, payee string, cli string) (string, bool) {
+	lines := strings.Split(text, "\n")
+	if len(lines) == 0 || !headerRegex.MatchString(lines[0]) {
+		return text, false
+	}
+
+	match := headerRegex.FindStringSubmatch(lines[0])
+	description := payee
+	if cli == "beancount" {
+		payee = "\"" + strings.ReplaceAll(payee, "\"", "'") + "\""
+		description = strings.TrimSpace(payee + " " + match[3])
+		if loc := quotedRegex.FindStringIndex(match[3]); loc != nil {
+			description = match[3][:loc[0]] + payee + match[3][loc[1]:]
+		}
+	}
+
+	if match[3] == description {
+		return text, false
+	}
+	lines[0] = match[1] + match[2] + description + match[4]
+	return strings.Join(lines, "\n"), true
+}
+
+// AddTag appends the tag to the transaction header line using the tag
+// syntax of the ledger client.
+func AddTag(text string, tag string, cli string) (string, bool) {
+	lines := strings.Split(text, "\n")
+	if len(lines) == 0 || !headerRegex.MatchString(lines[0]) {
+		return text, false
+	}
+
+	var formatted string
+	var existing *regexp.Regexp
+	switch cli {
+	case "beancount":
+		formatted = "#" + tag
+		existing = regexp.MustCompile(`(?:^|\s)#` + regexp.QuoteMeta(tag) + `(?:\s|$)`)
+	case "hledger":
+		formatted = tag + ":"
+		existing = regexp.MustCompile(`;(?:.*[\s,])?` + regexp.QuoteMeta(tag) + `:`)
+	default:
+		formatted = ":" + tag + ":"
+		existing = regexp.MustCompile(regexp.QuoteMeta(formatted))
+	}
+
+	if existing.MatchString(lines[0]) {
+		return text, false
+	}
+
+	if cli == "beancount" {
+		match := headerRegex.FindStringSubmatch(lines[0])
+		lines[0] = match[1] + match[2] + strings.TrimRight(match[3], " ") + " " + formatted + match[4]
+	} else if strings.Contains(lines[0], ";") {
+		lines[0] = fmt.Sprintf("%s %s", lines[0], formatted)
+	} else {
+		lines[0] = fmt.Sprintf("%s ; %s", lines[0], formatted)
+	}
+	return strings.Join(lines, "\n"), true
+}
+
+// SetStatus changes the status flag in the transaction header line.
+// Flags on the individual postings are left as is. beancount requires
+// a flag, so a transaction can't be unmarked.
+func SetStatus(text string, status string, cli string) (string, bool) {
+	lines := strings.Split(text, "\n")
+	if len(lines) == 0 || !statusRegex.MatchString(lines[0]) {
+		return text, false
+	}
+
+	var flag string
+	switch status {
+	case "cleared":
+		flag = " *"
+	case "pending":
+		flag = " !"
+	default:
+		if cli == "beancount" {
+			return text, false
+		}
+	}
+
+	match := statusRegex.FindStringSubmatch(lines[0])
+	header := match[1] + flag + match[2]
+	if header == lines[0] {
+		return text, false
+	}
+	lines[0] = header
+	return strings.Join(lines, "\n"), true
+}
+
+var postingLineRegex = regexp.MustCompile(`^(\s+(?:[*!]\s+)?)([^\s;]+(?: [^\s;]+)*)((?:\t|\s{2})\s*)?([^;@{=\[]*?)(\s*[@{=\[][^;]*?)?(\s*;\s*(.*))?$`)
+
+var metadataRegex = regexp.MustCompile(`^\s+[a-z][\w-]*:(?:\s|$)`)
+
+// UpdateTransaction updates the transaction text with the fields of
+// the transaction that are different. Everything else like the code,
+// the metadata, the comments and the price annotations is kept as
+// is. The postings are matched by their account first and then by
+// their position, extra postings are removed along with their comments
+// and new postings are appended.
+func UpdateTransaction(text string, t Transaction, cli string) string {
+	lines := strings.Split(text, "\n")
+	if len(lines) == 0 || !headerRegex.MatchString(lines[0]) {
+		return t.Format()
+	}
+
+	header := lines[0]
+	date := parseDate(header)
+	if date != [3]int{t.Date.Year(), int(t.Date.Month()), t.Date.Day()} {
+		separator := string(header[4])
+		header = dateRegex.ReplaceAllLiteralString(header, t.Date.Format("2006"+separator+"01"+separator+"02"))
+	}
+
+	status := t.Status
+	if status == "unmarked" {
+		status = ""
+	}
+	header, _ = SetStatus(header, status, cli)
+
+	if payee(header, cli) != t.Payee {
+		header = changeDescription(header, t.Payee, cli)
+	}
+
+	match := headerRegex.FindStringSubmatch(header)
+	if comment(match[4]) != strings.TrimSpace(t.Note) {
+		note := ""
+		if strings.TrimSpace(t.Note) != "" {
+			note = " ; " + strings.TrimSpace(t.Note)
+		}
+		header = match[1] + match[2] + match[3] + note
+	}
+
+	var accounts []string
+	for _, line := range lines[1:] {
+		if isPosting(line, cli) {
+			account := ""
+			if match := postingLineRegex.FindStringSubmatch(line); match != nil {
+				account = match[2]
+			}
+			accounts = append(accounts, account)
+		}
+	}
+
+	matched := make([]int, len(accounts))
+	used := make([]bool, len(t.Postings))
+	for i, account := range accounts {
+		matched[i] = -1
+		for j, p := range t.Postings {
+			if !used[j] && strings.TrimSpace(p.Account) == account {
+				matched[i], used[j] = j, true
+				break
+			}
+		}
+	}
+	for i := range accounts {
+		for j := range t.Postings {
+			if matched[i] == -1 && !used[j] {
+				matched[i], used[j] = j, true
+			}
+		}
+	}
+
+	result := []string{header}
+	i := 0
+	removing := false
+	for _, line := range lines[1:] {
+		if !isPosting(line, cli) {
+			if !removing {
+				result = append(result, line)
+			}
+			continue
+		}
+
+		removing = matched[i] == -1
+		if !removing {
+			result = append(result, updatePosting(line, t.Postings[matched[i]]))
+		}
+		i++
+	}
+
+	for j, p := range t.Postings {
+		if !used[j] {
+			result = append(result, formatPosting(p))
+		}
+	}
+
+	return strings.Join(result, "\n")
+}
+
+func isPosting(line string, cli string) bool {
+	if !isIndented(line) || strings.TrimSpace(line) == "" || isComment(strings.TrimSpace(line)) {
+		return false
+	}
+	return cli != "beancount" || !metadataRegex.MatchString(line)
+}
+
+func updatePosting(line string, p Posting) string {
+	match := postingLineRegex.FindStringSubmatch(line)
+	if match == nil {
+		return formatPosting(p)
+	}
+
+	prefix, account, separator, amount, annotation, note := match[1], match[2], match[3], match[4], match[5], match[6]
+	account = strings.TrimSpace(p.Account)
+
+	if strings.TrimSpace(amount) != strings.TrimSpace(p.Amount) {
+		amount = strings.TrimSpace(p.Amount)
+		if amount == "" {
+			separator, annotation = "", ""
+		} else if separator == "" {
+			separator = "  "
+		}
+	}
+
+	if strings.TrimSpace(match[7]) != strings.TrimSpace(p.Note) {
+		note = ""
+		if strings.TrimSpace(p.Note) != "" {
+			note = " ; " + strings.TrimSpace(p.Note)
+		}
+	}
+
+	return prefix + account + separator + amount + annotation + note
+}
+
+// changeDescription replaces the payee and the narration of the
+// beancount header line when both are given, the payee otherwise.
+func changeDescription(header string, description string, cli string) string {
+	match := headerRegex.FindStringSubmatch(header)
+	parts := strings.SplitN(description, " | ", 2)
+	if cli != "beancount" || len(parts) != 2 || len(quotedRegex.FindAllString(match[3], 2)) != 2 {
+		header, _ = ChangePayee(header, description, cli)
+		return header
+	}
+
+	i := 0
+	replaced := quotedRegex.ReplaceAllStringFunc(match[3], func(quoted string) string {
+		if i >= len(parts) {
+			return quoted
+		}
+		i++
+		return "\"" + strings.ReplaceAll(parts[i-1], "\"", "'") + "\""
+	})
+	return match[1] + match[2] + replaced + match[4]
+}
+
+// payee returns the payee of the header line the way it's shown, the
+// beancount payee and narration are joined with a pipe.
+func payee(header string, cli string) string {
+	description := headerRegex.FindStringSubmatch(header)[3]
+	if cli != "beancount" {
+		return strings.TrimSpace(description)
+	}
+
+	var parts []string
+	for _, quoted := range quotedRegex.FindAllString(description, 2) {
+		parts = append(parts, strings.TrimSpace(strings.ReplaceAll(quoted[1:len(quoted)-1], `\"`, `"`)))
+	}
+	return strings.Join(lo.Filter(parts, func(part string, _ int) bool { return part != "" }), " | ")
+}
+
+func comment(text string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text), ";"))
+}
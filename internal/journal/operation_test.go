@@ -0,0 +1,315 @@
+package journal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenameAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		old      string
+		new      string
+		expected string
+		updated  bool
+	}{
+		{
+			name:     "posting",
+			text:     "2023/01/01 Salary\n    Assets:Checking  1000 INR\n    Income:Salary",
+			old:      "Assets:Checking",
+			new:      "Assets:Bank:Checking",
+			expected: "2023/01/01 Salary\n    Assets:Bank:Checking  1000 INR\n    Income:Salary",
+			updated:  true,
+		},
+		{
+			name:     "sub account",
+			text:     "2023/01/01 Lunch\n    Expenses:Food:Dining Out  100 INR\n    Assets:Checking",
+			old:      "Expenses:Food",
+			new:      "Expenses:Meals",
+			expected: "2023/01/01 Lunch\n    Expenses:Meals:Dining Out  100 INR\n    Assets:Checking",
+			updated:  true,
+		},
+		{
+			name:     "account as a prefix of another account",
+			text:     "2023/01/01 Lunch\n    Expenses:FoodCourt  100 INR\n    Expenses:Food Truck  50 INR\n    Assets:Checking",
+			old:      "Expenses:Food",
+			new:      "Expenses:Meals",
+			expected: "2023/01/01 Lunch\n    Expenses:FoodCourt  100 INR\n    Expenses:Food Truck  50 INR\n    Assets:Checking",
+			updated:  false,
+		},
+		{
+			name:     "account as a suffix of another account",
+			text:     "2023/01/01 Lunch\n    Expenses:Business:Food  100 INR\n    Assets:Checking",
+			old:      "Business:Food",
+			new:      "Business:Meals",
+			expected: "2023/01/01 Lunch\n    Expenses:Business:Food  100 INR\n    Assets:Checking",
+			updated:  false,
+		},
+		{
+			name:     "account in the payee and comment",
+			text:     "2023/01/01 Transfer to Assets:Checking ; Assets:Checking\n    Assets:Checking  100 INR ; from Assets:Checking\n    Assets:Savings",
+			old:      "Assets:Checking",
+			new:      "Assets:Current",
+			expected: "2023/01/01 Transfer to Assets:Checking ; Assets:Checking\n    Assets:Current  100 INR ; from Assets:Checking\n    Assets:Savings",
+			updated:  true,
+		},
+		{
+			name:     "cleared posting with a tab",
+			text:     "2023/01/01 Salary\n\t* Assets:Checking\t1000 INR\n\tIncome:Salary",
+			old:      "Assets:Checking",
+			new:      "Assets:Current",
+			expected: "2023/01/01 Salary\n\t* Assets:Current\t1000 INR\n\tIncome:Salary",
+			updated:  true,
+		},
+		{
+			name:     "virtual postings",
+			text:     "2023/01/01 Budget\n    [Assets:Checking]  1000 INR\n    (Assets:Checking:Savings)  -1000 INR\n    [Assets:CheckingOld]",
+			old:      "Assets:Checking",
+			new:      "Assets:Current",
+			expected: "2023/01/01 Budget\n    [Assets:Current]  1000 INR\n    (Assets:Current:Savings)  -1000 INR\n    [Assets:CheckingOld]",
+			updated:  true,
+		},
+		{
+			name:     "replacement with a dollar",
+			text:     "2023/01/01 Salary\n    Assets:Checking  1000 INR\n    Income:Salary",
+			old:      "Assets:Checking",
+			new:      "Assets:$1",
+			expected: "2023/01/01 Salary\n    Assets:$1  1000 INR\n    Income:Salary",
+			updated:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, updated := RenameAccount(test.text, test.old, test.new)
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.updated, updated)
+		})
+	}
+}
+
+func TestChangePayee(t *testing.T) {
+	tests := []struct {
+		name     string
+		cli      string
+		text     string
+		payee    string
+		expected string
+		updated  bool
+	}{
+		{
+			name:     "payee",
+			text:     "2023/01/01 Swiggy\n    Expenses:Food  100 INR\n    Assets:Checking",
+			payee:    "Zomato",
+			expected: "2023/01/01 Zomato\n    Expenses:Food  100 INR\n    Assets:Checking",
+			updated:  true,
+		},
+		{
+			name:     "status, code and comment",
+			text:     "2023/01/01 * (42) Swiggy  ; :food:\n    Expenses:Food  100 INR\n    Assets:Checking",
+			payee:    "Zomato",
+			expected: "2023/01/01 * (42) Zomato  ; :food:\n    Expenses:Food  100 INR\n    Assets:Checking",
+			updated:  true,
+		},
+		{
+			name:     "same payee",
+			text:     "2023/01/01 Swiggy\n    Expenses:Food  100 INR\n    Assets:Checking",
+			payee:    "Swiggy",
+			expected: "2023/01/01 Swiggy\n    Expenses:Food  100 INR\n    Assets:Checking",
+			updated:  false,
+		},
+		{
+			name:     "not a transaction",
+			text:     "P 2023/01/01 NIFTY 100 INR",
+			payee:    "Zomato",
+			expected: "P 2023/01/01 NIFTY 100 INR",
+			updated:  false,
+		},
+		{
+			name:     "beancount payee and narration",
+			cli:      "beancount",
+			text:     "2023-01-01 * \"Swiggy\" \"Dinner\" #food\n  Expenses:Food  100 INR",
+			payee:    "Zomato",
+			expected: "2023-01-01 * \"Zomato\" \"Dinner\" #food\n  Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "beancount narration",
+			cli:      "beancount",
+			text:     "2023-01-01 * \"Swiggy\" #food\n  Expenses:Food  100 INR",
+			payee:    "Zomato",
+			expected: "2023-01-01 * \"Zomato\" #food\n  Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "beancount same payee",
+			cli:      "beancount",
+			text:     "2023-01-01 * \"Swiggy\" \"Dinner\"\n  Expenses:Food  100 INR",
+			payee:    "Swiggy",
+			expected: "2023-01-01 * \"Swiggy\" \"Dinner\"\n  Expenses:Food  100 INR",
+			updated:  false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, updated := ChangePayee(test.text, test.payee, test.cli)
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.updated, updated)
+		})
+	}
+}
+
+func TestAddTag(t *testing.T) {
+	tests := []struct {
+		name     string
+		cli      string
+		text     string
+		tag      string
+		expected string
+		updated  bool
+	}{
+		{
+			name:     "ledger",
+			text:     "2023/01/01 Swiggy\n    Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023/01/01 Swiggy ; :food:\n    Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "ledger with a comment",
+			text:     "2023/01/01 Swiggy ; :travel:\n    Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023/01/01 Swiggy ; :travel: :food:\n    Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "ledger existing tag",
+			text:     "2023/01/01 Swiggy ; :travel:food:\n    Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023/01/01 Swiggy ; :travel:food:\n    Expenses:Food  100 INR",
+			updated:  false,
+		},
+		{
+			name:     "ledger tag as a substring of an existing tag",
+			text:     "2023/01/01 Swiggy ; :seafood:\n    Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023/01/01 Swiggy ; :seafood: :food:\n    Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "hledger",
+			cli:      "hledger",
+			text:     "2023-01-01 Swiggy\n    Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023-01-01 Swiggy ; food:\n    Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "hledger existing tag with a value",
+			cli:      "hledger",
+			text:     "2023-01-01 Swiggy ; trip:goa, food:dinner\n    Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023-01-01 Swiggy ; trip:goa, food:dinner\n    Expenses:Food  100 INR",
+			updated:  false,
+		},
+		{
+			name:     "hledger tag as a substring of an existing tag",
+			cli:      "hledger",
+			text:     "2023-01-01 Swiggy ; seafood:\n    Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023-01-01 Swiggy ; seafood: food:\n    Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "beancount",
+			cli:      "beancount",
+			text:     "2023-01-01 * \"Swiggy\" #travel\n  Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023-01-01 * \"Swiggy\" #travel #food\n  Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "beancount tag as a prefix of an existing tag",
+			cli:      "beancount",
+			text:     "2023-01-01 * \"Swiggy\" #foodie\n  Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023-01-01 * \"Swiggy\" #foodie #food\n  Expenses:Food  100 INR",
+			updated:  true,
+		},
+		{
+			name:     "beancount existing tag",
+			cli:      "beancount",
+			text:     "2023-01-01 * \"Swiggy\" #food #travel\n  Expenses:Food  100 INR",
+			tag:      "food",
+			expected: "2023-01-01 * \"Swiggy\" #food #travel\n  Expenses:Food  100 INR",
+			updated:  false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			actual, updated := AddTag(test.text, test.tag, test.cli)
+			assert.Equal(t, test.expected, actual)
+			assert.Equal(t, test.updated, updated)
+		})
+	}
+}
+
+func TestUpdateTransaction(t *testing.T) {
+	date := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		cli         string
+		text        string
+		transaction Transaction
+		expected    string
+	}{
+		{
+			name: "unchanged",
+			text: "2023/01/02 * (42) Swiggy  ; :food:\n    ; order: 1234\n    Expenses:Food  2 PIZZA @ 100 INR ; dinner\n    Assets:Checking",
+			transaction: Transaction{Date: date, Payee: "Swiggy", Status: "cleared", Note: ":food:", Postings: []Posting{
+				{Account: "Expenses:Food", Amount: "2 PIZZA", Note: "dinner"},
+				{Account: "Assets:Checking"},
+			}},
+			expected: "2023/01/02 * (42) Swiggy  ; :food:\n    ; order: 1234\n    Expenses:Food  2 PIZZA @ 100 INR ; dinner\n    Assets:Checking",
+		},
+		{
+			name: "changed fields",
+			text: "2023/01/01 * (42) Swiggy  ; :food:\n    ; order: 1234\n    Expenses:Food  2 PIZZA @ 100 INR ; dinner\n    Assets:Checking",
+			transaction: Transaction{Date: date, Payee: "Zomato", Status: "pending", Note: ":food:", Postings: []Posting{
+				{Account: "Expenses:Dining", Amount: "3 PIZZA", Note: "dinner"},
+				{Account: "Assets:Savings", Note: "card"},
+			}},
+			expected: "2023/01/02 ! (42) Zomato  ; :food:\n    ; order: 1234\n    Expenses:Dining  3 PIZZA @ 100 INR ; dinner\n    Assets:Savings ; card",
+		},
+		{
+			name: "removed and added postings",
+			text: "2023/01/02 Swiggy\n    Expenses:Food  100 INR\n    Expenses:Tip  10 INR\n    ; cash\n    Assets:Checking",
+			transaction: Transaction{Date: date, Payee: "Swiggy", Postings: []Posting{
+				{Account: "Expenses:Food", Amount: "100 INR"},
+				{Account: "Assets:Checking", Amount: "-100 INR"},
+			}},
+			expected: "2023/01/02 Swiggy\n    Expenses:Food  100 INR\n    Assets:Checking  -100 INR",
+		},
+		{
+			name: "beancount metadata",
+			cli:  "beancount",
+			text: "2023-01-02 * \"Swiggy\" \"Dinner\" #food\n  order: \"1234\"\n  Expenses:Food  100 INR\n  Assets:Checking",
+			transaction: Transaction{Date: date, Payee: "Zomato | Dinner", Status: "cleared", Postings: []Posting{
+				{Account: "Expenses:Food", Amount: "120 INR"},
+				{Account: "Assets:Checking"},
+			}},
+			expected: "2023-01-02 * \"Zomato\" \"Dinner\" #food\n  order: \"1234\"\n  Expenses:Food  120 INR\n  Assets:Checking",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, UpdateTransaction(test.text, test.transaction, test.cli))
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package journal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+)
+
+var plainCommodityRegex = regexp.MustCompile(`^[A-Za-z_]+$`)
+
+type Posting struct {
+	Account string `json:"account"`
+	Amount  string `json:"amount"`
+	Note    string `json:"note"`
+}
+
+type Transaction struct {
+	Date     time.Time `json:"date"`
+	Payee    string    `json:"payee"`
+	Status   string    `json:"status"`
+	Note     string    `json:"note"`
+	Postings []Posting `json:"postings"`
+}
+
+func (t Transaction) Validate() error {
+	if t.Date.IsZero() {
+		return fmt.Errorf("Transaction date is required")
+	}
+
+	if len(t.Postings) < 2 {
+		return fmt.Errorf("Transaction should have at least two postings")
+	}
+
+	for _, p := range t.Postings {
+		if strings.TrimSpace(p.Account) == "" {
+			return fmt.Errorf("Posting account is required")
+		}
+	}
+
+	missingAmounts := 0
+	for _, p := range t.Postings {
+		if strings.TrimSpace(p.Amount) == "" {
+			missingAmounts++
+		}
+	}
+
+	if missingAmounts > 1 {
+		return fmt.Errorf("Only one posting can have empty amount")
+	}
+
+	return nil
+}
+
+func (t Transaction) Format() string {
+	beancount := config.LedgerCliFor(config.GetJournalPath()) == "beancount"
+	var lines []string
+
+	var header string
+	if beancount {
+		flag := "*"
+		if t.Status == "pending" {
+			flag = "!"
+		}
+		header = fmt.Sprintf("%s %s \"%s\"", t.Date.Format("2006-01-02"), flag, strings.ReplaceAll(t.Payee, "\"", "'"))
+	} else {
+		header = t.Date.Format("2006/01/02")
+		switch t.Status {
+		case "cleared":
+			header += " *"
+		case "pending":
+			header += " !"
+		}
+		header += " " + t.Payee
+	}
+
+	if t.Note != "" {
+		header += " ; " + t.Note
+	}
+	lines = append(lines, header)
+
+	for _, p := range t.Postings {
+		lines = append(lines, formatPosting(p))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// PeriodicTransaction is the ledger forecast entry, the period is a
+// ledger period expression like "Monthly from 2024/01/01". Beancount
+// has no equivalent.
+type PeriodicTransaction struct {
+	Period   string    `json:"period"`
+	Postings []Posting `json:"postings"`
+}
+
+func (t PeriodicTransaction) Format() string {
+	lines := []string{"~ " + t.Period}
+	for _, p := range t.Postings {
+		lines = append(lines, formatPosting(p))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatPosting(p Posting) string {
+	alignment := config.GetConfig().AmountAlignmentColumn
+	account := strings.TrimSpace(p.Account)
+	amount := strings.TrimSpace(p.Amount)
+
+	line := strings.Repeat(" ", 4) + account
+	if amount != "" {
+		padding := alignment - 4 - len(account) - len(amount)
+		if padding < 2 {
+			padding = 2
+		}
+		line += strings.Repeat(" ", padding) + amount
+	}
+
+	if p.Note != "" {
+		line += " ; " + p.Note
+	}
+
+	return line
+}
+
+// FormatAmount formats the amount along with the commodity. Symbols
+// like $ are placed before the amount, commodities with characters
+// other than letters are quoted.
+func FormatAmount(amount decimal.Decimal, commodity string) string {
+	if plainCommodityRegex.MatchString(commodity) {
+		return amount.String() + " " + commodity
+	}
+
+	if len([]rune(commodity)) == 1 {
+		if amount.IsNegative() {
+			return "-" + commodity + amount.Neg().String()
+		}
+		return commodity + amount.String()
+	}
+
+	return fmt.Sprintf("%s \"%s\"", amount.String(), commodity)
+}
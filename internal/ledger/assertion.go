@@ -0,0 +1,187 @@
+package ledger
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/assertion"
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/shopspring/decimal"
+)
+
+// AssertionParser is implemented by the ledger clients that can list
+// the balance assertions in the journal.
+type AssertionParser interface {
+	Assertions(journalPath string) ([]*assertion.Assertion, error)
+}
+
+var (
+	ledgerIncludeRegex = regexp.MustCompile(`^!?include\s+(.+)$`)
+	ledgerDateRegex    = regexp.MustCompile(`^(\d{4})[/.-](\d{1,2})[/.-](\d{1,2})`)
+	lotRegex           = regexp.MustCompile(`\{[^}]*\}`)
+	plainNumberRegex   = regexp.MustCompile(`^-?[0-9.,]+$`)
+)
+
+// Assertions returns the balance assertions on the postings. Neither
+// ledger nor hledger list them in their reports, so the files are
+// read directly.
+func (LedgerCLI) Assertions(journalPath string) ([]*assertion.Assertion, error) {
+	return readAssertions(journalPath, parseLedgerAssertions)
+}
+
+func (HLedgerCLI) Assertions(journalPath string) ([]*assertion.Assertion, error) {
+	return readAssertions(journalPath, parseLedgerAssertions)
+}
+
+type assertionsParser func(content string, fileName string) ([]*assertion.Assertion, []string, error)
+
+// readAssertions parses the journal and the files included from it.
+// Include paths are relative to the including file and could be glob
+// patterns.
+func readAssertions(journalPath string, parse assertionsParser) ([]*assertion.Assertion, error) {
+	dir := filepath.Dir(journalPath)
+	assertions := []*assertion.Assertion{}
+	visited := map[string]bool{}
+
+	var read func(path string) error
+	read = func(path string) error {
+		if visited[path] {
+			return nil
+		}
+		visited[path] = true
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		fileName, err := filepath.Rel(dir, path)
+		if err != nil {
+			fileName = path
+		}
+
+		parsed, includes, err := parse(string(content), fileName)
+		if err != nil {
+			return err
+		}
+		assertions = append(assertions, parsed...)
+
+		for _, include := range includes {
+			if !filepath.IsAbs(include) {
+				include = filepath.Join(filepath.Dir(path), include)
+			}
+
+			matches, err := doublestar.FilepathGlob(include)
+			if err != nil {
+				return err
+			}
+			for _, match := range matches {
+				if err := read(match); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	err := read(journalPath)
+	return assertions, err
+}
+
+func parseLedgerAssertions(content string, fileName string) ([]*assertion.Assertion, []string, error) {
+	assertions := []*assertion.Assertion{}
+	includes := []string{}
+	var date time.Time
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			date = time.Time{}
+			if match := ledgerIncludeRegex.FindStringSubmatch(line); match != nil {
+				includes = append(includes, strings.Trim(strings.TrimSpace(match[1]), `"`))
+			} else if match := ledgerDateRegex.FindStringSubmatch(line); match != nil {
+				year, _ := strconv.Atoi(match[1])
+				month, _ := strconv.Atoi(match[2])
+				day, _ := strconv.Atoi(match[3])
+				date = time.Date(year, time.Month(month), day, 0, 0, 0, 0, config.TimeZone())
+			}
+			continue
+		}
+
+		// periodic and automated transactions have no date
+		if date.IsZero() {
+			continue
+		}
+
+		a, ok := parseLedgerAssertion(line)
+		if !ok {
+			continue
+		}
+		a.Date = date
+		a.FileName = fileName
+		a.Line = uint64(i + 1)
+		assertions = append(assertions, a)
+	}
+
+	return assertions, includes, nil
+}
+
+func parseLedgerAssertion(line string) (*assertion.Assertion, bool) {
+	line, _, _ = strings.Cut(line, ";")
+	line = strings.TrimSpace(line)
+	line = strings.TrimLeft(line, "*! ")
+
+	separator := strings.IndexByte(line, '\t')
+	if spaces := strings.Index(line, "  "); spaces != -1 && (separator == -1 || spaces < separator) {
+		separator = spaces
+	}
+	if separator == -1 {
+		return nil, false
+	}
+
+	account := strings.Trim(line[:separator], "()[]")
+	rest := lotRegex.ReplaceAllString(line[separator:], "")
+
+	amount, expected, found := strings.Cut(rest, "=")
+	// a balance assignment without an amount is not an assertion
+	if !found || strings.TrimSpace(amount) == "" {
+		return nil, false
+	}
+
+	// = and == check only the account, =* and ==* include the sub
+	// accounts
+	expected = strings.TrimPrefix(expected, "=")
+	subAccounts := strings.HasPrefix(expected, "*")
+	expected = strings.TrimPrefix(expected, "*")
+	expected, _, _ = strings.Cut(expected, "@")
+	expected = strings.TrimSpace(expected)
+
+	var commodity string
+	var quantity decimal.Decimal
+	var err error
+	if plainNumberRegex.MatchString(expected) {
+		commodity = config.DefaultCurrency()
+		quantity, err = decimal.NewFromString(strings.ReplaceAll(expected, ",", ""))
+	} else {
+		commodity, quantity, err = parseAmount(expected)
+	}
+	if err != nil {
+		return nil, false
+	}
+
+	return &assertion.Assertion{
+		Account:     strings.TrimSpace(account),
+		Commodity:   commodity,
+		Quantity:    quantity,
+		SubAccounts: subAccounts,
+	}, true
+}
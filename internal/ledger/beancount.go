@@ -0,0 +1,64 @@
+package ledger
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/assertion"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	beancountIncludeRegex = regexp.MustCompile(`^include\s+"([^"]+)"`)
+	beancountBalanceRegex = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})\s+balance\s+(\S+)\s+(-?[0-9.,]+)(?:\s*~\s*[0-9.,]+)?\s+([A-Z][A-Z0-9'._-]*)`)
+)
+
+// Assertions returns the balance directives of the journal. bean-query
+// doesn't expose the directives, so the files are read directly.
+func (Beancount) Assertions(journalPath string) ([]*assertion.Assertion, error) {
+	return readAssertions(journalPath, parseBeancountDirectives)
+}
+
+func parseBeancountDirectives(content string, fileName string) ([]*assertion.Assertion, []string, error) {
+	assertions := []*assertion.Assertion{}
+	includes := []string{}
+
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+
+		if match := beancountIncludeRegex.FindStringSubmatch(line); match != nil {
+			includes = append(includes, match[1])
+			continue
+		}
+
+		match := beancountBalanceRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		date, err := time.ParseInLocation("2006-01-02", match[1], config.TimeZone())
+		if err != nil {
+			return nil, nil, err
+		}
+
+		quantity, err := decimal.NewFromString(strings.ReplaceAll(match[3], ",", ""))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		assertions = append(assertions, &assertion.Assertion{
+			Date:        date,
+			Account:     match[2],
+			Commodity:   match[4],
+			Quantity:    quantity,
+			SubAccounts: true,
+			StartOfDay:  true,
+			FileName:    fileName,
+			Line:        uint64(i + 1),
+		})
+	}
+
+	return assertions, includes, nil
+}
@@ -44,11 +44,15 @@ type HLedgerCLI struct{}
 type Beancount struct{}
 
 func Cli() Ledger {
-	if config.GetConfig().LedgerCli == "hledger" {
-		return HLedgerCLI{}
-	}
+	return CliFor(config.GetJournalPath())
+}
 
-	if config.GetConfig().LedgerCli == "beancount" {
+// CliFor returns the ledger client that can read the journal.
+func CliFor(journalPath string) Ledger {
+	switch config.LedgerCliFor(journalPath) {
+	case "hledger":
+		return HLedgerCLI{}
+	case "beancount":
 		return Beancount{}
 	}
 
@@ -305,7 +309,7 @@ func (Beancount) Parse(journalPath string, prices []price.Price) ([]*posting.Pos
 		return nil, err
 	}
 
-	dir := filepath.Dir(config.GetJournalPath())
+	dir := filepath.Dir(journalPath)
 
 	locationRegex := regexp.MustCompile(`.*:(\d+):`)
 
@@ -344,7 +348,8 @@ func (Beancount) Parse(journalPath string, prices []price.Price) ([]*posting.Pos
 		}
 
 		var status string
-		if record[Status] == "*" {
+		// P marks the transactions generated by the pad directives
+		if record[Status] == "*" || record[Status] == "P" {
 			status = "cleared"
 		} else if record[Status] == "!" {
 			status = "pending"
@@ -596,7 +601,7 @@ func execLedgerCommand(journalPath string, flags []string) ([]*posting.Posting,
 		return nil, err
 	}
 
-	dir := filepath.Dir(config.GetJournalPath())
+	dir := filepath.Dir(journalPath)
 
 	for _, record := range records {
 		date, err := time.ParseInLocation("2006/01/02", record[Date], config.TimeZone())
@@ -787,7 +792,7 @@ func execHLedgerCommand(journalPath string, prices []price.Price, flags []string
 				continue
 			}
 
-			ps, err := buildHLedgerPostings(p, t, pricesTree, date)
+			ps, err := buildHLedgerPostings(journalPath, p, t, pricesTree, date)
 			if err != nil {
 				return nil, err
 			}
@@ -800,7 +805,7 @@ func execHLedgerCommand(journalPath string, prices []price.Price, flags []string
 	return postings, nil
 }
 
-func buildHLedgerPostings(p HLedgerPosting, t HLedgerTransaction, pricesTree map[string]*btree.BTree, date time.Time) ([]*posting.Posting, error) {
+func buildHLedgerPostings(journalPath string, p HLedgerPosting, t HLedgerTransaction, pricesTree map[string]*btree.BTree, date time.Time) ([]*posting.Posting, error) {
 	forecast := false
 	postings := []*posting.Posting{}
 
@@ -833,7 +838,7 @@ func buildHLedgerPostings(p HLedgerPosting, t HLedgerTransaction, pricesTree map
 		break
 	}
 
-	dir := filepath.Dir(config.GetJournalPath())
+	dir := filepath.Dir(journalPath)
 	var fileName string
 	var err error
 	if !forecast {
@@ -110,3 +110,50 @@ func TestParseAmount(t *testing.T) {
 	assert.Equal(t, "BTC", commodity)
 	assert.Equal(t, 1e-06, amount.InexactFloat64())
 }
+
+func TestParseBeancountDirectives(t *testing.T) {
+	assertions, includes, err := parseBeancountDirectives(`include "accounts/*.beancount"
+2023-01-01 open Assets:Checking USD
+2023-02-01 balance Assets:Checking  1,200.50 USD
+2023-03-01 balance Assets:Checking  -10 ~ 0.01 USD ; comment
+2023-03-01 * "Payee" "Narration"
+  Assets:Checking  10 USD
+`, "main.beancount")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"accounts/*.beancount"}, includes)
+	assert.Len(t, assertions, 2)
+	assert.Equal(t, "Assets:Checking", assertions[0].Account)
+	assert.Equal(t, "USD", assertions[0].Commodity)
+	assert.Equal(t, 1200.5, assertions[0].Quantity.InexactFloat64())
+	assert.Equal(t, uint64(3), assertions[0].Line)
+	assert.Equal(t, -10.0, assertions[1].Quantity.InexactFloat64())
+}
+
+func TestParseLedgerAssertions(t *testing.T) {
+	assertions, includes, err := parseLedgerAssertions(`include accounts.ledger
+2023/01/01 Salary
+    Assets:Checking    $1,000 = $1,500 ; pay day
+    Income:Salary
+
+~ Monthly
+    Assets:Checking    $10 = $10
+    Income:Salary
+
+2023-02-01 * Transfer
+    Assets:Savings      = 500
+    Assets:Checking    -100 INR =* 400 INR
+    Assets:Savings
+`, "main.ledger")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"accounts.ledger"}, includes)
+	assert.Len(t, assertions, 2)
+	assert.Equal(t, "Assets:Checking", assertions[0].Account)
+	assert.Equal(t, "$", assertions[0].Commodity)
+	assert.Equal(t, 1500.0, assertions[0].Quantity.InexactFloat64())
+	assert.Equal(t, "2023/01/01", assertions[0].Date.Format("2006/01/02"))
+	assert.False(t, assertions[0].SubAccounts)
+	assert.Equal(t, "INR", assertions[1].Commodity)
+	assert.Equal(t, 400.0, assertions[1].Quantity.InexactFloat64())
+	assert.True(t, assertions[1].SubAccounts)
+	assert.Equal(t, uint64(12), assertions[1].Line)
+}
@@ -0,0 +1,160 @@
+// Package locale formats the numbers and dates rendered by the server,
+// like the exports and the notifications, the same way as the
+// frontend. The rules are derived from the configured locale and can
+// be overridden in the configuration.
+package locale
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+type Grouping string
+
+const (
+	// 1,234,567
+	Thousand Grouping = "thousand"
+	// 12,34,567
+	Lakh Grouping = "lakh"
+)
+
+const DEFAULT_DATE_FORMAT = "YYYY-MM-DD"
+
+type Rules struct {
+	Locale           string   `json:"locale"`
+	DecimalSeparator string   `json:"decimal_separator"`
+	GroupSeparator   string   `json:"group_separator"`
+	Grouping         Grouping `json:"grouping"`
+	DateFormat       string   `json:"date_format"`
+	Currency         string   `json:"currency"`
+	Precision        int      `json:"precision"`
+}
+
+// Active returns the rules of the configured locale along with the
+// overrides in the configuration.
+func Active() Rules {
+	conf := config.GetConfig()
+	rules, err := ForLocale(conf.Locale)
+	if err != nil {
+		rules, _ = ForLocale("en-IN")
+	}
+
+	if conf.NumberFormat.DecimalSeparator != "" {
+		rules.DecimalSeparator = conf.NumberFormat.DecimalSeparator
+	}
+	if conf.NumberFormat.GroupSeparator != "" {
+		rules.GroupSeparator = conf.NumberFormat.GroupSeparator
+	}
+	if conf.NumberFormat.Grouping != "" {
+		rules.Grouping = Grouping(conf.NumberFormat.Grouping)
+	}
+	if conf.DateFormat != "" {
+		rules.DateFormat = conf.DateFormat
+	}
+	return rules
+}
+
+// ForLocale derives the separators and the grouping from the way the
+// locale formats a sample number.
+func ForLocale(locale string) (Rules, error) {
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return Rules{}, err
+	}
+
+	rules := Rules{
+		Locale:           locale,
+		DecimalSeparator: ".",
+		GroupSeparator:   ",",
+		Grouping:         Thousand,
+		DateFormat:       DEFAULT_DATE_FORMAT,
+		Currency:         config.DefaultCurrency(),
+		Precision:        config.GetConfig().DisplayPrecision,
+	}
+
+	sample := []rune(message.NewPrinter(tag).Sprint(number.Decimal(1234567.5, number.MinFractionDigits(1))))
+	separators := []string{}
+	digits := 0
+	for _, r := range sample {
+		if unicode.IsDigit(r) {
+			digits++
+			continue
+		}
+		separators = append(separators, string(r))
+		if len(separators) == 1 && digits == 2 {
+			rules.Grouping = Lakh
+		}
+	}
+
+	if len(separators) > 0 {
+		rules.DecimalSeparator = separators[len(separators)-1]
+		rules.GroupSeparator = ""
+		if len(separators) > 1 {
+			rules.GroupSeparator = separators[0]
+		}
+	}
+	return rules, nil
+}
+
+// Number formats the value with the given number of decimals.
+func (r Rules) Number(value decimal.Decimal, precision int) string {
+	return r.format(value.StringFixed(int32(precision)))
+}
+
+// Decimal formats the value with up to the given number of decimals,
+// the trailing zeros are dropped.
+func (r Rules) Decimal(value decimal.Decimal, precision int) string {
+	return r.format(value.Round(int32(precision)).String())
+}
+
+// Amount formats the value as per the display precision along with
+// the currency.
+func (r Rules) Amount(value decimal.Decimal) string {
+	return r.Number(value, r.Precision) + " " + r.Currency
+}
+
+func (r Rules) format(fixed string) string {
+	sign := ""
+	if strings.HasPrefix(fixed, "-") {
+		sign, fixed = "-", fixed[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(fixed, ".")
+	groups := []string{}
+	size := 3
+	for len(integer) > size {
+		groups = append([]string{integer[len(integer)-size:]}, groups...)
+		integer = integer[:len(integer)-size]
+		if r.Grouping == Lakh {
+			size = 2
+		}
+	}
+	groups = append([]string{integer}, groups...)
+
+	formatted := sign + strings.Join(groups, r.GroupSeparator)
+	if fraction != "" {
+		formatted += r.DecimalSeparator + fraction
+	}
+	return formatted
+}
+
+var dateTokens = strings.NewReplacer("YYYY", "2006", "YY", "06", "MMMM", "January", "MMM", "Jan", "MM", "01", "DD", "02", "D", "2")
+
+// Date formats the date as per the date format, which uses the YYYY,
+// YY, MMMM, MMM, MM, DD and D tokens.
+func (r Rules) Date(date time.Time) string {
+	return date.Format(DateLayout(r.DateFormat))
+}
+
+// DateLayout converts the date format to the layout used by the time
+// package.
+func DateLayout(format string) string {
+	return dateTokens.Replace(format)
+}
@@ -0,0 +1,53 @@
+package locale
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForLocale(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	rules, err := ForLocale("en-IN")
+	assert.NoError(t, err)
+	assert.Equal(t, Lakh, rules.Grouping)
+	assert.Equal(t, "12,34,567.50", rules.Number(decimal.NewFromFloat(1234567.5), 2))
+	assert.Equal(t, "-1,00,000", rules.Number(decimal.NewFromInt(-100000), 0))
+
+	rules, err = ForLocale("en-US")
+	assert.NoError(t, err)
+	assert.Equal(t, Thousand, rules.Grouping)
+	assert.Equal(t, "1,234,567.5", rules.Decimal(decimal.NewFromFloat(1234567.5), 4))
+
+	rules, err = ForLocale("de-DE")
+	assert.NoError(t, err)
+	assert.Equal(t, ",", rules.DecimalSeparator)
+	assert.Equal(t, ".", rules.GroupSeparator)
+	assert.Equal(t, "1.234,57", rules.Number(decimal.NewFromFloat(1234.567), 2))
+
+	_, err = ForLocale("invalid locale")
+	assert.Error(t, err)
+}
+
+func TestActive(t *testing.T) {
+	config.LoadConfig([]byte(`
+journal_path: main.ledger
+db_path: paisa.db
+locale: en-US
+default_currency: USD
+number_format:
+  group_separator: "'"
+date_format: DD/MM/YYYY
+`), "")
+
+	rules := Active()
+	assert.Equal(t, "1'234 USD", rules.Amount(decimal.NewFromFloat(1234.4)))
+	assert.Equal(t, "05/03/2024", rules.Date(time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)))
+
+	rules.DateFormat = "D MMM YY"
+	assert.Equal(t, "5 Mar 24", rules.Date(time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)))
+}
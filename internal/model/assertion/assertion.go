@@ -0,0 +1,56 @@
+package assertion
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Assertion is a balance assertion found in the journal. The balance
+// of the commodity in the account is expected to be Quantity. Balance
+// directives (beancount) are checked at the beginning of Date and
+// include the sub accounts, assertions on postings (ledger, hledger)
+// are checked right after the transaction.
+type Assertion struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	Date        time.Time       `json:"date"`
+	Account     string          `json:"account"`
+	Commodity   string          `json:"commodity"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	SubAccounts bool            `json:"sub_accounts"`
+	StartOfDay  bool            `json:"start_of_day"`
+	FileName    string          `json:"file_name"`
+	Line        uint64          `json:"line"`
+}
+
+func All(db *gorm.DB) []Assertion {
+	var assertions []Assertion
+	result := db.Order("date ASC, account ASC").Find(&assertions)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return assertions
+}
+
+func ReplaceAll(db *gorm.DB, assertions []*Assertion) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM assertions").Error
+		if err != nil {
+			return err
+		}
+		for _, a := range assertions {
+			err := tx.Create(a).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Entry records a single mutating api call. Before is the state the
+// call replaced, when known, After is the payload of the request and
+// Response is what the server answered.
+type Entry struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	User      string    `gorm:"index" json:"user"`
+	Method    string    `json:"method"`
+	Path      string    `gorm:"index" json:"path"`
+	Profile   string    `json:"profile"`
+	Status    int       `json:"status"`
+	Before    string    `json:"before"`
+	After     string    `json:"after"`
+	Response  string    `json:"response"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+type Filter struct {
+	User   string
+	Path   string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+func Record(db *gorm.DB, entry *Entry) error {
+	return db.Create(entry).Error
+}
+
+// Query returns the entries matching the filter, latest first, along
+// with the total number of matching entries. Path matches the prefix.
+func Query(db *gorm.DB, filter Filter) ([]Entry, int64, error) {
+	scope := db.Model(&Entry{})
+	if filter.User != "" {
+		scope = scope.Where("user = ?", filter.User)
+	}
+	if filter.Path != "" {
+		scope = scope.Where("path LIKE ?", filter.Path+"%")
+	}
+	if filter.From != nil {
+		scope = scope.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		scope = scope.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	err := scope.Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := []Entry{}
+	err = scope.Order("created_at DESC, id DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&entries).Error
+	return entries, total, err
+}
@@ -0,0 +1,172 @@
+package bankconnection
+
+import (
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/encryption"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type Status string
+
+const (
+	Pending Status = "pending"
+	Linked  Status = "linked"
+	Expired Status = "expired"
+)
+
+// Connection is the consent given to a bank account data provider to
+// read the accounts held with an institution. Reference is the
+// identifier shared with the provider while linking, ExternalID is the
+// identifier of the consent at the provider. Secret holds the
+// credentials the provider handed out for the connection, if any,
+// sealed with the journal encryption key. Cursor is the position of
+// the incremental sync for the providers that support one.
+type Connection struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Provider    string    `gorm:"index" json:"provider"`
+	Reference   string    `gorm:"uniqueIndex" json:"reference"`
+	ExternalID  string    `json:"external_id"`
+	Institution string    `json:"institution"`
+	Status      Status    `json:"status"`
+	Secret      string    `json:"-"`
+	Cursor      string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Account is an account of a connection, along with the balance as of
+// the last sync.
+type Account struct {
+	ID           uint            `gorm:"primaryKey" json:"id"`
+	ConnectionID uint            `gorm:"index" json:"connection_id"`
+	ExternalID   string          `gorm:"index" json:"external_id"`
+	Name         string          `json:"name"`
+	Number       string          `json:"number"`
+	Currency     string          `json:"currency"`
+	Balance      decimal.Decimal `json:"balance"`
+	BalanceDate  time.Time       `json:"balance_date"`
+	SyncedAt     time.Time       `json:"synced_at"`
+}
+
+// SetSecret seals the secret, it's kept as is when the encryption is
+// not enabled.
+func (c *Connection) SetSecret(secret string) error {
+	sealed, err := encryption.Seal([]byte(secret))
+	if err != nil {
+		return err
+	}
+	c.Secret = base64.StdEncoding.EncodeToString(sealed)
+	return nil
+}
+
+func (c Connection) OpenSecret() (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(c.Secret)
+	if err != nil {
+		return "", err
+	}
+	secret, err := encryption.Unseal(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func Create(db *gorm.DB, c *Connection) {
+	result := db.Create(c)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
+
+func Save(db *gorm.DB, c *Connection) {
+	result := db.Save(c)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
+
+func ByReference(db *gorm.DB, provider string, reference string) (Connection, bool) {
+	var c Connection
+	result := db.Where("provider = ? AND reference = ?", provider, reference).First(&c)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return c, false
+		}
+		log.Fatal(result.Error)
+	}
+	return c, true
+}
+
+func All(db *gorm.DB) []Connection {
+	var cs []Connection
+	result := db.Order("id ASC").Find(&cs)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return cs
+}
+
+func ByStatus(db *gorm.DB, provider string, status Status) []Connection {
+	var cs []Connection
+	result := db.Where("provider = ? AND status = ?", provider, status).Order("id ASC").Find(&cs)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return cs
+}
+
+// Delete removes the connection along with its accounts.
+func Delete(db *gorm.DB, id uint) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("connection_id = ?", id).Delete(&Account{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&Connection{}, id).Error
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Accounts(db *gorm.DB, connectionID uint) []Account {
+	var as []Account
+	result := db.Where("connection_id = ?", connectionID).Order("id ASC").Find(&as)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return as
+}
+
+// UpsertAccount creates the account or updates the details of the
+// account with the same external id in the connection.
+func UpsertAccount(db *gorm.DB, a *Account) {
+	var existing Account
+	result := db.Where("connection_id = ? AND external_id = ?", a.ConnectionID, a.ExternalID).First(&existing)
+	if result.Error != nil && !errors.Is(result.Error, gorm.ErrRecordNotFound) {
+		log.Fatal(result.Error)
+	}
+	if result.Error == nil {
+		a.ID = existing.ID
+		a.Balance = existing.Balance
+		a.BalanceDate = existing.BalanceDate
+		a.SyncedAt = existing.SyncedAt
+	}
+
+	result = db.Save(a)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
+
+func SaveAccount(db *gorm.DB, a *Account) {
+	result := db.Save(a)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
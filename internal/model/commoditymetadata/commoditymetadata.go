@@ -0,0 +1,75 @@
+package commoditymetadata
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Metadata is the details of a commodity that are not available from
+// the journal or the price providers. ExpenseRatio is the annual
+// percentage charged by the fund.
+type Metadata struct {
+	ID           uint            `gorm:"primaryKey" json:"id"`
+	Commodity    string          `gorm:"uniqueIndex" json:"commodity"`
+	ISIN         string          `json:"isin"`
+	ExpenseRatio decimal.Decimal `json:"expense_ratio"`
+	Category     string          `json:"category"`
+	Risk         string          `json:"risk"`
+	Notes        string          `json:"notes"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// Save creates the metadata or replaces the existing one of the same
+// commodity.
+func Save(db *gorm.DB, m *Metadata) {
+	existing, found := ByCommodity(db, m.Commodity)
+	if found {
+		m.ID = existing.ID
+	}
+
+	result := db.Save(m)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
+
+func Delete(db *gorm.DB, commodity string) {
+	result := db.Where("commodity = ?", commodity).Delete(&Metadata{})
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
+
+func ByCommodity(db *gorm.DB, commodity string) (Metadata, bool) {
+	var m Metadata
+	result := db.Where("commodity = ?", commodity).First(&m)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return m, false
+		}
+		log.Fatal(result.Error)
+	}
+	return m, true
+}
+
+func All(db *gorm.DB) []Metadata {
+	var ms []Metadata
+	result := db.Order("commodity ASC").Find(&ms)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return ms
+}
+
+// ByCommodities returns the metadata keyed by the commodity name.
+func ByCommodities(db *gorm.DB) map[string]Metadata {
+	byCommodity := make(map[string]Metadata)
+	for _, m := range All(db) {
+		byCommodity[m.Commodity] = m
+	}
+	return byCommodity
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/dailybalance"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"gorm.io/gorm"
+)
+
+// SyncDailyBalances materializes the running totals of every asset,
+// capital gains and liability account, so the networth timelines
+// don't have to walk all the postings on every request. The future
+// postings are included, they are skipped while reading till the
+// date arrives.
+func SyncDailyBalances(db *gorm.DB) {
+	// the interest classification depends on the postings just synced
+	service.ClearInterestCache()
+	service.ClearClassificationCache()
+
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%", "Liabilities:%").All()
+
+	type key struct {
+		account   string
+		commodity string
+	}
+	running := make(map[key]*dailybalance.DailyBalance)
+	touched := []key{}
+	balances := []dailybalance.DailyBalance{}
+
+	for i, p := range postings {
+		k := key{account: p.Account, commodity: p.Commodity}
+		b, ok := running[k]
+		if !ok {
+			b = &dailybalance.DailyBalance{Account: p.Account, Commodity: p.Commodity}
+			running[k] = b
+		}
+		if !b.Date.Equal(p.Date) {
+			touched = append(touched, k)
+		}
+		b.Date = p.Date
+
+		isInterest := service.IsInterest(db, p)
+		if p.Amount.IsPositive() && !isInterest {
+			b.Investment = b.Investment.Add(p.Amount)
+		}
+		if p.Amount.IsNegative() && !isInterest {
+			b.Withdrawal = b.Withdrawal.Add(p.Amount.Neg())
+		}
+		if !service.IsCapitalGains(p) {
+			b.Amount = b.Amount.Add(p.Amount)
+			b.Quantity = b.Quantity.Add(p.Quantity)
+		}
+
+		if i+1 == len(postings) || !postings[i+1].Date.Equal(p.Date) {
+			for _, k := range touched {
+				balances = append(balances, *running[k])
+			}
+			touched = touched[:0]
+		}
+	}
+
+	dailybalance.ReplaceAll(db, balances)
+}
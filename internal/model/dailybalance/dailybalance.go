@@ -0,0 +1,55 @@
+package dailybalance
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// DailyBalance holds the running totals of an account in a commodity
+// as of the end of the date. There is a row only for the dates the
+// account has postings, the totals carry over to the dates in
+// between. Amount is the sum of the posting amounts, the market value
+// is computed from Quantity using the price of the date the balance is
+// looked at.
+type DailyBalance struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	Date       time.Time       `gorm:"index" json:"date"`
+	Account    string          `gorm:"index" json:"account"`
+	Commodity  string          `json:"commodity"`
+	Investment decimal.Decimal `json:"investment"`
+	Withdrawal decimal.Decimal `json:"withdrawal"`
+	Amount     decimal.Decimal `json:"amount"`
+	Quantity   decimal.Decimal `json:"quantity"`
+}
+
+func ReplaceAll(db *gorm.DB, balances []DailyBalance) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM daily_balances").Error
+		if err != nil {
+			return err
+		}
+
+		if len(balances) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(balances, 500).Error
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ByAccounts returns the balances of the accounts till the given
+// date, oldest first.
+func ByAccounts(db *gorm.DB, accounts []string, until time.Time) []DailyBalance {
+	balances := []DailyBalance{}
+	result := db.Where("account IN ? AND date < ?", accounts, until).Order("date ASC, id ASC").Find(&balances)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return balances
+}
@@ -0,0 +1,82 @@
+package draft
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type Status string
+
+const (
+	Pending  Status = "pending"
+	Accepted Status = "accepted"
+	Rejected Status = "rejected"
+)
+
+// Draft is a transaction picked up from an external source like a
+// bank alert email, waiting in the import review queue to be accepted
+// into the journal. ExternalID identifies the item in the source, so
+// the same item is never imported twice irrespective of the status.
+type Draft struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	Source     string          `gorm:"uniqueIndex:idx_draft_source_external_id" json:"source"`
+	ExternalID string          `gorm:"uniqueIndex:idx_draft_source_external_id" json:"external_id"`
+	Date       time.Time       `json:"date"`
+	Payee      string          `json:"payee"`
+	Account    string          `json:"account"`
+	Amount     decimal.Decimal `json:"amount"`
+	Content    string          `json:"content"`
+	// text the draft was parsed from, shown along with the draft for
+	// review
+	Raw       string    `json:"raw"`
+	Status    Status    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func Create(db *gorm.DB, d *Draft) {
+	result := db.Create(d)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
+
+func Exists(db *gorm.DB, source string, externalID string) bool {
+	var count int64
+	result := db.Model(&Draft{}).Where("source = ? AND external_id = ?", source, externalID).Count(&count)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return count > 0
+}
+
+func Get(db *gorm.DB, id uint) (Draft, bool) {
+	var d Draft
+	result := db.First(&d, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return d, false
+		}
+		log.Fatal(result.Error)
+	}
+	return d, true
+}
+
+func ByStatus(db *gorm.DB, status Status, limit int) []Draft {
+	var ds []Draft
+	result := db.Where("status = ?", status).Order("date DESC, id DESC").Limit(limit).Find(&ds)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return ds
+}
+
+func UpdateStatus(db *gorm.DB, id uint, status Status) {
+	result := db.Model(&Draft{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
@@ -0,0 +1,111 @@
+package invoice
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type Item struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	InvoiceID   uint            `json:"invoice_id"`
+	Description string          `json:"description"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	Rate        decimal.Decimal `json:"rate"`
+}
+
+func (i Item) Amount() decimal.Decimal {
+	return i.Quantity.Mul(i.Rate)
+}
+
+// Invoice is the bill raised to a client. The amount is booked in the
+// journal under Account, the receivable account of the client, and the
+// payments received are booked against the same account. Both the
+// transactions carry the invoice number as a tag.
+type Invoice struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	Number        string    `gorm:"uniqueIndex" json:"number"`
+	Client        string    `json:"client"`
+	Date          time.Time `json:"date"`
+	DueDate       time.Time `json:"due_date"`
+	Account       string    `json:"account"`
+	IncomeAccount string    `json:"income_account"`
+	Notes         string    `json:"notes"`
+	Items         []Item    `gorm:"constraint:OnDelete:CASCADE" json:"items"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func (i Invoice) Total() decimal.Decimal {
+	total := decimal.Zero
+	for _, item := range i.Items {
+		total = total.Add(item.Amount())
+	}
+	return total
+}
+
+func Create(db *gorm.DB, i *Invoice) {
+	result := db.Create(i)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
+
+func Get(db *gorm.DB, number string) (Invoice, bool) {
+	var i Invoice
+	result := db.Preload("Items").Where("number = ?", number).First(&i)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return i, false
+		}
+		log.Fatal(result.Error)
+	}
+	return i, true
+}
+
+func All(db *gorm.DB) []Invoice {
+	var is []Invoice
+	result := db.Preload("Items").Order("date DESC, id DESC").Find(&is)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return is
+}
+
+func Delete(db *gorm.DB, number string) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		i, found := Get(tx, number)
+		if !found {
+			return nil
+		}
+		err := tx.Where("invoice_id = ?", i.ID).Delete(&Item{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(&i).Error
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// NextNumber returns the number of the next invoice, INV-0001,
+// INV-0002 etc.
+func NextNumber(db *gorm.DB) string {
+	var count int64
+	result := db.Model(&Invoice{}).Count(&count)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+
+	for n := count + 1; ; n++ {
+		number := fmt.Sprintf("INV-%04d", n)
+		if _, found := Get(db, number); !found {
+			return number
+		}
+	}
+}
@@ -1,95 +1,372 @@
 package model
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/ananthakumaran/paisa/internal/accrual"
 	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/corporateaction"
+	"github.com/ananthakumaran/paisa/internal/depreciation"
+	"github.com/ananthakumaran/paisa/internal/grant"
+	"github.com/ananthakumaran/paisa/internal/journal"
 	"github.com/ananthakumaran/paisa/internal/ledger"
+	"github.com/ananthakumaran/paisa/internal/model/assertion"
+	"github.com/ananthakumaran/paisa/internal/model/audit"
+	"github.com/ananthakumaran/paisa/internal/model/bankconnection"
 	"github.com/ananthakumaran/paisa/internal/model/cache"
 	"github.com/ananthakumaran/paisa/internal/model/cii"
 	"github.com/ananthakumaran/paisa/internal/model/commodity"
+	"github.com/ananthakumaran/paisa/internal/model/commoditymetadata"
+	"github.com/ananthakumaran/paisa/internal/model/dailybalance"
+	"github.com/ananthakumaran/paisa/internal/model/draft"
+	"github.com/ananthakumaran/paisa/internal/model/invoice"
 	mutualfundModel "github.com/ananthakumaran/paisa/internal/model/mutualfund/scheme"
 	npsModel "github.com/ananthakumaran/paisa/internal/model/nps/scheme"
 	"github.com/ananthakumaran/paisa/internal/model/portfolio"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/model/price"
+	"github.com/ananthakumaran/paisa/internal/model/reconciliation"
+	"github.com/ananthakumaran/paisa/internal/model/scheduledtransaction"
+	"github.com/ananthakumaran/paisa/internal/model/sourcefile"
 	"github.com/ananthakumaran/paisa/internal/scraper"
+	"github.com/ananthakumaran/paisa/internal/scraper/appreciation"
 	"github.com/ananthakumaran/paisa/internal/scraper/india"
 	"github.com/ananthakumaran/paisa/internal/scraper/mutualfund"
+	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// Models returns the tables managed by Paisa.
+func Models() []any {
+	return []any{
+		&npsModel.Scheme{},
+		&mutualfundModel.Scheme{},
+		&posting.Posting{},
+		&price.Price{},
+		&portfolio.Portfolio{},
+		&cii.CII{},
+		&cache.Cache{},
+		&sourcefile.SourceFile{},
+		&scheduledtransaction.ScheduledTransaction{},
+		&assertion.Assertion{},
+		&reconciliation.Statement{},
+		&audit.Entry{},
+		&dailybalance.DailyBalance{},
+		&draft.Draft{},
+		&bankconnection.Connection{},
+		&bankconnection.Account{},
+		&commoditymetadata.Metadata{},
+		&invoice.Invoice{},
+		&invoice.Item{},
+	}
+}
+
 func AutoMigrate(db *gorm.DB) {
-	db.AutoMigrate(&npsModel.Scheme{})
-	db.AutoMigrate(&mutualfundModel.Scheme{})
-	db.AutoMigrate(&posting.Posting{})
-	db.AutoMigrate(&price.Price{})
-	db.AutoMigrate(&portfolio.Portfolio{})
-	db.AutoMigrate(&price.Price{})
-	db.AutoMigrate(&cii.CII{})
-	db.AutoMigrate(&cache.Cache{})
+	for _, m := range Models() {
+		db.AutoMigrate(m)
+	}
 }
 
+// SyncJournal parses the journal and rebuilds all the postings.
 func SyncJournal(db *gorm.DB) (string, error) {
+	_, message, err := syncJournal(db, true)
+	return message, err
+}
+
+// SyncJournalChanges parses the journal only if any of the journal
+// files changed since the last sync and replaces the postings of the
+// files whose postings are different. The whole journal is parsed even
+// if a single file changed, the files depend on each other via the
+// includes, declarations and assertions. Returns whether anything was
+// imported.
+func SyncJournalChanges(db *gorm.DB) (bool, string, error) {
+	return syncJournal(db, false)
+}
+
+func syncJournal(db *gorm.DB, full bool) (bool, string, error) {
 	AutoMigrate(db)
-	log.Info("Syncing transactions from journal")
+	journalPath := journal.JournalPath(db)
+
+	previous := lo.KeyBy(sourcefile.All(db), func(f sourcefile.SourceFile) string { return f.Name })
+	contentHashes, fileHashes := journalContentHashes(db, journalPath)
+	if !full && journalUnchanged(previous, contentHashes) {
+		log.Info("Journal unchanged, skipping sync")
+		return false, "", nil
+	}
+
+	if full || len(previous) == 0 {
+		log.Info("Syncing transactions from journal")
+	} else {
+		// the ledger client can't parse a file on its own, it
+		// depends on the rest of the journal
+		log.Info("Syncing transactions from journal, changed files: ", strings.Join(changedFiles(previous, contentHashes), ", "))
+	}
 
-	errors, _, err := ledger.Cli().ValidateFile(config.GetJournalPath())
+	cli := ledger.CliFor(journalPath)
+	errors, _, err := cli.ValidateFile(journalPath)
 	if err != nil {
 
 		if len(errors) == 0 {
-			return err.Error(), err
+			return false, err.Error(), err
 		}
 
 		var message string
 		for _, error := range errors {
 			message += error.Message + "\n\n"
 		}
-		return strings.TrimRight(message, "\n"), err
+		return false, strings.TrimRight(message, "\n"), err
 	}
 
-	prices, err := ledger.Cli().Prices(config.GetJournalPath())
+	prices, err := cli.Prices(journalPath)
 	if err != nil {
-		return err.Error(), err
+		return false, err.Error(), err
 	}
 
-	price.UpsertAllByType(db, config.Unknown, prices)
+	postings, err := cli.Parse(journalPath, prices)
+	if err != nil {
+		return false, err.Error(), err
+	}
+
+	corporateaction.AdjustPostings(postings, config.GetConfig().CorporateActions)
+	prices = corporateaction.AdjustPrices(prices, config.GetConfig().CorporateActions)
+
+	postings = append(postings, accrual.Generate(postings, config.GetConfig().InterestAccruals, utils.EndOfToday())...)
+	postings = append(postings, grant.ForecastPostings(config.GetConfig().Grants, utils.EndOfToday(), func(commodity string) decimal.Decimal {
+		p, _ := price.Latest(db, commodity)
+		return p.Value
+	})...)
+	postings = append(postings, depreciation.Generate(config.GetConfig().FixedAssets, utils.EndOfToday())...)
+
+	generatedPrices := appreciation.Prices(commodity.All(), prices, postings, utils.EndOfToday())
+	price.UpsertAllByType(db, config.Unknown, append(prices, generatedPrices...))
+
+	if parser, ok := cli.(ledger.AssertionParser); ok {
+		assertions, err := parser.Assertions(journalPath)
+		if err != nil {
+			return false, err.Error(), err
+		}
+		assertion.ReplaceAll(db, assertions)
+	}
+
+	importPostings(db, postings, previous, contentHashes, fileHashes, full)
+
+	SyncDailyBalances(db)
+	return true, "", nil
+}
 
-	postings, err := ledger.Cli().Parse(config.GetJournalPath(), prices)
+// importPostings replaces the postings of the files whose postings
+// are different from the last sync. The postings of the other files
+// are left untouched.
+func importPostings(db *gorm.DB, postings []*posting.Posting, previous map[string]sourcefile.SourceFile, contentHashes map[string]string, fileHashes map[string]string, full bool) {
+	byFileName := lo.GroupBy(postings, func(p *posting.Posting) string { return p.FileName })
+	postingsHashes := lo.MapValues(byFileName, func(ps []*posting.Posting, _ string) string { return postingsHash(ps) })
+
+	if full || len(previous) == 0 {
+		posting.UpsertAll(db, postings)
+	} else {
+		var existing []string
+		db.Model(&posting.Posting{}).Distinct().Pluck("FileName", &existing)
+		changed := lo.Filter(lo.Uniq(append(existing, lo.Keys(byFileName)...)), func(name string, _ int) bool {
+			hash, ok := postingsHashes[name]
+			return !ok || previous[name].PostingsHash != hash
+		})
+
+		if len(changed) > 0 {
+			log.Info("Replacing postings of ", strings.Join(changed, ", "))
+			posting.ReplaceByFileName(db, changed, lo.Flatten(lo.Map(changed, func(name string, _ int) []*posting.Posting {
+				return byFileName[name]
+			})))
+		}
+	}
+
+	var files []sourcefile.SourceFile
+	for _, name := range lo.Uniq(append(lo.Keys(contentHashes), lo.Keys(postingsHashes)...)) {
+		files = append(files, sourcefile.SourceFile{Name: name, ContentHash: contentHashes[name], FileHash: fileHashes[name], PostingsHash: postingsHashes[name]})
+	}
+	sourcefile.ReplaceAll(db, files)
+}
+
+func journalUnchanged(previous map[string]sourcefile.SourceFile, contentHashes map[string]string) bool {
+	return len(previous) > 0 && len(changedFiles(previous, contentHashes)) == 0
+}
+
+// changedFiles returns the journal files created, changed or removed
+// since the last sync.
+func changedFiles(previous map[string]sourcefile.SourceFile, contentHashes map[string]string) []string {
+	changed := []string{}
+	for name, hash := range contentHashes {
+		// the file hash is missing on the files synced before it was
+		// tracked
+		if previous[name].ContentHash != hash || previous[name].FileHash == "" {
+			changed = append(changed, name)
+		}
+	}
+
+	for name, file := range previous {
+		if _, ok := contentHashes[name]; file.ContentHash != "" && !ok {
+			changed = append(changed, name)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
+
+// journalContentHashes hashes the content of all the journal files
+// along with the configuration and the current date, as the imported
+// postings depend on both. The hashes of the content alone are
+// returned as well.
+func journalContentHashes(db *gorm.DB, journalPath string) (map[string]string, map[string]string) {
+	configJson, err := json.Marshal(config.GetConfig())
 	if err != nil {
-		return err.Error(), err
+		log.Fatal(err)
 	}
-	posting.UpsertAll(db, postings)
+	fingerprint := utils.Sha256(string(configJson)) + utils.Now().Format("2006-01-02")
 
-	return "", nil
+	dir := filepath.Dir(journalPath)
+	paths, _ := config.GlobFiles(dir, filepath.Ext(journalPath))
+	var fileNames []string
+	db.Model(&posting.Posting{}).Distinct().Pluck("FileName", &fileNames)
+	for _, name := range fileNames {
+		if name != "" {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+
+	hashes := make(map[string]string)
+	fileHashes := make(map[string]string)
+	for _, path := range lo.Uniq(append(paths, journalPath)) {
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			continue
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		hashes[name] = utils.Sha256(fingerprint + "\n" + string(content))
+		fileHashes[name] = utils.Sha256(string(content))
+	}
+	return hashes, fileHashes
+}
+
+func postingsHash(postings []*posting.Posting) string {
+	var b strings.Builder
+	for _, p := range postings {
+		encoded, err := json.Marshal(p)
+		if err != nil {
+			log.Fatal(err)
+		}
+		b.Write(encoded)
+		b.WriteString("\n")
+	}
+	return utils.Sha256(b.String())
+}
+
+type PriceProgress struct {
+	Commodity string `json:"commodity"`
+	Provider  string `json:"provider"`
+	Status    string `json:"status"`
+	Count     int    `json:"count"`
+	Error     string `json:"error,omitempty"`
+	Done      int    `json:"done"`
+	Total     int    `json:"total"`
 }
 
+const (
+	PriceFetching = "fetching"
+	PriceStored   = "stored"
+	PriceFailed   = "failed"
+)
+
 func SyncCommodities(db *gorm.DB) error {
+	return SyncCommoditiesWithProgress(db, func(PriceProgress) {})
+}
+
+// SyncCommoditiesWithProgress fetches the price history of all the
+// commodities, reporting the progress of each commodity as it goes.
+// Prices are fetched by a bounded pool of workers while the results
+// are stored one at a time, sqlite doesn't cope well with concurrent
+// writers.
+func SyncCommoditiesWithProgress(db *gorm.DB, progress func(PriceProgress)) error {
 	AutoMigrate(db)
 	log.Info("Fetching commodities price history")
 	commodities := lo.Shuffle(commodity.All())
+	total := len(commodities)
 
-	var errors []error
-	for _, commodity := range commodities {
-		name := commodity.Name
-		log.Info("Fetching commodity ", name)
-		code := commodity.Price.Code
-		var prices []*price.Price
-		var err error
+	type result struct {
+		commodity config.Commodity
+		provider  config.Price
+		prices    []*price.Price
+		err       error
+	}
 
-		provider := scraper.GetProviderByCode(commodity.Price.Provider)
-		prices, err = provider.GetPrices(code, name)
+	var progressMu sync.Mutex
+	done := 0
+	report := func(p PriceProgress) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		if p.Status != PriceFetching {
+			done++
+		}
+		p.Done = done
+		p.Total = total
+		progress(p)
+	}
 
-		if err != nil {
-			log.Error(err)
-			errors = append(errors, fmt.Errorf("Failed to fetch price for %s: %w", name, err))
+	jobs := make(chan config.Commodity)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < lo.Clamp(config.GetConfig().PriceFetchConcurrency, 1, total); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for commodity := range jobs {
+				log.Info("Fetching commodity ", commodity.Name)
+				report(PriceProgress{Commodity: commodity.Name, Provider: commodity.Price.Provider, Status: PriceFetching})
+				provider, prices, err := fetchPrices(commodity)
+				results <- result{commodity: commodity, provider: provider, prices: prices, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		for _, commodity := range commodities {
+			jobs <- commodity
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var errors []error
+	for r := range results {
+		name := r.commodity.Name
+		if r.err != nil {
+			log.Error(r.err)
+			report(PriceProgress{Commodity: name, Provider: r.commodity.Price.Provider, Status: PriceFailed, Error: r.err.Error()})
+			errors = append(errors, fmt.Errorf("Failed to fetch price for %s: %w", name, r.err))
 			continue
 		}
 
-		price.UpsertAllByTypeNameAndID(db, commodity.Type, name, code, prices)
+		prices := r.prices
+		if !scraper.SplitAdjusted(r.provider.Provider) {
+			prices = corporateaction.AdjustFetchedPrices(name, prices, config.GetConfig().CorporateActions)
+		}
+		price.UpsertAllByTypeNameAndID(db, r.commodity.Type, name, r.provider.Code, prices)
+		report(PriceProgress{Commodity: name, Provider: r.provider.Provider, Status: PriceStored, Count: len(r.prices)})
 	}
 
 	if len(errors) > 0 {
@@ -102,6 +379,59 @@ func SyncCommodities(db *gorm.DB) error {
 	return nil
 }
 
+// fetchPrices goes through the price provider of the commodity and
+// its fallbacks till one of them returns a price history that's not
+// stale. The later providers only fill in the prices after the last
+// price of the earlier ones, each price records the provider it came
+// from. Returns the provider that supplied the latest price.
+func fetchPrices(commodity config.Commodity) (config.Price, []*price.Price, error) {
+	staleDays := config.GetConfig().PriceStaleDays
+	var prices []*price.Price
+	var used config.Price
+	var errs []error
+	for _, p := range append([]config.Price{commodity.Price}, commodity.Fallbacks...) {
+		provider := scraper.GetProviderByCode(p.Provider)
+		scraper.Throttle(p.Provider)
+		fetched, err := provider.GetPrices(p.Code, commodity.Name)
+		if err != nil {
+			log.Warnf("Failed to fetch price for %s from %s: %s", commodity.Name, p.Provider, err)
+			errs = append(errs, err)
+			continue
+		}
+
+		latest := latestPriceDate(prices)
+		for _, f := range fetched {
+			if f.Date.After(latest) {
+				f.Provider = p.Provider
+				prices = append(prices, f)
+			}
+		}
+		if latestPriceDate(prices).After(latest) || used.Provider == "" {
+			used = p
+		}
+
+		if staleDays == 0 || !latestPriceDate(prices).Before(utils.Now().AddDate(0, 0, -staleDays)) {
+			break
+		}
+		log.Warnf("Prices of %s from %s are stale", commodity.Name, p.Provider)
+	}
+
+	if used.Provider == "" {
+		return commodity.Price, nil, errs[0]
+	}
+	return used, prices, nil
+}
+
+func latestPriceDate(prices []*price.Price) time.Time {
+	latest := time.Time{}
+	for _, p := range prices {
+		if p.Date.After(latest) {
+			latest = p.Date
+		}
+	}
+	return latest
+}
+
 func SyncCII(db *gorm.DB) error {
 	AutoMigrate(db)
 	log.Info("Fetching taxation related info")
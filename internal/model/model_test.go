@@ -0,0 +1,83 @@
+package model
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/model/sourcefile"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func parsedPostings(rent int64) []*posting.Posting {
+	date := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	postings := []*posting.Posting{}
+	for _, p := range []struct {
+		file    string
+		account string
+		amount  int64
+	}{
+		{"main.ledger", "Assets:Checking", 1000},
+		{"main.ledger", "Income:Salary", -1000},
+		{"2023.ledger", "Expenses:Rent", rent},
+		{"2023.ledger", "Assets:Checking", -rent},
+	} {
+		postings = append(postings, &posting.Posting{
+			Date:      date,
+			Account:   p.account,
+			Commodity: "INR",
+			Quantity:  decimal.NewFromInt(p.amount),
+			Amount:    decimal.NewFromInt(p.amount),
+			FileName:  p.file,
+		})
+	}
+	return postings
+}
+
+func postingsOf(db *gorm.DB, fileName string) []posting.Posting {
+	var postings []posting.Posting
+	db.Where("file_name = ?", fileName).Order("id").Find(&postings)
+	return postings
+}
+
+func TestImportPostings(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	db, err := utils.OpenDBAt(filepath.Join(t.TempDir(), "paisa.db"))
+	require.NoError(t, err)
+	AutoMigrate(db)
+
+	contentHashes := map[string]string{"main.ledger": "main-1", "2023.ledger": "2023-1"}
+	fileHashes := map[string]string{"main.ledger": "main", "2023.ledger": "2023"}
+	importPostings(db, parsedPostings(300), map[string]sourcefile.SourceFile{}, contentHashes, fileHashes, false)
+	main := postingsOf(db, "main.ledger")
+	require.Len(t, main, 2)
+
+	previous := lo.KeyBy(sourcefile.All(db), func(f sourcefile.SourceFile) string { return f.Name })
+	assert.True(t, journalUnchanged(previous, contentHashes))
+
+	contentHashes = map[string]string{"main.ledger": "main-1", "2023.ledger": "2023-2"}
+	assert.False(t, journalUnchanged(previous, contentHashes))
+	assert.Equal(t, []string{"2023.ledger"}, changedFiles(previous, contentHashes))
+	importPostings(db, parsedPostings(400), previous, contentHashes, fileHashes, false)
+
+	// the postings of the unchanged file are not recreated
+	assert.Equal(t, lo.Map(main, func(p posting.Posting, _ int) uint { return p.ID }),
+		lo.Map(postingsOf(db, "main.ledger"), func(p posting.Posting, _ int) uint { return p.ID }))
+
+	rent := postingsOf(db, "2023.ledger")
+	require.Len(t, rent, 2)
+	assert.True(t, decimal.NewFromInt(400).Equal(rent[0].Amount))
+
+	current := lo.KeyBy(sourcefile.All(db), func(f sourcefile.SourceFile) string { return f.Name })
+	assert.Equal(t, previous["main.ledger"].PostingsHash, current["main.ledger"].PostingsHash)
+	assert.NotEqual(t, previous["2023.ledger"].PostingsHash, current["2023.ledger"].PostingsHash)
+	assert.True(t, journalUnchanged(current, contentHashes))
+}
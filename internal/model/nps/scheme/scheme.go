@@ -55,8 +55,10 @@ func GetPFMCompletions(db *gorm.DB) []price.AutoCompleteItem {
 
 func GetSchemeNameCompletions(db *gorm.DB, pfm string) []price.AutoCompleteItem {
 	var schemes []Scheme
-	db.Model(&Scheme{}).Where("pfm_name = ?", pfm).Find(&schemes)
+	db.Model(&Scheme{}).Where("pfm_name = ?", pfm).Order("scheme_id").Find(&schemes)
+	// the scheme code is part of the label, so the scheme could be
+	// searched by the code printed on the statement as well
 	return lo.Map(schemes, func(scheme Scheme, _ int) price.AutoCompleteItem {
-		return price.AutoCompleteItem{Label: scheme.SchemeName, ID: scheme.SchemeID}
+		return price.AutoCompleteItem{Label: scheme.SchemeID + " - " + scheme.SchemeName, ID: scheme.SchemeID}
 	})
 }
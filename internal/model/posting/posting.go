@@ -1,9 +1,11 @@
 package posting
 
 import (
+	"regexp"
 	"strings"
 	"time"
 
+	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
@@ -114,6 +116,63 @@ func (p Posting) HasBehaviour(behaviour string) bool {
 	return false
 }
 
+var (
+	ledgerTagRegex  = regexp.MustCompile(`(?:^|\s):((?:[^\s:]+:)+)`)
+	hashTagRegex    = regexp.MustCompile(`(?:^|\s)#([^\s#]+)`)
+	hledgerTagRegex = regexp.MustCompile(`(?:^|[\s,])([A-Za-z][\w-]*):`)
+)
+
+// Tags returns the tags of the posting and its transaction. The tags
+// are read from the notes, in ledger (:tag:), hledger (tag: or
+// tag:value) and hashtag (#tag) syntax. Recurring and Period are
+// handled separately and are not included.
+func (p Posting) Tags() []string {
+	tags := []string{}
+	for _, note := range []string{p.TransactionNote, p.Note} {
+		note = ledgerTagRegex.ReplaceAllStringFunc(note, func(match string) string {
+			for _, tag := range strings.Split(strings.TrimSpace(match), ":") {
+				if tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+			return " "
+		})
+
+		for _, match := range hashTagRegex.FindAllStringSubmatch(note, -1) {
+			tags = append(tags, match[1])
+		}
+
+		for _, match := range hledgerTagRegex.FindAllStringSubmatch(note, -1) {
+			if match[1] != "Recurring" && match[1] != "Period" {
+				tags = append(tags, match[1])
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	unique := []string{}
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			unique = append(unique, tag)
+		}
+	}
+	return unique
+}
+
+// TagValue returns the value of the hledger style tag (tag: value) in
+// the notes, the note of the posting takes precedence over the note of
+// the transaction.
+func (p Posting) TagValue(name string) (string, bool) {
+	pattern := regexp.MustCompile(`(?:^|[\s,])` + regexp.QuoteMeta(name) + `:[ \t]*([^,\n]*)`)
+	for _, note := range []string{p.Note, p.TransactionNote} {
+		if match := pattern.FindStringSubmatch(note); match != nil {
+			return strings.TrimSpace(match[1]), true
+		}
+	}
+	return "", false
+}
+
 func UpsertAll(db *gorm.DB, postings []*Posting) {
 	err := db.Transaction(func(tx *gorm.DB) error {
 		err := tx.Exec("DELETE FROM postings").Error
@@ -135,13 +194,36 @@ func UpsertAll(db *gorm.DB, postings []*Posting) {
 	}
 }
 
+// ReplaceByFileName replaces the postings of the given files, leaving
+// the postings of the other files untouched.
+func ReplaceByFileName(db *gorm.DB, fileNames []string, postings []*Posting) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("file_name in ?", fileNames).Delete(&Posting{}).Error
+		if err != nil {
+			return err
+		}
+		for _, posting := range postings {
+			err := tx.Create(posting).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
 func Behaviours(account string) []string {
 	var behaviours []string
 	if utils.IsParent(account, "Assets") {
 		behaviours = append(behaviours, ASSETS)
 	}
 
-	if utils.IsSameOrParent(account, "Assets:Checking") {
+	if utils.IsCheckingAccount(account) {
 		behaviours = append(behaviours, ASSETS_CASH)
 	}
 
@@ -149,7 +231,7 @@ func Behaviours(account string) []string {
 		behaviours = append(behaviours, INCOME)
 	}
 
-	if utils.IsSameOrParent(account, "Income:Interest") {
+	if utils.IsInterestAccount(account) {
 		behaviours = append(behaviours, INCOME_INTEREST)
 	}
 
@@ -157,7 +239,7 @@ func Behaviours(account string) []string {
 		behaviours = append(behaviours, INCOME_DIVIDEND)
 	}
 
-	if utils.IsSameOrParent(account, "Income:Capital Gains") {
+	if utils.IsSameOrParent(account, config.CapitalGainsAccount()) {
 		behaviours = append(behaviours, INCOME_CAPITAL_GAINS)
 	}
 
@@ -169,7 +251,7 @@ func Behaviours(account string) []string {
 		behaviours = append(behaviours, EXPENSES_CHARGES)
 	}
 
-	if utils.IsSameOrParent(account, "Expenses:Tax") {
+	if utils.IsTaxAccount(account) {
 		behaviours = append(behaviours, EXPENSES_TAXES)
 	}
 
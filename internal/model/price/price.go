@@ -7,6 +7,7 @@ import (
 
 	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/google/btree"
+	"github.com/samber/lo"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
 )
@@ -18,6 +19,7 @@ type Price struct {
 	CommodityID   string               `json:"commodity_id"`
 	CommodityName string               `json:"commodity_name"`
 	Value         decimal.Decimal      `json:"value"`
+	Provider      string               `json:"provider"`
 }
 
 func (p Price) Less(o btree.Item) bool {
@@ -74,3 +76,46 @@ func UpsertAllByType(db *gorm.DB, commodityType config.CommodityType, prices []P
 		log.Fatal(err)
 	}
 }
+
+// Latest returns the most recent price of the commodity, fetched or
+// from the journal.
+func Latest(db *gorm.DB, commodityName string) (Price, bool) {
+	var p Price
+	result := db.Where("commodity_name = ?", commodityName).Order("date DESC").Limit(1).Find(&p)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return p.Adjusted(), result.RowsAffected > 0
+}
+
+// Adjusted returns the price with the purity and the deduction of the
+// commodity applied. The metal prices are stored as fetched for the
+// price code, so changing the purity or the deduction doesn't need a
+// refetch.
+func (p Price) Adjusted() Price {
+	if p.CommodityType != config.Metal {
+		return p
+	}
+
+	c, ok := lo.Find(config.GetConfig().Commodities, func(c config.Commodity) bool { return c.Name == p.CommodityName })
+	if ok {
+		p.Value = p.Value.Mul(adjustment(c))
+	}
+	return p
+}
+
+// adjustment returns the factor to apply on the price of the metal
+// code to value the commodity. The purity scales the price, say to
+// value 22k jewelry using the price of 24k gold, and the deduction
+// accounts for what is lost on sale.
+func adjustment(c config.Commodity) decimal.Decimal {
+	factor := decimal.NewFromInt(1)
+	hundred := decimal.NewFromInt(100)
+	if c.Purity > 0 {
+		factor = factor.Mul(decimal.NewFromFloat(c.Purity)).Div(hundred)
+	}
+	if c.Deduction > 0 {
+		factor = factor.Mul(hundred.Sub(decimal.NewFromFloat(c.Deduction))).Div(hundred)
+	}
+	return factor
+}
@@ -0,0 +1,31 @@
+package price
+
+import (
+	"testing"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdjusted(t *testing.T) {
+	config.LoadConfig([]byte(`
+journal_path: main.ledger
+db_path: paisa.db
+commodities:
+  - name: JEWELRY
+    type: metal
+    price:
+      provider: com-purifiedbytes-metal
+      code: gold-999
+    purity: 91.6
+    deduction: 5
+`), "")
+
+	raw := Price{CommodityType: config.Metal, CommodityName: "JEWELRY", Value: decimal.NewFromInt(6000)}
+	assert.Equal(t, "5221.2", raw.Adjusted().Value.String())
+	assert.Equal(t, "6000", raw.Value.String())
+
+	other := Price{CommodityType: config.Metal, CommodityName: "GOLD", Value: decimal.NewFromInt(6000)}
+	assert.Equal(t, "6000", other.Adjusted().Value.String())
+}
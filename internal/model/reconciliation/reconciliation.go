@@ -0,0 +1,58 @@
+package reconciliation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Statement is the closing balance of the account as per the bank
+// statement. The cleared postings till the statement date are
+// expected to add up to the balance.
+type Statement struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	Account   string          `json:"account"`
+	Date      time.Time       `json:"date"`
+	Balance   decimal.Decimal `json:"balance"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Save records the statement, replacing the existing one of the same
+// date if any.
+func Save(db *gorm.DB, statement *Statement) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("account = ? and date = ?", statement.Account, statement.Date).Delete(&Statement{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(statement).Error
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func Latest(db *gorm.DB, account string) (Statement, bool) {
+	var statement Statement
+	result := db.Where("account = ?", account).Order("date DESC").First(&statement)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return statement, false
+		}
+		log.Fatal(result.Error)
+	}
+	return statement, true
+}
+
+func All(db *gorm.DB) []Statement {
+	var statements []Statement
+	result := db.Order("date DESC").Find(&statements)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return statements
+}
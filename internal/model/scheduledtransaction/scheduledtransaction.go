@@ -0,0 +1,79 @@
+package scheduledtransaction
+
+import (
+	"errors"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type Status string
+
+const (
+	Pending  Status = "pending"
+	Posted   Status = "posted"
+	Rejected Status = "rejected"
+)
+
+// ScheduledTransaction is an occurrence of a scheduled transaction
+// defined in the config. Every occurrence is recorded irrespective of
+// the status, so the same due date is never generated twice.
+type ScheduledTransaction struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	Date      time.Time `json:"date"`
+	File      string    `json:"file"`
+	Content   string    `json:"content"`
+	Status    Status    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func Create(db *gorm.DB, st *ScheduledTransaction) {
+	result := db.Create(st)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
+
+// LastDate returns the due date of the latest occurrence of the
+// scheduled transaction.
+func LastDate(db *gorm.DB, name string) (time.Time, bool) {
+	var st ScheduledTransaction
+	result := db.Where("name = ?", name).Order("date DESC").First(&st)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return time.Time{}, false
+		}
+		log.Fatal(result.Error)
+	}
+	return st.Date, true
+}
+
+func Get(db *gorm.DB, id uint) (ScheduledTransaction, bool) {
+	var st ScheduledTransaction
+	result := db.First(&st, id)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return st, false
+		}
+		log.Fatal(result.Error)
+	}
+	return st, true
+}
+
+func ByStatus(db *gorm.DB, status Status, limit int) []ScheduledTransaction {
+	var sts []ScheduledTransaction
+	result := db.Where("status = ?", status).Order("date DESC").Limit(limit).Find(&sts)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return sts
+}
+
+func UpdateStatus(db *gorm.DB, id uint, status Status) {
+	result := db.Model(&ScheduledTransaction{}).Where("id = ?", id).Update("status", status)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+}
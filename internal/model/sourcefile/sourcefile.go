@@ -0,0 +1,59 @@
+package sourcefile
+
+import (
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SourceFile tracks the state of a journal file as of the last sync.
+// ContentHash is the hash of the file content along with the
+// configuration, FileHash is the hash of the file content alone and
+// PostingsHash is the hash of the postings imported from the file.
+type SourceFile struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	Name         string `json:"name"`
+	ContentHash  string `json:"content_hash"`
+	FileHash     string `json:"file_hash"`
+	PostingsHash string `json:"postings_hash"`
+}
+
+func All(db *gorm.DB) []SourceFile {
+	var files []SourceFile
+	result := db.Find(&files)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return files
+}
+
+// FileHash returns the hash of the file content as of the last sync,
+// empty if the file was not synced.
+func FileHash(db *gorm.DB, name string) string {
+	var file SourceFile
+	result := db.Where("name = ?", name).Limit(1).Find(&file)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	return file.FileHash
+}
+
+func ReplaceAll(db *gorm.DB, files []SourceFile) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec("DELETE FROM source_files").Error
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			err := tx.Create(&file).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
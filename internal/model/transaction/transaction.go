@@ -6,6 +6,7 @@ import (
 
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/samber/lo"
 	"gorm.io/gorm"
 )
@@ -28,10 +29,10 @@ type transactionCache struct {
 	transactions map[string]Transaction
 }
 
-var tcache transactionCache
+var tcaches utils.NamespacedCache[transactionCache]
 
-func loadTransactionCache(db *gorm.DB) {
-	postings := query.Init(db).All()
+func loadTransactionCache(db *gorm.DB, tcache *transactionCache) {
+	postings := query.Init(db).IncludeExcluded().All()
 	tcache.transactions = make(map[string]Transaction)
 
 	for _, t := range Build(postings) {
@@ -40,13 +41,14 @@ func loadTransactionCache(db *gorm.DB) {
 }
 
 func GetById(db *gorm.DB, id string) (Transaction, bool) {
-	tcache.Do(func() { loadTransactionCache(db) })
+	tcache := tcaches.Get(db)
+	tcache.Do(func() { loadTransactionCache(db, tcache) })
 	t, found := tcache.transactions[id]
 	return t, found
 }
 
 func ClearCache() {
-	tcache = transactionCache{}
+	tcaches.Clear()
 }
 
 func Build(postings []posting.Posting) []Transaction {
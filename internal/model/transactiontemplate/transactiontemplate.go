@@ -0,0 +1,37 @@
+package transactiontemplate
+
+import (
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/samber/lo"
+)
+
+func All() []config.TransactionTemplate {
+	return config.GetConfig().TransactionTemplates
+}
+
+func Find(name string) (config.TransactionTemplate, bool) {
+	return lo.Find(All(), func(t config.TransactionTemplate) bool { return t.Name == name })
+}
+
+// Upsert replaces the template with the same name, the template is
+// added at the end otherwise.
+func Upsert(template config.TransactionTemplate) error {
+	cfg := config.GetConfig()
+	templates := append([]config.TransactionTemplate{}, cfg.TransactionTemplates...)
+	_, index, found := lo.FindIndexOf(templates, func(t config.TransactionTemplate) bool { return t.Name == template.Name })
+	if found {
+		templates[index] = template
+	} else {
+		templates = append(templates, template)
+	}
+	cfg.TransactionTemplates = templates
+	return config.SaveConfigObject(cfg)
+}
+
+func Delete(name string) error {
+	cfg := config.GetConfig()
+	cfg.TransactionTemplates = lo.Filter(cfg.TransactionTemplates, func(t config.TransactionTemplate, _ int) bool {
+		return t.Name != name
+	})
+	return config.SaveConfigObject(cfg)
+}
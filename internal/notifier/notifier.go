@@ -0,0 +1,157 @@
+// Package notifier sends messages to the user over the transports
+// configured in the notifications section, email and Telegram.
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+)
+
+type Transport interface {
+	Name() string
+	Send(subject string, body string) error
+}
+
+// Transports returns the transports that are configured.
+func Transports() []Transport {
+	notifications := config.GetConfig().Notifications
+	transports := []Transport{}
+	if notifications.Email.Host != "" {
+		transports = append(transports, &EmailTransport{config: notifications.Email})
+	}
+	if notifications.Telegram.BotToken != "" {
+		transports = append(transports, &TelegramTransport{config: notifications.Telegram})
+	}
+	return transports
+}
+
+// Send delivers the message over all the configured transports. A
+// failing transport doesn't stop the delivery over the others.
+func Send(subject string, body string) error {
+	var errs []error
+	for _, transport := range Transports() {
+		err := transport.Send(subject, body)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", transport.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type EmailTransport struct {
+	config config.EmailTransport
+}
+
+func (t *EmailTransport) Name() string {
+	return "email"
+}
+
+func (t *EmailTransport) Send(subject string, body string) error {
+	c := t.config
+	address := net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", c.From)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(c.To, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if c.Username != "" {
+		auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+
+	// SendMail upgrades the connection with STARTTLS when the server
+	// supports it, port 465 expects TLS from the start
+	if c.Port != 465 {
+		return smtp.SendMail(address, auth, c.From, c.To, message.Bytes())
+	}
+
+	conn, err := tls.Dial("tcp", address, &tls.Config{ServerName: c.Host})
+	if err != nil {
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, c.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(c.From); err != nil {
+		return err
+	}
+	for _, to := range c.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message.Bytes()); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+type TelegramTransport struct {
+	config config.TelegramTransport
+}
+
+var telegramClient = &http.Client{Timeout: 30 * time.Second}
+
+func (t *TelegramTransport) Name() string {
+	return "telegram"
+}
+
+func (t *TelegramTransport) Send(subject string, body string) error {
+	payload, err := json.Marshal(map[string]string{
+		"chat_id": t.config.ChatID,
+		"text":    subject + "\n\n" + body,
+	})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", t.config.BotToken)
+	resp, err := telegramClient.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var result struct {
+			Description string `json:"description"`
+		}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return fmt.Errorf("status %d %s", resp.StatusCode, result.Description)
+	}
+	return nil
+}
@@ -0,0 +1,269 @@
+// Package pdf renders simple text documents, headings, paragraphs and
+// tables, as PDF. Only the standard Helvetica fonts are used, so the
+// text is limited to the WinAnsi characters and nothing has to be
+// embedded.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 landscape, in points
+const (
+	pageWidth  = 842.0
+	pageHeight = 595.0
+	margin     = 40.0
+	cellGap    = 8.0
+)
+
+const (
+	regular = "F1"
+	bold    = "F2"
+)
+
+type Align int
+
+const (
+	Left Align = iota
+	Right
+)
+
+// Helvetica widths of the characters from space to tilde, per 1000
+// units of the font size.
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+type Document struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func New() *Document {
+	d := &Document{}
+	d.addPage()
+	return d
+}
+
+func (d *Document) addPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pageHeight - margin
+}
+
+// ensure starts a new page if there is no room for the given height
+func (d *Document) ensure(height float64) {
+	if d.y-height < margin {
+		d.addPage()
+	}
+}
+
+func (d *Document) text(x float64, font string, size float64, value string) {
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, d.y, escape(value))
+}
+
+func (d *Document) line(y float64) {
+	page := d.pages[len(d.pages)-1]
+	fmt.Fprintf(page, "0.5 w %.2f %.2f m %.2f %.2f l S\n", margin, y, pageWidth-margin, y)
+}
+
+func (d *Document) Heading(value string) {
+	d.ensure(30)
+	d.y -= 18
+	d.text(margin, bold, 16, value)
+	d.y -= 10
+}
+
+// Text writes the paragraph, wrapped to the page width.
+func (d *Document) Text(value string) {
+	size := 10.0
+	for _, line := range wrap(value, size, pageWidth-2*margin) {
+		d.ensure(14)
+		d.y -= 14
+		d.text(margin, regular, size, line)
+	}
+	d.y -= 6
+}
+
+// Table writes the rows below the column headers, which are repeated
+// on every page the table spans. The columns are sized to fit their
+// content and shrunk proportionally if the page is not wide enough.
+func (d *Document) Table(columns []string, aligns []Align, rows [][]string) {
+	size := 8.0
+	widths := make([]float64, len(columns))
+	for i, column := range columns {
+		widths[i] = Width(column, size)
+		for _, row := range rows {
+			if i < len(row) && Width(row[i], size) > widths[i] {
+				widths[i] = Width(row[i], size)
+			}
+		}
+	}
+
+	available := pageWidth - 2*margin - cellGap*float64(len(columns)-1)
+	total := 0.0
+	for _, w := range widths {
+		total += w
+	}
+	if total > available {
+		for i := range widths {
+			widths[i] = widths[i] * available / total
+		}
+	}
+
+	writeRow := func(values []string, font string) {
+		x := margin
+		for i, w := range widths {
+			value := ""
+			if i < len(values) {
+				value = truncate(values[i], size, w)
+			}
+			if i < len(aligns) && aligns[i] == Right {
+				d.text(x+w-Width(value, size), font, size, value)
+			} else {
+				d.text(x, font, size, value)
+			}
+			x += w + cellGap
+		}
+	}
+
+	header := func() {
+		d.y -= 12
+		writeRow(columns, bold)
+		d.line(d.y - 3)
+	}
+
+	d.ensure(36)
+	header()
+	for _, row := range rows {
+		if d.y-12 < margin {
+			d.addPage()
+			header()
+		}
+		d.y -= 12
+		writeRow(row, regular)
+	}
+	d.y -= 12
+}
+
+// Bytes returns the content of the PDF file.
+func (d *Document) Bytes() []byte {
+	var objects []string
+	add := func(object string) int {
+		objects = append(objects, object)
+		return len(objects)
+	}
+
+	catalog := add("")
+	pagesID := add("")
+	regularFont := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	boldFont := add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	objects[catalog-1] = fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesID)
+
+	kids := []string{}
+	for i, page := range d.pages {
+		footer := fmt.Sprintf("BT /%s 8.0 Tf %.2f %.2f Td (%d / %d) Tj ET\n", regular, pageWidth-margin-30, margin/2, i+1, len(d.pages))
+		content := page.String() + footer
+		contentID := add(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+		pageID := add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /%s %d 0 R /%s %d 0 R >> >> /Contents %d 0 R >>", pagesID, pageWidth, pageHeight, regular, regularFont, bold, boldFont, contentID))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+	objects[pagesID-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buffer bytes.Buffer
+	buffer.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buffer.Len()
+		fmt.Fprintf(&buffer, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buffer.Len()
+	fmt.Fprintf(&buffer, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buffer, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buffer, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, catalog, xref)
+	return buffer.Bytes()
+}
+
+// Width returns the width of the text in points, the characters
+// outside the printable ASCII range are assumed to be as wide as a
+// digit.
+func Width(value string, size float64) float64 {
+	total := 0
+	for _, r := range value {
+		if isSpace(r) {
+			r = ' '
+		}
+		if r >= ' ' && r <= '~' {
+			total += helveticaWidths[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+func truncate(value string, size float64, width float64) string {
+	if Width(value, size) <= width {
+		return value
+	}
+	runes := []rune(value)
+	for len(runes) > 0 && Width(string(runes)+"...", size) > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
+
+func wrap(value string, size float64, width float64) []string {
+	lines := []string{}
+	current := ""
+	for _, word := range strings.Fields(value) {
+		candidate := strings.TrimSpace(current + " " + word)
+		if current != "" && Width(candidate, size) > width {
+			lines = append(lines, current)
+			candidate = word
+		}
+		current = candidate
+	}
+	if current != "" {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// escape encodes the text as a WinAnsi string literal, the characters
+// that can't be encoded are replaced with a question mark.
+func escape(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case isSpace(r):
+			b.WriteByte(' ')
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= ' ' && r <= '~':
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// isSpace tells whether the rune is one of the spaces used as the
+// grouping separator by the number formatting of some locales.
+func isSpace(r rune) bool {
+	return r == '\u00a0' || r == '\u2009' || r == '\u202f'
+}
@@ -0,0 +1,53 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytes(t *testing.T) {
+	d := New()
+	d.Heading("Networth (2023)")
+	d.Text("Generated on 2023-04-01")
+
+	rows := [][]string{}
+	for i := 0; i < 100; i++ {
+		rows = append(rows, []string{fmt.Sprintf("Assets:Equity:%d", i), "1,000.50"})
+	}
+	d.Table([]string{"account", "amount"}, []Align{Left, Right}, rows)
+
+	content := d.Bytes()
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(content, []byte("%%EOF\n")))
+	assert.Contains(t, string(content), `(Networth \(2023\)) Tj`)
+	assert.Equal(t, 3, len(d.pages))
+	assert.Contains(t, string(content), "/Count 3")
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(content)
+	xref, _ := strconv.Atoi(string(startxref[1]))
+	assert.True(t, bytes.HasPrefix(content[xref:], []byte("xref\n")))
+
+	for i, match := range regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(content, -1) {
+		offset, _ := strconv.Atoi(string(match[1]))
+		assert.True(t, bytes.HasPrefix(content[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))))
+	}
+}
+
+func TestEscape(t *testing.T) {
+	assert.Equal(t, `a\(b\)\\c`, escape(`a(b)\c`))
+	assert.Equal(t, `1 234,5`, escape("1 234,5"))
+	assert.Equal(t, `\351t\351`, escape("été"))
+	assert.Equal(t, `? 100`, escape("₹ 100"))
+}
+
+func TestTruncate(t *testing.T) {
+	assert.Equal(t, "short", truncate("short", 8, 100))
+	truncated := truncate("Assets:Equity:Mutual Funds:Very Long Name", 8, 60)
+	assert.True(t, Width(truncated, 8) <= 60)
+	assert.Regexp(t, `^Assets.*\.\.\.$`, truncated)
+}
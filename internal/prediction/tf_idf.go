@@ -10,6 +10,7 @@ import (
 
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
 	"gorm.io/gorm"
@@ -26,10 +27,10 @@ type tfidfCache struct {
 	index  index
 }
 
-var cache tfidfCache
+var caches utils.NamespacedCache[tfidfCache]
 
-func loadVectorCache(db *gorm.DB) {
-	postings := query.Init(db).All()
+func loadVectorCache(db *gorm.DB, cache *tfidfCache) {
+	postings := query.Init(db).IncludeExcluded().All()
 	idx := buldIndex(postings)
 
 	cache.index = idx
@@ -41,7 +42,7 @@ func loadVectorCache(db *gorm.DB) {
 }
 
 func ClearCache() {
-	cache = tfidfCache{}
+	caches.Clear()
 }
 
 func buldIndex(postings []posting.Posting) index {
@@ -85,8 +86,9 @@ func tokenize(s string) []string {
 }
 
 func GetTfIdf(db *gorm.DB) gin.H {
+	cache := caches.Get(db)
 	cache.Do(func() {
-		loadVectorCache(db)
+		loadVectorCache(db, cache)
 	})
 	return gin.H{"tf_idf": cache.vector, "index": cache.index}
 }
@@ -0,0 +1,67 @@
+package profile
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	mu  sync.Mutex
+	dbs = make(map[string]*gorm.DB)
+)
+
+var ErrNotFound = errors.New("Profile not found")
+
+func Names() []string {
+	return lo.Map(config.GetConfig().Profiles, func(p config.Profile, _ int) string { return p.Name })
+}
+
+// DB returns the db of the profile. The db is opened and the journal
+// of the profile is synced on first use, after that it's kept in sync
+// like the main db, on every journal change.
+func DB(name string) (*gorm.DB, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	profile, found := lo.Find(config.GetConfig().Profiles, func(p config.Profile) bool { return p.Name == name })
+	if !found {
+		return nil, ErrNotFound
+	}
+
+	if db, ok := dbs[name]; ok {
+		return db, nil
+	}
+
+	db, err := utils.OpenDBAt(config.ResolvePath(profile.DBPath))
+	if err != nil {
+		return nil, err
+	}
+
+	db = journal.WithJournalPath(db, config.ResolvePath(profile.JournalPath))
+	db = utils.WithNamespace(db, "profile:"+name)
+
+	log.Info("Opening profile ", name)
+	_, _, err = model.SyncJournalChanges(db)
+	if err != nil {
+		return nil, err
+	}
+
+	dbs[name] = db
+	return db, nil
+}
+
+// Opened returns the dbs of the profiles that are in use
+func Opened() []*gorm.DB {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return lo.Values(dbs)
+}
@@ -2,23 +2,63 @@ package query
 
 import (
 	"errors"
+	"time"
 
 	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/exp/slices"
 	"gorm.io/gorm"
 )
 
+const INCLUDE_FUTURE_KEY = "paisa:include_future"
+const INCLUDE_EXCLUDED_KEY = "paisa:include_excluded"
+
 type Query struct {
-	context         *gorm.DB
-	order           string
-	includeForecast bool
+	context          *gorm.DB
+	order            string
+	includeForecast  bool
+	includeFuture    bool
+	excludeFuture    bool
+	excludedAccounts []string
+	tags             []string
 }
 
 func Init(db *gorm.DB) *Query {
-	return &Query{context: db, order: "ASC", includeForecast: false}
+	return &Query{context: db, order: "ASC", includeForecast: false, includeFuture: IncludeFuture(db), excludedAccounts: ExcludedAccounts(db)}
+}
+
+// WithIncludeFuture returns a db session which overrides the configured
+// policy for future dated postings for all the queries built on top of it.
+func WithIncludeFuture(db *gorm.DB, include bool) *gorm.DB {
+	return db.Set(INCLUDE_FUTURE_KEY, include).Session(&gorm.Session{})
+}
+
+func IncludeFuture(db *gorm.DB) bool {
+	if include, ok := db.Get(INCLUDE_FUTURE_KEY); ok {
+		return include.(bool)
+	}
+
+	return config.GetConfig().IncludeFuturePostings == config.Yes
+}
+
+// WithIncludeExcluded returns a db session which overrides the
+// configured excluded accounts for all the queries built on top of it.
+func WithIncludeExcluded(db *gorm.DB, include bool) *gorm.DB {
+	return db.Set(INCLUDE_EXCLUDED_KEY, include).Session(&gorm.Session{})
+}
+
+// ExcludedAccounts returns the account patterns ignored by the queries,
+// none if the db session includes the excluded accounts.
+func ExcludedAccounts(db *gorm.DB) []string {
+	if include, ok := db.Get(INCLUDE_EXCLUDED_KEY); ok && include.(bool) {
+		return []string{}
+	}
+
+	return config.GetConfig().ExcludedAccounts
 }
 
 func (q *Query) Desc() *Query {
@@ -31,13 +71,27 @@ func (q *Query) Forecast() *Query {
 	return q
 }
 
+// ExcludeFuture withholds postings dated after today, unless the
+// future postings are included by the config or the request
+func (q *Query) ExcludeFuture() *Query {
+	q.excludeFuture = true
+	return q
+}
+
+// IncludeExcluded includes postings of the excluded accounts
+// irrespective of the configuration
+func (q *Query) IncludeExcluded() *Query {
+	q.excludedAccounts = []string{}
+	return q
+}
+
 func (q *Query) Limit(n int) *Query {
 	q.context = q.context.Limit(n)
 	return q
 }
 
 func (q *Query) Clone() *Query {
-	return &Query{context: q.context.Session(&gorm.Session{}), order: q.order, includeForecast: q.includeForecast}
+	return &Query{context: q.context.Session(&gorm.Session{}), order: q.order, includeForecast: q.includeForecast, includeFuture: q.includeFuture, excludeFuture: q.excludeFuture, excludedAccounts: q.excludedAccounts, tags: slices.Clone(q.tags)}
 }
 
 func (q *Query) BeforeNMonths(n int) *Query {
@@ -65,6 +119,53 @@ func (q *Query) LastNMonths(n int) *Query {
 	return q
 }
 
+// Between includes the postings from the start date till the end
+// date, both inclusive.
+func (q *Query) Between(start time.Time, end time.Time) *Query {
+	q.context = q.context.Where("date >= ? and date <= ?", start, utils.EndOfDay(end))
+	return q
+}
+
+func (q *Query) Payee(payees ...string) *Query {
+	q.context = q.context.Where("payee in ?", payees)
+	return q
+}
+
+// PayeeLike matches the payee against the sql like pattern, which is
+// case insensitive.
+func (q *Query) PayeeLike(pattern string) *Query {
+	q.context = q.context.Where("payee like ?", pattern)
+	return q
+}
+
+// Tags includes only the postings having all the tags, either on the
+// posting or on its transaction. The tags are part of the notes, so the
+// notes are narrowed down in sql and the tags are matched exactly
+// after loading.
+func (q *Query) Tags(tags ...string) *Query {
+	for _, tag := range tags {
+		q.context = q.context.Where("note like ? or transaction_note like ?", "%"+tag+"%", "%"+tag+"%")
+	}
+	q.tags = append(q.tags, tags...)
+	return q
+}
+
+func (q *Query) MinAmount(amount decimal.Decimal) *Query {
+	q.context = q.context.Where("amount >= ?", amount)
+	return q
+}
+
+func (q *Query) MaxAmount(amount decimal.Decimal) *Query {
+	q.context = q.context.Where("amount <= ?", amount)
+	return q
+}
+
+// AmountBetween includes the postings with amount in the range, both
+// inclusive. The sign matters, debits are negative.
+func (q *Query) AmountBetween(min decimal.Decimal, max decimal.Decimal) *Query {
+	return q.MinAmount(min).MaxAmount(max)
+}
+
 func (q *Query) Commodities(commodities []config.Commodity) *Query {
 	q.context = q.context.Where("commodity in ?", lo.Map(commodities, func(c config.Commodity, _ int) string { return c.Name }))
 	return q
@@ -97,8 +198,18 @@ func (q *Query) AccountPrefix(account ...string) *Query {
 	return q
 }
 
-func (q *Query) NotAccountPrefix(account string) *Query {
-	q.context = q.context.Where("account not like ? and account != ?", account+":%", account)
+func (q *Query) NotAccountPrefix(account ...string) *Query {
+	query := "account not like ? and account != ?"
+	for range account[1:] {
+		query += " and account not like ? and account != ?"
+	}
+
+	args := make([]interface{}, len(account)*2)
+	for i, a := range account {
+		args[i*2] = a + ":%"
+		args[i*2+1] = a
+	}
+	q.context = q.context.Where(query, args...)
 	return q
 }
 
@@ -126,20 +237,38 @@ func (q *Query) Where(query interface{}, args ...interface{}) *Query {
 	return q
 }
 
+func (q *Query) applyPolicy() {
+	q.context = q.context.Where("forecast = ?", q.includeForecast)
+	if q.excludeFuture && !q.includeForecast && !q.includeFuture {
+		q.context = q.context.Where("date < ?", utils.EndOfToday())
+	}
+	for _, pattern := range q.excludedAccounts {
+		q.context = q.context.Where("account not like ? and account not like ?", pattern, pattern+":%")
+	}
+}
+
 func (q *Query) All() []posting.Posting {
 	var postings []posting.Posting
 
-	q.context = q.context.Where("forecast = ?", q.includeForecast)
+	q.applyPolicy()
 	result := q.context.Order("date " + q.order + ", amount desc, account asc").Find(&postings)
 	if result.Error != nil {
 		log.Fatal(result.Error)
 	}
-	return postings
+	return q.filterTags(postings)
 }
 
 func (q *Query) First() *posting.Posting {
+	if len(q.tags) > 0 {
+		postings := q.All()
+		if len(postings) == 0 {
+			return nil
+		}
+		return &postings[0]
+	}
+
 	var posting posting.Posting
-	q.context = q.context.Where("forecast = ?", q.includeForecast)
+	q.applyPolicy()
 	result := q.context.Order("date " + q.order + ", amount desc, account asc").First(&posting)
 
 	if result.Error != nil {
@@ -150,3 +279,14 @@ func (q *Query) First() *posting.Posting {
 	}
 	return &posting
 }
+
+func (q *Query) filterTags(postings []posting.Posting) []posting.Posting {
+	if len(q.tags) == 0 {
+		return postings
+	}
+
+	return lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		tags := p.Tags()
+		return lo.Every(tags, q.tags)
+	})
+}
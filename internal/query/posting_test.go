@@ -0,0 +1,37 @@
+package query
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExcludeFuture(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	db, err := utils.OpenDBAt(filepath.Join(t.TempDir(), "paisa.db"))
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&posting.Posting{}))
+
+	today := time.Now()
+	posting.UpsertAll(db, []*posting.Posting{
+		{Date: today, Account: "Assets:Checking", Commodity: "INR", Quantity: decimal.NewFromInt(100), Amount: decimal.NewFromInt(100)},
+		{Date: today.AddDate(0, 1, 0), Account: "Assets:Checking", Commodity: "INR", Quantity: decimal.NewFromInt(200), Amount: decimal.NewFromInt(200)},
+	})
+
+	// the reports which don't opt in see the future postings
+	assert.Len(t, Init(db).All(), 2)
+	assert.Len(t, Init(db).ExcludeFuture().All(), 1)
+	assert.Len(t, Init(WithIncludeFuture(db, true)).ExcludeFuture().All(), 2)
+
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\ninclude_future_postings: \"yes\"\n"), "")
+	assert.Len(t, Init(db).ExcludeFuture().All(), 2)
+	assert.Len(t, Init(WithIncludeFuture(db, false)).ExcludeFuture().All(), 1)
+}
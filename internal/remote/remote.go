@@ -0,0 +1,251 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ananthakumaran/paisa/internal/attachment"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// STATE_FILE keeps the hash of every file as of the last sync, which
+// is what both the sides are compared against to tell who changed it.
+const STATE_FILE = ".paisa-remote.json"
+
+// CONFLICT_EXTENSION is appended to the name of the file with the
+// remote content, when a file is changed on both the sides.
+const CONFLICT_EXTENSION = ".conflict"
+
+// Remote keeps the journal directory in sync with a remote storage.
+// The local directory acts as the cache, paisa reads and writes it as
+// usual. Names on the storage are flat, so the path relative to the
+// journal directory is escaped.
+type Remote struct {
+	storage attachment.Storage
+	dir     string
+	ext     string
+	base    map[string]string
+	mu      sync.Mutex
+}
+
+var current *Remote
+
+// Current returns the open remote, nil if the journal is stored
+// locally.
+func Current() *Remote {
+	return current
+}
+
+func GetStorage(c config.JournalStorage) (attachment.Storage, error) {
+	switch c.Storage {
+	case "", "local":
+		return nil, nil
+	case "s3":
+		return attachment.NewS3Storage(c.S3)
+	case "webdav":
+		return attachment.NewWebDAVStorage(c.WebDAV)
+	}
+
+	return nil, fmt.Errorf("Unknown journal storage %s", c.Storage)
+}
+
+// Open syncs the journal directory with the configured storage. It
+// does nothing if the journal is stored locally.
+func Open(journalPath string) (*Remote, error) {
+	c := config.GetConfig()
+	storage, err := GetStorage(c.JournalStorage)
+	if err != nil || storage == nil {
+		return nil, err
+	}
+	if c.Encryption.Enabled {
+		return nil, errors.New("Journal storage can't be used along with the encryption")
+	}
+
+	dir := filepath.Dir(journalPath)
+	err = os.MkdirAll(dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Remote{storage: storage, dir: dir, ext: filepath.Ext(journalPath), base: make(map[string]string)}
+	content, err := os.ReadFile(filepath.Join(dir, STATE_FILE))
+	if err == nil {
+		err = json.Unmarshal(content, &r.base)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", STATE_FILE, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	_, err = r.Sync()
+	if err != nil {
+		return nil, err
+	}
+
+	current = r
+	log.Info("Synced the journal with ", c.JournalStorage.Storage, " storage")
+	return r, nil
+}
+
+// Sync reconciles the given files, all the files on both the sides if
+// none given, and returns the ones with conflicts. A file changed only
+// on one side since the last sync is copied over to the other side.
+// When it is changed on both the sides, the local copy is kept and the
+// remote copy is saved next to it with the conflict extension. The
+// local copy overwrites the remote one on the next sync once the
+// conflict file is removed.
+func (r *Remote) Sync(names ...string) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(names) == 0 {
+		var err error
+		names, err = r.names()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conflicts := []string{}
+	for _, name := range names {
+		conflict, err := r.syncFile(filepath.ToSlash(name))
+		if err != nil {
+			return conflicts, fmt.Errorf("%s: %w", name, err)
+		}
+		if conflict {
+			log.Warnf("%s was changed both locally and remotely, the remote copy is saved as %s", name, name+CONFLICT_EXTENSION)
+			conflicts = append(conflicts, name)
+		}
+	}
+
+	return conflicts, r.saveState()
+}
+
+func (r *Remote) syncFile(name string) (bool, error) {
+	path := filepath.Join(r.dir, filepath.FromSlash(name))
+	local, err := os.ReadFile(path)
+	localExists := err == nil
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+
+	remote, err := r.storage.Get(url.PathEscape(name))
+	remoteExists := err == nil
+	if err != nil && !errors.Is(err, attachment.ErrNotFound) {
+		return false, err
+	}
+
+	localHash := hash(local, localExists)
+	remoteHash := hash(remote, remoteExists)
+	base, synced := r.base[name]
+	conflict := utils.FileExists(path + CONFLICT_EXTENSION)
+
+	switch {
+	case localHash == remoteHash:
+		base = localHash
+	case localExists && remoteExists && (conflict || (localHash != base && remoteHash != base)):
+		err = os.WriteFile(path+CONFLICT_EXTENSION, remote, 0600)
+		if err != nil {
+			return false, err
+		}
+		// the remote copy is in the conflict file, so the local copy
+		// wins once that is removed
+		r.base[name] = remoteHash
+		return true, nil
+	case remoteHash == base && synced || !remoteExists && localHash != base:
+		if localExists {
+			err = r.storage.Put(url.PathEscape(name), local)
+		} else {
+			err = r.storage.Delete(url.PathEscape(name))
+		}
+		base = localHash
+	default:
+		if remoteExists {
+			err = os.MkdirAll(filepath.Dir(path), 0700)
+			if err == nil {
+				err = os.WriteFile(path, remote, 0600)
+			}
+		} else {
+			err = os.Remove(path)
+		}
+		base = remoteHash
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if base == "" {
+		delete(r.base, name)
+	} else {
+		r.base[name] = base
+	}
+	return false, nil
+}
+
+// names returns the journal files present on either side or synced
+// before.
+func (r *Remote) names() ([]string, error) {
+	seen := make(map[string]bool)
+	for name := range r.base {
+		seen[name] = true
+	}
+
+	remoteNames, err := r.storage.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, escaped := range remoteNames {
+		name, err := url.PathUnescape(escaped)
+		if err != nil || !strings.HasSuffix(name, r.ext) || invalidName(name) {
+			continue
+		}
+		seen[name] = true
+	}
+
+	paths, err := config.GlobFiles(r.dir, r.ext)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		if strings.HasPrefix(filepath.Base(path), "paisa-tmp-") {
+			continue
+		}
+		name, err := filepath.Rel(r.dir, path)
+		if err != nil {
+			return nil, err
+		}
+		seen[filepath.ToSlash(name)] = true
+	}
+
+	return utils.SortedKeys(seen), nil
+}
+
+func (r *Remote) saveState() error {
+	content, err := json.MarshalIndent(r.base, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.dir, STATE_FILE), content, 0600)
+}
+
+// invalidName rejects the remote names that would be written outside
+// the journal directory.
+func invalidName(name string) bool {
+	return filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") || strings.Contains(name, "/../")
+}
+
+func hash(content []byte, exists bool) string {
+	if !exists {
+		return ""
+	}
+	return utils.Sha256(string(content))
+}
@@ -0,0 +1,296 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gofrs/uuid"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SANDBOX_TTL is how long an unused sandbox is kept, the sandbox of a
+// closed browser tab is never committed or discarded otherwise.
+const SANDBOX_TTL = 12 * time.Hour
+
+const EXPIRE_INTERVAL = 10 * time.Minute
+
+// Sandbox is a throw away copy of the journal and the database, kept
+// on disk in a temporary directory. Edits made while the sandbox is
+// active only touch the copy, until they are committed back to the
+// main journal.
+type Sandbox struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	dir       string
+	db        *gorm.DB
+	parent    *gorm.DB
+	hashes    map[string]string
+	usedAt    time.Time
+}
+
+var (
+	mu         sync.Mutex
+	sandboxes  = make(map[string]*Sandbox)
+	expireOnce sync.Once
+)
+
+func (s *Sandbox) DB() *gorm.DB {
+	return s.db
+}
+
+func Start(db *gorm.DB) (*Sandbox, error) {
+	expireOnce.Do(func() {
+		removeStale()
+		go func() {
+			for range time.Tick(EXPIRE_INTERVAL) {
+				expire()
+			}
+		}()
+	})
+
+	id := uuid.Must(uuid.NewV4()).String()
+	dir, err := os.MkdirTemp("", "paisa-sandbox-")
+	if err != nil {
+		return nil, err
+	}
+
+	hashes, err := copyJournal(db, dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	dbPath := filepath.Join(dir, ".paisa-sandbox.db")
+	result := db.Exec("VACUUM INTO ?", dbPath)
+	if result.Error != nil {
+		os.RemoveAll(dir)
+		return nil, result.Error
+	}
+
+	sandboxDB, err := utils.OpenDBAt(dbPath)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	rel, err := filepath.Rel(journal.Dir(db), journal.JournalPath(db))
+	if err != nil {
+		closeDB(sandboxDB)
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	sandboxDB = journal.WithJournalPath(sandboxDB, filepath.Join(dir, rel))
+	sandboxDB = utils.WithNamespace(sandboxDB, "sandbox:"+id)
+
+	now := time.Now()
+	s := &Sandbox{ID: id, CreatedAt: now, dir: dir, db: sandboxDB, parent: db, hashes: hashes, usedAt: now}
+	mu.Lock()
+	sandboxes[id] = s
+	mu.Unlock()
+
+	log.Info("Started sandbox ", id)
+	return s, nil
+}
+
+func Get(id string) (*Sandbox, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	s, ok := sandboxes[id]
+	if ok {
+		s.usedAt = time.Now()
+	}
+	return s, ok
+}
+
+// Parent returns the db the sandbox was started from, the one the
+// sandbox is committed to.
+func (s *Sandbox) Parent() *gorm.DB {
+	return s.parent
+}
+
+// Commit writes the files modified in the sandbox back to the journal
+// it was started from and discards the sandbox. The db should be
+// either the sandbox db or the db of that journal. The commit is
+// rejected if any of those files were changed in the journal after the
+// sandbox was started, or if any of the files were deleted in the
+// sandbox.
+func Commit(db *gorm.DB, id string) ([]string, error) {
+	s, ok := Get(id)
+	if !ok {
+		return nil, fmt.Errorf("Sandbox %s not found", id)
+	}
+
+	if path := journal.JournalPath(db); path != journal.JournalPath(s.db) && path != journal.JournalPath(s.parent) {
+		return nil, fmt.Errorf("Sandbox %s belongs to another journal", id)
+	}
+	db = s.parent
+
+	current, err := readJournal(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range utils.SortedKeys(s.hashes) {
+		if _, ok := current[name]; !ok {
+			return nil, fmt.Errorf("File %s was deleted in the sandbox, deleting files is not supported", name)
+		}
+	}
+
+	files := make(map[string]string)
+	for name, content := range current {
+		if s.hashes[name] == utils.Sha256(content) {
+			continue
+		}
+
+		existing, err := journal.Read(db, name)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		original, ok := s.hashes[name]
+		if (ok && original != utils.Sha256(existing)) || (!ok && err == nil) {
+			return nil, fmt.Errorf("File %s was modified outside the sandbox", name)
+		}
+
+		files[name] = content
+	}
+
+	if len(files) > 0 {
+		err = journal.WriteAll(db, "sandbox commit", files)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = Discard(id)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	return utils.SortedKeys(files), nil
+}
+
+// Discard closes the database of the sandbox and removes the copy.
+func Discard(id string) error {
+	mu.Lock()
+	s, ok := sandboxes[id]
+	delete(sandboxes, id)
+	mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("Sandbox %s not found", id)
+	}
+
+	closeDB(s.db)
+	log.Info("Discarded sandbox ", id)
+	return os.RemoveAll(s.dir)
+}
+
+// expire discards the sandboxes that are not used for SANDBOX_TTL.
+func expire() {
+	mu.Lock()
+	expired := []string{}
+	for id, s := range sandboxes {
+		if time.Since(s.usedAt) > SANDBOX_TTL {
+			expired = append(expired, id)
+		}
+	}
+	mu.Unlock()
+
+	for _, id := range expired {
+		log.Info("Sandbox ", id, " expired")
+		err := Discard(id)
+		if err != nil {
+			log.Warn(err)
+		}
+	}
+}
+
+// removeStale removes the sandboxes left behind by the previous runs,
+// like when the server is killed.
+func removeStale() {
+	dirs, _ := filepath.Glob(filepath.Join(os.TempDir(), "paisa-sandbox-*"))
+	for _, dir := range dirs {
+		stat, err := os.Stat(dir)
+		if err != nil || !stat.IsDir() || time.Since(stat.ModTime()) < SANDBOX_TTL {
+			continue
+		}
+
+		err = os.RemoveAll(dir)
+		if err != nil {
+			log.Warn(err)
+		}
+	}
+}
+
+func closeDB(db *gorm.DB) {
+	sqlDB, err := db.DB()
+	if err == nil {
+		sqlDB.Close()
+	}
+}
+
+func copyJournal(db *gorm.DB, to string) (map[string]string, error) {
+	files, err := readJournal(db)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make(map[string]string)
+	for name, content := range files {
+		path := filepath.Join(to, name)
+		err := os.MkdirAll(filepath.Dir(path), 0700)
+		if err != nil {
+			return nil, err
+		}
+
+		err = os.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			return nil, err
+		}
+		hashes[name] = utils.Sha256(content)
+	}
+
+	return hashes, nil
+}
+
+// readJournal reads the journal files, the ones with the extension of
+// the main journal and the ones the postings were imported from. The
+// files outside the journal directory are skipped.
+func readJournal(db *gorm.DB) (map[string]string, error) {
+	names, err := journal.Files(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var imported []string
+	db.Model(&posting.Posting{}).Distinct().Pluck("FileName", &imported)
+
+	files := make(map[string]string)
+	for _, name := range lo.Uniq(append(names, imported...)) {
+		if _, err := journal.Path(db, name); name == "" || err != nil {
+			continue
+		}
+
+		content, err := journal.Read(db, name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files[name] = content
+	}
+
+	return files, nil
+}
@@ -0,0 +1,80 @@
+package sandbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpire(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.ledger"), []byte(""), 0600))
+	db, err := utils.OpenDBAt(filepath.Join(dir, "paisa.db"))
+	require.NoError(t, err)
+	db = journal.WithJournalPath(db, filepath.Join(dir, "main.ledger"))
+
+	used, err := Start(db)
+	require.NoError(t, err)
+	unused, err := Start(db)
+	require.NoError(t, err)
+
+	mu.Lock()
+	unused.usedAt = time.Now().Add(-SANDBOX_TTL - time.Minute)
+	mu.Unlock()
+	expire()
+
+	_, ok := Get(unused.ID)
+	assert.False(t, ok)
+	assert.NoDirExists(t, unused.dir)
+	sqlDB, err := unused.db.DB()
+	require.NoError(t, err)
+	assert.Error(t, sqlDB.Ping())
+
+	_, ok = Get(used.ID)
+	assert.True(t, ok)
+	assert.DirExists(t, used.dir)
+	require.NoError(t, Discard(used.ID))
+	assert.NoDirExists(t, used.dir)
+}
+
+func TestCommit(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.ledger"), []byte("include 2023.ledger\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "2023.ledger"), []byte(""), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("notes"), 0600))
+	db, err := utils.OpenDBAt(filepath.Join(dir, "paisa.db"))
+	require.NoError(t, err)
+	db = journal.WithJournalPath(db, filepath.Join(dir, "main.ledger"))
+
+	s, err := Start(db)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(s.dir, "2023.ledger"))
+	assert.NoFileExists(t, filepath.Join(s.dir, "notes.txt"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(s.dir, "2023.ledger"), []byte("; edited\n"), 0600))
+	files, err := Commit(s.DB(), s.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2023.ledger"}, files)
+	content, err := os.ReadFile(filepath.Join(dir, "2023.ledger"))
+	require.NoError(t, err)
+	assert.Equal(t, "; edited\n", string(content))
+
+	s, err = Start(db)
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(filepath.Join(s.dir, "2023.ledger")))
+	_, err = Commit(db, s.ID)
+	assert.ErrorContains(t, err, "deleted in the sandbox")
+	assert.FileExists(t, filepath.Join(dir, "2023.ledger"))
+	require.NoError(t, Discard(s.ID))
+}
@@ -0,0 +1,156 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression with the standard five fields
+// (minute, hour, day of month, month, day of week). Each field accepts
+// *, numbers, ranges (1-5), lists (1,3,5) and steps (*/15, 1-10/2).
+// ? is accepted as an alias of * for day of month and day of week, as
+// used by the Period tag of recurring transactions.
+type Schedule struct {
+	minute     [60]bool
+	hour       [24]bool
+	dayOfMonth [32]bool
+	month      [13]bool
+	dayOfWeek  [7]bool
+
+	// day of month and day of week are OR-ed when both are restricted
+	dayOfMonthAny bool
+	dayOfWeekAny  bool
+}
+
+var macros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+func Parse(expression string) (Schedule, error) {
+	var s Schedule
+
+	expression = strings.TrimSpace(expression)
+	if macro, ok := macros[expression]; ok {
+		expression = macro
+	}
+
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return s, fmt.Errorf("Invalid cron expression %q, expected 5 fields", expression)
+	}
+
+	err := parseField(fields[0], 0, 59, s.minute[:])
+	if err != nil {
+		return s, err
+	}
+
+	err = parseField(fields[1], 0, 23, s.hour[:])
+	if err != nil {
+		return s, err
+	}
+
+	fields[2] = strings.Replace(fields[2], "?", "*", 1)
+	fields[4] = strings.Replace(fields[4], "?", "*", 1)
+
+	err = parseField(fields[2], 1, 31, s.dayOfMonth[:])
+	if err != nil {
+		return s, err
+	}
+
+	err = parseField(fields[3], 1, 12, s.month[:])
+	if err != nil {
+		return s, err
+	}
+
+	var dayOfWeek [8]bool
+	err = parseField(fields[4], 0, 7, dayOfWeek[:])
+	if err != nil {
+		return s, err
+	}
+	copy(s.dayOfWeek[:], dayOfWeek[:7])
+	if dayOfWeek[7] {
+		s.dayOfWeek[0] = true
+	}
+
+	s.dayOfMonthAny = strings.HasPrefix(fields[2], "*")
+	s.dayOfWeekAny = strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// Matches reports whether the schedule should run at the minute of the
+// given time.
+func (s Schedule) Matches(t time.Time) bool {
+	if !s.minute[t.Minute()] || !s.hour[t.Hour()] || !s.month[t.Month()] {
+		return false
+	}
+
+	dayOfMonth := s.dayOfMonth[t.Day()]
+	dayOfWeek := s.dayOfWeek[t.Weekday()]
+	if s.dayOfMonthAny || s.dayOfWeekAny {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
+
+// Next returns the first time after t that matches the schedule, or
+// zero time if there is none within the next few years.
+func (s Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		if s.Matches(t) {
+			return t
+		}
+		t = t.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+func parseField(field string, min int, max int, values []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step <= 0 {
+				return fmt.Errorf("Invalid step in %q", field)
+			}
+			part = part[:i]
+		}
+
+		start, end := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			start, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return fmt.Errorf("Invalid value in %q", field)
+			}
+
+			end = start
+			if len(bounds) == 2 {
+				end, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return fmt.Errorf("Invalid value in %q", field)
+				}
+			} else if step != 1 {
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return fmt.Errorf("Value out of range in %q, expected %d-%d", field, min, max)
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	for _, expression := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-1 * * * *"} {
+		_, err := Parse(expression)
+		assert.Error(t, err, expression)
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		parsed, _ := time.Parse("2006-01-02 15:04", s)
+		return parsed
+	}
+
+	next := func(expression string, from string) time.Time {
+		s, err := Parse(expression)
+		assert.NoError(t, err, expression)
+		return s.Next(at(from))
+	}
+
+	assert.Equal(t, at("2024-01-01 06:00"), next("0 6 * * *", "2024-01-01 05:30"))
+	assert.Equal(t, at("2024-01-02 06:00"), next("0 6 * * *", "2024-01-01 06:00"))
+	assert.Equal(t, at("2024-01-01 05:45"), next("*/15 * * * *", "2024-01-01 05:31"))
+	assert.Equal(t, at("2024-01-01 00:00"), next("@daily", "2023-12-31 13:00"))
+	assert.Equal(t, at("2024-01-05 09:00"), next("0 9 * * 1-5", "2024-01-04 10:00"))
+	assert.Equal(t, at("2024-01-08 09:00"), next("0 9 * * 1-5", "2024-01-05 10:00"))
+	assert.Equal(t, at("2024-01-07 00:00"), next("0 0 * * 7", "2024-01-01 00:00"))
+	// day of month and day of week are OR-ed when both are restricted
+	assert.Equal(t, at("2024-01-07 00:00"), next("0 0 15 * 0", "2024-01-01 00:00"))
+	assert.Equal(t, at("2024-01-15 00:00"), next("0 0 15 * 0", "2024-01-14 00:00"))
+	assert.Equal(t, at("2024-02-01 00:00"), next("0 0 1,15 * *", "2024-01-15 00:00"))
+	assert.Equal(t, at("2024-02-05 00:00"), next("0 0 5 * ?", "2024-01-05 00:00"))
+}
@@ -0,0 +1,155 @@
+package appreciation
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/model/price"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const CODE = "appreciation"
+
+const INDEX_PREFIX = "index:"
+
+// PriceProvider doesn't fetch anything, the prices depend on the
+// purchase price and the appraisals in the journal, so they are
+// generated along with the journal sync by Prices.
+type PriceProvider struct {
+}
+
+func (p *PriceProvider) Code() string {
+	return CODE
+}
+
+func (p *PriceProvider) Label() string {
+	return "Appreciation"
+}
+
+func (p *PriceProvider) Description() string {
+	return "Grows the purchase price or the last appraisal by an annual rate or by an index, for assets like real estate."
+}
+
+func (p *PriceProvider) AutoCompleteFields() []price.AutoCompleteField {
+	return []price.AutoCompleteField{
+		{Label: "Rate or Index", ID: "code", Help: "Annual appreciation rate in percentage like 6.5, or index:{commodity} to follow the price of the commodity.", InputType: "text"},
+	}
+}
+
+func (p *PriceProvider) AutoComplete(db *gorm.DB, field string, filter map[string]string) []price.AutoCompleteItem {
+	return []price.AutoCompleteItem{}
+}
+
+func (p *PriceProvider) ClearCache(db *gorm.DB) {
+}
+
+func (p *PriceProvider) GetPrices(code string, commodityName string) ([]*price.Price, error) {
+	return []*price.Price{}, nil
+}
+
+// Prices generates a price on the first of every month for the
+// commodities linked to the appreciation provider. The value grows
+// from the latest anchor, which is either a purchase or a price
+// (appraisal) in the journal.
+func Prices(commodities []config.Commodity, prices []price.Price, postings []*posting.Posting, until time.Time) []price.Price {
+	generated := []price.Price{}
+	for _, c := range commodities {
+		if c.Price.Provider != CODE {
+			continue
+		}
+
+		anchors := lo.Filter(prices, func(p price.Price, _ int) bool { return p.CommodityName == c.Name })
+		for _, p := range postings {
+			if p.Commodity == c.Name && p.Quantity.IsPositive() {
+				anchors = append(anchors, price.Price{Date: p.Date, CommodityName: c.Name, Value: p.Amount.Div(p.Quantity)})
+			}
+		}
+		if len(anchors) == 0 {
+			continue
+		}
+		sort.SliceStable(anchors, func(i, j int) bool { return anchors[i].Date.Before(anchors[j].Date) })
+
+		growth, err := growthFn(c.Price.Code, prices)
+		if err != nil {
+			log.Warn("Invalid appreciation code for ", c.Name, ": ", err)
+			continue
+		}
+
+		for date := utils.BeginningOfMonth(anchors[0].Date).AddDate(0, 1, 0); !date.After(until); date = date.AddDate(0, 1, 0) {
+			anchor := anchors[0]
+			for _, a := range anchors {
+				if !a.Date.After(date) {
+					anchor = a
+				}
+			}
+
+			if anchor.Date.Equal(date) {
+				continue
+			}
+
+			factor, ok := growth(anchor.Date, date)
+			if !ok {
+				continue
+			}
+
+			generated = append(generated, price.Price{
+				Date:          date,
+				CommodityType: config.Unknown,
+				CommodityID:   c.Name,
+				CommodityName: c.Name,
+				Value:         anchor.Value.Mul(factor),
+			})
+		}
+	}
+
+	return generated
+}
+
+type growthFunc func(from time.Time, to time.Time) (decimal.Decimal, bool)
+
+func growthFn(code string, prices []price.Price) (growthFunc, error) {
+	if strings.HasPrefix(code, INDEX_PREFIX) {
+		index := strings.TrimPrefix(code, INDEX_PREFIX)
+		values := lo.Filter(prices, func(p price.Price, _ int) bool { return p.CommodityName == index })
+		sort.SliceStable(values, func(i, j int) bool { return values[i].Date.Before(values[j].Date) })
+
+		valueOn := func(date time.Time) (decimal.Decimal, bool) {
+			value, found := decimal.Zero, false
+			for _, v := range values {
+				if v.Date.After(date) {
+					break
+				}
+				value, found = v.Value, true
+			}
+			return value, found && value.IsPositive()
+		}
+
+		return func(from time.Time, to time.Time) (decimal.Decimal, bool) {
+			start, ok := valueOn(from)
+			if !ok {
+				return decimal.Zero, false
+			}
+			end, _ := valueOn(to)
+			return end.Div(start), true
+		}, nil
+	}
+
+	rate, err := strconv.ParseFloat(code, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(from time.Time, to time.Time) (decimal.Decimal, bool) {
+		years := to.Sub(from).Hours() / (24 * 365)
+		return decimal.NewFromFloat(math.Pow(1+rate/100, years)), true
+	}, nil
+}
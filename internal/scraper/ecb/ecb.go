@@ -0,0 +1,111 @@
+package ecb
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+)
+
+// Rate is the price of one unit of the currency in euro.
+type Rate struct {
+	Date  time.Time
+	Value decimal.Decimal
+}
+
+type cachedRates struct {
+	fetchedOn string
+	rates     []Rate
+}
+
+var (
+	mu    sync.Mutex
+	cache = make(map[string]cachedRates)
+)
+
+// GetRates returns the euro foreign exchange reference rates of the
+// currency published by the European Central Bank, oldest first. They
+// are fetched once a day.
+func GetRates(currency string) ([]Rate, error) {
+	if currency == "EUR" {
+		return []Rate{{Date: time.Time{}, Value: decimal.NewFromInt(1)}}, nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	today := time.Now().Format("2006-01-02")
+	if cached, ok := cache[currency]; ok && cached.fetchedOn == today {
+		return cached.rates, nil
+	}
+
+	rates, err := fetchRates(currency)
+	if err != nil {
+		return nil, err
+	}
+	cache[currency] = cachedRates{fetchedOn: today, rates: rates}
+	return rates, nil
+}
+
+// RateOn returns the latest rate published on or before the date, the
+// ECB doesn't publish on the weekends and the TARGET holidays.
+func RateOn(rates []Rate, date time.Time) (decimal.Decimal, bool) {
+	i := sort.Search(len(rates), func(i int) bool { return rates[i].Date.After(date) })
+	if i == 0 {
+		return decimal.Zero, false
+	}
+	return rates[i-1].Value, true
+}
+
+func fetchRates(currency string) ([]Rate, error) {
+	log.Info("Fetching ECB reference rates of ", currency)
+	url := fmt.Sprintf("https://data-api.ecb.europa.eu/service/data/EXR/D.%s.EUR.SP00.A?format=csvdata", currency)
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ECB returned %s for %s", resp.Status, currency)
+	}
+
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("No ECB reference rates found for %s", currency)
+	}
+
+	dateColumn := lo.IndexOf(records[0], "TIME_PERIOD")
+	valueColumn := lo.IndexOf(records[0], "OBS_VALUE")
+	if dateColumn == -1 || valueColumn == -1 {
+		return nil, fmt.Errorf("Unexpected ECB response for %s", currency)
+	}
+
+	rates := []Rate{}
+	for _, record := range records[1:] {
+		date, err := time.ParseInLocation("2006-01-02", record[dateColumn], config.TimeZone())
+		if err != nil {
+			return nil, err
+		}
+
+		// the rates are quoted as the units of the currency per euro
+		value, err := decimal.NewFromString(record[valueColumn])
+		if err != nil || value.IsZero() {
+			continue
+		}
+		rates = append(rates, Rate{Date: date, Value: decimal.NewFromInt(1).DivRound(value, 8)})
+	}
+
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Date.Before(rates[j].Date) })
+	return rates, nil
+}
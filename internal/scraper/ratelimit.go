@@ -0,0 +1,44 @@
+package scraper
+
+import (
+	"sync"
+	"time"
+)
+
+// Minimum interval between two requests to the same provider. The
+// commodities are fetched concurrently, this keeps the workers from
+// tripping the limits of the upstream APIs.
+var rateLimits = map[string]time.Duration{
+	"in-mfapi":                200 * time.Millisecond,
+	"com-purifiedbytes-nps":   200 * time.Millisecond,
+	"com-purifiedbytes-metal": 200 * time.Millisecond,
+	"com-yahoo":               500 * time.Millisecond,
+	// free tier allows 5 requests per minute
+	"co-alphavantage": 12 * time.Second,
+}
+
+var (
+	rateLimitMu sync.Mutex
+	nextAllowed = make(map[string]time.Time)
+)
+
+// Throttle blocks until the provider can be called again. Each caller
+// reserves its own slot, so concurrent callers are spaced out by the
+// interval of the provider.
+func Throttle(code string) {
+	interval, ok := rateLimits[code]
+	if !ok {
+		return
+	}
+
+	rateLimitMu.Lock()
+	now := time.Now()
+	slot := nextAllowed[code]
+	if slot.Before(now) {
+		slot = now
+	}
+	nextAllowed[code] = slot.Add(interval)
+	rateLimitMu.Unlock()
+
+	time.Sleep(slot.Sub(now))
+}
@@ -2,6 +2,7 @@ package scraper
 
 import (
 	"github.com/ananthakumaran/paisa/internal/model/price"
+	"github.com/ananthakumaran/paisa/internal/scraper/appreciation"
 	"github.com/ananthakumaran/paisa/internal/scraper/metal"
 	"github.com/ananthakumaran/paisa/internal/scraper/mutualfund"
 	"github.com/ananthakumaran/paisa/internal/scraper/nps"
@@ -16,10 +17,21 @@ func GetAllProviders() []price.PriceProvider {
 		&stock.AlphaVantagePriceProvider{},
 		&nps.PriceProvider{},
 		&metal.PriceProvider{},
+		&appreciation.PriceProvider{},
 	}
 
 }
 
+// Providers whose price history is already restated for the splits
+// and the bonuses, the rest get adjusted as per the corporate actions.
+var splitAdjusted = map[string]bool{
+	"com-yahoo": true,
+}
+
+func SplitAdjusted(code string) bool {
+	return splitAdjusted[code]
+}
+
 func GetProviderByCode(code string) price.PriceProvider {
 	switch code {
 	case "in-mfapi":
@@ -32,6 +44,8 @@ func GetProviderByCode(code string) price.PriceProvider {
 		return &stock.YahooPriceProvider{}
 	case "co-alphavantage":
 		return &stock.AlphaVantagePriceProvider{}
+	case appreciation.CODE:
+		return &appreciation.PriceProvider{}
 	}
 	log.Fatal("Unknown price provider: ", code)
 	return nil
@@ -0,0 +1,104 @@
+package stock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/utils"
+)
+
+type LiveQuote struct {
+	Symbol             string
+	Currency           string
+	RegularMarketPrice float64
+}
+
+type QuoteResult struct {
+	Result []LiveQuote
+}
+
+type QuoteResponse struct {
+	QuoteResponse QuoteResult
+}
+
+// GetLiveQuotes fetches the current quote of the tickers, converted
+// to the default currency using the current exchange rate. The
+// tickers without a quote are left out of the result.
+func GetLiveQuotes(tickers []string) (map[string]decimal.Decimal, error) {
+	log.Info("Fetching live stock quotes from Yahoo")
+	quotes, err := getQuotes(tickers)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeTickers := lo.Uniq(lo.FilterMap(quotes, func(q LiveQuote, _ int) (string, bool) {
+		return exchangeTicker(q.Currency), !utils.IsCurrency(q.Currency)
+	}))
+
+	exchangeRates := make(map[string]float64)
+	if len(exchangeTickers) > 0 {
+		rates, err := getQuotes(exchangeTickers)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range rates {
+			exchangeRates[r.Symbol] = r.RegularMarketPrice
+		}
+	}
+
+	prices := make(map[string]decimal.Decimal)
+	for _, q := range quotes {
+		value := q.RegularMarketPrice
+		if !utils.IsCurrency(q.Currency) {
+			rate, ok := exchangeRates[exchangeTicker(q.Currency)]
+			if !ok {
+				log.Warnf("Missing exchange rate for %s, skipping the live quote of %s", q.Currency, q.Symbol)
+				continue
+			}
+			value = value * rate
+		}
+		prices[q.Symbol] = decimal.NewFromFloat(value)
+	}
+	return prices, nil
+}
+
+func exchangeTicker(currency string) string {
+	return fmt.Sprintf("%s%s=X", currency, config.DefaultCurrency())
+}
+
+func getQuotes(tickers []string) ([]LiveQuote, error) {
+	endpoint := fmt.Sprintf("https://query1.finance.yahoo.com/v7/finance/quote?symbols=%s", url.QueryEscape(strings.Join(tickers, ",")))
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Failed to fetch the live quotes, status %d", resp.StatusCode)
+	}
+
+	respBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response QuoteResponse
+	err = json.Unmarshal(respBytes, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	return lo.Filter(response.QuoteResponse.Result, func(q LiveQuote, _ int) bool {
+		return q.RegularMarketPrice != 0
+	}), nil
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/ananthakumaran/paisa/internal/accounting"
 	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/commoditymetadata"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
 	"github.com/ananthakumaran/paisa/internal/service"
@@ -23,6 +24,14 @@ type Aggregate struct {
 	MarketAmount decimal.Decimal `json:"market_amount"`
 }
 
+const UNCATEGORIZED = "Uncategorized"
+
+type CategoryAllocation struct {
+	Category     string          `json:"category"`
+	MarketAmount decimal.Decimal `json:"market_amount"`
+	Percentage   decimal.Decimal `json:"percentage"`
+}
+
 type AllocationTargetConfig struct {
 	Name     string
 	Target   decimal.Decimal
@@ -47,7 +56,35 @@ func GetAllocation(db *gorm.DB) gin.H {
 	aggregates := computeAggregate(db, postings, now)
 	aggregates_timeline := computeAggregateTimeline(db, postings)
 	allocation_targets := computeAllocationTargets(db, postings)
-	return gin.H{"aggregates": aggregates, "aggregates_timeline": aggregates_timeline, "allocation_targets": allocation_targets}
+	categories := computeCategoryAllocation(postings, commoditymetadata.ByCommodities(db))
+	return gin.H{"aggregates": aggregates, "aggregates_timeline": aggregates_timeline, "allocation_targets": allocation_targets, "categories": categories}
+}
+
+// computeCategoryAllocation splits the current market value by the
+// category in the commodity metadata.
+func computeCategoryAllocation(postings []posting.Posting, metadata map[string]commoditymetadata.Metadata) []CategoryAllocation {
+	byCategory := lo.GroupBy(postings, func(p posting.Posting) string {
+		if m, ok := metadata[p.Commodity]; ok && m.Category != "" {
+			return m.Category
+		}
+		return UNCATEGORIZED
+	})
+
+	total := utils.SumBy(postings, func(p posting.Posting) decimal.Decimal { return p.MarketAmount })
+	categories := []CategoryAllocation{}
+	for _, category := range utils.SortedKeys(byCategory) {
+		marketAmount := utils.SumBy(byCategory[category], func(p posting.Posting) decimal.Decimal { return p.MarketAmount })
+		if marketAmount.Abs().LessThan(decimal.NewFromFloat(0.01)) {
+			continue
+		}
+
+		percentage := decimal.Zero
+		if !total.IsZero() {
+			percentage = marketAmount.Div(total).Mul(decimal.NewFromInt(100))
+		}
+		categories = append(categories, CategoryAllocation{Category: category, MarketAmount: marketAmount, Percentage: percentage})
+	}
+	return categories
 }
 
 func computeAggregateTimeline(db *gorm.DB, postings []posting.Posting) []map[string]Aggregate {
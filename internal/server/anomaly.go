@@ -0,0 +1,24 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AnomalyRequest struct {
+	Days int `form:"days"`
+}
+
+// GetAnomalies lists the unusual expenses of the last few days, 90 by
+// default.
+func GetAnomalies(db *gorm.DB, request AnomalyRequest) gin.H {
+	days := request.Days
+	if days <= 0 {
+		days = 90
+	}
+
+	since := utils.EndOfDay(utils.Now().AddDate(0, 0, -days))
+	return gin.H{"anomalies": service.DetectAnomalies(db, since)}
+}
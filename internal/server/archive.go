@@ -0,0 +1,56 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/archive"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// DownloadArchive streams the archive to the client. The error can
+// only be reported if nothing is written yet, the download is cut
+// short otherwise.
+func DownloadArchive(c *gin.Context, db *gorm.DB) {
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=paisa-%s.tar.gz", time.Now().Format("2006-01-02-15-04-05")))
+	c.Status(http.StatusOK)
+
+	_, err := archive.Create(db, c.Writer)
+	if err != nil {
+		log.Warn(err)
+		if !c.Writer.Written() {
+			c.Header("Content-Disposition", "")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create archive"})
+			return
+		}
+		c.Abort()
+	}
+}
+
+// RestoreArchive restores the uploaded archive. The database in the
+// archive is not restored as it's in use, the journal is synced
+// instead.
+func RestoreArchive(c *gin.Context, db *gorm.DB) gin.H {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+	defer file.Close()
+
+	_, err = archive.Restore(db, file, archive.RestoreOptions{Force: c.PostForm("force") == "true"})
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	return Sync(db, SyncRequest{Journal: true})
+}
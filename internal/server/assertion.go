@@ -0,0 +1,60 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/model/assertion"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type AssertionResult struct {
+	assertion.Assertion
+	Actual     decimal.Decimal `json:"actual"`
+	Difference decimal.Decimal `json:"difference"`
+	Passed     bool            `json:"passed"`
+}
+
+func GetAssertions(db *gorm.DB) gin.H {
+	results := lo.Map(assertion.All(db), func(a assertion.Assertion, _ int) AssertionResult {
+		return checkAssertion(db, a)
+	})
+
+	failed := lo.CountBy(results, func(r AssertionResult) bool { return !r.Passed })
+	return gin.H{"assertions": results, "passed": len(results) - failed, "failed": failed}
+}
+
+func checkAssertion(db *gorm.DB, a assertion.Assertion) AssertionResult {
+	q := query.Init(db).Where("commodity = ?", a.Commodity)
+	if a.SubAccounts {
+		q = q.AccountPrefix(a.Account)
+	} else {
+		q = q.Where("account = ?", a.Account)
+	}
+
+	if a.StartOfDay {
+		q = q.Where("date < ?", a.Date)
+	} else {
+		// the transactions of the same day that come after the
+		// assertion in the same file are not considered
+		q = q.Where("date <= ?", a.Date).
+			Where("date < ? or file_name != ? or transaction_begin_line < ?", a.Date, a.FileName, a.Line)
+	}
+
+	actual := lo.Reduce(q.All(), func(sum decimal.Decimal, p posting.Posting, _ int) decimal.Decimal {
+		return sum.Add(p.Quantity)
+	}, decimal.Zero)
+
+	// the tolerance is half of the last digit of the asserted amount,
+	// same as beancount
+	tolerance := decimal.New(5, a.Quantity.Exponent()-1)
+	difference := actual.Sub(a.Quantity)
+	return AssertionResult{
+		Assertion:  a,
+		Actual:     actual,
+		Difference: difference,
+		Passed:     difference.Abs().LessThanOrEqual(tolerance),
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/shopspring/decimal"
 
 	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/commoditymetadata"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
 	"github.com/ananthakumaran/paisa/internal/service"
@@ -16,32 +18,63 @@ import (
 )
 
 type AssetBreakdown struct {
-	Group            string          `json:"group"`
-	InvestmentAmount decimal.Decimal `json:"investmentAmount"`
-	WithdrawalAmount decimal.Decimal `json:"withdrawalAmount"`
-	MarketAmount     decimal.Decimal `json:"marketAmount"`
-	BalanceUnits     decimal.Decimal `json:"balanceUnits"`
-	LatestPrice      decimal.Decimal `json:"latestPrice"`
-	XIRR             decimal.Decimal `json:"xirr"`
-	GainAmount       decimal.Decimal `json:"gainAmount"`
-	AbsoluteReturn   decimal.Decimal `json:"absoluteReturn"`
+	Group            string                      `json:"group"`
+	InvestmentAmount decimal.Decimal             `json:"investmentAmount"`
+	WithdrawalAmount decimal.Decimal             `json:"withdrawalAmount"`
+	MarketAmount     decimal.Decimal             `json:"marketAmount"`
+	BalanceUnits     decimal.Decimal             `json:"balanceUnits"`
+	LatestPrice      decimal.Decimal             `json:"latestPrice"`
+	XIRR             decimal.Decimal             `json:"xirr"`
+	GainAmount       decimal.Decimal             `json:"gainAmount"`
+	AbsoluteReturn   decimal.Decimal             `json:"absoluteReturn"`
+	Risk             *Risk                       `json:"risk,omitempty"`
+	Metadata         *commoditymetadata.Metadata `json:"metadata,omitempty"`
 }
 
 func GetCheckingBalance(db *gorm.DB) gin.H {
-	return doGetBalance(db, "Assets:Checking:%", false)
+	patterns := lo.Map(config.CheckingAccounts(), func(account string, _ int) string { return account + ":%" })
+	return doGetBalance(db, patterns, false, false)
 }
 
-func GetBalance(db *gorm.DB) gin.H {
-	return doGetBalance(db, "Assets:%", true)
+func GetBalance(db *gorm.DB, live bool) gin.H {
+	return doGetBalance(db, []string{"Assets:%"}, true, live)
 }
 
-func doGetBalance(db *gorm.DB, pattern string, rollup bool) gin.H {
-	postings := query.Init(db).Like(pattern, "Income:CapitalGains:%").All()
+func doGetBalance(db *gorm.DB, patterns []string, rollup bool, live bool) gin.H {
+	postings := query.Init(db).Like(append(patterns, config.CapitalGainsAccount()+":%")...).All()
 	postings = service.PopulateMarketPrice(db, postings)
+	if live {
+		postings = service.PopulateLiveMarketPrice(postings)
+	}
 	breakdowns := ComputeBreakdowns(db, postings, rollup)
+	metadata := commoditymetadata.ByCommodities(db)
+	for group, breakdown := range breakdowns {
+		ps := groupPostings(postings, group)
+		risk := ComputeRisk(db, ps)
+		breakdown.Risk = &risk
+		breakdown.Metadata = commodityMetadata(ps, metadata)
+		breakdowns[group] = breakdown
+	}
 	return gin.H{"asset_breakdowns": breakdowns}
 }
 
+// commodityMetadata returns the metadata of the commodity when the
+// postings hold a single one.
+func commodityMetadata(ps []posting.Posting, metadata map[string]commoditymetadata.Metadata) *commoditymetadata.Metadata {
+	commodities := lo.Uniq(lo.FilterMap(ps, func(p posting.Posting, _ int) (string, bool) {
+		return p.Commodity, !utils.IsCurrency(p.Commodity)
+	}))
+	if len(commodities) != 1 {
+		return nil
+	}
+
+	m, ok := metadata[commodities[0]]
+	if !ok {
+		return nil
+	}
+	return &m
+}
+
 func ComputeBreakdowns(db *gorm.DB, postings []posting.Posting, rollup bool) map[string]AssetBreakdown {
 	accounts := make(map[string]bool)
 	for _, p := range postings {
@@ -63,19 +96,22 @@ func ComputeBreakdowns(db *gorm.DB, postings []posting.Posting, rollup bool) map
 	result := make(map[string]AssetBreakdown)
 
 	for group, leaf := range accounts {
-		ps := lo.Filter(postings, func(p posting.Posting, _ int) bool {
-			account := p.Account
-			if service.IsCapitalGains(p) {
-				account = service.CapitalGainsSourceAccount(p.Account)
-			}
-			return utils.IsSameOrParent(account, group)
-		})
-		result[group] = ComputeBreakdown(db, ps, leaf, group)
+		result[group] = ComputeBreakdown(db, groupPostings(postings, group), leaf, group)
 	}
 
 	return result
 }
 
+func groupPostings(postings []posting.Posting, group string) []posting.Posting {
+	return lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		account := p.Account
+		if service.IsCapitalGains(p) {
+			account = service.CapitalGainsSourceAccount(p.Account)
+		}
+		return utils.IsSameOrParent(account, group)
+	})
+}
+
 func ComputeBreakdown(db *gorm.DB, ps []posting.Posting, leaf bool, group string) AssetBreakdown {
 	investmentAmount := lo.Reduce(ps, func(acc decimal.Decimal, p posting.Posting, _ int) decimal.Decimal {
 		if utils.IsCheckingAccount(p.Account) || p.Amount.LessThan(decimal.Zero) || service.IsInterest(db, p) || service.IsStockSplit(db, p) || service.IsCapitalGains(p) {
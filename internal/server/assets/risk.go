@@ -0,0 +1,120 @@
+package assets
+
+import (
+	"math"
+
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// number of daily returns used for the volatility
+const RISK_WINDOW = 365
+
+type Risk struct {
+	// largest fall in percentage from a peak
+	MaxDrawdown decimal.Decimal `json:"maxDrawdown"`
+	// annualized standard deviation of the daily returns over the
+	// last year, in percentage
+	Volatility decimal.Decimal `json:"volatility"`
+	// annualized return over the volatility of the last year, the risk
+	// free rate is taken as zero
+	Sharpe decimal.Decimal `json:"sharpe"`
+}
+
+// ComputeRisk measures the risk from the daily market value of the
+// postings. Investments and withdrawals are not returns, so the daily
+// returns are time weighted, which also makes the drawdown reflect
+// the fall in price instead of the money taken out.
+func ComputeRisk(db *gorm.DB, postings []posting.Posting) Risk {
+	returns := dailyReturns(db, postings)
+	risk := Risk{MaxDrawdown: decimal.Zero, Volatility: decimal.Zero, Sharpe: decimal.Zero}
+	if len(returns) == 0 {
+		return risk
+	}
+
+	index, peak, drawdown := 1.0, 1.0, 0.0
+	for _, r := range returns {
+		index = index * (1 + r)
+		peak = math.Max(peak, index)
+		drawdown = math.Max(drawdown, (peak-index)/peak)
+	}
+	risk.MaxDrawdown = decimal.NewFromFloat(drawdown * 100).Round(2)
+
+	if len(returns) > RISK_WINDOW {
+		returns = returns[len(returns)-RISK_WINDOW:]
+	}
+
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean = mean / float64(len(returns))
+
+	variance := 0.0
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance = variance / float64(len(returns))
+
+	volatility := math.Sqrt(variance) * math.Sqrt(365)
+	risk.Volatility = decimal.NewFromFloat(volatility * 100).Round(2)
+	if volatility > 0 {
+		risk.Sharpe = decimal.NewFromFloat(mean * 365 / volatility).Round(2)
+	}
+	return risk
+}
+
+func dailyReturns(db *gorm.DB, postings []posting.Posting) []float64 {
+	returns := []float64{}
+	if len(postings) == 0 {
+		return returns
+	}
+
+	units := make(map[string]decimal.Decimal)
+	balances := make(map[string]decimal.Decimal)
+	previous := decimal.Zero
+
+	end := utils.EndOfToday()
+	for start := postings[0].Date; start.Before(end); start = start.AddDate(0, 0, 1) {
+		flow := decimal.Zero
+		for len(postings) > 0 && !postings[0].Date.After(start) {
+			p := postings[0]
+			postings = postings[1:]
+			if service.IsCapitalGains(p) {
+				continue
+			}
+
+			units[p.Commodity] = units[p.Commodity].Add(p.Quantity)
+			balances[p.Commodity] = balances[p.Commodity].Add(p.Amount)
+			if !service.IsInterest(db, p) {
+				flow = flow.Add(p.Amount)
+			}
+		}
+
+		value := decimal.Zero
+		for commodity, balance := range balances {
+			if utils.IsCurrency(commodity) {
+				value = value.Add(balance)
+				continue
+			}
+
+			price := service.GetUnitPrice(db, commodity, start)
+			if price.Value.IsZero() {
+				value = value.Add(balance)
+			} else {
+				value = value.Add(units[commodity].Mul(price.Value))
+			}
+		}
+
+		if previous.IsPositive() {
+			r, _ := value.Sub(flow).Div(previous).Sub(decimal.NewFromInt(1)).Float64()
+			returns = append(returns, r)
+		}
+		previous = value
+	}
+
+	return returns
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/ananthakumaran/paisa/internal/attachment"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+)
+
+// INLINE_ATTACHMENT_TYPES are shown in the browser, the rest are
+// always downloaded. Types like html and svg can run scripts, so they
+// are never served inline.
+var INLINE_ATTACHMENT_TYPES = []string{"image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf"}
+
+type AttachmentRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func GetAttachments() gin.H {
+	storage, err := attachment.GetStorage()
+	if err != nil {
+		return gin.H{"attachments": []string{}, "message": err.Error()}
+	}
+
+	names, err := storage.List()
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"attachments": []string{}, "message": "Failed to list attachments"}
+	}
+
+	return gin.H{"attachments": names}
+}
+
+func UploadAttachment(c *gin.Context) gin.H {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	storage, err := attachment.GetStorage()
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	name := attachment.Name(header.Filename, content)
+	err = storage.Put(name, content)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": "Failed to save attachment"}
+	}
+
+	return gin.H{"saved": true, "name": name}
+}
+
+func DownloadAttachment(c *gin.Context, name string) {
+	if err := attachment.ValidateName(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	storage, err := attachment.GetStorage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, err := storage.Get(name)
+	if errors.Is(err, attachment.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Warn(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read attachment"})
+		return
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(content)
+	}
+
+	disposition := "inline"
+	if mediaType, _, _ := mime.ParseMediaType(contentType); !lo.Contains(INLINE_ATTACHMENT_TYPES, mediaType) {
+		disposition = "attachment"
+		contentType = "application/octet-stream"
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, contentType, content)
+}
+
+func DeleteAttachment(request AttachmentRequest) gin.H {
+	if err := attachment.ValidateName(request.Name); err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	storage, err := attachment.GetStorage()
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	err = storage.Delete(request.Name)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	return gin.H{"success": true}
+}
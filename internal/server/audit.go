@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const AUDIT_BEFORE_KEY = "audit_before"
+
+// payloads larger than this are truncated in the audit log
+const MAX_AUDIT_PAYLOAD = 64 * 1024
+
+const MAX_AUDIT_LIMIT = 1000
+
+// POST endpoints that only read
+var auditSkippedPaths = []string{
+	LOGOUT_PATH,
+	"/api/what_if",
+	"/api/price/autocomplete",
+	"/api/backups/diff",
+	"/api/config/validate",
+	"/api/editor/file",
+	"/api/editor/validate",
+	"/api/sheets/file",
+}
+
+// payloads of these endpoints hold credentials, which are redacted
+// before recording
+var auditRedactors = map[string]func([]byte) []byte{
+	"/api/config": config.RedactContent,
+}
+
+type AuditRequest struct {
+	User   string     `form:"user"`
+	Path   string     `form:"path"`
+	From   *time.Time `form:"from" time_format:"2006-01-02"`
+	To     *time.Time `form:"to" time_format:"2006-01-02"`
+	Limit  int        `form:"limit"`
+	Offset int        `form:"offset"`
+}
+
+type auditResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	if w.body.Len() < MAX_AUDIT_PAYLOAD {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *auditResponseWriter) WriteString(s string) (int, error) {
+	if w.body.Len() < MAX_AUDIT_PAYLOAD {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// AuditMiddleware records every mutating api call in the audit log of
+// the main database, irrespective of the profile or sandbox the call
+// was made against. Failing to record the entry doesn't fail the
+// call.
+func AuditMiddleware(db *gorm.DB) gin.HandlerFunc {
+	err := db.AutoMigrate(&audit.Entry{})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions ||
+			!strings.HasPrefix(path, "/api") || lo.Contains(auditSkippedPaths, path) {
+			c.Next()
+			return
+		}
+
+		var after string
+		if c.Request.Body != nil {
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			if redact, ok := auditRedactors[path]; ok {
+				body = redact(body)
+			}
+			after = auditPayload(c.ContentType(), body)
+		}
+
+		writer := &auditResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		entry := audit.Entry{
+			User:     c.GetString(USER_KEY),
+			Method:   c.Request.Method,
+			Path:     path,
+			Profile:  c.GetHeader(PROFILE_HEADER),
+			Status:   writer.Status(),
+			After:    after,
+			Response: auditPayload(writer.Header().Get("Content-Type"), writer.body.Bytes()),
+		}
+		if before, ok := c.Get(AUDIT_BEFORE_KEY); ok {
+			entry.Before = auditValue(before)
+		}
+
+		err := audit.Record(db, &entry)
+		if err != nil {
+			log.Error("Failed to record the audit log: ", err)
+		}
+	}
+}
+
+// auditBefore sets the state the call is about to replace.
+func auditBefore(c *gin.Context, value any) {
+	c.Set(AUDIT_BEFORE_KEY, value)
+}
+
+func GetAuditLog(db *gorm.DB, request AuditRequest) gin.H {
+	limit := request.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	if limit > MAX_AUDIT_LIMIT {
+		limit = MAX_AUDIT_LIMIT
+	}
+
+	filter := audit.Filter{User: request.User, Path: request.Path, From: request.From, Limit: limit, Offset: request.Offset}
+	if request.To != nil {
+		to := request.To.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+
+	entries, total, err := audit.Query(db, filter)
+	if err != nil {
+		return gin.H{"entries": []audit.Entry{}, "total": 0, "error": err.Error()}
+	}
+	return gin.H{"entries": entries, "total": total}
+}
+
+// auditPayload keeps the textual payloads, the binary ones like file
+// uploads are replaced with a short description.
+func auditPayload(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+
+	if strings.HasPrefix(contentType, "multipart/") || !utf8.Valid(body) {
+		return fmt.Sprintf("<%s, %d bytes>", contentType, len(body))
+	}
+
+	if len(body) > MAX_AUDIT_PAYLOAD {
+		return string(body[:MAX_AUDIT_PAYLOAD]) + "…"
+	}
+	return string(body)
+}
+
+func auditValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return auditPayload("text/plain", []byte(v))
+	case []byte:
+		return auditPayload("text/plain", v)
+	default:
+		content, err := json.Marshal(v)
+		if err != nil {
+			return err.Error()
+		}
+		return auditPayload("application/json", content)
+	}
+}
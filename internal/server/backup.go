@@ -0,0 +1,109 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/backup"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type RestoreBackupRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+type BackupsRequest struct {
+	File string `form:"file"`
+}
+
+type BackupDiffRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+type BackupChange struct {
+	FileName string `json:"file_name"`
+	Diff     string `json:"diff"`
+}
+
+// GetBackups lists the backups, newest first. With a file, only the
+// backups that contain the journal file are listed, which is the edit
+// history of the file.
+func GetBackups(db *gorm.DB, request BackupsRequest) gin.H {
+	backups, err := backup.List()
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"backups": []backup.Backup{}}
+	}
+
+	if request.File != "" {
+		path, err := journal.Path(db, request.File)
+		if err != nil {
+			return gin.H{"backups": []backup.Backup{}, "message": err.Error()}
+		}
+		backups = lo.Filter(backups, func(b backup.Backup, _ int) bool {
+			return lo.ContainsBy(b.Files, func(f backup.File) bool { return f.Path == path })
+		})
+	}
+
+	return gin.H{"backups": backups}
+}
+
+// GetBackupDiff returns the changes that a restore of the backup would
+// make to the current files.
+func GetBackupDiff(db *gorm.DB, request BackupDiffRequest) gin.H {
+	b, contents, err := backup.Get(request.ID)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"changes": []BackupChange{}, "message": err.Error()}
+	}
+
+	changes := []BackupChange{}
+	for i, file := range b.Files {
+		current, err := os.ReadFile(file.Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn(err)
+			return gin.H{"changes": []BackupChange{}, "message": err.Error()}
+		}
+
+		name := file.Path
+		if rel, err := filepath.Rel(journal.Dir(db), file.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+
+		content := contents[i]
+		if file.Path == config.GetConfigPath() {
+			// the diff is shown in the browser, like the config
+			current = config.RedactContent(current)
+			content = config.RedactContent(content)
+		}
+
+		diff := journal.Diff(name, string(current), string(content))
+		if diff != "" {
+			changes = append(changes, BackupChange{FileName: name, Diff: diff})
+		}
+	}
+
+	return gin.H{"backup": b, "changes": changes}
+}
+
+func RestoreBackup(db *gorm.DB, request RestoreBackupRequest) gin.H {
+	b, err := backup.Restore(request.ID)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	configPath := config.GetConfigPath()
+	if lo.ContainsBy(b.Files, func(f backup.File) bool { return f.Path == configPath }) {
+		config.LoadConfigFile(configPath)
+	}
+
+	return Sync(db, SyncRequest{Journal: true})
+}
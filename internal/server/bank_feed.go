@@ -0,0 +1,238 @@
+package server
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/bankfeed"
+	"github.com/ananthakumaran/paisa/internal/bankfeed/gocardless"
+	"github.com/ananthakumaran/paisa/internal/bankfeed/plaid"
+	"github.com/ananthakumaran/paisa/internal/bankfeed/simplefin"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/bankconnection"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const GOCARDLESS_CALLBACK_PATH = "/api/bank_feeds/gocardless/callback"
+const PLAID_WEBHOOK_PATH = "/api/bank_feeds/plaid/webhook"
+
+type BankAccountSummary struct {
+	bankconnection.Account
+	LedgerAccount string          `json:"ledger_account"`
+	LedgerBalance decimal.Decimal `json:"ledger_balance"`
+}
+
+type BankConnectionSummary struct {
+	bankconnection.Connection
+	Accounts []BankAccountSummary `json:"accounts"`
+}
+
+type BankConnectionRequest struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+type GoCardlessLinkRequest struct {
+	InstitutionID string `json:"institution_id" binding:"required"`
+}
+
+type SimpleFINLinkRequest struct {
+	SetupToken string `json:"setup_token" binding:"required"`
+}
+
+type PlaidExchangeRequest struct {
+	PublicToken string `json:"public_token" binding:"required"`
+	Institution string `json:"institution"`
+}
+
+var bankFeedsMu sync.Mutex
+
+// bankFeedProviders returns the providers with credentials configured.
+// SimpleFIN keeps the credentials with the connection instead.
+func bankFeedProviders() []bankfeed.Provider {
+	providers := []bankfeed.Provider{simplefin.New()}
+	if p := gocardless.New(); p.Configured() {
+		providers = append(providers, p)
+	}
+	if p := plaid.New(); p.Configured() {
+		providers = append(providers, p)
+	}
+	return providers
+}
+
+// GetBankFeeds lists the connections along with the balance reported
+// by the bank and the balance of the journal account it maps to, so a
+// difference stands out.
+func GetBankFeeds(db *gorm.DB) gin.H {
+	connections := []BankConnectionSummary{}
+	for _, c := range bankconnection.All(db) {
+		accounts := []BankAccountSummary{}
+		for _, a := range bankconnection.Accounts(db, c.ID) {
+			summary := BankAccountSummary{Account: a, LedgerAccount: bankfeed.LedgerAccount(c.Provider, a)}
+			if summary.LedgerAccount != bankfeed.UNKNOWN_ACCOUNT {
+				summary.LedgerBalance = accounting.CostSum(query.Init(db).Where("account = ?", summary.LedgerAccount).UntilToday().All())
+			}
+			accounts = append(accounts, summary)
+		}
+		connections = append(connections, BankConnectionSummary{Connection: c, Accounts: accounts})
+	}
+
+	return gin.H{"connections": connections}
+}
+
+func GetGoCardlessInstitutions(country string) gin.H {
+	institutions, err := gocardless.New().Institutions(country)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+	return gin.H{"success": true, "institutions": institutions}
+}
+
+// LinkGoCardless starts the consent flow, the bank redirects back to
+// the callback once the user is done.
+func LinkGoCardless(c *gin.Context, request GoCardlessLinkRequest) gin.H {
+	redirect := config.GetConfig().BankFeeds.GoCardless.RedirectURL
+	if redirect == "" {
+		redirect = externalURL(c, GOCARDLESS_CALLBACK_PATH)
+	}
+
+	link, err := gocardless.New().Link(requestDB(c), request.InstitutionID, redirect)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+	return gin.H{"success": true, "link": link}
+}
+
+// GoCardlessCallback is where the bank sends the user back to. It's
+// reached without the credentials of the user, the unguessable
+// reference of a pending connection is what identifies the request.
+func GoCardlessCallback(c *gin.Context) {
+	_, err := gocardless.New().Complete(requestDB(c), c.Query("ref"))
+	if err != nil {
+		log.Warn(err)
+		c.String(http.StatusBadRequest, err.Error())
+		return
+	}
+	c.Redirect(http.StatusFound, "/")
+}
+
+func CreatePlaidLinkToken(c *gin.Context) gin.H {
+	webhook := config.GetConfig().BankFeeds.Plaid.WebhookURL
+	if webhook == "" {
+		webhook = externalURL(c, PLAID_WEBHOOK_PATH)
+	}
+
+	token, err := plaid.New().LinkToken(webhook)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+	return gin.H{"success": true, "link_token": token}
+}
+
+// ExchangePlaidToken completes the link and pulls the transactions
+// right away, the history is usually ready by the time the user is
+// done with Plaid Link.
+func ExchangePlaidToken(db *gorm.DB, request PlaidExchangeRequest) gin.H {
+	provider := plaid.New()
+	connection, err := provider.Exchange(db, request.PublicToken, request.Institution)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	go syncConnection(db, provider, connection)
+	return gin.H{"success": true, "connection": connection}
+}
+
+// PlaidWebhook is called by Plaid without the credentials of the
+// user, the signature of the body is verified instead.
+func PlaidWebhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	provider := plaid.New()
+	webhook, err := provider.VerifyWebhook(c.GetHeader(plaid.VERIFICATION_HEADER), body)
+	if err != nil {
+		log.Warnf("Rejected Plaid webhook: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := requestDB(c)
+	connection, found := bankconnection.ByReference(db, plaid.NAME, webhook.ItemID)
+	if !found {
+		c.JSON(200, gin.H{"success": true})
+		return
+	}
+
+	if webhook.NeedsRelink() {
+		connection.Status = bankconnection.Expired
+		bankconnection.Save(db, &connection)
+	} else if webhook.NeedsSync() && connection.Status == bankconnection.Linked && !config.GetConfig().Readonly {
+		go syncConnection(db, provider, connection)
+	}
+	c.JSON(200, gin.H{"success": true})
+}
+
+func syncConnection(db *gorm.DB, provider bankfeed.Provider, connection bankconnection.Connection) {
+	bankFeedsMu.Lock()
+	defer bankFeedsMu.Unlock()
+
+	created, err := bankfeed.SyncConnection(db, provider, connection)
+	if err != nil {
+		log.Warnf("Bank feeds sync failed: %v", err)
+		return
+	}
+	log.Infof("Imported %d drafts from %s", created, connection.Institution)
+}
+
+// LinkSimpleFIN claims the setup token and pulls the transactions
+// right away.
+func LinkSimpleFIN(db *gorm.DB, request SimpleFINLinkRequest) gin.H {
+	provider := simplefin.New()
+	connection, err := provider.Link(db, request.SetupToken)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	go syncConnection(db, provider, connection)
+	return gin.H{"success": true, "connection": connection}
+}
+
+// externalURL is the address of the path as seen by the browser.
+func externalURL(c *gin.Context, path string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return (&url.URL{Scheme: scheme, Host: c.Request.Host, Path: path}).String()
+}
+
+func DeleteBankConnection(db *gorm.DB, request BankConnectionRequest) gin.H {
+	bankconnection.Delete(db, request.ID)
+	return gin.H{"success": true}
+}
+
+// SyncBankFeeds pulls the linked accounts into the review queue, one
+// run at a time.
+func SyncBankFeeds(db *gorm.DB) gin.H {
+	if !bankFeedsMu.TryLock() {
+		return gin.H{"success": false, "message": "Bank feeds sync is already running"}
+	}
+	defer bankFeedsMu.Unlock()
+
+	created, err := bankfeed.Sync(db, bankFeedProviders())
+	if err != nil {
+		log.Warnf("Bank feeds sync failed: %v", err)
+		return gin.H{"success": false, "message": err.Error(), "created": created}
+	}
+	return gin.H{"success": true, "created": created}
+}
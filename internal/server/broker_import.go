@@ -0,0 +1,72 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ananthakumaran/paisa/internal/brokerimport"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/draft"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type BrokerImportRequest struct {
+	Broker  string `json:"broker" binding:"required"`
+	Content string `json:"content" binding:"required"`
+	DryRun  bool   `json:"dry_run"`
+}
+
+// ImportBrokerStatement turns the uploaded trade file into drafts,
+// with dry run the drafts are only returned for preview.
+func ImportBrokerStatement(db *gorm.DB, request BrokerImportRequest) gin.H {
+	broker, found := lo.Find(config.GetConfig().BrokerImports, func(b config.BrokerImport) bool { return b.Broker == request.Broker })
+	if !found {
+		return gin.H{"success": false, "message": fmt.Sprintf("Broker %s is not configured", request.Broker)}
+	}
+
+	statement, err := brokerimport.Parse(broker.Broker, request.Content)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	drafts := brokerimport.Drafts(db, broker, statement)
+	if !request.DryRun {
+		for i := range drafts {
+			draft.Create(db, &drafts[i])
+		}
+	}
+	return gin.H{"success": true, "drafts": drafts, "created": lo.Ternary(request.DryRun, 0, len(drafts))}
+}
+
+// SyncBrokers runs the Flex Queries of the configured Interactive
+// Brokers accounts.
+func SyncBrokers(db *gorm.DB) gin.H {
+	created := 0
+	var errs []error
+	for _, broker := range config.GetConfig().BrokerImports {
+		if broker.Broker != brokerimport.IBKR || broker.FlexToken == "" {
+			continue
+		}
+
+		statement, err := brokerimport.FetchFlexStatement(broker.FlexToken, broker.FlexQueryID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		drafts := brokerimport.Drafts(db, broker, statement)
+		for i := range drafts {
+			draft.Create(db, &drafts[i])
+		}
+		created += len(drafts)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		log.Warnf("Broker sync failed: %v", err)
+		return gin.H{"success": false, "message": err.Error(), "created": created}
+	}
+	return gin.H{"success": true, "created": created}
+}
@@ -12,17 +12,35 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
 	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 type AccountBudget struct {
-	Account   string            `json:"account"`
-	Forecast  decimal.Decimal   `json:"forecast"`
-	Actual    decimal.Decimal   `json:"actual"`
-	Rollover  decimal.Decimal   `json:"rollover"`
-	Available decimal.Decimal   `json:"available"`
-	Date      time.Time         `json:"date"`
-	Expenses  []posting.Posting `json:"expenses"`
+	Account  string          `json:"account"`
+	Forecast decimal.Decimal `json:"forecast"`
+	Actual   decimal.Decimal `json:"actual"`
+	Rollover decimal.Decimal `json:"rollover"`
+	// net amount moved in from the other accounts, negative when moved
+	// out
+	Transferred decimal.Decimal      `json:"transferred"`
+	Available   decimal.Decimal      `json:"available"`
+	Date        time.Time            `json:"date"`
+	Expenses    []posting.Posting    `json:"expenses"`
+	SinkingFund *SinkingFundProgress `json:"sinkingFund,omitempty"`
+	// set for the accounts budgeted other than monthly
+	Period *BudgetPeriod `json:"period,omitempty"`
+}
+
+type SinkingFundProgress struct {
+	Target decimal.Decimal `json:"target"`
+	Date   time.Time       `json:"date"`
+	Saved  decimal.Decimal `json:"saved"`
+	// percentage of the target saved
+	Progress decimal.Decimal `json:"progress"`
+	// amount to be set aside every month from this month till the
+	// month of the date to reach the target
+	RequiredMonthly decimal.Decimal `json:"requiredMonthly"`
 }
 
 type Budget struct {
@@ -46,17 +64,38 @@ func GetCurrentBudget(db *gorm.DB) gin.H {
 }
 
 func computeBudet(db *gorm.DB, forecastPostings, expensesPostings []posting.Posting) gin.H {
-	checkingBalance := accounting.CostSum(query.Init(db).AccountPrefix("Assets:Checking").All())
+	checkingBalance := accounting.CostSum(query.Init(db).AccountPrefix(config.CheckingAccounts()...).All())
 	availableForBudgeting := checkingBalance
 
 	forecasts := utils.GroupByMonth(forecastPostings)
 	expenses := utils.GroupByMonth(expensesPostings)
 
-	accounts := lo.Uniq(lo.Map(forecastPostings, func(p posting.Posting, _ int) string {
+	accounts := lo.Uniq(append(lo.Map(forecastPostings, func(p posting.Posting, _ int) string {
 		return p.Account
-	}))
+	}), lo.Map(config.GetConfig().Budget.SinkingFunds, func(f config.SinkingFund, _ int) string {
+		return f.Account
+	})...))
 	sort.Strings(accounts)
 
+	// the accounts budgeted weekly, quarterly or yearly need the
+	// postings of the whole period, not just the month
+	periods := make(map[string]string)
+	periodForecasts := make(map[string][]posting.Posting)
+	periodExpenses := make(map[string][]posting.Posting)
+	for _, account := range accounts {
+		period := budgetPeriodOf(account)
+		if period == "monthly" {
+			continue
+		}
+		periods[account] = period
+		periodForecasts[account] = lo.Filter(forecastPostings, func(p posting.Posting, _ int) bool {
+			return p.Account == account && !isBudgetTransfer(p)
+		})
+		periodExpenses[account] = lo.Filter(expensesPostings, func(p posting.Posting, _ int) bool {
+			return utils.IsSameOrParent(p.Account, account)
+		})
+	}
+
 	budgetsByMonth := make(map[string]Budget)
 	balance := make(map[string]decimal.Decimal)
 
@@ -81,13 +120,23 @@ func computeBudet(db *gorm.DB, forecastPostings, expensesPostings []posting.Post
 			expensesByAccount := accounting.GroupByAccount(expensesByMonth)
 
 			for _, account := range accounts {
-				fs := forecastsByAccount[account]
+				fs := lo.Reject(forecastsByAccount[account], func(p posting.Posting, _ int) bool { return isBudgetTransfer(p) })
+				ts := lo.Filter(forecastsByAccount[account], func(p posting.Posting, _ int) bool { return isBudgetTransfer(p) })
 				es := popExpenses(account, expensesByAccount)
 				if !ok {
 					es = []posting.Posting{}
 				}
 
-				budget := buildBudget(date, account, balance[account], fs, es, date.Before(currentMonth))
+				forecast := accounting.CostSum(fs)
+				period, periodic := periods[account]
+				if periodic {
+					forecast = proratedForecast(period, date, periodForecasts[account])
+				}
+
+				budget := buildBudget(date, account, balance[account], forecast, ts, es, date.Before(currentMonth))
+				if periodic {
+					budget.Period = buildBudgetPeriod(period, date, periodForecasts[account], periodExpenses[account])
+				}
 				if budget.Available.IsPositive() {
 					balance[account] = budget.Available
 				} else {
@@ -133,28 +182,67 @@ func computeBudet(db *gorm.DB, forecastPostings, expensesPostings []posting.Post
 	}
 }
 
-func buildBudget(date time.Time, account string, balance decimal.Decimal, forecasts []posting.Posting, expenses []posting.Posting, past bool) AccountBudget {
-	forecast := accounting.CostSum(forecasts)
+func buildBudget(date time.Time, account string, balance decimal.Decimal, forecast decimal.Decimal, transfers []posting.Posting, expenses []posting.Posting, past bool) AccountBudget {
+	transferred := accounting.CostSum(transfers)
 	actual := accounting.CostSum(expenses)
 
 	rollover := decimal.Zero
-	available := forecast.Sub(actual)
+	available := forecast.Add(transferred).Sub(actual)
 	if past {
 		available = decimal.Zero
 	}
-	if config.GetConfig().Budget.Rollover == config.Yes {
+	fund, isSinkingFund := lo.Find(config.GetConfig().Budget.SinkingFunds, func(f config.SinkingFund) bool { return f.Account == account })
+	if config.GetConfig().Budget.Rollover == config.Yes || isSinkingFund {
 		rollover = balance
-		available = balance.Add(forecast.Sub(actual))
+		available = balance.Add(forecast.Add(transferred).Sub(actual))
+	}
+
+	budget := AccountBudget{
+		Account:     account,
+		Forecast:    forecast,
+		Actual:      actual,
+		Rollover:    rollover,
+		Transferred: transferred,
+		Available:   available,
+		Date:        date,
+		Expenses:    expenses,
+	}
+
+	if isSinkingFund {
+		budget.SinkingFund = buildSinkingFundProgress(fund, date, balance, available)
+	}
+	return budget
+}
+
+// buildSinkingFundProgress tracks the savings of the fund as of the
+// month. The required monthly amount is based on what was saved till
+// the previous month, so it doesn't shrink as the month gets funded.
+func buildSinkingFundProgress(fund config.SinkingFund, month time.Time, balance decimal.Decimal, available decimal.Decimal) *SinkingFundProgress {
+	date, err := time.ParseInLocation("2006-01-02", fund.Date, config.TimeZone())
+	if err != nil {
+		log.Warnf("Invalid date %s for the sinking fund %s", fund.Date, fund.Account)
+		return nil
+	}
+
+	target := decimal.NewFromFloat(fund.Target)
+	saved := decimal.Max(available, decimal.Zero)
+	progress := decimal.NewFromInt(100)
+	if target.IsPositive() {
+		progress = decimal.Min(saved.Div(target).Mul(decimal.NewFromInt(100)), progress).Round(2)
+	}
+
+	months := (date.Year()-month.Year())*12 + int(date.Month()-month.Month()) + 1
+	if months < 1 {
+		months = 1
 	}
+	remaining := decimal.Max(target.Sub(balance), decimal.Zero)
 
-	return AccountBudget{
-		Account:   account,
-		Forecast:  forecast,
-		Actual:    actual,
-		Rollover:  rollover,
-		Available: available,
-		Date:      date,
-		Expenses:  expenses,
+	return &SinkingFundProgress{
+		Target:          target,
+		Date:            date,
+		Saved:           saved,
+		Progress:        progress,
+		RequiredMonthly: remaining.Div(decimal.NewFromInt(int64(months))).Round(2),
 	}
 }
 
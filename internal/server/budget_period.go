@@ -0,0 +1,107 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+)
+
+type BudgetPeriod struct {
+	Period string    `json:"period"`
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	// forecast and actual of the whole period
+	Forecast decimal.Decimal `json:"forecast"`
+	Actual   decimal.Decimal `json:"actual"`
+	// share of the forecast for the time elapsed in the period
+	Expected decimal.Decimal `json:"expected"`
+	OnPace   bool            `json:"onPace"`
+}
+
+func budgetPeriodOf(account string) string {
+	override, found := lo.Find(config.GetConfig().Budget.Periods, func(p config.BudgetPeriod) bool { return p.Account == account })
+	if !found || override.Period == "" {
+		return "monthly"
+	}
+	return override.Period
+}
+
+// periodBounds returns the first and the last day of the period the
+// date falls in. The weeks start on Monday, the quarters and the years
+// follow the financial year.
+func periodBounds(period string, date time.Time) (time.Time, time.Time) {
+	day := lo.Must(time.ParseInLocation("2006-01-02", date.Format("2006-01-02"), config.TimeZone()))
+	switch period {
+	case "weekly":
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 6)
+	case "quarterly":
+		start := utils.BeginningOfFinancialYear(day)
+		for !start.AddDate(0, 3, 0).After(day) {
+			start = start.AddDate(0, 3, 0)
+		}
+		return start, start.AddDate(0, 3, -1)
+	case "yearly":
+		start := utils.BeginningOfFinancialYear(day)
+		return start, start.AddDate(1, 0, -1)
+	default:
+		start := utils.BeginningOfMonth(day)
+		return start, start.AddDate(0, 1, -1)
+	}
+}
+
+// daysIn counts the days from the start till the end, both included.
+func daysIn(start, end time.Time) int {
+	return int(end.Sub(start).Round(24*time.Hour).Hours()/24) + 1
+}
+
+// proratedForecast spreads the forecast of each period the month
+// overlaps with over the days of the period, and picks the share of
+// the days within the month. A yearly premium thus gets budgeted a
+// little every month instead of all at once in the month it's due.
+func proratedForecast(period string, month time.Time, forecasts []posting.Posting) decimal.Decimal {
+	monthStart, monthEnd := periodBounds("monthly", month)
+	total := decimal.Zero
+	for start, end := periodBounds(period, monthStart); !start.After(monthEnd); start, end = periodBounds(period, end.AddDate(0, 0, 1)) {
+		forecast := accounting.CostSum(filterBetween(forecasts, start, end))
+		overlap := daysIn(utils.MaxTime(start, monthStart), utils.MinTime(end, monthEnd))
+		total = total.Add(forecast.Mul(decimal.NewFromInt(int64(overlap))).Div(decimal.NewFromInt(int64(daysIn(start, end)))))
+	}
+	return total.Round(2)
+}
+
+// buildBudgetPeriod reports how the spending of the period the month
+// is in compares with the forecast. For the current month the period
+// is looked at as of today, the spending is on pace if it hasn't gone
+// over the share of the forecast for the days elapsed.
+func buildBudgetPeriod(period string, month time.Time, forecasts []posting.Posting, expenses []posting.Posting) *BudgetPeriod {
+	monthStart, monthEnd := periodBounds("monthly", month)
+	today := lo.Must(time.ParseInLocation("2006-01-02", utils.Now().Format("2006-01-02"), config.TimeZone()))
+	asOf := utils.MinTime(utils.MaxTime(today, monthStart), monthEnd)
+
+	start, end := periodBounds(period, asOf)
+	forecast := accounting.CostSum(filterBetween(forecasts, start, end))
+	actual := accounting.CostSum(filterBetween(expenses, start, asOf))
+	expected := forecast.Mul(decimal.NewFromInt(int64(daysIn(start, asOf)))).Div(decimal.NewFromInt(int64(daysIn(start, end)))).Round(2)
+
+	return &BudgetPeriod{
+		Period:   period,
+		Start:    start,
+		End:      end,
+		Forecast: forecast,
+		Actual:   actual,
+		Expected: expected,
+		OnPace:   actual.LessThanOrEqual(expected),
+	}
+}
+
+func filterBetween(postings []posting.Posting, start, end time.Time) []posting.Posting {
+	return lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		return !p.Date.Before(start) && !p.Date.After(utils.EndOfDay(end))
+	})
+}
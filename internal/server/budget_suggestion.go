@@ -0,0 +1,167 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var budgetSuggestionWindows = []int{3, 6, 12}
+
+type BudgetSuggestionRequest struct {
+	// number of trailing months the suggestion is based on, one of 3,
+	// 6 or 12
+	Months int `form:"months" json:"months"`
+}
+
+type BudgetSuggestion struct {
+	Account string `json:"account"`
+	// average monthly expense over the trailing 3, 6 and 12 months
+	Averages map[int]decimal.Decimal `json:"averages"`
+	// change in the monthly expense per month, fitted over the window
+	Trend     decimal.Decimal `json:"trend"`
+	Suggested decimal.Decimal `json:"suggested"`
+	// forecast of the current month, if any
+	Current decimal.Decimal `json:"current"`
+}
+
+type BudgetForecast struct {
+	Account string          `json:"account" binding:"required"`
+	Amount  decimal.Decimal `json:"amount"`
+}
+
+type SaveBudgetSuggestionRequest struct {
+	// first month of the forecast, defaults to the next month
+	Date           *time.Time       `json:"date"`
+	Forecasts      []BudgetForecast `json:"forecasts"`
+	FundingAccount string           `json:"funding_account"`
+	File           string           `json:"file"`
+	DryRun         bool             `json:"dry_run"`
+}
+
+// GetBudgetSuggestions proposes the monthly forecast of every expense
+// account from the complete months before the current one. The
+// average of the window is moved along the trend to the next month,
+// the trend being the slope of the least squares line fitted over the
+// monthly expenses of the window.
+func GetBudgetSuggestions(db *gorm.DB, request BudgetSuggestionRequest) gin.H {
+	window := request.Months
+	if !lo.Contains(budgetSuggestionWindows, window) {
+		window = 6
+	}
+
+	monthStart := utils.BeginningOfMonth(utils.Now())
+	longest := lo.Max(budgetSuggestionWindows)
+	start := monthStart.AddDate(0, -longest, 0)
+	expenses := utils.GroupByMonth(query.Init(db).Like("Expenses:%").Between(start, monthStart.AddDate(0, 0, -1)).All())
+	forecasts := accounting.GroupByAccount(query.Init(db).Like("Expenses:%").Forecast().Between(monthStart, utils.EndOfMonth(monthStart)).All())
+
+	// monthly expenses of each account, oldest first
+	monthly := make(map[string][]decimal.Decimal)
+	for i := 0; i < longest; i++ {
+		month := start.AddDate(0, i, 0).Format("2006-01")
+		byAccount := accounting.GroupByAccount(expenses[month])
+		for account := range byAccount {
+			if _, ok := monthly[account]; !ok {
+				monthly[account] = make([]decimal.Decimal, longest)
+			}
+		}
+		for account, amounts := range monthly {
+			amounts[i] = accounting.CostSum(byAccount[account])
+		}
+	}
+
+	suggestions := []BudgetSuggestion{}
+	for _, account := range utils.SortedKeys(monthly) {
+		amounts := monthly[account]
+		suggestion := BudgetSuggestion{Account: account, Averages: make(map[int]decimal.Decimal), Current: accounting.CostSum(forecasts[account])}
+		for _, w := range budgetSuggestionWindows {
+			suggestion.Averages[w] = utils.SumBy(amounts[longest-w:], func(d decimal.Decimal) decimal.Decimal { return d }).Div(decimal.NewFromInt(int64(w)))
+		}
+
+		recent := amounts[longest-window:]
+		suggestion.Trend = linearTrend(recent)
+		// the average sits at the middle of the window, the next month
+		// is (window + 1) / 2 months away from it
+		projected := suggestion.Averages[window].Add(suggestion.Trend.Mul(decimal.NewFromFloat(float64(window+1) / 2)))
+		suggestion.Suggested = decimal.Max(projected, decimal.Zero).Round(0)
+
+		if suggestion.Suggested.IsZero() && suggestion.Averages[longest].IsZero() {
+			continue
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	return gin.H{"months": window, "suggestions": suggestions}
+}
+
+// SaveBudgetSuggestions writes the forecasts as a monthly periodic
+// transaction, funded from the checking account by default.
+func SaveBudgetSuggestions(db *gorm.DB, request SaveBudgetSuggestionRequest) gin.H {
+	if config.LedgerCliFor(journal.JournalPath(db)) == "beancount" {
+		return gin.H{"saved": false, "message": "Periodic transactions are not supported by beancount"}
+	}
+
+	date := utils.BeginningOfMonth(utils.Now()).AddDate(0, 1, 0)
+	if request.Date != nil {
+		date = request.Date.In(config.TimeZone())
+	}
+
+	fundingAccount := request.FundingAccount
+	if fundingAccount == "" {
+		fundingAccount = config.CheckingAccounts()[0]
+	}
+
+	postings := []journal.Posting{}
+	for _, f := range request.Forecasts {
+		if f.Amount.IsNegative() {
+			return gin.H{"saved": false, "message": fmt.Sprintf("Forecast of %s can't be negative", f.Account)}
+		}
+		if f.Amount.IsZero() {
+			continue
+		}
+		postings = append(postings, journal.Posting{Account: f.Account, Amount: journal.FormatAmount(f.Amount, config.DefaultCurrency())})
+	}
+
+	if len(postings) == 0 {
+		return gin.H{"saved": false, "message": "At least one forecast is required"}
+	}
+	postings = append(postings, journal.Posting{Account: fundingAccount})
+
+	content := journal.PeriodicTransaction{Period: "Monthly from " + date.Format("2006/01/02"), Postings: postings}.Format()
+	if request.DryRun {
+		return gin.H{"saved": false, "content": content}
+	}
+
+	return appendToJournal(db, "budget suggestion", request.File, content)
+}
+
+// linearTrend is the slope of the least squares line fitted over the
+// values, which are a month apart.
+func linearTrend(values []decimal.Decimal) decimal.Decimal {
+	var sumX, sumY, sumXY, sumXX float64
+	n := float64(len(values))
+	for i, v := range values {
+		x := float64(i)
+		y := v.InexactFloat64()
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromFloat((n*sumXY - sumX*sumY) / denominator).Round(2)
+}
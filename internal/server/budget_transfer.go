@@ -0,0 +1,125 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const BUDGET_TRANSFER_TAG = "budget-transfer"
+
+type BudgetTransferRequest struct {
+	// month of the budget in the 2006-01 format, defaults to the
+	// current month
+	Month  string          `json:"month"`
+	From   string          `json:"from" binding:"required"`
+	To     string          `json:"to" binding:"required"`
+	Amount decimal.Decimal `json:"amount"`
+	File   string          `json:"file"`
+	DryRun bool            `json:"dry_run"`
+}
+
+type BudgetTransfer struct {
+	Date   time.Time       `json:"date"`
+	From   string          `json:"from"`
+	To     string          `json:"to"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+func isBudgetTransfer(p posting.Posting) bool {
+	return lo.Contains(p.Tags(), BUDGET_TRANSFER_TAG)
+}
+
+// CreateBudgetTransfer moves the available budget of a month from one
+// account to another. The transfer is a periodic transaction which
+// occurs only once, on the first of the month, so it shows up along
+// with the regular forecasts of the month.
+func CreateBudgetTransfer(db *gorm.DB, request BudgetTransferRequest) gin.H {
+	if config.LedgerCliFor(journal.JournalPath(db)) == "beancount" {
+		return gin.H{"saved": false, "message": "Periodic transactions are not supported by beancount"}
+	}
+
+	month := utils.BeginningOfMonth(utils.Now())
+	if request.Month != "" {
+		var err error
+		month, err = time.ParseInLocation("2006-01", request.Month, config.TimeZone())
+		if err != nil {
+			return gin.H{"saved": false, "message": fmt.Sprintf("Invalid month %s", request.Month)}
+		}
+	}
+
+	if !request.Amount.IsPositive() {
+		return gin.H{"saved": false, "message": "Amount should be positive"}
+	}
+	if request.From == request.To {
+		return gin.H{"saved": false, "message": "Can't transfer to the same account"}
+	}
+	for _, account := range []string{request.From, request.To} {
+		if !strings.HasPrefix(account, "Expenses:") {
+			return gin.H{"saved": false, "message": fmt.Sprintf("%s is not an expense account", account)}
+		}
+	}
+
+	note := "#" + BUDGET_TRANSFER_TAG
+	content := journal.PeriodicTransaction{
+		Period: fmt.Sprintf("Monthly from %s to %s", month.Format("2006/01/02"), month.AddDate(0, 1, 0).Format("2006/01/02")),
+		Postings: []journal.Posting{
+			{Account: request.From, Amount: journal.FormatAmount(request.Amount.Neg(), config.DefaultCurrency()), Note: note},
+			{Account: request.To, Amount: journal.FormatAmount(request.Amount, config.DefaultCurrency()), Note: note},
+		},
+	}.Format()
+
+	if request.DryRun {
+		return gin.H{"saved": false, "content": content}
+	}
+
+	return appendToJournal(db, "budget transfer", request.File, content)
+}
+
+// GetBudgetTransfers lists the transfers, latest first. The two sides
+// of a transfer are matched by the date and the amount as the
+// forecast postings of a date can't be told apart otherwise.
+func GetBudgetTransfers(db *gorm.DB) gin.H {
+	postings := lo.Filter(query.Init(db).Like("Expenses:%").Forecast().All(), func(p posting.Posting, _ int) bool {
+		return isBudgetTransfer(p)
+	})
+
+	transfers := []BudgetTransfer{}
+	byDate := lo.GroupBy(postings, func(p posting.Posting) time.Time { return p.Date })
+	for date, ps := range byDate {
+		sources := lo.Filter(ps, func(p posting.Posting, _ int) bool { return p.Amount.IsNegative() })
+		targets := lo.Reject(ps, func(p posting.Posting, _ int) bool { return p.Amount.IsNegative() })
+
+		for _, source := range sources {
+			_, index, found := lo.FindIndexOf(targets, func(p posting.Posting) bool {
+				return p.Amount.Equal(source.Amount.Neg())
+			})
+			if !found {
+				continue
+			}
+
+			transfers = append(transfers, BudgetTransfer{Date: date, From: source.Account, To: targets[index].Account, Amount: targets[index].Amount})
+			targets = append(targets[:index], targets[index+1:]...)
+		}
+	}
+
+	sort.SliceStable(transfers, func(i, j int) bool {
+		if transfers[i].Date.Equal(transfers[j].Date) {
+			return transfers[i].From < transfers[j].From
+		}
+		return transfers[i].Date.After(transfers[j].Date)
+	})
+
+	return gin.H{"transfers": transfers}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type BudgetVarianceRequest struct {
+	From string `form:"from"`
+	To   string `form:"to"`
+}
+
+type MonthlyVariance struct {
+	Date               time.Time       `json:"date"`
+	Forecast           decimal.Decimal `json:"forecast"`
+	Actual             decimal.Decimal `json:"actual"`
+	Variance           decimal.Decimal `json:"variance"`
+	CumulativeVariance decimal.Decimal `json:"cumulative_variance"`
+	// percentage of the forecast spent, nil when nothing was forecasted
+	Adherence *decimal.Decimal `json:"adherence"`
+}
+
+type AccountVariance struct {
+	Account            string            `json:"account"`
+	Forecast           decimal.Decimal   `json:"forecast"`
+	Actual             decimal.Decimal   `json:"actual"`
+	Variance           decimal.Decimal   `json:"variance"`
+	VariancePercentage decimal.Decimal   `json:"variance_percentage"`
+	Months             []MonthlyVariance `json:"months"`
+	// change in adherence per month, positive when the spending is
+	// moving over the forecast
+	Trend decimal.Decimal `json:"trend"`
+}
+
+// GetBudgetVariance compares the forecast with the actual expenses of
+// each budgeted account month by month. The variance is forecast minus
+// actual, so overspending shows up as negative. Defaults to the last 12
+// months.
+func GetBudgetVariance(db *gorm.DB, request BudgetVarianceRequest) (gin.H, error) {
+	now := utils.Now()
+	from := utils.BeginningOfMonth(now).AddDate(0, -11, 0)
+	to := utils.EndOfMonth(now)
+	var err error
+	if request.From != "" {
+		from, err = time.ParseInLocation("2006-01-02", request.From, config.TimeZone())
+		if err != nil {
+			return nil, err
+		}
+	}
+	if request.To != "" {
+		to, err = time.ParseInLocation("2006-01-02", request.To, config.TimeZone())
+		if err != nil {
+			return nil, err
+		}
+		to = utils.EndOfDay(to)
+	}
+
+	forecastPostings := query.Init(db).Like("Expenses:%").Forecast().Where("date >= ? and date <= ?", from, to).All()
+	expensePostings := query.Init(db).Like("Expenses:%").Where("date >= ? and date <= ?", from, to).All()
+
+	accounts := lo.Uniq(lo.Map(forecastPostings, func(p posting.Posting, _ int) string { return p.Account }))
+	sort.Strings(accounts)
+
+	forecasts := utils.GroupByMonth(forecastPostings)
+	expenses := utils.GroupByMonth(expensePostings)
+
+	variances := lo.Map(accounts, func(account string, _ int) AccountVariance {
+		return AccountVariance{Account: account, Months: []MonthlyVariance{}}
+	})
+
+	for month := utils.BeginningOfMonth(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		key := month.Format("2006-01")
+		forecastsByAccount := accounting.GroupByAccount(forecasts[key])
+		expensesByAccount := accounting.GroupByAccount(expenses[key])
+
+		for i, account := range accounts {
+			forecast := accounting.CostSum(forecastsByAccount[account])
+			actual := accounting.CostSum(popExpenses(account, expensesByAccount))
+
+			v := &variances[i]
+			v.Forecast = v.Forecast.Add(forecast)
+			v.Actual = v.Actual.Add(actual)
+			v.Variance = v.Forecast.Sub(v.Actual)
+
+			monthly := MonthlyVariance{
+				Date:               month,
+				Forecast:           forecast,
+				Actual:             actual,
+				Variance:           forecast.Sub(actual),
+				CumulativeVariance: v.Variance,
+			}
+			if forecast.IsPositive() {
+				adherence := actual.Div(forecast).Mul(decimal.NewFromInt(100))
+				monthly.Adherence = &adherence
+			}
+			v.Months = append(v.Months, monthly)
+		}
+	}
+
+	for i := range variances {
+		v := &variances[i]
+		if v.Forecast.IsPositive() {
+			v.VariancePercentage = v.Variance.Div(v.Forecast).Mul(decimal.NewFromInt(100))
+		}
+		v.Trend = adherenceTrend(v.Months)
+	}
+
+	forecast := utils.SumBy(variances, func(v AccountVariance) decimal.Decimal { return v.Forecast })
+	actual := utils.SumBy(variances, func(v AccountVariance) decimal.Decimal { return v.Actual })
+	return gin.H{
+		"from":     from,
+		"to":       to,
+		"accounts": variances,
+		"forecast": forecast,
+		"actual":   actual,
+		"variance": forecast.Sub(actual),
+	}, nil
+}
+
+// adherenceTrend is the slope of the least squares line fitted over the
+// monthly adherence, the months without forecast are skipped.
+func adherenceTrend(months []MonthlyVariance) decimal.Decimal {
+	var n, sumX, sumY, sumXY, sumXX float64
+	for i, m := range months {
+		if m.Adherence == nil {
+			continue
+		}
+		x := float64(i)
+		y := m.Adherence.InexactFloat64()
+		n++
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	denominator := n*sumXX - sumX*sumX
+	if n < 2 || denominator == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromFloat((n*sumXY - sumX*sumY) / denominator).Round(2)
+}
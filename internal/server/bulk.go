@@ -0,0 +1,180 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/model/transaction"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type BulkFilter struct {
+	TransactionIDs []string   `json:"transaction_ids"`
+	Account        string     `json:"account"`
+	Payee          string     `json:"payee"`
+	From           *time.Time `json:"from"`
+	To             *time.Time `json:"to"`
+}
+
+type BulkOperationRequest struct {
+	Filter    BulkFilter        `json:"filter"`
+	Operation string            `json:"operation"`
+	Args      map[string]string `json:"args"`
+	DryRun    bool              `json:"dry_run"`
+}
+
+type BulkChange struct {
+	FileName  string `json:"file_name"`
+	BeginLine uint64 `json:"begin_line"`
+	EndLine   uint64 `json:"end_line"`
+	Before    string `json:"before"`
+	After     string `json:"after"`
+}
+
+func ApplyBulkOperation(db *gorm.DB, request BulkOperationRequest) gin.H {
+	operation, err := bulkOperation(request, config.LedgerCliFor(journal.JournalPath(db)))
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	filter := request.Filter
+	if len(filter.TransactionIDs) == 0 && filter.Account == "" && filter.Payee == "" && filter.From == nil && filter.To == nil {
+		return gin.H{"saved": false, "message": "At least one filter is required"}
+	}
+
+	transactions := filterTransactions(db, filter)
+	grouped := lo.GroupBy(transactions, func(t transaction.Transaction) string { return t.FileName })
+
+	changes := []BulkChange{}
+	files := make(map[string]string)
+	for fileName, ts := range grouped {
+		content, err := journal.Read(db, fileName)
+		if err != nil {
+			log.Warn(err)
+			return gin.H{"saved": false, "message": fmt.Sprintf("Failed to read file %s", fileName)}
+		}
+
+		// apply bottom up so the line numbers of the remaining
+		// transactions stay valid
+		sort.Slice(ts, func(i, j int) bool { return ts[i].BeginLine > ts[j].BeginLine })
+		updatedContent := content
+		for _, t := range ts {
+			before, err := journal.Lines(updatedContent, t.BeginLine, t.EndLine)
+			if err != nil {
+				return gin.H{"saved": false, "message": fmt.Sprintf("Transaction at %s:%d not found. The journal might have changed, please reload and try again.", fileName, t.BeginLine)}
+			}
+
+			after, updated := operation(before)
+			if !updated {
+				continue
+			}
+
+			updatedContent, err = journal.ReplaceLines(updatedContent, t.BeginLine, t.EndLine, after)
+			if err != nil {
+				return gin.H{"saved": false, "message": err.Error()}
+			}
+			changes = append(changes, BulkChange{FileName: fileName, BeginLine: t.BeginLine, EndLine: t.EndLine, Before: before, After: after})
+		}
+
+		if updatedContent != content {
+			files[fileName] = updatedContent
+		}
+	}
+
+	if request.DryRun || len(files) == 0 {
+		return gin.H{"saved": false, "changes": changes}
+	}
+
+	for fileName, content := range files {
+		errors, _, err := validateFile(db, LedgerFile{Name: fileName, Content: content})
+		if err != nil {
+			return gin.H{"errors": errors, "saved": false, "message": fmt.Sprintf("Validation failed for %s", fileName), "changes": changes}
+		}
+	}
+
+	err = journal.WriteAll(db, "bulk "+request.Operation, files)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": "Failed to write files", "changes": changes}
+	}
+
+	Sync(db, SyncRequest{Journal: true})
+	return gin.H{"saved": true, "changes": changes}
+}
+
+func bulkOperation(request BulkOperationRequest, cli string) (func(text string) (string, bool), error) {
+	switch request.Operation {
+	case "rename_account":
+		oldAccount, newAccount := request.Args["old_account"], request.Args["new_account"]
+		if oldAccount == "" || newAccount == "" {
+			return nil, fmt.Errorf("old_account and new_account are required")
+		}
+		return func(text string) (string, bool) {
+			return journal.RenameAccount(text, oldAccount, newAccount)
+		}, nil
+	case "change_payee":
+		payee := request.Args["payee"]
+		if payee == "" {
+			return nil, fmt.Errorf("payee is required")
+		}
+		return func(text string) (string, bool) {
+			return journal.ChangePayee(text, payee, cli)
+		}, nil
+	case "add_tag":
+		tag := request.Args["tag"]
+		if tag == "" {
+			return nil, fmt.Errorf("tag is required")
+		}
+		return func(text string) (string, bool) {
+			return journal.AddTag(text, tag, cli)
+		}, nil
+	case "set_status":
+		status := request.Args["status"]
+		if !lo.Contains([]string{"cleared", "pending", "unmarked"}, status) {
+			return nil, fmt.Errorf("status should be one of cleared, pending or unmarked")
+		}
+		return func(text string) (string, bool) {
+			return journal.SetStatus(text, status, cli)
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown operation %s", request.Operation)
+}
+
+func filterTransactions(db *gorm.DB, filter BulkFilter) []transaction.Transaction {
+	q := query.Init(db)
+	if len(filter.TransactionIDs) > 0 {
+		q = q.Where("transaction_id in ?", filter.TransactionIDs)
+	}
+
+	if filter.Account != "" {
+		q = q.AccountPrefix(filter.Account)
+	}
+
+	if filter.Payee != "" {
+		q = q.Payee(filter.Payee)
+	}
+
+	if filter.From != nil {
+		q = q.Where("date >= ?", *filter.From)
+	}
+
+	if filter.To != nil {
+		q = q.Where("date <= ?", utils.EndOfDay(*filter.To))
+	}
+
+	ids := lo.Uniq(lo.Map(q.All(), func(p posting.Posting, _ int) string { return p.TransactionID }))
+
+	// filter matches postings, but the operation applies on the whole
+	// transaction
+	return transaction.Build(query.Init(db).Where("transaction_id in ?", ids).All())
+}
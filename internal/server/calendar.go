@@ -0,0 +1,237 @@
+package server
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/scheduler"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const CALENDAR_PATH = "/api/calendar.ics"
+
+// only the events in the near future are listed, calendar clients
+// refresh the feed periodically
+const calendarMonths = 6
+
+// CalendarTokenName returns the name of the calendar token, calendar
+// tokens only grant access to the feed.
+func CalendarTokenName(token string) (string, bool) {
+	if token == "" {
+		return "", false
+	}
+
+	hashed := "sha256:" + utils.Sha256(token)
+	for _, t := range config.GetConfig().Calendar.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(hashed)) == 1 {
+			return t.Name, true
+		}
+	}
+	return "", false
+}
+
+type CalendarEvent struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+}
+
+// GetCalendar returns an iCalendar feed of the upcoming forecast
+// postings, recurring transactions (EMIs, SIPs etc), credit card due
+// dates and savings goal target dates.
+func GetCalendar(db *gorm.DB) string {
+	now := utils.Now()
+	start := utils.EndOfDay(now.AddDate(0, 0, -1))
+	end := utils.EndOfDay(now.AddDate(0, calendarMonths, 0))
+
+	events := []CalendarEvent{}
+	events = append(events, forecastEvents(db, start, end)...)
+	events = append(events, recurringEvents(db, start, end)...)
+	events = append(events, creditCardEvents(start, end)...)
+	events = append(events, goalEvents(start, end)...)
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Date.Before(events[j].Date) })
+	return renderCalendar(events)
+}
+
+func forecastEvents(db *gorm.DB, start, end time.Time) []CalendarEvent {
+	postings := query.Init(db).Like("Expenses:%").Forecast().Where("date > ? and date <= ?", start, end).All()
+	return lo.Map(postings, func(p posting.Posting, _ int) CalendarEvent {
+		return CalendarEvent{
+			UID:         fmt.Sprintf("forecast-%s-%s", p.TransactionID, p.Account),
+			Date:        p.Date,
+			Summary:     fmt.Sprintf("%s %s", p.Payee, formatAmount(p.Amount)),
+			Description: p.Account,
+		}
+	})
+}
+
+func recurringEvents(db *gorm.DB, start, end time.Time) []CalendarEvent {
+	events := []CalendarEvent{}
+	for _, ts := range ComputeRecurringTransactions(query.Init(db).All()) {
+		last := ts.Transactions[0]
+		amount := lo.Reduce(last.Postings, func(sum decimal.Decimal, p posting.Posting, _ int) decimal.Decimal {
+			return sum.Add(decimal.Max(p.Amount, decimal.Zero))
+		}, decimal.Zero)
+
+		for _, date := range upcomingDates(ts, start, end) {
+			events = append(events, CalendarEvent{
+				UID:         fmt.Sprintf("recurring-%s-%s", date.Format("20060102"), ts.Key),
+				Date:        date,
+				Summary:     fmt.Sprintf("%s %s", ts.Key, formatAmount(amount)),
+				Description: last.Payee,
+			})
+		}
+	}
+	return events
+}
+
+// upcomingDates follows the period of the sequence if available,
+// otherwise the interval between the past transactions, same as the
+// recurring page.
+func upcomingDates(ts TransactionSequence, start, end time.Time) []time.Time {
+	last := ts.Transactions[0].Date
+	dates := []time.Time{}
+
+	if ts.Period != "" {
+		schedules := []scheduler.Schedule{}
+		for _, period := range strings.Split(ts.Period, "|") {
+			schedule, err := scheduler.Parse("0 0 " + strings.TrimSpace(period))
+			if err != nil {
+				schedules = nil
+				break
+			}
+			schedules = append(schedules, schedule)
+		}
+
+		if len(schedules) > 0 {
+			for _, schedule := range schedules {
+				for date := schedule.Next(last); !date.IsZero() && date.Before(end); date = schedule.Next(date) {
+					if date.After(start) {
+						dates = append(dates, date)
+					}
+				}
+			}
+			sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+			return dates
+		}
+	}
+
+	for date := nextRecurringDate(ts, last); date.Before(end); date = nextRecurringDate(ts, date) {
+		if date.After(start) {
+			dates = append(dates, date)
+		}
+	}
+	return dates
+}
+
+func nextRecurringDate(ts TransactionSequence, date time.Time) time.Time {
+	if ts.Interval >= 28 && ts.Interval <= 33 {
+		return date.AddDate(0, 1, 0)
+	}
+
+	if ts.Interval >= 360 && ts.Interval <= 370 {
+		return date.AddDate(1, 0, 0)
+	}
+
+	return date.AddDate(0, 0, ts.Interval)
+}
+
+func creditCardEvents(start, end time.Time) []CalendarEvent {
+	events := []CalendarEvent{}
+	for _, card := range config.GetConfig().CreditCards {
+		if card.DueDay <= 0 {
+			continue
+		}
+
+		for month := utils.BeginningOfMonth(start); month.Before(end); month = month.AddDate(0, 1, 0) {
+			// due day is capped at the last day of short months
+			day := lo.Min([]int{card.DueDay, utils.EndOfMonth(month).Day()})
+			date := time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, config.TimeZone())
+			if date.After(start) && date.Before(end) {
+				events = append(events, CalendarEvent{
+					UID:     fmt.Sprintf("credit-card-%s-%s", date.Format("20060102"), card.Account),
+					Date:    date,
+					Summary: fmt.Sprintf("%s bill due", card.Account),
+				})
+			}
+		}
+	}
+	return events
+}
+
+func goalEvents(start, end time.Time) []CalendarEvent {
+	events := []CalendarEvent{}
+	for _, goal := range config.GetConfig().Goals.Savings {
+		date, err := time.ParseInLocation("2006-01-02", goal.TargetDate, config.TimeZone())
+		if err != nil || !date.After(start) || !date.Before(end) {
+			continue
+		}
+
+		events = append(events, CalendarEvent{
+			UID:         fmt.Sprintf("goal-%s", goal.Name),
+			Date:        date,
+			Summary:     fmt.Sprintf("%s goal target date", goal.Name),
+			Description: fmt.Sprintf("Target %s", formatAmount(decimal.NewFromFloat(goal.Target))),
+		})
+	}
+	return events
+}
+
+func renderCalendar(events []CalendarEvent) string {
+	var b strings.Builder
+	line := func(content string) {
+		// lines longer than 75 octets are folded, continuation lines
+		// start with a space
+		for len(content) > 75 {
+			cut := 75
+			for cut > 0 && !utf8.RuneStart(content[cut]) {
+				cut--
+			}
+			b.WriteString(content[:cut] + "\r\n")
+			content = " " + content[cut:]
+		}
+		b.WriteString(content + "\r\n")
+	}
+
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//paisa//calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:Paisa")
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + escapeCalendarText(e.UID) + "@paisa")
+		line("DTSTAMP:" + stamp)
+		line("DTSTART;VALUE=DATE:" + e.Date.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeCalendarText(e.Summary))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeCalendarText(e.Description))
+		}
+		line("BEGIN:VALARM")
+		line("ACTION:DISPLAY")
+		line("DESCRIPTION:" + escapeCalendarText(e.Summary))
+		line("TRIGGER:-P1D")
+		line("END:VALARM")
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+func escapeCalendarText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(text)
+}
@@ -0,0 +1,170 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/scraper/ecb"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type profileGain struct {
+	Account       string
+	Commodity     string
+	PurchaseDate  *time.Time
+	SellDate      time.Time
+	Units         decimal.Decimal
+	PurchasePrice decimal.Decimal
+	SellPrice     decimal.Decimal
+	HoldingDays   int
+	LongTerm      bool
+}
+
+// exportCapitalGainsProfile lists each sale as per the rules of the
+// profile instead of the Indian ones. The purchase is converted at the
+// rate of the purchase date and the sale at the rate of the sale date.
+func exportCapitalGainsProfile(db *gorm.DB, profile config.CapitalGainsProfile) (exportTable, error) {
+	table := exportTable{Columns: []string{"year", "account", "commodity", "purchase_date", "sell_date", "units", "purchase_price", "sell_price", "gain", "holding_days", "long_term", "taxable"}}
+
+	convert, err := profileConverter(db, profile)
+	if err != nil {
+		return table, err
+	}
+
+	year := utils.CalendarYear
+	if profile.Year == string(utils.FiscalYear) {
+		year = utils.FiscalYear
+	}
+
+	postings := lo.Filter(accounting.FilterByGlob(query.Init(db).Like("Assets:%").ExcludeFuture().All(), profile.Accounts), func(p posting.Posting, _ int) bool {
+		return !utils.IsCurrency(p.Commodity)
+	})
+	byAccount := lo.GroupBy(postings, func(p posting.Posting) string { return p.Account })
+	for _, account := range utils.SortedKeys(byAccount) {
+		var gains []profileGain
+		if profile.LotMatching == "average" {
+			gains = averageCostGains(byAccount[account], convert)
+		} else {
+			gains = fifoGains(byAccount[account], convert, profile.HoldingPeriodDays)
+		}
+
+		for _, g := range gains {
+			gain := g.SellPrice.Sub(g.PurchasePrice)
+			taxable := gain
+			if g.LongTerm && profile.LongTermExempt {
+				taxable = decimal.Zero
+			}
+
+			var purchaseDate any = ""
+			if g.PurchaseDate != nil {
+				purchaseDate = *g.PurchaseDate
+			}
+			table.Rows = append(table.Rows, []any{utils.YearHuman(year, g.SellDate), g.Account, g.Commodity, purchaseDate, g.SellDate, g.Units, g.PurchasePrice, g.SellPrice, gain, g.HoldingDays, g.LongTerm, taxable})
+		}
+	}
+	return table, nil
+}
+
+func fifoGains(postings []posting.Posting, convert func(decimal.Decimal, time.Time) decimal.Decimal, holdingPeriodDays int) []profileGain {
+	gains := []profileGain{}
+	var available []posting.Posting
+	for _, p := range postings {
+		if p.Quantity.IsPositive() {
+			available = append(available, p)
+			continue
+		}
+
+		quantity := p.Quantity.Neg()
+		for quantity.IsPositive() && len(available) > 0 {
+			first := available[0]
+			q := decimal.Min(first.Quantity, quantity)
+			if first.Quantity.GreaterThan(quantity) {
+				first.AddQuantity(quantity.Neg())
+				available[0] = first
+			} else {
+				available = available[1:]
+			}
+			quantity = quantity.Sub(q)
+
+			purchaseDate := first.Date
+			days := int(p.Date.Sub(purchaseDate).Hours() / 24)
+			gains = append(gains, profileGain{
+				Account:       p.Account,
+				Commodity:     p.Commodity,
+				PurchaseDate:  &purchaseDate,
+				SellDate:      p.Date,
+				Units:         q,
+				PurchasePrice: convert(q.Mul(first.Price()), first.Date),
+				SellPrice:     convert(q.Mul(p.Price()), p.Date),
+				HoldingDays:   days,
+				LongTerm:      holdingPeriodDays > 0 && days > holdingPeriodDays,
+			})
+		}
+	}
+	return gains
+}
+
+// averageCostGains uses the weighted average cost of the units held at
+// the time of the sale, the purchases are converted as they come in.
+func averageCostGains(postings []posting.Posting, convert func(decimal.Decimal, time.Time) decimal.Decimal) []profileGain {
+	gains := []profileGain{}
+	units := decimal.Zero
+	cost := decimal.Zero
+	for _, p := range postings {
+		if p.Quantity.IsPositive() {
+			units = units.Add(p.Quantity)
+			cost = cost.Add(convert(p.Amount, p.Date))
+			continue
+		}
+
+		if units.IsZero() {
+			continue
+		}
+
+		q := decimal.Min(p.Quantity.Neg(), units)
+		purchasePrice := cost.Mul(q).Div(units)
+		units = units.Sub(q)
+		cost = cost.Sub(purchasePrice)
+		gains = append(gains, profileGain{
+			Account:       p.Account,
+			Commodity:     p.Commodity,
+			SellDate:      p.Date,
+			Units:         q,
+			PurchasePrice: purchasePrice.Round(2),
+			SellPrice:     convert(q.Mul(p.Price()), p.Date),
+		})
+	}
+	return gains
+}
+
+// profileConverter returns the function to convert the amounts in the
+// default currency. With the ecb rates, the amount is turned back into
+// the currency of the holdings using the price in the journal and
+// converted again at the reference rate of the day.
+func profileConverter(db *gorm.DB, profile config.CapitalGainsProfile) (func(decimal.Decimal, time.Time) decimal.Decimal, error) {
+	identity := func(amount decimal.Decimal, _ time.Time) decimal.Decimal { return amount.Round(2) }
+	if profile.ExchangeRates != "ecb" || profile.Currency == "" || utils.IsCurrency(profile.Currency) || !service.HasPrice(db, profile.Currency) {
+		return identity, nil
+	}
+
+	rates, err := ecb.GetRates(profile.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(amount decimal.Decimal, date time.Time) decimal.Decimal {
+		journalRate := service.GetUnitPrice(db, profile.Currency, date).Value
+		rate, found := ecb.RateOn(rates, date)
+		if journalRate.IsZero() || !found {
+			return amount.Round(2)
+		}
+		return amount.Div(journalRate).Mul(rate).Round(2)
+	}, nil
+}
@@ -1,12 +1,16 @@
 package server
 
 import (
+	"strings"
 	"time"
 
 	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
 	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -31,20 +35,29 @@ func GetCashFlow(db *gorm.DB) gin.H {
 }
 
 func GetCurrentCashFlow(db *gorm.DB) []CashFlow {
-	balance := accounting.CostSum(query.Init(db).BeforeNMonths(3).AccountPrefix("Assets:Checking").All())
+	balance := accounting.CostSum(query.Init(db).BeforeNMonths(3).AccountPrefix(config.CheckingAccounts()...).All())
 	return computeCashFlow(db, query.Init(db).LastNMonths(3), balance)
 }
 
 func computeCashFlow(db *gorm.DB, q *query.Query, balance decimal.Decimal) []CashFlow {
+	return computeCashFlowOf(q.Clone().All(), balance)
+}
+
+// computeCashFlowOf computes the monthly cash flow of the given
+// postings, which are expected to be sorted by date.
+func computeCashFlowOf(postings []posting.Posting, balance decimal.Decimal) []CashFlow {
 	var cashFlows []CashFlow
 
-	expenses := utils.GroupByMonth(q.Clone().Like("Expenses:%").NotAccountPrefix("Expenses:Tax").All())
-	incomes := utils.GroupByMonth(q.Clone().Like("Income:%").All())
-	liabilities := utils.GroupByMonth(q.Clone().Like("Liabilities:%").All())
-	investments := utils.GroupByMonth(q.Clone().Like("Assets:%").NotAccountPrefix("Assets:Checking").All())
-	taxes := utils.GroupByMonth(q.Clone().AccountPrefix("Expenses:Tax").All())
-	checkings := utils.GroupByMonth(q.Clone().AccountPrefix("Assets:Checking").All())
-	postings := q.Clone().All()
+	expenses := utils.GroupByMonth(lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		return strings.HasPrefix(p.Account, "Expenses:") && !utils.IsTaxAccount(p.Account)
+	}))
+	incomes := utils.GroupByMonth(filterByPrefix(postings, "Income:"))
+	liabilities := utils.GroupByMonth(filterByPrefix(postings, "Liabilities:"))
+	investments := utils.GroupByMonth(lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		return strings.HasPrefix(p.Account, "Assets:") && !utils.IsCheckingAccount(p.Account)
+	}))
+	taxes := utils.GroupByMonth(lo.Filter(postings, func(p posting.Posting, _ int) bool { return utils.IsTaxAccount(p.Account) }))
+	checkings := utils.GroupByMonth(lo.Filter(postings, func(p posting.Posting, _ int) bool { return utils.IsCheckingAccount(p.Account) }))
 
 	if len(postings) == 0 {
 		return []CashFlow{}
@@ -93,3 +106,7 @@ func computeCashFlow(db *gorm.DB, q *query.Query, balance decimal.Decimal) []Cas
 
 	return cashFlows
 }
+
+func filterByPrefix(postings []posting.Posting, prefix string) []posting.Posting {
+	return lo.Filter(postings, func(p posting.Posting, _ int) bool { return strings.HasPrefix(p.Account, prefix) })
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/cache"
+	"github.com/ananthakumaran/paisa/internal/model/commoditymetadata"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type CommodityMetadataRequest struct {
+	Commodity    string          `json:"commodity" binding:"required"`
+	ISIN         string          `json:"isin"`
+	ExpenseRatio decimal.Decimal `json:"expense_ratio"`
+	Category     string          `json:"category"`
+	Risk         string          `json:"risk"`
+	Notes        string          `json:"notes"`
+}
+
+type CommodityMetadataDeleteRequest struct {
+	Commodity string `json:"commodity" binding:"required"`
+}
+
+func GetCommodityMetadata(db *gorm.DB) gin.H {
+	return gin.H{"metadata": commoditymetadata.All(db)}
+}
+
+// SaveCommodityMetadata replaces the metadata of the commodity. The
+// cached reports are dropped as the asset breakdown and the allocation
+// include the metadata.
+func SaveCommodityMetadata(db *gorm.DB, request CommodityMetadataRequest) gin.H {
+	metadata := commoditymetadata.Metadata{
+		Commodity:    request.Commodity,
+		ISIN:         request.ISIN,
+		ExpenseRatio: request.ExpenseRatio,
+		Category:     request.Category,
+		Risk:         request.Risk,
+		Notes:        request.Notes,
+	}
+	commoditymetadata.Save(db, &metadata)
+	cache.Clear()
+	return gin.H{"saved": true, "metadata": metadata}
+}
+
+func DeleteCommodityMetadata(db *gorm.DB, request CommodityMetadataDeleteRequest) gin.H {
+	commoditymetadata.Delete(db, request.Commodity)
+	cache.Clear()
+	return gin.H{"success": true}
+}
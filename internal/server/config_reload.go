@@ -0,0 +1,38 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/cache"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/watcher"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReloadConfig loads the config file again. The new config replaces
+// the current one only if it's valid, the settings listed in
+// restart_required are not picked up till the server is restarted.
+func ReloadConfig() gin.H {
+	err := config.Reload()
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	cache.Clear()
+	return gin.H{"success": true, "restart_required": config.RestartRequired()}
+}
+
+// watchConfig reloads the config when the file is changed outside of
+// Paisa. An invalid config is logged and the current one is kept.
+func watchConfig(debounce time.Duration) {
+	w := watcher.NewFile(config.GetConfigPath())
+	w.Watch(debounce, func(_ []string) {
+		result := ReloadConfig()
+		if result["success"] == false {
+			log.Warn("Failed to reload the config: ", result["message"])
+			return
+		}
+		notifications.broadcast(Notification{Type: "config_changed"})
+	})
+}
@@ -28,6 +28,17 @@ type CreditCardSummary struct {
 	ExpirationDate time.Time                             `json:"expirationDate"`
 }
 
+type CreditCardStatement struct {
+	Account string `json:"account"`
+	// spends since the last statement
+	Unbilled        decimal.Decimal `json:"unbilled"`
+	StatementDate   time.Time       `json:"statementDate"`
+	StatementAmount decimal.Decimal `json:"statementAmount"`
+	DueDate         time.Time       `json:"dueDate"`
+	// statement amount minus the payments made after the statement
+	DueAmount decimal.Decimal `json:"dueAmount"`
+}
+
 type CreditCardBill struct {
 	StatementStartDate   time.Time       `json:"statementStartDate"`
 	StatementEndDate     time.Time       `json:"statementEndDate"`
@@ -127,12 +138,7 @@ func computeBills(db *gorm.DB, creditCardConfig config.CreditCard, ps []posting.
 		statementEndDate = statementEndDate.AddDate(0, 0, creditCardConfig.StatementEndDay-1)
 		statementStartDate := statementEndDate.AddDate(0, -1, 1)
 
-		var dueDate time.Time
-		if creditCardConfig.StatementEndDay < creditCardConfig.DueDay {
-			dueDate = utils.BeginningOfMonth(statementEndDate).AddDate(0, 0, creditCardConfig.DueDay-1)
-		} else {
-			dueDate = utils.BeginningOfMonth(statementEndDate).AddDate(0, 1, creditCardConfig.DueDay-1)
-		}
+		dueDate := statementDueDate(creditCardConfig, statementEndDate)
 
 		bill := CreditCardBill{
 			StatementStartDate: statementStartDate,
@@ -184,3 +190,50 @@ func computeBills(db *gorm.DB, creditCardConfig config.CreditCard, ps []posting.
 
 	return bills
 }
+
+// GetCreditCardStatements reports the last statement and the spends
+// since then for each credit card, based on the configured statement
+// and due day.
+func GetCreditCardStatements(db *gorm.DB) gin.H {
+	statements := []CreditCardStatement{}
+	for _, creditCardConfig := range config.GetConfig().CreditCards {
+		ps := query.Init(db).Where("account = ?", creditCardConfig.Account).UntilToday().All()
+		statements = append(statements, computeCreditCardStatement(db, creditCardConfig, ps, utils.Now()))
+	}
+
+	return gin.H{"statements": statements}
+}
+
+func computeCreditCardStatement(db *gorm.DB, creditCardConfig config.CreditCard, ps []posting.Posting, now time.Time) CreditCardStatement {
+	statementDate := utils.BeginningOfMonth(now).AddDate(0, 0, creditCardConfig.StatementEndDay-1)
+	if !utils.EndOfDay(statementDate).Before(now) {
+		statementDate = utils.BeginningOfMonth(now).AddDate(0, -1, creditCardConfig.StatementEndDay-1)
+	}
+
+	statement := CreditCardStatement{
+		Account:       creditCardConfig.Account,
+		StatementDate: statementDate,
+		DueDate:       statementDueDate(creditCardConfig, statementDate),
+	}
+
+	payments := decimal.Zero
+	for _, p := range ps {
+		if !p.Date.After(utils.EndOfDay(statementDate)) {
+			statement.StatementAmount = statement.StatementAmount.Add(p.Amount.Neg())
+		} else if p.Amount.IsNegative() || service.IsContraPostingRefund(db, p) {
+			statement.Unbilled = statement.Unbilled.Add(p.Amount.Neg())
+		} else {
+			payments = payments.Add(p.Amount)
+		}
+	}
+
+	statement.DueAmount = decimal.Max(decimal.Zero, statement.StatementAmount.Sub(payments))
+	return statement
+}
+
+func statementDueDate(creditCardConfig config.CreditCard, statementEndDate time.Time) time.Time {
+	if creditCardConfig.StatementEndDay < creditCardConfig.DueDay {
+		return utils.BeginningOfMonth(statementEndDate).AddDate(0, 0, creditCardConfig.DueDay-1)
+	}
+	return utils.BeginningOfMonth(statementEndDate).AddDate(0, 1, creditCardConfig.DueDay-1)
+}
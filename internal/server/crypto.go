@@ -0,0 +1,32 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/ananthakumaran/paisa/internal/cryptosync"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var cryptoMu sync.Mutex
+
+// GetCrypto returns the balances reported by the exchanges and the
+// wallets as of the last sync, next to the balances in the journal.
+func GetCrypto() gin.H {
+	return gin.H{"holdings": cryptosync.Holdings()}
+}
+
+func SyncCrypto(db *gorm.DB) gin.H {
+	if !cryptoMu.TryLock() {
+		return gin.H{"success": false, "message": "Crypto sync is already running"}
+	}
+	defer cryptoMu.Unlock()
+
+	created, err := cryptosync.Sync(db)
+	if err != nil {
+		log.Warnf("Crypto sync failed: %v", err)
+		return gin.H{"success": false, "message": err.Error(), "created": created, "holdings": cryptosync.Holdings()}
+	}
+	return gin.H{"success": true, "created": created, "holdings": cryptosync.Holdings()}
+}
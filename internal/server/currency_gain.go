@@ -0,0 +1,105 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/model/commodity"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type CurrencyGainLot struct {
+	Date         time.Time       `json:"date"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	Amount       decimal.Decimal `json:"amount"`
+	Rate         decimal.Decimal `json:"rate"`
+	NativeAmount decimal.Decimal `json:"native_amount"`
+}
+
+type CurrencyGain struct {
+	Account            string            `json:"account"`
+	Commodity          string            `json:"commodity"`
+	Currency           string            `json:"currency"`
+	Quantity           decimal.Decimal   `json:"quantity"`
+	CurrentRate        decimal.Decimal   `json:"current_rate"`
+	NativeCost         decimal.Decimal   `json:"native_cost"`
+	NativeMarketAmount decimal.Decimal   `json:"native_market_amount"`
+	NativeGain         decimal.Decimal   `json:"native_gain"`
+	Cost               decimal.Decimal   `json:"cost"`
+	MarketAmount       decimal.Decimal   `json:"market_amount"`
+	Gain               decimal.Decimal   `json:"gain"`
+	PriceGain          decimal.Decimal   `json:"price_gain"`
+	CurrencyGain       decimal.Decimal   `json:"currency_gain"`
+	Lots               []CurrencyGainLot `json:"lots"`
+}
+
+// GetCurrencyGain splits the unrealized gain of the commodities
+// traded in a foreign currency into the gain due to the price in that
+// currency and the gain due to the exchange rate. The rate at purchase
+// is the price of the currency in the journal on the purchase date.
+func GetCurrencyGain(db *gorm.DB) gin.H {
+	postings := query.Init(db).Like("Assets:%").ExcludeFuture().All()
+	now := utils.EndOfToday()
+
+	gains := []CurrencyGain{}
+	for _, c := range commodity.All() {
+		if c.Currency == "" || c.Currency == c.Name || !service.HasPrice(db, c.Currency) {
+			continue
+		}
+
+		ps := lo.Filter(postings, func(p posting.Posting, _ int) bool { return p.Commodity == c.Name })
+		byAccount := lo.GroupBy(ps, func(p posting.Posting) string { return p.Account })
+		for _, account := range utils.SortedKeys(byAccount) {
+			lots := accounting.FIFO(byAccount[account])
+			if len(lots) == 0 {
+				continue
+			}
+
+			gain := computeCurrencyGain(db, lots, c.Currency, now)
+			gain.Account = account
+			gain.Commodity = c.Name
+			gains = append(gains, gain)
+		}
+	}
+
+	return gin.H{"currency_gains": gains}
+}
+
+func computeCurrencyGain(db *gorm.DB, lots []posting.Posting, currency string, now time.Time) CurrencyGain {
+	gain := CurrencyGain{Currency: currency, CurrentRate: service.GetUnitPrice(db, currency, now).Value, Lots: []CurrencyGainLot{}}
+	for _, lot := range lots {
+		rate := service.GetUnitPrice(db, currency, lot.Date).Value
+		native := lot.Amount
+		if !rate.IsZero() {
+			native = lot.Amount.Div(rate)
+		}
+
+		gain.Lots = append(gain.Lots, CurrencyGainLot{Date: lot.Date, Quantity: lot.Quantity, Amount: lot.Amount, Rate: rate, NativeAmount: native.Round(2)})
+		gain.Quantity = gain.Quantity.Add(lot.Quantity)
+		gain.Cost = gain.Cost.Add(lot.Amount)
+		gain.NativeCost = gain.NativeCost.Add(native)
+		gain.MarketAmount = gain.MarketAmount.Add(service.GetMarketPrice(db, lot, now))
+	}
+
+	if !gain.CurrentRate.IsZero() {
+		gain.NativeMarketAmount = gain.MarketAmount.Div(gain.CurrentRate)
+	}
+	gain.NativeGain = gain.NativeMarketAmount.Sub(gain.NativeCost)
+	gain.Gain = gain.MarketAmount.Sub(gain.Cost)
+	gain.PriceGain = gain.NativeGain.Mul(gain.CurrentRate)
+	gain.CurrencyGain = gain.Gain.Sub(gain.PriceGain)
+
+	gain.NativeCost = gain.NativeCost.Round(2)
+	gain.NativeMarketAmount = gain.NativeMarketAmount.Round(2)
+	gain.NativeGain = gain.NativeGain.Round(2)
+	gain.PriceGain = gain.PriceGain.Round(2)
+	gain.CurrencyGain = gain.CurrencyGain.Round(2)
+	return gain
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const CUSTOM_REPORT_NONE = "(none)"
+
+type CustomReportValue struct {
+	Group  string          `json:"group"`
+	Series string          `json:"series"`
+	Value  decimal.Decimal `json:"value"`
+	Count  int             `json:"count"`
+}
+
+// CustomReportResult is a table of the aggregated values, one for
+// each group and series that has postings. Series is empty when the
+// report is grouped by a single dimension.
+type CustomReportResult struct {
+	Report config.CustomReport `json:"report"`
+	Groups []string            `json:"groups"`
+	Series []string            `json:"series"`
+	Values []CustomReportValue `json:"values"`
+	Totals []CustomReportValue `json:"totals"`
+}
+
+func GetCustomReports() gin.H {
+	return gin.H{"reports": config.GetConfig().CustomReports}
+}
+
+func FindCustomReport(name string) (config.CustomReport, bool) {
+	return lo.Find(config.GetConfig().CustomReports, func(r config.CustomReport) bool { return r.Name == name })
+}
+
+func GetCustomReport(db *gorm.DB, report config.CustomReport) gin.H {
+	result, err := RunCustomReport(db, report)
+	if err != nil {
+		return gin.H{"error": err.Error()}
+	}
+	return gin.H{"result": result}
+}
+
+// RunCustomReport filters the postings, groups them by the dimensions
+// of the report and aggregates the amount of each group. The date
+// groups are in chronological order, the rest are ordered by the
+// aggregated value, largest first.
+func RunCustomReport(db *gorm.DB, report config.CustomReport) (CustomReportResult, error) {
+	q := query.Init(db)
+	if report.Payee != "" {
+		q = q.PayeeLike(report.Payee)
+	}
+	if len(report.Tags) > 0 {
+		q = q.Tags(report.Tags...)
+	}
+	if report.LastNMonths > 0 {
+		q = q.LastNMonths(report.LastNMonths)
+	}
+	if report.From != "" || report.Until != "" {
+		from, until := time.Time{}, utils.EndOfToday()
+		var err error
+		if report.From != "" {
+			from, err = time.ParseInLocation("2006-01-02", report.From, config.TimeZone())
+			if err != nil {
+				return CustomReportResult{}, fmt.Errorf("Invalid from date: %w", err)
+			}
+		}
+		if report.Until != "" {
+			until, err = time.ParseInLocation("2006-01-02", report.Until, config.TimeZone())
+			if err != nil {
+				return CustomReportResult{}, fmt.Errorf("Invalid until date: %w", err)
+			}
+		}
+		q = q.Between(from, until)
+	}
+
+	postings := q.All()
+	if len(report.Accounts) > 0 {
+		postings = accounting.FilterByGlob(postings, report.Accounts)
+	}
+
+	dimensions := report.GroupBy
+	if len(dimensions) > 2 {
+		return CustomReportResult{}, fmt.Errorf("At most two group_by dimensions are supported")
+	}
+	for _, dimension := range dimensions {
+		if _, err := customReportKey(report, dimension, posting.Posting{}); err != nil {
+			return CustomReportResult{}, err
+		}
+	}
+
+	type key struct{ group, series string }
+	grouped := make(map[key][]decimal.Decimal)
+	byGroup := make(map[string][]decimal.Decimal)
+	for _, p := range postings {
+		amount := p.Amount
+		if report.Negate {
+			amount = amount.Neg()
+		}
+
+		k := key{group: "Total"}
+		if len(dimensions) > 0 {
+			k.group, _ = customReportKey(report, dimensions[0], p)
+		}
+		if len(dimensions) > 1 {
+			k.series, _ = customReportKey(report, dimensions[1], p)
+		}
+		grouped[k] = append(grouped[k], amount)
+		byGroup[k.group] = append(byGroup[k.group], amount)
+	}
+
+	result := CustomReportResult{Report: report, Groups: []string{}, Series: []string{}, Values: []CustomReportValue{}, Totals: []CustomReportValue{}}
+	bySeries := make(map[string][]decimal.Decimal)
+	for k, amounts := range grouped {
+		result.Values = append(result.Values, CustomReportValue{Group: k.group, Series: k.series, Value: aggregateCustomReport(report.Aggregation, amounts), Count: len(amounts)})
+		if len(dimensions) > 1 {
+			bySeries[k.series] = append(bySeries[k.series], amounts...)
+		}
+	}
+	for group, amounts := range byGroup {
+		result.Totals = append(result.Totals, CustomReportValue{Group: group, Value: aggregateCustomReport(report.Aggregation, amounts), Count: len(amounts)})
+	}
+
+	result.Groups = orderCustomReportKeys(dimensions, 0, byGroup, report.Aggregation)
+	if len(dimensions) > 1 {
+		result.Series = orderCustomReportKeys(dimensions, 1, bySeries, report.Aggregation)
+	}
+
+	position := make(map[string]int)
+	for i, g := range result.Groups {
+		position[g] = i
+	}
+	seriesPosition := make(map[string]int)
+	for i, s := range result.Series {
+		seriesPosition[s] = i
+	}
+	sort.Slice(result.Values, func(i, j int) bool {
+		a, b := result.Values[i], result.Values[j]
+		if a.Group != b.Group {
+			return position[a.Group] < position[b.Group]
+		}
+		return seriesPosition[a.Series] < seriesPosition[b.Series]
+	})
+	sort.Slice(result.Totals, func(i, j int) bool { return position[result.Totals[i].Group] < position[result.Totals[j].Group] })
+
+	return result, nil
+}
+
+func customReportKey(report config.CustomReport, dimension string, p posting.Posting) (string, error) {
+	switch dimension {
+	case "month":
+		return p.Date.Format("2006-01"), nil
+	case "quarter":
+		return fmt.Sprintf("%d-Q%d", p.Date.Year(), (p.Date.Month()-1)/3+1), nil
+	case "year":
+		return p.Date.Format("2006"), nil
+	case "financial_year":
+		return utils.FY(p.Date), nil
+	case "account":
+		if report.AccountDepth > 0 {
+			parts := strings.Split(p.Account, ":")
+			return strings.Join(parts[:min(report.AccountDepth, len(parts))], ":"), nil
+		}
+		return p.Account, nil
+	case "payee":
+		return p.Payee, nil
+	case "commodity":
+		return p.Commodity, nil
+	}
+
+	if tag, ok := strings.CutPrefix(dimension, "tag:"); ok && tag != "" {
+		if value, found := p.TagValue(tag); found && value != "" {
+			return value, nil
+		}
+		return CUSTOM_REPORT_NONE, nil
+	}
+	return "", fmt.Errorf("Unknown group_by %s", dimension)
+}
+
+func isDateDimension(dimension string) bool {
+	return lo.Contains([]string{"month", "quarter", "year", "financial_year"}, dimension)
+}
+
+// orderCustomReportKeys sorts the keys of the dimension, the date keys
+// sort chronologically as strings.
+func orderCustomReportKeys(dimensions []string, i int, amounts map[string][]decimal.Decimal, aggregation string) []string {
+	keys := lo.Keys(amounts)
+	if i < len(dimensions) && isDateDimension(dimensions[i]) {
+		sort.Strings(keys)
+		return keys
+	}
+
+	values := lo.MapValues(amounts, func(a []decimal.Decimal, _ string) decimal.Decimal {
+		return aggregateCustomReport(aggregation, a).Abs()
+	})
+	sort.Slice(keys, func(a, b int) bool {
+		if !values[keys[a]].Equal(values[keys[b]]) {
+			return values[keys[a]].GreaterThan(values[keys[b]])
+		}
+		return keys[a] < keys[b]
+	})
+	return keys
+}
+
+func aggregateCustomReport(aggregation string, amounts []decimal.Decimal) decimal.Decimal {
+	if len(amounts) == 0 {
+		return decimal.Zero
+	}
+
+	switch aggregation {
+	case "count":
+		return decimal.NewFromInt(int64(len(amounts)))
+	case "average":
+		return decimal.Sum(amounts[0], amounts[1:]...).Div(decimal.NewFromInt(int64(len(amounts))))
+	case "min":
+		return decimal.Min(amounts[0], amounts[1:]...)
+	case "max":
+		return decimal.Max(amounts[0], amounts[1:]...)
+	default:
+		return decimal.Sum(amounts[0], amounts[1:]...)
+	}
+}
@@ -8,10 +8,10 @@ import (
 	"gorm.io/gorm"
 )
 
-func GetDashboard(db *gorm.DB) gin.H {
+func GetDashboard(db *gorm.DB, live bool) gin.H {
 	return gin.H{
 		"checkingBalances":     assets.GetCheckingBalance(db),
-		"networth":             GetCurrentNetworth(db),
+		"networth":             GetCurrentNetworth(db, live),
 		"expenses":             GetCurrentExpense(db),
 		"cashFlows":            GetCurrentCashFlow(db),
 		"transactionSequences": ComputeRecurringTransactions(query.Init(db).All()),
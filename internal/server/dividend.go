@@ -0,0 +1,143 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type DividendYear struct {
+	FY           string          `json:"fy"`
+	Amount       decimal.Decimal `json:"amount"`
+	MarketAmount decimal.Decimal `json:"market_amount"`
+	Yield        decimal.Decimal `json:"yield"`
+}
+
+type Dividend struct {
+	Name         string            `json:"name"`
+	Accounts     []string          `json:"accounts"`
+	Postings     []posting.Posting `json:"postings"`
+	Years        []DividendYear    `json:"years"`
+	Total        decimal.Decimal   `json:"total"`
+	MarketAmount decimal.Decimal   `json:"market_amount"`
+	TrailingYear decimal.Decimal   `json:"trailing_year"`
+	Yield        decimal.Decimal   `json:"yield"`
+	Projected    decimal.Decimal   `json:"projected"`
+}
+
+// GetDividends groups the Income:Dividend postings by the last
+// segment of the account, which is matched against the commodity or
+// the last segment of the asset accounts to find the holding.
+func GetDividends(db *gorm.DB) gin.H {
+	dividendPostings := query.Init(db).AccountPrefix("Income:Dividend").ExcludeFuture().All()
+	assetPostings := query.Init(db).Like("Assets:%").ExcludeFuture().All()
+
+	byName := lo.GroupBy(dividendPostings, func(p posting.Posting) string {
+		return lastSegment(p.Account)
+	})
+
+	dividends := []Dividend{}
+	for _, name := range utils.SortedKeys(byName) {
+		holdings := lo.Filter(assetPostings, func(p posting.Posting, _ int) bool {
+			return p.Commodity == name || lastSegment(p.Account) == name
+		})
+		dividends = append(dividends, computeDividend(db, name, byName[name], holdings))
+	}
+
+	byFY := utils.GroupByFY(dividendPostings)
+	yearly := lo.Map(utils.SortedKeys(byFY), func(fy string, _ int) gin.H {
+		return gin.H{"fy": fy, "amount": dividendAmount(byFY[fy])}
+	})
+
+	return gin.H{
+		"dividends": dividends,
+		"yearly":    yearly,
+		"projected": utils.SumBy(dividends, func(d Dividend) decimal.Decimal { return d.Projected }),
+	}
+}
+
+func computeDividend(db *gorm.DB, name string, postings []posting.Posting, holdings []posting.Posting) Dividend {
+	now := utils.EndOfToday()
+	hundred := decimal.NewFromInt(100)
+	d := Dividend{
+		Name:         name,
+		Accounts:     lo.Uniq(lo.Map(postings, func(p posting.Posting, _ int) string { return p.Account })),
+		Postings:     postings,
+		Years:        []DividendYear{},
+		Total:        dividendAmount(postings),
+		MarketAmount: marketAmountOn(db, holdings, now),
+	}
+
+	byFY := utils.GroupByFY(postings)
+	for _, fy := range utils.SortedKeys(byFY) {
+		_, end := utils.ParseFY(fy)
+		if end.After(now) {
+			end = now
+		}
+
+		year := DividendYear{FY: fy, Amount: dividendAmount(byFY[fy]), MarketAmount: marketAmountOn(db, holdings, end)}
+		if year.MarketAmount.IsPositive() {
+			year.Yield = year.Amount.Div(year.MarketAmount).Mul(hundred)
+		}
+		d.Years = append(d.Years, year)
+	}
+
+	yearAgo := now.AddDate(-1, 0, 0)
+	trailing := lo.Filter(postings, func(p posting.Posting, _ int) bool { return p.Date.After(yearAgo) })
+	d.TrailingYear = dividendAmount(trailing)
+	if d.MarketAmount.IsPositive() {
+		d.Yield = d.TrailingYear.Div(d.MarketAmount).Mul(hundred)
+	}
+
+	// the projection assumes the same dividend per unit as the last
+	// year on the units held now. Without units, the dividend of the
+	// last year is repeated.
+	units := unitsOn(holdings, now)
+	if units.IsZero() {
+		d.Projected = d.TrailingYear
+	} else {
+		d.Projected = decimal.Zero
+		for _, p := range trailing {
+			if held := unitsOn(holdings, p.Date); held.IsPositive() {
+				d.Projected = d.Projected.Add(p.Amount.Neg().Div(held).Mul(units))
+			}
+		}
+	}
+
+	return d
+}
+
+func dividendAmount(postings []posting.Posting) decimal.Decimal {
+	return utils.SumBy(postings, func(p posting.Posting) decimal.Decimal { return p.Amount.Neg() })
+}
+
+func marketAmountOn(db *gorm.DB, postings []posting.Posting, date time.Time) decimal.Decimal {
+	return utils.SumBy(postings, func(p posting.Posting) decimal.Decimal {
+		if p.Date.After(date) {
+			return decimal.Zero
+		}
+		return service.GetMarketPrice(db, p, date)
+	})
+}
+
+func unitsOn(postings []posting.Posting, date time.Time) decimal.Decimal {
+	return utils.SumBy(postings, func(p posting.Posting) decimal.Decimal {
+		if p.Date.After(date) || utils.IsCurrency(p.Commodity) {
+			return decimal.Zero
+		}
+		return p.Quantity
+	})
+}
+
+func lastSegment(account string) string {
+	parts := strings.Split(account, ":")
+	return parts[len(parts)-1]
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ananthakumaran/paisa/internal/emailimport"
+	"github.com/ananthakumaran/paisa/internal/model/draft"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type DraftRequest struct {
+	ID uint `json:"id" binding:"required"`
+	// edited content of the draft, the content as imported is used if
+	// empty
+	Content string `json:"content"`
+	File    string `json:"file"`
+}
+
+var emailImportMu sync.Mutex
+
+func GetDrafts(db *gorm.DB) gin.H {
+	return gin.H{
+		"pending":  draft.ByStatus(db, draft.Pending, 200),
+		"accepted": draft.ByStatus(db, draft.Accepted, 20),
+	}
+}
+
+// AcceptDraft appends the draft to the journal. The draft stays
+// pending if the journal fails to validate, so it can be fixed and
+// accepted again.
+func AcceptDraft(db *gorm.DB, request DraftRequest) gin.H {
+	d, found := draft.Get(db, request.ID)
+	if !found || d.Status != draft.Pending {
+		return gin.H{"saved": false, "message": "Draft not found"}
+	}
+
+	content := d.Content
+	if strings.TrimSpace(request.Content) != "" {
+		content = strings.TrimSpace(request.Content)
+	}
+
+	result := appendToJournal(db, fmt.Sprintf("import %s draft", d.Source), request.File, content)
+	if result["saved"] == true {
+		draft.UpdateStatus(db, d.ID, draft.Accepted)
+	}
+	return result
+}
+
+func RejectDraft(db *gorm.DB, request DraftRequest) gin.H {
+	d, found := draft.Get(db, request.ID)
+	if !found || d.Status != draft.Pending {
+		return gin.H{"success": false, "message": "Draft not found"}
+	}
+
+	draft.UpdateStatus(db, d.ID, draft.Rejected)
+	return gin.H{"success": true}
+}
+
+// ImportEmails checks the mailbox for new bank alerts, one run at a
+// time.
+func ImportEmails(db *gorm.DB) gin.H {
+	if !emailImportMu.TryLock() {
+		return gin.H{"success": false, "message": "Email import is already running"}
+	}
+	defer emailImportMu.Unlock()
+
+	created, err := emailimport.Import(db)
+	if err != nil {
+		log.Warnf("Email import failed: %v", err)
+		return gin.H{"success": false, "message": err.Error(), "created": created}
+	}
+	return gin.H{"success": true, "created": created}
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type AllocationGroup struct {
+	Name     string           `json:"name"`
+	Target   *decimal.Decimal `json:"target"`
+	postings []posting.Posting
+}
+
+type AllocationDrift struct {
+	Date    time.Time                  `json:"date"`
+	Percent map[string]decimal.Decimal `json:"percent"`
+	// maximum deviation from the target across the groups
+	Drift      decimal.Decimal `json:"drift"`
+	Rebalanced bool            `json:"rebalanced"`
+}
+
+// GetAllocationDrift returns the month end allocation of each of the
+// allocation targets. Without any targets, the assets are grouped by
+// the second level account (Assets:Equity, Assets:Debt etc). A month
+// is marked as rebalanced if money was taken out of one group and put
+// into another.
+func GetAllocationDrift(db *gorm.DB) gin.H {
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%").ExcludeFuture().All()
+	postings = service.PopulateMarketPrice(db, postings)
+	groups := allocationGroups(postings)
+
+	total := computeNetworthTimeline(db, postings, false)
+	timelines := lo.Map(groups, func(g AllocationGroup, _ int) map[string]Networth {
+		return lo.KeyBy(computeNetworthTimeline(db, g.postings, false), func(n Networth) string {
+			return n.Date.Format("2006-01-02")
+		})
+	})
+
+	hundred := decimal.NewFromInt(100)
+	drifts := []AllocationDrift{}
+	previous := make([]Networth, len(groups))
+	for i, n := range total {
+		if i+1 < len(total) && total[i+1].Date.Month() == n.Date.Month() {
+			continue
+		}
+
+		drift := AllocationDrift{Date: n.Date, Percent: make(map[string]decimal.Decimal), Drift: decimal.Zero}
+		invested, withdrawn := []string{}, []string{}
+		for j, g := range groups {
+			// the timeline of a group starts only from its first posting
+			current := timelines[j][n.Date.Format("2006-01-02")]
+
+			percent := decimal.Zero
+			if !n.BalanceAmount.IsZero() {
+				percent = current.BalanceAmount.Div(n.BalanceAmount).Mul(hundred)
+			}
+			drift.Percent[g.Name] = percent
+
+			if g.Target != nil {
+				drift.Drift = decimal.Max(drift.Drift, percent.Sub(*g.Target).Abs())
+			}
+
+			if current.InvestmentAmount.GreaterThan(previous[j].InvestmentAmount) {
+				invested = append(invested, g.Name)
+			}
+			if current.WithdrawalAmount.GreaterThan(previous[j].WithdrawalAmount) {
+				withdrawn = append(withdrawn, g.Name)
+			}
+			previous[j] = current
+		}
+
+		drift.Rebalanced = len(drifts) > 0 && len(invested) > 0 && len(withdrawn) > 0 &&
+			(len(invested) > 1 || len(withdrawn) > 1 || invested[0] != withdrawn[0])
+		drifts = append(drifts, drift)
+	}
+
+	return gin.H{"groups": groups, "drifts": drifts}
+}
+
+func allocationGroups(postings []posting.Posting) []AllocationGroup {
+	targets := config.GetConfig().AllocationTargets
+	if len(targets) > 0 {
+		return lo.Map(targets, func(t config.AllocationTarget, _ int) AllocationGroup {
+			target := decimal.NewFromFloat(t.Target)
+			return AllocationGroup{Name: t.Name, Target: &target, postings: accounting.FilterByGlob(postings, t.Accounts)}
+		})
+	}
+
+	byGroup := lo.GroupBy(postings, func(p posting.Posting) string {
+		account := p.Account
+		if service.IsCapitalGains(p) {
+			account = service.CapitalGainsSourceAccount(p.Account)
+		}
+		return strings.Join(lo.Slice(strings.Split(account, ":"), 0, 2), ":")
+	})
+
+	return lo.Map(utils.SortedKeys(byGroup), func(name string, _ int) AllocationGroup {
+		return AllocationGroup{Name: name, postings: byGroup[name]}
+	})
+}
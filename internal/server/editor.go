@@ -8,9 +8,9 @@ import (
 	"os"
 
 	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
 	"github.com/ananthakumaran/paisa/internal/ledger"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
-	"github.com/bmatcuk/doublestar/v4"
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
@@ -32,11 +32,11 @@ func GetFiles(db *gorm.DB) gin.H {
 	db.Model(&posting.Posting{}).Distinct().Pluck("Payee", &payees)
 	db.Model(&posting.Posting{}).Distinct().Pluck("Commodity", &commodities)
 
-	path := config.GetJournalPath()
+	path := journal.JournalPath(db)
 
 	files := []*LedgerFile{}
 	dir := filepath.Dir(path)
-	paths, _ := doublestar.FilepathGlob(dir + "/**/*" + filepath.Ext(path))
+	paths, _ := config.GlobFiles(dir, filepath.Ext(path))
 
 	for _, path = range paths {
 		files = append(files, readLedgerFileWithVersions(dir, path))
@@ -45,17 +45,17 @@ func GetFiles(db *gorm.DB) gin.H {
 	return gin.H{"files": files, "accounts": accounts, "payees": payees, "commodities": commodities}
 }
 
-func GetFile(file LedgerFile) gin.H {
-	path := config.GetJournalPath()
+func GetFile(db *gorm.DB, file LedgerFile) gin.H {
+	path := journal.JournalPath(db)
 	dir := filepath.Dir(path)
 	return gin.H{"file": readLedgerFile(dir, filepath.Join(dir, file.Name))}
 }
 
-func DeleteBackups(file LedgerFile) gin.H {
-	path := config.GetJournalPath()
+func DeleteBackups(db *gorm.DB, file LedgerFile, readonly bool) gin.H {
+	path := journal.JournalPath(db)
 	dir := filepath.Dir(path)
 
-	if !config.GetConfig().Readonly {
+	if !readonly {
 		versions, _ := filepath.Glob(filepath.Join(dir, file.Name+".backup.*"))
 		for _, version := range versions {
 			err := os.Remove(version)
@@ -69,12 +69,12 @@ func DeleteBackups(file LedgerFile) gin.H {
 }
 
 func SaveFile(db *gorm.DB, file LedgerFile) gin.H {
-	errors, _, err := validateFile(file)
+	errors, _, err := validateFile(db, file)
 	if err != nil {
 		return gin.H{"errors": errors, "saved": false, "message": "Validation failed"}
 	}
 
-	path := config.GetJournalPath()
+	path := journal.JournalPath(db)
 	dir := filepath.Dir(path)
 
 	filePath := filepath.Join(dir, file.Name)
@@ -105,6 +105,12 @@ func SaveFile(db *gorm.DB, file LedgerFile) gin.H {
 			return gin.H{"errors": errors, "saved": false, "message": "Failed to read file"}
 		}
 
+		err = journal.Backup(db, "save "+file.Name, file.Name)
+		if err != nil {
+			log.Warn(err)
+			return gin.H{"errors": errors, "saved": false, "message": "Failed to create backup"}
+		}
+
 		err = os.WriteFile(backupPath, existingContent, perm)
 		if err != nil {
 			log.Warn(err)
@@ -118,18 +124,19 @@ func SaveFile(db *gorm.DB, file LedgerFile) gin.H {
 		return gin.H{"errors": errors, "saved": false, "message": "Failed to write file"}
 	}
 
+	journal.Written(db, "save "+file.Name, file.Name)
 	Sync(db, SyncRequest{Journal: true})
 
 	return gin.H{"errors": errors, "saved": true, "file": readLedgerFileWithVersions(dir, filePath)}
 }
 
-func ValidateFile(file LedgerFile) gin.H {
-	errors, output, _ := validateFile(file)
+func ValidateFile(db *gorm.DB, file LedgerFile) gin.H {
+	errors, output, _ := validateFile(db, file)
 	return gin.H{"errors": errors, "output": output}
 }
 
-func validateFile(file LedgerFile) ([]ledger.LedgerFileError, string, error) {
-	path := config.GetJournalPath()
+func validateFile(db *gorm.DB, file LedgerFile) ([]ledger.LedgerFileError, string, error) {
+	path := journal.JournalPath(db)
 
 	tmpfile, err := os.CreateTemp(filepath.Dir(path), "paisa-tmp-")
 	if err != nil {
@@ -146,7 +153,7 @@ func validateFile(file LedgerFile) ([]ledger.LedgerFileError, string, error) {
 		log.Fatal(err)
 	}
 
-	return ledger.Cli().ValidateFile(tmpfile.Name())
+	return ledger.CliFor(path).ValidateFile(tmpfile.Name())
 }
 
 func readLedgerFile(dir string, path string) *LedgerFile {
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/event"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/server/goal"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/ananthakumaran/paisa/internal/watcher"
+	"github.com/ananthakumaran/paisa/internal/webhook"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var eventSourcesOnce sync.Once
+
+// startEventSources publishes the events that are not triggered by a
+// request handler, the end of a month, changes to the journal files
+// and the config file, and the budget thresholds and goals that are
+// re-evaluated every time the ledger is loaded. Connected clients and
+// webhooks are notified about the changes.
+func startEventSources(db *gorm.DB) {
+	eventSourcesOnce.Do(func() {
+		go monthClock(time.Hour)
+		go watchJournal(db, 500*time.Millisecond)
+		go watchConfig(500 * time.Millisecond)
+		webhook.Start()
+
+		thresholds := budgetThresholds{crossed: make(map[string]bool)}
+		event.Subscribe(event.LedgerLoaded, func(e event.Event) {
+			if e.Data["namespace"] != "" {
+				return
+			}
+			thresholds.check(db)
+		})
+
+		goals := goalProgress{reached: make(map[string]bool)}
+		for _, name := range []event.Name{event.LedgerLoaded, event.PriceUpdated} {
+			event.Subscribe(name, func(e event.Event) {
+				if e.Data["namespace"] != "" {
+					return
+				}
+				goals.check(db)
+			})
+		}
+
+		event.Subscribe(event.LedgerLoaded, func(e event.Event) {
+			if e.Data["namespace"] != "" {
+				return
+			}
+			notifications.broadcast(Notification{Type: "sync_completed"})
+		})
+
+		event.Subscribe(event.MonthClosed, func(e event.Event) {
+			month, _ := e.Data["month"].(string)
+			if config.GetConfig().Notifications.BudgetSummary {
+				sendBudgetSummary(db, month)
+			}
+			if config.GetConfig().Notifications.MonthlySummary {
+				if date, err := (MonthRequest{Month: month}).ParseMonth(utils.Now()); err == nil {
+					sendMonthlySummary(db, date)
+				}
+			}
+		})
+
+		// with watch_journal, the server syncs the journal and the
+		// clients refresh on sync_completed, otherwise the clients
+		// trigger the sync
+		event.Subscribe(event.JournalChanged, func(e event.Event) {
+			if config.GetConfig().WatchJournal {
+				syncChangedJournal(db)
+				return
+			}
+			files, _ := e.Data["files"].([]string)
+			notifications.broadcast(Notification{Type: "journal_changed", Files: files})
+		})
+	})
+}
+
+func watchJournal(db *gorm.DB, debounce time.Duration) {
+	dir := journal.Dir(db)
+	w := watcher.New(dir, filepath.Ext(journal.JournalPath(db)), config.GetBackupsDir())
+	w.Watch(debounce, func(paths []string) {
+		files := lo.Map(paths, func(path string, _ int) string {
+			name, err := filepath.Rel(dir, path)
+			if err != nil {
+				return path
+			}
+			return name
+		})
+		event.Publish(event.JournalChanged, map[string]any{"files": files})
+	})
+}
+
+var watchSyncMu sync.Mutex
+
+// syncChangedJournal imports the changed journal files, the clients
+// are refreshed once the ledger is loaded. The changes made by Paisa
+// itself are already imported by the time they are noticed, so the
+// sync finds nothing new in that case.
+func syncChangedJournal(db *gorm.DB) {
+	watchSyncMu.Lock()
+	defer watchSyncMu.Unlock()
+
+	result := Sync(db, SyncRequest{Journal: true})
+	if result["success"] == false {
+		log.Warn("Failed to sync the changed journal: ", result["message"])
+	}
+}
+
+func monthClock(interval time.Duration) {
+	month := utils.Now().Format("2006-01")
+	for range time.Tick(interval) {
+		current := utils.Now().Format("2006-01")
+		if current != month {
+			event.Publish(event.MonthClosed, map[string]any{"month": month})
+			month = current
+		}
+	}
+}
+
+type budgetThresholds struct {
+	sync.Mutex
+	initialized bool
+	crossed     map[string]bool
+}
+
+// check publishes an event for each budget account of the current
+// month that went over the budget since the last check. Accounts that
+// are already over the budget when the server starts are not reported.
+func (t *budgetThresholds) check(db *gorm.DB) {
+	t.Lock()
+	defer t.Unlock()
+
+	month := utils.Now().Format("2006-01")
+	budgets, ok := GetCurrentBudget(db)["budgetsByMonth"].(map[string]Budget)
+	if !ok {
+		return
+	}
+
+	for _, budget := range budgets[month].Accounts {
+		key := month + ":" + budget.Account
+		if !budget.Available.IsNegative() {
+			delete(t.crossed, key)
+			continue
+		}
+
+		if t.crossed[key] {
+			continue
+		}
+		t.crossed[key] = true
+
+		if t.initialized {
+			event.Publish(event.ThresholdCrossed, map[string]any{
+				"kind":    "budget",
+				"account": budget.Account,
+				"value":   budget.Actual,
+				"limit":   budget.Forecast.Add(budget.Rollover),
+			})
+		}
+	}
+	t.initialized = true
+}
+
+type goalProgress struct {
+	sync.Mutex
+	initialized bool
+	reached     map[string]bool
+}
+
+// check publishes an event for each goal that reached its target
+// since the last check, like the budget thresholds the goals that are
+// already reached when the server starts are not reported.
+func (g *goalProgress) check(db *gorm.DB) {
+	g.Lock()
+	defer g.Unlock()
+
+	for _, summary := range goal.GetGoalSummaries(db) {
+		if summary.Target.IsZero() || summary.Current.LessThan(summary.Target) {
+			delete(g.reached, summary.Id)
+			continue
+		}
+
+		if g.reached[summary.Id] {
+			continue
+		}
+		g.reached[summary.Id] = true
+
+		if g.initialized {
+			event.Publish(event.GoalReached, map[string]any{
+				"type":    summary.Type,
+				"name":    summary.Name,
+				"current": summary.Current,
+				"target":  summary.Target,
+			})
+		}
+	}
+	g.initialized = true
+}
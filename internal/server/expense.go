@@ -4,6 +4,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/model/transaction"
 	"github.com/ananthakumaran/paisa/internal/query"
@@ -36,19 +37,19 @@ type Graph struct {
 }
 
 func GetCurrentExpense(db *gorm.DB) map[string][]posting.Posting {
-	expenses := query.Init(db).LastNMonths(3).Like("Expenses:%").NotAccountPrefix("Expenses:Tax").All()
+	expenses := query.Init(db).LastNMonths(3).Like("Expenses:%").NotAccountPrefix(config.TaxAccounts()...).All()
 	return utils.GroupByMonth(expenses)
 }
 
-func GetExpense(db *gorm.DB) gin.H {
-	expenses := query.Init(db).Like("Expenses:%").NotAccountPrefix("Expenses:Tax").All()
+func GetExpense(db *gorm.DB, year utils.YearKind) gin.H {
+	expenses := query.Init(db).Like("Expenses:%").NotAccountPrefix(config.TaxAccounts()...).All()
 	incomes := query.Init(db).Like("Income:%").All()
-	investments := query.Init(db).Like("Assets:%").NotAccountPrefix("Assets:Checking").All()
-	taxes := query.Init(db).AccountPrefix("Expenses:Tax").All()
+	investments := query.Init(db).Like("Assets:%").NotAccountPrefix(config.CheckingAccounts()...).All()
+	taxes := query.Init(db).AccountPrefix(config.TaxAccounts()...).All()
 	postings := query.Init(db).All()
 
 	graph := make(map[string]Graph)
-	for fy, ps := range utils.GroupByFY(postings) {
+	for fy, ps := range utils.GroupByYear(postings, year) {
 		graph[fy] = sortGraph(computeHierarchyGraph(ps))
 	}
 
@@ -60,10 +61,10 @@ func GetExpense(db *gorm.DB) gin.H {
 			"investments": utils.GroupByMonth(investments),
 			"taxes":       utils.GroupByMonth(taxes)},
 		"year_wise": gin.H{
-			"expenses":    utils.GroupByFY(expenses),
-			"incomes":     utils.GroupByFY(incomes),
-			"investments": utils.GroupByFY(investments),
-			"taxes":       utils.GroupByFY(taxes)},
+			"expenses":    utils.GroupByYear(expenses, year),
+			"incomes":     utils.GroupByYear(incomes, year),
+			"investments": utils.GroupByYear(investments, year),
+			"taxes":       utils.GroupByYear(taxes, year)},
 		"graph": graph}
 }
 
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type ExpenseCalendarDay struct {
+	Date     time.Time         `json:"date"`
+	InMonth  bool              `json:"in_month"`
+	Expense  decimal.Decimal   `json:"expense"`
+	Forecast decimal.Decimal   `json:"forecast"`
+	Postings []posting.Posting `json:"postings"`
+	// upcoming forecast postings of the day
+	Scheduled []posting.Posting `json:"scheduled"`
+}
+
+// GetExpenseCalendar lays out the days of the month as weeks starting
+// on Sunday, padded with the days of the adjacent months, along with
+// the expenses of each day and the forecast postings that are yet to
+// happen.
+func GetExpenseCalendar(db *gorm.DB, month time.Time) gin.H {
+	start := utils.BeginningOfMonth(month)
+	end := utils.EndOfMonth(month)
+	first := start.AddDate(0, 0, -int(start.Weekday()))
+	last := utils.EndOfDay(end.AddDate(0, 0, int(time.Saturday-end.Weekday())))
+
+	expenses := lo.GroupBy(query.Init(db).Like("Expenses:%").Between(first, last).All(), func(p posting.Posting) string {
+		return p.Date.Format("2006-01-02")
+	})
+	today := utils.EndOfToday()
+	forecasts := lo.GroupBy(query.Init(db).Like("Expenses:%").Forecast().Between(utils.MaxTime(first, today), last).All(), func(p posting.Posting) string {
+		return p.Date.Format("2006-01-02")
+	})
+
+	weeks := [][]ExpenseCalendarDay{}
+	for week := first; week.Before(last); week = week.AddDate(0, 0, 7) {
+		days := []ExpenseCalendarDay{}
+		for day := week; day.Before(week.AddDate(0, 0, 7)); day = day.AddDate(0, 0, 1) {
+			key := day.Format("2006-01-02")
+			days = append(days, ExpenseCalendarDay{
+				Date:      day,
+				InMonth:   day.Month() == start.Month(),
+				Expense:   utils.SumBy(expenses[key], func(p posting.Posting) decimal.Decimal { return p.Amount }),
+				Forecast:  utils.SumBy(forecasts[key], func(p posting.Posting) decimal.Decimal { return p.Amount }),
+				Postings:  lo.Ternary(expenses[key] == nil, []posting.Posting{}, expenses[key]),
+				Scheduled: lo.Ternary(forecasts[key] == nil, []posting.Posting{}, forecasts[key]),
+			})
+		}
+		weeks = append(weeks, days)
+	}
+
+	inMonth := lo.Filter(lo.Flatten(weeks), func(d ExpenseCalendarDay, _ int) bool { return d.InMonth })
+	return gin.H{
+		"month":    start.Format("2006-01"),
+		"weeks":    weeks,
+		"expense":  utils.SumBy(inMonth, func(d ExpenseCalendarDay) decimal.Decimal { return d.Expense }),
+		"forecast": utils.SumBy(inMonth, func(d ExpenseCalendarDay) decimal.Decimal { return d.Forecast }),
+	}
+}
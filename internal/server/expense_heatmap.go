@@ -0,0 +1,65 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ExpenseHeatmap has the average spending of each category, indexed
+// by the weekday (Sunday is 0) and the month (January is 0).
+type ExpenseHeatmap struct {
+	Category  string              `json:"category"`
+	DayOfWeek [7]decimal.Decimal  `json:"day_of_week"`
+	Month     [12]decimal.Decimal `json:"month"`
+}
+
+// GetExpenseHeatmap averages the weekday spending over the number of
+// weeks and the monthly spending over the number of times the month
+// occurred since the first expense.
+func GetExpenseHeatmap(db *gorm.DB) gin.H {
+	expenses := query.Init(db).Like("Expenses:%").NotAccountPrefix(config.TaxAccounts()...).ExcludeFuture().All()
+	if len(expenses) == 0 {
+		return gin.H{"heatmaps": []ExpenseHeatmap{}}
+	}
+
+	start := expenses[0].Date
+	end := utils.EndOfToday()
+	weeks := decimal.NewFromFloat(end.Sub(start).Hours() / (24 * 7))
+	if weeks.LessThan(decimal.NewFromInt(1)) {
+		weeks = decimal.NewFromInt(1)
+	}
+
+	months := [12]int{}
+	for month := utils.BeginningOfMonth(start); month.Before(end); month = month.AddDate(0, 1, 0) {
+		months[month.Month()-time.January]++
+	}
+
+	byCategory := lo.GroupBy(expenses, func(p posting.Posting) string { return accountCategory(p.Account) })
+	heatmaps := lo.Map(utils.SortedKeys(byCategory), func(category string, _ int) ExpenseHeatmap {
+		heatmap := ExpenseHeatmap{Category: category}
+		for _, p := range byCategory[category] {
+			heatmap.DayOfWeek[p.Date.Weekday()] = heatmap.DayOfWeek[p.Date.Weekday()].Add(p.Amount)
+			heatmap.Month[p.Date.Month()-time.January] = heatmap.Month[p.Date.Month()-time.January].Add(p.Amount)
+		}
+
+		for i := range heatmap.DayOfWeek {
+			heatmap.DayOfWeek[i] = heatmap.DayOfWeek[i].Div(weeks)
+		}
+		for i, count := range months {
+			if count > 0 {
+				heatmap.Month[i] = heatmap.Month[i].Div(decimal.NewFromInt(int64(count)))
+			}
+		}
+		return heatmap
+	})
+
+	return gin.H{"heatmaps": heatmaps}
+}
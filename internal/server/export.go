@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/locale"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/pdf"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/server/assets"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type ExportRequest struct {
+	Format  string `form:"format"`
+	Locale  string `form:"locale"`
+	Profile string `form:"profile"`
+}
+
+type exportTable struct {
+	Columns []string
+	Rows    [][]any
+}
+
+var exporters = map[string]func(db *gorm.DB) exportTable{
+	"networth":           exportNetworth,
+	"cash_flow":          exportCashFlow,
+	"budget":             exportBudget,
+	"assets":             exportAssets,
+	"capital_gains":      exportCapitalGains,
+	"yearly_summary":     exportYearlySummary,
+	"networth_statement": exportNetworthStatement,
+}
+
+// ExportReport writes the report as a csv, json or pdf attachment. The
+// csv and pdf numbers are formatted as per the locale, which defaults
+// to the configured one. Locales that use comma as the decimal
+// separator get semicolon as the field separator, which is what the
+// spreadsheets expect there. The json numbers are left as is.
+func ExportReport(c *gin.Context, db *gorm.DB, report string, request ExportRequest) {
+	exporter, ok := exporters[report]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Unknown report %s", report)})
+		return
+	}
+
+	table := exportTable{}
+	filename := fmt.Sprintf("paisa-%s-%s", strings.ReplaceAll(report, "_", "-"), utils.Now().Format("2006-01-02"))
+	if report == "capital_gains" && request.Profile != "" {
+		profile, found := lo.Find(config.GetConfig().CapitalGainsProfiles, func(p config.CapitalGainsProfile) bool { return p.Name == request.Profile })
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Unknown capital gains profile %s", request.Profile)})
+			return
+		}
+
+		var err error
+		table, err = exportCapitalGainsProfile(db, profile)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		filename = fmt.Sprintf("%s-%s", filename, profile.Name)
+	} else {
+		table = exporter(db)
+	}
+
+	switch request.Format {
+	case "json":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", filename))
+		c.JSON(http.StatusOK, exportRecords(table))
+	case "", "csv", "pdf":
+		rules := locale.Active()
+		if request.Locale != "" {
+			requested, err := locale.ForLocale(request.Locale)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			requested.DateFormat = rules.DateFormat
+			rules = requested
+		}
+
+		if request.Format == "pdf" {
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.pdf", filename))
+			c.Data(http.StatusOK, "application/pdf", renderPDF(report, table, rules))
+			return
+		}
+
+		content, err := renderCSV(table, rules)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", content)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown format %s", request.Format)})
+	}
+}
+
+// ExportReportNames returns the reports that can be exported.
+func ExportReportNames() []string {
+	return utils.SortedKeys(exporters)
+}
+
+// WriteReport writes the report to w in the json, csv or table format,
+// the table format aligns the columns for the terminal. It's used by
+// the report command to run the reports without the server.
+func WriteReport(w io.Writer, db *gorm.DB, report string, format string) error {
+	exporter, ok := exporters[report]
+	if !ok {
+		return fmt.Errorf("Unknown report %s", report)
+	}
+
+	table := exporter(db)
+	rules := locale.Active()
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(exportRecords(table))
+	case "csv":
+		content, err := renderCSV(table, rules)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
+		return err
+	case "", "table":
+		return renderText(w, table, rules)
+	default:
+		return fmt.Errorf("Unknown format %s", format)
+	}
+}
+
+func exportRecords(table exportTable) []map[string]any {
+	return lo.Map(table.Rows, func(row []any, _ int) map[string]any {
+		record := make(map[string]any)
+		for i, column := range table.Columns {
+			record[column] = row[i]
+		}
+		return record
+	})
+}
+
+func renderText(w io.Writer, table exportTable, rules locale.Rules) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(lo.Map(table.Columns, func(column string, _ int) string { return exportTitle(column) }), "\t"))
+	for _, row := range table.Rows {
+		fmt.Fprintln(writer, strings.Join(lo.Map(row, func(value any, _ int) string {
+			switch v := value.(type) {
+			case decimal.Decimal:
+				return rules.Number(v, 2)
+			case time.Time:
+				return rules.Date(v)
+			default:
+				return fmt.Sprint(v)
+			}
+		}), "\t"))
+	}
+	return writer.Flush()
+}
+
+func renderCSV(table exportTable, rules locale.Rules) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	if rules.DecimalSeparator == "," {
+		writer.Comma = ';'
+	}
+
+	err := writer.Write(table.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range table.Rows {
+		record := lo.Map(row, func(value any, _ int) string {
+			switch v := value.(type) {
+			case decimal.Decimal:
+				return rules.Decimal(v, 4)
+			case time.Time:
+				return rules.Date(v)
+			default:
+				return fmt.Sprint(v)
+			}
+		})
+		err = writer.Write(record)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+// renderPDF lays out the table as a statement that can be shared, the
+// numbers are right aligned and rounded to 2 decimals.
+func renderPDF(report string, table exportTable, rules locale.Rules) []byte {
+	aligns := lo.Map(table.Columns, func(_ string, i int) pdf.Align {
+		numeric := len(table.Rows) > 0 && lo.EveryBy(table.Rows, func(row []any) bool {
+			_, ok := row[i].(decimal.Decimal)
+			return ok
+		})
+		return lo.Ternary(numeric, pdf.Right, pdf.Left)
+	})
+
+	rows := lo.Map(table.Rows, func(row []any, _ int) []string {
+		return lo.Map(row, func(value any, _ int) string {
+			switch v := value.(type) {
+			case decimal.Decimal:
+				return rules.Number(v, 2)
+			case time.Time:
+				return rules.Date(v)
+			default:
+				return fmt.Sprint(v)
+			}
+		})
+	})
+
+	document := pdf.New()
+	document.Heading(exportTitle(report))
+	document.Text(fmt.Sprintf("Generated on %s. Amounts are in %s.", rules.Date(utils.Now()), config.DefaultCurrency()))
+	document.Table(lo.Map(table.Columns, func(column string, _ int) string { return exportTitle(column) }), aligns, rows)
+	return document.Bytes()
+}
+
+func exportTitle(name string) string {
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func exportNetworth(db *gorm.DB) exportTable {
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%", "Liabilities:%").ExcludeFuture().All()
+	postings = service.PopulateMarketPrice(db, postings)
+	table := exportTable{Columns: []string{"date", "investment", "withdrawal", "net_investment", "gain", "balance"}}
+	for _, n := range computeNetworthTimeline(db, postings, false) {
+		table.Rows = append(table.Rows, []any{n.Date, n.InvestmentAmount, n.WithdrawalAmount, n.NetInvestmentAmount, n.GainAmount, n.BalanceAmount})
+	}
+	return table
+}
+
+func exportCashFlow(db *gorm.DB) exportTable {
+	table := exportTable{Columns: []string{"date", "income", "expenses", "liabilities", "investment", "tax", "checking", "balance"}}
+	for _, f := range computeCashFlow(db, query.Init(db), decimal.Zero) {
+		table.Rows = append(table.Rows, []any{f.Date, f.Income, f.Expenses, f.Liabilities, f.Investment, f.Tax, f.Checking, f.Balance})
+	}
+	return table
+}
+
+func exportBudget(db *gorm.DB) exportTable {
+	budgetsByMonth := GetBudget(db)["budgetsByMonth"].(map[string]Budget)
+	table := exportTable{Columns: []string{"month", "account", "forecast", "actual", "rollover", "available"}}
+	for _, month := range utils.SortedKeys(budgetsByMonth) {
+		for _, b := range budgetsByMonth[month].Accounts {
+			table.Rows = append(table.Rows, []any{month, b.Account, b.Forecast, b.Actual, b.Rollover, b.Available})
+		}
+	}
+	return table
+}
+
+func exportAssets(db *gorm.DB) exportTable {
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%").All()
+	postings = service.PopulateMarketPrice(db, postings)
+	breakdowns := assets.ComputeBreakdowns(db, postings, true)
+	table := exportTable{Columns: []string{"account", "investment", "withdrawal", "market_value", "units", "latest_price", "gain", "absolute_return", "xirr"}}
+	for _, group := range utils.SortedKeys(breakdowns) {
+		b := breakdowns[group]
+		table.Rows = append(table.Rows, []any{b.Group, b.InvestmentAmount, b.WithdrawalAmount, b.MarketAmount, b.BalanceUnits, b.LatestPrice, b.GainAmount, b.AbsoluteReturn, b.XIRR})
+	}
+	return table
+}
+
+func exportCapitalGains(db *gorm.DB) exportTable {
+	capitalGains := GetCapitalGains(db)["capital_gains"].(map[string]CapitalGain)
+	table := exportTable{Columns: []string{"financial_year", "account", "tax_category", "units", "purchase_price", "sell_price", "gain", "taxable", "short_term_tax", "long_term_tax", "slab_tax"}}
+	for _, account := range utils.SortedKeys(capitalGains) {
+		cg := capitalGains[account]
+		for _, fy := range utils.SortedKeys(cg.FY) {
+			g := cg.FY[fy]
+			table.Rows = append(table.Rows, []any{fy, cg.Account, cg.TaxCategory, g.Units, g.PurchasePrice, g.SellPrice, g.Tax.Gain, g.Tax.Taxable, g.Tax.ShortTerm, g.Tax.LongTerm, g.Tax.Slab})
+		}
+	}
+	return table
+}
+
+func exportYearlySummary(db *gorm.DB) exportTable {
+	statements := computeStatement(db, utils.FiscalYear, query.Init(db).All())
+	sum := func(amounts map[string]decimal.Decimal) decimal.Decimal {
+		return utils.SumBy(lo.Values(amounts), func(amount decimal.Decimal) decimal.Decimal { return amount })
+	}
+
+	table := exportTable{Columns: []string{"financial_year", "starting_balance", "income", "interest", "equity", "pnl", "liabilities", "tax", "expenses", "ending_balance"}}
+	for _, fy := range utils.SortedKeys(statements) {
+		s := statements[fy]
+		table.Rows = append(table.Rows, []any{fy, s.StartingBalance, sum(s.Income), sum(s.Interest), sum(s.Equity), sum(s.Pnl), sum(s.Liabilities), sum(s.Tax), sum(s.Expenses), s.EndingBalance})
+	}
+	return table
+}
+
+// exportNetworthStatement lists the balance of each asset and
+// liability account as of today, along with the total networth.
+func exportNetworthStatement(db *gorm.DB) exportTable {
+	postings := query.Init(db).Like("Assets:%", "Liabilities:%").ExcludeFuture().All()
+	postings = service.PopulateMarketPrice(db, postings)
+	byAccount := lo.GroupBy(postings, func(p posting.Posting) string { return p.Account })
+
+	table := exportTable{Columns: []string{"account", "cost", "market_value"}}
+	cost, marketValue := decimal.Zero, decimal.Zero
+	for _, account := range utils.SortedKeys(byAccount) {
+		ps := byAccount[account]
+		c := accounting.CostBalance(ps)
+		m := accounting.CurrentBalance(ps)
+		if m.Abs().LessThan(decimal.NewFromFloat(0.01)) && c.Abs().LessThan(decimal.NewFromFloat(0.01)) {
+			continue
+		}
+
+		table.Rows = append(table.Rows, []any{account, c, m})
+		cost = cost.Add(c)
+		marketValue = marketValue.Add(m)
+	}
+	table.Rows = append(table.Rows, []any{"Networth", cost, marketValue})
+	return table
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const FEDERATION_TOKEN_HEADER = "X-Federation-Token"
+const FEDERATION_SUMMARY_PATH = "/api/federation/summary"
+
+type FederationSummary struct {
+	Name       string               `json:"name"`
+	Currency   string               `json:"currency"`
+	Networth   Networth             `json:"networth"`
+	Allocation map[string]Aggregate `json:"allocation"`
+	Error      string               `json:"error,omitempty"`
+}
+
+// FederationTokenFor returns the configured federation token matching
+// the token sent by the other instance.
+func FederationTokenFor(token string) (config.FederationToken, bool) {
+	if token == "" {
+		return config.FederationToken{}, false
+	}
+
+	hashed := "sha256:" + utils.Sha256(token)
+	for _, t := range config.GetConfig().Federation.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t.Token), []byte(hashed)) == 1 {
+			return t, true
+		}
+	}
+	return config.FederationToken{}, false
+}
+
+func GetFederationSummary(db *gorm.DB) FederationSummary {
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%", "Liabilities:%").ExcludeFuture().All()
+	postings = service.PopulateMarketPrice(db, postings)
+	networth := computeNetworth(db, postings)
+
+	now := utils.EndOfToday()
+	assets := lo.Filter(postings, func(p posting.Posting, _ int) bool { return strings.HasPrefix(p.Account, "Assets:") })
+	assets = lo.Map(assets, func(p posting.Posting, _ int) posting.Posting {
+		p.MarketAmount = service.GetMarketPrice(db, p, now)
+		return p
+	})
+
+	// only the top level accounts are shared, the rest of the account
+	// tree stays private to the instance
+	allocation := lo.PickBy(computeAggregate(db, assets, now), func(account string, _ Aggregate) bool {
+		return len(strings.Split(account, ":")) <= 2
+	})
+
+	return FederationSummary{
+		Currency:   config.DefaultCurrency(),
+		Networth:   networth,
+		Allocation: allocation,
+	}
+}
+
+// GetFederation combines the summary of this instance with the
+// summaries of the configured remote instances. Remotes that use a
+// different default currency are listed but left out of the total.
+func GetFederation(db *gorm.DB) gin.H {
+	local := GetFederationSummary(db)
+	local.Name = "Self"
+
+	remotes := config.GetConfig().Federation.Remotes
+	summaries := make([]FederationSummary, len(remotes))
+	var wg sync.WaitGroup
+	for i, remote := range remotes {
+		wg.Add(1)
+		go func(i int, remote config.FederationRemote) {
+			defer wg.Done()
+			summary, err := fetchFederationSummary(remote)
+			if err != nil {
+				log.Warn(err)
+				summary = FederationSummary{Error: err.Error()}
+			}
+			summary.Name = remote.Name
+			summaries[i] = summary
+		}(i, remote)
+	}
+	wg.Wait()
+
+	summaries = append([]FederationSummary{local}, summaries...)
+
+	total := decimal.Zero
+	allocation := make(map[string]decimal.Decimal)
+	for _, summary := range summaries {
+		if summary.Error != "" || summary.Currency != local.Currency {
+			continue
+		}
+
+		total = total.Add(summary.Networth.BalanceAmount)
+		for account, aggregate := range summary.Allocation {
+			allocation[account] = allocation[account].Add(aggregate.MarketAmount)
+		}
+	}
+
+	return gin.H{"summaries": summaries, "total": total, "allocation": allocation, "currency": local.Currency}
+}
+
+var federationClient = &http.Client{Timeout: 30 * time.Second}
+
+func fetchFederationSummary(remote config.FederationRemote) (FederationSummary, error) {
+	var summary FederationSummary
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(remote.URL, "/")+FEDERATION_SUMMARY_PATH, nil)
+	if err != nil {
+		return summary, err
+	}
+	req.Header.Set(FEDERATION_TOKEN_HEADER, remote.Token)
+
+	resp, err := federationClient.Do(req)
+	if err != nil {
+		return summary, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return summary, fmt.Errorf("Failed to fetch summary from %s: status %d", remote.Name, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&summary)
+	return summary, err
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/depreciation"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type FixedAssetSummary struct {
+	Name           string               `json:"name"`
+	Account        string               `json:"account"`
+	ExpenseAccount string               `json:"expense_account"`
+	Method         string               `json:"method"`
+	PurchaseDate   string               `json:"purchase_date"`
+	Cost           decimal.Decimal      `json:"cost"`
+	Salvage        decimal.Decimal      `json:"salvage"`
+	Accumulated    decimal.Decimal      `json:"accumulated"`
+	BookValue      decimal.Decimal      `json:"book_value"`
+	Posted         bool                 `json:"posted"`
+	Schedule       []depreciation.Point `json:"schedule"`
+}
+
+// GetFixedAssets reports the book value of each fixed asset as of
+// today along with the depreciation schedule. The schedule covers the
+// whole life of the asset, or till today when the life is not known.
+func GetFixedAssets(db *gorm.DB) gin.H {
+	today := utils.EndOfToday()
+	summaries := []FixedAssetSummary{}
+	for _, asset := range config.GetConfig().FixedAssets {
+		until := today
+		if asset.Life > 0 {
+			until = today.AddDate(asset.Life+1, 0, 0)
+		}
+		schedule, err := depreciation.Schedule(asset, until)
+		if err != nil {
+			return gin.H{"fixed_assets": summaries, "error": err.Error()}
+		}
+
+		summary := FixedAssetSummary{
+			Name:           asset.Name,
+			Account:        asset.Account,
+			ExpenseAccount: depreciation.ExpenseAccount(asset),
+			Method:         depreciation.Method(asset),
+			PurchaseDate:   asset.PurchaseDate,
+			Cost:           decimal.NewFromFloat(asset.Cost),
+			Salvage:        decimal.NewFromFloat(asset.Salvage),
+			BookValue:      decimal.NewFromFloat(asset.Cost),
+			Posted:         asset.Post,
+			Schedule:       schedule,
+		}
+		for _, point := range schedule {
+			if point.Date.After(today) {
+				break
+			}
+			summary.Accumulated = point.Accumulated
+			summary.BookValue = point.BookValue
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return gin.H{"fixed_assets": summaries}
+}
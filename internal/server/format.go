@@ -0,0 +1,73 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type FormatRequest struct {
+	Name   string `json:"name"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type FormatChange struct {
+	FileName string `json:"file_name"`
+	Diff     string `json:"diff"`
+}
+
+// FormatFiles reformats the given journal file, or all the journal
+// files if the name is empty. In dry run mode, only the diff of the
+// files that would change is returned.
+func FormatFiles(db *gorm.DB, request FormatRequest) gin.H {
+	names := []string{request.Name}
+	if request.Name == "" {
+		var err error
+		names, err = journal.Files(db)
+		if err != nil {
+			log.Warn(err)
+			return gin.H{"saved": false, "message": "Failed to list files"}
+		}
+	}
+
+	changes := []FormatChange{}
+	files := make(map[string]string)
+	for _, name := range names {
+		content, err := journal.Read(db, name)
+		if err != nil {
+			log.Warn(err)
+			return gin.H{"saved": false, "message": fmt.Sprintf("Failed to read file %s", name)}
+		}
+
+		formatted := journal.FormatFile(content)
+		if formatted == content {
+			continue
+		}
+
+		files[name] = formatted
+		changes = append(changes, FormatChange{FileName: name, Diff: journal.Diff(name, content, formatted)})
+	}
+
+	if request.DryRun || len(files) == 0 {
+		return gin.H{"saved": false, "changes": changes}
+	}
+
+	for name, content := range files {
+		errors, _, err := validateFile(db, LedgerFile{Name: name, Content: content})
+		if err != nil {
+			return gin.H{"errors": errors, "saved": false, "message": fmt.Sprintf("Validation failed for %s", name), "changes": changes}
+		}
+	}
+
+	err := journal.WriteAll(db, "format", files)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": "Failed to write files", "changes": changes}
+	}
+
+	Sync(db, SyncRequest{Journal: true})
+	return gin.H{"saved": true, "changes": changes}
+}
@@ -1,9 +1,8 @@
 package server
 
 import (
-	"strings"
-
 	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
 	"github.com/ananthakumaran/paisa/internal/server/assets"
@@ -30,7 +29,7 @@ type AccountGain struct {
 }
 
 func GetGain(db *gorm.DB) gin.H {
-	postings := query.Init(db).Like("Assets:%", "Income:CapitalGains:%").NotAccountPrefix("Assets:Checking").All()
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%").NotAccountPrefix(config.CheckingAccounts()...).All()
 	postings = service.PopulateMarketPrice(db, postings)
 	byAccount := lo.GroupBy(postings, func(p posting.Posting) string {
 		if service.IsCapitalGains(p) {
@@ -48,7 +47,7 @@ func GetGain(db *gorm.DB) gin.H {
 }
 
 func GetAccountGain(db *gorm.DB, account string) gin.H {
-	capitalGainsAccount := strings.Replace(account, "Assets", "Income:CapitalGains", 1)
+	capitalGainsAccount := service.CapitalGainsAccount(account)
 	postings := query.Init(db).AccountPrefix(account, capitalGainsAccount).All()
 	postings = service.PopulateMarketPrice(db, postings)
 	gain := AccountGain{Account: account, XIRR: service.XIRR(db, postings), NetworthTimeline: computeNetworthTimeline(db, postings, accounting.IsLeafAccount(db, account)), Postings: postings}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/grant"
+	"github.com/ananthakumaran/paisa/internal/model/price"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type UpcomingVest struct {
+	grant.Vest
+	Amount      decimal.Decimal `json:"amount"`
+	Withholding decimal.Decimal `json:"withholding"`
+}
+
+type GrantSummary struct {
+	Name           string          `json:"name"`
+	Commodity      string          `json:"commodity"`
+	Units          decimal.Decimal `json:"units"`
+	Price          decimal.Decimal `json:"price"`
+	VestedUnits    decimal.Decimal `json:"vested_units"`
+	UnvestedUnits  decimal.Decimal `json:"unvested_units"`
+	VestedAmount   decimal.Decimal `json:"vested_amount"`
+	UnvestedAmount decimal.Decimal `json:"unvested_amount"`
+	// tax expected to be withheld on the unvested shares at the
+	// current price
+	Withholding decimal.Decimal `json:"withholding"`
+	Upcoming    []UpcomingVest  `json:"upcoming"`
+}
+
+// GetGrants reports the vested and unvested value of each grant at
+// the latest price. The vested value is the value on the current
+// price, not the value on the vest date.
+func GetGrants(db *gorm.DB) gin.H {
+	now := utils.EndOfToday()
+	summaries := []GrantSummary{}
+	for _, g := range config.GetConfig().Grants {
+		vests, err := grant.Vests(g)
+		if err != nil {
+			return gin.H{"grants": summaries, "error": err.Error()}
+		}
+
+		latest, _ := price.Latest(db, g.Commodity)
+		withholding := decimal.NewFromFloat(g.Withholding).Div(decimal.NewFromInt(100))
+		summary := GrantSummary{
+			Name:          g.Name,
+			Commodity:     g.Commodity,
+			Units:         decimal.NewFromFloat(g.Units),
+			Price:         latest.Value,
+			VestedUnits:   decimal.Zero,
+			UnvestedUnits: decimal.Zero,
+			Upcoming:      []UpcomingVest{},
+		}
+
+		for _, v := range vests {
+			if !v.Date.After(now) {
+				summary.VestedUnits = summary.VestedUnits.Add(v.Units)
+				continue
+			}
+
+			summary.UnvestedUnits = summary.UnvestedUnits.Add(v.Units)
+			amount := v.Units.Mul(latest.Value)
+			summary.Upcoming = append(summary.Upcoming, UpcomingVest{Vest: v, Amount: amount, Withholding: amount.Mul(withholding)})
+		}
+
+		summary.VestedAmount = summary.VestedUnits.Mul(latest.Value)
+		summary.UnvestedAmount = summary.UnvestedUnits.Mul(latest.Value)
+		summary.Withholding = summary.UnvestedAmount.Mul(withholding)
+		summaries = append(summaries, summary)
+	}
+
+	return gin.H{"grants": summaries}
+}
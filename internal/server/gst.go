@@ -0,0 +1,157 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	GST_INPUT  = "input"
+	GST_OUTPUT = "output"
+)
+
+type GSTRequest struct {
+	// monthly or quarterly, defaults to the gst.period in the
+	// configuration
+	Period string `form:"period" binding:"omitempty,oneof=monthly quarterly"`
+}
+
+type GSTPosting struct {
+	Posting posting.Posting `json:"posting"`
+	Kind    string          `json:"kind"`
+	Rate    decimal.Decimal `json:"rate"`
+	// amount including the tax
+	Gross   decimal.Decimal `json:"gross"`
+	Taxable decimal.Decimal `json:"taxable"`
+	Tax     decimal.Decimal `json:"tax"`
+}
+
+type GSTRateSummary struct {
+	Kind    string          `json:"kind"`
+	Rate    decimal.Decimal `json:"rate"`
+	Taxable decimal.Decimal `json:"taxable"`
+	Tax     decimal.Decimal `json:"tax"`
+}
+
+type GSTPeriod struct {
+	Period    string          `json:"period"`
+	Start     time.Time       `json:"start"`
+	End       time.Time       `json:"end"`
+	OutputTax decimal.Decimal `json:"output_tax"`
+	InputTax  decimal.Decimal `json:"input_tax"`
+	// negative when the input tax credit is more than the output tax
+	Payable  decimal.Decimal  `json:"payable"`
+	Rates    []GSTRateSummary `json:"rates"`
+	Postings []GSTPosting     `json:"postings"`
+}
+
+// GetGST extracts the tax included in the business income (output
+// tax) and expenses (input tax) and aggregates them per filing period.
+// The rate of a posting is taken from the gst tag (gst: 18%) and falls
+// back to the first matching rate in the configuration.
+func GetGST(db *gorm.DB, request GSTRequest) gin.H {
+	conf := config.GetConfig().GST
+	period := request.Period
+	if period == "" {
+		period = conf.Period
+	}
+
+	postings := query.Init(db).Like("Income:%", "Expenses:%").All()
+	gstPostings := lo.FilterMap(postings, func(p posting.Posting, _ int) (GSTPosting, bool) {
+		rate, ok := gstRate(p, conf)
+		if !ok {
+			return GSTPosting{}, false
+		}
+		return extractGST(p, rate), true
+	})
+
+	grouped := lo.GroupBy(gstPostings, func(g GSTPosting) time.Time { return gstPeriodStart(g.Posting.Date, period) })
+	starts := lo.Keys(grouped)
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	periods := lo.Map(starts, func(start time.Time, _ int) GSTPeriod {
+		return computeGSTPeriod(start, period, grouped[start])
+	})
+	return gin.H{"period": period, "periods": periods}
+}
+
+func gstRate(p posting.Posting, conf config.GST) (decimal.Decimal, bool) {
+	if value, ok := p.TagValue(conf.Tag); ok {
+		rate, err := decimal.NewFromString(strings.TrimSpace(strings.TrimSuffix(value, "%")))
+		return rate, err == nil
+	}
+
+	for _, r := range conf.Rates {
+		if match, _ := filepath.Match(r.Account, p.Account); match {
+			return decimal.NewFromFloat(r.Rate), true
+		}
+	}
+	return decimal.Zero, false
+}
+
+// extractGST splits the amount of the posting, which includes the
+// tax, into the taxable value and the tax.
+func extractGST(p posting.Posting, rate decimal.Decimal) GSTPosting {
+	g := GSTPosting{Posting: p, Kind: GST_INPUT, Rate: rate, Gross: p.Amount}
+	if utils.IsParent(p.Account, "Income") {
+		g.Kind = GST_OUTPUT
+		g.Gross = p.Amount.Neg()
+	}
+
+	hundred := decimal.NewFromInt(100)
+	g.Tax = g.Gross.Mul(rate).Div(hundred.Add(rate)).Round(2)
+	g.Taxable = g.Gross.Sub(g.Tax)
+	return g
+}
+
+func gstPeriodStart(date time.Time, period string) time.Time {
+	start := utils.BeginningOfMonth(date)
+	if period == "quarterly" {
+		start = start.AddDate(0, -(int(start.Month()-1) % 3), 0)
+	}
+	return start
+}
+
+func computeGSTPeriod(start time.Time, period string, postings []GSTPosting) GSTPeriod {
+	g := GSTPeriod{Start: start, End: utils.EndOfMonth(start), Period: start.Format("2006-01"), Rates: []GSTRateSummary{}, Postings: postings}
+	if period == "quarterly" {
+		g.End = utils.EndOfMonth(start.AddDate(0, 2, 0))
+		g.Period = fmt.Sprintf("%d-Q%d", start.Year(), (start.Month()-1)/3+1)
+	}
+
+	byRate := lo.GroupBy(postings, func(p GSTPosting) string { return p.Kind + ":" + p.Rate.String() })
+	for _, ps := range byRate {
+		summary := GSTRateSummary{Kind: ps[0].Kind, Rate: ps[0].Rate}
+		for _, p := range ps {
+			summary.Taxable = summary.Taxable.Add(p.Taxable)
+			summary.Tax = summary.Tax.Add(p.Tax)
+		}
+		g.Rates = append(g.Rates, summary)
+
+		if summary.Kind == GST_OUTPUT {
+			g.OutputTax = g.OutputTax.Add(summary.Tax)
+		} else {
+			g.InputTax = g.InputTax.Add(summary.Tax)
+		}
+	}
+	sort.Slice(g.Rates, func(i, j int) bool {
+		if g.Rates[i].Kind != g.Rates[j].Kind {
+			return g.Rates[i].Kind > g.Rates[j].Kind
+		}
+		return g.Rates[i].Rate.LessThan(g.Rates[j].Rate)
+	})
+	g.Payable = g.OutputTax.Sub(g.InputTax)
+	return g
+}
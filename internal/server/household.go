@@ -0,0 +1,178 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/profile"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/server/assets"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// name used for the main journal in the household view
+const MAIN_PROFILE = "Self"
+
+type HouseholdRequest struct {
+	Profiles string `form:"profiles"`
+}
+
+type HouseholdMember struct {
+	Name     string   `json:"name"`
+	Networth Networth `json:"networth"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type household struct {
+	networth        Networth
+	cashFlows       []CashFlow
+	assetBreakdowns map[string]assets.AssetBreakdown
+}
+
+// GetHousehold combines the networth, cash flow and asset breakdown of
+// the selected profiles, all the profiles including the main journal
+// by default. The profiles share the config, so the amounts are
+// already in the default currency, the commodities of each book are
+// valued using the prices of the book.
+func GetHousehold(db *gorm.DB, request HouseholdRequest) gin.H {
+	names := append([]string{MAIN_PROFILE}, profile.Names()...)
+	if request.Profiles != "" {
+		names = lo.Uniq(lo.Map(strings.Split(request.Profiles, ","), func(name string, _ int) string { return strings.TrimSpace(name) }))
+	}
+
+	members := []HouseholdMember{}
+	books := []household{}
+	for _, name := range names {
+		memberDB := db
+		if name != MAIN_PROFILE {
+			var err error
+			memberDB, err = profile.DB(name)
+			if err != nil {
+				members = append(members, HouseholdMember{Name: name, Error: err.Error()})
+				continue
+			}
+		}
+
+		book := computeHousehold(memberDB)
+		members = append(members, HouseholdMember{Name: name, Networth: book.networth})
+		books = append(books, book)
+	}
+
+	return gin.H{
+		"currency":         config.DefaultCurrency(),
+		"members":          members,
+		"networth":         mergeNetworth(books),
+		"cash_flows":       mergeCashFlows(books),
+		"asset_breakdowns": mergeAssetBreakdowns(books),
+	}
+}
+
+func computeHousehold(db *gorm.DB) household {
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%", "Liabilities:%").ExcludeFuture().All()
+	postings = service.PopulateMarketPrice(db, postings)
+	networth := computeNetworth(db, postings)
+
+	assetPostings := lo.Filter(postings, func(p posting.Posting, _ int) bool { return !strings.HasPrefix(p.Account, "Liabilities:") })
+	return household{
+		networth:        networth,
+		cashFlows:       computeCashFlow(db, query.Init(db), decimal.Zero),
+		assetBreakdowns: assets.ComputeBreakdowns(db, assetPostings, true),
+	}
+}
+
+func mergeNetworth(books []household) Networth {
+	networth := Networth{Date: utils.EndOfToday()}
+	for _, b := range books {
+		networth.InvestmentAmount = networth.InvestmentAmount.Add(b.networth.InvestmentAmount)
+		networth.WithdrawalAmount = networth.WithdrawalAmount.Add(b.networth.WithdrawalAmount)
+		networth.GainAmount = networth.GainAmount.Add(b.networth.GainAmount)
+		networth.BalanceAmount = networth.BalanceAmount.Add(b.networth.BalanceAmount)
+		networth.NetInvestmentAmount = networth.NetInvestmentAmount.Add(b.networth.NetInvestmentAmount)
+	}
+	return networth
+}
+
+// mergeCashFlows adds up the monthly cash flows. The books could start
+// on different months, the balance of a book is carried forward over
+// the months it has no cash flow.
+func mergeCashFlows(books []household) []CashFlow {
+	byMonth := make(map[time.Time]CashFlow)
+	for _, b := range books {
+		for _, c := range b.cashFlows {
+			month := utils.BeginningOfMonth(c.Date)
+			merged, ok := byMonth[month]
+			if !ok {
+				merged = CashFlow{Date: c.Date}
+			}
+			merged.Income = merged.Income.Add(c.Income)
+			merged.Expenses = merged.Expenses.Add(c.Expenses)
+			merged.Liabilities = merged.Liabilities.Add(c.Liabilities)
+			merged.Investment = merged.Investment.Add(c.Investment)
+			merged.Tax = merged.Tax.Add(c.Tax)
+			merged.Checking = merged.Checking.Add(c.Checking)
+			byMonth[month] = merged
+		}
+	}
+
+	months := lo.Keys(byMonth)
+	sort.Slice(months, func(i, j int) bool { return months[i].Before(months[j]) })
+
+	balances := make([]decimal.Decimal, len(books))
+	cashFlows := []CashFlow{}
+	for _, month := range months {
+		merged := byMonth[month]
+		merged.Balance = decimal.Zero
+		for i, b := range books {
+			for _, c := range b.cashFlows {
+				if utils.BeginningOfMonth(c.Date).Equal(month) {
+					balances[i] = c.Balance
+				}
+			}
+			merged.Balance = merged.Balance.Add(balances[i])
+		}
+		cashFlows = append(cashFlows, merged)
+	}
+	return cashFlows
+}
+
+// mergeAssetBreakdowns adds up the amounts of the groups present in
+// more than one book. The XIRR of such a group is the average of the
+// XIRR of the books weighted by the market value.
+func mergeAssetBreakdowns(books []household) map[string]assets.AssetBreakdown {
+	merged := make(map[string]assets.AssetBreakdown)
+	weightedXIRR := make(map[string]decimal.Decimal)
+	for _, b := range books {
+		for group, breakdown := range b.assetBreakdowns {
+			m, ok := merged[group]
+			if !ok {
+				m = assets.AssetBreakdown{Group: group, LatestPrice: breakdown.LatestPrice}
+			}
+			m.InvestmentAmount = m.InvestmentAmount.Add(breakdown.InvestmentAmount)
+			m.WithdrawalAmount = m.WithdrawalAmount.Add(breakdown.WithdrawalAmount)
+			m.MarketAmount = m.MarketAmount.Add(breakdown.MarketAmount)
+			m.BalanceUnits = m.BalanceUnits.Add(breakdown.BalanceUnits)
+			m.GainAmount = m.GainAmount.Add(breakdown.GainAmount)
+			weightedXIRR[group] = weightedXIRR[group].Add(breakdown.XIRR.Mul(breakdown.MarketAmount))
+			merged[group] = m
+		}
+	}
+
+	for group, m := range merged {
+		if !m.MarketAmount.IsZero() {
+			m.XIRR = weightedXIRR[group].Div(m.MarketAmount)
+		}
+		if !m.InvestmentAmount.IsZero() {
+			m.AbsoluteReturn = m.GainAmount.Div(m.InvestmentAmount)
+		}
+		merged[group] = m
+	}
+	return merged
+}
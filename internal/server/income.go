@@ -3,6 +3,7 @@ package server
 import (
 	"time"
 
+	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
 	"github.com/ananthakumaran/paisa/internal/utils"
@@ -31,16 +32,16 @@ type Tax struct {
 	Postings  []posting.Posting `json:"postings"`
 }
 
-func GetIncome(db *gorm.DB) gin.H {
+func GetIncome(db *gorm.DB, year utils.YearKind) gin.H {
 	incomePostings := query.Init(db).Like("Income:%").All()
-	taxPostings := query.Init(db).AccountPrefix("Expenses:Tax").All()
+	taxPostings := query.Init(db).AccountPrefix(config.TaxAccounts()...).All()
 	p := query.Init(db).First()
 
 	if p == nil {
 		return gin.H{"income_timeline": []Income{}, "tax_timeline": []Tax{}, "yearly_cards": []IncomeYearlyCard{}}
 	}
 
-	return gin.H{"income_timeline": computeIncomeTimeline(incomePostings), "tax_timeline": computeTaxTimeline(taxPostings), "yearly_cards": computeIncomeYearlyCard(p.Date, taxPostings, incomePostings)}
+	return gin.H{"income_timeline": computeIncomeTimeline(incomePostings), "tax_timeline": computeTaxTimeline(year, taxPostings), "yearly_cards": computeIncomeYearlyCard(year, p.Date, taxPostings, incomePostings)}
 }
 
 func computeIncomeTimeline(postings []posting.Posting) []Income {
@@ -65,7 +66,7 @@ func computeIncomeTimeline(postings []posting.Posting) []Income {
 	return incomes
 }
 
-func computeTaxTimeline(postings []posting.Posting) []Tax {
+func computeTaxTimeline(year utils.YearKind, postings []posting.Posting) []Tax {
 	var taxes []Tax = make([]Tax, 0)
 
 	if len(postings) == 0 {
@@ -74,8 +75,8 @@ func computeTaxTimeline(postings []posting.Posting) []Tax {
 
 	var p posting.Posting
 	end := utils.EndOfToday()
-	for start := utils.BeginningOfFinancialYear(postings[0].Date); start.Before(end); start = start.AddDate(1, 0, 0) {
-		yearEnd := utils.EndOfFinancialYear(start)
+	for start := utils.BeginningOfYear(year, postings[0].Date); start.Before(end); start = start.AddDate(1, 0, 0) {
+		yearEnd := utils.EndOfYear(year, start)
 		var currentMonthPostings []posting.Posting = make([]posting.Posting, 0)
 		for len(postings) > 0 && (postings[0].Date.Before(yearEnd) || postings[0].Date.Equal(start)) {
 			p, postings = postings[0], postings[1:]
@@ -88,13 +89,13 @@ func computeTaxTimeline(postings []posting.Posting) []Tax {
 	return taxes
 }
 
-func computeIncomeYearlyCard(start time.Time, taxes []posting.Posting, incomes []posting.Posting) []IncomeYearlyCard {
+func computeIncomeYearlyCard(year utils.YearKind, start time.Time, taxes []posting.Posting, incomes []posting.Posting) []IncomeYearlyCard {
 	var yearlyCards []IncomeYearlyCard = make([]IncomeYearlyCard, 0)
 
 	var p posting.Posting
 	end := utils.EndOfToday()
-	for start = utils.BeginningOfFinancialYear(start); start.Before(end); start = start.AddDate(1, 0, 0) {
-		yearEnd := utils.EndOfFinancialYear(start)
+	for start = utils.BeginningOfYear(year, start); start.Before(end); start = start.AddDate(1, 0, 0) {
+		yearEnd := utils.EndOfYear(year, start)
 		var netTax decimal.Decimal = decimal.Zero
 		for len(taxes) > 0 && utils.IsWithDate(taxes[0].Date, start, yearEnd) {
 			p, taxes = taxes[0], taxes[1:]
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type IncomeBreakdownYear struct {
+	FY               string                     `json:"fy"`
+	Components       map[string]decimal.Decimal `json:"components"`
+	GrossIncome      decimal.Decimal            `json:"gross_income"`
+	Tax              decimal.Decimal            `json:"tax"`
+	EffectiveTaxRate decimal.Decimal            `json:"effective_tax_rate"`
+	Growth           *decimal.Decimal           `json:"growth"`
+}
+
+type IncomeSeasonality struct {
+	Month   time.Month      `json:"month"`
+	Average decimal.Decimal `json:"average"`
+}
+
+// GetIncomeBreakdown splits the income per financial (or calendar)
+// year by the second level account (Income:Salary, Income:Interest
+// etc).
+func GetIncomeBreakdown(db *gorm.DB, kind utils.YearKind) gin.H {
+	incomePostings := query.Init(db).Like("Income:%").ExcludeFuture().All()
+	taxPostings := query.Init(db).AccountPrefix(config.TaxAccounts()...).ExcludeFuture().All()
+
+	incomeByFY := utils.GroupByYear(incomePostings, kind)
+	taxByFY := utils.GroupByYear(taxPostings, kind)
+	hundred := decimal.NewFromInt(100)
+
+	years := []IncomeBreakdownYear{}
+	for _, fy := range utils.SortedKeys(incomeByFY) {
+		year := IncomeBreakdownYear{FY: fy, Components: make(map[string]decimal.Decimal)}
+		for _, p := range incomeByFY[fy] {
+			component := accountCategory(p.Account)
+			year.Components[component] = year.Components[component].Add(p.Amount.Neg())
+		}
+
+		year.GrossIncome = utils.SumBy(incomeByFY[fy], func(p posting.Posting) decimal.Decimal { return p.Amount.Neg() })
+		year.Tax = utils.SumBy(taxByFY[fy], func(p posting.Posting) decimal.Decimal { return p.Amount })
+		if year.GrossIncome.IsPositive() {
+			year.EffectiveTaxRate = year.Tax.Div(year.GrossIncome).Mul(hundred)
+		}
+
+		if len(years) > 0 {
+			previous := years[len(years)-1].GrossIncome
+			if previous.IsPositive() {
+				growth := year.GrossIncome.Sub(previous).Div(previous).Mul(hundred)
+				year.Growth = &growth
+			}
+		}
+
+		years = append(years, year)
+	}
+
+	return gin.H{"years": years, "seasonality": computeIncomeSeasonality(incomePostings)}
+}
+
+// computeIncomeSeasonality returns the average income of each
+// calendar month over the years which had any income in that month.
+func computeIncomeSeasonality(postings []posting.Posting) []IncomeSeasonality {
+	byMonth := lo.GroupBy(postings, func(p posting.Posting) time.Month { return p.Date.Month() })
+
+	return lo.Map(lo.RangeFrom(time.January, 12), func(month time.Month, _ int) IncomeSeasonality {
+		ps := byMonth[month]
+		years := len(lo.Uniq(lo.Map(ps, func(p posting.Posting, _ int) int { return p.Date.Year() })))
+		average := decimal.Zero
+		if years > 0 {
+			total := utils.SumBy(ps, func(p posting.Posting) decimal.Decimal { return p.Amount.Neg() })
+			average = total.Div(decimal.NewFromInt(int64(years)))
+		}
+		return IncomeSeasonality{Month: month, Average: average}
+	})
+}
+
+func accountCategory(account string) string {
+	parts := strings.Split(account, ":")
+	if len(parts) < 2 {
+		return account
+	}
+	return strings.Join(parts[:2], ":")
+}
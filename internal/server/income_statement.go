@@ -2,7 +2,6 @@ package server
 
 import (
 	"sort"
-	"strings"
 	"time"
 
 	"github.com/ananthakumaran/paisa/internal/model/posting"
@@ -33,16 +32,16 @@ type RunningBalance struct {
 	quantity map[string]decimal.Decimal
 }
 
-func GetIncomeStatement(db *gorm.DB) gin.H {
+func GetIncomeStatement(db *gorm.DB, year utils.YearKind) gin.H {
 	postings := query.Init(db).All()
-	statements := computeStatement(db, postings)
+	statements := computeStatement(db, year, postings)
 	return gin.H{"yearly": statements}
 }
 
-func computeStatement(db *gorm.DB, postings []posting.Posting) map[string]IncomeStatement {
+func computeStatement(db *gorm.DB, year utils.YearKind, postings []posting.Posting) map[string]IncomeStatement {
 	statements := make(map[string]IncomeStatement)
 
-	grouped := utils.GroupByFY(postings)
+	grouped := utils.GroupByYear(postings, year)
 	fys := lo.Keys(grouped)
 	sort.Strings(fys)
 
@@ -51,7 +50,7 @@ func computeStatement(db *gorm.DB, postings []posting.Posting) map[string]Income
 
 	for _, fy := range fys {
 		incomeStatement := IncomeStatement{}
-		start, end := utils.ParseFY(fy)
+		start, end := utils.ParseYear(year, fy)
 		incomeStatement.Date = start
 		incomeStatement.StartingBalance = startingBalance
 		incomeStatement.Income = make(map[string]decimal.Decimal)
@@ -76,7 +75,7 @@ func computeStatement(db *gorm.DB, postings []posting.Posting) map[string]Income
 					}
 					r.amount = r.amount.Add(p.Amount)
 					runnings[sourceAccount] = r
-				} else if strings.HasPrefix(p.Account, "Income:Interest") {
+				} else if utils.IsInterestAccount(p.Account) {
 					incomeStatement.Interest[p.Account] = incomeStatement.Interest[p.Account].Add(p.Amount)
 				} else {
 					incomeStatement.Income[p.Account] = incomeStatement.Income[p.Account].Add(p.Amount)
@@ -84,7 +83,7 @@ func computeStatement(db *gorm.DB, postings []posting.Posting) map[string]Income
 			case "Equity":
 				incomeStatement.Equity[p.Account] = incomeStatement.Equity[p.Account].Add(p.Amount)
 			case "Expenses":
-				if strings.HasPrefix(p.Account, "Expenses:Tax") {
+				if utils.IsTaxAccount(p.Account) {
 					incomeStatement.Tax[p.Account] = incomeStatement.Tax[p.Account].Add(p.Amount)
 				} else {
 					incomeStatement.Expenses[p.Account] = incomeStatement.Expenses[p.Account].Add(p.Amount)
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/taxation"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type IncomeTaxEstimate struct {
+	StartDate   time.Time                        `json:"start_date"`
+	EndDate     time.Time                        `json:"end_date"`
+	Declaration taxation.IncomeTaxDeclaration    `json:"declaration"`
+	OldRegime   taxation.RegimeTax               `json:"old_regime"`
+	NewRegime   taxation.RegimeTax               `json:"new_regime"`
+	Regime      string                           `json:"regime"`
+	TDS         decimal.Decimal                  `json:"tds"`
+	Balance     decimal.Decimal                  `json:"balance"`
+	AdvanceTax  []taxation.AdvanceTaxInstallment `json:"advance_tax"`
+}
+
+// GetIncomeTax estimates the income tax of each financial year from
+// the income, excluding the capital gains which are taxed separately.
+// The tax already booked under Expenses:Tax is treated as TDS and the
+// rest is split into the advance tax installments.
+func GetIncomeTax(db *gorm.DB) gin.H {
+	incomes := query.Init(db).Like("Income:%").NotAccountPrefix(config.CapitalGainsAccount()).All()
+	taxes := query.Init(db).AccountPrefix(config.TaxAccounts()...).All()
+	investments := section80CPostings(db)
+	conf := config.GetConfig().IncomeTax
+
+	incomesByFY := utils.GroupByFY(incomes)
+	taxesByFY := utils.GroupByFY(taxes)
+	investmentsByFY := utils.GroupByFY(investments)
+
+	estimates := make(map[string]IncomeTaxEstimate)
+	for fy, ps := range incomesByFY {
+		start, end := utils.ParseFY(fy)
+		declaration := taxation.IncomeTaxDeclaration{
+			GrossIncome:     utils.SumBy(ps, func(p posting.Posting) decimal.Decimal { return p.Amount.Neg() }),
+			Section80C:      section80CInvestment(investmentsByFY[fy]),
+			HRAExemption:    decimal.NewFromFloat(conf.HRAExemption),
+			OtherDeductions: decimal.NewFromFloat(conf.OtherDeductions),
+		}
+
+		oldRegime, newRegime := taxation.EstimateIncomeTax(start.Year(), declaration)
+		preferred := taxation.PreferredRegime(oldRegime, newRegime)
+		tds := accounting.CostSum(taxesByFY[fy])
+		balance := decimal.Max(preferred.Total.Sub(tds), decimal.Zero)
+
+		estimates[fy] = IncomeTaxEstimate{
+			StartDate:   start,
+			EndDate:     end,
+			Declaration: declaration,
+			OldRegime:   oldRegime,
+			NewRegime:   newRegime,
+			Regime:      preferred.Regime,
+			TDS:         tds,
+			Balance:     balance,
+			AdvanceTax:  taxation.AdvanceTaxSchedule(start.Year(), balance),
+		}
+	}
+
+	return gin.H{"estimates": estimates}
+}
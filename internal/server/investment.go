@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
 	"github.com/ananthakumaran/paisa/internal/service"
@@ -29,7 +30,7 @@ type InvestmentYearlyCard struct {
 }
 
 func GetInvestment(db *gorm.DB) gin.H {
-	assets := query.Init(db).Like("Assets:%").NotAccountPrefix("Assets:Checking").
+	assets := query.Init(db).Like("Assets:%").NotAccountPrefix(config.CheckingAccounts()...).
 		Where("transaction_id not in (select transaction_id from postings p where p.account like ? and p.transaction_id = transaction_id)", "Liabilities:%").
 		All()
 	incomes := query.Init(db).Like("Income:%").All()
@@ -66,7 +67,7 @@ func computeInvestmentYearlyCard(start time.Time, assets []posting.Posting, expe
 
 		for len(expenses) > 0 && utils.IsWithDate(expenses[0].Date, start, yearEnd) {
 			p, expenses = expenses[0], expenses[1:]
-			if utils.IsSameOrParent(p.Account, "Expenses:Tax") {
+			if utils.IsTaxAccount(p.Account) {
 				currentYearTaxes = append(currentYearTaxes, p)
 			} else {
 				currentYearExpenses = append(currentYearExpenses, p)
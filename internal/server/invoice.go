@@ -0,0 +1,269 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/cache"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/invoice"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// INVOICE_TAG links the transactions in the journal to the invoice
+const INVOICE_TAG = "invoice"
+
+type InvoiceStatus string
+
+const (
+	Unpaid        InvoiceStatus = "unpaid"
+	PartiallyPaid InvoiceStatus = "partially_paid"
+	Paid          InvoiceStatus = "paid"
+	Overdue       InvoiceStatus = "overdue"
+)
+
+type InvoiceSummary struct {
+	invoice.Invoice
+	Total       decimal.Decimal `json:"total"`
+	Paid        decimal.Decimal `json:"paid"`
+	Outstanding decimal.Decimal `json:"outstanding"`
+	Status      InvoiceStatus   `json:"status"`
+	DaysOverdue int             `json:"days_overdue"`
+}
+
+// ReceivablesAging buckets the outstanding amount of the invoices by
+// the number of days past the due date.
+type ReceivablesAging struct {
+	Current    decimal.Decimal `json:"current"`
+	Days1To30  decimal.Decimal `json:"days_1_30"`
+	Days31To60 decimal.Decimal `json:"days_31_60"`
+	Days61To90 decimal.Decimal `json:"days_61_90"`
+	Over90     decimal.Decimal `json:"over_90"`
+	Total      decimal.Decimal `json:"total"`
+}
+
+type InvoiceRequest struct {
+	// generated if empty, INV-0001, INV-0002 etc
+	Number  string     `json:"number"`
+	Client  string     `json:"client" binding:"required"`
+	Date    *time.Time `json:"date"`
+	DueDate *time.Time `json:"due_date"`
+	// defaults to the invoices.income_account in the configuration
+	IncomeAccount string         `json:"income_account"`
+	Notes         string         `json:"notes"`
+	Items         []invoice.Item `json:"items" binding:"required,min=1"`
+	File          string         `json:"file"`
+	DryRun        bool           `json:"dry_run"`
+}
+
+type InvoicePaymentRequest struct {
+	Number string `json:"number" binding:"required"`
+	// defaults to the outstanding amount
+	Amount decimal.Decimal `json:"amount"`
+	// account that received the money, defaults to the first checking
+	// account
+	Account string     `json:"account"`
+	Date    *time.Time `json:"date"`
+	File    string     `json:"file"`
+	DryRun  bool       `json:"dry_run"`
+}
+
+type InvoiceDeleteRequest struct {
+	Number string `json:"number" binding:"required"`
+}
+
+func GetInvoices(db *gorm.DB) gin.H {
+	invoices := computeInvoices(db)
+	return gin.H{"invoices": invoices, "aging": computeReceivablesAging(invoices)}
+}
+
+// computeInvoices adds up the payments of each invoice, which are the
+// credits to the receivable account tagged with the invoice number.
+func computeInvoices(db *gorm.DB) []InvoiceSummary {
+	conf := config.GetConfig().Invoices
+	paid := make(map[string]decimal.Decimal)
+	for _, p := range query.Init(db).IncludeExcluded().ExcludeFuture().AccountPrefix(conf.ReceivableAccount).All() {
+		number, ok := p.TagValue(INVOICE_TAG)
+		if ok && p.Amount.IsNegative() {
+			paid[number] = paid[number].Add(p.Amount.Neg())
+		}
+	}
+
+	today := utils.BeginningOfDay(utils.Now())
+	return lo.Map(invoice.All(db), func(i invoice.Invoice, _ int) InvoiceSummary {
+		s := InvoiceSummary{Invoice: i, Total: i.Total(), Paid: paid[i.Number]}
+		s.Outstanding = decimal.Max(s.Total.Sub(s.Paid), decimal.Zero)
+		switch {
+		case s.Outstanding.IsZero():
+			s.Status = Paid
+		case today.After(i.DueDate):
+			s.Status = Overdue
+			s.DaysOverdue = int(today.Sub(utils.BeginningOfDay(i.DueDate)).Hours() / 24)
+		case s.Paid.IsPositive():
+			s.Status = PartiallyPaid
+		default:
+			s.Status = Unpaid
+		}
+		return s
+	})
+}
+
+func computeReceivablesAging(invoices []InvoiceSummary) ReceivablesAging {
+	aging := ReceivablesAging{}
+	for _, i := range invoices {
+		switch {
+		case i.Outstanding.IsZero():
+			continue
+		case i.DaysOverdue == 0:
+			aging.Current = aging.Current.Add(i.Outstanding)
+		case i.DaysOverdue <= 30:
+			aging.Days1To30 = aging.Days1To30.Add(i.Outstanding)
+		case i.DaysOverdue <= 60:
+			aging.Days31To60 = aging.Days31To60.Add(i.Outstanding)
+		case i.DaysOverdue <= 90:
+			aging.Days61To90 = aging.Days61To90.Add(i.Outstanding)
+		default:
+			aging.Over90 = aging.Over90.Add(i.Outstanding)
+		}
+		aging.Total = aging.Total.Add(i.Outstanding)
+	}
+	return aging
+}
+
+// CreateInvoice books the invoice in the journal against the
+// receivable account of the client and records the line items.
+func CreateInvoice(db *gorm.DB, request InvoiceRequest) gin.H {
+	conf := config.GetConfig().Invoices
+	client := strings.TrimSpace(request.Client)
+	if client == "" || strings.Contains(client, ":") {
+		return gin.H{"saved": false, "message": "Client name should not be empty or contain :"}
+	}
+
+	number := strings.TrimSpace(request.Number)
+	if number == "" {
+		number = invoice.NextNumber(db)
+	} else if _, found := invoice.Get(db, number); found {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Invoice %s already exists", number)}
+	}
+
+	date := utils.BeginningOfDay(utils.Now())
+	if request.Date != nil {
+		date = request.Date.In(config.TimeZone())
+	}
+	dueDate := date.AddDate(0, 0, conf.DueDays)
+	if request.DueDate != nil {
+		dueDate = request.DueDate.In(config.TimeZone())
+	}
+	if dueDate.Before(date) {
+		return gin.H{"saved": false, "message": "Due date can't be before the invoice date"}
+	}
+
+	for _, item := range request.Items {
+		if strings.TrimSpace(item.Description) == "" || !item.Quantity.IsPositive() || item.Rate.IsNegative() {
+			return gin.H{"saved": false, "message": "Each item should have a description, a positive quantity and a rate"}
+		}
+	}
+
+	i := invoice.Invoice{
+		Number:        number,
+		Client:        client,
+		Date:          date,
+		DueDate:       dueDate,
+		Account:       conf.ReceivableAccount + ":" + client,
+		IncomeAccount: lo.Ternary(request.IncomeAccount != "", request.IncomeAccount, conf.IncomeAccount),
+		Notes:         request.Notes,
+		Items: lo.Map(request.Items, func(item invoice.Item, _ int) invoice.Item {
+			return invoice.Item{Description: item.Description, Quantity: item.Quantity, Rate: item.Rate}
+		}),
+	}
+	if !i.Total().IsPositive() {
+		return gin.H{"saved": false, "message": "Invoice total should be positive"}
+	}
+
+	transaction := journal.Transaction{
+		Date:  date,
+		Payee: client,
+		Note:  fmt.Sprintf("%s: %s", INVOICE_TAG, number),
+		Postings: []journal.Posting{
+			{Account: i.Account, Amount: journal.FormatAmount(i.Total(), config.DefaultCurrency())},
+			{Account: i.IncomeAccount},
+		},
+	}
+	if err := transaction.Validate(); err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	content := transaction.Format()
+	if request.DryRun {
+		return gin.H{"saved": false, "content": content, "invoice": i}
+	}
+
+	result := appendToJournal(db, fmt.Sprintf("invoice %s", number), request.File, content)
+	if result["saved"] == true {
+		invoice.Create(db, &i)
+		cache.Clear()
+		result["invoice"] = i
+	}
+	return result
+}
+
+// PayInvoice books the payment received for the invoice.
+func PayInvoice(db *gorm.DB, request InvoicePaymentRequest) gin.H {
+	s, found := lo.Find(computeInvoices(db), func(s InvoiceSummary) bool { return s.Number == request.Number })
+	if !found {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Invoice %s not found", request.Number)}
+	}
+	if s.Outstanding.IsZero() {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Invoice %s is already paid", s.Number)}
+	}
+
+	amount := request.Amount
+	if amount.IsZero() {
+		amount = s.Outstanding
+	}
+	if amount.IsNegative() || amount.GreaterThan(s.Outstanding) {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Amount should be between 0 and %s", s.Outstanding)}
+	}
+
+	date := utils.Now()
+	if request.Date != nil {
+		date = request.Date.In(config.TimeZone())
+	}
+
+	account := request.Account
+	if account == "" {
+		account = config.CheckingAccounts()[0]
+	}
+
+	transaction := journal.Transaction{
+		Date:  date,
+		Payee: s.Client,
+		Note:  fmt.Sprintf("%s: %s", INVOICE_TAG, s.Number),
+		Postings: []journal.Posting{
+			{Account: account, Amount: journal.FormatAmount(amount, config.DefaultCurrency())},
+			{Account: s.Account, Amount: journal.FormatAmount(amount.Neg(), config.DefaultCurrency())},
+		},
+	}
+
+	content := transaction.Format()
+	if request.DryRun {
+		return gin.H{"saved": false, "content": content}
+	}
+
+	return appendToJournal(db, fmt.Sprintf("payment of invoice %s", s.Number), request.File, content)
+}
+
+// DeleteInvoice removes the invoice, the transactions in the journal
+// are left untouched.
+func DeleteInvoice(db *gorm.DB, request InvoiceDeleteRequest) gin.H {
+	invoice.Delete(db, request.Number)
+	cache.Clear()
+	return gin.H{"success": true}
+}
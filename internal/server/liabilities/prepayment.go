@@ -0,0 +1,196 @@
+package liabilities
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const MAX_TENURE_MONTHS = 50 * 12
+
+type PrepaymentRequest struct {
+	Account string  `form:"account" binding:"required"`
+	Amount  float64 `form:"amount" binding:"required"`
+	// expected annual return of the investment in percentage
+	ExpectedReturn float64 `form:"expected_return"`
+	// tax on the investment gains in percentage, paid at the end
+	TaxRate float64 `form:"tax_rate"`
+	// defaults to the configured rate or the APR of the loan
+	InterestRate *float64 `form:"interest_rate"`
+	// defaults to the average repayment of the last 3 months
+	EMI *float64 `form:"emi"`
+}
+
+type PrepaymentMonth struct {
+	Date time.Time `json:"date"`
+	// outstanding loan and the value of the investment when the amount
+	// is used to prepay the loan. The EMIs saved after the loan is
+	// closed are invested.
+	PrepayBalance    decimal.Decimal `json:"prepay_balance"`
+	PrepayInvestment decimal.Decimal `json:"prepay_investment"`
+	// outstanding loan and the value of the investment when the amount
+	// is invested
+	InvestBalance    decimal.Decimal `json:"invest_balance"`
+	InvestInvestment decimal.Decimal `json:"invest_investment"`
+	// networth (investment after tax minus the outstanding loan) of the
+	// prepay option minus that of the invest option
+	Difference decimal.Decimal `json:"difference"`
+}
+
+type amortization struct {
+	balance     float64
+	investment  float64
+	contributed float64
+}
+
+// GetPrepayment compares prepaying the loan with investing the same
+// amount, with the same EMI being paid in both the cases till the loan
+// is closed. The break even rate is the interest rate of the loan at
+// which both the options end up with the same networth.
+func GetPrepayment(db *gorm.DB, request PrepaymentRequest) (gin.H, error) {
+	account := request.Account
+	postings := query.Init(db).AccountPrefix(account).ExcludeFuture().All()
+	if len(postings) == 0 {
+		return nil, errors.New("No postings found for " + account)
+	}
+	postings = service.PopulateMarketPrice(db, postings)
+	outstanding := accounting.CurrentBalance(postings).Neg().InexactFloat64()
+	if outstanding <= 0 {
+		return nil, errors.New(account + " doesn't have any outstanding balance")
+	}
+
+	expenses := query.Init(db).AccountPrefix(interestAccount(account)).ExcludeFuture().All()
+
+	var rate float64
+	if current, ok := CurrentRate(account); ok {
+		rate = current.InexactFloat64()
+	} else {
+		rate = service.APR(db, append(postings, expenses...)).Abs().InexactFloat64()
+	}
+	if request.InterestRate != nil {
+		rate = *request.InterestRate
+	}
+
+	var emi float64
+	if request.EMI != nil {
+		emi = *request.EMI
+	} else {
+		emi = averageRepayment(append(postings, expenses...), 3)
+	}
+	if emi <= outstanding*rate/1200 {
+		return nil, errors.New("EMI is not enough to cover the interest, specify the emi")
+	}
+
+	amount := math.Min(request.Amount, outstanding)
+	months := simulatePrepayment(outstanding, amount, emi, rate, request.ExpectedReturn)
+
+	start := utils.BeginningOfMonth(utils.Now()).AddDate(0, 1, 0)
+	timeline := []PrepaymentMonth{}
+	for i, m := range months {
+		prepay, invest := m[0], m[1]
+		timeline = append(timeline, PrepaymentMonth{
+			Date:             start.AddDate(0, i, 0),
+			PrepayBalance:    decimal.NewFromFloat(prepay.balance).Round(2),
+			PrepayInvestment: decimal.NewFromFloat(prepay.investment).Round(2),
+			InvestBalance:    decimal.NewFromFloat(invest.balance).Round(2),
+			InvestInvestment: decimal.NewFromFloat(invest.investment).Round(2),
+			Difference:       decimal.NewFromFloat(netDifference(prepay, invest, request.TaxRate)).Round(2),
+		})
+	}
+
+	// the difference is positive when the rate is high, invest wins
+	// when the rate is low
+	low, high := 0.0, 100.0
+	for i := 0; i < 50; i++ {
+		mid := (low + high) / 2
+		result := simulatePrepayment(outstanding, amount, emi, mid, request.ExpectedReturn)
+		last := result[len(result)-1]
+		if netDifference(last[0], last[1], request.TaxRate) > 0 {
+			high = mid
+		} else {
+			low = mid
+		}
+	}
+
+	last := timeline[len(timeline)-1]
+	return gin.H{
+		"account":         account,
+		"outstanding":     decimal.NewFromFloat(outstanding).Round(2),
+		"interest_rate":   decimal.NewFromFloat(rate).Round(2),
+		"emi":             decimal.NewFromFloat(emi).Round(2),
+		"amount":          decimal.NewFromFloat(amount),
+		"expected_return": decimal.NewFromFloat(request.ExpectedReturn),
+		"months":          timeline,
+		"prepay_better":   last.Difference.IsPositive(),
+		"break_even_rate": decimal.NewFromFloat((low + high) / 2).Round(2),
+	}, nil
+}
+
+// simulatePrepayment returns the state of the prepay and the invest
+// options at the end of every month till the loan of the invest option
+// is closed.
+func simulatePrepayment(outstanding, amount, emi, rate, expectedReturn float64) [][2]amortization {
+	monthlyRate := rate / 1200
+	monthlyReturn := math.Pow(1+expectedReturn/100, 1.0/12) - 1
+
+	prepay := amortization{balance: outstanding - amount}
+	invest := amortization{balance: outstanding, investment: amount, contributed: amount}
+	months := [][2]amortization{}
+	for i := 0; i < MAX_TENURE_MONTHS && (invest.balance > 0 || i == 0); i++ {
+		prepay = prepay.next(emi, monthlyRate, monthlyReturn)
+		invest = invest.next(emi, monthlyRate, monthlyReturn)
+		months = append(months, [2]amortization{prepay, invest})
+	}
+	return months
+}
+
+// next pays the EMI towards the loan, whatever is left of the EMI
+// after the loan is closed gets invested.
+func (a amortization) next(emi, monthlyRate, monthlyReturn float64) amortization {
+	a.investment = a.investment * (1 + monthlyReturn)
+	if a.balance <= 0 {
+		a.investment += emi
+		a.contributed += emi
+		return a
+	}
+
+	due := a.balance * (1 + monthlyRate)
+	if due <= emi {
+		a.investment += emi - due
+		a.contributed += emi - due
+		a.balance = 0
+	} else {
+		a.balance = due - emi
+	}
+	return a
+}
+
+func netDifference(prepay, invest amortization, taxRate float64) float64 {
+	return prepay.net(taxRate) - invest.net(taxRate)
+}
+
+func (a amortization) net(taxRate float64) float64 {
+	tax := math.Max(0, a.investment-a.contributed) * taxRate / 100
+	return a.investment - tax - a.balance
+}
+
+// averageRepayment returns the average of the principal and the
+// interest paid in the last n months
+func averageRepayment(postings []posting.Posting, n int) float64 {
+	start := utils.BeginningOfMonth(utils.Now()).AddDate(0, -n, 0)
+	end := utils.BeginningOfMonth(utils.Now())
+	paid := utils.SumBy(lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		return p.Amount.IsPositive() && !p.Date.Before(start) && p.Date.Before(end)
+	}), func(p posting.Posting) decimal.Decimal { return p.Amount })
+	return paid.InexactFloat64() / float64(n)
+}
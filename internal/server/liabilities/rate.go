@@ -0,0 +1,100 @@
+package liabilities
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/accrual"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type RateMonth struct {
+	Date             time.Time       `json:"date"`
+	Rate             decimal.Decimal `json:"rate"`
+	Balance          decimal.Decimal `json:"balance"`
+	ExpectedInterest decimal.Decimal `json:"expected_interest"`
+	ActualInterest   decimal.Decimal `json:"actual_interest"`
+}
+
+type LoanRate struct {
+	Account  string         `json:"account"`
+	Rates    []accrual.Rate `json:"rates"`
+	Timeline []RateMonth    `json:"timeline"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// GetRates returns the rate history of the configured loans along with
+// the interest expected at the rate applicable each month and the
+// interest actually charged. The expected interest is computed on the
+// outstanding balance at the beginning of the month.
+func GetRates(db *gorm.DB) gin.H {
+	loans := []LoanRate{}
+	for _, loan := range config.GetConfig().Loans {
+		rates, err := accrual.ParseRates(loan.Rates)
+		if err != nil {
+			loans = append(loans, LoanRate{Account: loan.Account, Rates: []accrual.Rate{}, Timeline: []RateMonth{}, Error: err.Error()})
+			continue
+		}
+
+		postings := query.Init(db).AccountPrefix(loan.Account).ExcludeFuture().All()
+		expenses := query.Init(db).AccountPrefix(interestAccount(loan.Account)).ExcludeFuture().All()
+		loans = append(loans, LoanRate{Account: loan.Account, Rates: rates, Timeline: computeRateTimeline(rates, postings, expenses)})
+	}
+
+	return gin.H{"loans": loans}
+}
+
+func computeRateTimeline(rates []accrual.Rate, postings, expenses []posting.Posting) []RateMonth {
+	timeline := []RateMonth{}
+	if len(postings) == 0 {
+		return timeline
+	}
+
+	interestByMonth := utils.GroupByMonth(expenses)
+	balance := decimal.Zero
+	end := utils.EndOfToday()
+	for month := utils.BeginningOfMonth(postings[0].Date); !month.After(end); month = month.AddDate(0, 1, 0) {
+		rate, found := accrual.RateOn(rates, month)
+		if found {
+			timeline = append(timeline, RateMonth{
+				Date:             month,
+				Rate:             rate,
+				Balance:          balance,
+				ExpectedInterest: balance.Mul(rate).Div(decimal.NewFromInt(1200)).Round(2),
+				ActualInterest:   accounting.CostSum(interestByMonth[month.Format("2006-01")]),
+			})
+		}
+
+		monthEnd := utils.EndOfMonth(month)
+		balance = balance.Add(accounting.CostSum(lo.Filter(postings, func(p posting.Posting, _ int) bool {
+			return !p.Date.Before(month) && !p.Date.After(monthEnd)
+		})).Neg())
+	}
+	return timeline
+}
+
+// CurrentRate returns the configured rate of the loan applicable today
+func CurrentRate(account string) (decimal.Decimal, bool) {
+	loan, found := lo.Find(config.GetConfig().Loans, func(l config.Loan) bool { return l.Account == account })
+	if !found {
+		return decimal.Zero, false
+	}
+
+	rates, err := accrual.ParseRates(loan.Rates)
+	if err != nil {
+		return decimal.Zero, false
+	}
+	return accrual.RateOn(rates, utils.Now())
+}
+
+func interestAccount(account string) string {
+	return "Expenses:Interest:" + strings.TrimPrefix(account, "Liabilities:")
+}
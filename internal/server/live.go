@@ -0,0 +1,7 @@
+package server
+
+// LiveRequest lets the reports of today's values overlay the current
+// quotes fetched on demand over the last stored price.
+type LiveRequest struct {
+	Live bool `form:"live"`
+}
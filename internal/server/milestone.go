@@ -0,0 +1,182 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type Milestone struct {
+	Amount decimal.Decimal `json:"amount"`
+	Date   time.Time       `json:"date"`
+	// days taken since the previous milestone
+	Days int `json:"days"`
+}
+
+type Streak struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Months int       `json:"months"`
+}
+
+type Drawdown struct {
+	Peak       time.Time       `json:"peak"`
+	Trough     time.Time       `json:"trough"`
+	Recovery   *time.Time      `json:"recovery"`
+	Amount     decimal.Decimal `json:"amount"`
+	Percentage decimal.Decimal `json:"percentage"`
+	// days from the trough till the peak was crossed again, nil if it
+	// has not recovered yet
+	RecoveryDays *int `json:"recovery_days"`
+}
+
+var fastestMilestoneStep = decimal.NewFromInt(100000)
+
+// GetMilestones summarizes the networth timeline: when each of the
+// round amounts (1, 2 and 5 times a power of ten) was first crossed,
+// the fastest 100k added, the longest run of months with positive
+// savings and the largest drawdown.
+func GetMilestones(db *gorm.DB) gin.H {
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%", "Liabilities:%").ExcludeFuture().All()
+	postings = service.PopulateMarketPrice(db, postings)
+	timeline := computeNetworthTimeline(db, postings, false)
+
+	return gin.H{
+		"milestones":     computeMilestones(timeline),
+		"fastest":        computeFastestMilestone(timeline, fastestMilestoneStep),
+		"savings_streak": computeSavingsStreak(computeSavingsRates(db)),
+		"drawdown":       computeMaxDrawdown(timeline),
+	}
+}
+
+func computeMilestones(timeline []Networth) []Milestone {
+	milestones := []Milestone{}
+	threshold := decimal.NewFromInt(1000)
+	multipliers := []int64{1, 2, 5}
+	step := 0
+	next := func() decimal.Decimal {
+		power := decimal.NewFromInt(10).Pow(decimal.NewFromInt(int64(step / 3)))
+		return threshold.Mul(power).Mul(decimal.NewFromInt(multipliers[step%3]))
+	}
+
+	var previous time.Time
+	if len(timeline) > 0 {
+		previous = timeline[0].Date
+	}
+
+	for _, n := range timeline {
+		for n.BalanceAmount.GreaterThanOrEqual(next()) {
+			milestones = append(milestones, Milestone{Amount: next(), Date: n.Date, Days: daysBetween(previous, n.Date)})
+			previous = n.Date
+			step++
+		}
+	}
+
+	return milestones
+}
+
+// computeFastestMilestone finds the quickest the networth went from
+// one multiple of step to the next.
+func computeFastestMilestone(timeline []Networth, step decimal.Decimal) *Milestone {
+	var fastest *Milestone
+	var previous *time.Time
+	reached := decimal.Zero
+
+	for _, n := range timeline {
+		for n.BalanceAmount.GreaterThanOrEqual(reached.Add(step)) {
+			reached = reached.Add(step)
+			if previous != nil {
+				days := daysBetween(*previous, n.Date)
+				if fastest == nil || days < fastest.Days {
+					fastest = &Milestone{Amount: reached, Date: n.Date, Days: days}
+				}
+			}
+			date := n.Date
+			previous = &date
+		}
+	}
+
+	return fastest
+}
+
+func computeSavingsStreak(rates []SavingsRate) *Streak {
+	var longest *Streak
+	var current *Streak
+
+	for _, r := range rates {
+		if !r.Savings.IsPositive() {
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			current = &Streak{Start: r.Date}
+		}
+		current.End = r.Date
+		current.Months++
+
+		if longest == nil || current.Months > longest.Months {
+			streak := *current
+			longest = &streak
+		}
+	}
+
+	return longest
+}
+
+// computeMaxDrawdown finds the largest fall in percentage from a peak
+// of the balance.
+func computeMaxDrawdown(timeline []Networth) *Drawdown {
+	var largest *Drawdown
+	var peak, maxPeak Networth
+	trough := -1
+	hundred := decimal.NewFromInt(100)
+
+	for i, n := range timeline {
+		if i == 0 || n.BalanceAmount.GreaterThanOrEqual(peak.BalanceAmount) {
+			peak = n
+			continue
+		}
+
+		if !peak.BalanceAmount.IsPositive() {
+			continue
+		}
+
+		percentage := peak.BalanceAmount.Sub(n.BalanceAmount).Div(peak.BalanceAmount).Mul(hundred)
+		if largest == nil || percentage.GreaterThan(largest.Percentage) {
+			largest = &Drawdown{
+				Peak:       peak.Date,
+				Trough:     n.Date,
+				Amount:     peak.BalanceAmount.Sub(n.BalanceAmount),
+				Percentage: percentage,
+			}
+			maxPeak = peak
+			trough = i
+		}
+	}
+
+	if largest == nil {
+		return nil
+	}
+
+	for _, n := range timeline[trough+1:] {
+		if n.BalanceAmount.GreaterThanOrEqual(maxPeak.BalanceAmount) {
+			date := n.Date
+			days := daysBetween(largest.Trough, date)
+			largest.Recovery = &date
+			largest.RecoveryDays = &days
+			break
+		}
+	}
+
+	return largest
+}
+
+func daysBetween(start time.Time, end time.Time) int {
+	return int(end.Sub(start).Hours() / 24)
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/utils"
+)
+
+// MonthRequest picks the month of the monthly reports.
+type MonthRequest struct {
+	// month in the 2006-01 format
+	Month string `form:"month" json:"month"`
+}
+
+// ParseMonth returns the beginning of the requested month, or of the
+// month of the given date if none is requested.
+func (request MonthRequest) ParseMonth(fallback time.Time) (time.Time, error) {
+	if request.Month == "" {
+		return utils.BeginningOfMonth(fallback), nil
+	}
+	return time.ParseInLocation("2006-01", request.Month, config.TimeZone())
+}
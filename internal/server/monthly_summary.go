@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/locale"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/notifier"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type MonthlySummaryCategory struct {
+	Category string          `json:"category"`
+	Actual   decimal.Decimal `json:"actual"`
+	Budget   decimal.Decimal `json:"budget"`
+	// negative when the category is over budget
+	Available decimal.Decimal `json:"available"`
+}
+
+type MonthlySummary struct {
+	Month           string                   `json:"month"`
+	Income          decimal.Decimal          `json:"income"`
+	Expenses        decimal.Decimal          `json:"expenses"`
+	Savings         decimal.Decimal          `json:"savings"`
+	SavingsRate     decimal.Decimal          `json:"savings_rate"`
+	OpeningNetworth decimal.Decimal          `json:"opening_networth"`
+	ClosingNetworth decimal.Decimal          `json:"closing_networth"`
+	NetworthChange  decimal.Decimal          `json:"networth_change"`
+	Categories      []MonthlySummaryCategory `json:"categories"`
+	Anomalies       []service.Anomaly        `json:"anomalies"`
+}
+
+func GetMonthlySummary(db *gorm.DB, month time.Time) gin.H {
+	return gin.H{"summary": computeMonthlySummary(db, month)}
+}
+
+// computeMonthlySummary closes the month with the income, the
+// expenses of each category against the budget, the savings rate, the
+// change in networth and the unusual expenses of the month. The
+// income, expenses and savings rate are the same as the savings rate
+// report.
+func computeMonthlySummary(db *gorm.DB, month time.Time) MonthlySummary {
+	start := utils.BeginningOfMonth(month)
+	end := utils.EndOfMonth(month)
+	key := start.Format("2006-01")
+	summary := MonthlySummary{Month: key, Categories: []MonthlySummaryCategory{}, Anomalies: []service.Anomaly{}}
+
+	rate, found := lo.Find(computeSavingsRates(db), func(r SavingsRate) bool { return r.Date.Format("2006-01") == key })
+	if found {
+		summary.Income = rate.Income
+		summary.Expenses = rate.Expenses
+		summary.Savings = rate.Savings
+		summary.SavingsRate = rate.Rate
+	}
+
+	balances := query.Init(db).Like("Assets:%", "Liabilities:%").ExcludeFuture().All()
+	networthOn := func(date time.Time) decimal.Decimal {
+		return accounting.CurrentBalanceOn(db, lo.Filter(balances, func(p posting.Posting, _ int) bool { return !p.Date.After(date) }), date)
+	}
+	closing := utils.MinTime(end, utils.EndOfToday())
+	summary.OpeningNetworth = networthOn(start.Add(-time.Nanosecond))
+	summary.ClosingNetworth = networthOn(closing)
+	summary.NetworthChange = summary.ClosingNetworth.Sub(summary.OpeningNetworth)
+
+	expenses := query.Init(db).Like("Expenses:%").Between(start, end).All()
+	actuals := lo.GroupBy(expenses, func(p posting.Posting) string { return accountCategory(p.Account) })
+	budgets := make(map[string]decimal.Decimal)
+	if budgetsByMonth, ok := GetBudget(db)["budgetsByMonth"].(map[string]Budget); ok {
+		for _, account := range budgetsByMonth[key].Accounts {
+			category := accountCategory(account.Account)
+			budgets[category] = budgets[category].Add(account.Forecast)
+		}
+	}
+
+	categories := lo.Uniq(append(utils.SortedKeys(actuals), utils.SortedKeys(budgets)...))
+	sort.Strings(categories)
+	for _, category := range categories {
+		actual := accounting.CostSum(actuals[category])
+		budget := budgets[category]
+		c := MonthlySummaryCategory{Category: category, Actual: actual, Budget: budget}
+		if !budget.IsZero() {
+			c.Available = budget.Sub(actual)
+		}
+		summary.Categories = append(summary.Categories, c)
+	}
+
+	summary.Anomalies = lo.Filter(service.DetectAnomalies(db, start), func(a service.Anomaly, _ int) bool {
+		return !a.Posting.Date.After(end)
+	})
+	return summary
+}
+
+// SendMonthlySummary sends the summary of the month over the
+// configured notification transports.
+func SendMonthlySummary(db *gorm.DB, month time.Time) gin.H {
+	if len(notifier.Transports()) == 0 {
+		return gin.H{"success": false, "message": "No notification transport is configured"}
+	}
+
+	err := sendMonthlySummary(db, month)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+	return gin.H{"success": true}
+}
+
+// sendMonthlySummary notifies the summary of the month, it's sent
+// when the month ends if monthly_summary is enabled.
+func sendMonthlySummary(db *gorm.DB, month time.Time) error {
+	summary := computeMonthlySummary(db, month)
+	err := notifier.Send(fmt.Sprintf("Paisa monthly summary for %s", summary.Month), renderMonthlySummary(summary))
+	if err != nil {
+		log.Warn("Failed to send monthly summary: ", err)
+	}
+	return err
+}
+
+func renderMonthlySummary(summary MonthlySummary) string {
+	sections := []string{
+		strings.Join([]string{
+			fmt.Sprintf("Income  %s", formatAmount(summary.Income)),
+			fmt.Sprintf("Expenses  %s", formatAmount(summary.Expenses)),
+			fmt.Sprintf("Savings  %s (%s%%)", formatAmount(summary.Savings), locale.Active().Number(summary.SavingsRate, 1)),
+			fmt.Sprintf("Networth  %s (%s)", formatAmount(summary.ClosingNetworth), formatAmount(summary.NetworthChange)),
+		}, "\n"),
+	}
+
+	if len(summary.Categories) > 0 {
+		lines := []string{"Expenses by category"}
+		for _, c := range summary.Categories {
+			line := fmt.Sprintf("%s  %s", c.Category, formatAmount(c.Actual))
+			if !c.Budget.IsZero() {
+				line = fmt.Sprintf("%s of %s", line, formatAmount(c.Budget))
+				if c.Available.IsNegative() {
+					line = fmt.Sprintf("%s, over by %s", line, formatAmount(c.Available.Neg()))
+				}
+			}
+			lines = append(lines, line)
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	if len(summary.Anomalies) > 0 {
+		lines := []string{"Unusual expenses"}
+		for _, a := range summary.Anomalies {
+			p := a.Posting
+			lines = append(lines, fmt.Sprintf("%s  %s  %s  %s (usually %s)", formatDate(p.Date), p.Payee, p.Account, formatAmount(p.Amount), formatAmount(a.Median)))
+		}
+		sections = append(sections, strings.Join(lines, "\n"))
+	}
+
+	return strings.Join(sections, "\n\n")
+}
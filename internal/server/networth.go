@@ -3,11 +3,15 @@ package server
 import (
 	"time"
 
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/dailybalance"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/server/assets"
 	"github.com/ananthakumaran/paisa/internal/service"
 	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
@@ -22,19 +26,32 @@ type Networth struct {
 	NetInvestmentAmount decimal.Decimal `json:"netInvestmentAmount"`
 }
 
-func GetNetworth(db *gorm.DB) gin.H {
-	postings := query.Init(db).Like("Assets:%", "Income:CapitalGains:%", "Liabilities:%").UntilToday().All()
+func GetNetworth(db *gorm.DB, live bool) gin.H {
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%", "Liabilities:%").ExcludeFuture().All()
 
 	postings = service.PopulateMarketPrice(db, postings)
 	networthTimeline := computeNetworthTimeline(db, postings, false)
+	if live {
+		livePostings := service.PopulateLiveMarketPrice(postings)
+		if len(networthTimeline) > 0 && utils.IsSameDate(networthTimeline[len(networthTimeline)-1].Date, utils.Now()) {
+			networthTimeline[len(networthTimeline)-1] = overlayLiveNetworth(networthTimeline[len(networthTimeline)-1], postings, livePostings)
+		}
+		postings = livePostings
+	}
 	xirr := service.XIRR(db, postings)
-	return gin.H{"networthTimeline": networthTimeline, "xirr": xirr}
+	risk := assets.ComputeRisk(db, postings)
+	return gin.H{"networthTimeline": networthTimeline, "xirr": xirr, "risk": risk}
 }
 
-func GetCurrentNetworth(db *gorm.DB) gin.H {
-	postings := query.Init(db).Like("Assets:%", "Income:CapitalGains:%", "Liabilities:%").UntilToday().All()
+func GetCurrentNetworth(db *gorm.DB, live bool) gin.H {
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%", "Liabilities:%").ExcludeFuture().All()
 	postings = service.PopulateMarketPrice(db, postings)
 	networth := computeNetworth(db, postings)
+	if live {
+		livePostings := service.PopulateLiveMarketPrice(postings)
+		networth = overlayLiveNetworth(networth, postings, livePostings)
+		postings = livePostings
+	}
 	xirr := service.XIRR(db, postings)
 	return gin.H{"networth": networth, "xirr": xirr}
 }
@@ -88,7 +105,105 @@ func computeNetworth(db *gorm.DB, postings []posting.Posting) Networth {
 	return networth
 }
 
+// overlayLiveNetworth moves the balance and the gain of today's
+// networth by the difference the live quotes make to the market amount
+// of the postings.
+func overlayLiveNetworth(networth Networth, postings []posting.Posting, livePostings []posting.Posting) Networth {
+	marketAmount := func(p posting.Posting) decimal.Decimal { return p.MarketAmount }
+	difference := utils.SumBy(livePostings, marketAmount).Sub(utils.SumBy(postings, marketAmount))
+	networth.BalanceAmount = networth.BalanceAmount.Add(difference)
+	networth.GainAmount = networth.GainAmount.Add(difference)
+	return networth
+}
+
+// computeNetworthTimeline returns the daily networth of the accounts
+// of the postings, which should have all the postings of those
+// accounts till today. The running totals materialized at sync are
+// used when available, the postings are walked otherwise.
 func computeNetworthTimeline(db *gorm.DB, postings []posting.Posting, computeBalanceUnits bool) []Networth {
+	if len(postings) == 0 {
+		return []Networth{}
+	}
+
+	accounts := lo.Uniq(lo.Map(postings, func(p posting.Posting, _ int) string { return p.Account }))
+	balances := dailybalance.ByAccounts(db, accounts, utils.EndOfToday())
+	if len(balances) == 0 {
+		return walkNetworthTimeline(db, postings, computeBalanceUnits)
+	}
+	return balancesNetworthTimeline(db, balances, computeBalanceUnits)
+}
+
+func balancesNetworthTimeline(db *gorm.DB, balances []dailybalance.DailyBalance, computeBalanceUnits bool) []Networth {
+	var networths []Networth
+
+	type key struct {
+		account   string
+		commodity string
+	}
+	latest := make(map[key]dailybalance.DailyBalance)
+	accumulator := make(map[string]dailybalance.DailyBalance)
+
+	var b dailybalance.DailyBalance
+	end := utils.EndOfToday()
+	for start := balances[0].Date; start.Before(end); start = start.AddDate(0, 0, 1) {
+		for len(balances) > 0 && !balances[0].Date.After(start) {
+			b, balances = balances[0], balances[1:]
+			k := key{account: b.Account, commodity: b.Commodity}
+			previous := latest[k]
+			latest[k] = b
+
+			// the rows are running totals of an account, the commodity
+			// totals move by the difference from the previous row
+			rs := accumulator[b.Commodity]
+			rs.Investment = rs.Investment.Add(b.Investment.Sub(previous.Investment))
+			rs.Withdrawal = rs.Withdrawal.Add(b.Withdrawal.Sub(previous.Withdrawal))
+			rs.Amount = rs.Amount.Add(b.Amount.Sub(previous.Amount))
+			rs.Quantity = rs.Quantity.Add(b.Quantity.Sub(previous.Quantity))
+			accumulator[b.Commodity] = rs
+		}
+
+		var investment decimal.Decimal = decimal.Zero
+		var withdrawal decimal.Decimal = decimal.Zero
+		var balance decimal.Decimal = decimal.Zero
+		var balanceUnits decimal.Decimal = decimal.Zero
+
+		for commodity, rs := range accumulator {
+			investment = investment.Add(rs.Investment)
+			withdrawal = withdrawal.Add(rs.Withdrawal)
+
+			if utils.IsCurrency(commodity) {
+				balance = balance.Add(rs.Amount)
+			} else {
+				if computeBalanceUnits {
+					balanceUnits = balanceUnits.Add(rs.Quantity)
+				}
+				price := service.GetUnitPrice(db, commodity, start)
+				if !price.Value.Equal(decimal.Zero) {
+					balance = balance.Add(rs.Quantity.Mul(price.Value))
+				} else {
+					balance = balance.Add(rs.Amount)
+				}
+			}
+		}
+
+		networths = append(networths, Networth{
+			Date:                start,
+			InvestmentAmount:    investment,
+			WithdrawalAmount:    withdrawal,
+			GainAmount:          balance.Add(withdrawal).Sub(investment),
+			BalanceAmount:       balance,
+			BalanceUnits:        balanceUnits,
+			NetInvestmentAmount: investment.Sub(withdrawal),
+		})
+
+		if len(balances) == 0 && balance.Abs().LessThan(decimal.NewFromFloat(0.01)) {
+			break
+		}
+	}
+	return networths
+}
+
+func walkNetworthTimeline(db *gorm.DB, postings []posting.Posting, computeBalanceUnits bool) []Networth {
 	var networths []Networth
 
 	var p posting.Posting
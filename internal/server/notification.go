@@ -0,0 +1,67 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+const NOTIFICATIONS_PATH = "/api/notifications"
+
+type Notification struct {
+	Type  string   `json:"type"`
+	Files []string `json:"files,omitempty"`
+}
+
+type notificationHub struct {
+	sync.Mutex
+	clients map[*websocket.Conn]bool
+}
+
+var notifications = notificationHub{clients: make(map[*websocket.Conn]bool)}
+
+// NotificationsHandler keeps the websocket connection open and pushes
+// notifications to the client. Messages sent by the client are
+// ignored.
+var NotificationsHandler = websocket.Handler(func(ws *websocket.Conn) {
+	notifications.add(ws)
+	defer notifications.remove(ws)
+
+	var message string
+	for {
+		err := websocket.Message.Receive(ws, &message)
+		if err != nil {
+			return
+		}
+	}
+})
+
+func (h *notificationHub) add(ws *websocket.Conn) {
+	h.Lock()
+	defer h.Unlock()
+	h.clients[ws] = true
+}
+
+func (h *notificationHub) remove(ws *websocket.Conn) {
+	h.Lock()
+	defer h.Unlock()
+	delete(h.clients, ws)
+	ws.Close()
+}
+
+func (h *notificationHub) broadcast(notification Notification) {
+	h.Lock()
+	defer h.Unlock()
+
+	for ws := range h.clients {
+		ws.SetWriteDeadline(time.Now().Add(5 * time.Second))
+		err := websocket.JSON.Send(ws, notification)
+		if err != nil {
+			log.Debug(err)
+			delete(h.clients, ws)
+			ws.Close()
+		}
+	}
+}
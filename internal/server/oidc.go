@@ -0,0 +1,367 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+)
+
+const OIDC_LOGIN_PATH = "/api/auth/oidc/login"
+const OIDC_CALLBACK_PATH = "/api/auth/oidc/callback"
+const AUTH_METHODS_PATH = "/api/auth/methods"
+const LOGOUT_PATH = "/api/auth/logout"
+
+const SESSION_COOKIE = "paisa_session"
+const SESSION_DURATION = 7 * 24 * time.Hour
+
+// time allowed between redirecting to the provider and the callback
+const loginDuration = 10 * time.Minute
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+type oidcLogin struct {
+	verifier  string
+	nonce     string
+	expiresAt time.Time
+}
+
+type session struct {
+	user      string
+	readonly  bool
+	expiresAt time.Time
+}
+
+// Sessions are kept in memory, users have to login again after a
+// restart.
+var oidcState = struct {
+	sync.Mutex
+	discovery map[string]oidcDiscovery
+	logins    map[string]oidcLogin
+	sessions  map[string]session
+}{
+	discovery: make(map[string]oidcDiscovery),
+	logins:    make(map[string]oidcLogin),
+	sessions:  make(map[string]session),
+}
+
+var oidcClient = &http.Client{Timeout: 30 * time.Second}
+
+func isOIDCEnabled() bool {
+	return config.GetConfig().OIDC.Issuer != ""
+}
+
+func GetAuthMethods() gin.H {
+	return gin.H{"password": len(config.GetConfig().UserAccounts) > 0, "oidc": isOIDCEnabled()}
+}
+
+// OIDCLogin redirects to the provider using the authorization code
+// flow with PKCE.
+func OIDCLogin(c *gin.Context) {
+	oidc := config.GetConfig().OIDC
+	discovery, err := discover(oidc.Issuer)
+	if err != nil {
+		log.Error(err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	state := randomToken()
+	login := oidcLogin{verifier: randomToken(), nonce: randomToken(), expiresAt: time.Now().Add(loginDuration)}
+
+	oidcState.Lock()
+	expire(oidcState.logins, func(l oidcLogin) time.Time { return l.expiresAt })
+	oidcState.logins[state] = login
+	oidcState.Unlock()
+
+	challenge := sha256.Sum256([]byte(login.verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {oidc.ClientID},
+		"redirect_uri":          {oidc.RedirectURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"nonce":                 {login.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	c.Redirect(http.StatusFound, discovery.AuthorizationEndpoint+separator+query.Encode())
+}
+
+func OIDCCallback(c *gin.Context) {
+	if errorCode := c.Query("error"); errorCode != "" {
+		c.String(http.StatusUnauthorized, "Login failed: %s %s", errorCode, c.Query("error_description"))
+		return
+	}
+
+	oidcState.Lock()
+	login, found := oidcState.logins[c.Query("state")]
+	delete(oidcState.logins, c.Query("state"))
+	oidcState.Unlock()
+
+	if !found || time.Now().After(login.expiresAt) {
+		c.String(http.StatusUnauthorized, "Login expired, please try again")
+		return
+	}
+
+	claims, err := exchangeCode(c.Query("code"), login)
+	if err != nil {
+		log.Error(err)
+		c.String(http.StatusUnauthorized, "Login failed: %s", err.Error())
+		return
+	}
+
+	oidc := config.GetConfig().OIDC
+	user, allowed := authorizedUser(claims, oidc.AllowedUsers)
+	if user == "" {
+		log.Warn("Id token has neither a verified email nor a subject")
+		c.String(http.StatusUnauthorized, "Login failed: id token has neither a verified email nor a subject")
+		return
+	}
+	if !allowed {
+		log.Warnf("User %s is not allowed to login", user)
+		c.String(http.StatusForbidden, "User %s is not allowed to login", user)
+		return
+	}
+
+	id := randomToken()
+	oidcState.Lock()
+	expire(oidcState.sessions, func(s session) time.Time { return s.expiresAt })
+	oidcState.sessions[id] = session{user: user, readonly: oidc.Readonly, expiresAt: time.Now().Add(SESSION_DURATION)}
+	oidcState.Unlock()
+
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SESSION_COOKIE, id, int(SESSION_DURATION.Seconds()), "/", "", secure, true)
+	c.Redirect(http.StatusFound, "/")
+}
+
+func Logout(c *gin.Context) {
+	if id, err := c.Cookie(SESSION_COOKIE); err == nil {
+		oidcState.Lock()
+		delete(oidcState.sessions, id)
+		oidcState.Unlock()
+	}
+
+	c.SetCookie(SESSION_COOKIE, "", -1, "/", "", false, true)
+	c.JSON(200, gin.H{"success": true})
+}
+
+// sessionFromCookie returns the session of the request if it has a
+// valid session cookie.
+func sessionFromCookie(c *gin.Context) (session, bool) {
+	id, err := c.Cookie(SESSION_COOKIE)
+	if err != nil || id == "" {
+		return session{}, false
+	}
+
+	oidcState.Lock()
+	defer oidcState.Unlock()
+	s, found := oidcState.sessions[id]
+	if !found || time.Now().After(s.expiresAt) {
+		return session{}, false
+	}
+	return s, true
+}
+
+func discover(issuer string) (oidcDiscovery, error) {
+	oidcState.Lock()
+	discovery, found := oidcState.discovery[issuer]
+	oidcState.Unlock()
+	if found {
+		return discovery, nil
+	}
+
+	resp, err := oidcClient.Get(strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration")
+	if err != nil {
+		return discovery, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return discovery, fmt.Errorf("Failed to fetch openid configuration of %s: status %d", issuer, resp.StatusCode)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&discovery)
+	if err != nil {
+		return discovery, err
+	}
+
+	oidcState.Lock()
+	oidcState.discovery[issuer] = discovery
+	oidcState.Unlock()
+	return discovery, nil
+}
+
+type idTokenClaims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	ExpiresAt     int64    `json:"exp"`
+	Nonce         string   `json:"nonce"`
+	Email         string   `json:"email"`
+	EmailVerified verified `json:"email_verified"`
+}
+
+// aud is either a string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = []string{single}
+		return nil
+	}
+	var multiple []string
+	err := json.Unmarshal(data, &multiple)
+	*a = multiple
+	return err
+}
+
+// email_verified is either a boolean or a string, depending on the
+// provider
+type verified bool
+
+func (v *verified) UnmarshalJSON(data []byte) error {
+	var value any
+	err := json.Unmarshal(data, &value)
+	*v = value == true || value == "true"
+	return err
+}
+
+// exchangeCode trades the authorization code for the claims of the id
+// token. The token comes straight from the token endpoint over TLS,
+// which the spec allows in place of verifying the signature, the
+// claims are still validated.
+func exchangeCode(code string, login oidcLogin) (idTokenClaims, error) {
+	var claims idTokenClaims
+	oidc := config.GetConfig().OIDC
+	discovery, err := discover(oidc.Issuer)
+	if err != nil {
+		return claims, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {oidc.RedirectURL},
+		"code_verifier": {login.verifier},
+	}
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return claims, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(oidc.ClientID), url.QueryEscape(oidc.ClientSecret))
+
+	resp, err := oidcClient.Do(req)
+	if err != nil {
+		return claims, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return claims, fmt.Errorf("Token request failed: status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&token)
+	if err != nil {
+		return claims, err
+	}
+
+	parts := strings.Split(token.IDToken, ".")
+	if len(parts) != 3 {
+		return claims, fmt.Errorf("Invalid id token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, err
+	}
+
+	err = json.Unmarshal(payload, &claims)
+	if err != nil {
+		return claims, err
+	}
+
+	if claims.Issuer != discovery.Issuer {
+		return claims, fmt.Errorf("Unexpected issuer %s", claims.Issuer)
+	}
+	if !lo.Contains(claims.Audience, oidc.ClientID) {
+		return claims, fmt.Errorf("Id token is not issued for %s", oidc.ClientID)
+	}
+	if time.Now().After(time.Unix(claims.ExpiresAt, 0)) {
+		return claims, fmt.Errorf("Id token expired")
+	}
+	if claims.Nonce != login.nonce {
+		return claims, fmt.Errorf("Invalid nonce")
+	}
+
+	return claims, nil
+}
+
+// authorizedUser picks the claim identifying the user, preferring the
+// one present in the allowed users. The email is trusted only if the
+// provider has verified it. The username can be changed by the user
+// on most providers, so it's never trusted.
+func authorizedUser(claims idTokenClaims, allowedUsers []string) (string, bool) {
+	var email string
+	if claims.EmailVerified {
+		email = claims.Email
+	}
+	candidates := lo.Compact([]string{email, claims.Subject})
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	if len(allowedUsers) == 0 {
+		return candidates[0], true
+	}
+
+	for _, user := range candidates {
+		if lo.Contains(allowedUsers, user) {
+			return user, true
+		}
+	}
+	return candidates[0], false
+}
+
+func randomToken() string {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func expire[T any](entries map[string]T, expiresAt func(T) time.Time) {
+	now := time.Now()
+	for key, entry := range entries {
+		if now.After(expiresAt(entry)) {
+			delete(entries, key)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const DEFAULT_OPENING_BALANCE_ACCOUNT = "Equity:OpeningBalance"
+
+type OpeningBalance struct {
+	Account   string          `json:"account" binding:"required"`
+	Amount    decimal.Decimal `json:"amount"`
+	Commodity string          `json:"commodity"`
+}
+
+type OpeningHolding struct {
+	Account   string          `json:"account" binding:"required"`
+	Commodity string          `json:"commodity" binding:"required"`
+	Units     decimal.Decimal `json:"units"`
+	// average cost of a single unit in the default currency
+	AverageCost decimal.Decimal `json:"average_cost"`
+	// current price of a single unit, optional
+	Price *decimal.Decimal `json:"price"`
+}
+
+type OpeningBalanceRequest struct {
+	Date          *time.Time       `json:"date"`
+	EquityAccount string           `json:"equity_account"`
+	Balances      []OpeningBalance `json:"balances"`
+	Holdings      []OpeningHolding `json:"holdings"`
+	Prices        bool             `json:"prices"`
+	File          string           `json:"file"`
+	DryRun        bool             `json:"dry_run"`
+}
+
+// CreateOpeningBalance generates the transaction that brings the
+// accounts to their current balances, balanced against the equity
+// account. The holdings are recorded at their average cost. With
+// prices enabled, the average cost is also recorded as the price on
+// the opening date and the current price, if known, as the price of
+// today, so the gains are visible from day one.
+func CreateOpeningBalance(db *gorm.DB, request OpeningBalanceRequest) gin.H {
+	date := utils.Now()
+	if request.Date != nil {
+		date = request.Date.In(config.TimeZone())
+	}
+
+	equityAccount := request.EquityAccount
+	if equityAccount == "" {
+		equityAccount = DEFAULT_OPENING_BALANCE_ACCOUNT
+	}
+
+	beancount := config.LedgerCliFor(journal.JournalPath(db)) == "beancount"
+	defaultCurrency := config.DefaultCurrency()
+
+	postings := []journal.Posting{}
+	for _, b := range request.Balances {
+		if b.Amount.IsZero() {
+			continue
+		}
+
+		commodity := b.Commodity
+		if commodity == "" {
+			commodity = defaultCurrency
+		}
+		postings = append(postings, journal.Posting{Account: b.Account, Amount: journal.FormatAmount(b.Amount, commodity)})
+	}
+
+	prices := []string{}
+	for _, h := range request.Holdings {
+		if !h.Units.IsPositive() {
+			return gin.H{"saved": false, "message": fmt.Sprintf("Units of %s should be positive", h.Commodity)}
+		}
+		if h.AverageCost.IsNegative() {
+			return gin.H{"saved": false, "message": fmt.Sprintf("Average cost of %s can't be negative", h.Commodity)}
+		}
+
+		units := journal.FormatAmount(h.Units, h.Commodity)
+		cost := journal.FormatAmount(h.AverageCost, defaultCurrency)
+		amount := units + " @ " + cost
+		if beancount {
+			amount = units + " {" + cost + "}"
+		}
+		postings = append(postings, journal.Posting{Account: h.Account, Amount: amount})
+
+		if request.Prices {
+			prices = append(prices, priceDirective(beancount, date, h.Commodity, cost))
+			if h.Price != nil && utils.Now().After(utils.EndOfDay(date)) {
+				prices = append(prices, priceDirective(beancount, utils.Now(), h.Commodity, journal.FormatAmount(*h.Price, defaultCurrency)))
+			}
+		}
+	}
+
+	if len(postings) == 0 {
+		return gin.H{"saved": false, "message": "At least one balance or holding is required"}
+	}
+	postings = append(postings, journal.Posting{Account: equityAccount})
+
+	transaction := journal.Transaction{Date: date, Payee: "Opening Balance", Postings: postings}
+	if err := transaction.Validate(); err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	content := transaction.Format()
+	if len(prices) > 0 {
+		content += "\n\n" + strings.Join(prices, "\n")
+	}
+
+	if request.DryRun {
+		return gin.H{"saved": false, "content": content}
+	}
+
+	return appendToJournal(db, "opening balance", request.File, content)
+}
+
+func priceDirective(beancount bool, date time.Time, commodity string, price string) string {
+	if strings.ContainsAny(commodity, " 0123456789") {
+		commodity = "\"" + commodity + "\""
+	}
+	if beancount {
+		return fmt.Sprintf("%s price %s %s", date.Format("2006-01-02"), commodity, price)
+	}
+	return fmt.Sprintf("P %s %s %s", date.Format("2006/01/02"), commodity, price)
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/commodity"
+	"github.com/ananthakumaran/paisa/internal/model/commoditymetadata"
+	"github.com/ananthakumaran/paisa/internal/model/portfolio"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type CommonSecurity struct {
+	SecurityID   string            `json:"security_id"`
+	SecurityName string            `json:"security_name"`
+	Percentages  []decimal.Decimal `json:"percentages"`
+}
+
+type PortfolioOverlap struct {
+	Commodities []string `json:"commodities"`
+	// sum of the smaller weight of each common security
+	Percentage decimal.Decimal  `json:"percentage"`
+	Securities []CommonSecurity `json:"securities"`
+}
+
+// GetPortfolioOverlap compares the holdings of each pair of funds
+// currently held. Along with the overlap, the true sector exposure is
+// returned, which is the industry breakdown after unwrapping the funds.
+func GetPortfolioOverlap(db *gorm.DB) gin.H {
+	commodities := lo.Map(portfolio.GetAllParentCommodityIDs(db), func(code string, _ int) config.Commodity { return commodity.FindByCode(code) })
+	postings := query.Init(db).AccountPrefix("Assets").Commodities(commodities).All()
+	postings = service.PopulateMarketPrice(db, postings)
+	byCommodity := lo.GroupBy(postings, func(p posting.Posting) string { return p.Commodity })
+
+	held := lo.Filter(commodities, func(c config.Commodity, _ int) bool {
+		return accounting.CurrentBalance(byCommodity[c.Name]).GreaterThan(decimal.NewFromFloat(0.0001))
+	})
+	sort.Slice(held, func(i, j int) bool { return held[i].Name < held[j].Name })
+
+	holdings := lo.Map(held, func(c config.Commodity, _ int) map[string]portfolio.Portfolio {
+		securities := lo.Filter(portfolio.GetPortfolios(db, c.Price.Code), func(p portfolio.Portfolio, _ int) bool {
+			return p.SecurityID != ""
+		})
+		return lo.KeyBy(securities, func(p portfolio.Portfolio) string { return p.SecurityID })
+	})
+
+	overlaps := []PortfolioOverlap{}
+	for i := range held {
+		for j := i + 1; j < len(held); j++ {
+			overlaps = append(overlaps, computePortfolioOverlap(held[i].Name, holdings[i], held[j].Name, holdings[j]))
+		}
+	}
+	sort.SliceStable(overlaps, func(i, j int) bool { return overlaps[i].Percentage.GreaterThan(overlaps[j].Percentage) })
+
+	metadata := commoditymetadata.ByCommodities(db)
+	return gin.H{
+		"overlaps": overlaps,
+		"industry": GetAccountPortfolioAllocation(db, "Assets").Industry,
+		"metadata": lo.PickByKeys(metadata, lo.Map(held, func(c config.Commodity, _ int) string { return c.Name })),
+	}
+}
+
+func computePortfolioOverlap(a string, aHoldings map[string]portfolio.Portfolio, b string, bHoldings map[string]portfolio.Portfolio) PortfolioOverlap {
+	overlap := PortfolioOverlap{Commodities: []string{a, b}, Percentage: decimal.Zero, Securities: []CommonSecurity{}}
+	for id, x := range aHoldings {
+		y, found := bHoldings[id]
+		if !found {
+			continue
+		}
+
+		overlap.Percentage = overlap.Percentage.Add(decimal.Min(x.Percentage, y.Percentage))
+		overlap.Securities = append(overlap.Securities, CommonSecurity{
+			SecurityID:   id,
+			SecurityName: x.SecurityName,
+			Percentages:  []decimal.Decimal{x.Percentage, y.Percentage},
+		})
+	}
+
+	sort.Slice(overlap.Securities, func(i, j int) bool {
+		return decimal.Min(overlap.Securities[i].Percentages[0], overlap.Securities[i].Percentages[1]).
+			GreaterThan(decimal.Min(overlap.Securities[j].Percentages[0], overlap.Securities[j].Percentages[1]))
+	})
+	return overlap
+}
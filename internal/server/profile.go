@@ -0,0 +1,12 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/profile"
+	"github.com/gin-gonic/gin"
+)
+
+const PROFILE_HEADER = "X-Profile"
+
+func GetProfiles() gin.H {
+	return gin.H{"profiles": profile.Names()}
+}
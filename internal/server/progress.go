@@ -0,0 +1,66 @@
+package server
+
+import (
+	"io"
+	"sync"
+
+	"github.com/ananthakumaran/paisa/internal/model"
+	"github.com/gin-gonic/gin"
+)
+
+const PRICE_PROGRESS_PATH = "/api/sync/progress"
+
+// sent once the price update is over, after the progress of the last
+// commodity
+const priceUpdateCompleted = "completed"
+
+type progressBroker struct {
+	sync.Mutex
+	subscribers map[chan model.PriceProgress]bool
+}
+
+var priceProgress = progressBroker{subscribers: make(map[chan model.PriceProgress]bool)}
+
+func (b *progressBroker) subscribe() chan model.PriceProgress {
+	b.Lock()
+	defer b.Unlock()
+	ch := make(chan model.PriceProgress, 64)
+	b.subscribers[ch] = true
+	return ch
+}
+
+func (b *progressBroker) unsubscribe(ch chan model.PriceProgress) {
+	b.Lock()
+	defer b.Unlock()
+	delete(b.subscribers, ch)
+}
+
+// publish never blocks the price update, slow subscribers miss
+// intermediate progress instead.
+func (b *progressBroker) publish(progress model.PriceProgress) {
+	b.Lock()
+	defer b.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- progress:
+		default:
+		}
+	}
+}
+
+// StreamPriceProgress streams the progress of the price updates as
+// server sent events until the client disconnects.
+func StreamPriceProgress(c *gin.Context) {
+	ch := priceProgress.subscribe()
+	defer priceProgress.unsubscribe(ch)
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case progress := <-ch:
+			c.SSEvent("progress", progress)
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
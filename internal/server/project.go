@@ -0,0 +1,126 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type ProjectBurnDown struct {
+	Date  time.Time       `json:"date"`
+	Spent decimal.Decimal `json:"spent"`
+	// budget left after the spends till the date
+	Remaining decimal.Decimal `json:"remaining"`
+	// budget that should be left if the budget is spent evenly over the
+	// project duration
+	Planned *decimal.Decimal `json:"planned"`
+}
+
+type ProjectSummary struct {
+	Name      string                     `json:"name"`
+	Budget    decimal.Decimal            `json:"budget"`
+	Spent     decimal.Decimal            `json:"spent"`
+	Remaining decimal.Decimal            `json:"remaining"`
+	Start     *time.Time                 `json:"start"`
+	End       *time.Time                 `json:"end"`
+	Accounts  map[string]decimal.Decimal `json:"accounts"`
+	BurnDown  []ProjectBurnDown          `json:"burn_down"`
+	Postings  []posting.Posting          `json:"postings"`
+	Error     string                     `json:"error,omitempty"`
+}
+
+func GetProjects(db *gorm.DB) gin.H {
+	expenses := query.Init(db).Like("Expenses:%").All()
+	projects := lo.Map(config.GetConfig().Projects, func(project config.Project, _ int) ProjectSummary {
+		return computeProject(project, expenses)
+	})
+	return gin.H{"projects": projects}
+}
+
+// computeProject picks the expenses tagged with the tag of the project
+// or booked under the project accounts, within the project dates when
+// specified.
+func computeProject(project config.Project, expenses []posting.Posting) ProjectSummary {
+	summary := ProjectSummary{
+		Name:     project.Name,
+		Budget:   decimal.NewFromFloat(project.Budget),
+		Accounts: make(map[string]decimal.Decimal),
+		BurnDown: []ProjectBurnDown{},
+		Postings: []posting.Posting{},
+	}
+
+	start, err := parseOptionalDate(project.Start)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+	end, err := parseOptionalDate(project.End)
+	if err != nil {
+		summary.Error = err.Error()
+		return summary
+	}
+
+	byAccount := lo.KeyBy(accounting.FilterByGlob(expenses, project.Accounts), func(p posting.Posting) uint { return p.ID })
+	postings := lo.Filter(expenses, func(p posting.Posting, _ int) bool {
+		if start != nil && p.Date.Before(*start) {
+			return false
+		}
+		if end != nil && p.Date.After(utils.EndOfDay(*end)) {
+			return false
+		}
+
+		_, ok := byAccount[p.ID]
+		return ok || (project.Tag != "" && lo.Contains(p.Tags(), project.Tag))
+	})
+	summary.Postings = postings
+
+	if len(postings) > 0 {
+		if start == nil {
+			start = &postings[0].Date
+		}
+		if end == nil {
+			end = &postings[len(postings)-1].Date
+		}
+	}
+	summary.Start, summary.End = start, end
+
+	spent := decimal.Zero
+	byDate := lo.GroupBy(postings, func(p posting.Posting) time.Time { return p.Date })
+	for _, date := range lo.Uniq(lo.Map(postings, func(p posting.Posting, _ int) time.Time { return p.Date })) {
+		spent = spent.Add(accounting.CostSum(byDate[date]))
+		point := ProjectBurnDown{Date: date, Spent: spent, Remaining: summary.Budget.Sub(spent)}
+		if summary.Budget.IsPositive() && start != nil && end != nil && end.After(*start) {
+			elapsed := decimal.NewFromFloat(date.Sub(*start).Hours() / end.Sub(*start).Hours())
+			planned := summary.Budget.Sub(summary.Budget.Mul(decimal.Min(elapsed, decimal.NewFromInt(1))))
+			point.Planned = &planned
+		}
+		summary.BurnDown = append(summary.BurnDown, point)
+	}
+
+	for _, p := range postings {
+		summary.Accounts[p.Account] = summary.Accounts[p.Account].Add(p.Amount)
+	}
+	summary.Spent = spent
+	summary.Remaining = summary.Budget.Sub(spent)
+	return summary
+}
+
+func parseOptionalDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, config.TimeZone())
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/server/assets"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type RebalanceSuggestion struct {
+	Name          string          `json:"name"`
+	Target        decimal.Decimal `json:"target"`
+	Current       decimal.Decimal `json:"current"`
+	MarketAmount  decimal.Decimal `json:"market_amount"`
+	TargetAmount  decimal.Decimal `json:"target_amount"`
+	Amount        decimal.Decimal `json:"amount"`
+	Action        string          `json:"action"`
+	EstimatedGain decimal.Decimal `json:"estimated_gain"`
+}
+
+// RebalanceRequest is read from the query params. Cash is the new
+// money available for investment, which is considered part of the
+// portfolio. AvoidSelling overrides the config.
+type RebalanceRequest struct {
+	Cash         float64 `form:"cash"`
+	AvoidSelling *bool   `form:"avoid_selling"`
+}
+
+// GetRebalance suggests the amount to buy or sell in each of the
+// allocation targets to bring the allocation back to the target.
+func GetRebalance(db *gorm.DB, request RebalanceRequest) gin.H {
+	cash := decimal.NewFromFloat(request.Cash)
+	minimumTradeAmount := decimal.NewFromFloat(config.GetConfig().Rebalance.MinimumTradeAmount)
+	avoidSelling := config.GetConfig().Rebalance.AvoidSelling
+	if request.AvoidSelling != nil {
+		avoidSelling = *request.AvoidSelling
+	}
+
+	postings := query.Init(db).Like("Assets:%", config.CapitalGainsAccount()+":%").All()
+	postings = service.PopulateMarketPrice(db, postings)
+
+	total := accounting.CurrentBalance(lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		return !service.IsCapitalGains(p)
+	})).Add(cash)
+
+	suggestions := []RebalanceSuggestion{}
+	if total.IsZero() {
+		return gin.H{"total": total, "suggestions": suggestions}
+	}
+
+	hundred := decimal.NewFromInt(100)
+	for _, target := range config.GetConfig().AllocationTargets {
+		breakdown := assets.ComputeBreakdown(db, accounting.FilterByGlob(postings, target.Accounts), false, target.Name)
+		targetPercent := decimal.NewFromFloat(target.Target)
+		targetAmount := total.Mul(targetPercent).Div(hundred)
+
+		suggestion := RebalanceSuggestion{
+			Name:         target.Name,
+			Target:       targetPercent,
+			Current:      breakdown.MarketAmount.Div(total).Mul(hundred),
+			MarketAmount: breakdown.MarketAmount,
+			TargetAmount: targetAmount,
+			Amount:       targetAmount.Sub(breakdown.MarketAmount),
+		}
+
+		// gain is assumed to be spread evenly across the holdings
+		if suggestion.Amount.IsNegative() && breakdown.MarketAmount.IsPositive() {
+			suggestion.EstimatedGain = breakdown.GainAmount.Mul(suggestion.Amount.Neg()).Div(breakdown.MarketAmount)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+
+	if avoidSelling {
+		distributeCash(suggestions, cash)
+	}
+
+	for i, s := range suggestions {
+		switch {
+		case s.Amount.Abs().LessThan(minimumTradeAmount) || s.Amount.IsZero():
+			suggestions[i].Action = "hold"
+			suggestions[i].Amount = decimal.Zero
+			suggestions[i].EstimatedGain = decimal.Zero
+		case s.Amount.IsPositive():
+			suggestions[i].Action = "buy"
+		default:
+			suggestions[i].Action = "sell"
+		}
+	}
+
+	return gin.H{"total": total, "cash": cash, "suggestions": suggestions}
+}
+
+// distributeCash drops the sell suggestions and splits the cash among
+// the underweight targets in proportion to how far they are from the
+// target.
+func distributeCash(suggestions []RebalanceSuggestion, cash decimal.Decimal) {
+	deficit := decimal.Zero
+	for _, s := range suggestions {
+		if s.Amount.IsPositive() {
+			deficit = deficit.Add(s.Amount)
+		}
+	}
+
+	for i, s := range suggestions {
+		if !s.Amount.IsPositive() {
+			suggestions[i].Amount = decimal.Zero
+			suggestions[i].EstimatedGain = decimal.Zero
+			continue
+		}
+
+		if deficit.GreaterThan(cash) {
+			suggestions[i].Amount = s.Amount.Mul(cash).Div(deficit)
+		}
+	}
+}
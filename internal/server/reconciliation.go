@@ -0,0 +1,99 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/model/reconciliation"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type ReconciliationStatementRequest struct {
+	Account string          `json:"account" binding:"required"`
+	Date    time.Time       `json:"date" binding:"required"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+type ReconciliationMarkRequest struct {
+	TransactionIDs []string `json:"transaction_ids" binding:"required"`
+	Status         string   `json:"status" binding:"required"`
+}
+
+type AccountReconciliation struct {
+	Account        string                    `json:"account"`
+	Statement      *reconciliation.Statement `json:"statement"`
+	ClearedBalance decimal.Decimal           `json:"cleared_balance"`
+	Difference     decimal.Decimal           `json:"difference"`
+	Unreconciled   []posting.Posting         `json:"unreconciled"`
+}
+
+// GetReconciliation lists the assets and liabilities accounts along
+// with their latest statement and the count of postings that are yet
+// to be cleared.
+func GetReconciliation(db *gorm.DB) gin.H {
+	postings := query.Init(db).IncludeExcluded().Like("Assets:%", "Liabilities:%").All()
+	accounts := []gin.H{}
+	for _, account := range utils.SortedKeys(lo.GroupBy(postings, func(p posting.Posting) string { return p.Account })) {
+		r := reconcileAccount(db, account)
+		accounts = append(accounts, gin.H{
+			"account":            r.Account,
+			"statement":          r.Statement,
+			"cleared_balance":    r.ClearedBalance,
+			"difference":         r.Difference,
+			"unreconciled_count": len(r.Unreconciled),
+		})
+	}
+	return gin.H{"accounts": accounts}
+}
+
+func GetAccountReconciliation(db *gorm.DB, account string) gin.H {
+	return gin.H{"reconciliation": reconcileAccount(db, account)}
+}
+
+// reconcileAccount compares the cleared postings against the latest
+// statement. Without a statement, the cleared balance till date is
+// reported.
+func reconcileAccount(db *gorm.DB, account string) AccountReconciliation {
+	r := AccountReconciliation{Account: account}
+
+	until := utils.EndOfToday()
+	if statement, found := reconciliation.Latest(db, account); found {
+		r.Statement = &statement
+		until = utils.EndOfDay(statement.Date)
+	}
+
+	cleared := query.Init(db).IncludeExcluded().AccountPrefix(account).Status("cleared").Where("date <= ?", until).All()
+	r.ClearedBalance = accounting.CostSum(cleared)
+	if r.Statement != nil {
+		r.Difference = r.Statement.Balance.Sub(r.ClearedBalance)
+	}
+
+	r.Unreconciled = query.Init(db).IncludeExcluded().AccountPrefix(account).Where("status != ?", "cleared").All()
+	return r
+}
+
+func SaveReconciliationStatement(db *gorm.DB, request ReconciliationStatementRequest) gin.H {
+	statement := reconciliation.Statement{
+		Account: request.Account,
+		Date:    request.Date,
+		Balance: request.Balance,
+	}
+	reconciliation.Save(db, &statement)
+	return gin.H{"saved": true, "reconciliation": reconcileAccount(db, request.Account)}
+}
+
+// MarkReconciled updates the status of the transactions in the
+// journal, same as the bulk edit.
+func MarkReconciled(db *gorm.DB, request ReconciliationMarkRequest) gin.H {
+	return ApplyBulkOperation(db, BulkOperationRequest{
+		Filter:    BulkFilter{TransactionIDs: request.TransactionIDs},
+		Operation: "set_status",
+		Args:      map[string]string{"status": request.Status},
+	})
+}
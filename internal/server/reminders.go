@@ -0,0 +1,163 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/locale"
+	"github.com/ananthakumaran/paisa/internal/notifier"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// sendReminders notifies about the forecast expenses and the credit
+// card bills due in the next few days, the accounts that went below
+// the configured balance and the unusual expenses. Nothing is sent
+// when there is nothing to remind about.
+func sendReminders(db *gorm.DB) {
+	notifications := config.GetConfig().Notifications
+	sections := []string{}
+
+	if bills := upcomingBills(db, notifications.BillReminderDays); bills != "" {
+		sections = append(sections, bills)
+	}
+
+	if dues := creditCardDues(db, notifications.BillReminderDays); dues != "" {
+		sections = append(sections, dues)
+	}
+
+	if balances := lowBalances(db, notifications.LowBalance); balances != "" {
+		sections = append(sections, balances)
+	}
+
+	if anomalies := unusualExpenses(db, notifications.AnomalyDays); anomalies != "" {
+		sections = append(sections, anomalies)
+	}
+
+	if len(sections) == 0 {
+		return
+	}
+
+	err := notifier.Send("Paisa reminders", strings.Join(sections, "\n\n"))
+	if err != nil {
+		log.Warn("Failed to send reminders: ", err)
+	}
+}
+
+func upcomingBills(db *gorm.DB, days int) string {
+	if days <= 0 {
+		return ""
+	}
+
+	now := utils.Now()
+	bills := query.Init(db).
+		Like("Expenses:%").
+		Forecast().
+		Where("date > ? and date <= ?", utils.EndOfDay(now.AddDate(0, 0, -1)), utils.EndOfDay(now.AddDate(0, 0, days))).
+		All()
+	if len(bills) == 0 {
+		return ""
+	}
+
+	lines := []string{fmt.Sprintf("Due in the next %d days", days)}
+	for _, p := range bills {
+		lines = append(lines, fmt.Sprintf("%s  %s  %s  %s", formatDate(p.Date), p.Payee, p.Account, formatAmount(p.Amount)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func creditCardDues(db *gorm.DB, days int) string {
+	if days <= 0 {
+		return ""
+	}
+
+	until := utils.EndOfDay(utils.Now().AddDate(0, 0, days))
+	lines := []string{}
+	for _, statement := range GetCreditCardStatements(db)["statements"].([]CreditCardStatement) {
+		if statement.DueAmount.IsPositive() && !statement.DueDate.After(until) {
+			lines = append(lines, fmt.Sprintf("%s  %s  %s", formatDate(statement.DueDate), statement.Account, formatAmount(statement.DueAmount)))
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(append([]string{"Credit card dues"}, lines...), "\n")
+}
+
+func lowBalances(db *gorm.DB, alerts []config.LowBalanceAlert) string {
+	lines := []string{}
+	for _, alert := range alerts {
+		balance := accounting.CostSum(query.Init(db).AccountPrefix(alert.Account).All())
+		threshold := decimal.NewFromFloat(alert.Threshold)
+		if balance.LessThan(threshold) {
+			lines = append(lines, fmt.Sprintf("%s  %s (below %s)", alert.Account, formatAmount(balance), formatAmount(threshold)))
+		}
+	}
+
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(append([]string{"Low balance"}, lines...), "\n")
+}
+
+func unusualExpenses(db *gorm.DB, days int) string {
+	if days <= 0 {
+		return ""
+	}
+
+	anomalies := service.DetectAnomalies(db, utils.EndOfDay(utils.Now().AddDate(0, 0, -days)))
+	if len(anomalies) == 0 {
+		return ""
+	}
+
+	lines := []string{"Unusual expenses"}
+	for _, a := range anomalies {
+		p := a.Posting
+		lines = append(lines, fmt.Sprintf("%s  %s  %s  %s (usually %s)", formatDate(p.Date), p.Payee, p.Account, formatAmount(p.Amount), formatAmount(a.Median)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// sendBudgetSummary notifies about the budget of the month that just
+// ended.
+func sendBudgetSummary(db *gorm.DB, month string) {
+	budgets, ok := GetBudget(db)["budgetsByMonth"].(map[string]Budget)
+	if !ok {
+		return
+	}
+
+	budget, found := budgets[month]
+	if !found || len(budget.Accounts) == 0 {
+		return
+	}
+
+	lines := []string{}
+	for _, account := range budget.Accounts {
+		status := "within budget"
+		if account.Available.IsNegative() {
+			status = "over by " + formatAmount(account.Available.Neg())
+		}
+		lines = append(lines, fmt.Sprintf("%s  spent %s of %s, %s", account.Account, formatAmount(account.Actual), formatAmount(account.Forecast), status))
+	}
+
+	err := notifier.Send(fmt.Sprintf("Paisa budget summary for %s", month), strings.Join(lines, "\n"))
+	if err != nil {
+		log.Warn("Failed to send budget summary: ", err)
+	}
+}
+
+func formatAmount(amount decimal.Decimal) string {
+	return locale.Active().Amount(amount)
+}
+
+func formatDate(date time.Time) string {
+	return locale.Active().Date(date)
+}
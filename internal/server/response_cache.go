@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/ananthakumaran/paisa/internal/cache"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const CACHE_STATUS_HEADER = "X-Paisa-Cache"
+
+type cachingResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *cachingResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *cachingResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// cacheResponse serves the report from the response cache when
+// nothing it depends on changed since it was computed. The cache is
+// dropped whenever the journal or the prices are synced, the key
+// covers the rest: the profile or sandbox, the parameters, the config
+// and the current date.
+func cacheResponse(c *gin.Context) {
+	key := fmt.Sprintf("%s|%s|%d|%s", utils.Namespace(requestDB(c)), c.Request.URL.RequestURI(), config.Revision(), utils.Now().Format("2006-01-02"))
+	if response, ok := cache.GetResponse(key); ok {
+		c.Header(CACHE_STATUS_HEADER, "hit")
+		c.Data(http.StatusOK, response.ContentType, response.Body)
+		c.Abort()
+		return
+	}
+
+	generation := cache.Generation()
+	c.Header(CACHE_STATUS_HEADER, "miss")
+	writer := &cachingResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+
+	if writer.Status() == http.StatusOK && len(c.Errors) == 0 {
+		cache.PutResponse(key, generation, cache.Response{ContentType: writer.Header().Get("Content-Type"), Body: writer.body.Bytes()})
+	}
+}
+
+// cacheUnlessLive caches the response like cacheResponse, except when
+// the live quotes are requested, which change during the day.
+func cacheUnlessLive(c *gin.Context) {
+	if live, _ := strconv.ParseBool(c.Query("live")); live {
+		c.Next()
+		return
+	}
+	cacheResponse(c)
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/cache"
+	"github.com/ananthakumaran/paisa/internal/sandbox"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const SANDBOX_HEADER = "X-Sandbox"
+
+type SandboxRequest struct {
+	ID string `json:"id" binding:"required"`
+}
+
+func StartSandbox(db *gorm.DB) gin.H {
+	s, err := sandbox.Start(db)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"success": false, "message": "Failed to create sandbox"}
+	}
+
+	return gin.H{"success": true, "sandbox": s}
+}
+
+func CommitSandbox(db *gorm.DB, request SandboxRequest) gin.H {
+	s, ok := sandbox.Get(request.ID)
+	if !ok {
+		return gin.H{"success": false, "message": "Sandbox not found"}
+	}
+
+	files, err := sandbox.Commit(db, request.ID)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+	db = s.Parent()
+
+	if len(files) == 0 {
+		cache.Clear()
+		return gin.H{"success": true, "files": files}
+	}
+
+	result := Sync(db, SyncRequest{Journal: true})
+	result["files"] = files
+	return result
+}
+
+func DiscardSandbox(request SandboxRequest) gin.H {
+	err := sandbox.Discard(request.ID)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	cache.Clear()
+	return gin.H{"success": true}
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type SavingsRate struct {
+	Date     time.Time       `json:"date"`
+	Income   decimal.Decimal `json:"income"`
+	Expenses decimal.Decimal `json:"expenses"`
+	Savings  decimal.Decimal `json:"savings"`
+	Rate     decimal.Decimal `json:"rate"`
+	// rate over the last 12 months including the current one
+	TrailingRate decimal.Decimal `json:"trailing_rate"`
+}
+
+func GetSavingsRate(db *gorm.DB) gin.H {
+	return gin.H{"savings_rates": computeSavingsRates(db)}
+}
+
+func computeSavingsRates(db *gorm.DB) []SavingsRate {
+	cashFlows := computeCashFlow(db, query.Init(db).ExcludeFuture(), decimal.Zero)
+	emis := utils.GroupByMonth(loanRepayments(query.Init(db).ExcludeFuture().Like("Liabilities:%").All()))
+	sr := config.GetConfig().SavingsRate
+
+	rates := []SavingsRate{}
+	for i, cf := range cashFlows {
+		income := cf.Income
+		expenses := cf.Expenses
+		if sr.Taxes == "expense" {
+			expenses = expenses.Add(cf.Tax)
+		} else {
+			income = income.Sub(cf.Tax)
+		}
+
+		if sr.EMIAsExpense {
+			expenses = expenses.Add(utils.SumBy(emis[cf.Date.Format("2006-01")], func(p posting.Posting) decimal.Decimal {
+				return p.Amount
+			}))
+		}
+
+		rate := SavingsRate{Date: cf.Date, Income: income, Expenses: expenses, Savings: income.Sub(expenses)}
+		rate.Rate = savingsRate(rate.Income, rate.Expenses)
+
+		trailing := append([]SavingsRate{rate}, lo.Slice(rates, i-11, i)...)
+		rate.TrailingRate = savingsRate(
+			utils.SumBy(trailing, func(r SavingsRate) decimal.Decimal { return r.Income }),
+			utils.SumBy(trailing, func(r SavingsRate) decimal.Decimal { return r.Expenses }),
+		)
+
+		rates = append(rates, rate)
+	}
+
+	return rates
+}
+
+func savingsRate(income decimal.Decimal, expenses decimal.Decimal) decimal.Decimal {
+	if !income.IsPositive() {
+		return decimal.Zero
+	}
+	return income.Sub(expenses).Div(income).Mul(decimal.NewFromInt(100))
+}
+
+// loanRepayments returns the principal repaid on the liabilities,
+// except the credit cards, whose payments are already part of the
+// expenses.
+func loanRepayments(postings []posting.Posting) []posting.Posting {
+	creditCards := lo.Map(config.GetConfig().CreditCards, func(c config.CreditCard, _ int) string { return c.Account })
+	return lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		if !p.Amount.IsPositive() || utils.IsSameOrParent(p.Account, "Liabilities:CreditCard") {
+			return false
+		}
+		return !lo.ContainsBy(creditCards, func(account string) bool { return utils.IsSameOrParent(p.Account, account) })
+	})
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type ScheduleFAAmount struct {
+	Native decimal.Decimal `json:"native"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type ScheduleFAEntry struct {
+	Entity          config.ScheduleFAEntity `json:"entity"`
+	AcquisitionDate time.Time               `json:"acquisition_date"`
+	InitialValue    ScheduleFAAmount        `json:"initial_value"`
+	PeakValue       ScheduleFAAmount        `json:"peak_value"`
+	PeakDate        time.Time               `json:"peak_date"`
+	ClosingValue    ScheduleFAAmount        `json:"closing_value"`
+	Income          ScheduleFAAmount        `json:"income"`
+	Proceeds        ScheduleFAAmount        `json:"proceeds"`
+}
+
+// GetScheduleFA reports the foreign holdings of each calendar year,
+// which is the period the Schedule FA of the Indian income tax return
+// asks for. The amounts are converted to the native currency with the
+// price of the currency in the journal and back at the configured
+// rates.
+func GetScheduleFA(db *gorm.DB) gin.H {
+	conf := config.GetConfig().ScheduleFA
+	assets := query.Init(db).Like("Assets:%").ExcludeFuture().All()
+	dividends := query.Init(db).AccountPrefix("Income:Dividend").ExcludeFuture().All()
+
+	scheduleFAs := make(map[string][]ScheduleFAEntry)
+	for _, entity := range conf.Entities {
+		holdings := accounting.FilterByGlob(assets, entity.Accounts)
+		if len(holdings) == 0 {
+			continue
+		}
+
+		names := lo.Uniq(lo.FlatMap(holdings, func(p posting.Posting, _ int) []string { return []string{p.Commodity, lastSegment(p.Account)} }))
+		income := lo.Filter(dividends, func(p posting.Posting, _ int) bool { return lo.Contains(names, lastSegment(p.Account)) })
+		converter := newScheduleFAConverter(db, entity.Currency, conf.Rates)
+
+		end := utils.EndOfToday()
+		for start := utils.BeginningOfYear(utils.CalendarYear, holdings[0].Date); start.Before(end); start = start.AddDate(1, 0, 0) {
+			entry, ok := computeScheduleFAEntry(db, entity, converter, holdings, income, start, utils.MinTime(utils.EndOfYear(utils.CalendarYear, start), end))
+			if ok {
+				year := utils.YearHuman(utils.CalendarYear, start)
+				scheduleFAs[year] = append(scheduleFAs[year], entry)
+			}
+		}
+	}
+
+	return gin.H{"schedule_fas": scheduleFAs}
+}
+
+func computeScheduleFAEntry(db *gorm.DB, entity config.ScheduleFAEntity, converter scheduleFAConverter, holdings []posting.Posting, income []posting.Posting, start time.Time, end time.Time) (ScheduleFAEntry, bool) {
+	opening := accounting.FIFO(lo.Filter(holdings, func(p posting.Posting, _ int) bool { return p.Date.Before(start) }))
+	current := lo.Filter(holdings, func(p posting.Posting, _ int) bool { return utils.IsWithDate(p.Date, start, end) })
+	lots := append(opening, lo.Filter(current, func(p posting.Posting, _ int) bool { return p.Amount.IsPositive() })...)
+	if len(lots) == 0 {
+		return ScheduleFAEntry{}, false
+	}
+
+	entry := ScheduleFAEntry{Entity: entity, AcquisitionDate: lots[0].Date}
+	for _, lot := range lots {
+		entry.InitialValue = entry.InitialValue.add(converter.convert(lot.Amount, lot.Date))
+	}
+
+	held := lo.Filter(holdings, func(p posting.Posting, _ int) bool { return !p.Date.After(end) })
+	for day := start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		value := converter.convert(marketAmountOn(db, held, utils.EndOfDay(day)), day)
+		if value.Native.GreaterThan(entry.PeakValue.Native) {
+			entry.PeakValue = value
+			entry.PeakDate = day
+		}
+	}
+
+	entry.ClosingValue = converter.convert(marketAmountOn(db, held, end), end)
+	for _, p := range lo.Filter(income, func(p posting.Posting, _ int) bool { return utils.IsWithDate(p.Date, start, end) }) {
+		entry.Income = entry.Income.add(converter.convert(p.Amount.Neg(), p.Date))
+	}
+	for _, p := range lo.Filter(current, func(p posting.Posting, _ int) bool { return p.Amount.IsNegative() }) {
+		entry.Proceeds = entry.Proceeds.add(converter.convert(p.Amount.Neg(), p.Date))
+	}
+
+	return entry, true
+}
+
+func (a ScheduleFAAmount) add(b ScheduleFAAmount) ScheduleFAAmount {
+	return ScheduleFAAmount{Native: a.Native.Add(b.Native), Amount: a.Amount.Add(b.Amount)}
+}
+
+type scheduleFAConverter struct {
+	db       *gorm.DB
+	currency string
+	rates    []config.ExchangeRate
+	priced   bool
+}
+
+func newScheduleFAConverter(db *gorm.DB, currency string, rates []config.ExchangeRate) scheduleFAConverter {
+	rates = lo.Filter(rates, func(r config.ExchangeRate, _ int) bool { return r.Currency == currency })
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Date < rates[j].Date })
+	return scheduleFAConverter{db: db, currency: currency, rates: rates, priced: service.HasPrice(db, currency)}
+}
+
+// convert turns the amount in the default currency into the native
+// currency with the price in the journal, falling back to the
+// configured rate, and reports it in the default currency at the
+// configured rate.
+func (c scheduleFAConverter) convert(amount decimal.Decimal, date time.Time) ScheduleFAAmount {
+	configured, found := c.configuredRate(date)
+	rate := configured
+	if c.priced {
+		rate = service.GetUnitPrice(c.db, c.currency, date).Value
+	}
+	if rate.IsZero() {
+		return ScheduleFAAmount{Native: decimal.Zero, Amount: amount}
+	}
+
+	native := amount.Div(rate).Round(2)
+	if !found {
+		return ScheduleFAAmount{Native: native, Amount: amount}
+	}
+	return ScheduleFAAmount{Native: native, Amount: native.Mul(configured).Round(0)}
+}
+
+func (c scheduleFAConverter) configuredRate(date time.Time) (decimal.Decimal, bool) {
+	day := date.Format("2006-01-02")
+	for i := len(c.rates) - 1; i >= 0; i-- {
+		if c.rates[i].Date <= day {
+			return decimal.NewFromFloat(c.rates[i].Rate), true
+		}
+	}
+	return decimal.Zero, false
+}
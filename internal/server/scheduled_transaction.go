@@ -0,0 +1,145 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/scheduledtransaction"
+	"github.com/ananthakumaran/paisa/internal/scheduler"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type ScheduledTransactionRequest struct {
+	ID uint `json:"id"`
+}
+
+var scheduledTransactionsMu sync.Mutex
+
+// postScheduledTransactions generates the occurrences of the scheduled
+// transactions that are due till today. Occurrences that need a review
+// are kept pending, the rest are appended to the journal right away.
+func postScheduledTransactions(db *gorm.DB) {
+	scheduledTransactionsMu.Lock()
+	defer scheduledTransactionsMu.Unlock()
+
+	posted := false
+	for _, definition := range config.GetConfig().ScheduledTransactions {
+		for _, date := range dueDates(db, definition) {
+			st := scheduledtransaction.ScheduledTransaction{
+				Name:    definition.Name,
+				Date:    date,
+				File:    definition.File,
+				Content: renderScheduledTransaction(definition.Content, date),
+				Status:  scheduledtransaction.Pending,
+			}
+
+			if !definition.Review {
+				err := writeScheduledTransaction(db, st)
+				if err != nil {
+					log.Errorf("Failed to post scheduled transaction %s: %v", st.Name, err)
+					continue
+				}
+				st.Status = scheduledtransaction.Posted
+				posted = true
+			}
+
+			scheduledtransaction.Create(db, &st)
+		}
+	}
+
+	if posted {
+		Sync(db, SyncRequest{Journal: true})
+	}
+}
+
+// dueDates returns the due dates after the last occurrence till today.
+// When there is no occurrence yet, only the dates from today are
+// considered, the past is not backfilled.
+func dueDates(db *gorm.DB, definition config.ScheduledTransaction) []time.Time {
+	schedule, err := scheduler.Parse("0 0 " + definition.Schedule)
+	if err != nil {
+		log.Warn(err)
+		return nil
+	}
+
+	today := utils.EndOfToday()
+	from, found := scheduledtransaction.LastDate(db, definition.Name)
+	if !found {
+		from = utils.EndOfDay(today.AddDate(0, 0, -1))
+	}
+
+	dates := []time.Time{}
+	for date := schedule.Next(from.In(config.TimeZone())); !date.IsZero() && !date.After(today); date = schedule.Next(date) {
+		dates = append(dates, date)
+	}
+	return dates
+}
+
+func renderScheduledTransaction(content string, date time.Time) string {
+	return strings.NewReplacer(
+		"{{date}}", date.Format("2006/01/02"),
+		"{{month}}", date.Format("Jan 2006"),
+	).Replace(content)
+}
+
+func writeScheduledTransaction(db *gorm.DB, st scheduledtransaction.ScheduledTransaction) error {
+	name := st.File
+	if name == "" {
+		name = filepath.Base(journal.JournalPath(db))
+	}
+
+	content, err := journal.Read(db, name)
+	if err != nil {
+		return err
+	}
+
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	content += "\n" + strings.TrimSpace(st.Content) + "\n"
+	return journal.Write(db, fmt.Sprintf("scheduled transaction %s", st.Name), name, content)
+}
+
+func GetScheduledTransactions(db *gorm.DB) gin.H {
+	return gin.H{
+		"definitions": config.GetConfig().ScheduledTransactions,
+		"pending":     scheduledtransaction.ByStatus(db, scheduledtransaction.Pending, 100),
+		"posted":      scheduledtransaction.ByStatus(db, scheduledtransaction.Posted, 20),
+	}
+}
+
+func ApproveScheduledTransaction(db *gorm.DB, id uint) gin.H {
+	scheduledTransactionsMu.Lock()
+	defer scheduledTransactionsMu.Unlock()
+
+	st, found := scheduledtransaction.Get(db, id)
+	if !found || st.Status != scheduledtransaction.Pending {
+		return gin.H{"success": false, "message": "Scheduled transaction not found"}
+	}
+
+	err := writeScheduledTransaction(db, st)
+	if err != nil {
+		return gin.H{"success": false, "message": err.Error()}
+	}
+
+	scheduledtransaction.UpdateStatus(db, id, scheduledtransaction.Posted)
+	return Sync(db, SyncRequest{Journal: true})
+}
+
+func RejectScheduledTransaction(db *gorm.DB, id uint) gin.H {
+	st, found := scheduledtransaction.Get(db, id)
+	if !found || st.Status != scheduledtransaction.Pending {
+		return gin.H{"success": false, "message": "Scheduled transaction not found"}
+	}
+
+	scheduledtransaction.UpdateStatus(db, id, scheduledtransaction.Rejected)
+	return gin.H{"success": true}
+}
@@ -0,0 +1,138 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model"
+	"github.com/ananthakumaran/paisa/internal/profile"
+	"github.com/ananthakumaran/paisa/internal/scheduler"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type ScheduledSyncStatus struct {
+	Schedule  string                `json:"schedule"`
+	Running   bool                  `json:"running"`
+	LastRunAt time.Time             `json:"last_run_at"`
+	Duration  float64               `json:"duration"`
+	NextRunAt time.Time             `json:"next_run_at"`
+	Success   bool                  `json:"success"`
+	Message   string                `json:"message"`
+	Errors    []model.PriceProgress `json:"errors"`
+}
+
+var scheduledSync struct {
+	sync.Mutex
+	status ScheduledSyncStatus
+}
+
+// StartScheduler runs the journal and price sync whenever the
+// configured sync_schedule matches, the reminders whenever the
+// notifications schedule matches and the email import, the bank feeds
+// and the crypto sync whenever their schedules match. The schedules
+// are read every minute, so changes to the configuration take effect
+// without a restart. Due scheduled transactions are posted on start
+// and every hour.
+func StartScheduler(db *gorm.DB) {
+	go func() {
+		if !config.GetConfig().Readonly {
+			postScheduledTransactions(db)
+		}
+
+		for {
+			now := time.Now()
+			time.Sleep(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+			now = time.Now().In(config.TimeZone())
+
+			if !config.GetConfig().Readonly && scheduleMatches(config.GetConfig().SyncSchedule, now) {
+				go runScheduledSync(db)
+			}
+
+			if !config.GetConfig().Readonly && now.Minute() == 0 {
+				go postScheduledTransactions(db)
+			}
+
+			if !config.GetConfig().Readonly && scheduleMatches(config.GetConfig().EmailImport.Schedule, now) {
+				go ImportEmails(db)
+			}
+
+			if !config.GetConfig().Readonly && scheduleMatches(config.GetConfig().BankFeeds.Schedule, now) {
+				go SyncBankFeeds(db)
+			}
+
+			if !config.GetConfig().Readonly && scheduleMatches(config.GetConfig().Crypto.Schedule, now) {
+				go SyncCrypto(db)
+			}
+
+			if scheduleMatches(config.GetConfig().Notifications.Schedule, now) {
+				go sendReminders(db)
+			}
+		}
+	}()
+}
+
+func scheduleMatches(expression string, now time.Time) bool {
+	if expression == "" {
+		return false
+	}
+
+	schedule, err := scheduler.Parse(expression)
+	if err != nil {
+		log.Warn(err)
+		return false
+	}
+	return schedule.Matches(now)
+}
+
+func runScheduledSync(db *gorm.DB) {
+	scheduledSync.Lock()
+	if scheduledSync.status.Running {
+		scheduledSync.Unlock()
+		log.Warn("Skipping scheduled sync, previous run is still in progress")
+		return
+	}
+	scheduledSync.status.Running = true
+	scheduledSync.Unlock()
+
+	log.Info("Running scheduled sync")
+	start := time.Now()
+	failures := []model.PriceProgress{}
+	var mu sync.Mutex
+	result := SyncWithProgress(db, SyncRequest{Journal: true, Prices: true}, func(progress model.PriceProgress) {
+		if progress.Status == model.PriceFailed {
+			mu.Lock()
+			failures = append(failures, progress)
+			mu.Unlock()
+		}
+	})
+
+	for _, p := range profile.Opened() {
+		SyncWithProgress(p, SyncRequest{Journal: true, Prices: true}, func(model.PriceProgress) {})
+	}
+
+	scheduledSync.Lock()
+	defer scheduledSync.Unlock()
+	scheduledSync.status.Running = false
+	scheduledSync.status.LastRunAt = start
+	scheduledSync.status.Duration = time.Since(start).Seconds()
+	scheduledSync.status.Success = result["success"] == true
+	scheduledSync.status.Message, _ = result["message"].(string)
+	scheduledSync.status.Errors = failures
+}
+
+func GetScheduledSyncStatus() gin.H {
+	scheduledSync.Lock()
+	status := scheduledSync.status
+	scheduledSync.Unlock()
+
+	status.Schedule = config.GetConfig().SyncSchedule
+	status.NextRunAt = time.Time{}
+	if schedule, err := scheduler.Parse(status.Schedule); status.Schedule != "" && err == nil {
+		status.NextRunAt = schedule.Next(time.Now().In(config.TimeZone()))
+	}
+
+	return gin.H{"status": status}
+}
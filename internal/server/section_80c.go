@@ -0,0 +1,122 @@
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/taxation"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var ELSS_LOCK_IN_YEARS = 3
+
+type Section80CInvestment struct {
+	Account string          `json:"account"`
+	ELSS    bool            `json:"elss"`
+	Amount  decimal.Decimal `json:"amount"`
+}
+
+type ELSSLot struct {
+	Account      string          `json:"account"`
+	Commodity    string          `json:"commodity"`
+	Date         time.Time       `json:"date"`
+	Quantity     decimal.Decimal `json:"quantity"`
+	Amount       decimal.Decimal `json:"amount"`
+	LockInExpiry time.Time       `json:"lock_in_expiry"`
+	Locked       bool            `json:"locked"`
+}
+
+type Section80CRequest struct {
+	FY string `form:"fy"`
+}
+
+// GetSection80C totals the investments of the financial year which
+// qualify for the section 80C deduction against the limit, along with
+// the ELSS lots still held and the date their lock-in ends.
+func GetSection80C(db *gorm.DB, request Section80CRequest) gin.H {
+	fy := request.FY
+	if fy == "" {
+		fy = utils.FYHuman(utils.Now())
+	}
+	start, end := utils.ParseFY(fy)
+
+	conf := config.GetConfig().IncomeTax
+	postings := section80CPostings(db)
+	current := lo.Filter(postings, func(p posting.Posting, _ int) bool { return utils.IsWithDate(p.Date, start, end) })
+
+	investments := []Section80CInvestment{}
+	for _, account := range utils.SortedKeys(lo.GroupBy(current, func(p posting.Posting) string { return p.Account })) {
+		ps := lo.Filter(current, func(p posting.Posting, _ int) bool { return p.Account == account })
+		investments = append(investments, Section80CInvestment{
+			Account: account,
+			ELSS:    len(accounting.FilterByGlob(ps, conf.ELSSAccounts)) > 0,
+			Amount:  section80CInvestment(ps),
+		})
+	}
+
+	total := utils.SumBy(investments, func(i Section80CInvestment) decimal.Decimal { return i.Amount })
+	eligible := decimal.Min(total, taxation.SECTION_80C_LIMIT)
+
+	return gin.H{
+		"fy":          fy,
+		"start_date":  start,
+		"end_date":    end,
+		"investments": investments,
+		"total":       total,
+		"limit":       taxation.SECTION_80C_LIMIT,
+		"eligible":    eligible,
+		"headroom":    taxation.SECTION_80C_LIMIT.Sub(eligible),
+		"elss_lots":   computeELSSLots(postings, conf.ELSSAccounts)}
+}
+
+func section80CPostings(db *gorm.DB) []posting.Posting {
+	conf := config.GetConfig().IncomeTax
+	accounts := append(append([]string{}, conf.Section80CAccounts...), conf.ELSSAccounts...)
+	if len(accounts) == 0 {
+		return []posting.Posting{}
+	}
+	return accounting.FilterByGlob(query.Init(db).Like("Assets:%").All(), accounts)
+}
+
+// section80CInvestment sums the money put into the 80C accounts during
+// the year, the withdrawals don't reduce the deduction.
+func section80CInvestment(postings []posting.Posting) decimal.Decimal {
+	return utils.SumBy(lo.Filter(postings, func(p posting.Posting, _ int) bool {
+		return p.Amount.IsPositive()
+	}), func(p posting.Posting) decimal.Decimal { return p.Amount })
+}
+
+func computeELSSLots(postings []posting.Posting, accounts []string) []ELSSLot {
+	lots := []ELSSLot{}
+	if len(accounts) == 0 {
+		return lots
+	}
+
+	now := utils.Now()
+	byAccount := lo.GroupBy(accounting.FilterByGlob(postings, accounts), func(p posting.Posting) string { return p.Account })
+	for _, ps := range byAccount {
+		for _, p := range accounting.FIFO(ps) {
+			expiry := p.Date.AddDate(ELSS_LOCK_IN_YEARS, 0, 0)
+			lots = append(lots, ELSSLot{
+				Account:      p.Account,
+				Commodity:    p.Commodity,
+				Date:         p.Date,
+				Quantity:     p.Quantity,
+				Amount:       p.Amount,
+				LockInExpiry: expiry,
+				Locked:       expiry.After(now),
+			})
+		}
+	}
+
+	sort.Slice(lots, func(i, j int) bool { return lots[i].LockInExpiry.Before(lots[j].LockInExpiry) })
+	return lots
+}
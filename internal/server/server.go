@@ -5,15 +5,24 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/backup"
+	"github.com/ananthakumaran/paisa/internal/cache"
 	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/generator"
+	"github.com/ananthakumaran/paisa/internal/journal"
 	"github.com/ananthakumaran/paisa/internal/ledger"
+	"github.com/ananthakumaran/paisa/internal/locale"
 	"github.com/ananthakumaran/paisa/internal/model/template"
+	"github.com/ananthakumaran/paisa/internal/model/transactiontemplate"
 	"github.com/ananthakumaran/paisa/internal/prediction"
+	"github.com/ananthakumaran/paisa/internal/profile"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/sandbox"
 	"github.com/ananthakumaran/paisa/internal/server/assets"
 	"github.com/ananthakumaran/paisa/internal/server/goal"
 	"github.com/ananthakumaran/paisa/internal/server/liabilities"
@@ -22,6 +31,7 @@ import (
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
 	"github.com/throttled/throttled/v2"
 	"github.com/throttled/throttled/v2/store/memstore"
@@ -29,17 +39,25 @@ import (
 )
 
 func Build(db *gorm.DB, enableCompression bool) *gin.Engine {
+	startEventSources(db)
+
 	gin.SetMode(gin.ReleaseMode)
 
 	router := gin.New()
 	if enableCompression {
-		router.Use(gzip.Gzip(gzip.DefaultCompression))
+		// the fastest level compresses json almost as well at a fraction
+		// of the cpu, which matters on low powered hosts
+		router.Use(gzip.Gzip(gzip.BestSpeed))
 	}
 
 	router.Use(Logger(log.StandardLogger()), gin.Recovery())
 
 	router.Use(TokenAuthMiddleware())
 
+	router.Use(AuditMiddleware(db))
+
+	router.Use(ScopedDBMiddleware(db))
+
 	router.GET("/robots.txt", func(c *gin.Context) {
 		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte("User-agent: *\nDisallow: /"))
 	})
@@ -52,17 +70,30 @@ func Build(db *gorm.DB, enableCompression bool) *gin.Engine {
 		c.JSON(200, gin.H{"success": true})
 	})
 
+	router.GET(AUTH_METHODS_PATH, func(c *gin.Context) {
+		c.JSON(200, GetAuthMethods())
+	})
+	router.GET(OIDC_LOGIN_PATH, OIDCLogin)
+	router.GET(OIDC_CALLBACK_PATH, OIDCCallback)
+	router.POST(LOGOUT_PATH, Logout)
+
 	router.GET("/api/config", func(c *gin.Context) {
 		var now *time.Time
 		if utils.IsNowDefined() {
 			n := utils.Now()
 			now = &n
 		}
-		c.JSON(200, gin.H{"config": config.GetConfig(), "accounts": accounting.AllAccounts(db), "now": now, "schema": config.GetSchema()})
+		cfg := config.Redact(config.GetConfig())
+		cfg.Readonly = isReadonly(c)
+		c.JSON(200, gin.H{"config": cfg, "accounts": accounting.AllAccounts(requestDB(c)), "now": now, "schema": config.GetSchema(), "restart_required": config.RestartRequired()})
+	})
+
+	router.GET("/api/locale", func(c *gin.Context) {
+		c.JSON(200, gin.H{"locale": locale.Active()})
 	})
 
 	router.POST("/api/config", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
+		if isReadonly(c) {
 			c.JSON(200, gin.H{"success": true})
 			return
 		}
@@ -73,29 +104,78 @@ func Build(db *gorm.DB, enableCompression bool) *gin.Engine {
 			return
 		}
 
-		err = config.SaveConfig(body)
+		body, err = config.RestoreSecrets(body)
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
 			return
 		}
 
-		c.JSON(200, gin.H{"success": true})
+		if before, err := os.ReadFile(config.GetConfigPath()); err == nil {
+			auditBefore(c, config.RedactContent(before))
+		}
+
+		_, err = backup.Create("save config", config.GetConfigPath())
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "error": err.Error()})
+			return
+		}
+
+		err = config.SaveConfig(body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error(), "errors": config.Validate(body)})
+			return
+		}
+
+		cache.Clear()
+		c.JSON(200, gin.H{"success": true, "restart_required": config.RestartRequired()})
+	})
+
+	router.POST("/api/config/reload", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, ReloadConfig())
+	})
+
+	router.POST("/api/config/validate", func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		errors := config.Validate(body)
+		c.JSON(200, gin.H{"valid": len(errors) == 0, "errors": errors})
 	})
 
 	router.POST("/api/init", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
+		if isReadonly(c) {
 			c.JSON(200, gin.H{"success": true})
 			return
 		}
 
+		_, err := backup.Create("init", config.GetConfigPath(), config.GetJournalPath())
+		if err != nil {
+			c.JSON(200, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+
 		generator.Demo(config.GetConfigDir())
 		config.LoadConfigFile(config.GetConfigPath())
-		Sync(db, SyncRequest{Journal: true, Prices: true, Portfolios: true})
+		Sync(requestDB(c), SyncRequest{Journal: true, Prices: true, Portfolios: true})
 		c.JSON(200, gin.H{"success": true})
 	})
 
+	router.GET(PRICE_PROGRESS_PATH, StreamPriceProgress)
+
+	router.GET("/api/sync/status", func(c *gin.Context) {
+		c.JSON(200, GetScheduledSyncStatus())
+	})
+
 	router.POST("/api/sync", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
+		if isReadonly(c) {
 			c.JSON(200, gin.H{"success": true})
 			return
 		}
@@ -106,301 +186,1330 @@ func Build(db *gorm.DB, enableCompression bool) *gin.Engine {
 			return
 		}
 
-		c.JSON(200, Sync(db, syncRequest))
+		c.JSON(200, Sync(requestDB(c), syncRequest))
 	})
 
-	router.GET("/api/dashboard", func(c *gin.Context) {
-		c.JSON(200, GetDashboard(db))
-	})
+	router.GET("/api/dashboard", cacheUnlessLive, func(c *gin.Context) {
+		var request LiveRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.GET("/api/networth", func(c *gin.Context) {
-		c.JSON(200, GetNetworth(db))
+		c.JSON(200, GetDashboard(requestDB(c), request.Live))
 	})
 
-	router.GET("/api/assets/balance", func(c *gin.Context) {
-		c.JSON(200, assets.GetBalance(db))
-	})
+	router.GET("/api/networth", cacheUnlessLive, func(c *gin.Context) {
+		var request LiveRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.GET("/api/investment", func(c *gin.Context) {
-		c.JSON(200, GetInvestment(db))
-	})
-	router.GET("/api/gain", func(c *gin.Context) {
-		c.JSON(200, GetGain(db))
-	})
-	router.GET("/api/gain/:account", func(c *gin.Context) {
-		account := c.Param("account")
-		c.JSON(200, GetAccountGain(db, account))
+		streamJSON(c, GetNetworth(requestDB(c), request.Live))
 	})
-	router.GET("/api/income", func(c *gin.Context) {
-		c.JSON(200, GetIncome(db))
-	})
-	router.GET("/api/expense", func(c *gin.Context) {
-		c.JSON(200, GetExpense(db))
+	router.GET("/api/networth/milestones", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetMilestones(requestDB(c)))
 	})
 
-	router.GET("/api/budget", func(c *gin.Context) {
-		c.JSON(200, GetBudget(db))
-	})
+	router.GET("/api/assets/balance", cacheUnlessLive, func(c *gin.Context) {
+		var request LiveRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.GET("/api/cash_flow", func(c *gin.Context) {
-		c.JSON(200, GetCashFlow(db))
-	})
-	router.GET("/api/income_statement", func(c *gin.Context) {
-		c.JSON(200, GetIncomeStatement(db))
+		c.JSON(200, assets.GetBalance(requestDB(c), request.Live))
 	})
-	router.GET("/api/recurring", func(c *gin.Context) {
-		c.JSON(200, GetRecurringTransactions(db))
+
+	router.GET("/api/investment", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetInvestment(requestDB(c)))
 	})
-	router.GET("/api/allocation", func(c *gin.Context) {
-		c.JSON(200, GetAllocation(db))
+	router.GET("/api/gain", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetGain(requestDB(c)))
 	})
-	router.GET("/api/portfolio_allocation", func(c *gin.Context) {
-		c.JSON(200, GetPortfolioAllocation(db))
+	router.GET("/api/gain/:account", cacheResponse, func(c *gin.Context) {
+		account := c.Param("account")
+		c.JSON(200, GetAccountGain(requestDB(c), account))
 	})
-	router.GET("/api/ledger", func(c *gin.Context) {
-		c.JSON(200, GetLedger(db))
+	router.GET("/api/currency_gain", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetCurrencyGain(requestDB(c)))
 	})
-	router.POST("/api/price/delete", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
-			c.JSON(200, gin.H{"success": true})
+	router.GET("/api/income", cacheResponse, func(c *gin.Context) {
+		var request YearRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, ClearPriceCache(db))
-	})
-	router.GET("/api/price", func(c *gin.Context) {
-		c.JSON(200, GetPrices(db))
-	})
-	router.GET("/api/price/providers", func(c *gin.Context) {
-		c.JSON(200, GetPriceProviders(db))
+		c.JSON(200, GetIncome(requestDB(c), request.Kind()))
 	})
+	router.GET("/api/expense", cacheResponse, func(c *gin.Context) {
+		var request YearRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.POST("/api/price/providers/delete/:provider", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
-			c.JSON(200, gin.H{"success": true})
+		c.JSON(200, GetExpense(requestDB(c), request.Kind()))
+	})
+	router.GET("/api/expense/anomalies", func(c *gin.Context) {
+		var request AnomalyRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		provider := c.Param("provider")
-		c.JSON(200, ClearPriceProviderCache(db, provider))
+		c.JSON(200, GetAnomalies(requestDB(c), request))
 	})
+	router.GET("/api/expense/heatmap", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetExpenseHeatmap(requestDB(c)))
+	})
+	router.GET("/api/expense/calendar", cacheResponse, func(c *gin.Context) {
+		var request MonthRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.POST("/api/price/autocomplete", func(c *gin.Context) {
-		var autoCompleteRequest AutoCompleteRequest
-		if err := c.ShouldBindJSON(&autoCompleteRequest); err != nil {
+		month, err := request.ParseMonth(utils.Now())
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, GetPriceAutoCompletions(db, autoCompleteRequest))
+		c.JSON(200, GetExpenseCalendar(requestDB(c), month))
 	})
 
-	router.GET("/api/transaction/balanced", func(c *gin.Context) {
-		c.JSON(200, GetBalancedPostings(db))
-	})
-	router.GET("/api/transaction", func(c *gin.Context) {
-		c.JSON(200, GetTransactions(db))
-	})
-	router.GET("/api/harvest", func(c *gin.Context) {
-		c.JSON(200, GetHarvest(db))
+	router.GET("/api/budget", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetBudget(requestDB(c)))
 	})
 
-	router.GET("/api/capital_gains", func(c *gin.Context) {
-		c.JSON(200, GetCapitalGains(db))
-	})
+	router.POST("/api/what_if", func(c *gin.Context) {
+		var request WhatIfRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.GET("/api/schedule_al", func(c *gin.Context) {
-		c.JSON(200, GetScheduleAL(db))
-	})
-	router.GET("/api/diagnosis", func(c *gin.Context) {
-		c.JSON(200, GetDiagnosis(db))
+		result, err := GetWhatIf(requestDB(c), request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, result)
 	})
 
-	router.GET("/api/liabilities/interest", func(c *gin.Context) {
-		c.JSON(200, liabilities.GetInterest(db))
-	})
+	router.GET("/api/budget/suggestion", func(c *gin.Context) {
+		var request BudgetSuggestionRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.GET("/api/liabilities/balance", func(c *gin.Context) {
-		c.JSON(200, liabilities.GetBalance(db))
+		c.JSON(200, GetBudgetSuggestions(requestDB(c), request))
 	})
 
-	router.GET("/api/liabilities/repayment", func(c *gin.Context) {
-		c.JSON(200, liabilities.GetRepayment(db))
-	})
+	router.POST("/api/budget/suggestion/save", func(c *gin.Context) {
+		var request SaveBudgetSuggestionRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.GET("/api/logs", func(c *gin.Context) {
-		c.JSON(200, GetLogs())
+		if !request.DryRun && isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, SaveBudgetSuggestions(requestDB(c), request))
 	})
 
-	router.GET("/api/editor/files", func(c *gin.Context) {
-		c.JSON(200, GetFiles(db))
+	router.GET("/api/budget/transfers", func(c *gin.Context) {
+		c.JSON(200, GetBudgetTransfers(requestDB(c)))
 	})
 
-	router.POST("/api/editor/file", func(c *gin.Context) {
-		var ledgerFile LedgerFile
-		if err := c.ShouldBindJSON(&ledgerFile); err != nil {
+	router.POST("/api/budget/transfer", func(c *gin.Context) {
+		var request BudgetTransferRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, GetFile(ledgerFile))
+		if !request.DryRun && isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, CreateBudgetTransfer(requestDB(c), request))
 	})
 
-	router.POST("/api/editor/file/delete_backups", func(c *gin.Context) {
-		var ledgerFile LedgerFile
-		if err := c.ShouldBindJSON(&ledgerFile); err != nil {
+	router.GET("/api/budget/variance", func(c *gin.Context) {
+		var request BudgetVarianceRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, DeleteBackups(ledgerFile))
+		result, err := GetBudgetVariance(requestDB(c), request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, result)
 	})
 
-	router.POST("/api/editor/validate", func(c *gin.Context) {
-		var ledgerFile LedgerFile
-		if err := c.ShouldBindJSON(&ledgerFile); err != nil {
+	router.GET("/api/cash_flow", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetCashFlow(requestDB(c)))
+	})
+	router.GET("/api/cash_flow/tags", func(c *gin.Context) {
+		var request TagCashFlowRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, ValidateFile(ledgerFile))
+		c.JSON(200, GetTagCashFlows(requestDB(c), request))
 	})
-
-	router.POST("/api/editor/save", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
-			c.JSON(200, gin.H{"errors": []ledger.LedgerFileError{}, "saved": false, "message": "Readonly mode"})
+	router.GET("/api/projects", func(c *gin.Context) {
+		c.JSON(200, GetProjects(requestDB(c)))
+	})
+	router.GET("/api/savings_rate", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetSavingsRate(requestDB(c)))
+	})
+	router.GET("/api/monthly_summary", cacheResponse, func(c *gin.Context) {
+		var request MonthRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		var ledgerFile LedgerFile
-		if err := c.ShouldBindJSON(&ledgerFile); err != nil {
+		month, err := request.ParseMonth(utils.BeginningOfMonth(utils.Now()).AddDate(0, -1, 0))
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, SaveFile(db, ledgerFile))
+		c.JSON(200, GetMonthlySummary(requestDB(c), month))
 	})
+	router.POST("/api/monthly_summary/send", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
 
-	router.GET("/api/sheets/files", func(c *gin.Context) {
-		c.JSON(200, GetSheets(db))
-	})
+		var request MonthRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
-	router.POST("/api/sheets/file", func(c *gin.Context) {
-		var sheetFile SheetFile
-		if err := c.ShouldBindJSON(&sheetFile); err != nil {
+		month, err := request.ParseMonth(utils.BeginningOfMonth(utils.Now()).AddDate(0, -1, 0))
+		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, GetSheet(sheetFile))
+		c.JSON(200, SendMonthlySummary(requestDB(c), month))
 	})
-
-	router.POST("/api/sheets/file/delete_backups", func(c *gin.Context) {
-		var sheetFile SheetFile
-		if err := c.ShouldBindJSON(&sheetFile); err != nil {
+	router.GET("/api/income/breakdown", cacheResponse, func(c *gin.Context) {
+		var request YearRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, DeleteSheetBackups(sheetFile))
+		c.JSON(200, GetIncomeBreakdown(requestDB(c), request.Kind()))
 	})
-
-	router.POST("/api/sheets/save", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
-			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+	router.GET("/api/income/dividends", func(c *gin.Context) {
+		c.JSON(200, GetDividends(requestDB(c)))
+	})
+	router.GET("/api/income_statement", cacheResponse, func(c *gin.Context) {
+		var request YearRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		var sheetFile SheetFile
-		if err := c.ShouldBindJSON(&sheetFile); err != nil {
+		c.JSON(200, GetIncomeStatement(requestDB(c), request.Kind()))
+	})
+	router.GET("/api/recurring", func(c *gin.Context) {
+		c.JSON(200, GetRecurringTransactions(requestDB(c)))
+	})
+	router.GET("/api/assertions", func(c *gin.Context) {
+		c.JSON(200, GetAssertions(requestDB(c)))
+	})
+	router.GET("/api/allocation", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetAllocation(requestDB(c)))
+	})
+	router.GET("/api/allocation/drift", func(c *gin.Context) {
+		c.JSON(200, GetAllocationDrift(requestDB(c)))
+	})
+	router.GET("/api/grants", func(c *gin.Context) {
+		c.JSON(200, GetGrants(requestDB(c)))
+	})
+	router.GET("/api/fixed_assets", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetFixedAssets(requestDB(c)))
+	})
+	router.GET("/api/custom_reports", func(c *gin.Context) {
+		c.JSON(200, GetCustomReports())
+	})
+	router.GET("/api/custom_reports/:name", cacheResponse, func(c *gin.Context) {
+		report, found := FindCustomReport(c.Param("name"))
+		if !found {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("Unknown report %s", c.Param("name"))})
+			return
+		}
+		c.JSON(200, GetCustomReport(requestDB(c), report))
+	})
+	router.GET("/api/rebalance", func(c *gin.Context) {
+		var request RebalanceRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, SaveSheetFile(db, sheetFile))
+		c.JSON(200, GetRebalance(requestDB(c), request))
 	})
-
-	router.GET("/api/account/tf_idf", func(c *gin.Context) {
-		c.JSON(200, prediction.GetTfIdf(db))
+	router.GET("/api/portfolio_allocation", func(c *gin.Context) {
+		c.JSON(200, GetPortfolioAllocation(requestDB(c)))
 	})
-
-	router.GET("/api/templates", func(c *gin.Context) {
-		c.JSON(200, gin.H{"templates": template.All()})
+	router.GET("/api/portfolio_allocation/overlap", func(c *gin.Context) {
+		c.JSON(200, GetPortfolioOverlap(requestDB(c)))
 	})
-
-	router.POST("/api/templates/upsert", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
+	router.GET("/api/commodity/metadata", func(c *gin.Context) {
+		c.JSON(200, GetCommodityMetadata(requestDB(c)))
+	})
+	router.POST("/api/commodity/metadata", func(c *gin.Context) {
+		if isReadonly(c) {
 			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
 			return
 		}
 
-		var t template.Template
-		if err := c.ShouldBindJSON(&t); err != nil {
+		var request CommodityMetadataRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(200, gin.H{"template": template.Upsert(t.Name, t.Content), "saved": true})
+		c.JSON(200, SaveCommodityMetadata(requestDB(c), request))
 	})
-
-	router.POST("/api/templates/delete", func(c *gin.Context) {
-		if config.GetConfig().Readonly {
+	router.POST("/api/commodity/metadata/delete", func(c *gin.Context) {
+		if isReadonly(c) {
 			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
 			return
 		}
 
-		var t template.Template
-		if err := c.ShouldBindJSON(&t); err != nil {
+		var request CommodityMetadataDeleteRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
 
-		template.Delete(t.Name)
-		c.JSON(200, gin.H{"success": true})
+		c.JSON(200, DeleteCommodityMetadata(requestDB(c), request))
+	})
+	router.GET("/api/ledger", func(c *gin.Context) {
+		streamJSON(c, GetLedger(requestDB(c)))
 	})
+	router.POST("/api/price/delete", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": true})
+			return
+		}
 
-	router.GET("/api/goals", func(c *gin.Context) {
-		c.JSON(200, gin.H{"goals": goal.GetGoalSummaries(db)})
+		c.JSON(200, ClearPriceCache(requestDB(c)))
+	})
+	router.GET("/api/price", func(c *gin.Context) {
+		c.JSON(200, GetPrices(requestDB(c)))
+	})
+	router.GET("/api/price/providers", func(c *gin.Context) {
+		c.JSON(200, GetPriceProviders(requestDB(c)))
 	})
 
-	router.GET("/api/goals/:type/:name", func(c *gin.Context) {
-		c.JSON(200, goal.GetGoalDetails(db, c.Param("type"), c.Param("name")))
+	router.POST("/api/price/providers/delete/:provider", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": true})
+			return
+		}
+
+		provider := c.Param("provider")
+		c.JSON(200, ClearPriceProviderCache(requestDB(c), provider))
 	})
 
-	router.GET("/api/credit_cards", func(c *gin.Context) {
-		c.JSON(200, GetCreditCards(db))
+	router.POST("/api/price/autocomplete", func(c *gin.Context) {
+		var autoCompleteRequest AutoCompleteRequest
+		if err := c.ShouldBindJSON(&autoCompleteRequest); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetPriceAutoCompletions(requestDB(c), autoCompleteRequest))
 	})
 
-	router.GET("/api/credit_cards/:account", func(c *gin.Context) {
-		c.JSON(200, GetCreditCard(db, c.Param("account")))
+	router.GET("/api/transaction/balanced", func(c *gin.Context) {
+		streamJSON(c, GetBalancedPostings(requestDB(c)))
+	})
+	router.GET("/api/transaction", func(c *gin.Context) {
+		streamJSON(c, GetTransactions(requestDB(c)))
 	})
 
-	router.NoRoute(func(c *gin.Context) {
-		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(web.Index))
+	router.GET(NOTIFICATIONS_PATH, func(c *gin.Context) {
+		NotificationsHandler.ServeHTTP(c.Writer, c.Request)
 	})
 
-	return router
-}
+	// the federation token is validated by TokenAuthMiddleware
+	router.GET(FEDERATION_SUMMARY_PATH, func(c *gin.Context) {
+		c.JSON(200, GetFederationSummary(requestDB(c)))
+	})
 
-func Listen(db *gorm.DB, port int) {
-	router := Build(db, true)
+	router.GET("/api/federation", func(c *gin.Context) {
+		c.JSON(200, GetFederation(requestDB(c)))
+	})
 
-	log.Infof("Listening on http://localhost:%d", port)
-	err := router.Run(fmt.Sprintf(":%d", port))
-	if err != nil {
-		log.Fatal(err)
-	}
-}
+	router.GET("/api/attachments", func(c *gin.Context) {
+		c.JSON(200, GetAttachments())
+	})
 
-func TokenAuthMiddleware() gin.HandlerFunc {
-	store, err := memstore.NewCtx(10)
-	if err != nil {
-		log.Fatal(err)
-	}
+	router.GET("/api/attachments/:name", func(c *gin.Context) {
+		DownloadAttachment(c, c.Param("name"))
+	})
 
-	quota := throttled.RateQuota{
-		MaxRate:  throttled.PerMin(6),
-		MaxBurst: 3,
+	router.POST("/api/attachments", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, UploadAttachment(c))
+	})
+
+	router.POST("/api/attachments/delete", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request AttachmentRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, DeleteAttachment(request))
+	})
+
+	router.GET("/api/backups", func(c *gin.Context) {
+		var request BackupsRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetBackups(db, request))
+	})
+
+	router.POST("/api/backups/diff", func(c *gin.Context) {
+		var request BackupDiffRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetBackupDiff(db, request))
+	})
+
+	router.POST("/api/backups/restore", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request RestoreBackupRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, RestoreBackup(db, request))
+	})
+
+	router.GET("/api/archive", func(c *gin.Context) {
+		// the archive has the config with all the credentials
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		DownloadArchive(c, db)
+	})
+
+	router.POST("/api/archive/restore", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, RestoreArchive(c, db))
+	})
+
+	router.GET("/api/profiles", func(c *gin.Context) {
+		c.JSON(200, GetProfiles())
+	})
+
+	router.GET("/api/household", func(c *gin.Context) {
+		var request HouseholdRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetHousehold(db, request))
+	})
+
+	router.POST("/api/sandbox", func(c *gin.Context) {
+		c.JSON(200, StartSandbox(requestDB(c)))
+	})
+
+	router.POST("/api/sandbox/commit", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request SandboxRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, CommitSandbox(requestDB(c), request))
+	})
+
+	router.POST("/api/sandbox/discard", func(c *gin.Context) {
+		var request SandboxRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, DiscardSandbox(request))
+	})
+
+	router.POST("/api/transaction/update", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request TransactionUpdateRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, UpdateTransaction(requestDB(c), request))
+	})
+
+	router.POST("/api/transaction/split", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request TransactionSplitRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, SplitTransaction(requestDB(c), request))
+	})
+
+	router.POST("/api/transaction/delete", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var location TransactionLocation
+		if err := c.ShouldBindJSON(&location); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, DeleteTransaction(requestDB(c), location))
+	})
+
+	router.POST("/api/transaction/bulk", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request BulkOperationRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, ApplyBulkOperation(requestDB(c), request))
+	})
+
+	router.GET("/api/reconciliation", func(c *gin.Context) {
+		c.JSON(200, GetReconciliation(requestDB(c)))
+	})
+
+	router.GET("/api/reconciliation/:account", func(c *gin.Context) {
+		c.JSON(200, GetAccountReconciliation(requestDB(c), c.Param("account")))
+	})
+
+	router.POST("/api/reconciliation/statement", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request ReconciliationStatementRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, SaveReconciliationStatement(requestDB(c), request))
+	})
+
+	router.POST("/api/reconciliation/mark", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request ReconciliationMarkRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, MarkReconciled(requestDB(c), request))
+	})
+
+	router.GET("/api/harvest", func(c *gin.Context) {
+		c.JSON(200, GetHarvest(requestDB(c)))
+	})
+
+	router.GET("/api/capital_gains", func(c *gin.Context) {
+		c.JSON(200, GetCapitalGains(requestDB(c)))
+	})
+
+	router.GET("/api/schedule_al", func(c *gin.Context) {
+		c.JSON(200, GetScheduleAL(requestDB(c)))
+	})
+	router.GET("/api/schedule_fa", func(c *gin.Context) {
+		c.JSON(200, GetScheduleFA(requestDB(c)))
+	})
+	router.GET("/api/income_tax", func(c *gin.Context) {
+		c.JSON(200, GetIncomeTax(requestDB(c)))
+	})
+	router.GET("/api/income_tax/80c", func(c *gin.Context) {
+		var request Section80CRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetSection80C(requestDB(c), request))
+	})
+	router.GET("/api/diagnosis", func(c *gin.Context) {
+		c.JSON(200, GetDiagnosis(requestDB(c)))
+	})
+
+	router.GET("/api/liabilities/interest", func(c *gin.Context) {
+		c.JSON(200, liabilities.GetInterest(requestDB(c)))
+	})
+
+	router.GET("/api/liabilities/balance", func(c *gin.Context) {
+		c.JSON(200, liabilities.GetBalance(requestDB(c)))
+	})
+
+	router.GET("/api/liabilities/repayment", func(c *gin.Context) {
+		c.JSON(200, liabilities.GetRepayment(requestDB(c)))
+	})
+
+	router.GET("/api/liabilities/rates", func(c *gin.Context) {
+		c.JSON(200, liabilities.GetRates(requestDB(c)))
+	})
+
+	router.GET("/api/liabilities/prepayment", func(c *gin.Context) {
+		var request liabilities.PrepaymentRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		result, err := liabilities.GetPrepayment(requestDB(c), request)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, result)
+	})
+
+	router.GET("/api/export/:report", func(c *gin.Context) {
+		var request ExportRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		ExportReport(c, requestDB(c), c.Param("report"), request)
+	})
+
+	router.GET("/api/audit", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Readonly mode"})
+			return
+		}
+
+		var request AuditRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetAuditLog(db, request))
+	})
+
+	router.GET("/api/logs", func(c *gin.Context) {
+		c.JSON(200, GetLogs())
+	})
+
+	router.GET("/api/editor/files", func(c *gin.Context) {
+		c.JSON(200, GetFiles(requestDB(c)))
+	})
+
+	router.POST("/api/editor/file", func(c *gin.Context) {
+		var ledgerFile LedgerFile
+		if err := c.ShouldBindJSON(&ledgerFile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetFile(requestDB(c), ledgerFile))
+	})
+
+	router.POST("/api/editor/file/delete_backups", func(c *gin.Context) {
+		var ledgerFile LedgerFile
+		if err := c.ShouldBindJSON(&ledgerFile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, DeleteBackups(requestDB(c), ledgerFile, isReadonly(c)))
+	})
+
+	router.POST("/api/editor/validate", func(c *gin.Context) {
+		var ledgerFile LedgerFile
+		if err := c.ShouldBindJSON(&ledgerFile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, ValidateFile(requestDB(c), ledgerFile))
+	})
+
+	router.POST("/api/editor/save", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"errors": []ledger.LedgerFileError{}, "saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var ledgerFile LedgerFile
+		if err := c.ShouldBindJSON(&ledgerFile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if before, err := journal.Read(requestDB(c), ledgerFile.Name); err == nil {
+			auditBefore(c, before)
+		}
+
+		c.JSON(200, SaveFile(requestDB(c), ledgerFile))
+	})
+
+	router.POST("/api/editor/format", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request FormatRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, FormatFiles(requestDB(c), request))
+	})
+
+	router.POST("/api/opening_balance", func(c *gin.Context) {
+		var request OpeningBalanceRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !request.DryRun && isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, CreateOpeningBalance(requestDB(c), request))
+	})
+
+	router.GET("/api/sheets/files", func(c *gin.Context) {
+		c.JSON(200, GetSheets(requestDB(c)))
+	})
+
+	router.POST("/api/sheets/file", func(c *gin.Context) {
+		var sheetFile SheetFile
+		if err := c.ShouldBindJSON(&sheetFile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetSheet(sheetFile))
+	})
+
+	router.POST("/api/sheets/file/delete_backups", func(c *gin.Context) {
+		var sheetFile SheetFile
+		if err := c.ShouldBindJSON(&sheetFile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, DeleteSheetBackups(sheetFile, isReadonly(c)))
+	})
+
+	router.POST("/api/sheets/save", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var sheetFile SheetFile
+		if err := c.ShouldBindJSON(&sheetFile); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, SaveSheetFile(requestDB(c), sheetFile))
+	})
+
+	router.GET("/api/account/tf_idf", func(c *gin.Context) {
+		c.JSON(200, prediction.GetTfIdf(requestDB(c)))
+	})
+
+	router.GET("/api/templates", func(c *gin.Context) {
+		c.JSON(200, gin.H{"templates": template.All()})
+	})
+
+	router.POST("/api/templates/upsert", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var t template.Template
+		if err := c.ShouldBindJSON(&t); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, gin.H{"template": template.Upsert(t.Name, t.Content), "saved": true})
+	})
+
+	router.POST("/api/templates/delete", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var t template.Template
+		if err := c.ShouldBindJSON(&t); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		template.Delete(t.Name)
+		c.JSON(200, gin.H{"success": true})
+	})
+
+	router.GET("/api/transaction_templates", func(c *gin.Context) {
+		c.JSON(200, GetTransactionTemplates())
+	})
+
+	router.POST("/api/transaction_templates/upsert", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var t config.TransactionTemplate
+		if err := c.ShouldBindJSON(&t); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := transactiontemplate.Upsert(t); err != nil {
+			c.JSON(200, gin.H{"saved": false, "message": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"template": t, "saved": true})
+	})
+
+	router.POST("/api/transaction_templates/delete", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var t config.TransactionTemplate
+		if err := c.ShouldBindJSON(&t); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := transactiontemplate.Delete(t.Name); err != nil {
+			c.JSON(200, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		c.JSON(200, gin.H{"success": true})
+	})
+
+	router.POST("/api/transaction_templates/instantiate", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request TransactionTemplateRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, InstantiateTransactionTemplate(requestDB(c), request))
+	})
+
+	router.GET("/api/scheduled_transactions", func(c *gin.Context) {
+		c.JSON(200, GetScheduledTransactions(requestDB(c)))
+	})
+
+	router.POST("/api/scheduled_transactions/approve", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request ScheduledTransactionRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, ApproveScheduledTransaction(requestDB(c), request.ID))
+	})
+
+	router.POST("/api/scheduled_transactions/reject", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request ScheduledTransactionRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, RejectScheduledTransaction(requestDB(c), request.ID))
+	})
+
+	router.GET("/api/drafts", func(c *gin.Context) {
+		c.JSON(200, GetDrafts(requestDB(c)))
+	})
+
+	router.POST("/api/drafts/accept", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request DraftRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, AcceptDraft(requestDB(c), request))
+	})
+
+	router.POST("/api/drafts/reject", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request DraftRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, RejectDraft(requestDB(c), request))
+	})
+
+	router.POST("/api/email_import/sync", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, ImportEmails(requestDB(c)))
+	})
+
+	router.POST("/api/broker_import", func(c *gin.Context) {
+		var request BrokerImportRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !request.DryRun && isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, ImportBrokerStatement(requestDB(c), request))
+	})
+
+	router.POST("/api/statement_import", func(c *gin.Context) {
+		var request StatementImportRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !request.DryRun && isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, ImportStatement(requestDB(c), request))
+	})
+
+	router.POST("/api/broker_import/sync", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, SyncBrokers(requestDB(c)))
+	})
+
+	router.GET("/api/crypto", func(c *gin.Context) {
+		c.JSON(200, GetCrypto())
+	})
+
+	router.POST("/api/crypto/sync", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, SyncCrypto(requestDB(c)))
+	})
+
+	router.GET("/api/bank_feeds", func(c *gin.Context) {
+		c.JSON(200, GetBankFeeds(requestDB(c)))
+	})
+
+	router.GET("/api/bank_feeds/gocardless/institutions", func(c *gin.Context) {
+		c.JSON(200, GetGoCardlessInstitutions(c.Query("country")))
+	})
+
+	router.POST("/api/bank_feeds/gocardless/link", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request GoCardlessLinkRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, LinkGoCardless(c, request))
+	})
+
+	router.GET(GOCARDLESS_CALLBACK_PATH, GoCardlessCallback)
+
+	router.POST("/api/bank_feeds/plaid/link_token", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, CreatePlaidLinkToken(c))
+	})
+
+	router.POST("/api/bank_feeds/plaid/exchange", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request PlaidExchangeRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, ExchangePlaidToken(requestDB(c), request))
+	})
+
+	router.POST(PLAID_WEBHOOK_PATH, PlaidWebhook)
+
+	router.POST("/api/bank_feeds/simplefin/link", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request SimpleFINLinkRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, LinkSimpleFIN(requestDB(c), request))
+	})
+
+	router.POST("/api/bank_feeds/delete", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request BankConnectionRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, DeleteBankConnection(requestDB(c), request))
+	})
+
+	router.POST("/api/bank_feeds/sync", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, SyncBankFeeds(requestDB(c)))
+	})
+
+	router.GET(CALENDAR_PATH, func(c *gin.Context) {
+		c.Data(200, "text/calendar; charset=utf-8", []byte(GetCalendar(requestDB(c))))
+	})
+
+	router.GET("/api/goals", func(c *gin.Context) {
+		c.JSON(200, gin.H{"goals": goal.GetGoalSummaries(requestDB(c))})
+	})
+
+	router.GET("/api/goals/:type/:name", func(c *gin.Context) {
+		c.JSON(200, goal.GetGoalDetails(requestDB(c), c.Param("type"), c.Param("name")))
+	})
+
+	router.GET("/api/credit_cards", func(c *gin.Context) {
+		c.JSON(200, GetCreditCards(requestDB(c)))
+	})
+
+	router.GET("/api/credit_cards/statements", func(c *gin.Context) {
+		c.JSON(200, GetCreditCardStatements(requestDB(c)))
+	})
+
+	router.GET("/api/credit_cards/:account", func(c *gin.Context) {
+		c.JSON(200, GetCreditCard(requestDB(c), c.Param("account")))
+	})
+
+	router.GET("/api/sharing", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetSharing(requestDB(c)))
+	})
+
+	router.POST("/api/sharing/settle", func(c *gin.Context) {
+		var request SettleRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !request.DryRun && isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, Settle(requestDB(c), request))
+	})
+
+	router.GET("/api/gst", cacheResponse, func(c *gin.Context) {
+		var request GSTRequest
+		if err := c.ShouldBindQuery(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, GetGST(requestDB(c), request))
+	})
+
+	router.GET("/api/invoices", cacheResponse, func(c *gin.Context) {
+		c.JSON(200, GetInvoices(requestDB(c)))
+	})
+
+	router.POST("/api/invoices/create", func(c *gin.Context) {
+		var request InvoiceRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !request.DryRun && isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, CreateInvoice(requestDB(c), request))
+	})
+
+	router.POST("/api/invoices/pay", func(c *gin.Context) {
+		var request InvoicePaymentRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if !request.DryRun && isReadonly(c) {
+			c.JSON(200, gin.H{"saved": false, "message": "Readonly mode"})
+			return
+		}
+
+		c.JSON(200, PayInvoice(requestDB(c), request))
+	})
+
+	router.POST("/api/invoices/delete", func(c *gin.Context) {
+		if isReadonly(c) {
+			c.JSON(200, gin.H{"success": false, "message": "Readonly mode"})
+			return
+		}
+
+		var request InvoiceDeleteRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(200, DeleteInvoice(requestDB(c), request))
+	})
+
+	router.NoRoute(func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(web.Index))
+	})
+
+	return router
+}
+
+func Listen(db *gorm.DB, port int) {
+	router := Build(db, true)
+	StartScheduler(db)
+
+	log.Infof("Listening on http://localhost:%d", port)
+	err := router.Run(fmt.Sprintf(":%d", port))
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+const DB_CONTEXT_KEY = "db"
+
+// ScopedDBMiddleware attaches a request specific db session to the
+// context, which carries the query options (like includeFuture and
+// includeExcluded) down to the query layer. Requests made for a profile
+// get the profile db and requests made within a sandbox get the sandbox
+// db.
+func ScopedDBMiddleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scoped := db
+		if name := c.GetHeader(PROFILE_HEADER); name != "" {
+			p, err := profile.DB(name)
+			if err != nil {
+				status := http.StatusInternalServerError
+				if err == profile.ErrNotFound {
+					status = http.StatusNotFound
+				}
+				c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+				return
+			}
+			scoped = p
+		}
+
+		if id := c.GetHeader(SANDBOX_HEADER); id != "" {
+			s, ok := sandbox.Get(id)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Sandbox not found"})
+				return
+			}
+			scoped = s.DB()
+		}
+
+		if includeFuture, ok := c.GetQuery("includeFuture"); ok {
+			scoped = query.WithIncludeFuture(scoped, includeFuture == "true" || includeFuture == "1")
+		}
+
+		if includeExcluded, ok := c.GetQuery("includeExcluded"); ok {
+			scoped = query.WithIncludeExcluded(scoped, includeExcluded == "true" || includeExcluded == "1")
+		}
+
+		c.Set(DB_CONTEXT_KEY, scoped)
+		c.Next()
+	}
+}
+
+func requestDB(c *gin.Context) *gorm.DB {
+	return c.MustGet(DB_CONTEXT_KEY).(*gorm.DB)
+}
+
+const READONLY_USER_KEY = "readonly_user"
+
+// USER_KEY holds the name of the logged in user, empty when the
+// authentication is not enabled.
+const USER_KEY = "user"
+
+// isReadonly reports whether the request is allowed to make changes,
+// either the whole instance or the logged in user could be readonly.
+func isReadonly(c *gin.Context) bool {
+	return config.GetConfig().Readonly || c.GetBool(READONLY_USER_KEY)
+}
+
+func TokenAuthMiddleware() gin.HandlerFunc {
+	store, err := memstore.NewCtx(10)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	quota := throttled.RateQuota{
+		MaxRate:  throttled.PerMin(6),
+		MaxBurst: 3,
 	}
 
 	rateLimiter, err := throttled.NewGCRARateLimiterCtx(store, quota)
@@ -409,8 +1518,52 @@ func TokenAuthMiddleware() gin.HandlerFunc {
 	}
 
 	return func(c *gin.Context) {
+		if c.Request.URL.Path == FEDERATION_SUMMARY_PATH {
+			// federation summary is authenticated using the federation
+			// token instead of the user account, even if the user
+			// accounts are not configured. The token only grants access
+			// to the summary of its own profile.
+			_, detail, _ := rateLimiter.RateLimitCtx(c.Request.Context(), "federation", 0)
+			if detail.Remaining <= 0 {
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+				return
+			}
+
+			token, ok := FederationTokenFor(c.GetHeader(FEDERATION_TOKEN_HEADER))
+			if !ok {
+				rateLimiter.RateLimitCtx(c.Request.Context(), "federation", 1)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid federation token"})
+				return
+			}
+
+			c.Request.Header.Del(SANDBOX_HEADER)
+			if token.Profile == "" {
+				c.Request.Header.Del(PROFILE_HEADER)
+			} else {
+				c.Request.Header.Set(PROFILE_HEADER, token.Profile)
+			}
+			c.Set(READONLY_USER_KEY, true)
+			c.Set(USER_KEY, "federation:"+token.Name)
+			c.Next()
+			return
+		}
+
 		userAccounts := config.GetConfig().UserAccounts
-		if len(userAccounts) == 0 || !strings.HasPrefix(c.Request.RequestURI, "/api") {
+		if (len(userAccounts) == 0 && !isOIDCEnabled()) || !strings.HasPrefix(c.Request.RequestURI, "/api") {
+			c.Next()
+			return
+		}
+
+		switch c.Request.URL.Path {
+		// the login itself and the callbacks of the providers
+		case AUTH_METHODS_PATH, OIDC_LOGIN_PATH, OIDC_CALLBACK_PATH, LOGOUT_PATH, GOCARDLESS_CALLBACK_PATH, PLAID_WEBHOOK_PATH:
+			c.Next()
+			return
+		}
+
+		if session, ok := sessionFromCookie(c); ok {
+			c.Set(READONLY_USER_KEY, session.readonly)
+			c.Set(USER_KEY, session.user)
 			c.Next()
 			return
 		}
@@ -421,7 +1574,29 @@ func TokenAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokens := strings.SplitN(c.Request.Header.Get("X-Auth"), ":", 2)
+		if c.Request.URL.Path == CALENDAR_PATH {
+			// calendar clients can't send custom headers, the feed is
+			// authenticated using a calendar token in the query string
+			if name, ok := CalendarTokenName(c.Query("token")); ok {
+				c.Set(READONLY_USER_KEY, true)
+				c.Set(USER_KEY, "calendar:"+name)
+				c.Next()
+				return
+			}
+
+			rateLimiter.RateLimitCtx(c.Request.Context(), "user", 1)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Token"})
+			return
+		}
+
+		token := c.Request.Header.Get("X-Auth")
+		if token == "" && lo.Contains([]string{NOTIFICATIONS_PATH, PRICE_PROGRESS_PATH}, c.Request.URL.Path) {
+			// browsers don't allow custom headers on websocket and
+			// event source requests
+			token = c.Query("token")
+		}
+
+		tokens := strings.SplitN(token, ":", 2)
 		if len(tokens) != 2 {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid Token"})
 			return
@@ -431,6 +1606,8 @@ func TokenAuthMiddleware() gin.HandlerFunc {
 		for _, userAccount := range userAccounts {
 			if subtle.ConstantTimeCompare([]byte(userAccount.Username), []byte(tokens[0])) == 1 &&
 				subtle.ConstantTimeCompare([]byte(userAccount.Password), []byte("sha256:"+hashed)) == 1 {
+				c.Set(READONLY_USER_KEY, userAccount.Readonly)
+				c.Set(USER_KEY, userAccount.Username)
 				c.Next()
 				return
 			}
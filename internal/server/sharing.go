@@ -0,0 +1,164 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type SharedExpense struct {
+	Posting posting.Posting `json:"posting"`
+	// percentage of the expense borne by the counterparty
+	Share  decimal.Decimal `json:"share"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type Counterparty struct {
+	Name    string `json:"name"`
+	Account string `json:"account"`
+	// share of the expenses paid by you
+	Shared decimal.Decimal `json:"shared"`
+	// balance of the account of the counterparty, the expenses paid by
+	// them and the settlements
+	Balance decimal.Decimal `json:"balance"`
+	// positive when the counterparty owes you
+	Outstanding decimal.Decimal   `json:"outstanding"`
+	Expenses    []SharedExpense   `json:"expenses"`
+	Postings    []posting.Posting `json:"postings"`
+}
+
+type SettleRequest struct {
+	Name string `json:"name" binding:"required"`
+	// account that receives or pays the money, defaults to the first
+	// checking account
+	Account string     `json:"account"`
+	Date    *time.Time `json:"date"`
+	File    string     `json:"file"`
+	DryRun  bool       `json:"dry_run"`
+}
+
+var DEFAULT_SHARE = decimal.NewFromInt(50)
+
+// GetSharing computes how much each counterparty owes. The expenses
+// tagged with the counterparty (shared: Alice) are split equally
+// unless the share of the counterparty is given (shared: Alice 40%).
+// The expenses paid by the counterparty on your behalf and the money
+// exchanged to settle up are booked under the account of the
+// counterparty, Assets:Receivable:Alice.
+func GetSharing(db *gorm.DB) gin.H {
+	return gin.H{"counterparties": computeCounterparties(db)}
+}
+
+func computeCounterparties(db *gorm.DB) []Counterparty {
+	conf := config.GetConfig().ExpenseSharing
+	counterparties := make(map[string]*Counterparty)
+	get := func(name string) *Counterparty {
+		c, ok := counterparties[name]
+		if !ok {
+			c = &Counterparty{Name: name, Account: conf.ReceivableAccount + ":" + name, Expenses: []SharedExpense{}, Postings: []posting.Posting{}}
+			counterparties[name] = c
+		}
+		return c
+	}
+
+	for _, p := range query.Init(db).IncludeExcluded().ExcludeFuture().Like("Expenses:%").All() {
+		value, ok := p.TagValue(conf.Tag)
+		if !ok {
+			continue
+		}
+		name, share := parseShare(value)
+		if name == "" {
+			continue
+		}
+
+		c := get(name)
+		amount := p.Amount.Mul(share).Div(decimal.NewFromInt(100)).Round(2)
+		c.Expenses = append(c.Expenses, SharedExpense{Posting: p, Share: share, Amount: amount})
+		c.Shared = c.Shared.Add(amount)
+	}
+
+	for _, p := range query.Init(db).IncludeExcluded().ExcludeFuture().Like(conf.ReceivableAccount + ":%").All() {
+		if _, ok := p.TagValue(INVOICE_TAG); ok {
+			continue
+		}
+		name := strings.Split(strings.TrimPrefix(p.Account, conf.ReceivableAccount+":"), ":")[0]
+		c := get(name)
+		c.Postings = append(c.Postings, p)
+	}
+
+	result := []Counterparty{}
+	for _, name := range utils.SortedKeys(counterparties) {
+		c := counterparties[name]
+		c.Balance = accounting.CostSum(c.Postings)
+		c.Outstanding = c.Shared.Add(c.Balance)
+		sort.SliceStable(c.Expenses, func(i, j int) bool { return c.Expenses[i].Posting.Date.After(c.Expenses[j].Posting.Date) })
+		result = append(result, *c)
+	}
+	return result
+}
+
+// parseShare splits the tag value into the name of the counterparty
+// and their share in percentage.
+func parseShare(value string) (string, decimal.Decimal) {
+	fields := strings.Fields(value)
+	if len(fields) > 1 && strings.HasSuffix(fields[len(fields)-1], "%") {
+		share, err := decimal.NewFromString(strings.TrimSuffix(fields[len(fields)-1], "%"))
+		if err == nil {
+			return strings.Join(fields[:len(fields)-1], " "), share
+		}
+	}
+	return strings.Join(fields, " "), DEFAULT_SHARE
+}
+
+// Settle writes the transaction that clears the outstanding balance
+// of the counterparty.
+func Settle(db *gorm.DB, request SettleRequest) gin.H {
+	c, found := lo.Find(computeCounterparties(db), func(c Counterparty) bool { return c.Name == request.Name })
+	if !found {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Counterparty %s not found", request.Name)}
+	}
+	if c.Outstanding.IsZero() {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Nothing to settle with %s", c.Name)}
+	}
+
+	date := utils.Now()
+	if request.Date != nil {
+		date = request.Date.In(config.TimeZone())
+	}
+
+	account := request.Account
+	if account == "" {
+		account = config.CheckingAccounts()[0]
+	}
+
+	transaction := journal.Transaction{
+		Date:  date,
+		Payee: fmt.Sprintf("Settlement with %s", c.Name),
+		Postings: []journal.Posting{
+			{Account: account, Amount: journal.FormatAmount(c.Outstanding, config.DefaultCurrency())},
+			{Account: c.Account, Amount: journal.FormatAmount(c.Outstanding.Neg(), config.DefaultCurrency())},
+		},
+	}
+	if err := transaction.Validate(); err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	content := transaction.Format()
+	if request.DryRun {
+		return gin.H{"saved": false, "content": content}
+	}
+
+	return appendToJournal(db, fmt.Sprintf("settlement with %s", c.Name), request.File, content)
+}
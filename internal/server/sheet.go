@@ -7,10 +7,10 @@ import (
 
 	"os"
 
+	"github.com/ananthakumaran/paisa/internal/backup"
 	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/query"
 	"github.com/ananthakumaran/paisa/internal/service"
-	"github.com/bmatcuk/doublestar/v4"
 	"github.com/gin-gonic/gin"
 	"github.com/samber/lo"
 	log "github.com/sirupsen/logrus"
@@ -28,7 +28,7 @@ type SheetFile struct {
 
 func GetSheets(db *gorm.DB) gin.H {
 	dir := config.GetSheetDir()
-	paths, _ := doublestar.FilepathGlob(dir + "/**/*" + EXTENSION)
+	paths, _ := config.GlobFiles(dir, EXTENSION)
 
 	files := []*SheetFile{}
 	for _, path := range paths {
@@ -46,10 +46,10 @@ func GetSheet(file SheetFile) gin.H {
 	return gin.H{"file": readSheetFile(dir, filepath.Join(dir, file.Name))}
 }
 
-func DeleteSheetBackups(file SheetFile) gin.H {
+func DeleteSheetBackups(file SheetFile, readonly bool) gin.H {
 	dir := config.GetSheetDir()
 
-	if !config.GetConfig().Readonly {
+	if !readonly {
 		versions, _ := filepath.Glob(filepath.Join(dir, file.Name+".backup.*"))
 		for _, version := range versions {
 			err := os.Remove(version)
@@ -93,6 +93,12 @@ func SaveSheetFile(db *gorm.DB, file SheetFile) gin.H {
 			return gin.H{"saved": false, "message": "Failed to read file"}
 		}
 
+		_, err = backup.Create("save sheet "+file.Name, filePath)
+		if err != nil {
+			log.Warn(err)
+			return gin.H{"saved": false, "message": "Failed to create backup"}
+		}
+
 		err = os.WriteFile(backupPath, existingContent, perm)
 		if err != nil {
 			log.Warn(err)
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ananthakumaran/paisa/internal/bankfeed"
+	"github.com/ananthakumaran/paisa/internal/brokerimport"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/csvimport"
+	"github.com/ananthakumaran/paisa/internal/model/draft"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+type StatementImportRequest struct {
+	Content string `json:"content" binding:"required"`
+	// bank statements are read as per the rules, the trade files of
+	// the configured broker ignore the rules
+	Rules  csvimport.Rules `json:"rules"`
+	Broker string          `json:"broker"`
+	File   string          `json:"file"`
+	DryRun bool            `json:"dry_run"`
+}
+
+// ImportStatement appends the transactions of the statement to the
+// journal, skipping the ones imported before. Unlike the bank feeds,
+// the transactions don't wait in the review queue, but they are
+// recorded as accepted drafts so that they are not imported again.
+func ImportStatement(db *gorm.DB, request StatementImportRequest) gin.H {
+	var drafts []draft.Draft
+	if request.Broker != "" {
+		broker, found := lo.Find(config.GetConfig().BrokerImports, func(b config.BrokerImport) bool { return b.Broker == request.Broker })
+		if !found {
+			return gin.H{"saved": false, "message": fmt.Sprintf("Broker %s is not configured", request.Broker)}
+		}
+
+		statement, err := brokerimport.Parse(broker.Broker, request.Content)
+		if err != nil {
+			return gin.H{"saved": false, "message": err.Error()}
+		}
+		drafts = brokerimport.Drafts(db, broker, statement)
+	} else {
+		transactions, err := csvimport.Parse(request.Content, request.Rules)
+		if err != nil {
+			return gin.H{"saved": false, "message": err.Error()}
+		}
+
+		rules := append(append([]config.ImportRule{}, request.Rules.Rules...), config.GetConfig().ImportRules...)
+		for _, t := range transactions {
+			if !draft.Exists(db, csvimport.SOURCE, t.ID) {
+				drafts = append(drafts, bankfeed.BuildDraft(db, csvimport.SOURCE, request.Rules.Account, rules, t))
+			}
+		}
+	}
+
+	if len(drafts) == 0 {
+		return gin.H{"saved": false, "message": "No new transactions found", "imported": 0}
+	}
+
+	content := strings.Join(lo.Map(drafts, func(d draft.Draft, _ int) string { return strings.TrimSpace(d.Content) }), "\n\n")
+	if request.DryRun {
+		return gin.H{"saved": false, "content": content, "imported": 0}
+	}
+
+	result := appendToJournal(db, fmt.Sprintf("import %d transactions", len(drafts)), request.File, content)
+	if result["saved"] == true {
+		for i := range drafts {
+			drafts[i].Status = draft.Accepted
+			draft.Create(db, &drafts[i])
+		}
+		result["imported"] = len(drafts)
+	}
+	return result
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+)
+
+const STREAM_BUFFER_SIZE = 32 * 1024
+
+// streamJSON is a drop in replacement for c.JSON meant for the
+// responses with large arrays. The arrays are encoded an element at a
+// time into a small buffer, which is written out as a chunk whenever
+// it fills up, instead of marshalling the whole response in memory
+// before sending the first byte. The keys are written in sorted order
+// like json.Marshal does.
+func streamJSON(c *gin.Context, body gin.H) {
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	w := bufio.NewWriterSize(c.Writer, STREAM_BUFFER_SIZE)
+	encoder := json.NewEncoder(w)
+	err := func() error {
+		w.WriteByte('{')
+		for i, key := range utils.SortedKeys(body) {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			if err := encoder.Encode(key); err != nil {
+				return err
+			}
+			w.WriteByte(':')
+
+			value := reflect.ValueOf(body[key])
+			if value.Kind() != reflect.Slice || value.IsNil() || value.Type().Elem().Kind() == reflect.Uint8 {
+				if err := encoder.Encode(body[key]); err != nil {
+					return err
+				}
+				continue
+			}
+
+			w.WriteByte('[')
+			for j := 0; j < value.Len(); j++ {
+				if j > 0 {
+					w.WriteByte(',')
+				}
+				if err := encoder.Encode(value.Index(j).Interface()); err != nil {
+					return err
+				}
+			}
+			w.WriteByte(']')
+		}
+		w.WriteByte('}')
+		return w.Flush()
+	}()
+
+	// the status is already sent, the client sees a truncated response
+	if err != nil {
+		_ = c.Error(err)
+	}
+}
@@ -2,7 +2,10 @@ package server
 
 import (
 	"github.com/ananthakumaran/paisa/internal/cache"
+	"github.com/ananthakumaran/paisa/internal/event"
 	"github.com/ananthakumaran/paisa/internal/model"
+	"github.com/ananthakumaran/paisa/internal/remote"
+	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -11,20 +14,66 @@ type SyncRequest struct {
 	Journal    bool `json:"journal"`
 	Prices     bool `json:"prices"`
 	Portfolios bool `json:"portfolios"`
+	Full       bool `json:"full"`
 }
 
+// Sync imports the journal only if it changed since the last sync,
+// unless a full sync is requested. The in memory caches are kept when
+// there is nothing new to import.
 func Sync(db *gorm.DB, request SyncRequest) gin.H {
-	cache.Clear()
+	return SyncWithProgress(db, request, func(model.PriceProgress) {})
+}
+
+// SyncWithProgress reports the progress of the price update to the
+// given callback, in addition to the clients streaming the progress.
+func SyncWithProgress(db *gorm.DB, request SyncRequest, progress func(model.PriceProgress)) gin.H {
+	if request.Full || request.Prices || request.Portfolios {
+		cache.Clear()
+		// drop whatever was computed while the sync was in progress
+		defer cache.Clear()
+	}
 
 	if request.Journal {
-		message, err := model.SyncJournal(db)
+		if r := remote.Current(); r != nil {
+			if _, err := r.Sync(); err != nil {
+				return gin.H{"success": false, "message": err.Error()}
+			}
+		}
+
+		var changed bool
+		var message string
+		var err error
+		if request.Full {
+			changed = true
+			message, err = model.SyncJournal(db)
+		} else {
+			changed, message, err = model.SyncJournalChanges(db)
+		}
+
 		if err != nil {
 			return gin.H{"success": false, "message": message}
 		}
+
+		if changed {
+			cache.Clear()
+			event.Publish(event.LedgerLoaded, map[string]any{"namespace": utils.Namespace(db)})
+		}
 	}
 
 	if request.Prices {
-		err := model.SyncCommodities(db)
+		err := model.SyncCommoditiesWithProgress(db, func(p model.PriceProgress) {
+			progress(p)
+			priceProgress.publish(p)
+			if p.Status == model.PriceFailed {
+				event.Publish(event.PriceFailed, map[string]any{
+					"namespace": utils.Namespace(db),
+					"commodity": p.Commodity,
+					"provider":  p.Provider,
+					"error":     p.Error,
+				})
+			}
+		})
+		priceProgress.publish(model.PriceProgress{Status: priceUpdateCompleted})
 		if err != nil {
 			return gin.H{"success": false, "message": err.Error()}
 		}
@@ -32,6 +81,7 @@ func Sync(db *gorm.DB, request SyncRequest) gin.H {
 		if err != nil {
 			return gin.H{"success": false, "message": err.Error()}
 		}
+		event.Publish(event.PriceUpdated, map[string]any{"namespace": utils.Namespace(db)})
 	}
 
 	if request.Portfolios {
@@ -0,0 +1,83 @@
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type TagCashFlowRequest struct {
+	Tag string `form:"tag"`
+}
+
+type TagMonth struct {
+	Date     time.Time       `json:"date"`
+	Income   decimal.Decimal `json:"income"`
+	Expenses decimal.Decimal `json:"expenses"`
+}
+
+type TagCashFlow struct {
+	Tag      string                     `json:"tag"`
+	Income   decimal.Decimal            `json:"income"`
+	Expenses decimal.Decimal            `json:"expenses"`
+	Start    time.Time                  `json:"start"`
+	End      time.Time                  `json:"end"`
+	Accounts map[string]decimal.Decimal `json:"accounts"`
+	Months   []TagMonth                 `json:"months"`
+}
+
+// GetTagCashFlows totals the income and the expenses of each tag month
+// by month. A transaction tagged with multiple tags is counted under
+// each of them.
+func GetTagCashFlows(db *gorm.DB, request TagCashFlowRequest) gin.H {
+	postings := query.Init(db).Like("Income:%", "Expenses:%").All()
+
+	byTag := make(map[string][]posting.Posting)
+	for _, p := range postings {
+		for _, tag := range p.Tags() {
+			if request.Tag == "" || request.Tag == tag {
+				byTag[tag] = append(byTag[tag], p)
+			}
+		}
+	}
+
+	cashFlows := lo.Map(utils.SortedKeys(byTag), func(tag string, _ int) TagCashFlow {
+		return computeTagCashFlow(tag, byTag[tag])
+	})
+	sort.SliceStable(cashFlows, func(i, j int) bool { return cashFlows[i].Start.After(cashFlows[j].Start) })
+	return gin.H{"tags": cashFlows}
+}
+
+func computeTagCashFlow(tag string, postings []posting.Posting) TagCashFlow {
+	cashFlow := TagCashFlow{
+		Tag:      tag,
+		Start:    postings[0].Date,
+		End:      postings[len(postings)-1].Date,
+		Accounts: make(map[string]decimal.Decimal),
+		Months:   []TagMonth{},
+	}
+
+	for _, p := range postings {
+		cashFlow.Accounts[p.Account] = cashFlow.Accounts[p.Account].Add(p.Amount)
+	}
+
+	byMonth := utils.GroupByMonth(postings)
+	for _, month := range utils.SortedKeys(byMonth) {
+		ps := byMonth[month]
+		income := accounting.CostSum(lo.Filter(ps, func(p posting.Posting, _ int) bool { return utils.IsSameOrParent(p.Account, "Income") })).Neg()
+		expenses := accounting.CostSum(lo.Filter(ps, func(p posting.Posting, _ int) bool { return utils.IsSameOrParent(p.Account, "Expenses") }))
+		cashFlow.Income = cashFlow.Income.Add(income)
+		cashFlow.Expenses = cashFlow.Expenses.Add(expenses)
+		cashFlow.Months = append(cashFlow.Months, TagMonth{Date: utils.BeginningOfMonth(ps[0].Date), Income: income, Expenses: expenses})
+	}
+
+	return cashFlow
+}
@@ -1,18 +1,26 @@
 package server
 
 import (
+	"fmt"
 	"sort"
 
 	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/model/sourcefile"
 	"github.com/ananthakumaran/paisa/internal/model/transaction"
 	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
 
 	"gorm.io/gorm"
 )
 
 func GetTransactions(db *gorm.DB) gin.H {
-	postings := query.Init(db).Desc().All()
+	postings := query.Init(db).IncludeExcluded().Desc().All()
 	transactions := transaction.Build(postings)
 
 	sort.Slice(transactions, func(i, j int) bool { return transactions[i].ID > transactions[j].ID })
@@ -38,3 +46,145 @@ func GetLatestTransactions(db *gorm.DB) []transaction.Transaction {
 
 	return transactions
 }
+
+type TransactionLocation struct {
+	FileName  string `json:"file_name"`
+	BeginLine uint64 `json:"begin_line"`
+	EndLine   uint64 `json:"end_line"`
+}
+
+type TransactionUpdateRequest struct {
+	TransactionLocation
+	Transaction journal.Transaction `json:"transaction"`
+}
+
+func UpdateTransaction(db *gorm.DB, request TransactionUpdateRequest) gin.H {
+	err := request.Transaction.Validate()
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	content, err := journal.Read(db, request.FileName)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": "Failed to read file"}
+	}
+
+	before, err := journal.Lines(content, request.BeginLine, request.EndLine)
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	after := journal.UpdateTransaction(before, request.Transaction, config.LedgerCliFor(journal.JournalPath(db)))
+	return rewriteTransaction(db, "update transaction", request.TransactionLocation, after)
+}
+
+func DeleteTransaction(db *gorm.DB, location TransactionLocation) gin.H {
+	return rewriteTransaction(db, "delete transaction", location, "")
+}
+
+func rewriteTransaction(db *gorm.DB, operation string, location TransactionLocation, replacement string) gin.H {
+	var count int64
+	db.Model(&posting.Posting{}).
+		Where("file_name = ? and transaction_begin_line = ? and transaction_end_line = ? and forecast = ?", location.FileName, location.BeginLine, location.EndLine, false).
+		Count(&count)
+	if count == 0 {
+		return gin.H{"saved": false, "message": "Transaction not found. The journal might have changed, please reload and try again."}
+	}
+
+	content, err := journal.Read(db, location.FileName)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": "Failed to read file"}
+	}
+
+	// the line numbers are valid only if the file is not changed
+	// after the last sync
+	if sourcefile.FileHash(db, location.FileName) != utils.Sha256(content) {
+		return gin.H{"saved": false, "conflict": true, "message": "The file has changed since the last sync, please reload and try again."}
+	}
+
+	updated, err := journal.ReplaceLines(content, location.BeginLine, location.EndLine, replacement)
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	errors, _, err := validateFile(db, LedgerFile{Name: location.FileName, Content: updated})
+	if err != nil {
+		return gin.H{"errors": errors, "saved": false, "message": "Validation failed"}
+	}
+
+	err = journal.Write(db, operation, location.FileName, updated)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": "Failed to write file"}
+	}
+
+	Sync(db, SyncRequest{Journal: true})
+	return gin.H{"saved": true}
+}
+
+type TransactionSplit struct {
+	Account string          `json:"account" binding:"required"`
+	Amount  decimal.Decimal `json:"amount"`
+	Note    string          `json:"note"`
+}
+
+type TransactionSplitRequest struct {
+	TransactionLocation
+	Account string             `json:"account" binding:"required"`
+	Splits  []TransactionSplit `json:"splits" binding:"required"`
+}
+
+// SplitTransaction replaces the posting of the account with multiple
+// postings, like a supermarket bill split into groceries and
+// household. The splits should add up to the amount of the original
+// posting and are written in the commodity of the original posting.
+func SplitTransaction(db *gorm.DB, request TransactionSplitRequest) gin.H {
+	var postings []posting.Posting
+	db.Where("file_name = ? and transaction_begin_line = ? and transaction_end_line = ? and forecast = ? and account = ?", request.FileName, request.BeginLine, request.EndLine, false, request.Account).
+		Find(&postings)
+	if len(postings) == 0 {
+		return gin.H{"saved": false, "message": "Posting not found. The journal might have changed, please reload and try again."}
+	}
+	if len(postings) > 1 {
+		return gin.H{"saved": false, "message": fmt.Sprintf("%s appears more than once in the transaction", request.Account)}
+	}
+	original := postings[0]
+
+	if len(request.Splits) < 2 {
+		return gin.H{"saved": false, "message": "At least two splits are required"}
+	}
+
+	total := decimal.Zero
+	splits := []journal.Posting{}
+	for _, split := range request.Splits {
+		if split.Amount.IsZero() {
+			return gin.H{"saved": false, "message": fmt.Sprintf("Amount of %s should not be zero", split.Account)}
+		}
+		total = total.Add(split.Amount)
+		splits = append(splits, journal.Posting{Account: split.Account, Amount: journal.FormatAmount(split.Amount, original.Commodity), Note: split.Note})
+	}
+
+	if !total.Equal(original.Quantity) {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Splits add up to %s, expected %s", total.String(), original.Quantity.String())}
+	}
+
+	content, err := journal.Read(db, request.FileName)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": "Failed to read file"}
+	}
+
+	before, err := journal.Lines(content, request.BeginLine, request.EndLine)
+	if err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	after, updated := journal.SplitPosting(before, request.Account, splits)
+	if !updated {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Failed to find the posting line of %s", request.Account)}
+	}
+
+	return rewriteTransaction(db, "split transaction", request.TransactionLocation, after)
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/journal"
+	"github.com/ananthakumaran/paisa/internal/model/transactiontemplate"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var placeholderRegex = regexp.MustCompile(`{{\s*([\w-]+)\s*}}`)
+
+type TransactionTemplateRequest struct {
+	Name   string            `json:"name" binding:"required"`
+	Date   *time.Time        `json:"date"`
+	Values map[string]string `json:"values"`
+	DryRun bool              `json:"dry_run"`
+}
+
+func GetTransactionTemplates() gin.H {
+	templates := lo.Map(transactiontemplate.All(), func(t config.TransactionTemplate, _ int) gin.H {
+		return gin.H{"template": t, "placeholders": templatePlaceholders(t)}
+	})
+	return gin.H{"templates": templates}
+}
+
+// InstantiateTransactionTemplate fills in the placeholders of the
+// template and appends the transaction to the journal. The date
+// defaults to today.
+func InstantiateTransactionTemplate(db *gorm.DB, request TransactionTemplateRequest) gin.H {
+	t, found := transactiontemplate.Find(request.Name)
+	if !found {
+		return gin.H{"saved": false, "message": fmt.Sprintf("Template %s not found", request.Name)}
+	}
+
+	date := utils.Now()
+	if request.Date != nil {
+		date = request.Date.In(config.TimeZone())
+	}
+
+	missing := lo.Filter(templatePlaceholders(t), func(name string, _ int) bool {
+		return strings.TrimSpace(request.Values[name]) == ""
+	})
+	if len(missing) > 0 {
+		return gin.H{"saved": false, "message": "Missing values for " + strings.Join(missing, ", ")}
+	}
+
+	fill := func(text string) string {
+		return placeholderRegex.ReplaceAllStringFunc(text, func(match string) string {
+			return strings.TrimSpace(request.Values[placeholderRegex.FindStringSubmatch(match)[1]])
+		})
+	}
+
+	transaction := journal.Transaction{
+		Date:  date,
+		Payee: fill(t.Payee),
+		Note:  fill(t.Note),
+		Postings: lo.Map(t.Postings, func(p config.TransactionTemplatePosting, _ int) journal.Posting {
+			return journal.Posting{Account: p.Account, Amount: fill(p.Amount)}
+		}),
+	}
+	if err := transaction.Validate(); err != nil {
+		return gin.H{"saved": false, "message": err.Error()}
+	}
+
+	content := transaction.Format()
+	if request.DryRun {
+		return gin.H{"saved": false, "content": content}
+	}
+
+	return appendToJournal(db, fmt.Sprintf("template %s", t.Name), t.File, content)
+}
+
+// appendToJournal appends the content to the end of the journal file,
+// the main journal file if the name is empty. The file is validated
+// before it is written.
+func appendToJournal(db *gorm.DB, operation string, name string, content string) gin.H {
+	if name == "" {
+		name = filepath.Base(journal.JournalPath(db))
+	}
+
+	existing, err := journal.Read(db, name)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": fmt.Sprintf("Failed to read file %s", name)}
+	}
+
+	if existing != "" && !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	updated := existing + "\n" + content + "\n"
+
+	errors, _, err := validateFile(db, LedgerFile{Name: name, Content: updated})
+	if err != nil {
+		return gin.H{"errors": errors, "saved": false, "message": "Validation failed", "content": content}
+	}
+
+	err = journal.Write(db, operation, name, updated)
+	if err != nil {
+		log.Warn(err)
+		return gin.H{"saved": false, "message": "Failed to write file"}
+	}
+
+	Sync(db, SyncRequest{Journal: true})
+	return gin.H{"saved": true, "content": content}
+}
+
+func templatePlaceholders(t config.TransactionTemplate) []string {
+	texts := append([]string{t.Payee, t.Note}, lo.Map(t.Postings, func(p config.TransactionTemplatePosting, _ int) string { return p.Amount })...)
+	names := []string{}
+	for _, text := range texts {
+		for _, match := range placeholderRegex.FindAllStringSubmatch(text, -1) {
+			names = append(names, match[1])
+		}
+	}
+	return lo.Uniq(names)
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/accounting"
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/ananthakumaran/paisa/internal/service"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const WHAT_IF_DEFAULT_MONTHS = 12
+
+// ledger generates the forecast transactions for the next 3 years
+const WHAT_IF_MAX_MONTHS = 36
+
+// WhatIfChange either scales the forecasted amount of the account by
+// the percentage or adds the amount to the account every month.
+type WhatIfChange struct {
+	Account    string   `json:"account" binding:"required"`
+	Percentage *float64 `json:"percentage"`
+	Amount     *float64 `json:"amount"`
+}
+
+type WhatIfRequest struct {
+	Months  int            `json:"months"`
+	Changes []WhatIfChange `json:"changes"`
+}
+
+type NetworthProjection struct {
+	Date   time.Time       `json:"date"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type WhatIfProjection struct {
+	CashFlows             []CashFlow           `json:"cash_flows"`
+	Networth              []NetworthProjection `json:"networth"`
+	AvailableForBudgeting decimal.Decimal      `json:"available_for_budgeting"`
+}
+
+// GetWhatIf projects the cash flow, the budget and the networth over
+// the coming months using the forecast (periodic) transactions of the
+// journal, with and without the requested changes. The projection
+// starts from next month and doesn't assume any return on the
+// investments.
+func GetWhatIf(db *gorm.DB, request WhatIfRequest) (gin.H, error) {
+	months := request.Months
+	if months == 0 {
+		months = WHAT_IF_DEFAULT_MONTHS
+	}
+	if months < 0 || months > WHAT_IF_MAX_MONTHS {
+		return nil, fmt.Errorf("months should be between 1 and %d", WHAT_IF_MAX_MONTHS)
+	}
+
+	for _, change := range request.Changes {
+		if (change.Percentage == nil) == (change.Amount == nil) {
+			return nil, errors.New("Either percentage or amount should be specified for " + change.Account)
+		}
+	}
+
+	start := utils.BeginningOfMonth(utils.Now()).AddDate(0, 1, 0)
+	end := utils.EndOfMonth(start.AddDate(0, months-1, 0))
+	forecasts := lo.Filter(
+		query.Init(db).Forecast().Where("date >= ? and date <= ?", start, end).All(),
+		func(p posting.Posting, _ int) bool { return p.Forecast },
+	)
+
+	scenario := append(lo.Flatten(lo.Map(forecasts, func(p posting.Posting, _ int) []posting.Posting {
+		return scaledPostings(p, request.Changes)
+	})), recurringPostings(start, months, request.Changes)...)
+	sort.SliceStable(scenario, func(i, j int) bool { return scenario[i].Date.Before(scenario[j].Date) })
+
+	return gin.H{
+		"start":    start,
+		"end":      end,
+		"baseline": projectWhatIf(db, start, months, forecasts),
+		"scenario": projectWhatIf(db, start, months, scenario),
+	}, nil
+}
+
+func projectWhatIf(db *gorm.DB, start time.Time, months int, forecasts []posting.Posting) WhatIfProjection {
+	today := query.Init(db).ExcludeFuture()
+	checking := accounting.CostSum(today.Clone().AccountPrefix(config.CheckingAccounts()...).All())
+	current := today.Clone().Like("Assets:%", config.CapitalGainsAccount()+":%", "Liabilities:%").All()
+	networth := computeNetworth(db, service.PopulateMarketPrice(db, current)).BalanceAmount
+
+	cashFlows := lo.Filter(computeCashFlowOf(forecasts, checking), func(c CashFlow, _ int) bool {
+		return !c.Date.Before(start)
+	})
+
+	byMonth := utils.GroupByMonth(lo.Filter(forecasts, func(p posting.Posting, _ int) bool {
+		return utils.IsSameOrParent(p.Account, "Assets") || utils.IsSameOrParent(p.Account, "Liabilities")
+	}))
+	projection := []NetworthProjection{}
+	for i := 0; i < months; i++ {
+		month := start.AddDate(0, i, 0)
+		networth = networth.Add(accounting.CostSum(byMonth[month.Format("2006-01")]))
+		projection = append(projection, NetworthProjection{Date: utils.EndOfMonth(month), Amount: networth})
+	}
+
+	expenses := lo.Filter(forecasts, func(p posting.Posting, _ int) bool { return utils.IsSameOrParent(p.Account, "Expenses") })
+	budget := computeBudet(db, expenses, []posting.Posting{})
+
+	return WhatIfProjection{
+		CashFlows:             cashFlows,
+		Networth:              projection,
+		AvailableForBudgeting: budget["availableForBudgeting"].(decimal.Decimal),
+	}
+}
+
+// scaledPostings returns the posting along with the adjustment for the
+// percentage changes that apply to it. The adjustment is funded from
+// the checking account.
+func scaledPostings(p posting.Posting, changes []WhatIfChange) []posting.Posting {
+	postings := []posting.Posting{p}
+	for _, change := range changes {
+		if change.Percentage == nil || !utils.IsSameOrParent(p.Account, change.Account) {
+			continue
+		}
+
+		delta := p.Amount.Mul(decimal.NewFromFloat(*change.Percentage)).Div(decimal.NewFromInt(100))
+		postings = append(postings, whatIfPosting(p.Date, p.Account, delta)...)
+	}
+	return postings
+}
+
+func recurringPostings(start time.Time, months int, changes []WhatIfChange) []posting.Posting {
+	postings := []posting.Posting{}
+	for _, change := range changes {
+		if change.Amount == nil {
+			continue
+		}
+
+		for i := 0; i < months; i++ {
+			postings = append(postings, whatIfPosting(start.AddDate(0, i, 0), change.Account, decimal.NewFromFloat(*change.Amount))...)
+		}
+	}
+	return postings
+}
+
+func whatIfPosting(date time.Time, account string, amount decimal.Decimal) []posting.Posting {
+	return []posting.Posting{
+		{Date: date, Account: account, Commodity: config.DefaultCurrency(), Amount: amount, Quantity: amount, Forecast: true},
+		{Date: date, Account: config.CheckingAccounts()[0], Commodity: config.DefaultCurrency(), Amount: amount.Neg(), Quantity: amount.Neg(), Forecast: true},
+	}
+}
@@ -0,0 +1,18 @@
+package server
+
+import (
+	"github.com/ananthakumaran/paisa/internal/utils"
+)
+
+// YearRequest lets the yearly reports be grouped either by the
+// financial year (the default) or by the calendar year.
+type YearRequest struct {
+	Year utils.YearKind `form:"year" binding:"omitempty,oneof=fiscal calendar"`
+}
+
+func (request YearRequest) Kind() utils.YearKind {
+	if request.Year == "" {
+		return utils.FiscalYear
+	}
+	return request.Year
+}
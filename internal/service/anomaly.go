@@ -0,0 +1,88 @@
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/query"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// minimum number of past postings of the account or payee required
+// to decide what is unusual
+const ANOMALY_MIN_HISTORY = 6
+
+// postings above Q3 + ANOMALY_IQR_FACTOR * IQR are flagged
+var ANOMALY_IQR_FACTOR = decimal.NewFromInt(3)
+
+type Anomaly struct {
+	Posting posting.Posting `json:"posting"`
+	// account or payee
+	GroupBy   string          `json:"group_by"`
+	Median    decimal.Decimal `json:"median"`
+	Threshold decimal.Decimal `json:"threshold"`
+}
+
+type quartiles struct {
+	median    decimal.Decimal
+	threshold decimal.Decimal
+}
+
+// DetectAnomalies flags the expenses made on or after since that are
+// unusually high compared to the past expenses of the same account or
+// payee. Interquartile range is used instead of the standard deviation
+// so that a few large expenses in the history don't hide the others.
+func DetectAnomalies(db *gorm.DB, since time.Time) []Anomaly {
+	expenses := query.Init(db).Like("Expenses:%").ExcludeFuture().All()
+	history := lo.Filter(expenses, func(p posting.Posting, _ int) bool { return p.Date.Before(since) })
+
+	byAccount := lo.MapValues(lo.GroupBy(history, func(p posting.Posting) string { return p.Account }), computeQuartiles)
+	byPayee := lo.MapValues(lo.GroupBy(history, func(p posting.Posting) string { return p.Payee }), computeQuartiles)
+
+	anomalies := []Anomaly{}
+	for _, p := range expenses {
+		if p.Date.Before(since) || !p.Amount.IsPositive() {
+			continue
+		}
+
+		if q := byAccount[p.Account]; q != nil && p.Amount.GreaterThan(q.threshold) {
+			anomalies = append(anomalies, Anomaly{Posting: p, GroupBy: "account", Median: q.median, Threshold: q.threshold})
+		} else if q := byPayee[p.Payee]; q != nil && p.Payee != "" && p.Amount.GreaterThan(q.threshold) {
+			anomalies = append(anomalies, Anomaly{Posting: p, GroupBy: "payee", Median: q.median, Threshold: q.threshold})
+		}
+	}
+
+	return anomalies
+}
+
+func computeQuartiles(postings []posting.Posting, _ string) *quartiles {
+	amounts := lo.FilterMap(postings, func(p posting.Posting, _ int) (decimal.Decimal, bool) {
+		return p.Amount, p.Amount.IsPositive()
+	})
+	if len(amounts) < ANOMALY_MIN_HISTORY {
+		return nil
+	}
+
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i].LessThan(amounts[j]) })
+	q1 := percentile(amounts, 25)
+	q3 := percentile(amounts, 75)
+	return &quartiles{
+		median:    percentile(amounts, 50),
+		threshold: q3.Add(q3.Sub(q1).Mul(ANOMALY_IQR_FACTOR)),
+	}
+}
+
+// percentile interpolates linearly between the closest ranks of the
+// sorted amounts
+func percentile(sorted []decimal.Decimal, p int64) decimal.Decimal {
+	rank := decimal.NewFromInt(int64(len(sorted) - 1)).Mul(decimal.NewFromInt(p)).Div(decimal.NewFromInt(100))
+	lower := int(rank.IntPart())
+	if lower+1 >= len(sorted) {
+		return sorted[lower]
+	}
+	fraction := rank.Sub(decimal.NewFromInt(int64(lower)))
+	return sorted[lower].Add(sorted[lower+1].Sub(sorted[lower]).Mul(fraction))
+}
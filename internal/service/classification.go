@@ -0,0 +1,70 @@
+package service
+
+import (
+	"sync"
+
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/utils"
+	"gorm.io/gorm"
+)
+
+type classification uint8
+
+const (
+	interest classification = iota
+	interestRepayment
+	stockSplit
+	sellWithCapitalGains
+	contraPostingRefund
+)
+
+// postings are at times split or adjusted in memory while keeping
+// the id, so the fields the classification depends on are part of
+// the key as well
+type classificationKey struct {
+	kind      classification
+	postingID uint
+	account   string
+	amount    string
+}
+
+// The same postings get classified over and over by the reports,
+// the results are remembered per posting till the next sync.
+type classificationCache struct {
+	sync.RWMutex
+	results map[classificationKey]bool
+}
+
+var classificationCaches utils.NamespacedCache[classificationCache]
+
+func ClearClassificationCache() {
+	classificationCaches.Clear()
+}
+
+func classify(db *gorm.DB, kind classification, p posting.Posting, compute func() bool) bool {
+	// postings that are not persisted (ex: synthetic balance postings)
+	// don't have a stable identity
+	if p.ID == 0 {
+		return compute()
+	}
+
+	ccache := classificationCaches.Get(db)
+	key := classificationKey{kind: kind, postingID: p.ID, account: p.Account, amount: p.Amount.String()}
+
+	ccache.RLock()
+	result, found := ccache.results[key]
+	ccache.RUnlock()
+	if found {
+		return result
+	}
+
+	result = compute()
+
+	ccache.Lock()
+	if ccache.results == nil {
+		ccache.results = make(map[classificationKey]bool)
+	}
+	ccache.results[key] = result
+	ccache.Unlock()
+	return result
+}
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/ananthakumaran/paisa/internal/config"
 	"github.com/ananthakumaran/paisa/internal/model/posting"
 	"github.com/ananthakumaran/paisa/internal/model/transaction"
 	"github.com/ananthakumaran/paisa/internal/query"
@@ -17,10 +18,10 @@ type interestCache struct {
 	postings map[int64][]posting.Posting
 }
 
-var icache interestCache
+var icaches utils.NamespacedCache[interestCache]
 
-func loadInterestCache(db *gorm.DB) {
-	postings := query.Init(db).Like("Income:Interest:%").All()
+func loadInterestCache(db *gorm.DB, icache *interestCache) {
+	postings := query.Init(db).AccountPrefix(config.InterestAccounts()...).All()
 	icache.postings = lo.GroupBy(postings, func(p posting.Posting) int64 { return p.Date.Unix() })
 }
 
@@ -29,30 +30,28 @@ type interestRepaymentCache struct {
 	postings map[int64][]posting.Posting
 }
 
-var irepaymentCache interestRepaymentCache
+var irepaymentCaches utils.NamespacedCache[interestRepaymentCache]
 
-func loadInterestRepaymentCache(db *gorm.DB) {
+func loadInterestRepaymentCache(db *gorm.DB, irepaymentCache *interestRepaymentCache) {
 	postings := query.Init(db).Like("Expenses:Interest:%").All()
 	irepaymentCache.postings = lo.GroupBy(postings, func(p posting.Posting) int64 { return p.Date.Unix() })
 }
 
 func ClearInterestCache() {
-	icache = interestCache{}
-	irepaymentCache = interestRepaymentCache{}
+	icaches.Clear()
+	irepaymentCaches.Clear()
 }
 
 func CapitalGainsSourceAccount(account string) string {
-	parts := strings.Split(account, ":")
-	return "Assets:" + strings.Join(parts[2:], ":")
+	return "Assets:" + strings.TrimPrefix(account, config.CapitalGainsAccount()+":")
 }
 
 func CapitalGainsAccount(account string) string {
-	parts := strings.Split(account, ":")
-	return "Income:CapitalGains:" + strings.Join(parts[1:], ":")
+	return config.CapitalGainsAccount() + ":" + strings.TrimPrefix(account, "Assets:")
 }
 
 func IsCapitalGains(p posting.Posting) bool {
-	if utils.IsParent(p.Account, "Income:CapitalGains") {
+	if utils.IsParent(p.Account, config.CapitalGainsAccount()) {
 		return true
 	}
 
@@ -68,6 +67,10 @@ func IsRefund(p posting.Posting) bool {
 }
 
 func IsStockSplit(db *gorm.DB, p posting.Posting) bool {
+	return classify(db, stockSplit, p, func() bool { return isStockSplit(db, p) })
+}
+
+func isStockSplit(db *gorm.DB, p posting.Posting) bool {
 	if utils.IsCurrency(p.Commodity) {
 		return false
 	}
@@ -86,6 +89,10 @@ func IsStockSplit(db *gorm.DB, p posting.Posting) bool {
 }
 
 func IsSellWithCapitalGains(db *gorm.DB, p posting.Posting) bool {
+	return classify(db, sellWithCapitalGains, p, func() bool { return isSellWithCapitalGains(db, p) })
+}
+
+func isSellWithCapitalGains(db *gorm.DB, p posting.Posting) bool {
 	if utils.IsCurrency(p.Commodity) {
 		return false
 	}
@@ -104,6 +111,10 @@ func IsSellWithCapitalGains(db *gorm.DB, p posting.Posting) bool {
 }
 
 func IsContraPostingRefund(db *gorm.DB, p posting.Posting) bool {
+	return classify(db, contraPostingRefund, p, func() bool { return isContraPostingRefund(db, p) })
+}
+
+func isContraPostingRefund(db *gorm.DB, p posting.Posting) bool {
 	t, found := transaction.GetById(db, p.TransactionID)
 	if !found {
 		return false
@@ -118,7 +129,12 @@ func IsContraPostingRefund(db *gorm.DB, p posting.Posting) bool {
 }
 
 func IsInterestRepayment(db *gorm.DB, p posting.Posting) bool {
-	irepaymentCache.Do(func() { loadInterestRepaymentCache(db) })
+	return classify(db, interestRepayment, p, func() bool { return isInterestRepayment(db, p) })
+}
+
+func isInterestRepayment(db *gorm.DB, p posting.Posting) bool {
+	irepaymentCache := irepaymentCaches.Get(db)
+	irepaymentCache.Do(func() { loadInterestRepaymentCache(db, irepaymentCache) })
 
 	if !utils.IsCurrency(p.Commodity) {
 		return false
@@ -141,7 +157,12 @@ func IsInterestRepayment(db *gorm.DB, p posting.Posting) bool {
 }
 
 func IsInterest(db *gorm.DB, p posting.Posting) bool {
-	icache.Do(func() { loadInterestCache(db) })
+	return classify(db, interest, p, func() bool { return isInterest(db, p) })
+}
+
+func isInterest(db *gorm.DB, p posting.Posting) bool {
+	icache := icaches.Get(db)
+	icache.Do(func() { loadInterestCache(db, icache) })
 
 	if !utils.IsCurrency(p.Commodity) {
 		return false
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/ananthakumaran/paisa/internal/model/commodity"
+	"github.com/ananthakumaran/paisa/internal/model/posting"
+	"github.com/ananthakumaran/paisa/internal/scraper/stock"
+)
+
+const LIVE_QUOTE_PROVIDER = "com-yahoo"
+
+// PopulateLiveMarketPrice overlays the current quote on the market
+// amount of the postings of the commodities priced by Yahoo. The
+// quotes are fetched on every call and never persisted, the market
+// amount populated from the stored prices is kept if the quotes can't
+// be fetched.
+func PopulateLiveMarketPrice(ps []posting.Posting) []posting.Posting {
+	tickers := make(map[string]string)
+	for _, c := range commodity.All() {
+		if c.Price.Provider == LIVE_QUOTE_PROVIDER && c.Price.Code != "" {
+			tickers[c.Name] = c.Price.Code
+		}
+	}
+
+	held := lo.Uniq(lo.FilterMap(ps, func(p posting.Posting, _ int) (string, bool) {
+		ticker, ok := tickers[p.Commodity]
+		return ticker, ok
+	}))
+	if len(held) == 0 {
+		return ps
+	}
+
+	quotes, err := stock.GetLiveQuotes(held)
+	if err != nil {
+		log.Warnf("Failed to fetch live quotes: %v", err)
+		return ps
+	}
+
+	return lo.Map(ps, func(p posting.Posting, _ int) posting.Posting {
+		if quote, ok := quotes[tickers[p.Commodity]]; ok {
+			p.MarketAmount = p.Quantity.Mul(quote)
+		}
+		return p
+	})
+}
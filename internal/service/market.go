@@ -20,11 +20,21 @@ type priceCache struct {
 	sync.Once
 	pricesTree        map[string]*btree.BTree
 	postingPricesTree map[string]*btree.BTree
+	unitPrices        *utils.LRU[unitPriceKey, price.Price]
 }
 
-var pcache priceCache
+// timelines look up the same commodity for every day, so the resolved
+// prices are memoized on top of the btree lookups
+type unitPriceKey struct {
+	commodity string
+	date      int64
+}
+
+const UNIT_PRICE_CACHE_SIZE = 100000
+
+var pcaches utils.NamespacedCache[priceCache]
 
-func loadPriceCache(db *gorm.DB) {
+func loadPriceCache(db *gorm.DB, pcache *priceCache) {
 	var prices []price.Price
 	result := db.Where("commodity_type != ?", config.Unknown).Find(&prices)
 	if result.Error != nil {
@@ -32,13 +42,14 @@ func loadPriceCache(db *gorm.DB) {
 	}
 	pcache.pricesTree = make(map[string]*btree.BTree)
 	pcache.postingPricesTree = make(map[string]*btree.BTree)
+	pcache.unitPrices = utils.NewLRU[unitPriceKey, price.Price](UNIT_PRICE_CACHE_SIZE)
 
 	for _, price := range prices {
 		if pcache.pricesTree[price.CommodityName] == nil {
 			pcache.pricesTree[price.CommodityName] = btree.New(2)
 		}
 
-		pcache.pricesTree[price.CommodityName].ReplaceOrInsert(price)
+		pcache.pricesTree[price.CommodityName].ReplaceOrInsert(price.Adjusted())
 	}
 
 	var postings []posting.Posting
@@ -47,15 +58,17 @@ func loadPriceCache(db *gorm.DB) {
 		log.Fatal(result.Error)
 	}
 
+	var postingPrices []price.Price
+	result = db.Where("commodity_type = ?", config.Unknown).Find(&postingPrices)
+	if result.Error != nil {
+		log.Fatal(result.Error)
+	}
+	postingPricesByCommodity := lo.GroupBy(postingPrices, func(p price.Price) string { return p.CommodityName })
+
 	for commodityName, postings := range lo.GroupBy(postings, func(p posting.Posting) string { return p.Commodity }) {
 		if !utils.IsCurrency(postings[0].Commodity) {
-			result := db.Where("commodity_type = ? and commodity_name = ?", config.Unknown, commodityName).Find(&prices)
-			if result.Error != nil {
-				log.Fatal(result.Error)
-			}
-
 			postingPricesTree := btree.New(2)
-			for _, price := range prices {
+			for _, price := range postingPricesByCommodity[commodityName] {
 				postingPricesTree.ReplaceOrInsert(price)
 			}
 			pcache.postingPricesTree[commodityName] = postingPricesTree
@@ -67,13 +80,36 @@ func loadPriceCache(db *gorm.DB) {
 	}
 }
 
+func getPriceCache(db *gorm.DB) *priceCache {
+	pcache := pcaches.Get(db)
+	pcache.Do(func() { loadPriceCache(db, pcache) })
+	return pcache
+}
+
 func ClearPriceCache() {
-	pcache = priceCache{}
+	pcaches.Clear()
 }
 
 func GetUnitPrice(db *gorm.DB, commodity string, date time.Time) price.Price {
-	pcache.Do(func() { loadPriceCache(db) })
+	pcache := getPriceCache(db)
+
+	key := unitPriceKey{commodity: commodity, date: date.UnixNano()}
+	if pc, ok := pcache.unitPrices.Get(key); ok {
+		return pc
+	}
+
+	pc := lookupUnitPrice(pcache, commodity, date)
+	pcache.unitPrices.Put(key, pc)
+	return pc
+}
+
+// HasPrice tells whether the commodity has any price to look up,
+// GetUnitPrice fails otherwise.
+func HasPrice(db *gorm.DB, commodity string) bool {
+	return getPriceCache(db).pricesTree[commodity] != nil
+}
 
+func lookupUnitPrice(pcache *priceCache, commodity string, date time.Time) price.Price {
 	pt := pcache.pricesTree[commodity]
 	if pt == nil {
 		log.Fatal("Price not found ", commodity)
@@ -93,7 +129,7 @@ func GetUnitPrice(db *gorm.DB, commodity string, date time.Time) price.Price {
 }
 
 func GetAllPrices(db *gorm.DB, commodity string) []price.Price {
-	pcache.Do(func() { loadPriceCache(db) })
+	pcache := getPriceCache(db)
 
 	pt := pcache.postingPricesTree[commodity]
 	if pt == nil {
@@ -0,0 +1,181 @@
+package taxation
+
+import (
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/samber/lo"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	OldRegime = "old"
+	NewRegime = "new"
+)
+
+var SECTION_80C_LIMIT = decimal.NewFromInt(150000)
+var ADVANCE_TAX_THRESHOLD = decimal.NewFromInt(10000)
+
+type slab struct {
+	upto int64
+	rate int64
+}
+
+type regimeRules struct {
+	from              int
+	slabs             []slab
+	standardDeduction int64
+	rebateLimit       int64
+	maxSurchargeRate  int64
+}
+
+// The rules are picked by the year the financial year starts in, the
+// last slab of each set is unbounded.
+var oldRegimeRules = []regimeRules{
+	{from: 0, slabs: []slab{{250000, 0}, {500000, 5}, {1000000, 20}, {0, 30}}, standardDeduction: 50000, rebateLimit: 500000, maxSurchargeRate: 37},
+}
+
+var newRegimeRules = []regimeRules{
+	{from: 0, slabs: []slab{{250000, 0}, {500000, 5}, {750000, 10}, {1000000, 15}, {1250000, 20}, {1500000, 25}, {0, 30}}, standardDeduction: 0, rebateLimit: 500000, maxSurchargeRate: 37},
+	{from: 2023, slabs: []slab{{300000, 0}, {600000, 5}, {900000, 10}, {1200000, 15}, {1500000, 20}, {0, 30}}, standardDeduction: 50000, rebateLimit: 700000, maxSurchargeRate: 25},
+	{from: 2024, slabs: []slab{{300000, 0}, {700000, 5}, {1000000, 10}, {1200000, 15}, {1500000, 20}, {0, 30}}, standardDeduction: 75000, rebateLimit: 700000, maxSurchargeRate: 25},
+	{from: 2025, slabs: []slab{{400000, 0}, {800000, 5}, {1200000, 10}, {1600000, 15}, {2000000, 20}, {2400000, 25}, {0, 30}}, standardDeduction: 75000, rebateLimit: 1200000, maxSurchargeRate: 25},
+}
+
+var surcharges = []slab{{5000000, 0}, {10000000, 10}, {20000000, 15}, {50000000, 25}, {0, 37}}
+
+type IncomeTaxDeclaration struct {
+	GrossIncome     decimal.Decimal `json:"gross_income"`
+	Section80C      decimal.Decimal `json:"section_80c"`
+	HRAExemption    decimal.Decimal `json:"hra_exemption"`
+	OtherDeductions decimal.Decimal `json:"other_deductions"`
+}
+
+type RegimeTax struct {
+	Regime            string          `json:"regime"`
+	StandardDeduction decimal.Decimal `json:"standard_deduction"`
+	Deductions        decimal.Decimal `json:"deductions"`
+	TaxableIncome     decimal.Decimal `json:"taxable_income"`
+	SlabTax           decimal.Decimal `json:"slab_tax"`
+	Rebate            decimal.Decimal `json:"rebate"`
+	Surcharge         decimal.Decimal `json:"surcharge"`
+	Cess              decimal.Decimal `json:"cess"`
+	Total             decimal.Decimal `json:"total"`
+}
+
+type AdvanceTaxInstallment struct {
+	DueDate    time.Time       `json:"due_date"`
+	Percentage int64           `json:"percentage"`
+	Cumulative decimal.Decimal `json:"cumulative"`
+	Amount     decimal.Decimal `json:"amount"`
+}
+
+// EstimateIncomeTax computes the tax of the financial year starting in
+// the given year under the old and the new regime. The deductions
+// other than the standard deduction are only allowed in the old
+// regime. Marginal relief on the surcharge is not considered.
+func EstimateIncomeTax(year int, declaration IncomeTaxDeclaration) (RegimeTax, RegimeTax) {
+	section80C := decimal.Min(declaration.Section80C, SECTION_80C_LIMIT)
+	deductions := section80C.Add(declaration.HRAExemption).Add(declaration.OtherDeductions)
+	oldTax := computeRegimeTax(OldRegime, rulesFor(oldRegimeRules, year), declaration.GrossIncome, deductions)
+	newTax := computeRegimeTax(NewRegime, rulesFor(newRegimeRules, year), declaration.GrossIncome, decimal.Zero)
+	return oldTax, newTax
+}
+
+func rulesFor(rules []regimeRules, year int) regimeRules {
+	applicable := lo.Filter(rules, func(r regimeRules, _ int) bool { return r.from <= year })
+	return applicable[len(applicable)-1]
+}
+
+func computeRegimeTax(regime string, rules regimeRules, grossIncome decimal.Decimal, deductions decimal.Decimal) RegimeTax {
+	standardDeduction := decimal.Min(decimal.NewFromInt(rules.standardDeduction), decimal.Max(grossIncome, decimal.Zero))
+	deductions = decimal.Min(deductions, decimal.Max(grossIncome.Sub(standardDeduction), decimal.Zero))
+	taxable := decimal.Max(grossIncome.Sub(standardDeduction).Sub(deductions), decimal.Zero)
+
+	slabTax := decimal.Zero
+	lower := decimal.Zero
+	for _, s := range rules.slabs {
+		upper := decimal.NewFromInt(s.upto)
+		if s.upto == 0 || taxable.LessThan(upper) {
+			upper = taxable
+		}
+		if upper.GreaterThan(lower) {
+			slabTax = slabTax.Add(upper.Sub(lower).Mul(decimal.NewFromInt(s.rate)).Div(decimal.NewFromInt(100)))
+		}
+		if s.upto == 0 || taxable.LessThanOrEqual(decimal.NewFromInt(s.upto)) {
+			break
+		}
+		lower = decimal.NewFromInt(s.upto)
+	}
+
+	rebate := decimal.Zero
+	if taxable.LessThanOrEqual(decimal.NewFromInt(rules.rebateLimit)) {
+		rebate = slabTax
+	}
+	tax := slabTax.Sub(rebate)
+
+	surchargeRate := int64(0)
+	for _, s := range surcharges {
+		surchargeRate = s.rate
+		if s.upto == 0 || taxable.LessThanOrEqual(decimal.NewFromInt(s.upto)) {
+			break
+		}
+	}
+	surcharge := tax.Mul(decimal.NewFromInt(min(surchargeRate, rules.maxSurchargeRate))).Div(decimal.NewFromInt(100))
+	cess := tax.Add(surcharge).Mul(decimal.NewFromInt(4)).Div(decimal.NewFromInt(100))
+
+	return RegimeTax{
+		Regime:            regime,
+		StandardDeduction: standardDeduction,
+		Deductions:        deductions,
+		TaxableIncome:     taxable,
+		SlabTax:           slabTax.Round(0),
+		Rebate:            rebate.Round(0),
+		Surcharge:         surcharge.Round(0),
+		Cess:              cess.Round(0),
+		Total:             tax.Add(surcharge).Add(cess).Round(0),
+	}
+}
+
+// PreferredRegime returns the configured regime, or the one with the
+// lower tax if none is configured.
+func PreferredRegime(oldTax, newTax RegimeTax) RegimeTax {
+	switch config.GetConfig().IncomeTax.Regime {
+	case OldRegime:
+		return oldTax
+	case NewRegime:
+		return newTax
+	}
+
+	if oldTax.Total.LessThan(newTax.Total) {
+		return oldTax
+	}
+	return newTax
+}
+
+// AdvanceTaxSchedule splits the tax left after TDS into the
+// cumulative installments due on 15th June, September, December and
+// March of the financial year starting in the given year. No advance
+// tax is due if it's less than 10,000.
+func AdvanceTaxSchedule(year int, balance decimal.Decimal) []AdvanceTaxInstallment {
+	if balance.LessThan(ADVANCE_TAX_THRESHOLD) {
+		return []AdvanceTaxInstallment{}
+	}
+
+	installments := []AdvanceTaxInstallment{}
+	paid := decimal.Zero
+	for _, due := range []struct {
+		date       time.Time
+		percentage int64
+	}{
+		{time.Date(year, time.June, 15, 0, 0, 0, 0, config.TimeZone()), 15},
+		{time.Date(year, time.September, 15, 0, 0, 0, 0, config.TimeZone()), 45},
+		{time.Date(year, time.December, 15, 0, 0, 0, 0, config.TimeZone()), 75},
+		{time.Date(year+1, time.March, 15, 0, 0, 0, 0, config.TimeZone()), 100},
+	} {
+		cumulative := balance.Mul(decimal.NewFromInt(due.percentage)).Div(decimal.NewFromInt(100)).Round(0)
+		installments = append(installments, AdvanceTaxInstallment{DueDate: due.date, Percentage: due.percentage, Cumulative: cumulative, Amount: cumulative.Sub(paid)})
+		paid = cumulative
+	}
+	return installments
+}
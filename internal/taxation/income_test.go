@@ -0,0 +1,43 @@
+package taxation
+
+import (
+	"testing"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateIncomeTax(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	oldTax, newTax := EstimateIncomeTax(2024, IncomeTaxDeclaration{
+		GrossIncome:  decimal.NewFromInt(1500000),
+		Section80C:   decimal.NewFromInt(200000),
+		HRAExemption: decimal.NewFromInt(100000),
+	})
+
+	assert.Equal(t, "1200000", oldTax.TaxableIncome.String())
+	assert.Equal(t, "172500", oldTax.SlabTax.String())
+	assert.Equal(t, "179400", oldTax.Total.String())
+
+	assert.Equal(t, "1425000", newTax.TaxableIncome.String())
+	assert.Equal(t, "125000", newTax.SlabTax.String())
+	assert.Equal(t, "130000", newTax.Total.String())
+	assert.Equal(t, NewRegime, PreferredRegime(oldTax, newTax).Regime)
+
+	_, newTax = EstimateIncomeTax(2025, IncomeTaxDeclaration{GrossIncome: decimal.NewFromInt(1275000)})
+	assert.Equal(t, "60000", newTax.Rebate.String())
+	assert.True(t, newTax.Total.IsZero())
+}
+
+func TestAdvanceTaxSchedule(t *testing.T) {
+	config.LoadConfig([]byte("journal_path: main.ledger\ndb_path: paisa.db\n"), "")
+
+	assert.Empty(t, AdvanceTaxSchedule(2024, decimal.NewFromInt(9999)))
+
+	installments := AdvanceTaxSchedule(2024, decimal.NewFromInt(100000))
+	assert.Len(t, installments, 4)
+	assert.Equal(t, "2025-03-15", installments[3].DueDate.Format("2006-01-02"))
+	assert.Equal(t, []string{"15000", "30000", "30000", "25000"}, []string{installments[0].Amount.String(), installments[1].Amount.String(), installments[2].Amount.String(), installments[3].Amount.String()})
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const NAMESPACE_KEY = "paisa:namespace"
+
+// WithNamespace tags the db session with a namespace. In memory caches
+// built from the session are kept separate from the ones built from
+// the main database.
+func WithNamespace(db *gorm.DB, namespace string) *gorm.DB {
+	return db.Set(NAMESPACE_KEY, namespace).Session(&gorm.Session{})
+}
+
+func Namespace(db *gorm.DB) string {
+	if namespace, ok := db.Get(NAMESPACE_KEY); ok {
+		return namespace.(string)
+	}
+	return ""
+}
+
+type NamespacedCache[T any] struct {
+	mu      sync.Mutex
+	entries map[string]*T
+}
+
+func (c *NamespacedCache[T]) Get(db *gorm.DB) *T {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*T)
+	}
+
+	namespace := Namespace(db)
+	entry, ok := c.entries[namespace]
+	if !ok {
+		entry = new(T)
+		c.entries[namespace] = entry
+	}
+	return entry
+}
+
+func (c *NamespacedCache[T]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -40,6 +41,23 @@ func BTreeToSlice[I btree.Item](tree *btree.BTree) []I {
 	return items
 }
 
+// YearKind decides where the yearly groupings of the reports start,
+// either at the configured financial year starting month or in
+// January.
+type YearKind string
+
+const (
+	FiscalYear   YearKind = "fiscal"
+	CalendarYear YearKind = "calendar"
+)
+
+func (kind YearKind) StartingMonth() time.Month {
+	if kind == CalendarYear {
+		return time.January
+	}
+	return config.GetConfig().FinancialYearStartingMonth
+}
+
 func FY(date time.Time) string {
 	if config.GetConfig().FinancialYearStartingMonth == time.January {
 		return fmt.Sprintf("%d", date.Year())
@@ -53,11 +71,16 @@ func FY(date time.Time) string {
 }
 
 func FYHuman(date time.Time) string {
-	if config.GetConfig().FinancialYearStartingMonth == time.January {
+	return YearHuman(FiscalYear, date)
+}
+
+func YearHuman(kind YearKind, date time.Time) string {
+	startingMonth := kind.StartingMonth()
+	if startingMonth == time.January {
 		return fmt.Sprintf("%d", date.Year())
 	}
 
-	if date.Month() < config.GetConfig().FinancialYearStartingMonth {
+	if date.Month() < startingMonth {
 		return fmt.Sprintf("%d - %d", date.Year()-1, date.Year()%100)
 	} else {
 		return fmt.Sprintf("%d - %d", date.Year(), (date.Year()+1)%100)
@@ -73,22 +96,37 @@ func YearHumanCutOffAt(date time.Time, cutoff time.Time) string {
 }
 
 func ParseFY(fy string) (time.Time, time.Time) {
-	start, _ := time.ParseInLocation("2006", strings.Split(fy, " ")[0], config.TimeZone())
-	start = start.AddDate(0, int(config.GetConfig().FinancialYearStartingMonth-time.January), 0)
-	return BeginningOfFinancialYear(start), EndOfFinancialYear(start)
+	return ParseYear(FiscalYear, fy)
+}
+
+// ParseYear returns the first and the last day of the year labelled
+// by YearHuman.
+func ParseYear(kind YearKind, year string) (time.Time, time.Time) {
+	start, _ := time.ParseInLocation("2006", strings.Split(year, " ")[0], config.TimeZone())
+	start = start.AddDate(0, int(kind.StartingMonth()-time.January), 0)
+	return BeginningOfYear(kind, start), EndOfYear(kind, start)
 }
 
 func BeginningOfFinancialYear(date time.Time) time.Time {
+	return BeginningOfYear(FiscalYear, date)
+}
+
+func EndOfFinancialYear(date time.Time) time.Time {
+	return EndOfYear(FiscalYear, date)
+}
+
+func BeginningOfYear(kind YearKind, date time.Time) time.Time {
+	startingMonth := kind.StartingMonth()
 	beginningOfMonth := BeginningOfMonth(date)
-	if beginningOfMonth.Month() < config.GetConfig().FinancialYearStartingMonth {
-		return beginningOfMonth.AddDate(-1, int(config.GetConfig().FinancialYearStartingMonth-beginningOfMonth.Month()), 0)
+	if beginningOfMonth.Month() < startingMonth {
+		return beginningOfMonth.AddDate(-1, int(startingMonth-beginningOfMonth.Month()), 0)
 	} else {
-		return beginningOfMonth.AddDate(0, -int(beginningOfMonth.Month()-config.GetConfig().FinancialYearStartingMonth), 0)
+		return beginningOfMonth.AddDate(0, -int(beginningOfMonth.Month()-startingMonth), 0)
 	}
 }
 
-func EndOfFinancialYear(date time.Time) time.Time {
-	return EndOfMonth(BeginningOfFinancialYear(date).AddDate(0, 11, 0))
+func EndOfYear(kind YearKind, date time.Time) time.Time {
+	return EndOfMonth(BeginningOfYear(kind, date).AddDate(0, 11, 0))
 }
 
 func BeginningOfMonth(date time.Time) time.Time {
@@ -107,6 +145,10 @@ func IsSameDate(a time.Time, b time.Time) bool {
 	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
 }
 
+func BeginningOfDay(date time.Time) time.Time {
+	return toDate(date)
+}
+
 func EndOfDay(date time.Time) time.Time {
 	return toDate(date).AddDate(0, 0, 1).Add(-time.Nanosecond)
 }
@@ -161,8 +203,20 @@ func IsCurrency(currency string) bool {
 	return currency == config.DefaultCurrency()
 }
 
+func IsSameOrParentOfAny(account string, comparisons []string) bool {
+	return lo.SomeBy(comparisons, func(comparison string) bool { return IsSameOrParent(account, comparison) })
+}
+
 func IsCheckingAccount(account string) bool {
-	return IsSameOrParent(account, "Assets:Checking")
+	return IsSameOrParentOfAny(account, config.CheckingAccounts())
+}
+
+func IsTaxAccount(account string) bool {
+	return IsSameOrParentOfAny(account, config.TaxAccounts())
+}
+
+func IsInterestAccount(account string) bool {
+	return IsSameOrParentOfAny(account, config.InterestAccounts())
 }
 
 func IsExpenseInterestAccount(account string) bool {
@@ -177,6 +231,14 @@ func MaxTime(a time.Time, b time.Time) time.Time {
 	}
 }
 
+func MinTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	} else {
+		return b
+	}
+}
+
 type GroupableByDate interface {
 	GroupDate() time.Time
 }
@@ -212,9 +274,13 @@ func GroupByMonth[G GroupableByDate](groupables []G) map[string][]G {
 }
 
 func GroupByFY[G GroupableByDate](groupables []G) map[string][]G {
+	return GroupByYear(groupables, FiscalYear)
+}
+
+func GroupByYear[G GroupableByDate](groupables []G, kind YearKind) map[string][]G {
 	grouped := make(map[string][]G)
 	for _, g := range groupables {
-		key := FYHuman(g.GroupDate())
+		key := YearHuman(kind, g.GroupDate())
 		ps, ok := grouped[key]
 		if ok {
 			grouped[key] = append(ps, g)
@@ -273,7 +339,11 @@ func UnQuote(str string) string {
 }
 
 func OpenDB() (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open(config.GetDBPath()), &gorm.Config{Logger: gorm_logrus.New()})
+	return OpenDBAt(config.GetDBPath())
+}
+
+func OpenDBAt(path string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: gorm_logrus.New()})
 	return db, err
 }
 
@@ -294,3 +364,30 @@ func Sha256(str string) string {
 	h.Write([]byte(str))
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// WriteFileAtomic writes the content to a temporary file in the same
+// directory and renames it over the file, so a failed write never
+// leaves the file truncated.
+func WriteFileAtomic(path string, content []byte, perm os.FileMode) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = file.Chmod(perm)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
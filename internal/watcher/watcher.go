@@ -0,0 +1,213 @@
+// Package watcher detects changes to the journal files, either via the
+// file system notifications or by polling their modification time and
+// size.
+package watcher
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// POLL_INTERVAL is used when the file system notifications are not
+// available
+const POLL_INTERVAL = 2 * time.Second
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+type Watcher struct {
+	dir     string
+	ext     string
+	file    string
+	pattern string
+	skipped []string
+	files   map[string]fileState
+	stop    chan struct{}
+}
+
+// New creates a watcher for all the files inside the directory (and
+// sub directories) that has the given extension, except for the ones
+// inside the skipped directories.
+func New(dir string, ext string, skipped ...string) *Watcher {
+	w := &Watcher{dir: dir, ext: ext, pattern: dir + "/**/*" + ext, skipped: skipped, stop: make(chan struct{})}
+	w.files = w.scan()
+	return w
+}
+
+// NewFile creates a watcher for a single file.
+func NewFile(path string) *Watcher {
+	path = filepath.Clean(path)
+	w := &Watcher{dir: filepath.Dir(path), file: path, stop: make(chan struct{})}
+	w.files = w.scan()
+	return w
+}
+
+// Start calls onChange with the list of changed files (created,
+// modified or removed) whenever a change is detected. It blocks until
+// Stop is called.
+func (w *Watcher) Start(interval time.Duration, onChange func(files []string)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			files := w.scan()
+			changed := diff(w.files, files)
+			w.files = files
+			if len(changed) > 0 {
+				onChange(changed)
+			}
+		}
+	}
+}
+
+// Watch is like Start, but relies on the file system notifications
+// instead of polling. The notifications are debounced, onChange is
+// called once the files stay unchanged for the debounce duration, so an
+// editor saving a file in multiple steps triggers a single change. It
+// falls back to polling if the notifications are not available, like
+// on some network file systems.
+func (w *Watcher) Watch(debounce time.Duration, onChange func(files []string)) {
+	notifier, err := fsnotify.NewWatcher()
+	if err == nil {
+		err = w.addDirs(notifier)
+	}
+	if err != nil {
+		log.Warn("Falling back to polling the journal files: ", err)
+		if notifier != nil {
+			notifier.Close()
+		}
+		w.Start(POLL_INTERVAL, onChange)
+		return
+	}
+	defer notifier.Close()
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case e, ok := <-notifier.Events:
+			if !ok {
+				return
+			}
+			if e.Has(fsnotify.Create) && w.file == "" {
+				if stat, err := os.Stat(e.Name); err == nil && stat.IsDir() {
+					w.addDirs(notifier)
+				}
+			}
+			if w.matches(e) {
+				timer.Reset(debounce)
+			}
+		case err, ok := <-notifier.Errors:
+			if !ok {
+				return
+			}
+			log.Warn("Failed to watch the journal files: ", err)
+		case <-timer.C:
+			files := w.scan()
+			changed := diff(w.files, files)
+			w.files = files
+			if len(changed) > 0 {
+				onChange(changed)
+			}
+		}
+	}
+}
+
+func (w *Watcher) matches(e fsnotify.Event) bool {
+	if w.file != "" {
+		return filepath.Clean(e.Name) == w.file
+	}
+	return strings.HasSuffix(e.Name, w.ext) || e.Has(fsnotify.Remove) || e.Has(fsnotify.Rename)
+}
+
+// addDirs watches the directory and the sub directories, the
+// notifications are not recursive. Only the parent directory is
+// watched for a single file, editors usually replace the file instead
+// of writing to it.
+func (w *Watcher) addDirs(notifier *fsnotify.Watcher) error {
+	if w.file != "" {
+		return notifier.Add(w.dir)
+	}
+
+	return filepath.WalkDir(w.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		if path != w.dir && (strings.HasPrefix(d.Name(), ".") || w.isSkipped(path)) {
+			return filepath.SkipDir
+		}
+		return notifier.Add(path)
+	})
+}
+
+func (w *Watcher) Stop() {
+	close(w.stop)
+}
+
+func (w *Watcher) scan() map[string]fileState {
+	files := make(map[string]fileState)
+	paths := []string{w.file}
+	if w.file == "" {
+		paths, _ = doublestar.FilepathGlob(w.pattern)
+	}
+	for _, path := range paths {
+		if w.isSkipped(path) {
+			continue
+		}
+		stat, err := os.Stat(path)
+		if err != nil || !stat.Mode().IsRegular() {
+			continue
+		}
+		files[filepath.Clean(path)] = fileState{modTime: stat.ModTime(), size: stat.Size()}
+	}
+	return files
+}
+
+func (w *Watcher) isSkipped(path string) bool {
+	for _, dir := range w.skipped {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func diff(before map[string]fileState, after map[string]fileState) []string {
+	changed := []string{}
+	for path, state := range after {
+		if previous, ok := before[path]; !ok || previous != state {
+			changed = append(changed, path)
+		}
+	}
+
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+
+	sort.Strings(changed)
+	return changed
+}
@@ -0,0 +1,86 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.ledger"), []byte(""), 0644)
+
+	w := New(dir, ".ledger")
+	changes := make(chan []string, 10)
+	go w.Watch(100*time.Millisecond, func(files []string) { changes <- files })
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		os.WriteFile(filepath.Join(dir, "main.ledger"), []byte(time.Now().String()), 0644)
+		time.Sleep(10 * time.Millisecond)
+	}
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644)
+
+	select {
+	case files := <-changes:
+		assert.Equal(t, []string{filepath.Join(dir, "main.ledger")}, files)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not detected")
+	}
+
+	select {
+	case files := <-changes:
+		t.Fatalf("unexpected change %v", files)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatchSkipped(t *testing.T) {
+	dir := t.TempDir()
+	backups := filepath.Join(dir, "backups")
+	os.MkdirAll(backups, 0750)
+	os.WriteFile(filepath.Join(dir, "main.ledger"), []byte(""), 0644)
+
+	w := New(dir, ".ledger", backups)
+	changes := make(chan []string, 10)
+	go w.Watch(100*time.Millisecond, func(files []string) { changes <- files })
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	os.WriteFile(filepath.Join(backups, "0-main.ledger"), []byte(""), 0644)
+	os.WriteFile(filepath.Join(dir, "main.ledger"), []byte(time.Now().String()), 0644)
+
+	select {
+	case files := <-changes:
+		assert.Equal(t, []string{filepath.Join(dir, "main.ledger")}, files)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not detected")
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "paisa.yaml")
+	os.WriteFile(path, []byte("journal_path: main.ledger"), 0644)
+
+	w := NewFile(path)
+	changes := make(chan []string, 10)
+	go w.Watch(100*time.Millisecond, func(files []string) { changes <- files })
+	defer w.Stop()
+	time.Sleep(100 * time.Millisecond)
+
+	os.WriteFile(filepath.Join(dir, "other.yaml"), []byte(""), 0644)
+	os.WriteFile(path+".tmp", []byte("journal_path: personal.ledger"), 0644)
+	os.Rename(path+".tmp", path)
+
+	select {
+	case files := <-changes:
+		assert.Equal(t, []string{path}, files)
+	case <-time.After(5 * time.Second):
+		t.Fatal("change not detected")
+	}
+}
@@ -0,0 +1,124 @@
+// Package webhook delivers the events published on the event bus to
+// the webhooks configured by the user.
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/ananthakumaran/paisa/internal/config"
+	"github.com/ananthakumaran/paisa/internal/event"
+	"github.com/samber/lo"
+	log "github.com/sirupsen/logrus"
+)
+
+const SIGNATURE_HEADER = "X-Paisa-Signature"
+
+const maxAttempts = 3
+
+type Payload struct {
+	event.Event
+	Text string `json:"text"`
+}
+
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Start subscribes to all the events. The webhooks are read from the
+// config on every event, so changes to the config apply without a
+// restart.
+func Start() func() {
+	return event.Subscribe(event.All, deliver)
+}
+
+func deliver(e event.Event) {
+	// events from sandboxes and other scoped databases are not
+	// interesting outside of the request
+	if namespace, ok := e.Data["namespace"].(string); ok && namespace != "" {
+		return
+	}
+
+	for _, hook := range config.GetConfig().Webhooks {
+		if len(hook.Events) > 0 && !lo.Contains(hook.Events, string(e.Name)) {
+			continue
+		}
+
+		err := Send(hook, e)
+		if err != nil {
+			log.Warnf("Failed to deliver %s to webhook %s: %v", e.Name, hook.Name, err)
+		}
+	}
+}
+
+// Send posts the event to the webhook, retrying a few times if the
+// endpoint is unreachable or fails with a server error.
+func Send(hook config.Webhook, e event.Event) error {
+	body, err := json.Marshal(Payload{Event: e, Text: Describe(e)})
+	if err != nil {
+		return err
+	}
+
+	for attempt := 1; ; attempt++ {
+		retry, err := post(hook, body)
+		if err == nil || !retry || attempt == maxAttempts {
+			return err
+		}
+		time.Sleep(time.Duration(attempt*attempt) * time.Second)
+	}
+}
+
+func post(hook config.Webhook, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range hook.Headers {
+		req.Header.Set(key, value)
+	}
+
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(body)
+		req.Header.Set(SIGNATURE_HEADER, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode >= 500, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// Describe returns a human readable summary of the event.
+func Describe(e event.Event) string {
+	d := e.Data
+	switch e.Name {
+	case event.LedgerLoaded:
+		return "Journal synced"
+	case event.PriceUpdated:
+		return "Prices updated"
+	case event.PriceFailed:
+		return fmt.Sprintf("Failed to fetch the price of %v: %v", d["commodity"], d["error"])
+	case event.JournalChanged:
+		return fmt.Sprintf("Journal files changed: %v", d["files"])
+	case event.MonthClosed:
+		return fmt.Sprintf("Month %v closed", d["month"])
+	case event.ThresholdCrossed:
+		return fmt.Sprintf("%v exceeded the %v of %v, currently at %v", d["account"], d["kind"], d["limit"], d["value"])
+	case event.GoalReached:
+		return fmt.Sprintf("%v goal %v reached its target of %v", d["type"], d["name"], d["target"])
+	}
+	return string(e.Name)
+}
@@ -0,0 +1,275 @@
+import * as d3 from "d3";
+import _ from "lodash";
+import { generateColorScheme } from "./colors";
+import {
+  formatCurrency,
+  formatCurrencyCrude,
+  formatFloat,
+  rem,
+  skipTicks,
+  tooltip,
+  type Legend
+} from "./utils";
+
+export interface CustomReport {
+  name: string;
+  description: string;
+  accounts: string[];
+  payee: string;
+  tags: string[];
+  from: string;
+  until: string;
+  last_n_months: number;
+  group_by: string[];
+  account_depth: number;
+  aggregation: "" | "sum" | "count" | "average" | "min" | "max";
+  negate: boolean;
+  chart: "" | "bar" | "line" | "pie" | "table";
+}
+
+export interface CustomReportValue {
+  group: string;
+  series: string;
+  value: number;
+  count: number;
+}
+
+export interface CustomReportResult {
+  report: CustomReport;
+  groups: string[];
+  series: string[];
+  values: CustomReportValue[];
+  totals: CustomReportValue[];
+}
+
+export function formatCustomReportValue(report: CustomReport, value: number) {
+  return report.aggregation === "count" ? formatFloat(value, 0) : formatCurrency(value);
+}
+
+// seriesOf returns the series of the report, the groups are treated as
+// a single series when the report has only one dimension.
+function seriesOf(result: CustomReportResult) {
+  return _.isEmpty(result.series) ? [result.report.name] : result.series;
+}
+
+function valueOf(result: CustomReportResult, group: string, series: string) {
+  const found = _.find(
+    result.values,
+    (v) => v.group === group && (_.isEmpty(result.series) || v.series === series)
+  );
+  return found?.value || 0;
+}
+
+export function renderCustomReport(result: CustomReportResult, element: Element): Legend[] {
+  d3.select(element).selectAll("*").remove();
+  switch (result.report.chart) {
+    case "table":
+      return [];
+    case "line":
+      return renderLine(result, element);
+    case "pie":
+      return renderPie(result, element);
+    default:
+      return renderBar(result, element);
+  }
+}
+
+function setup(element: Element) {
+  const svg = d3.select(element),
+    margin = { top: rem(20), right: rem(30), bottom: rem(80), left: rem(60) },
+    width = element.parentElement.clientWidth - margin.left - margin.right,
+    height = +svg.attr("height") - margin.top - margin.bottom,
+    g = svg.append("g").attr("transform", "translate(" + margin.left + "," + margin.top + ")");
+  return { g, width, height };
+}
+
+function renderAxis(
+  g: d3.Selection<SVGGElement, unknown, null, undefined>,
+  x: d3.ScaleBand<string> | d3.ScalePoint<string>,
+  y: d3.ScaleLinear<number, number>,
+  width: number,
+  height: number,
+  report: CustomReport
+) {
+  g.append("g")
+    .attr("class", "axis x")
+    .attr("transform", "translate(0," + height + ")")
+    .call(d3.axisBottom(x).tickFormat(skipTicks(30, x, (d) => d.toString())))
+    .selectAll("text")
+    .attr("y", 10)
+    .attr("x", -8)
+    .attr("dy", ".35em")
+    .attr("transform", "rotate(-45)")
+    .style("text-anchor", "end");
+
+  g.append("g")
+    .attr("class", "axis y")
+    .call(
+      d3
+        .axisLeft(y)
+        .tickSize(-width)
+        .tickFormat((v) =>
+          report.aggregation === "count"
+            ? formatFloat(v as number, 0)
+            : formatCurrencyCrude(v as number)
+        )
+    );
+}
+
+function groupTooltip(result: CustomReportResult, group: string) {
+  const series = seriesOf(result);
+  const rows = _.chain(series)
+    .map((s) => [s, valueOf(result, group, s)] as [string, number])
+    .filter(([, value]) => value !== 0)
+    .map(([s, value]) => [
+      s,
+      [formatCustomReportValue(result.report, value), "has-text-weight-bold has-text-right"]
+    ])
+    .value();
+  const total = _.find(result.totals, (t) => t.group === group);
+  return tooltip(rows, {
+    header: group,
+    total:
+      series.length > 1 && total ? formatCustomReportValue(result.report, total.value) : undefined
+  });
+}
+
+function renderBar(result: CustomReportResult, element: Element): Legend[] {
+  const MAX_BAR_WIDTH = rem(40);
+  const { g, width, height } = setup(element);
+  const series = seriesOf(result);
+
+  const points = _.map(result.groups, (group) =>
+    _.merge({ group }, _.fromPairs(_.map(series, (s) => [s, valueOf(result, group, s)])))
+  );
+
+  const stacked = d3.stack().offset(d3.stackOffsetDiverging).keys(series)(
+    points as unknown as { [key: string]: number }[]
+  );
+
+  const x = d3.scaleBand().range([0, width]).paddingInner(0.1).paddingOuter(0);
+  x.domain(result.groups);
+  const y = d3
+    .scaleLinear()
+    .range([height, 0])
+    .domain([
+      Math.min(0, d3.min(stacked, (s) => d3.min(s, (d) => d[0]))),
+      Math.max(0, d3.max(stacked, (s) => d3.max(s, (d) => d[1])))
+    ]);
+  const z = generateColorScheme(series);
+
+  renderAxis(g, x, y, width, height, result.report);
+
+  g.append("g")
+    .selectAll("g")
+    .data(stacked)
+    .join("g")
+    .attr("fill", (d) => z(d.key))
+    .selectAll("rect")
+    .data((d) => d)
+    .join("rect")
+    .attr("data-tippy-content", (d) => groupTooltip(result, (d.data as any).group))
+    .attr(
+      "x",
+      (d) =>
+        x((d.data as any).group) + (x.bandwidth() - Math.min(x.bandwidth(), MAX_BAR_WIDTH)) / 2
+    )
+    .attr("y", (d) => y(d[1]))
+    .attr("height", (d) => y(d[0]) - y(d[1]))
+    .attr("width", Math.min(x.bandwidth(), MAX_BAR_WIDTH));
+
+  return legends(series, z);
+}
+
+function renderLine(result: CustomReportResult, element: Element): Legend[] {
+  const { g, width, height } = setup(element);
+  const series = seriesOf(result);
+
+  const x = d3.scalePoint().range([0, width]).padding(0.5).domain(result.groups);
+  const values = _.map(result.values, (v) => v.value);
+  const y = d3
+    .scaleLinear()
+    .range([height, 0])
+    .domain([Math.min(0, _.min(values) || 0), Math.max(0, _.max(values) || 0)]);
+  const z = generateColorScheme(series);
+
+  renderAxis(g, x, y, width, height, result.report);
+
+  for (const s of series) {
+    const points = _.map(result.groups, (group) => ({ group, value: valueOf(result, group, s) }));
+    g.append("path")
+      .style("stroke", z(s))
+      .style("fill", "none")
+      .attr(
+        "d",
+        d3
+          .line<{ group: string; value: number }>()
+          .curve(d3.curveMonotoneX)
+          .x((d) => x(d.group))
+          .y((d) => y(d.value))(points)
+      );
+
+    g.append("g")
+      .selectAll("circle")
+      .data(points)
+      .join("circle")
+      .attr("r", 3)
+      .attr("fill", z(s))
+      .attr("cx", (d) => x(d.group))
+      .attr("cy", (d) => y(d.value))
+      .attr("data-tippy-content", (d) => groupTooltip(result, d.group));
+  }
+
+  return legends(series, z);
+}
+
+// renderPie shows the share of each group in the total, the series are
+// not used.
+function renderPie(result: CustomReportResult, element: Element): Legend[] {
+  const svg = d3.select(element),
+    width = element.parentElement.clientWidth,
+    height = +svg.attr("height"),
+    radius = Math.min(width, height) / 2 - rem(20),
+    g = svg.append("g").attr("transform", `translate(${width / 2},${height / 2})`);
+
+  const totals = _.filter(result.totals, (t) => t.value !== 0);
+  const total = _.sumBy(totals, (t) => Math.abs(t.value));
+  const z = generateColorScheme(_.map(totals, (t) => t.group));
+
+  const pie = d3.pie<CustomReportValue>().value((t) => Math.abs(t.value));
+  const arc = d3
+    .arc<d3.PieArcDatum<CustomReportValue>>()
+    .innerRadius(radius * 0.5)
+    .outerRadius(radius);
+
+  g.selectAll("path")
+    .data(pie(totals))
+    .join("path")
+    .attr("d", arc)
+    .attr("fill", (d) => z(d.data.group))
+    .attr("data-tippy-content", (d) =>
+      tooltip([
+        [
+          d.data.group,
+          [
+            formatCustomReportValue(result.report, d.data.value),
+            "has-text-weight-bold has-text-right"
+          ]
+        ],
+        ["", [formatFloat((Math.abs(d.data.value) / total) * 100) + "%", "has-text-right"]]
+      ])
+    );
+
+  return legends(
+    _.map(totals, (t) => t.group),
+    z
+  );
+}
+
+function legends(keys: string[], z: d3.ScaleOrdinal<string, string>): Legend[] {
+  if (keys.length <= 1) {
+    return [];
+  }
+
+  return _.map(keys, (k) => ({ label: k, color: z(k), shape: "square" as const }));
+}
@@ -0,0 +1,34 @@
+import { describe, expect, test } from "bun:test";
+import { convertRules } from "./hledger_rules";
+
+describe("hledger rules", () => {
+  test("convert", () => {
+    const { template, reverse } = convertRules(`# bank
+skip 1
+newest-first
+fields date, description, , amount-out, amount-in
+date-format %d/%m/%Y
+currency INR
+account1 Assets:Checking
+
+if SWIGGY|ZOMATO
+ account2 Expenses:Food
+
+if
+%description salary
+& %amount-in 5000
+ account2 Income:Salary
+ comment salary
+`);
+
+    expect(reverse).toBe(true);
+    expect(template).toBe(
+      `{{#if (gte ROW.index 1)}}
+{{date ROW.A "DD/MM/YYYY"}} {{trim ROW.B}} {{#if (and (regexpTest ROW.B "salary" flags="i") (regexpTest ROW.E "5000" flags="i"))}}; salary{{/if}}
+    Assets:Checking    {{#if (isBlank ROW.E)}}{{negate ROW.D}}{{else}}{{amount ROW.E}}{{/if}} INR
+    {{#if (and (regexpTest ROW.B "salary" flags="i") (regexpTest ROW.E "5000" flags="i"))}}Income:Salary{{else if (regexpTest (textRange "A" "Z" separator=",") "SWIGGY|ZOMATO" flags="i")}}Expenses:Food{{else}}Expenses:Unknown{{/if}}
+{{/if}}
+`
+    );
+  });
+});
@@ -0,0 +1,281 @@
+import _ from "lodash";
+
+// Converts a hledger CSV rules file to an import template. Only the
+// commonly used subset is supported: skip, fields, date-format,
+// newest-first, field assignments and if blocks.
+// https://hledger.org/hledger.html#csv
+
+interface Override {
+  condition: string;
+  value: string;
+}
+
+interface Assignment {
+  value?: string;
+  overrides: Override[];
+}
+
+export interface ConvertedRules {
+  template: string;
+  reverse: boolean;
+}
+
+const AMOUNT_FIELD = /^amount\d*(-in|-out)?$/;
+const ASSIGNABLE_FIELD =
+  /^(date2?|status|code|description|comment|currency\d*|balance\d*|account\d+|amount\d*(-in|-out)?|comment\d+)$/;
+
+const STRFTIME: Record<string, string> = {
+  Y: "YYYY",
+  y: "YY",
+  m: "MM",
+  "-m": "M",
+  d: "DD",
+  "-d": "D",
+  e: "D",
+  b: "MMM",
+  h: "MMM",
+  B: "MMMM",
+  H: "HH",
+  "-H": "H",
+  M: "mm",
+  S: "ss",
+  p: "A",
+  "%": "%"
+};
+
+function column(index: number) {
+  return String.fromCharCode(65 + index);
+}
+
+function quote(str: string) {
+  return `"${str.replaceAll('"', '\\"')}"`;
+}
+
+function toDayjsFormat(format: string) {
+  return format.replace(/%(-?[a-zA-Z%])/g, (match, spec) => STRFTIME[spec] ?? match);
+}
+
+export function convertRules(rules: string): ConvertedRules {
+  const fields: string[] = [];
+  const assignments: Record<string, Assignment> = {};
+  const skipConditions: string[] = [];
+  let skip = 0;
+  let dateFormat: string = null;
+  let reverse = false;
+
+  const assignment = (name: string) => {
+    assignments[name] = assignments[name] || { overrides: [] };
+    return assignments[name];
+  };
+
+  const ref = (name: string) => {
+    if (/^\d+$/.test(name)) {
+      return `ROW.${column(parseInt(name) - 1)}`;
+    }
+    const index = fields.indexOf(name);
+    return index >= 0 ? `ROW.${column(index)}` : null;
+  };
+
+  const matcher = (line: string) => {
+    let negate = false;
+    if (line.startsWith("!")) {
+      negate = true;
+      line = _.trim(line.slice(1));
+    }
+
+    let subject = `(textRange "A" "Z" separator=",")`;
+    const fieldMatch = line.match(/^%(\S+)\s+(.*)$/);
+    if (fieldMatch && ref(fieldMatch[1])) {
+      subject = ref(fieldMatch[1]);
+      line = fieldMatch[2];
+    }
+
+    const test = `(regexpTest ${subject} ${quote(line)} flags="i")`;
+    return negate ? `(not ${test})` : test;
+  };
+
+  const combine = (helper: string, conditions: string[]) =>
+    conditions.length == 1 ? conditions[0] : `(${helper} ${conditions.join(" ")})`;
+
+  const lines = rules.split(/\r?\n/);
+  for (let i = 0; i < lines.length; i++) {
+    const line = lines[i];
+    const trimmed = _.trim(line);
+    if (trimmed == "" || /^[#;*]/.test(trimmed)) {
+      continue;
+    }
+
+    const [directive, ...rest] = trimmed.split(/\s+/);
+    const argument = _.trim(trimmed.slice(directive.length));
+
+    switch (directive) {
+      case "skip":
+        skip = argument == "" ? 1 : parseInt(argument);
+        continue;
+      case "fields":
+        fields.push(...argument.split(",").map((f) => _.trim(f).toLowerCase()));
+        fields.forEach((field) => {
+          if (field != "" && ASSIGNABLE_FIELD.test(field) && !assignments[field]?.value) {
+            assignment(field).value = `%${field}`;
+          }
+        });
+        continue;
+      case "date-format":
+        dateFormat = toDayjsFormat(argument);
+        continue;
+      case "newest-first":
+        reverse = true;
+        continue;
+      case "if": {
+        // patterns on the following lines are ORed, unless they start
+        // with & in which case they are ANDed with the previous one
+        const groups: string[][] = [];
+        const addPattern = (pattern: string) => {
+          if (pattern.startsWith("&")) {
+            _.last(groups)?.push(matcher(_.trim(pattern.slice(1))));
+          } else {
+            groups.push([matcher(pattern)]);
+          }
+        };
+
+        if (argument != "") {
+          addPattern(argument);
+        }
+
+        // without an inline pattern, every line till the assignments is
+        // a pattern, otherwise only the ones that can't be a directive
+        const continuation = argument == "" ? /^\S/ : /^[&!%]/;
+        while (i + 1 < lines.length && continuation.test(lines[i + 1])) {
+          i++;
+          if (!/^[#;*]/.test(lines[i])) {
+            addPattern(_.trim(lines[i]));
+          }
+        }
+
+        const condition = combine(
+          "or",
+          groups.map((g) => combine("and", g))
+        );
+
+        while (i + 1 < lines.length && /^\s+\S/.test(lines[i + 1])) {
+          i++;
+          const [name, ...value] = _.trim(lines[i]).split(/\s+/);
+          if (name == "skip" || name == "end") {
+            skipConditions.push(condition);
+          } else if (ASSIGNABLE_FIELD.test(name)) {
+            assignment(name).overrides.push({ condition, value: value.join(" ") });
+          }
+        }
+        continue;
+      }
+    }
+
+    if (ASSIGNABLE_FIELD.test(directive)) {
+      assignment(directive).value = rest.join(" ");
+    }
+  }
+
+  const interpolate = (name: string, value: string) => {
+    const single = value.match(/^%([\w-]+)$/);
+    if (single && ref(single[1])) {
+      const r = ref(single[1]);
+      if (AMOUNT_FIELD.test(name)) {
+        return `{{amount ${r}}}`;
+      }
+      if (name == "date" && dateFormat) {
+        return `{{date ${r} ${quote(dateFormat)}}}`;
+      }
+    }
+
+    return value.replace(/%([\w-]+)/g, (match, field) => {
+      const r = ref(field);
+      return r ? `{{trim ${r}}}` : match;
+    });
+  };
+
+  // prefix is added only when the field has a value, to avoid empty
+  // comments
+  const expression = (name: string, fallback = "", prefix = "") => {
+    const a = assignments[name];
+    if (!a) {
+      return fallback;
+    }
+
+    const base = a.value != null ? prefix + interpolate(name, a.value) : fallback;
+    if (_.isEmpty(a.overrides)) {
+      return base;
+    }
+
+    // later rules take precedence over the earlier ones
+    const branches = [...a.overrides].reverse();
+    let result = `{{#if ${branches[0].condition}}}${prefix}${interpolate(name, branches[0].value)}`;
+    for (const o of branches.slice(1)) {
+      result += `{{else if ${o.condition}}}${prefix}${interpolate(name, o.value)}`;
+    }
+    return result + (base == "" ? "{{/if}}" : `{{else}}${base}{{/if}}`);
+  };
+
+  const amountExpression = (n: string) => {
+    const names = n == "1" ? ["amount1", "amount"] : [`amount${n}`];
+    for (const name of names) {
+      if (assignments[name]) {
+        return expression(name);
+      }
+      const inField = assignments[`${name}-in`];
+      const outField = assignments[`${name}-out`];
+      if (inField || outField) {
+        const amountIn = expression(`${name}-in`);
+        const amountOut = expression(`${name}-out`);
+        const outRef = outField?.value?.match(/^%([\w-]+)$/);
+        const negated =
+          outRef && ref(outRef[1]) ? `{{negate ${ref(outRef[1])}}}` : `-${amountOut}`;
+        const inRef = inField?.value?.match(/^%([\w-]+)$/);
+        if (inRef && ref(inRef[1])) {
+          return `{{#if (isBlank ${ref(inRef[1])})}}${negated}{{else}}${amountIn}{{/if}}`;
+        }
+        return amountIn || negated;
+      }
+    }
+    return "";
+  };
+
+  const postings = _.chain(Object.keys(assignments))
+    .map((name) => name.match(/^(?:account|amount)(\d+)/)?.[1])
+    .concat(["1", "2"])
+    .compact()
+    .uniq()
+    .sortBy((n) => parseInt(n))
+    .value();
+
+  const currency = (n: string) => expression(`currency${n}`, expression("currency"));
+
+  const header = _.compact([
+    expression("date"),
+    expression("status"),
+    expression("description"),
+    expression("comment", "", "; ")
+  ]).join(" ");
+
+  const postingLines = postings.map((n) => {
+    const fallback = n == "1" ? "Assets:Unknown" : n == "2" ? "Expenses:Unknown" : "";
+    const account = expression(`account${n}`, fallback);
+    const amount = amountExpression(n);
+    const commodity = amount != "" ? currency(n) : "";
+    return _.trimEnd(`    ${account}    ${amount} ${commodity}`);
+  });
+
+  const conditions: string[] = [];
+  if (skip > 0) {
+    conditions.push(`(gte ROW.index ${skip})`);
+  }
+  if (!_.isEmpty(skipConditions)) {
+    conditions.push(`(not ${combine("or", skipConditions)})`);
+  }
+
+  let template = [header, ...postingLines].join("\n");
+  if (!_.isEmpty(conditions)) {
+    template = `{{#if ${combine("and", conditions)}}}\n${template}\n{{/if}}`;
+  }
+
+  return { template: template + "\n", reverse };
+}
@@ -20,7 +20,7 @@ function space(length: number) {
   return " ".repeat(length);
 }
 
-const DATE = /^\d{4}[/-]\d{2}[/-]\d{2}/;
+const DATE = /^\d{4}[/.-]\d{1,2}[/.-]\d{1,2}/;
 
 // https://ledger-cli.org/doc/ledger3.html#Journal-Format
 function formatLine(line: string, state: State) {
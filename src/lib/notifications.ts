@@ -0,0 +1,46 @@
+import _ from "lodash";
+import { refresh } from "../store";
+import { sync } from "./sync";
+import { authToken } from "./utils";
+
+interface Notification {
+  type: "journal_changed" | "sync_completed" | "config_changed";
+  files?: string[];
+}
+
+let socket: WebSocket = null;
+
+export function connectNotifications() {
+  if (socket) {
+    return;
+  }
+
+  const protocol = window.location.protocol === "https:" ? "wss:" : "ws:";
+  let url = `${protocol}//${window.location.host}/api/notifications`;
+  const token = authToken();
+  if (!_.isEmpty(token)) {
+    url += `?token=${encodeURIComponent(token)}`;
+  }
+
+  const refreshLater = _.debounce(() => refresh(), 500);
+  const syncLater = _.debounce(() => sync({ journal: true }), 500);
+
+  socket = new WebSocket(url);
+  socket.onmessage = (message) => {
+    const notification: Notification = JSON.parse(message.data);
+    switch (notification.type) {
+      case "journal_changed":
+        syncLater();
+        break;
+      case "sync_completed":
+      case "config_changed":
+        refreshLater();
+        break;
+    }
+  };
+
+  socket.onclose = () => {
+    socket = null;
+    setTimeout(connectNotifications, 5000);
+  };
+}
@@ -1,9 +1,9 @@
 import type { StreamParser, StringStream } from "@codemirror/language";
 import _ from "lodash";
 
-const DATE = /^\d{4}[/-]\d{2}[/-]\d{2}/;
-const DATE_TIME = /^\d{4}[/-]\d{2}[/-]\d{2} \d{2}:\d{2}:\d{2}/;
-const LOT_DATE = /^\[\d{4}[/-]\d{2}[/-]\d{2}\]/;
+const DATE = /^\d{4}[/.-]\d{1,2}[/.-]\d{1,2}/;
+const DATE_TIME = /^\d{4}[/.-]\d{1,2}[/.-]\d{1,2} \d{2}:\d{2}:\d{2}/;
+const LOT_DATE = /^\[\d{4}[/.-]\d{1,2}[/.-]\d{1,2}\]/;
 const AMOUNT = /^[+-]?(?:[0-9,])+(\.(?:[0-9,])+)?/;
 const COMMODITY_DIRECTIVE = /^commodity/;
 const ACCOUNT_DIRECTIVE = /^account/;
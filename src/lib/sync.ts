@@ -1,17 +1,53 @@
 import * as toast from "bulma-toast";
-import { ajax } from "./utils";
+import _ from "lodash";
+import { priceProgress, type PriceProgress } from "../store";
+import { ajax, authToken } from "./utils";
 
 export async function sync(request: Record<string, any>) {
-  const { success, message } = await ajax("/api/sync", {
-    method: "POST",
-    body: JSON.stringify(request)
-  });
+  const events = request.prices ? watchPriceProgress() : null;
 
-  if (!success) {
-    toast.toast({
-      message: `<b>Failed to sync</b>\n${message}`,
-      type: "is-danger",
-      duration: 10000
+  try {
+    const { success, message } = await ajax("/api/sync", {
+      method: "POST",
+      body: JSON.stringify(request)
     });
+
+    if (!success) {
+      toast.toast({
+        message: `<b>Failed to sync</b>\n${message}`,
+        type: "is-danger",
+        duration: 10000
+      });
+    }
+  } finally {
+    events?.close();
+    priceProgress.set(null);
+  }
+}
+
+function watchPriceProgress() {
+  let url = "/api/sync/progress";
+  const token = authToken();
+  if (!_.isEmpty(token)) {
+    url += `?token=${encodeURIComponent(token)}`;
   }
+
+  const events = new EventSource(url);
+  events.addEventListener("progress", (event: MessageEvent) => {
+    const progress: PriceProgress = JSON.parse(event.data);
+    if (progress.status == "completed") {
+      priceProgress.set(null);
+      return;
+    }
+
+    priceProgress.set(progress);
+    if (progress.status == "failed") {
+      toast.toast({
+        message: `<b>Failed to fetch price of ${progress.commodity}</b>\n${progress.error}`,
+        type: "is-warning",
+        duration: 10000
+      });
+    }
+  });
+  return events;
 }
@@ -188,12 +188,12 @@ export default {
     }
     return cells.join(options.hash.separator || " ");
   },
-  regexpTest(str: string, regexp: string) {
+  regexpTest(str: string, regexp: string, options: any) {
     if (!_.isString(str)) {
       return;
     }
 
-    return new RegExp(regexp).test(str);
+    return new RegExp(regexp, options.hash.flags).test(str);
   },
   regexpMatch(str: string, regexp: string, options: any) {
     if (!_.isString(str)) {
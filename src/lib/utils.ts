@@ -10,6 +10,7 @@ import { error } from "@sveltejs/kit";
 import { goto } from "$app/navigation";
 import chroma from "chroma-js";
 import { iconGlyph } from "./icon";
+import type { CustomReport, CustomReportResult } from "./custom_report";
 
 export interface AutoCompleteItem {
   label: string;
@@ -82,6 +83,15 @@ export interface TransactionSchedule {
   amount: number;
 }
 
+export interface ScheduledTransaction {
+  id: number;
+  name: string;
+  date: string;
+  file: string;
+  content: string;
+  status: "pending" | "posted" | "rejected";
+}
+
 export interface TransactionSequence {
   transactions: Transaction[];
   period: string;
@@ -174,6 +184,13 @@ export interface AssetBreakdown {
   xirr: number;
   gainAmount: number;
   absoluteReturn: number;
+  risk?: Risk;
+}
+
+export interface Risk {
+  maxDrawdown: number;
+  volatility: number;
+  sharpe: number;
 }
 
 export interface LiabilityBreakdown {
@@ -457,6 +474,13 @@ export interface LedgerFileError {
   message: string;
 }
 
+export interface ConfigValidationError {
+  path: string;
+  line: number;
+  column: number;
+  message: string;
+}
+
 export interface SheetFileError {
   line_from: number;
   line_to: number;
@@ -538,6 +562,9 @@ export interface SheetLineResult {
 }
 
 const tokenKey = "token";
+const sandboxKey = "sandbox";
+const profileKey = "profile";
+const ssoKey = "sso";
 
 type RequestOptions = RequestInit & {
   background?: boolean;
@@ -555,6 +582,12 @@ export function ajax(route: "/api/schedule_al"): Promise<{
 }>;
 export function ajax(route: "/api/diagnosis"): Promise<{ issues: Issue[] }>;
 export function ajax(route: "/api/logs"): Promise<{ logs: Log[] }>;
+export function ajax(route: "/api/custom_reports"): Promise<{ reports: CustomReport[] }>;
+export function ajax(
+  route: "/api/custom_reports/:name",
+  options?: RequestOptions,
+  params?: Record<string, string>
+): Promise<{ result?: CustomReportResult; error?: string }>;
 export function ajax(
   route: "/api/investment"
 ): Promise<{ assets: Posting[]; yearly_cards: InvestmentYearlyCard[] }>;
@@ -574,6 +607,7 @@ export function ajax(
 export function ajax(route: "/api/networth"): Promise<{
   networthTimeline: Networth[];
   xirr: number;
+  risk: Risk;
 }>;
 export function ajax(route: "/api/gain"): Promise<{
   gain_breakdown: Gain[];
@@ -644,6 +678,15 @@ export function ajax(
   route: "/api/recurring"
 ): Promise<{ transaction_sequences: TransactionSequence[] }>;
 
+export function ajax(route: "/api/scheduled_transactions"): Promise<{
+  pending: ScheduledTransaction[];
+  posted: ScheduledTransaction[];
+}>;
+export function ajax(
+  route: "/api/scheduled_transactions/approve" | "/api/scheduled_transactions/reject",
+  options?: RequestOptions
+): Promise<{ success: boolean; message?: string }>;
+
 export function ajax(route: "/api/liabilities/interest"): Promise<{
   interest_timeline_breakdown: Interest[];
 }>;
@@ -767,6 +810,11 @@ export function ajax(
   options?: RequestOptions
 ): Promise<{ success: boolean; error?: string }>;
 
+export function ajax(
+  route: "/api/config/validate",
+  options?: RequestOptions
+): Promise<{ valid: boolean; errors: ConfigValidationError[] }>;
+
 export function ajax(route: "/api/ping"): Promise<{ success: boolean; error?: string }>;
 
 export async function ajax(
@@ -796,6 +844,16 @@ export async function ajax(
     options.headers["X-Auth"] = token;
   }
 
+  const profile = sessionStorage.getItem(profileKey);
+  if (!_.isEmpty(profile)) {
+    options.headers["X-Profile"] = profile;
+  }
+
+  const sandbox = sessionStorage.getItem(sandboxKey);
+  if (!_.isEmpty(sandbox)) {
+    options.headers["X-Sandbox"] = sandbox;
+  }
+
   const response = await fetch(route, options);
   const body = await response.text();
   if (!background) {
@@ -808,6 +866,15 @@ export async function ajax(
     error(401, "Unauthorized");
   }
 
+  if (
+    response.status == 404 &&
+    !_.isEmpty(sandbox) &&
+    JSON.parse(body).error == "Sandbox not found"
+  ) {
+    // discarded after being unused for a while
+    setSandbox(null);
+  }
+
   return JSON.parse(body, (key, value) => {
     if (
       _.isString(value) &&
@@ -827,12 +894,49 @@ export async function login(username: string, password: string) {
   return await ajax("/api/ping");
 }
 
+export function authToken() {
+  return localStorage.getItem(tokenKey);
+}
+
+export function loginWithSSO() {
+  localStorage.setItem(ssoKey, "true");
+  window.location.href = "/api/auth/oidc/login";
+}
+
 export function isLoggedIn() {
-  return !_.isEmpty(localStorage.getItem(tokenKey));
+  return !_.isEmpty(localStorage.getItem(tokenKey)) || !_.isEmpty(localStorage.getItem(ssoKey));
 }
 
 export function logout() {
   localStorage.removeItem(tokenKey);
+  if (!_.isEmpty(localStorage.getItem(ssoKey))) {
+    localStorage.removeItem(ssoKey);
+    fetch("/api/auth/logout", { method: "POST" });
+  }
+}
+
+export function currentSandbox() {
+  return sessionStorage.getItem(sandboxKey);
+}
+
+export function setSandbox(id: string | null) {
+  if (_.isEmpty(id)) {
+    sessionStorage.removeItem(sandboxKey);
+  } else {
+    sessionStorage.setItem(sandboxKey, id);
+  }
+}
+
+export function currentProfile() {
+  return sessionStorage.getItem(profileKey);
+}
+
+export function setProfile(name: string | null) {
+  if (_.isEmpty(name)) {
+    sessionStorage.removeItem(profileKey);
+  } else {
+    sessionStorage.setItem(profileKey, name);
+  }
 }
 
 function normalize(value: number) {
@@ -80,6 +80,18 @@ export const theme = writable("light");
 
 export const loading = writable(false);
 
+export interface PriceProgress {
+  commodity: string;
+  provider: string;
+  status: "fetching" | "stored" | "failed" | "completed";
+  count: number;
+  error?: string;
+  done: number;
+  total: number;
+}
+
+export const priceProgress = writable<PriceProgress>(null);
+
 const DELAY = 200;
 const DEBOUNCE_DELAY = 200;
 